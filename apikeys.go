@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// apiKeySettings are the defaults for requests made with one API key
+type apiKeySettings struct {
+	Language string // caption and summary language when a request names none
+}
+
+// parseAPIKeys parses YTSUMMARY_SERVER_API_KEYS: comma-separated KEY or
+// KEY=LANGUAGE entries, e.g. "team-es-key=es,team-en-key". Each key is
+// accepted alongside YTSUMMARY_SERVER_API_KEY.
+func parseAPIKeys(v string) (map[string]apiKeySettings, error) {
+	keys := map[string]apiKeySettings{}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, lang, _ := strings.Cut(entry, "=")
+		key, lang = strings.TrimSpace(key), strings.TrimSpace(lang)
+		if key == "" {
+			return nil, fmt.Errorf("invalid API key entry %q (use KEY or KEY=LANGUAGE)", entry)
+		}
+		if strings.ContainsFunc(lang, func(r rune) bool {
+			return !(r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		}) {
+			return nil, fmt.Errorf("invalid language %q for an API key (use a code such as es or pt-BR)", lang)
+		}
+		keys[key] = apiKeySettings{Language: lang}
+	}
+	return keys, nil
+}
+
+// apiKeysFromEnv reads YTSUMMARY_SERVER_API_KEYS, which may be a secret
+// reference
+func apiKeysFromEnv() (map[string]apiKeySettings, error) {
+	return parseAPIKeys(getConfig("", "YTSUMMARY_SERVER_API_KEYS"))
+}
+
+func (s *Server) setAPIKeys(keys map[string]apiKeySettings) {
+	s.apiKeys.Store(&keys)
+}
+
+// authEnabled reports whether requests need an API key
+func (s *Server) authEnabled() bool {
+	return s.getAPIKey() != "" || len(*s.apiKeys.Load()) > 0
+}
+
+// ErrAuthRequired is returned by endpoints that stay closed while the server
+// accepts no API key
+const ErrAuthRequired = "auth_required"
+
+// requireAuthConfigured refuses every request while the server accepts no API
+// key, for endpoints that must not be open to anyone who can reach the port.
+// It's checked per request, so a reload that sets or clears keys applies.
+func (s *Server) requireAuthConfigured(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authEnabled() {
+			writeError(w, http.StatusForbidden, ErrAuthRequired, "This endpoint is disabled until the server has an API key (YTSUMMARY_SERVER_API_KEY)")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// lookupAPIKey returns the settings for a key the server accepts
+func (s *Server) lookupAPIKey(key string) (apiKeySettings, bool) {
+	if key == "" {
+		return apiKeySettings{}, false
+	}
+	if key == s.getAPIKey() {
+		return apiKeySettings{}, true
+	}
+	settings, ok := (*s.apiKeys.Load())[key]
+	return settings, ok
+}
+
+// requestLanguage returns the language a request asked for, else its API
+// key's default, else the top language of its Accept-Language header, else
+// defaultLanguage
+func requestLanguage(r *http.Request, lang string) string {
+	if lang != "" {
+		return lang
+	}
+	if keyLang := getRequestContext(r).KeyLanguage; keyLang != "" {
+		return keyLang
+	}
+	if accepted := acceptedLanguage(r.Header.Get("Accept-Language")); accepted != "" {
+		return accepted
+	}
+	return defaultLanguage
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	got, err := parseAPIKeys(" team-es=es, team-br=pt-BR ,plain,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]apiKeySettings{"team-es": {Language: "es"}, "team-br": {Language: "pt-BR"}, "plain": {}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAPIKeys() = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"=es", "key=e s", "key=es;rm"} {
+		if _, err := parseAPIKeys(bad); err == nil {
+			t.Errorf("parseAPIKeys(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestAPIKeyDefaultLanguage(t *testing.T) {
+	var fetchedLang string
+	s := newServer(ServerConfig{
+		APIKey:  "main",
+		APIKeys: map[string]apiKeySettings{"team-es": {Language: "es"}, "other": {}},
+		Cache:   newTestCache(t),
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			fetchedLang = lang
+			return &FetchResult{Title: "Injected", Transcript: "Uno. Dos."}, nil
+		},
+	})
+	handler := s.Handler()
+
+	tests := []struct {
+		key, body, acceptLanguage, wantLang string
+		wantStatus                          int
+	}{
+		{"team-es", `{"url": "https://youtu.be/dQw4w9WgXcQ"}`, "", "es", http.StatusOK},
+		{"team-es", `{"url": "https://youtu.be/jNQXAC9IVRw", "language": "fr"}`, "", "fr", http.StatusOK},
+		{"main", `{"url": "https://youtu.be/9bZkp7q19f0"}`, "", "en", http.StatusOK},
+		{"other", `{"url": "https://youtu.be/kJQP7kiw5Fk"}`, "", "en", http.StatusOK},
+		{"wrong", `{"url": "https://youtu.be/dQw4w9WgXcQ"}`, "", "", http.StatusUnauthorized},
+		{"main", `{"url": "https://youtu.be/OPf0YbXqDm0"}`, "de-DE,de;q=0.9,en;q=0.5", "de", http.StatusOK},
+		{"main", `{"url": "https://youtu.be/OPf0YbXqDm0", "language": "fr"}`, "de", "fr", http.StatusOK},
+		{"team-es", `{"url": "https://youtu.be/OPf0YbXqDm0"}`, "de", "es", http.StatusOK},
+	}
+	for i, tt := range tests {
+		fetchedLang = ""
+		req := httptest.NewRequest("POST", "/v1/transcript", bytes.NewBufferString(tt.body))
+		req.Header.Set("X-API-Key", tt.key)
+		req.Header.Set("Accept-Language", tt.acceptLanguage)
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", i+1)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("key %s: status = %d, want %d", tt.key, w.Code, tt.wantStatus)
+		}
+		if fetchedLang != tt.wantLang {
+			t.Errorf("key %s: fetched language %q, want %q", tt.key, fetchedLang, tt.wantLang)
+		}
+	}
+}
+
+func TestRestrictedEndpointsNeedAPIKey(t *testing.T) {
+	cache := newTestCache(t)
+	s := newServer(ServerConfig{Cache: cache, DeadLetters: cache})
+	handler := s.Handler()
+
+	routes := []string{
+		"GET /v1/admin/dashboard",
+		"GET /v1/admin/audit/dQw4w9WgXcQ",
+		"GET /v1/admin/queue",
+		"GET /v1/admin/deadletter",
+		"POST /v1/admin/deadletter/dQw4w9WgXcQ/retry",
+		"POST /v1/admin/deadletter/dQw4w9WgXcQ/suppress",
+		"GET /v1/export",
+	}
+	client := 0
+	serve := func(route string) *httptest.ResponseRecorder {
+		client++
+		method, path, _ := strings.Cut(route, " ")
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", client)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	for _, route := range routes {
+		if w := serve(route); w.Code != http.StatusForbidden || !bytes.Contains(w.Body.Bytes(), []byte(ErrAuthRequired)) {
+			t.Errorf("%s without a server key: status %d %s, want 403 %s", route, w.Code, w.Body, ErrAuthRequired)
+		}
+	}
+
+	// A key set by a reload opens them to requests that present it
+	s.setAPIKey("secret")
+	for _, route := range routes {
+		if w := serve(route); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s without the key: status %d, want 401", route, w.Code)
+		}
+	}
+}
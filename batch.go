@@ -0,0 +1,394 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Batch configuration
+var (
+	batchFile     string
+	batchOutDir   string
+	batchManifest string
+	batchDelay    time.Duration
+
+	// Resuming an interrupted or partially failed run
+	batchResume       string
+	batchRetryClasses []string
+)
+
+// Manifest entry statuses
+const (
+	batchStatusOK         = "ok"
+	batchStatusFailed     = "failed"
+	batchStatusPending    = "pending"
+	batchStatusDeadLetter = "dead_letter" // skipped after failing too many runs
+	batchStatusSkipped    = "skipped"     // a music video, with --music-videos skip
+)
+
+// BatchManifest records the outcome of every video in a batch run. It is
+// rewritten after each video so an interrupted run leaves an accurate record.
+type BatchManifest struct {
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Language   string           `json:"language"`
+	Template   string           `json:"template,omitempty"`
+	Model      string           `json:"model"`
+	Videos     []*ManifestEntry `json:"videos"`
+}
+
+// ManifestEntry is the result for one video in a batch run
+type ManifestEntry struct {
+	URL               string             `json:"url"`
+	VideoID           string             `json:"video_id,omitempty"`
+	Title             string             `json:"title,omitempty"`
+	Status            string             `json:"status"`
+	ErrorClass        string             `json:"error_class,omitempty"`
+	Error             string             `json:"error,omitempty"`
+	Language          string             `json:"language,omitempty"` // set when a channel rule changed it
+	Template          string             `json:"template,omitempty"` // set when a channel rule or the video's format changed it
+	OutputPath        string             `json:"output_path,omitempty"`
+	Cached            bool               `json:"cached"`
+	PromptTokens      int                `json:"prompt_tokens"`
+	CompletionTokens  int                `json:"completion_tokens"`
+	CostUSD           float64            `json:"cost_usd,omitempty"` // estimated from the model's price
+	FetchMS           int64              `json:"fetch_ms"`
+	SummarizeMS       int64              `json:"summarize_ms"`
+	TranscriptQuality *TranscriptQuality `json:"transcript_quality,omitempty"` // auto-generated captions only
+	ContentNotes      *ContentNotes      `json:"content_notes,omitempty"`      // with --content-filter
+}
+
+// totalCost is the estimated LLM spend across all videos
+func (m *BatchManifest) totalCost() float64 {
+	var total float64
+	for _, entry := range m.Videos {
+		total += entry.CostUSD
+	}
+	return total
+}
+
+// writeManifest saves the manifest atomically so readers never see a partial file
+func writeManifest(path string, m *BatchManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// readManifest loads a manifest written by a previous batch run
+func readManifest(path string) (*BatchManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var m BatchManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// shouldRetry reports whether a resumed run should process entry again.
+// Pending entries always run; successful ones only if their output is gone;
+// failures only if their class is in retryClasses (all classes when empty).
+func shouldRetry(entry *ManifestEntry, retryClasses []string) bool {
+	switch entry.Status {
+	case batchStatusOK:
+		_, err := os.Stat(entry.OutputPath)
+		return err != nil
+	case batchStatusFailed:
+		return len(retryClasses) == 0 || slices.Contains(retryClasses, entry.ErrorClass)
+	default:
+		return true
+	}
+}
+
+// runBatch summarizes every URL in a list, writing one Markdown file per video
+// plus a manifest describing each result
+func runBatch(cmd *cobra.Command, args []string) error {
+	if batchFile == "" && batchResume == "" {
+		return fmt.Errorf("either --file or --resume is required")
+	}
+	if err := validMusicVideos(musicVideosMode()); err != nil {
+		return err
+	}
+	// Interactive users of a shared cache server go first
+	cacheServerPriority = priorityBatch
+
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	var urls []string
+	if batchFile != "" {
+		if urls, err = readVideoList(batchFile); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(batchOutDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	manifestPath := batchManifest
+	if manifestPath == "" {
+		manifestPath = filepath.Join(batchOutDir, "manifest.json")
+	}
+
+	client, err := newLLMClient()
+	if err != nil {
+		return err
+	}
+	if _, err := invocationBudget(); err != nil {
+		return err
+	}
+	defaults, err := newChannelDefaults(cmd)
+	if err != nil {
+		return err
+	}
+	notifiers, err := newNotifiersFromConfig()
+	if err != nil {
+		return err
+	}
+
+	manifest := &BatchManifest{
+		StartedAt: time.Now().UTC(),
+		Language:  language,
+		Template:  summaryTemplate,
+		Model:     client.Model(),
+	}
+	if batchResume != "" {
+		// Resumed runs keep every previous result and update the same manifest
+		// unless --manifest says otherwise
+		if manifest, err = readManifest(batchResume); err != nil {
+			return err
+		}
+		manifest.FinishedAt = nil
+		if batchManifest == "" {
+			manifestPath = batchResume
+		}
+	}
+
+	// URLs from --file that the manifest doesn't know yet are added as pending
+	known := make(map[string]bool)
+	for _, entry := range manifest.Videos {
+		known[entry.URL] = true
+	}
+	for _, url := range urls {
+		if !known[url] {
+			manifest.Videos = append(manifest.Videos, &ManifestEntry{URL: url, Status: batchStatusPending})
+			known[url] = true
+		}
+	}
+	if err := writeManifest(manifestPath, manifest); err != nil {
+		return err
+	}
+
+	total := len(manifest.Videos)
+	log("Summarizing %d videos into %s (manifest: %s)...", total, batchOutDir, manifestPath)
+
+	var succeeded, failed, skipped, suppressed, music int
+	var failures []EventFailure
+	needsDelay := false
+	for i, entry := range manifest.Videos {
+		if batchResume != "" && !shouldRetry(entry, batchRetryClasses) {
+			if entry.Status == batchStatusOK {
+				succeeded++
+			} else {
+				failed++
+			}
+			skipped++
+			cliMetrics.recordSkipped()
+			continue
+		}
+
+		log("[%d/%d] %s", i+1, total, entry.URL)
+
+		// Videos that keep failing stay skipped until 'ytsummary jobs retry'
+		if videoID, err := extractVideoID(entry.URL); err == nil {
+			if d := deadLettered(cache, videoID); d != nil {
+				entry.VideoID = videoID
+				entry.Status = batchStatusDeadLetter
+				entry.ErrorClass, entry.Error = d.ErrorClass, deadLetterMessage(d)
+				cliMetrics.recordSkipped()
+				if d.Suppressed {
+					suppressed++
+				} else {
+					failed++
+					failures = append(failures, entry.eventFailure())
+					fmt.Fprintf(os.Stderr, "[%d/%d] %s skipped: %s\n", i+1, total, entry.URL, entry.Error)
+				}
+				if err := writeManifest(manifestPath, manifest); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		// Be polite to YouTube: only pause before actual network fetches
+		if needsDelay {
+			time.Sleep(batchDelay)
+		}
+		processBatchEntry(client, cache, defaults, entry)
+		needsDelay = entry.VideoID != "" && !entry.Cached
+		if entry.VideoID != "" {
+			recordJobResult(cache, entry.VideoID, entry.ErrorClass, entry.Error)
+		}
+
+		switch entry.Status {
+		case batchStatusOK:
+			succeeded++
+			cliMetrics.recordProcessed()
+		case batchStatusSkipped:
+			music++
+			cliMetrics.recordSkipped()
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s skipped: a music video\n", i+1, total, entry.URL)
+		default:
+			failed++
+			failures = append(failures, entry.eventFailure())
+			cliMetrics.recordFailure(entry.ErrorClass)
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s failed (%s): %s\n", i+1, total, entry.URL, entry.ErrorClass, entry.Error)
+		}
+
+		if err := writeManifest(manifestPath, manifest); err != nil {
+			return err
+		}
+	}
+
+	finished := time.Now().UTC()
+	manifest.FinishedAt = &finished
+	if err := writeManifest(manifestPath, manifest); err != nil {
+		return err
+	}
+
+	log("Done! %d succeeded, %d failed (%d skipped from the previous run, %d suppressed)", succeeded, failed, skipped, suppressed)
+	if music > 0 {
+		log("Music videos skipped: %d", music)
+	}
+	if cost := manifest.totalCost(); cost > 0 {
+		log("Estimated LLM cost: $%.4f", cost)
+	}
+	publish(notifiers, newJobEvent("batch", succeeded, failed, failures))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d videos failed", failed, total)
+	}
+	return nil
+}
+
+// eventFailure describes a failed entry for notifications
+func (e *ManifestEntry) eventFailure() EventFailure {
+	return EventFailure{VideoID: e.VideoID, URL: e.URL, ErrorClass: e.ErrorClass, Error: e.Error}
+}
+
+// processBatchEntry fetches, summarizes and writes one video, recording the outcome in entry
+func processBatchEntry(client LLMClient, cache Cache, defaults *channelDefaults, entry *ManifestEntry) {
+	fail := func(class string, err error) {
+		entry.Status = batchStatusFailed
+		entry.ErrorClass = class
+		entry.Error = err.Error()
+	}
+
+	videoID, err := extractVideoID(entry.URL)
+	if err != nil {
+		fail(ErrInvalidRequest, err)
+		return
+	}
+	entry.VideoID = videoID
+
+	fetchStart := time.Now()
+	settings, err := defaults.settingsFor(videoID)
+	if err != nil {
+		entry.FetchMS = time.Since(fetchStart).Milliseconds()
+		fail(fetchErrorClass(err), err)
+		return
+	}
+	entry.Language, entry.Template = "", ""
+	if settings.Language != language {
+		entry.Language = settings.Language
+	}
+	if settings.Template != summaryTemplate {
+		entry.Template = settings.Template
+	}
+
+	_, cacheErr := cache.GetTranscript(videoID, settings.Language)
+	entry.Cached = cacheErr == nil
+	transcript, err := loadTranscript(cache, videoID, settings.Language, timingNone)
+	entry.FetchMS = time.Since(fetchStart).Milliseconds()
+	if err != nil {
+		fail(fetchErrorClass(err), err)
+		return
+	}
+	entry.Title = transcript.Title
+	entry.TranscriptQuality = warnTranscriptQuality(transcript)
+	if settings.Template, err = pickTemplate(settings.Template, transcript, musicVideosMode()); err != nil {
+		entry.Status, entry.ErrorClass, entry.Error = batchStatusSkipped, ErrMusicVideo, err.Error()
+		return
+	}
+	if settings.Template != summaryTemplate {
+		entry.Template = settings.Template
+	}
+	entry.ContentNotes = applyContentFilter(transcript, contentFilterMode())
+	if !keepNonSpeech {
+		stripCaptionArtifacts(transcript)
+	}
+
+	promptBefore, completionBefore := cliMetrics.tokens()
+	summarizeStart := time.Now()
+	budget, _ := invocationBudget()
+	meta := &SummaryMeta{}
+	summary, err := summarizeWith(client, transcript.Transcript, SummaryOptions{
+		Template:    settings.Template,
+		Vars:        promptVarsFromEntry(transcript, summaryLanguage(settings.Language)),
+		Checkpoints: cache,
+		MaxWords:    maxWords,
+		Lint:        lintEnabled(),
+		Budget:      budget,
+		Meta:        meta,
+	})
+	entry.SummarizeMS = time.Since(summarizeStart).Milliseconds()
+	promptAfter, completionAfter := cliMetrics.tokens()
+	entry.PromptTokens, entry.CompletionTokens = promptAfter-promptBefore, completionAfter-completionBefore
+	entry.CostUSD, _ = estimateCost(client.Model(), entry.PromptTokens, entry.CompletionTokens)
+	if err != nil {
+		fail(llmErrorClass(err), err)
+		return
+	}
+	// Caches supplied by embedders may not keep summaries
+	store, _ := cache.(SummaryStore)
+	keepSummary(store, newStoredSummary(videoID, summaryLanguage(settings.Language), settings.Template, maxWords, summary, meta))
+
+	path := filepath.Join(batchOutDir, videoID+".md")
+	if err := os.WriteFile(path, []byte(formatSummaryMarkdown(transcript, withContentNote(summary, entry.ContentNotes))), 0644); err != nil {
+		fail(ErrInternal, err)
+		return
+	}
+
+	entry.OutputPath = path
+	entry.Status = batchStatusOK
+	entry.ErrorClass, entry.Error = "", ""
+}
+
+// formatSummaryMarkdown renders a summary file with the video title and link
+func formatSummaryMarkdown(entry *CacheEntry, summary string) string {
+	title := entry.Title
+	if title == "" {
+		title = entry.VideoID
+	}
+	return fmt.Sprintf("# %s\n\n%s\n\n%s\n", title, canonicalVideoURL(entry.VideoID), summary)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newBatchTest(t *testing.T, urls string) (manifestPath string, youtube *fakeYouTube) {
+	t.Helper()
+
+	youtube = newFakeYouTube(t)
+	cacheDir = t.TempDir()
+	llmProvider = "fake"
+	batchOutDir = t.TempDir()
+	batchManifest = ""
+	batchDelay = 0
+	language = defaultLanguage
+	t.Cleanup(func() {
+		llmProvider = ""
+	})
+
+	batchFile = filepath.Join(t.TempDir(), "urls.txt")
+	if err := os.WriteFile(batchFile, []byte(urls), 0644); err != nil {
+		t.Fatalf("failed to write URL list: %v", err)
+	}
+	return filepath.Join(batchOutDir, "manifest.json"), youtube
+}
+
+func TestRunBatch(t *testing.T) {
+	manifestPath, _ := newBatchTest(t, "https://youtu.be/dQw4w9WgXcQ\nhttps://youtu.be/noCaptions1\nnot a url\n")
+
+	err := runBatch(nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "2 of 3 videos failed") {
+		t.Fatalf("runBatch() error = %v, want 2 of 3 failed", err)
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	var manifest BatchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+	if manifest.FinishedAt == nil || manifest.Model != "fake" || len(manifest.Videos) != 3 {
+		t.Fatalf("manifest = %+v, want 3 finished videos from the fake model", manifest)
+	}
+
+	ok := manifest.Videos[0]
+	if ok.Status != batchStatusOK || ok.VideoID != "dQw4w9WgXcQ" || ok.PromptTokens == 0 {
+		t.Errorf("first entry = %+v, want ok with token usage", ok)
+	}
+	summary, err := os.ReadFile(ok.OutputPath)
+	if err != nil {
+		t.Fatalf("failed to read summary file: %v", err)
+	}
+	if !strings.HasPrefix(string(summary), "# Rick Astley") {
+		t.Errorf("summary file = %q, want title heading", summary)
+	}
+
+	if got := manifest.Videos[1]; got.Status != batchStatusFailed || got.ErrorClass != ErrNoCaptions {
+		t.Errorf("second entry = %+v, want failed no_captions", got)
+	}
+	if got := manifest.Videos[2]; got.Status != batchStatusFailed || got.ErrorClass != ErrInvalidRequest {
+		t.Errorf("third entry = %+v, want failed invalid_request", got)
+	}
+}
+
+func TestRunBatchResume(t *testing.T) {
+	_, youtube := newBatchTest(t, "")
+	batchFile = ""
+
+	done := filepath.Join(batchOutDir, "jNQXAC9IVRw.md")
+	if err := os.WriteFile(done, []byte("# Done\n"), 0644); err != nil {
+		t.Fatalf("failed to write summary: %v", err)
+	}
+
+	batchResume = filepath.Join(t.TempDir(), "manifest.json")
+	batchRetryClasses = []string{ErrRateLimited}
+	t.Cleanup(func() {
+		batchResume = ""
+		batchRetryClasses = nil
+	})
+	previous := &BatchManifest{
+		Model: "fake",
+		Videos: []*ManifestEntry{
+			{URL: "https://youtu.be/jNQXAC9IVRw", VideoID: "jNQXAC9IVRw", Status: batchStatusOK, OutputPath: done},
+			{URL: "https://youtu.be/dQw4w9WgXcQ", VideoID: "dQw4w9WgXcQ", Status: batchStatusFailed, ErrorClass: ErrRateLimited},
+			{URL: "https://youtu.be/noCaptions1", VideoID: "noCaptions1", Status: batchStatusFailed, ErrorClass: ErrNoCaptions},
+		},
+	}
+	if err := writeManifest(batchResume, previous); err != nil {
+		t.Fatalf("writeManifest() error = %v", err)
+	}
+
+	err := runBatch(nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 videos failed") {
+		t.Fatalf("runBatch() error = %v, want only the no_captions failure left", err)
+	}
+
+	manifest, err := readManifest(batchResume)
+	if err != nil {
+		t.Fatalf("readManifest() error = %v", err)
+	}
+	statuses := []string{manifest.Videos[0].Status, manifest.Videos[1].Status, manifest.Videos[2].Status}
+	want := []string{batchStatusOK, batchStatusOK, batchStatusFailed}
+	if strings.Join(statuses, ",") != strings.Join(want, ",") {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+
+	// Only the rate-limited video should have been fetched again
+	if got := youtube.playerRequests.Load(); got != 1 {
+		t.Errorf("YouTube player requests = %d, want 1", got)
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	existing := filepath.Join(t.TempDir(), "done.md")
+	os.WriteFile(existing, []byte("x"), 0644)
+
+	tests := []struct {
+		name    string
+		entry   ManifestEntry
+		classes []string
+		want    bool
+	}{
+		{"pending", ManifestEntry{Status: batchStatusPending}, nil, true},
+		{"ok with output", ManifestEntry{Status: batchStatusOK, OutputPath: existing}, nil, false},
+		{"ok with missing output", ManifestEntry{Status: batchStatusOK, OutputPath: existing + ".gone"}, nil, true},
+		{"failed, all classes", ManifestEntry{Status: batchStatusFailed, ErrorClass: ErrNoCaptions}, nil, true},
+		{"failed, class listed", ManifestEntry{Status: batchStatusFailed, ErrorClass: ErrRateLimited}, []string{ErrRateLimited, ErrScrapeFailed}, true},
+		{"failed, class not listed", ManifestEntry{Status: batchStatusFailed, ErrorClass: ErrNoCaptions}, []string{ErrRateLimited}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRetry(&tt.entry, tt.classes); got != tt.want {
+				t.Errorf("shouldRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunBatchSkipsMusicVideos(t *testing.T) {
+	manifestPath, _ := newBatchTest(t, "https://youtu.be/dQw4w9WgXcQ\nhttps://youtu.be/noCaptions1\n")
+	musicVideos = musicVideosSkip
+	t.Cleanup(func() { musicVideos = "" })
+
+	// A skip isn't a failure
+	if err := runBatch(nil, nil); err == nil || !strings.Contains(err.Error(), "1 of 2 videos failed") {
+		t.Fatalf("runBatch() error = %v, want 1 of 2 failed", err)
+	}
+	data, _ := os.ReadFile(manifestPath)
+	var manifest BatchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if got := manifest.Videos[0]; got.Status != batchStatusSkipped || got.ErrorClass != ErrMusicVideo || got.OutputPath != "" {
+		t.Errorf("music video entry = %+v, want skipped music_video", got)
+	}
+	if got := manifest.Videos[1]; got.Status != batchStatusFailed {
+		t.Errorf("second entry = %+v, want failed", got)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Transcripts larger than this are stored in the blob store (when configured)
+// and only referenced from SQLite
+const blobThreshold = 64 * 1024
+
+// errBlobNotFound is returned when an object does not exist in the blob store
+var errBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores large transcript bodies outside the SQLite database
+type BlobStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+// newBlobStoreFromConfig returns the blob store configured by --blob-store /
+// YTSUMMARY_BLOB_STORE, or nil when none is. Supported locations:
+//   - s3://bucket/prefix (AWS S3 or any S3-compatible endpoint)
+//   - gs://bucket/prefix (Google Cloud Storage via its S3-compatible XML API, using HMAC keys)
+//
+// Credentials come from AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY and the region from
+// AWS_REGION. YTSUMMARY_BLOB_ENDPOINT overrides the endpoint (e.g. for MinIO).
+func newBlobStoreFromConfig() (BlobStore, error) {
+	location := getConfig(blobStoreURL, "YTSUMMARY_BLOB_STORE")
+	if location == "" {
+		return nil, nil
+	}
+
+	store, err := newS3BlobStore(location, os.Getenv("YTSUMMARY_BLOB_ENDPOINT"),
+		os.Getenv("AWS_REGION"), os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// blobKey returns the object key for a transcript body
+func blobKey(videoID, language string) string {
+	return fmt.Sprintf("transcripts/%s/%s.txt", videoID, language)
+}
+
+// segmentsBlobKey is where the caption timings for an offloaded transcript live
+func segmentsBlobKey(transcriptKey string) string {
+	return strings.TrimSuffix(transcriptKey, ".txt") + ".segments.json"
+}
+
+// s3BlobStore talks to S3-compatible object storage using path-style
+// requests signed with AWS Signature Version 4
+type s3BlobStore struct {
+	endpoint  string
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// newS3BlobStore parses an s3:// or gs:// location into a blob store
+func newS3BlobStore(location, endpoint, region, accessKey, secretKey string) (*s3BlobStore, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob store location: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid blob store location %q: missing bucket", location)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		if region == "" {
+			region = "us-east-1"
+		}
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+	case "gs":
+		if region == "" {
+			region = "auto"
+		}
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported blob store scheme %q (use s3:// or gs://)", u.Scheme)
+	}
+
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("blob store credentials missing. Set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	return &s3BlobStore{
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		bucket:    u.Host,
+		prefix:    strings.Trim(u.Path, "/"),
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 60 * time.Second},
+		now:       time.Now,
+	}, nil
+}
+
+func (s *s3BlobStore) objectPath(key string) string {
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+	return "/" + s.bucket + "/" + key
+}
+
+func (s *s3BlobStore) Put(key string, data []byte) error {
+	resp, err := s.do("PUT", key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("blob store PUT failed: status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func (s *s3BlobStore) Get(key string) ([]byte, error) {
+	resp, err := s.do("GET", key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errBlobNotFound
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("blob store GET failed: status %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+func (s *s3BlobStore) Delete(key string) error {
+	resp, err := s.do("DELETE", key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("blob store DELETE failed: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// do builds, signs, and sends a request for the given object
+func (s *s3BlobStore) do(method, key string, body []byte) (*http.Response, error) {
+	path := uriEncodePath(s.objectPath(key))
+	req, err := http.NewRequest(method, s.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob request: %w", err)
+	}
+	req.URL.RawPath = path
+
+	s.sign(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("blob store request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (s *s3BlobStore) sign(req *http.Request, body []byte) {
+	signAWSRequest(req, body, awsCredentials{AccessKey: s.accessKey, SecretKey: s.secretKey}, s.region, "s3", s.now())
+}
+
+// awsCredentials are the keys AWS requests are signed with; SessionToken is
+// set for temporary credentials
+type awsCredentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// signAWSRequest adds AWS Signature Version 4 headers to req for service in
+// region, signing the host and every x-amz-* header
+func signAWSRequest(req *http.Request, body []byte, creds awsCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if name = strings.ToLower(name); strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := dateStamp + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+creds.SecretKey), dateStamp)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncodePath percent-encodes everything except RFC 3986 unreserved characters and '/'
+func uriEncodePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a minimal in-memory S3-compatible server
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case "PUT":
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+	case "GET":
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case "DELETE":
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3BlobStore(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	store, err := newS3BlobStore("s3://my-bucket/archive", srv.URL, "eu-west-1", "AKID", "SECRET")
+	if err != nil {
+		t.Fatalf("newS3BlobStore() error = %v", err)
+	}
+
+	if err := store.Put("transcripts/abc/en.txt", []byte("hello")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, ok := fake.objects["/my-bucket/archive/transcripts/abc/en.txt"]; !ok {
+		t.Errorf("object not stored at expected path, have %v", fake.objects)
+	}
+
+	got, err := store.Get("transcripts/abc/en.txt")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("Get() = %q, want hello", got)
+	}
+
+	if err := store.Delete("transcripts/abc/en.txt"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("transcripts/abc/en.txt"); err != errBlobNotFound {
+		t.Errorf("Get() after delete error = %v, want %v", err, errBlobNotFound)
+	}
+}
+
+func TestNewS3BlobStore(t *testing.T) {
+	tests := []struct {
+		name         string
+		location     string
+		wantEndpoint string
+		wantErr      bool
+	}{
+		{"s3 default endpoint", "s3://bucket", "https://s3.us-east-1.amazonaws.com", false},
+		{"gcs", "gs://bucket/prefix", "https://storage.googleapis.com", false},
+		{"missing bucket", "s3:///prefix", "", true},
+		{"unsupported scheme", "ftp://bucket", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := newS3BlobStore(tt.location, "", "", "AKID", "SECRET")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newS3BlobStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && store.endpoint != tt.wantEndpoint {
+				t.Errorf("endpoint = %q, want %q", store.endpoint, tt.wantEndpoint)
+			}
+		})
+	}
+
+	if _, err := newS3BlobStore("s3://bucket", "", "", "", ""); err == nil {
+		t.Error("expected error for missing credentials")
+	}
+}
+
+func TestCacheWithBlobStore(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	blobStoreURL = "s3://bucket"
+	t.Setenv("YTSUMMARY_BLOB_ENDPOINT", srv.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	defer func() { blobStoreURL = "" }()
+
+	blobs, err := newBlobStoreFromConfig()
+	if err != nil {
+		t.Fatalf("newBlobStoreFromConfig() error = %v", err)
+	}
+	cache := newSQLiteCache(SQLiteCacheConfig{Dir: t.TempDir(), Blobs: blobs})
+	defer cache.Close()
+
+	large := strings.Repeat("word ", blobThreshold/4)
+	if err := cacheTranscript(cache, "dQw4w9WgXcQ", "en", "Title", large); err != nil {
+		t.Fatalf("cacheTranscript() error = %v", err)
+	}
+	if err := cacheTranscript(cache, "abc123xyz99", "en", "Small", "small transcript"); err != nil {
+		t.Fatalf("cacheTranscript() error = %v", err)
+	}
+
+	if len(fake.objects) != 1 {
+		t.Errorf("blob store has %d objects, want 1 (only the large transcript)", len(fake.objects))
+	}
+
+	entry, err := cache.GetTranscript("dQw4w9WgXcQ", "en")
+	if err != nil {
+		t.Fatalf("cache.GetTranscript() error = %v", err)
+	}
+	if entry.Transcript != large {
+		t.Errorf("large transcript not restored from blob store (got %d chars)", len(entry.Transcript))
+	}
+
+	entry, err = cache.GetTranscript("abc123xyz99", "en")
+	if err != nil {
+		t.Fatalf("cache.GetTranscript() error = %v", err)
+	}
+	if entry.Transcript != "small transcript" {
+		t.Errorf("Transcript = %q, want small transcript", entry.Transcript)
+	}
+
+	// Re-storing without timings drops the old segments blob
+	segments := []TranscriptSegment{{Start: 0, Duration: 1, Text: "word"}}
+	if err := cache.StoreTranscript(&CacheEntry{VideoID: "jNQXAC9IVRw", Language: "en", Transcript: large, Segments: segments}); err != nil {
+		t.Fatalf("StoreTranscript() error = %v", err)
+	}
+	if err := cache.StoreTranscript(&CacheEntry{VideoID: "jNQXAC9IVRw", Language: "en", Transcript: large}); err != nil {
+		t.Fatalf("StoreTranscript() error = %v", err)
+	}
+	if entry, err := cache.GetTranscript("jNQXAC9IVRw", "en"); err != nil || len(entry.Segments) != 0 {
+		t.Errorf("GetTranscript() segments = %v, %v; want none", entry, err)
+	}
+
+	// Re-storing a small body inline deletes the offloaded ones
+	if err := cacheTranscript(cache, "dQw4w9WgXcQ", "en", "Title", "now small"); err != nil {
+		t.Fatalf("cacheTranscript() error = %v", err)
+	}
+	if _, ok := fake.objects["/bucket/"+blobKey("dQw4w9WgXcQ", "en")]; ok || len(fake.objects) != 1 {
+		t.Errorf("blob store objects after re-storing inline = %d, want only jNQXAC9IVRw's", len(fake.objects))
+	}
+}
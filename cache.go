@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -12,35 +19,135 @@ import (
 
 // CacheEntry represents a cached transcript
 type CacheEntry struct {
-	VideoID    string
-	Language   string
-	Title      string
-	Transcript string
-	FetchedAt  time.Time
+	VideoID         string
+	Language        string
+	Title           string
+	Channel         string
+	DurationSeconds int
+	Category        string // YouTube's category, e.g. "Music"; "" when unknown
+	Transcript      string
+	Segments        []TranscriptSegment // caption timings, nil for entries cached before they were kept
+	TranslatedFrom  string              // source language when YouTube machine-translated the captions
+	AutoGenerated   bool                // captions come from YouTube's speech recognition
+	Format          string              // classified format, e.g. "interview" (see classifyFormat); "" for entries cached before
+	FetchedAt       time.Time
 }
 
-var db *sql.DB
+// Cache stores fetched transcripts and chunk-summary checkpoints. SQLiteCache
+// backs the CLI and server; tests and embedders can supply their own.
+type Cache interface {
+	// GetTranscript returns the cached transcript, or an error if there is none
+	GetTranscript(videoID, language string) (*CacheEntry, error)
+	StoreTranscript(entry *CacheEntry) error
 
-// initCache initializes the SQLite database connection
-func initCache() error {
-	dbPath := cacheDir
-	if dbPath == "" {
-		dbPath = "./cache"
+	// ListTranscripts returns up to limit transcripts fetched at or after since,
+	// ordered by (fetched_at, video_id, language) and starting after the given
+	// cursor position for keyset pagination
+	ListTranscripts(since time.Time, after *exportCursor, limit int) ([]*CacheEntry, error)
+	CountTranscripts() (int, error)
+
+	// GetCaptionTracks returns the caption tracks last seen for a video, or
+	// errCacheMiss if its player response was never fetched
+	GetCaptionTracks(videoID string) ([]CaptionInfo, error)
+	StoreCaptionTracks(videoID string, tracks []CaptionInfo) error
+
+	GetCheckpoint(chunkHash string) (string, error)
+	SaveCheckpoint(chunkHash, model, summary string) error
+	DeleteCheckpoints(chunkHashes []string) error
+
+	Close() error
+}
+
+// SQLiteCacheConfig configures a SQLiteCache
+type SQLiteCacheConfig struct {
+	Dir      string    // directory holding transcripts.db (default ./cache)
+	ReadOnly bool      // open an existing database without taking write locks
+	Blobs    BlobStore // offloads large transcripts; nil keeps everything in SQLite
+}
+
+// SQLiteCache is the SQLite-backed Cache. The database is opened on first use.
+type SQLiteCache struct {
+	cfg SQLiteCacheConfig
+
+	mu sync.Mutex
+	db *sql.DB
+}
+
+// sqliteTimeFormat matches how CURRENT_TIMESTAMP stores fetched_at (UTC)
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// errCacheReadOnly is returned by writes when the cache was opened with --cache-readonly
+var errCacheReadOnly = errors.New("cache is read-only")
+
+// errCacheMiss is returned when a transcript or checkpoint isn't cached
+var errCacheMiss = errors.New("not found")
+
+func newSQLiteCache(cfg SQLiteCacheConfig) *SQLiteCache {
+	if cfg.Dir == "" {
+		cfg.Dir = "./cache"
+	}
+	return &SQLiteCache{cfg: cfg}
+}
+
+// openCache returns the cache configured by --cache-dir, --cache-readonly and
+// --blob-store
+func openCache() (*SQLiteCache, error) {
+	blobs, err := newBlobStoreFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newSQLiteCache(SQLiteCacheConfig{
+		Dir:      cacheDir,
+		ReadOnly: cacheReadOnly,
+		Blobs:    blobs,
+	}), nil
+}
+
+// conn returns the database connection, opening it on first use
+func (c *SQLiteCache) conn() (*sql.DB, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.db != nil {
+		return c.db, nil
+	}
+
+	var err error
+	if c.cfg.ReadOnly {
+		c.db, err = openSQLiteReadOnly(c.cfg.Dir)
+	} else {
+		c.db, err = openSQLite(c.cfg.Dir)
+	}
+	return c.db, err
+}
+
+// Close closes the database connection
+func (c *SQLiteCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.db == nil {
+		return nil
 	}
+	err := c.db.Close()
+	c.db = nil
+	return err
+}
 
+// openSQLite opens (creating if needed) the cache database in dir
+func openSQLite(dir string) (*sql.DB, error) {
 	// Ensure cache directory exists
-	if err := os.MkdirAll(dbPath, 0755); err != nil {
-		return fmt.Errorf("failed to create cache directory: %w", err)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
-	dbFile := filepath.Join(dbPath, "transcripts.db")
+	dbFile := filepath.Join(dir, "transcripts.db")
 
 	// Open with WAL mode and busy timeout for concurrent access
 	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_synchronous=NORMAL", dbFile)
-	var err error
-	db, err = sql.Open("sqlite3", dsn)
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// SQLite handles one writer at a time, limit connections
@@ -57,33 +164,170 @@ func initCache() error {
 			PRIMARY KEY (video_id, language)
 		);
 		CREATE INDEX IF NOT EXISTS idx_fetched_at ON transcripts(fetched_at);
+		CREATE TABLE IF NOT EXISTS chunk_checkpoints (
+			chunk_hash TEXT PRIMARY KEY,
+			model TEXT NOT NULL,
+			summary TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS caption_tracks (
+			video_id TEXT PRIMARY KEY,
+			tracks TEXT NOT NULL,
+			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS job_failures (
+			video_id TEXT PRIMARY KEY,
+			error_class TEXT NOT NULL,
+			error TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			first_failed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_failed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			suppressed INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE TABLE IF NOT EXISTS summaries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			video_id TEXT NOT NULL,
+			language TEXT NOT NULL,
+			template TEXT NOT NULL,
+			model TEXT NOT NULL,
+			prompt_hash TEXT NOT NULL,
+			max_words INTEGER NOT NULL DEFAULT 0,
+			summary TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_summaries_video ON summaries(video_id, created_at);
+		CREATE TABLE IF NOT EXISTS video_notes (
+			video_id TEXT PRIMARY KEY,
+			notes TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS listings (
+			kind TEXT NOT NULL,
+			key TEXT NOT NULL,
+			data TEXT NOT NULL,
+			complete INTEGER NOT NULL DEFAULT 1,
+			fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (kind, key)
+		);
+		CREATE TABLE IF NOT EXISTS embeddings (
+			video_id TEXT NOT NULL,
+			language TEXT NOT NULL,
+			model TEXT NOT NULL,
+			text_hash TEXT NOT NULL,
+			vector BLOB NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (video_id, language, model)
+		);
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
+		db.Close()
+		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
 
-	return nil
+	if err := migrateCache(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
 }
 
-// closeCache closes the database connection
-func closeCache() error {
-	if db != nil {
-		return db.Close()
+// migrateCache adds columns introduced after the initial schema
+func migrateCache(db *sql.DB) error {
+	if err := addMissingColumns(db, "transcripts", []struct{ name, def string }{
+		{"blob_key", "TEXT"},
+		{"channel", "TEXT"},
+		{"duration_seconds", "INTEGER"},
+		{"segments", "TEXT"},
+		{"translated_from", "TEXT"},
+		{"auto_generated", "INTEGER"},
+		{"format", "TEXT"},
+		{"category", "TEXT"},
+	}); err != nil {
+		return err
 	}
-	return nil
+	return addMissingColumns(db, "summaries", []struct{ name, def string }{
+		{"prompt_tokens", "INTEGER NOT NULL DEFAULT 0"},
+		{"completion_tokens", "INTEGER NOT NULL DEFAULT 0"},
+		{"experiment", "TEXT NOT NULL DEFAULT ''"},
+		{"variant", "TEXT NOT NULL DEFAULT ''"},
+		{"rating", "INTEGER NOT NULL DEFAULT 0"},
+		{"feedback", "TEXT NOT NULL DEFAULT ''"},
+		{"request_hash", "TEXT NOT NULL DEFAULT ''"},
+	})
 }
 
-// getCachedTranscript retrieves a transcript from the cache if it exists
-func getCachedTranscript(videoID, language string) (*CacheEntry, error) {
-	if db == nil {
-		if err := initCache(); err != nil {
-			return nil, err
+// addMissingColumns adds the columns a table doesn't have yet
+func addMissingColumns(db *sql.DB, table string, added []struct{ name, def string }) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			ctype     string
+			notnull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dfltValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to inspect schema: %w", err)
+		}
+		columns[name] = true
+	}
+	rows.Close()
+
+	for _, col := range added {
+		if columns[col.name] {
+			continue
 		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.name, col.def)); err != nil {
+			return fmt.Errorf("failed to migrate schema: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// openSQLiteReadOnly opens an existing cache database without taking write locks,
+// so CLI runs can share a cache that a serve instance owns
+func openSQLiteReadOnly(dir string) (*sql.DB, error) {
+	dbFile := filepath.Join(dir, "transcripts.db")
+	if _, err := os.Stat(dbFile); err != nil {
+		return nil, fmt.Errorf("read-only cache not found: %w", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=ro&_busy_timeout=5000", dbFile)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return db, nil
+}
+
+// GetTranscript retrieves a transcript from the cache if it exists
+func (c *SQLiteCache) GetTranscript(videoID, language string) (*CacheEntry, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
 	}
 
 	var entry CacheEntry
-	err := db.QueryRow(`
-		SELECT video_id, language, title, transcript, fetched_at
+	var key, channel, segments, translatedFrom, format, category sql.NullString
+	var duration sql.NullInt64
+	var autoGenerated sql.NullBool
+	err = db.QueryRow(`
+		SELECT video_id, language, title, transcript, fetched_at, blob_key, channel, duration_seconds, segments, translated_from, auto_generated, format, category
 		FROM transcripts
 		WHERE video_id = ? AND language = ?
 	`, videoID, language).Scan(
@@ -92,51 +336,415 @@ func getCachedTranscript(videoID, language string) (*CacheEntry, error) {
 		&entry.Title,
 		&entry.Transcript,
 		&entry.FetchedAt,
+		&key,
+		&channel,
+		&duration,
+		&segments,
+		&translatedFrom,
+		&autoGenerated,
+		&format,
+		&category,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("not found")
+		return nil, errCacheMiss
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query cache: %w", err)
 	}
 
+	entry.Channel = channel.String
+	entry.DurationSeconds = int(duration.Int64)
+	entry.TranslatedFrom = translatedFrom.String
+	entry.AutoGenerated = autoGenerated.Bool
+	entry.Format = format.String
+	entry.Category = category.String
+
+	// Large bodies live in the blob store; the row only holds the key
+	if key.String != "" {
+		body, err := c.getBlob(key.String)
+		if err != nil {
+			return nil, err
+		}
+		entry.Transcript = string(body)
+
+		// Segments of offloaded transcripts are stored next to the body
+		if data, err := c.cfg.Blobs.Get(segmentsBlobKey(key.String)); err == nil {
+			segments = sql.NullString{String: string(data), Valid: true}
+		} else if !errors.Is(err, errBlobNotFound) {
+			return nil, fmt.Errorf("failed to load segments from blob store: %w", err)
+		}
+	}
+
+	if segments.String != "" {
+		if err := json.Unmarshal([]byte(segments.String), &entry.Segments); err != nil {
+			return nil, fmt.Errorf("failed to decode cached segments: %w", err)
+		}
+	}
+
 	return &entry, nil
 }
 
-// cacheTranscript saves a transcript to the cache
-func cacheTranscript(videoID, language, title, transcript string) error {
-	if db == nil {
-		if err := initCache(); err != nil {
-			return err
+// getBlob loads an offloaded transcript body
+func (c *SQLiteCache) getBlob(key string) ([]byte, error) {
+	if c.cfg.Blobs == nil {
+		return nil, fmt.Errorf("transcript stored in blob store but none is configured")
+	}
+	body, err := c.cfg.Blobs.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transcript from blob store: %w", err)
+	}
+	return body, nil
+}
+
+// cacheFetchResult saves a freshly fetched transcript and its metadata under the
+// requested language, along with the video's caption tracks when they're known
+func cacheFetchResult(cache Cache, videoID, language string, result *FetchResult) error {
+	if err := cache.StoreTranscript(result.cacheEntry(videoID, language)); err != nil {
+		return err
+	}
+	if result.Captions != nil {
+		return cache.StoreCaptionTracks(videoID, result.Captions)
+	}
+	return nil
+}
+
+// cacheEntry converts a fetch result into the entry stored under the requested
+// language, classifying the video's format on the way
+func (r *FetchResult) cacheEntry(videoID, language string) *CacheEntry {
+	entry := &CacheEntry{
+		VideoID:         videoID,
+		Language:        language,
+		Title:           r.Title,
+		Channel:         r.Channel,
+		DurationSeconds: r.DurationSeconds,
+		Category:        r.Category,
+		Transcript:      r.Transcript,
+		Segments:        r.Segments,
+		TranslatedFrom:  r.TranslatedFrom,
+		AutoGenerated:   r.AutoGenerated,
+	}
+	entry.Format = classifyFormat(entry).Format
+	return entry
+}
+
+// StoreTranscript saves a transcript with its metadata to the cache
+func (c *SQLiteCache) StoreTranscript(entry *CacheEntry) error {
+	videoID, language, transcript := entry.VideoID, entry.Language, entry.Transcript
+
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	var segments sql.NullString
+	if len(entry.Segments) > 0 {
+		data, err := json.Marshal(entry.Segments)
+		if err != nil {
+			return fmt.Errorf("failed to encode segments: %w", err)
+		}
+		segments = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// A body offloaded before may be stale once this row replaces it
+	var previousKey sql.NullString
+	if c.cfg.Blobs != nil {
+		err := db.QueryRow("SELECT blob_key FROM transcripts WHERE video_id = ? AND language = ?", videoID, language).Scan(&previousKey)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to query cache: %w", err)
+		}
+	}
+
+	// Offload large bodies to the blob store, keeping only metadata in SQLite
+	var key sql.NullString
+	if c.cfg.Blobs != nil && len(transcript) > blobThreshold {
+		key = sql.NullString{String: blobKey(videoID, language), Valid: true}
+		if err := c.cfg.Blobs.Put(key.String, []byte(transcript)); err != nil {
+			return fmt.Errorf("failed to store transcript in blob store: %w", err)
+		}
+		transcript = ""
+
+		if segments.Valid {
+			if err := c.cfg.Blobs.Put(segmentsBlobKey(key.String), []byte(segments.String)); err != nil {
+				return fmt.Errorf("failed to store segments in blob store: %w", err)
+			}
+			segments = sql.NullString{}
 		}
 	}
 
-	_, err := db.Exec(`
-		INSERT OR REPLACE INTO transcripts (video_id, language, title, transcript, fetched_at)
-		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
-	`, videoID, language, title, transcript)
+	_, err = db.Exec(`
+		INSERT OR REPLACE INTO transcripts (video_id, language, title, transcript, fetched_at, blob_key, channel, duration_seconds, segments, translated_from, auto_generated, format, category)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, videoID, language, entry.Title, transcript, key, entry.Channel, entry.DurationSeconds, segments,
+		sql.NullString{String: entry.TranslatedFrom, Valid: entry.TranslatedFrom != ""}, entry.AutoGenerated,
+		sql.NullString{String: entry.Format, Valid: entry.Format != ""},
+		sql.NullString{String: entry.Category, Valid: entry.Category != ""})
 
 	if err != nil {
 		return fmt.Errorf("failed to cache transcript: %w", err)
 	}
 
+	return c.deleteStaleBlobs(previousKey.String, key.String, len(entry.Segments) > 0)
+}
+
+// deleteStaleBlobs removes the blobs of a replaced row that the new row no
+// longer uses: its body and segments when the new row is stored inline, and
+// its segments when the new row has none, so they aren't loaded with it
+func (c *SQLiteCache) deleteStaleBlobs(previousKey, key string, hasSegments bool) error {
+	if previousKey == "" {
+		return nil
+	}
+	var stale []string
+	if previousKey != key {
+		stale = append(stale, previousKey)
+	}
+	if previousKey != key || !hasSegments {
+		stale = append(stale, segmentsBlobKey(previousKey))
+	}
+	for _, k := range stale {
+		if err := c.cfg.Blobs.Delete(k); err != nil {
+			return fmt.Errorf("failed to delete stale blob %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// ListTranscripts returns up to limit cached transcripts fetched at or after since,
+// ordered by (fetched_at, video_id, language) and starting after the given cursor
+// position for keyset pagination
+func (c *SQLiteCache) ListTranscripts(since time.Time, after *exportCursor, limit int) ([]*CacheEntry, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT video_id, language, title, transcript, fetched_at, blob_key, channel, duration_seconds
+		FROM transcripts
+		WHERE fetched_at >= ?`
+	args := []any{since.UTC().Format(sqliteTimeFormat)}
+	if after != nil {
+		query += ` AND (fetched_at, video_id, language) > (?, ?, ?)`
+		args = append(args, after.FetchedAt, after.VideoID, after.Language)
+	}
+	query += ` ORDER BY fetched_at, video_id, language LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcripts: %w", err)
+	}
+
+	type listedRow struct {
+		entry        CacheEntry
+		key, channel sql.NullString
+		duration     sql.NullInt64
+	}
+	var listed []*listedRow
+	for rows.Next() {
+		var row listedRow
+		if err := rows.Scan(&row.entry.VideoID, &row.entry.Language, &row.entry.Title, &row.entry.Transcript,
+			&row.entry.FetchedAt, &row.key, &row.channel, &row.duration); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to list transcripts: %w", err)
+		}
+		listed = append(listed, &row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list transcripts: %w", err)
+	}
+
+	// Load offloaded bodies after the query so the connection isn't held meanwhile
+	entries := make([]*CacheEntry, 0, len(listed))
+	for _, row := range listed {
+		entry := row.entry
+		entry.Channel = row.channel.String
+		entry.DurationSeconds = int(row.duration.Int64)
+		if row.key.String != "" {
+			body, err := c.getBlob(row.key.String)
+			if err != nil {
+				return nil, err
+			}
+			entry.Transcript = string(body)
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+// GetCaptionTracks returns the caption tracks stored for a video
+func (c *SQLiteCache) GetCaptionTracks(videoID string) ([]CaptionInfo, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	var data string
+	err = db.QueryRow("SELECT tracks FROM caption_tracks WHERE video_id = ?", videoID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query caption tracks: %w", err)
+	}
+
+	tracks := []CaptionInfo{}
+	if err := json.Unmarshal([]byte(data), &tracks); err != nil {
+		return nil, fmt.Errorf("failed to decode cached caption tracks: %w", err)
+	}
+	return tracks, nil
+}
+
+// StoreCaptionTracks replaces the caption tracks stored for a video
+func (c *SQLiteCache) StoreCaptionTracks(videoID string, tracks []CaptionInfo) error {
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	if tracks == nil {
+		tracks = []CaptionInfo{}
+	}
+	data, err := json.Marshal(tracks)
+	if err != nil {
+		return fmt.Errorf("failed to encode caption tracks: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT OR REPLACE INTO caption_tracks (video_id, tracks, fetched_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+	`, videoID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to cache caption tracks: %w", err)
+	}
+	return nil
+}
+
+// GetCheckpoint returns a previously saved chunk summary
+func (c *SQLiteCache) GetCheckpoint(chunkHash string) (string, error) {
+	db, err := c.conn()
+	if err != nil {
+		return "", err
+	}
+
+	var summary string
+	err = db.QueryRow("SELECT summary FROM chunk_checkpoints WHERE chunk_hash = ?", chunkHash).Scan(&summary)
+	if err == sql.ErrNoRows {
+		return "", errCacheMiss
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query checkpoint: %w", err)
+	}
+
+	return summary, nil
+}
+
+// SaveCheckpoint persists a chunk summary so an interrupted run can resume
+func (c *SQLiteCache) SaveCheckpoint(chunkHash, model, summary string) error {
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT OR REPLACE INTO chunk_checkpoints (chunk_hash, model, summary, created_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	`, chunkHash, model, summary)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+
 	return nil
 }
 
-// getCacheStats returns statistics about the cache
-func getCacheStats() (int, error) {
-	if db == nil {
-		if err := initCache(); err != nil {
-			return 0, err
+// DeleteCheckpoints removes checkpoints once the full summary has succeeded
+func (c *SQLiteCache) DeleteCheckpoints(chunkHashes []string) error {
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range chunkHashes {
+		if _, err := db.Exec("DELETE FROM chunk_checkpoints WHERE chunk_hash = ?", hash); err != nil {
+			return fmt.Errorf("failed to delete checkpoint: %w", err)
 		}
 	}
 
+	return nil
+}
+
+// CountTranscripts returns the number of cached transcripts
+func (c *SQLiteCache) CountTranscripts() (int, error) {
+	db, err := c.conn()
+	if err != nil {
+		return 0, err
+	}
+
 	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM transcripts").Scan(&count)
+	err = db.QueryRow("SELECT COUNT(*) FROM transcripts").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get cache stats: %w", err)
 	}
 
 	return count, nil
 }
+
+// fetchTranscriptViaServer asks a running serve instance to fetch the transcript,
+// which caches it in the shared database as a side effect. Used by read-only
+// CLI runs so the server remains the only writer.
+func fetchTranscriptViaServer(serverURL, apiKey, videoURL, language string) (*TranscriptResponse, error) {
+	reqBody, err := json.Marshal(TranscriptRequest{URL: videoURL, Language: language, Priority: cacheServerPriority})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(serverURL, "/")+"/transcript", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach cache server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache server response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		if json.Unmarshal(body, &errResp) == nil && errResp.Message != "" {
+			return nil, fmt.Errorf("cache server error (%d): %s", resp.StatusCode, errResp.Message)
+		}
+		return nil, fmt.Errorf("cache server error: status %d", resp.StatusCode)
+	}
+
+	var tr TranscriptResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, fmt.Errorf("failed to parse cache server response: %w", err)
+	}
+
+	return &tr, nil
+}
@@ -1,23 +1,34 @@
 package main
 
 import (
-	"os"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 )
 
-func TestCache(t *testing.T) {
-	// Use temp directory for test cache
-	tmpDir, err := os.MkdirTemp("", "ytsummary-test-*")
-	if err != nil {
-		t.Fatalf("failed to create temp dir: %v", err)
-	}
-	defer os.RemoveAll(tmpDir)
+// newTestCache returns an empty cache in a temporary directory
+func newTestCache(t *testing.T) *SQLiteCache {
+	t.Helper()
+	cache := newSQLiteCache(SQLiteCacheConfig{Dir: t.TempDir()})
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
 
-	// Set cache directory
-	cacheDir = tmpDir
+// cacheTranscript stores a transcript without segments or metadata
+func cacheTranscript(cache Cache, videoID, language, title, transcript string) error {
+	return cache.StoreTranscript(&CacheEntry{
+		VideoID:    videoID,
+		Language:   language,
+		Title:      title,
+		Transcript: transcript,
+	})
+}
 
-	// Reset global db for clean test
-	db = nil
+func TestCache(t *testing.T) {
+	cache := newTestCache(t)
 
 	// Test caching a transcript
 	videoID := "dQw4w9WgXcQ"
@@ -25,15 +36,15 @@ func TestCache(t *testing.T) {
 	title := "Never Gonna Give You Up"
 	transcript := "We're no strangers to love..."
 
-	err = cacheTranscript(videoID, lang, title, transcript)
+	err := cacheTranscript(cache, videoID, lang, title, transcript)
 	if err != nil {
 		t.Fatalf("cacheTranscript() error = %v", err)
 	}
 
 	// Test retrieving it
-	entry, err := getCachedTranscript(videoID, lang)
+	entry, err := cache.GetTranscript(videoID, lang)
 	if err != nil {
-		t.Fatalf("getCachedTranscript() error = %v", err)
+		t.Fatalf("cache.GetTranscript() error = %v", err)
 	}
 
 	if entry.VideoID != videoID {
@@ -56,78 +67,67 @@ func TestCache(t *testing.T) {
 	langES := "es"
 	transcriptES := "Nunca te voy a dejar..."
 
-	err = cacheTranscript(videoID, langES, title, transcriptES)
+	err = cacheTranscript(cache, videoID, langES, title, transcriptES)
 	if err != nil {
 		t.Fatalf("cacheTranscript(es) error = %v", err)
 	}
 
 	// Original English should still be there
-	entryEN, err := getCachedTranscript(videoID, lang)
+	entryEN, err := cache.GetTranscript(videoID, lang)
 	if err != nil {
-		t.Fatalf("getCachedTranscript(en) error = %v", err)
+		t.Fatalf("cache.GetTranscript(en) error = %v", err)
 	}
 	if entryEN.Transcript != transcript {
 		t.Errorf("English transcript changed unexpectedly")
 	}
 
 	// Spanish should be different
-	entryES, err := getCachedTranscript(videoID, langES)
+	entryES, err := cache.GetTranscript(videoID, langES)
 	if err != nil {
-		t.Fatalf("getCachedTranscript(es) error = %v", err)
+		t.Fatalf("cache.GetTranscript(es) error = %v", err)
 	}
 	if entryES.Transcript != transcriptES {
 		t.Errorf("Spanish transcript = %v, want %v", entryES.Transcript, transcriptES)
 	}
 
 	// Test cache stats
-	count, err := getCacheStats()
+	count, err := cache.CountTranscripts()
 	if err != nil {
-		t.Fatalf("getCacheStats() error = %v", err)
+		t.Fatalf("cache.CountTranscripts() error = %v", err)
 	}
 	if count != 2 {
 		t.Errorf("cache count = %v, want 2", count)
 	}
 
 	// Test cache miss
-	_, err = getCachedTranscript("nonexistent", "en")
-	if err == nil {
-		t.Error("expected error for nonexistent video")
+	_, err = cache.GetTranscript("nonexistent", "en")
+	if !errors.Is(err, errCacheMiss) {
+		t.Errorf("error = %v, want %v", err, errCacheMiss)
 	}
-
-	// Clean up
-	closeCache()
 }
 
 func TestCacheUpdate(t *testing.T) {
-	// Use temp directory for test cache
-	tmpDir, err := os.MkdirTemp("", "ytsummary-test-*")
-	if err != nil {
-		t.Fatalf("failed to create temp dir: %v", err)
-	}
-	defer os.RemoveAll(tmpDir)
-
-	cacheDir = tmpDir
-	db = nil
+	cache := newTestCache(t)
 
 	videoID := "abc123xyz99"
 	lang := "en"
 
 	// Cache initial version
-	err = cacheTranscript(videoID, lang, "Title v1", "Transcript v1")
+	err := cacheTranscript(cache, videoID, lang, "Title v1", "Transcript v1")
 	if err != nil {
 		t.Fatalf("cacheTranscript() error = %v", err)
 	}
 
 	// Update with INSERT OR REPLACE
-	err = cacheTranscript(videoID, lang, "Title v2", "Transcript v2")
+	err = cacheTranscript(cache, videoID, lang, "Title v2", "Transcript v2")
 	if err != nil {
 		t.Fatalf("cacheTranscript() update error = %v", err)
 	}
 
 	// Should get updated version
-	entry, err := getCachedTranscript(videoID, lang)
+	entry, err := cache.GetTranscript(videoID, lang)
 	if err != nil {
-		t.Fatalf("getCachedTranscript() error = %v", err)
+		t.Fatalf("cache.GetTranscript() error = %v", err)
 	}
 
 	if entry.Title != "Title v2" {
@@ -138,13 +138,155 @@ func TestCacheUpdate(t *testing.T) {
 	}
 
 	// Should still be only 1 entry
-	count, err := getCacheStats()
+	count, err := cache.CountTranscripts()
 	if err != nil {
-		t.Fatalf("getCacheStats() error = %v", err)
+		t.Fatalf("cache.CountTranscripts() error = %v", err)
 	}
 	if count != 1 {
 		t.Errorf("cache count = %v, want 1", count)
 	}
+}
+
+func TestCacheSegments(t *testing.T) {
+	cache := newTestCache(t)
+
+	segments := []TranscriptSegment{
+		{Start: 1.36, Duration: 1.68, Text: "Hello"},
+		{Start: 3.04, Duration: 2, Text: "world", Words: []TranscriptWord{{Word: "world", Start: 3.04, Duration: 0.5}}},
+	}
+	err := cache.StoreTranscript(&CacheEntry{
+		VideoID:    "abc123xyz99",
+		Language:   "en",
+		Transcript: "Hello world",
+		Segments:   segments,
+	})
+	if err != nil {
+		t.Fatalf("cache.StoreTranscript() error = %v", err)
+	}
 
-	closeCache()
+	entry, err := cache.GetTranscript("abc123xyz99", "en")
+	if err != nil {
+		t.Fatalf("cache.GetTranscript() error = %v", err)
+	}
+	if len(entry.Segments) != len(segments) {
+		t.Fatalf("got %d segments, want %d", len(entry.Segments), len(segments))
+	}
+	for i := range segments {
+		if !reflect.DeepEqual(entry.Segments[i], segments[i]) {
+			t.Errorf("segment %d = %+v, want %+v", i, entry.Segments[i], segments[i])
+		}
+	}
+
+	// Entries cached without timings come back with nil segments
+	if err := cacheTranscript(cache, "untimed0001", "en", "", "plain"); err != nil {
+		t.Fatalf("cacheTranscript() error = %v", err)
+	}
+	entry, err = cache.GetTranscript("untimed0001", "en")
+	if err != nil {
+		t.Fatalf("cache.GetTranscript() error = %v", err)
+	}
+	if entry.Segments != nil {
+		t.Errorf("Segments = %+v, want nil", entry.Segments)
+	}
+}
+
+func TestCacheCaptionTracks(t *testing.T) {
+	cache := newTestCache(t)
+
+	if _, err := cache.GetCaptionTracks("dQw4w9WgXcQ"); !errors.Is(err, errCacheMiss) {
+		t.Fatalf("GetCaptionTracks() error = %v, want %v", err, errCacheMiss)
+	}
+
+	tracks := []CaptionInfo{
+		{Language: "en", Name: "English (auto-generated)", AutoGenerated: true},
+		{Language: "es", Name: "Spanish"},
+	}
+	if err := cache.StoreCaptionTracks("dQw4w9WgXcQ", tracks); err != nil {
+		t.Fatalf("StoreCaptionTracks() error = %v", err)
+	}
+	got, err := cache.GetCaptionTracks("dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatalf("GetCaptionTracks() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != tracks[0] || got[1] != tracks[1] {
+		t.Errorf("GetCaptionTracks() = %+v, want %+v", got, tracks)
+	}
+
+	// A video without captions is cached as an empty list, not a miss
+	if err := cache.StoreCaptionTracks("nocaptions1", nil); err != nil {
+		t.Fatalf("StoreCaptionTracks() error = %v", err)
+	}
+	if got, err := cache.GetCaptionTracks("nocaptions1"); err != nil || len(got) != 0 {
+		t.Errorf("GetCaptionTracks() = %+v, %v; want empty list", got, err)
+	}
+}
+
+func TestCacheReadOnly(t *testing.T) {
+	dir := t.TempDir()
+
+	// Populate the cache as the writer would
+	writer := newSQLiteCache(SQLiteCacheConfig{Dir: dir})
+	if err := cacheTranscript(writer, "dQw4w9WgXcQ", "en", "Title", "Transcript"); err != nil {
+		t.Fatalf("cacheTranscript() error = %v", err)
+	}
+	writer.Close()
+
+	// Reopen read-only
+	cache := newSQLiteCache(SQLiteCacheConfig{Dir: dir, ReadOnly: true})
+	defer cache.Close()
+
+	entry, err := cache.GetTranscript("dQw4w9WgXcQ", "en")
+	if err != nil {
+		t.Fatalf("cache.GetTranscript() error = %v", err)
+	}
+	if entry.Transcript != "Transcript" {
+		t.Errorf("Transcript = %v, want Transcript", entry.Transcript)
+	}
+
+	err = cacheTranscript(cache, "abc123xyz99", "en", "", "New")
+	if !errors.Is(err, errCacheReadOnly) {
+		t.Errorf("cacheTranscript() error = %v, want %v", err, errCacheReadOnly)
+	}
+}
+
+func TestCacheReadOnlyMissingDatabase(t *testing.T) {
+	cache := newSQLiteCache(SQLiteCacheConfig{Dir: t.TempDir(), ReadOnly: true})
+	defer cache.Close()
+
+	if _, err := cache.GetTranscript("dQw4w9WgXcQ", "en"); err == nil {
+		t.Error("expected error opening missing read-only cache")
+	}
+}
+
+func TestFetchTranscriptViaServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transcript" {
+			t.Errorf("path = %q, want /transcript", r.URL.Path)
+		}
+		if got := r.Header.Get("X-API-Key"); got != "secret" {
+			t.Errorf("X-API-Key = %q, want secret", got)
+		}
+		writeJSON(w, http.StatusOK, TranscriptResponse{VideoID: "dQw4w9WgXcQ", Transcript: "hello", Language: "en"})
+	}))
+	defer srv.Close()
+
+	resp, err := fetchTranscriptViaServer(srv.URL+"/", "secret", "https://youtu.be/dQw4w9WgXcQ", "en")
+	if err != nil {
+		t.Fatalf("fetchTranscriptViaServer() error = %v", err)
+	}
+	if resp.Transcript != "hello" {
+		t.Errorf("Transcript = %q, want hello", resp.Transcript)
+	}
+}
+
+func TestFetchTranscriptViaServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing API key")
+	}))
+	defer srv.Close()
+
+	_, err := fetchTranscriptViaServer(srv.URL, "", "https://youtu.be/dQw4w9WgXcQ", "en")
+	if err == nil || !strings.Contains(err.Error(), "Invalid or missing API key") {
+		t.Errorf("error = %v, want server message", err)
+	}
 }
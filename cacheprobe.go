@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maxCacheStatusVideos caps how many videos one POST /cache/status checks
+const maxCacheStatusVideos = 100
+
+// maxCacheStatusBodySize leaves room for maxCacheStatusVideos full URLs
+const maxCacheStatusBodySize = 32 * 1024
+
+// CacheProbeResponse describes a cached transcript without its text
+type CacheProbeResponse struct {
+	VideoID         string    `json:"video_id"`
+	Language        string    `json:"language"`
+	Title           string    `json:"title,omitempty"`
+	Channel         string    `json:"channel,omitempty"`
+	DurationSeconds int       `json:"duration_seconds,omitempty"`
+	FetchedAt       time.Time `json:"fetched_at"`
+	AgeSeconds      int64     `json:"age_seconds"`
+	TranscriptChars int       `json:"transcript_chars"`
+	AutoGenerated   bool      `json:"auto_generated"`
+	TranslatedFrom  string    `json:"translated_from,omitempty"`
+
+	// HasSegments is false for entries cached before caption timings were
+	// kept; format=segments and from/to refetch those
+	HasSegments bool `json:"has_segments"`
+}
+
+// handleCacheProbe reports whether a video's transcript is cached, so a
+// client can tell whether /summarize will skip the fetch. It never fetches.
+// HEAD gets the same status without a body.
+func (s *Server) handleCacheProbe(w http.ResponseWriter, r *http.Request) {
+	videoID, err := extractVideoID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid video ID")
+		return
+	}
+	getRequestContext(r).setVideo(videoID)
+	lang := requestLanguage(r, r.URL.Query().Get("lang"))
+
+	entry, err := s.cache.GetTranscript(videoID, lang)
+	if errors.Is(err, errCacheMiss) {
+		writeErrorWithVideo(w, http.StatusNotFound, "not_found", "Transcript is not cached in this language", videoID)
+		return
+	}
+	if err != nil {
+		writeErrorWithVideo(w, http.StatusInternalServerError, ErrInternal, "Failed to read cache", videoID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CacheProbeResponse{
+		VideoID:         videoID,
+		Language:        lang,
+		Title:           entry.Title,
+		Channel:         entry.Channel,
+		DurationSeconds: entry.DurationSeconds,
+		FetchedAt:       entry.FetchedAt.UTC(),
+		AgeSeconds:      int64(max(time.Since(entry.FetchedAt), 0).Seconds()),
+		TranscriptChars: len(entry.Transcript),
+		AutoGenerated:   entry.AutoGenerated,
+		TranslatedFrom:  entry.TranslatedFrom,
+		HasSegments:     len(entry.Segments) > 0,
+	})
+}
+
+// CacheStatusRequest lists videos, as IDs or URLs, to look up in the cache
+type CacheStatusRequest struct {
+	Videos   []string `json:"videos"`
+	Language string   `json:"language,omitempty"`
+}
+
+// CacheStatus is whether one requested video is cached, and since when
+type CacheStatus struct {
+	Video       string     `json:"video"` // as given in the request
+	VideoID     string     `json:"video_id,omitempty"`
+	Cached      bool       `json:"cached"`
+	FetchedAt   *time.Time `json:"fetched_at,omitempty"`
+	AgeSeconds  int64      `json:"age_seconds,omitempty"`
+	HasSegments bool       `json:"has_segments,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// CacheStatusResponse answers POST /cache/status in request order
+type CacheStatusResponse struct {
+	Language string        `json:"language"`
+	Cached   int           `json:"cached"`
+	Videos   []CacheStatus `json:"videos"`
+}
+
+// handleCacheStatus reports which of a list of videos are cached, so batch
+// frontends can show what's ready without probing each one. An invalid
+// video or failed lookup is reported on its item rather than failing the rest.
+func (s *Server) handleCacheStatus(w http.ResponseWriter, r *http.Request) {
+	var req CacheStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if len(req.Videos) == 0 {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "videos is required")
+		return
+	}
+	if len(req.Videos) > maxCacheStatusVideos {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, fmt.Sprintf("at most %d videos per request", maxCacheStatusVideos))
+		return
+	}
+
+	resp := CacheStatusResponse{
+		Language: requestLanguage(r, req.Language),
+		Videos:   make([]CacheStatus, len(req.Videos)),
+	}
+	for i, video := range req.Videos {
+		status := CacheStatus{Video: video}
+		videoID, err := extractVideoID(video)
+		if err != nil {
+			status.Error = "invalid video ID"
+			resp.Videos[i] = status
+			continue
+		}
+		status.VideoID = videoID
+
+		entry, err := s.cache.GetTranscript(videoID, resp.Language)
+		switch {
+		case errors.Is(err, errCacheMiss):
+		case err != nil:
+			status.Error = "failed to read cache"
+		default:
+			fetchedAt := entry.FetchedAt.UTC()
+			status.Cached = true
+			status.FetchedAt = &fetchedAt
+			status.AgeSeconds = int64(max(time.Since(entry.FetchedAt), 0).Seconds())
+			status.HasSegments = len(entry.Segments) > 0
+			resp.Cached++
+		}
+		resp.Videos[i] = status
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
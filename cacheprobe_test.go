@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCacheProbe(t *testing.T) {
+	cache := newTestCache(t)
+	cacheTranscript(cache, "dQw4w9WgXcQ", "en", "Kept", "One. Two. Three.")
+	fetches := 0
+	s := newServer(ServerConfig{
+		Cache: cache,
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			fetches++
+			return &FetchResult{Transcript: "Fetched."}, nil
+		},
+	})
+	handler := s.Handler()
+
+	probe := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := probe("GET", "/v1/cache/dQw4w9WgXcQ")
+	if w.Code != http.StatusOK {
+		t.Fatalf("cached: status = %d: %s", w.Code, w.Body)
+	}
+	var resp CacheProbeResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.VideoID != "dQw4w9WgXcQ" || resp.Language != "en" || resp.Title != "Kept" || resp.TranscriptChars != len("One. Two. Three.") {
+		t.Errorf("response = %+v", resp)
+	}
+
+	if w := probe("HEAD", "/v1/cache/dQw4w9WgXcQ?lang=en"); w.Code != http.StatusOK {
+		t.Errorf("HEAD cached: status = %d", w.Code)
+	}
+	if w := probe("GET", "/v1/cache/dQw4w9WgXcQ?lang=de"); w.Code != http.StatusNotFound {
+		t.Errorf("other language: status = %d, want 404", w.Code)
+	}
+	if w := probe("HEAD", "/v1/cache/https%3A%2F%2Fexample.com"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID: status = %d, want 400", w.Code)
+	}
+	if fetches != 0 {
+		t.Errorf("probing fetched %d times, want never", fetches)
+	}
+}
+
+func TestCacheStatus(t *testing.T) {
+	cache := newTestCache(t)
+	cacheTranscript(cache, "dQw4w9WgXcQ", "en", "Kept", "One. Two. Three.")
+	handler := newServer(ServerConfig{Cache: cache}).Handler()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/v1/cache/status", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(`{"videos": ["https://youtu.be/dQw4w9WgXcQ", "jNQXAC9IVRw", "not a video"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp CacheStatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Language != "en" || resp.Cached != 1 || len(resp.Videos) != 3 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if v := resp.Videos[0]; !v.Cached || v.VideoID != "dQw4w9WgXcQ" || v.FetchedAt == nil {
+		t.Errorf("cached video = %+v", v)
+	}
+	if v := resp.Videos[1]; v.Cached || v.VideoID != "jNQXAC9IVRw" || v.Error != "" {
+		t.Errorf("uncached video = %+v", v)
+	}
+	if v := resp.Videos[2]; v.Cached || v.Error == "" {
+		t.Errorf("invalid video = %+v", v)
+	}
+
+	// A full batch of URLs fits the body limit, one more video doesn't
+	videos := make([]string, maxCacheStatusVideos+1)
+	for i := range videos {
+		videos[i] = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	}
+	body, _ := json.Marshal(CacheStatusRequest{Videos: videos[:maxCacheStatusVideos]})
+	if w := post(string(body)); w.Code != http.StatusOK {
+		t.Errorf("%d videos: status = %d: %s", maxCacheStatusVideos, w.Code, w.Body)
+	}
+	body, _ = json.Marshal(CacheStatusRequest{Videos: videos})
+	if w := post(string(body)); w.Code != http.StatusBadRequest {
+		t.Errorf("%d videos: status = %d, want 400", len(videos), w.Code)
+	}
+	if w := post(`{"videos": []}`); w.Code != http.StatusBadRequest {
+		t.Errorf("no videos: status = %d, want 400", w.Code)
+	}
+}
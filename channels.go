@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// channelRulesFile is a JSON file of per-channel defaults (--channel-rules / YTSUMMARY_CHANNEL_RULES)
+var channelRulesFile string
+
+// ChannelRule sets the caption language and prompt template for videos from
+// one channel, matched by channel ID or by channel name (case-insensitive)
+type ChannelRule struct {
+	ChannelID string `json:"channel_id,omitempty"`
+	Channel   string `json:"channel,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Template  string `json:"template,omitempty"`
+}
+
+// loadChannelRules reads the rules file, returning nil when none is configured
+func loadChannelRules() ([]ChannelRule, error) {
+	path := getConfig(channelRulesFile, "YTSUMMARY_CHANNEL_RULES")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read channel rules: %w", err)
+	}
+	var rules []ChannelRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse channel rules %s: %w", path, err)
+	}
+	for i, rule := range rules {
+		if rule.ChannelID == "" && rule.Channel == "" {
+			return nil, fmt.Errorf("channel rule %d: channel_id or channel is required", i+1)
+		}
+	}
+	return rules, nil
+}
+
+// matchChannelRule returns the rule for a channel. ID matches win over name matches.
+func matchChannelRule(rules []ChannelRule, channelID, channel string) *ChannelRule {
+	for i := range rules {
+		if rules[i].ChannelID != "" && rules[i].ChannelID == channelID {
+			return &rules[i]
+		}
+	}
+	for i := range rules {
+		if rules[i].Channel != "" && strings.EqualFold(rules[i].Channel, channel) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// videoSettings are the language and template used for one video
+type videoSettings struct {
+	Language string
+	Template string
+}
+
+// channelDefaults applies per-channel rules on top of the command's settings.
+// Settings given explicitly on the command line always win.
+type channelDefaults struct {
+	rules            []ChannelRule
+	explicitLanguage bool
+	explicitTemplate bool
+}
+
+func newChannelDefaults(cmd *cobra.Command) (*channelDefaults, error) {
+	rules, err := loadChannelRules()
+	if err != nil {
+		return nil, err
+	}
+	return &channelDefaults{
+		rules:            rules,
+		explicitLanguage: flagChanged(cmd, "lang"),
+		explicitTemplate: flagChanged(cmd, "template"),
+	}, nil
+}
+
+// settingsFor returns the settings for a video. Matching a rule needs the
+// video's channel, which costs one player request; it's skipped when no rule
+// could change anything.
+func (d *channelDefaults) settingsFor(videoID string) (videoSettings, error) {
+	settings := videoSettings{Language: language, Template: summaryTemplate}
+	if len(d.rules) == 0 || (d.explicitLanguage && d.explicitTemplate) {
+		return settings, nil
+	}
+
+	info, err := fetchVideoInfo(youtubeClient(), videoID)
+	if err != nil {
+		return settings, err
+	}
+	rule := matchChannelRule(d.rules, info.ChannelID, info.Channel)
+	if rule == nil {
+		return settings, nil
+	}
+
+	if rule.Language != "" && !d.explicitLanguage {
+		settings.Language = rule.Language
+	}
+	if rule.Template != "" && !d.explicitTemplate {
+		settings.Template = rule.Template
+	}
+	log("Using channel defaults for %s (language '%s', template '%s')", info.Channel, settings.Language, settings.Template)
+	return settings, nil
+}
+
+// flagChanged reports whether a flag was set on the command line
+func flagChanged(cmd *cobra.Command, name string) bool {
+	if cmd == nil {
+		return false
+	}
+	f := cmd.Flags().Lookup(name)
+	return f != nil && f.Changed
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchChannelRule(t *testing.T) {
+	rules := []ChannelRule{
+		{Channel: "Rick Astley", Language: "es"},
+		{ChannelID: "UCuAXFkgsw1L7xaCfnd5JJOw", Template: "detailed"},
+		{Channel: "Other", Language: "fr"},
+	}
+
+	tests := []struct {
+		name      string
+		channelID string
+		channel   string
+		want      int // index into rules, -1 for no match
+	}{
+		{"ID wins over name", "UCuAXFkgsw1L7xaCfnd5JJOw", "Rick Astley", 1},
+		{"name is case-insensitive", "UCunknown", "rick astley", 0},
+		{"no match", "UCunknown", "Someone Else", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchChannelRule(rules, tt.channelID, tt.channel)
+			if tt.want < 0 {
+				if got != nil {
+					t.Errorf("matchChannelRule() = %+v, want nil", got)
+				}
+				return
+			}
+			if got != &rules[tt.want] {
+				t.Errorf("matchChannelRule() = %+v, want %+v", got, rules[tt.want])
+			}
+		})
+	}
+}
+
+func TestLoadChannelRulesRequiresChannel(t *testing.T) {
+	channelRulesFile = filepath.Join(t.TempDir(), "rules.json")
+	t.Cleanup(func() { channelRulesFile = "" })
+	os.WriteFile(channelRulesFile, []byte(`[{"language": "es"}]`), 0644)
+
+	if _, err := loadChannelRules(); err == nil || !strings.Contains(err.Error(), "channel_id or channel is required") {
+		t.Errorf("loadChannelRules() error = %v, want missing channel error", err)
+	}
+}
+
+func TestRunBatchChannelRules(t *testing.T) {
+	manifestPath, _ := newBatchTest(t, "https://youtu.be/dQw4w9WgXcQ\n")
+
+	channelRulesFile = filepath.Join(t.TempDir(), "rules.json")
+	t.Cleanup(func() { channelRulesFile = "" })
+	rules := `[{"channel": "Rick Astley", "language": "es", "template": "detailed"}]`
+	if err := os.WriteFile(channelRulesFile, []byte(rules), 0644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+
+	if err := runBatch(nil, nil); err != nil {
+		t.Fatalf("runBatch() error = %v", err)
+	}
+
+	manifest, err := readManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("readManifest() error = %v", err)
+	}
+	if got := manifest.Videos[0]; got.Language != "es" || got.Template != "detailed" {
+		t.Errorf("entry = %+v, want language es and template detailed from the channel rule", got)
+	}
+
+	cache := newSQLiteCache(SQLiteCacheConfig{Dir: cacheDir})
+	defer cache.Close()
+	entry, err := cache.GetTranscript("dQw4w9WgXcQ", "es")
+	if err != nil {
+		t.Fatalf("Spanish transcript not cached: %v", err)
+	}
+	if !strings.Contains(entry.Transcript, "Nunca te voy a abandonar") {
+		t.Errorf("cached transcript = %q, want the Spanish captions", entry.Transcript)
+	}
+}
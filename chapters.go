@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// summaryFormatChapters asks /summarize for highlights as a chapter block
+const summaryFormatChapters = "chapters"
+
+const (
+	minChapterSeconds = 10 // YouTube ignores chapters shorter than this
+	minChapters       = 3  // YouTube only shows chapters when there are this many
+)
+
+// youtubeChapters renders highlights as chapter lines ("00:00 Intro") ready to
+// paste into a YouTube description. YouTube wants the first chapter at 00:00,
+// so one is added for the start of the video when the first highlight is
+// later, and highlights starting within minChapterSeconds of the previous
+// chapter are left out. The second result is the number of chapters.
+func youtubeChapters(highlights []Highlight) (string, int) {
+	var lines []string
+	last := 0.0
+	for _, h := range highlights {
+		start := h.Start
+		switch {
+		case len(lines) == 0 && start < minChapterSeconds:
+			start = 0
+		case len(lines) == 0:
+			lines = append(lines, chapterTimestamp(0)+" Intro")
+		case start-last < minChapterSeconds:
+			continue
+		}
+		lines = append(lines, chapterTimestamp(start)+" "+chapterTitle(h.Description))
+		last = start
+	}
+	return strings.Join(lines, "\n"), len(lines)
+}
+
+// chapterTimestamp renders seconds as MM:SS, or H:MM:SS from an hour on
+func chapterTimestamp(seconds float64) string {
+	total := int(seconds)
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// chapterTitle makes a highlight's description fit on one chapter line
+func chapterTitle(description string) string {
+	return strings.TrimRight(strings.Join(strings.Fields(description), " "), ".")
+}
+
+// chaptersWarning explains why YouTube won't show too few chapters, or is
+// empty when there are enough
+func chaptersWarning(count int) string {
+	if count >= minChapters {
+		return ""
+	}
+	return fmt.Sprintf("only %d chapters; YouTube shows chapters when a description has at least %d", count, minChapters)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestYouTubeChapters(t *testing.T) {
+	highlights := []Highlight{
+		{Start: 74.2, End: 131.9, Description: "Why the starter needs  daily\nfeeding."},
+		{Start: 80, End: 90, Description: "Too close to the last chapter"},
+		{Start: 402.5, End: 455, Description: "Shaping the loaf"},
+		{Start: 3723, End: 3800, Description: "Baking temperature and timing"},
+	}
+	want := "00:00 Intro\n01:14 Why the starter needs daily feeding\n06:42 Shaping the loaf\n1:02:03 Baking temperature and timing"
+	got, n := youtubeChapters(highlights)
+	if got != want || n != 4 {
+		t.Errorf("youtubeChapters = %q (%d), want %q", got, n, want)
+	}
+	if w := chaptersWarning(n); w != "" {
+		t.Errorf("chaptersWarning(%d) = %q", n, w)
+	}
+
+	// A highlight near the start becomes the first chapter
+	got, n = youtubeChapters([]Highlight{{Start: 4, Description: "Welcome"}, {Start: 60, Description: "Recipe"}})
+	if got != "00:00 Welcome\n01:00 Recipe" || n != 2 {
+		t.Errorf("youtubeChapters = %q (%d)", got, n)
+	}
+	if w := chaptersWarning(n); !strings.Contains(w, "at least 3") {
+		t.Errorf("chaptersWarning(%d) = %q", n, w)
+	}
+}
+
+func TestSummarizeChapters(t *testing.T) {
+	cache := newTestCache(t)
+	cache.StoreTranscript(&CacheEntry{VideoID: "dQw4w9WgXcQ", Language: "en", Transcript: segmentsText(talkSegments), Segments: talkSegments})
+	handler := newServer(ServerConfig{
+		Cache: cache,
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			return `[{"from": 4, "to": 4, "score": 8, "description": "Baking the loaf"}]`, nil
+		},
+	}).Handler()
+
+	req := httptest.NewRequest("GET", "/v1/summarize?url=dQw4w9WgXcQ&mode=highlights&format=chapters", nil)
+	req.RemoteAddr = "192.0.2.24:1234"
+	req.Header.Set("Accept", "text/plain")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if want := "00:00 Intro\n00:15 Baking the loaf\n"; w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body, want)
+	}
+
+	req = httptest.NewRequest("GET", "/v1/summarize?url=dQw4w9WgXcQ&format=chapters", nil)
+	req.RemoteAddr = "192.0.2.24:1234"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != 400 {
+		t.Errorf("chapters without highlights: status = %d, want 400", w.Code)
+	}
+}
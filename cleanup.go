@@ -0,0 +1,65 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Caption annotations that describe sounds rather than speech: anything in
+// square brackets ([Music], [Applause], [Laughter], [__]) and parenthesized
+// sound effects such as (laughs) or (door slams)
+var (
+	bracketAnnotationRe = regexp.MustCompile(`\[[^\]]*\]`)
+	soundEffectRe       = regexp.MustCompile(`(?i)\((?:[^()]*\s)?(?:music|applause|laughter|laughs|laughing|chuckles|cheering|cheers|sighs|coughs|clears throat|inaudible|silence|gasps|groans|whistles|sound|noise|slams|beeps)(?:\s[^()]*)?\)`)
+)
+
+// fillerWords are hesitations ASR transcribes that carry no meaning
+var fillerWords = map[string]bool{
+	"um": true, "umm": true, "uh": true, "uhh": true, "er": true, "erm": true,
+	"ah": true, "hmm": true, "mm": true, "mhm": true,
+}
+
+// removeNonSpeech strips sound annotations, music notes and filler words from
+// caption text, returning the remaining speech with whitespace collapsed
+func removeNonSpeech(text string) string {
+	if strings.ContainsAny(text, "[(") {
+		text = bracketAnnotationRe.ReplaceAllString(text, " ")
+		text = soundEffectRe.ReplaceAllString(text, " ")
+	}
+
+	words := strings.Fields(text)
+	out := words[:0]
+	for _, word := range words {
+		word = strings.Trim(word, "♪♫♬")
+		if word == "" {
+			continue
+		}
+		bare := strings.ToLower(strings.TrimRight(word, ",.…"))
+		if fillerWords[bare] {
+			// Keep sentence ends the filler carried ("so um." → "so.")
+			if strings.HasSuffix(word, ".") && len(out) > 0 && !strings.HasSuffix(out[len(out)-1], ".") {
+				out[len(out)-1] = strings.TrimRight(out[len(out)-1], ",") + "."
+			}
+			continue
+		}
+		out = append(out, word)
+	}
+	return strings.Join(out, " ")
+}
+
+// stripCaptionArtifacts removes non-speech annotations from an entry's
+// transcript and caption cues, dropping cues that held nothing else
+func stripCaptionArtifacts(entry *CacheEntry) {
+	entry.Transcript = removeNonSpeech(entry.Transcript)
+	if len(entry.Segments) == 0 {
+		return
+	}
+
+	segments := make([]TranscriptSegment, 0, len(entry.Segments))
+	for _, seg := range entry.Segments {
+		if seg.Text = removeNonSpeech(seg.Text); seg.Text != "" {
+			segments = append(segments, seg)
+		}
+	}
+	entry.Segments = segments
+}
@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestRemoveNonSpeech(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain speech", "hello and welcome back", "hello and welcome back"},
+		{"bracket annotations", "[Music] hello [Applause] everyone [Laughter]", "hello everyone"},
+		{"censored word", "what the [__] was that", "what the was that"},
+		{"sound effects", "(laughs) okay (door slams) so anyway", "okay so anyway"},
+		{"keeps other parentheses", "the function (which we saw earlier) returns", "the function (which we saw earlier) returns"},
+		{"music notes", "♪ never gonna give you up ♪", "never gonna give you up"},
+		{"fillers", "so um I think uh, the answer is um.", "so I think the answer is."},
+		{"repeated fillers", "um um um okay", "okay"},
+		{"only annotations", "[Music] ♪♪♪ [Applause]", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := removeNonSpeech(tt.in); got != tt.want {
+				t.Errorf("removeNonSpeech(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripCaptionArtifacts(t *testing.T) {
+	entry := &CacheEntry{
+		Transcript: "[Music] hello um everyone",
+		Segments: []TranscriptSegment{
+			{Start: 0, Duration: 2, Text: "[Music]"},
+			{Start: 2, Duration: 2, Text: "hello um"},
+			{Start: 4, Duration: 2, Text: "everyone"},
+		},
+	}
+
+	stripCaptionArtifacts(entry)
+
+	if entry.Transcript != "hello everyone" {
+		t.Errorf("Transcript = %q, want %q", entry.Transcript, "hello everyone")
+	}
+	if len(entry.Segments) != 2 || entry.Segments[0].Text != "hello" || entry.Segments[0].Start != 2 {
+		t.Errorf("Segments = %+v, want the annotation-only cue dropped", entry.Segments)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// clientProfile is the client identity the scraper presents to YouTube. The
+// innertube player and timedtext responses vary by client, so when YouTube
+// starts refusing one, operators can switch profiles or override the
+// User-Agent without waiting for a release.
+type clientProfile struct {
+	ClientName    string            // innertube context.client.clientName
+	ClientVersion string            // innertube context.client.clientVersion
+	UserAgent     string            // User-Agent on player and caption requests
+	Headers       map[string]string // extra headers on player and caption requests
+}
+
+const defaultClientProfile = "android"
+
+// clientProfiles are the built-in profiles selectable with --client-profile
+var clientProfiles = map[string]clientProfile{
+	// Android reliably returns caption tracks without a signed-in session
+	"android": {
+		ClientName:    "ANDROID",
+		ClientVersion: "19.09.37",
+		UserAgent:     "com.google.android.youtube/19.09.37 (Linux; U; Android 11) gzip",
+	},
+	"ios": {
+		ClientName:    "IOS",
+		ClientVersion: "19.09.3",
+		UserAgent:     "com.google.ios.youtube/19.09.3 (iPhone14,3; U; CPU iOS 15_6 like Mac OS X)",
+	},
+	"web": {
+		ClientName:    "WEB",
+		ClientVersion: "2.20240304.00.00",
+		UserAgent:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		Headers: map[string]string{
+			"Accept-Language": "en-US,en;q=0.9",
+			"Origin":          "https://www.youtube.com",
+		},
+	},
+}
+
+// clientProfileNames lists the built-in profiles for help and error messages
+func clientProfileNames() []string {
+	names := make([]string, 0, len(clientProfiles))
+	for name := range clientProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scraperProfile returns the configured profile: --client-profile
+// (YTSUMMARY_CLIENT_PROFILE) with --user-agent (YTSUMMARY_USER_AGENT),
+// YTSUMMARY_CLIENT_VERSION and --header (YTSUMMARY_HEADERS) applied on top.
+// Everything is read per request, so /admin/reload picks up .env changes.
+func scraperProfile() (clientProfile, error) {
+	name := getConfig(clientProfileName, "YTSUMMARY_CLIENT_PROFILE")
+	if name == "" {
+		name = defaultClientProfile
+	}
+	base, ok := clientProfiles[strings.ToLower(name)]
+	if !ok {
+		return clientProfile{}, fmt.Errorf("unknown client profile %q (available: %s)", name, strings.Join(clientProfileNames(), ", "))
+	}
+
+	profile := base
+	if ua := getConfig(userAgent, "YTSUMMARY_USER_AGENT"); ua != "" {
+		profile.UserAgent = ua
+	}
+	if version := os.Getenv("YTSUMMARY_CLIENT_VERSION"); version != "" {
+		profile.ClientVersion = version
+	}
+
+	// Copy so overrides never leak into the built-in profile
+	profile.Headers = make(map[string]string, len(base.Headers))
+	for k, v := range base.Headers {
+		profile.Headers[k] = v
+	}
+	extra := extraHeaders
+	if len(extra) == 0 {
+		if env := os.Getenv("YTSUMMARY_HEADERS"); env != "" {
+			extra = strings.Split(env, "|")
+		}
+	}
+	for _, h := range extra {
+		key, value, ok := strings.Cut(h, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return clientProfile{}, fmt.Errorf("invalid header %q (use \"Name: value\")", h)
+		}
+		profile.Headers[http.CanonicalHeaderKey(key)] = strings.TrimSpace(value)
+	}
+
+	return profile, nil
+}
+
+// apply sets the profile's User-Agent and extra headers on a request
+func (p clientProfile) apply(req *http.Request) {
+	req.Header.Set("User-Agent", p.UserAgent)
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScraperProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantClient string
+		wantUA     string
+		wantHeader map[string]string
+		wantErr    string
+	}{
+		{
+			name:       "default",
+			wantClient: "ANDROID",
+			wantUA:     clientProfiles["android"].UserAgent,
+		},
+		{
+			name:       "named profile",
+			env:        map[string]string{"YTSUMMARY_CLIENT_PROFILE": "Web"},
+			wantClient: "WEB",
+			wantUA:     clientProfiles["web"].UserAgent,
+			wantHeader: map[string]string{"Accept-Language": "en-US,en;q=0.9"},
+		},
+		{
+			name: "overrides",
+			env: map[string]string{
+				"YTSUMMARY_CLIENT_PROFILE": "web",
+				"YTSUMMARY_USER_AGENT":     "custom/1.0",
+				"YTSUMMARY_HEADERS":        "accept-language: de-DE | X-Goog-Visitor-Id: abc",
+			},
+			wantClient: "WEB",
+			wantUA:     "custom/1.0",
+			wantHeader: map[string]string{"Accept-Language": "de-DE", "X-Goog-Visitor-Id": "abc"},
+		},
+		{
+			name:    "unknown profile",
+			env:     map[string]string{"YTSUMMARY_CLIENT_PROFILE": "smart-fridge"},
+			wantErr: "unknown client profile",
+		},
+		{
+			name:    "malformed header",
+			env:     map[string]string{"YTSUMMARY_HEADERS": "no colon here"},
+			wantErr: "invalid header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"YTSUMMARY_CLIENT_PROFILE", "YTSUMMARY_USER_AGENT", "YTSUMMARY_HEADERS"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			got, err := scraperProfile()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("scraperProfile() error = %v", err)
+			}
+			if got.ClientName != tt.wantClient || got.UserAgent != tt.wantUA {
+				t.Errorf("profile = %s %q, want %s %q", got.ClientName, got.UserAgent, tt.wantClient, tt.wantUA)
+			}
+			for k, v := range tt.wantHeader {
+				if got.Headers[k] != v {
+					t.Errorf("header %s = %q, want %q", k, got.Headers[k], v)
+				}
+			}
+		})
+	}
+
+	// Overrides must not leak into the built-in profile
+	if clientProfiles["web"].Headers["Accept-Language"] != "en-US,en;q=0.9" {
+		t.Error("built-in web profile was modified")
+	}
+}
+
+func TestScraperSendsClientProfile(t *testing.T) {
+	youtube := newFakeYouTube(t)
+	t.Setenv("YTSUMMARY_CLIENT_PROFILE", "ios")
+	t.Setenv("YTSUMMARY_USER_AGENT", "")
+	t.Setenv("YTSUMMARY_HEADERS", "X-Test: 1")
+
+	if _, err := fetchPlayerResponse("dQw4w9WgXcQ"); err != nil {
+		t.Fatalf("fetchPlayerResponse() error = %v", err)
+	}
+
+	call := youtube.lastPlayer.Load()
+	if call == nil {
+		t.Fatal("no player request recorded")
+	}
+	if call.client != "IOS" {
+		t.Errorf("clientName = %q, want IOS", call.client)
+	}
+	if got := call.header.Get("User-Agent"); got != clientProfiles["ios"].UserAgent {
+		t.Errorf("User-Agent = %q", got)
+	}
+	if got := call.header.Get("X-Test"); got != "1" {
+		t.Errorf("X-Test = %q, want 1", got)
+	}
+}
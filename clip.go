@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// youtubeWebURL is the base for YouTube web pages; overridden by tests to point at a fake server
+var youtubeWebURL = "https://www.youtube.com"
+
+var clipIDRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{10,64}$`)
+
+// The clip page embeds its range as "clipConfig":{...,"startTimeMs":"1000","endTimeMs":"16000"}
+var (
+	clipConfigRe  = regexp.MustCompile(`"clipConfig":\{[^}]*\}`)
+	clipStartMsRe = regexp.MustCompile(`"startTimeMs":"(\d+)"`)
+	clipEndMsRe   = regexp.MustCompile(`"endTimeMs":"(\d+)"`)
+)
+
+// errClipResolve marks failures to resolve a clip page, which are upstream
+// errors rather than bad requests
+var errClipResolve = errors.New("failed to resolve clip")
+
+// clipIDFromURL returns the clip ID for youtube.com/clip/<id> links, or ""
+func clipIDFromURL(raw string) string {
+	u, err := parseLooseURL(strings.TrimSpace(raw))
+	if err != nil || youtubeHost(u.Hostname()) != "youtube.com" {
+		return ""
+	}
+	id, ok := strings.CutPrefix(u.Path, "/clip/")
+	if !ok {
+		return ""
+	}
+	id = strings.TrimSuffix(id, "/")
+	if !clipIDRe.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+// resolveVideoURL extracts the video ID from any supported URL. Clip links
+// don't contain the video ID, so their page is fetched to find the underlying
+// video and the clipped time range, which is returned as clip.
+func resolveVideoURL(raw string) (videoID string, clip *TimeRange, err error) {
+	clipID := clipIDFromURL(raw)
+	if clipID == "" {
+		videoID, err = extractVideoID(raw)
+		return videoID, nil, err
+	}
+
+	videoID, r, err := resolveClip(clipID)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w %s: %v", errClipResolve, clipID, err)
+	}
+	return videoID, &r, nil
+}
+
+// resolveClip fetches a clip page and returns the source video ID and clip range
+func resolveClip(clipID string) (string, TimeRange, error) {
+	req, err := http.NewRequest("GET", youtubeWebURL+"/clip/"+clipID, nil)
+	if err != nil {
+		return "", TimeRange{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	// Skip the EU consent interstitial
+	req.Header.Set("Cookie", "CONSENT=YES+cb")
+
+	resp, err := youtubeClient().Do(req)
+	if err != nil {
+		return "", TimeRange{}, fmt.Errorf("failed to fetch clip page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", TimeRange{}, fmt.Errorf("clip not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", TimeRange{}, fmt.Errorf("clip page error: status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", TimeRange{}, fmt.Errorf("failed to read clip page: %w", err)
+	}
+
+	return parseClipPage(string(body))
+}
+
+// parseClipPage extracts the source video ID and clip range from a clip page
+func parseClipPage(page string) (string, TimeRange, error) {
+	pr, err := extractPlayerResponse(page)
+	if err != nil {
+		return "", TimeRange{}, err
+	}
+	videoID := pr.VideoDetails.VideoID
+	if !videoIDRe.MatchString(videoID) {
+		return "", TimeRange{}, fmt.Errorf("clip page has no video ID")
+	}
+
+	config := clipConfigRe.FindString(page)
+	start := clipStartMsRe.FindStringSubmatch(config)
+	end := clipEndMsRe.FindStringSubmatch(config)
+	if start == nil || end == nil {
+		return "", TimeRange{}, fmt.Errorf("clip page has no clip range")
+	}
+
+	startMs, _ := strconv.ParseInt(start[1], 10, 64)
+	endMs, _ := strconv.ParseInt(end[1], 10, 64)
+	if endMs <= startMs {
+		return "", TimeRange{}, fmt.Errorf("invalid clip range %d-%dms", startMs, endMs)
+	}
+
+	return videoID, TimeRange{Start: float64(startMs) / 1000, End: float64(endMs) / 1000}, nil
+}
+
+// restrictToRange narrows an entry's transcript to the segments within r.
+// Entries without caption timings can't be restricted.
+func restrictToRange(entry *CacheEntry, r TimeRange) error {
+	if len(entry.Segments) == 0 {
+		return fmt.Errorf("caption timings unavailable for this video")
+	}
+	entry.Segments = sliceSegments(entry.Segments, r)
+	entry.Transcript = segmentsText(entry.Segments)
+	if entry.Transcript == "" {
+		return fmt.Errorf("no captions within the requested time range")
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestClipIDFromURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.youtube.com/clip/UgkxTestClip0001", "UgkxTestClip0001"},
+		{"https://youtube.com/clip/UgkxTestClip0001?si=abc", "UgkxTestClip0001"},
+		{"youtube.com/clip/UgkxTestClip0001/", "UgkxTestClip0001"},
+		{"https://m.youtube.com/clip/UgkxTestClip0001", "UgkxTestClip0001"},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", ""},
+		{"https://example.com/clip/UgkxTestClip0001", ""},
+		{"https://www.youtube.com/clip/", ""},
+		{"dQw4w9WgXcQ", ""},
+	}
+
+	for _, tt := range tests {
+		if got := clipIDFromURL(tt.url); got != tt.want {
+			t.Errorf("clipIDFromURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseClipPage(t *testing.T) {
+	page := `<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ"}};</script>` +
+		`<script>var ytInitialData = {"clipConfig":{"postId":"x","startTimeMs":"1500","endTimeMs":"16000"}};</script>`
+
+	videoID, r, err := parseClipPage(page)
+	if err != nil {
+		t.Fatalf("parseClipPage() error = %v", err)
+	}
+	if videoID != "dQw4w9WgXcQ" {
+		t.Errorf("videoID = %q, want dQw4w9WgXcQ", videoID)
+	}
+	if r != (TimeRange{Start: 1.5, End: 16}) {
+		t.Errorf("range = %+v, want {1.5 16}", r)
+	}
+
+	if _, _, err := parseClipPage(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ"}};</script>`); err == nil {
+		t.Error("expected error for page without clipConfig")
+	}
+}
+
+func TestResolveVideoURL(t *testing.T) {
+	newFakeYouTube(t)
+
+	videoID, clip, err := resolveVideoURL("https://youtube.com/clip/UgkxTestClip0001")
+	if err != nil {
+		t.Fatalf("resolveVideoURL() error = %v", err)
+	}
+	if videoID != "dQw4w9WgXcQ" {
+		t.Errorf("videoID = %q, want dQw4w9WgXcQ", videoID)
+	}
+	if clip == nil || *clip != (TimeRange{Start: 40, End: 50}) {
+		t.Errorf("clip = %+v, want {40 50}", clip)
+	}
+
+	// Regular URLs resolve without a clip range or network access
+	if _, clip, err := resolveVideoURL("https://youtu.be/dQw4w9WgXcQ"); err != nil || clip != nil {
+		t.Errorf("resolveVideoURL(youtu.be) = %+v, %v; want no clip", clip, err)
+	}
+
+	_, _, err = resolveVideoURL("https://youtube.com/clip/UgkxMissingClip1")
+	if !errors.Is(err, errClipResolve) {
+		t.Errorf("error = %v, want errClipResolve", err)
+	}
+}
+
+func TestRestrictToRange(t *testing.T) {
+	entry := &CacheEntry{
+		Transcript: "a b c",
+		Segments: []TranscriptSegment{
+			{Start: 0, Duration: 2, Text: "a"},
+			{Start: 2, Duration: 2, Text: "b"},
+			{Start: 4, Duration: 2, Text: "c"},
+		},
+	}
+	if err := restrictToRange(entry, TimeRange{Start: 2, End: 4}); err != nil {
+		t.Fatalf("restrictToRange() error = %v", err)
+	}
+	if entry.Transcript != "b" {
+		t.Errorf("Transcript = %q, want %q", entry.Transcript, "b")
+	}
+
+	err := restrictToRange(&CacheEntry{Transcript: "untimed"}, TimeRange{Start: 1, End: 2})
+	if err == nil || !strings.Contains(err.Error(), "timings unavailable") {
+		t.Errorf("error = %v, want timings unavailable", err)
+	}
+}
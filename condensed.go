@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxSentenceSeconds ends a caption sentence at the next cue boundary once it
+// runs this long, since auto-generated captions have no punctuation
+const maxSentenceSeconds = 10
+
+// captionSentence is a sentence of the transcript with the time its captions
+// are on screen
+type captionSentence struct {
+	Start, End float64
+	Text       string
+}
+
+// transcriptSentences splits caption segments into sentences, each starting
+// with the cue it begins in and ending with the cue it ends in
+func transcriptSentences(segments []TranscriptSegment) []captionSentence {
+	var sentences []captionSentence
+	var current strings.Builder
+	var start float64
+	flush := func(end float64) {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			sentences = append(sentences, captionSentence{Start: start, End: end, Text: text})
+		}
+		current.Reset()
+	}
+
+	for _, seg := range segments {
+		end := seg.Start + seg.Duration
+		if current.Len() > 0 && seg.Start-start >= maxSentenceSeconds {
+			flush(seg.Start)
+		}
+		text := seg.Text
+		for text != "" {
+			if current.Len() == 0 {
+				start = seg.Start
+			} else if !startsWithMark(text) {
+				current.WriteByte(' ')
+			}
+			i := sentenceEnd(text)
+			if i < 0 {
+				current.WriteString(text)
+				break
+			}
+			current.WriteString(text[:i])
+			flush(end)
+			text = strings.TrimLeftFunc(text[i:], unicode.IsSpace)
+		}
+	}
+	if len(segments) > 0 {
+		last := segments[len(segments)-1]
+		flush(last.Start + last.Duration)
+	}
+	return sentences
+}
+
+// sentenceEnd returns the byte offset just past the first sentence-ending
+// punctuation in s that is followed by a space or the end of s, or -1
+func sentenceEnd(s string) int {
+	for i, r := range s {
+		switch r {
+		case '.', '!', '?', '…', '。', '！', '？':
+			j := i + utf8.RuneLen(r)
+			for j < len(s) && strings.IndexByte(`"')]`, s[j]) >= 0 {
+				j++
+			}
+			if j == len(s) || r >= utf8.RuneSelf && r != '…' {
+				return j
+			}
+			if next, _ := utf8.DecodeRuneInString(s[j:]); unicode.IsSpace(next) {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// summaryBullet matches the list or heading mark opening a summary line
+var summaryBullet = regexp.MustCompile(`^(?:[-*•>]|#+|\d+[.)])\s*`)
+
+// summaryPoints splits a summary into its points: lines without their
+// bullets or heading marks, and the sentences of each line
+func summaryPoints(summary string) []string {
+	var points []string
+	for _, line := range strings.Split(summary, "\n") {
+		line = strings.TrimSpace(summaryBullet.ReplaceAllString(strings.TrimSpace(line), ""))
+		for line != "" {
+			i := sentenceEnd(line)
+			if i < 0 {
+				points = append(points, line)
+				break
+			}
+			points = append(points, line[:i])
+			line = strings.TrimSpace(line[i:])
+		}
+	}
+	return points
+}
+
+// keywords returns the words of s worth matching on: lowercased, without
+// punctuation, and longer than three letters so most function words drop out
+func keywords(s string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		w = strings.ToLower(strings.TrimFunc(w, unicode.IsPunct))
+		if utf8.RuneCountInString(w) > 3 {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+// condensedSubtitles picks the transcript sentence that best matches each
+// point of the summary, giving the key sentences in playback order with their
+// original timings: a "watch the highlights" subtitle track
+func condensedSubtitles(segments []TranscriptSegment, summary string) []captionSentence {
+	sentences := transcriptSentences(segments)
+	sentenceWords := make([]map[string]bool, len(sentences))
+	for i, s := range sentences {
+		sentenceWords[i] = keywords(s.Text)
+	}
+
+	picked := make(map[int]bool)
+	for _, point := range summaryPoints(summary) {
+		pointWords := keywords(point)
+		best, bestScore := -1, 0.0
+		for i, words := range sentenceWords {
+			if picked[i] || len(words) == 0 {
+				continue
+			}
+			shared := 0
+			for w := range pointWords {
+				if words[w] {
+					shared++
+				}
+			}
+			// Long sentences share more words by chance
+			if score := float64(shared) / math.Sqrt(float64(len(words))); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best >= 0 {
+			picked[best] = true
+		}
+	}
+
+	var cues []captionSentence
+	for i, s := range sentences {
+		if picked[i] {
+			cues = append(cues, s)
+		}
+	}
+	// Captions can overlap; keep each cue off the screen before the next
+	for i := 0; i+1 < len(cues); i++ {
+		if cues[i].End > cues[i+1].Start {
+			cues[i].End = cues[i+1].Start
+		}
+	}
+	return cues
+}
+
+// formatSRT renders cues as a SubRip subtitle file
+func formatSRT(cues []captionSentence) string {
+	var b strings.Builder
+	for i, cue := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, srtTimestamp(cue.Start), srtTimestamp(cue.End), cue.Text)
+	}
+	return b.String()
+}
+
+// srtTimestamp renders seconds as HH:MM:SS,mmm
+func srtTimestamp(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// writeCondensedSubtitles saves the condensed subtitles for a summary as an
+// SRT file
+func writeCondensedSubtitles(path string, segments []TranscriptSegment, summary string) error {
+	cues := condensedSubtitles(segments, summary)
+	if len(cues) == 0 {
+		return fmt.Errorf("no transcript sentences match the summary; subtitles not written")
+	}
+	if err := os.WriteFile(path, []byte(formatSRT(cues)), 0644); err != nil {
+		return fmt.Errorf("failed to write subtitles: %w", err)
+	}
+	log("Wrote %d highlight subtitles to %s", len(cues), path)
+	return nil
+}
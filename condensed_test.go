@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// talkSegments is a short talk whose sentences span and share caption cues
+var talkSegments = []TranscriptSegment{
+	{Start: 0, Duration: 3, Text: "Welcome back to the channel."},
+	{Start: 3, Duration: 4, Text: "Today we're looking at sourdough starters, which"},
+	{Start: 7, Duration: 3, Text: "need flour, water and patience. Feed yours daily!"},
+	{Start: 10, Duration: 5, Text: "Also, like and subscribe."},
+	{Start: 15, Duration: 4, Text: "Bake the loaf at 250 degrees for forty minutes."},
+}
+
+func TestTranscriptSentences(t *testing.T) {
+	want := []captionSentence{
+		{0, 3, "Welcome back to the channel."},
+		{3, 10, "Today we're looking at sourdough starters, which need flour, water and patience."},
+		{7, 10, "Feed yours daily!"},
+		{10, 15, "Also, like and subscribe."},
+		{15, 19, "Bake the loaf at 250 degrees for forty minutes."},
+	}
+	got := transcriptSentences(talkSegments)
+	if len(got) != len(want) {
+		t.Fatalf("got %d sentences, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sentence %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Unpunctuated captions are cut at a cue boundary every maxSentenceSeconds
+	var asr []TranscriptSegment
+	for i := 0; i < 8; i++ {
+		asr = append(asr, TranscriptSegment{Start: float64(i * 3), Duration: 3, Text: "and then we"})
+	}
+	if got := transcriptSentences(asr); len(got) != 2 || got[0].Start != 0 || got[0].End != 12 || got[1].Start != 12 || got[1].End != 24 {
+		t.Errorf("unpunctuated = %+v", got)
+	}
+}
+
+func TestSummaryPoints(t *testing.T) {
+	summary := "## Sourdough basics\n\n- Starters need flour and water. Feed them daily.\n2. Bake at 250 degrees"
+	want := []string{"Sourdough basics", "Starters need flour and water.", "Feed them daily.", "Bake at 250 degrees"}
+	got := summaryPoints(summary)
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("summaryPoints = %q, want %q", got, want)
+	}
+}
+
+func TestCondensedSubtitles(t *testing.T) {
+	summary := "- Sourdough starters need flour, water and patience\n- Bake the loaf for forty minutes at 250 degrees"
+	want := "1\n00:00:03,000 --> 00:00:10,000\nToday we're looking at sourdough starters, which need flour, water and patience.\n\n" +
+		"2\n00:00:15,000 --> 00:00:19,000\nBake the loaf at 250 degrees for forty minutes.\n\n"
+	if got := formatSRT(condensedSubtitles(talkSegments, summary)); got != want {
+		t.Errorf("condensed subtitles:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Overlapping picks are cut so one leaves the screen before the next
+	cues := condensedSubtitles(talkSegments, "Starters need flour, water and patience. Feed yours daily!")
+	if len(cues) != 2 || cues[0].End != cues[1].Start {
+		t.Errorf("overlapping cues = %+v", cues)
+	}
+
+	if got := srtTimestamp(3723.4567); got != "01:02:03,457" {
+		t.Errorf("srtTimestamp = %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "highlights.srt")
+	if err := writeCondensedSubtitles(path, talkSegments, summary); err != nil {
+		t.Fatalf("writeCondensedSubtitles() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != want {
+		t.Errorf("file = %q", data)
+	}
+	if err := writeCondensedSubtitles(path, talkSegments, "Nothing relevant"); err == nil {
+		t.Error("expected an error when no sentence matches the summary")
+	}
+}
+
+func TestSummarizeSubtitles(t *testing.T) {
+	cache := newTestCache(t)
+	cache.StoreTranscript(&CacheEntry{VideoID: "dQw4w9WgXcQ", Language: "en", Transcript: segmentsText(talkSegments), Segments: talkSegments})
+	cacheTranscript(cache, "untimed0001", "en", "", "No timings here.")
+	handler := newServer(ServerConfig{
+		Cache: cache,
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Transcript: "Still no timings.", Language: "en"}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			return "- Bake the loaf for forty minutes", nil
+		},
+	}).Handler()
+
+	req := httptest.NewRequest("GET", "/v1/summarize?url=dQw4w9WgXcQ&subtitles=true", nil)
+	req.RemoteAddr = "192.0.2.21:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	var resp TranscriptResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status = %d, %v", w.Code, err)
+	}
+	if want := "1\n00:00:15,000 --> 00:00:19,000\nBake the loaf at 250 degrees for forty minutes.\n\n"; resp.Subtitles != want {
+		t.Errorf("Subtitles = %q, want %q", resp.Subtitles, want)
+	}
+
+	req = httptest.NewRequest("POST", "/v1/summarize", strings.NewReader(`{"url": "https://youtu.be/untimed0001", "subtitles": true}`))
+	req.RemoteAddr = "192.0.2.21:1234"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("untimed: status = %d, want 404: %s", w.Code, w.Body)
+	}
+}
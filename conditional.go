@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cachedResponseMaxAge is how long clients and intermediaries may reuse a
+// response built from a cached transcript. Captions rarely change once a
+// video is published.
+const cachedResponseMaxAge = time.Hour
+
+// requestFromQuery reads a GET /transcript or GET /summarize request. Only
+// the commonly used fields are accepted; POST takes the full JSON body.
+func requestFromQuery(q url.Values) (TranscriptRequest, error) {
+	req := TranscriptRequest{
+		URL:              q.Get("url"),
+		Language:         q.Get("language"),
+		Template:         q.Get("template"),
+		From:             q.Get("from"),
+		To:               q.Get("to"),
+		Format:           q.Get("format"),
+		ContentFilter:    q.Get("content_filter"),
+		MusicVideos:      q.Get("music_videos"),
+		SummaryLanguage:  q.Get("summary_language"),
+		PromptLanguage:   q.Get("prompt_language"),
+		Priority:         q.Get("priority"),
+		Mode:             q.Get("mode"),
+		TruncateStrategy: q.Get("truncate_strategy"),
+	}
+	for name, dst := range map[string]*int{"offset": &req.Offset, "limit": &req.Limit, "max_words": &req.MaxWords, "count": &req.Count, "max_transcript_chars": &req.MaxTranscriptChars} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return req, fmt.Errorf("invalid %s %q", name, v)
+			}
+			*dst = n
+		}
+	}
+	for name, dst := range map[string]*bool{"clip_only": &req.ClipOnly, "allow_auto_translate": &req.AllowAutoTranslate, "subtitles": &req.Subtitles, "with_notes": &req.WithNotes} {
+		if v := q.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return req, fmt.Errorf("invalid %s %q", name, v)
+			}
+			*dst = b
+		}
+	}
+	return req, nil
+}
+
+// responseETag identifies a response by its content, ignoring how long it
+// took to produce. The plain text form gets its own tag since its body
+// differs.
+func responseETag(resp TranscriptResponse, plain bool) string {
+	resp.DurationMS = 0
+	body, _ := json.Marshal(resp)
+	if plain {
+		return `"` + sha256Hex(body)[:32] + `-text"`
+	}
+	return `"` + sha256Hex(body)[:32] + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTranscriptResponse writes a successful /transcript response. When the
+// transcript came from the cache it carries Cache-Control, Age and an ETag,
+// and a GET whose If-None-Match matches gets 304 Not Modified. Either way the
+// body is JSON or plain text as the Accept header prefers.
+func (s *Server) writeTranscriptResponse(w http.ResponseWriter, r *http.Request, resp TranscriptResponse) {
+	if !resp.Cached {
+		writeTranscriptBody(w, r, resp)
+		return
+	}
+
+	etag := responseETag(resp, prefersPlainText(r.Header.Get("Accept")))
+	h := w.Header()
+	h.Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", s.cacheVisibility(), int(cachedResponseMaxAge.Seconds())))
+	h.Set("ETag", etag)
+	if !resp.FetchedAt.IsZero() {
+		h.Set("Age", strconv.FormatInt(int64(max(time.Since(resp.FetchedAt), 0).Seconds()), 10))
+	}
+
+	if r.Method == http.MethodGet && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		h.Add("Vary", varyHeaders)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeTranscriptBody(w, r, resp)
+}
+
+// cacheVisibility is "private" when responses are for an authenticated
+// client alone, else "public"
+func (s *Server) cacheVisibility() string {
+	if s.authEnabled() {
+		return "private"
+	}
+	return "public"
+}
+
+// summaryETag identifies a /summarize response by the stored summary it
+// carries and the request options that shape the rest of the body, so it
+// doesn't change with the wording of a regenerated summary. It leads with
+// the summary's ID, which revalidateSummary looks up.
+func summaryETag(kept *StoredSummary, req TranscriptRequest, plain bool) string {
+	// How the request was addressed and paid for doesn't change the body
+	req.URL, req.Priority, req.MaxCost = "", "", 0
+	params, _ := json.Marshal(req)
+	key := strings.Join([]string{kept.VideoID, kept.Language, kept.Template, kept.Model, strconv.Itoa(kept.MaxWords), string(params)}, "\x00")
+	tag := fmt.Sprintf("s%d-%s", kept.ID, sha256Hex([]byte(key))[:24])
+	if plain {
+		tag += "-text"
+	}
+	return `"` + tag + `"`
+}
+
+// summaryETagIDs returns the stored summary IDs of the summaryETag tags an
+// If-None-Match header lists
+func summaryETagIDs(header string) []int64 {
+	var ids []int64
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.Trim(strings.TrimPrefix(strings.TrimSpace(candidate), "W/"), `"`)
+		rest, ok := strings.CutPrefix(candidate, "s")
+		if !ok {
+			continue
+		}
+		idText, _, _ := strings.Cut(rest, "-")
+		if id, err := strconv.ParseInt(idText, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// revalidateSummary checks a GET /summarize's If-None-Match against the
+// stored summaries it names. When one is of this video, language and
+// template and its ETag still matches the request, it returns that ETag and
+// the client's copy can be reused without calling the LLM.
+func (s *Server) revalidateSummary(r *http.Request, req TranscriptRequest, videoID, lang string) (string, bool) {
+	header := r.Header.Get("If-None-Match")
+	if r.Method != http.MethodGet || s.summaries == nil || header == "" {
+		return "", false
+	}
+	template := req.Template
+	if template == "" {
+		template = defaultTemplateName
+	}
+	for _, id := range summaryETagIDs(header) {
+		kept, err := s.summaries.GetSummary(id)
+		if err != nil || kept.VideoID != videoID || kept.Language != lang || kept.Template != template || kept.MaxWords != req.MaxWords {
+			continue
+		}
+		if etag := summaryETag(kept, req, prefersPlainText(r.Header.Get("Accept"))); etagMatches(header, etag) {
+			return etag, true
+		}
+	}
+	return "", false
+}
+
+// setSummaryCacheHeaders marks a /summarize response as reusable only after
+// revalidating etag, since a new request would generate a new summary
+func (s *Server) setSummaryCacheHeaders(w http.ResponseWriter, etag string) {
+	w.Header().Set("Cache-Control", s.cacheVisibility()+", no-cache")
+	w.Header().Set("ETag", etag)
+}
+
+// writeSummaryResponse writes a successful /summarize response. A summary
+// kept in the summary store gets an ETag for conditional requests; one that
+// wasn't kept can't be revalidated and gets no caching headers.
+func (s *Server) writeSummaryResponse(w http.ResponseWriter, r *http.Request, resp TranscriptResponse, req TranscriptRequest, kept *StoredSummary) {
+	if kept.ID != 0 {
+		s.setSummaryCacheHeaders(w, summaryETag(kept, req, prefersPlainText(r.Header.Get("Accept"))))
+	}
+	writeTranscriptBody(w, r, resp)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestConditionalTranscript(t *testing.T) {
+	s := newServer(ServerConfig{
+		APIKey: "secret",
+		Cache:  newTestCache(t),
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Injected", Transcript: "One. Two. Three."}, nil
+		},
+	})
+	handler := s.Handler()
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/transcript?url=https://youtu.be/dQw4w9WgXcQ", nil)
+		req.Header.Set("X-API-Key", "secret")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// The first request fetches the transcript, so there's nothing to revalidate
+	if w := get(""); w.Code != http.StatusOK || w.Header().Get("ETag") != "" {
+		t.Fatalf("fresh fetch: status = %d, ETag = %q; want 200 without an ETag", w.Code, w.Header().Get("ETag"))
+	}
+
+	w := get("")
+	if w.Code != http.StatusOK {
+		t.Fatalf("cached: status = %d: %s", w.Code, w.Body)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("cached response has no ETag")
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, max-age=3600" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if _, err := strconv.Atoi(w.Header().Get("Age")); err != nil {
+		t.Errorf("Age = %q, want seconds", w.Header().Get("Age"))
+	}
+
+	if w := get(etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("If-None-Match: status = %d with %d bytes, want empty 304", w.Code, w.Body.Len())
+	}
+	if w := get(`"stale", W/` + etag); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match list: status = %d, want 304", w.Code)
+	}
+	if w := get(`"stale"`); w.Code != http.StatusOK {
+		t.Errorf("mismatched If-None-Match: status = %d, want 200", w.Code)
+	}
+}
+
+func TestConditionalSummarize(t *testing.T) {
+	cache := newTestCache(t)
+	calls := 0
+	s := newServer(ServerConfig{
+		APIKey:    "secret",
+		Cache:     cache,
+		Summaries: cache,
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Injected", Transcript: "One. Two. Three."}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			calls++
+			return fmt.Sprintf("Summary number %d.", calls), nil
+		},
+	})
+	handler := s.Handler()
+
+	requests := 0
+	get := func(query, etag string) *httptest.ResponseRecorder {
+		requests++
+		req := httptest.NewRequest("GET", "/summarize?url=https://youtu.be/dQw4w9WgXcQ"+query, nil)
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:1234", requests) // stay under the per-IP limit
+		req.Header.Set("X-API-Key", "secret")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag = %q; want 200 with an ETag", w.Code, etag)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, no-cache" {
+		t.Errorf("Cache-Control = %q, want private, no-cache", got)
+	}
+
+	// Revalidating the kept summary doesn't call the LLM again
+	for _, header := range []string{etag, `"stale", W/` + etag} {
+		if w := get("", header); w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: status = %d with %d bytes, ETag %q; want empty 304", header, w.Code, w.Body.Len(), w.Header().Get("ETag"))
+		}
+	}
+	if calls != 1 {
+		t.Errorf("LLM calls = %d, want 1", calls)
+	}
+
+	// Other options, or an unknown summary, get a new summary and tag
+	for _, tt := range []struct{ query, etag string }{
+		{"&max_words=50", etag},
+		{"&template=key-points", etag},
+		{"", `"stale"`},
+		{"", `"s999-0000"`},
+	} {
+		w := get(tt.query, tt.etag)
+		if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+			t.Errorf("%q with %s: status = %d, ETag %q; want 200 with a new tag", tt.query, tt.etag, w.Code, w.Header().Get("ETag"))
+		}
+	}
+	if calls != 5 {
+		t.Errorf("LLM calls = %d, want 5", calls)
+	}
+
+	// Summaries that aren't kept can't be revalidated
+	s = newServer(ServerConfig{
+		Cache: cache,
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			return "Not kept.", nil
+		},
+	})
+	req := httptest.NewRequest("GET", "/summarize?url=https://youtu.be/dQw4w9WgXcQ", nil)
+	w = httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != "" || w.Header().Get("Cache-Control") != "" {
+		t.Errorf("without a summary store: status = %d, headers %v", w.Code, w.Header())
+	}
+}
+
+func TestRequestFromQuery(t *testing.T) {
+	r := httptest.NewRequest("GET", "/transcript?url=dQw4w9WgXcQ&language=de&format=segments&limit=10&clip_only=true", nil)
+	req, videoID, lang, err := parseRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if videoID != "dQw4w9WgXcQ" || lang != "de" || req.Format != "segments" || req.Limit != 10 || !req.ClipOnly {
+		t.Errorf("parseRequest() = %+v, %s, %s", req, videoID, lang)
+	}
+
+	if _, _, _, err := parseRequest(httptest.NewRequest("GET", "/transcript?url=dQw4w9WgXcQ&limit=ten", nil)); err == nil {
+		t.Error("expected an error for a non-numeric limit")
+	}
+}
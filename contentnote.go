@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Content filter modes (--content-filter / "content_filter")
+const (
+	contentFilterOff  = ""
+	contentFilterFlag = "flag" // count sensitive content and add a content note
+	contentFilterMask = "mask" // also mask profanity in the transcript and summary
+)
+
+// Word patterns; a trailing * matches any word starting with the rest
+var (
+	profanityWords = []string{
+		"fuck*", "motherfuck*", "shit*", "bullshit*", "ass", "asshole*", "bitch*",
+		"bastard*", "dickhead*", "cunt*", "piss", "pissed", "crap", "crappy",
+		"damn", "damned", "dammit", "goddamn*", "wtf",
+	}
+	sensitiveTopics = []struct {
+		name  string
+		words []string
+	}{
+		{"violence", []string{"kill", "killed", "killing", "killings", "murder*", "shooting", "shootings", "stabbed", "stabbing", "massacre*", "assault*"}},
+		{"drugs", []string{"cocaine", "heroin", "meth", "fentanyl", "overdose*"}},
+		{"self-harm", []string{"suicide*", "suicidal", "self-harm"}},
+		{"sexual content", []string{"sex", "sexual", "sexually", "porn*"}},
+	}
+)
+
+var (
+	wordRe = regexp.MustCompile(`\p{L}+(?:-\p{L}+)?`)
+
+	// YouTube's auto-generated captions censor profanity as [ __ ]
+	censoredRe = regexp.MustCompile(`\[\s*_+\s*\]`)
+)
+
+// ContentNotes summarizes the sensitive content found in a transcript
+type ContentNotes struct {
+	Profanity int      `json:"profanity"`        // number of profane words
+	Topics    []string `json:"topics,omitempty"` // sensitive topics mentioned, e.g. "violence"
+	Masked    bool     `json:"masked"`           // profanity was masked before summarizing
+}
+
+// validContentFilter checks a --content-filter / content_filter value
+func validContentFilter(mode string) error {
+	switch mode {
+	case contentFilterOff, contentFilterFlag, contentFilterMask:
+		return nil
+	}
+	return fmt.Errorf("invalid content filter %q (use flag or mask)", mode)
+}
+
+// contentFilterMode returns the CLI content filter (--content-filter / YTSUMMARY_CONTENT_FILTER)
+func contentFilterMode() string {
+	return getConfig(contentFilter, "YTSUMMARY_CONTENT_FILTER")
+}
+
+// matchesWord reports whether a lowercase word matches any pattern
+func matchesWord(word string, patterns []string) bool {
+	for _, p := range patterns {
+		if stem, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(word, stem) {
+				return true
+			}
+		} else if word == p {
+			return true
+		}
+	}
+	return false
+}
+
+// scanContent counts profanity and sensitive topics in text. With mask set,
+// profane words are replaced by their first letter and asterisks ("f***").
+func scanContent(text string, mask bool) (string, *ContentNotes) {
+	notes := &ContentNotes{Masked: mask}
+	notes.Profanity = len(censoredRe.FindAllStringIndex(text, -1))
+	topics := make(map[string]bool)
+
+	text = wordRe.ReplaceAllStringFunc(text, func(word string) string {
+		lower := strings.ToLower(word)
+		for _, topic := range sensitiveTopics {
+			if matchesWord(lower, topic.words) {
+				topics[topic.name] = true
+			}
+		}
+		if !matchesWord(lower, profanityWords) {
+			return word
+		}
+		notes.Profanity++
+		if !mask {
+			return word
+		}
+		_, size := utf8.DecodeRuneInString(word)
+		return word[:size] + strings.Repeat("*", utf8.RuneCountInString(word)-1)
+	})
+
+	// Keep the declared topic order so notes are stable
+	for _, topic := range sensitiveTopics {
+		if topics[topic.name] {
+			notes.Topics = append(notes.Topics, topic.name)
+		}
+	}
+	return text, notes
+}
+
+// applyContentFilter scans an entry's transcript, masking its text and caption
+// cues in mask mode. It returns nil when the filter is off.
+func applyContentFilter(entry *CacheEntry, mode string) *ContentNotes {
+	if mode == contentFilterOff {
+		return nil
+	}
+
+	mask := mode == contentFilterMask
+	var notes *ContentNotes
+	entry.Transcript, notes = scanContent(entry.Transcript, mask)
+	if mask {
+		for i := range entry.Segments {
+			entry.Segments[i].Text, _ = scanContent(entry.Segments[i].Text, true)
+			for j := range entry.Segments[i].Words {
+				entry.Segments[i].Words[j].Word, _ = scanContent(entry.Segments[i].Words[j].Word, true)
+			}
+		}
+	}
+	return notes
+}
+
+// filterText is applyContentFilter for plain text without caption cues
+func filterText(text, mode string) (string, *ContentNotes) {
+	entry := &CacheEntry{Transcript: text}
+	notes := applyContentFilter(entry, mode)
+	return entry.Transcript, notes
+}
+
+// String renders the notes as a one-line content note, or "" when nothing was found
+func (n *ContentNotes) String() string {
+	var parts []string
+	if n.Profanity > 0 {
+		word := "instances"
+		if n.Profanity == 1 {
+			word = "instance"
+		}
+		parts = append(parts, fmt.Sprintf("strong language (%d %s)", n.Profanity, word))
+	}
+	if len(n.Topics) > 0 {
+		parts = append(parts, "mentions of "+strings.Join(n.Topics, ", "))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "This video contains " + strings.Join(parts, " and ") + "."
+}
+
+// withContentNote appends a content-note section to a summary. Masking also
+// covers profanity the LLM repeated in its summary.
+func withContentNote(summary string, notes *ContentNotes) string {
+	if notes == nil {
+		return summary
+	}
+	if notes.Masked {
+		summary, _ = scanContent(summary, true)
+	}
+	if note := notes.String(); note != "" {
+		summary += "\n\n**Content note:** " + note
+	}
+	return summary
+}
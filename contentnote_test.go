@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanContent(t *testing.T) {
+	tests := []struct {
+		name          string
+		in            string
+		mask          bool
+		wantText      string
+		wantProfanity int
+		wantTopics    []string
+	}{
+		{"clean", "a calm talk about gardening", false, "a calm talk about gardening", 0, nil},
+		{"flag only", "well shit, that was a fucking mess", false, "well shit, that was a fucking mess", 2, nil},
+		{"mask", "well shit, that was a Fucking mess", true, "well s***, that was a F****** mess", 2, nil},
+		{"no false positives", "the class assignment was passed", true, "the class assignment was passed", 0, nil},
+		{"censored captions", "what the [ __ ] is that", false, "what the [ __ ] is that", 1, nil},
+		{"topics", "the film covers a murder and a heroin overdose", false, "the film covers a murder and a heroin overdose", 0, []string{"violence", "drugs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, notes := scanContent(tt.in, tt.mask)
+			if got != tt.wantText {
+				t.Errorf("text = %q, want %q", got, tt.wantText)
+			}
+			if notes.Profanity != tt.wantProfanity {
+				t.Errorf("Profanity = %d, want %d", notes.Profanity, tt.wantProfanity)
+			}
+			if !reflect.DeepEqual(notes.Topics, tt.wantTopics) {
+				t.Errorf("Topics = %v, want %v", notes.Topics, tt.wantTopics)
+			}
+		})
+	}
+}
+
+func TestWithContentNote(t *testing.T) {
+	if got := withContentNote("Summary.", nil); got != "Summary." {
+		t.Errorf("no filter: got %q", got)
+	}
+	if got := withContentNote("Summary.", &ContentNotes{}); got != "Summary." {
+		t.Errorf("nothing found: got %q", got)
+	}
+
+	notes := &ContentNotes{Profanity: 1, Topics: []string{"violence"}, Masked: true}
+	want := "The host says s*** a lot.\n\n**Content note:** This video contains strong language (1 instance) and mentions of violence."
+	if got := withContentNote("The host says shit a lot.", notes); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestValidContentFilter(t *testing.T) {
+	for _, mode := range []string{"", "flag", "mask"} {
+		if err := validContentFilter(mode); err != nil {
+			t.Errorf("validContentFilter(%q) = %v", mode, err)
+		}
+	}
+	if err := validContentFilter("censor"); err == nil {
+		t.Error("validContentFilter(\"censor\") = nil, want error")
+	}
+}
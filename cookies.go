@@ -0,0 +1,598 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Flag for --cookie-store
+var cookieStorePath string
+
+const (
+	// cookieStoreVersion 2 binds the header to the sealed data
+	cookieStoreVersion = 2
+	// Key sources of a cookie store
+	keySourceFile       = "key-file"
+	keySourcePassphrase = "passphrase"
+	// pbkdf2Iterations follows OWASP's advice for PBKDF2-HMAC-SHA256
+	pbkdf2Iterations = 600000
+	// cookieExpiryWarning is how long before cookies run out ytsummary warns
+	cookieExpiryWarning = 7 * 24 * time.Hour
+)
+
+// cookieDomains are the sites whose cookies the scraper may send; cookies
+// for anything else in an export are left out of the store
+var cookieDomains = []string{"youtube.com", "google.com"}
+
+// storedCookie is one cookie of a browser's cookies.txt export
+type storedCookie struct {
+	Domain            string    `json:"domain"`
+	IncludeSubdomains bool      `json:"include_subdomains"`
+	Path              string    `json:"path"`
+	Secure            bool      `json:"secure"`
+	Expires           time.Time `json:"expires,omitempty"` // zero for a session cookie
+	Name              string    `json:"name"`
+	Value             string    `json:"value"`
+}
+
+// cookieJar is what the store keeps encrypted
+type cookieJar struct {
+	ImportedAt time.Time      `json:"imported_at"`
+	Cookies    []storedCookie `json:"cookies"`
+}
+
+// cookieStoreFile is the store on disk. Data is the AES-256-GCM sealed JSON
+// of a cookieJar; the rest says how to get the key back, and is sealed in as
+// additional data so it can't be changed without breaking decryption.
+type cookieStoreFile struct {
+	Version   int    `json:"version"`
+	KeySource string `json:"key_source"`
+	Salt      []byte `json:"salt,omitempty"` // PBKDF2 salt of a passphrase
+	Nonce     []byte `json:"nonce"`
+	Data      []byte `json:"data"`
+}
+
+// cookieStoreFilePath returns --cookie-store (YTSUMMARY_COOKIE_STORE),
+// else cookies.enc in the cache directory. explicit reports whether it was
+// configured, in which case a missing store is an error.
+func cookieStoreFilePath() (path string, explicit bool) {
+	if path := getConfig(cookieStorePath, "YTSUMMARY_COOKIE_STORE"); path != "" {
+		return path, true
+	}
+	return filepath.Join(cacheDir, "cookies.enc"), false
+}
+
+// additionalData is the header of f as sealed with its data
+func (f *cookieStoreFile) additionalData() []byte {
+	return fmt.Appendf(nil, "ytsummary cookies v%d %s %s", f.Version, f.KeySource, base64.StdEncoding.EncodeToString(f.Salt))
+}
+
+// secretKeyFilePath returns YTSUMMARY_SECRET_KEY_FILE, else secret.key in
+// the user's config directory. The key never goes next to the store: without
+// a config directory one of the variables has to be set.
+func secretKeyFilePath() (string, error) {
+	if path := os.Getenv("YTSUMMARY_SECRET_KEY_FILE"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("no config directory for the cookie store's secret key (%v): set YTSUMMARY_SECRET_KEY_FILE or YTSUMMARY_SECRET_KEY", err)
+	}
+	return filepath.Join(dir, "ytsummary", "secret.key"), nil
+}
+
+// readKeyFile returns the 32-byte key in path, generating one readable by
+// the owner alone when create is set and there is none
+func readKeyFile(path string, create bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		return writeNewKeyFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("invalid secret key in %s", path)
+	}
+	return key, nil
+}
+
+// writeNewKeyFile generates a key and saves it to path
+func writeNewKeyFile(path string) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create secret key directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to save secret key: %w", err)
+	}
+	return key, nil
+}
+
+// passphraseKey derives a key from a passphrase such as YTSUMMARY_SECRET_KEY
+func passphraseKey(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+}
+
+// sealCookies encrypts jar with key; salt goes into the file for a
+// passphrase key
+func sealCookies(jar *cookieJar, source string, key, salt []byte) (*cookieStoreFile, error) {
+	plaintext, err := json.Marshal(jar)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	f := &cookieStoreFile{
+		Version:   cookieStoreVersion,
+		KeySource: source,
+		Salt:      salt,
+		Nonce:     nonce,
+	}
+	f.Data = gcm.Seal(nil, nonce, plaintext, f.additionalData())
+	return f, nil
+}
+
+// sealCookiesWithConfiguredKey encrypts jar with YTSUMMARY_SECRET_KEY when
+// set, else with the key file, which is created on first use
+func sealCookiesWithConfiguredKey(jar *cookieJar) (*cookieStoreFile, error) {
+	if passphrase := os.Getenv("YTSUMMARY_SECRET_KEY"); passphrase != "" {
+		return sealCookiesWithPassphrase(jar, passphrase)
+	}
+	keyPath, err := secretKeyFilePath()
+	if err != nil {
+		return nil, err
+	}
+	key, err := readKeyFile(keyPath, true)
+	if err != nil {
+		return nil, err
+	}
+	return sealCookies(jar, keySourceFile, key, nil)
+}
+
+// sealCookiesWithPassphrase encrypts jar with a key derived from passphrase
+// and a new salt
+func sealCookiesWithPassphrase(jar *cookieJar, passphrase string) (*cookieStoreFile, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := passphraseKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return sealCookies(jar, keySourcePassphrase, key, salt)
+}
+
+// openCookies decrypts a store with the key it was sealed with
+func openCookies(f *cookieStoreFile) (*cookieJar, error) {
+	if f.Version == 1 {
+		return nil, fmt.Errorf("the cookie store is in an older format: run 'ytsummary auth import-cookies' again")
+	}
+	if f.Version != cookieStoreVersion {
+		return nil, fmt.Errorf("unsupported cookie store version %d", f.Version)
+	}
+	var key []byte
+	var err error
+	switch f.KeySource {
+	case keySourcePassphrase:
+		passphrase := os.Getenv("YTSUMMARY_SECRET_KEY")
+		if passphrase == "" {
+			return nil, fmt.Errorf("the cookie store is encrypted with a passphrase: set YTSUMMARY_SECRET_KEY")
+		}
+		key, err = passphraseKey(passphrase, f.Salt)
+	case keySourceFile:
+		var keyPath string
+		if keyPath, err = secretKeyFilePath(); err == nil {
+			key, err = readKeyFile(keyPath, false)
+		}
+	default:
+		return nil, fmt.Errorf("unknown cookie store key source %q", f.KeySource)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, f.Nonce, f.Data, f.additionalData())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the cookie store: wrong secret key or a damaged file")
+	}
+	var jar cookieJar
+	if err := json.Unmarshal(plaintext, &jar); err != nil {
+		return nil, fmt.Errorf("failed to parse the cookie store: %w", err)
+	}
+	return &jar, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readCookieStore reads the store at path without decrypting it
+func readCookieStore(path string) (*cookieStoreFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f cookieStoreFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse cookie store %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// writeCookieStore replaces the store at path, readable by the owner alone
+func writeCookieStore(path string, f *cookieStoreFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cookie store directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save cookie store: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// parseCookiesTxt parses a Netscape cookies.txt export, as written by
+// browser extensions and yt-dlp --cookies-from-browser, keeping the cookies
+// of cookieDomains. skipped counts the cookies of other sites.
+func parseCookiesTxt(data []byte) (cookies []storedCookie, skipped int, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// HttpOnly cookies are written as comments with this prefix
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, 0, fmt.Errorf("line %d is not in cookies.txt (Netscape) format", lineNo)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: invalid expiry %q", lineNo, fields[4])
+		}
+		c := storedCookie{
+			Domain:            strings.ToLower(fields[0]),
+			IncludeSubdomains: strings.EqualFold(fields[1], "TRUE"),
+			Path:              fields[2],
+			Secure:            strings.EqualFold(fields[3], "TRUE"),
+			Name:              fields[5],
+			Value:             fields[6],
+		}
+		if expires > 0 {
+			c.Expires = time.Unix(expires, 0).UTC()
+		}
+		if !cookieForYouTube(c.Domain) {
+			skipped++
+			continue
+		}
+		cookies = append(cookies, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return cookies, skipped, nil
+}
+
+// cookieForYouTube reports whether a cookie domain is one of cookieDomains
+func cookieForYouTube(domain string) bool {
+	domain = strings.TrimPrefix(domain, ".")
+	for _, d := range cookieDomains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the cookie goes with a request to u at now
+func (c storedCookie) matches(u *url.URL, now time.Time) bool {
+	if !c.Expires.IsZero() && !now.Before(c.Expires) {
+		return false
+	}
+	if c.Secure && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	domain := strings.TrimPrefix(c.Domain, ".")
+	subdomains := c.IncludeSubdomains || strings.HasPrefix(c.Domain, ".")
+	if host != domain && !(subdomains && strings.HasSuffix(host, "."+domain)) {
+		return false
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return strings.HasPrefix(path, c.Path)
+}
+
+// header returns the Cookie header for a request to u, or ""
+func (j *cookieJar) header(u *url.URL, now time.Time) string {
+	if j == nil {
+		return ""
+	}
+	var pairs []string
+	for _, c := range j.Cookies {
+		if c.matches(u, now) {
+			pairs = append(pairs, c.Name+"="+c.Value)
+		}
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// expiry returns when the first of the jar's persistent cookies runs out
+// and how many already have; zero when none expire
+func (j *cookieJar) expiry(now time.Time) (first time.Time, expired int) {
+	for _, c := range j.Cookies {
+		if c.Expires.IsZero() {
+			continue
+		}
+		if !now.Before(c.Expires) {
+			expired++
+			continue
+		}
+		if first.IsZero() || c.Expires.Before(first) {
+			first = c.Expires
+		}
+	}
+	return first, expired
+}
+
+// expiryWarning describes cookies that have run out or are about to, or
+// returns "" when they are fine for another cookieExpiryWarning
+func (j *cookieJar) expiryWarning(now time.Time) string {
+	first, expired := j.expiry(now)
+	switch {
+	case expired == len(j.Cookies):
+		return "all imported YouTube cookies have expired; export them again and run 'ytsummary auth import-cookies'"
+	case expired > 0:
+		return fmt.Sprintf("%d of %d imported YouTube cookies have expired; export them again and run 'ytsummary auth import-cookies'", expired, len(j.Cookies))
+	case !first.IsZero() && first.Sub(now) < cookieExpiryWarning:
+		return fmt.Sprintf("imported YouTube cookies expire on %s; export them again and run 'ytsummary auth import-cookies'", first.Format(time.DateOnly))
+	}
+	return ""
+}
+
+// cookieCache keeps the decrypted cookies in memory, reloading them when
+// the store changes so a re-import reaches a running server
+var cookieCache struct {
+	sync.Mutex
+	path    string
+	modTime time.Time
+	jar     *cookieJar
+}
+
+// storedCookies returns the imported cookies, or nil when there are none
+func storedCookies() (*cookieJar, error) {
+	path, explicit := cookieStoreFilePath()
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookie store: %w", err)
+	}
+
+	cookieCache.Lock()
+	defer cookieCache.Unlock()
+	if cookieCache.path == path && cookieCache.modTime.Equal(info.ModTime()) {
+		return cookieCache.jar, nil
+	}
+	f, err := readCookieStore(path)
+	if err != nil {
+		return nil, err
+	}
+	jar, err := openCookies(f)
+	if err != nil {
+		return nil, err
+	}
+	if msg := jar.expiryWarning(time.Now()); msg != "" {
+		fmt.Fprintln(os.Stderr, "WARNING: "+msg)
+		logWarn(msg, slog.String("cookie_store", path))
+	}
+	cookieCache.path, cookieCache.modTime, cookieCache.jar = path, info.ModTime(), jar
+	return jar, nil
+}
+
+// applyCookies adds the imported cookies that go with a scraper request
+func applyCookies(req *http.Request) error {
+	jar, err := storedCookies()
+	if err != nil {
+		return err
+	}
+	if header := jar.header(req.URL, time.Now()); header != "" {
+		req.Header.Set("Cookie", header)
+	}
+	return nil
+}
+
+// runAuthImportCookies encrypts a cookies.txt export into the store
+func runAuthImportCookies(cmd *cobra.Command, args []string) error {
+	data, err := readInput(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read cookies: %w", err)
+	}
+	cookies, skipped, err := parseCookiesTxt(data)
+	if err != nil {
+		return err
+	}
+	if len(cookies) == 0 {
+		return fmt.Errorf("no YouTube or Google cookies in %s", inputName(args[0]))
+	}
+
+	jar := &cookieJar{ImportedAt: time.Now().UTC(), Cookies: cookies}
+	f, err := sealCookiesWithConfiguredKey(jar)
+	if err != nil {
+		return err
+	}
+	path, _ := cookieStoreFilePath()
+	if err := writeCookieStore(path, f); err != nil {
+		return err
+	}
+
+	log("Stored %d cookies encrypted in %s (%d for other sites left out)", len(cookies), path, skipped)
+	if f.KeySource == keySourceFile {
+		keyPath, _ := secretKeyFilePath()
+		log("Encrypted with the key in %s; keep it out of backups of the store", keyPath)
+	}
+	if msg := jar.expiryWarning(time.Now()); msg != "" {
+		fmt.Fprintln(os.Stderr, "WARNING: "+msg)
+	}
+	if args[0] != stdinPath {
+		log("Delete the plaintext export now: rm %s", args[0])
+	}
+	return nil
+}
+
+// runAuthStatus says where the LLM API key comes from and describes the
+// cookie store, without printing either
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	fmt.Printf("API key:   %s\n", apiKeySource())
+	path, _ := cookieStoreFilePath()
+	f, err := readCookieStore(path)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("No cookies imported (%s)\n", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	jar, err := openCookies(f)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Store:     %s\n", path)
+	if f.KeySource == keySourceFile {
+		keyPath, _ := secretKeyFilePath()
+		fmt.Printf("Key:       %s\n", keyPath)
+	} else {
+		fmt.Printf("Key:       YTSUMMARY_SECRET_KEY passphrase\n")
+	}
+	fmt.Printf("Imported:  %s\n", jar.ImportedAt.Local().Format(time.RFC1123))
+	fmt.Printf("Cookies:   %d\n", len(jar.Cookies))
+	if first, _ := jar.expiry(time.Now()); !first.IsZero() {
+		fmt.Printf("Expires:   %s\n", first.Local().Format(time.RFC1123))
+	}
+	if msg := jar.expiryWarning(time.Now()); msg != "" {
+		fmt.Println("WARNING: " + msg)
+	}
+	return nil
+}
+
+// runAuthRotateKey re-encrypts the store with a new key file, or with the
+// YTSUMMARY_NEW_SECRET_KEY passphrase when it was encrypted with one
+func runAuthRotateKey(cmd *cobra.Command, args []string) error {
+	path, _ := cookieStoreFilePath()
+	f, err := readCookieStore(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no cookies imported (%s)", path)
+	}
+	if err != nil {
+		return err
+	}
+	jar, err := openCookies(f)
+	if err != nil {
+		return err
+	}
+
+	if f.KeySource == keySourcePassphrase {
+		passphrase := os.Getenv("YTSUMMARY_NEW_SECRET_KEY")
+		if passphrase == "" {
+			return fmt.Errorf("set YTSUMMARY_NEW_SECRET_KEY to the new passphrase")
+		}
+		rotated, err := sealCookiesWithPassphrase(jar, passphrase)
+		if err != nil {
+			return err
+		}
+		if err := writeCookieStore(path, rotated); err != nil {
+			return err
+		}
+		log("Cookie store re-encrypted; set YTSUMMARY_SECRET_KEY to the new passphrase")
+		return nil
+	}
+
+	// Seal with the new key before replacing the old one, so a failure leaves
+	// the store readable
+	keyPath, err := secretKeyFilePath()
+	if err != nil {
+		return err
+	}
+	newKeyPath := keyPath + ".new"
+	key, err := writeNewKeyFile(newKeyPath)
+	if err != nil {
+		return err
+	}
+	rotated, err := sealCookies(jar, keySourceFile, key, nil)
+	if err != nil {
+		return err
+	}
+	if err := writeCookieStore(path+".rotated", rotated); err != nil {
+		return err
+	}
+	if err := os.Rename(newKeyPath, keyPath); err != nil {
+		return fmt.Errorf("failed to replace secret key: %w", err)
+	}
+	if err := os.Rename(path+".rotated", path); err != nil {
+		return fmt.Errorf("failed to replace cookie store: %w", err)
+	}
+	log("Cookie store re-encrypted with a new key in %s", keyPath)
+	return nil
+}
+
+// runAuthClear deletes the cookie store
+func runAuthClear(cmd *cobra.Command, args []string) error {
+	path, _ := cookieStoreFilePath()
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete cookie store: %w", err)
+	}
+	log("Cookies cleared")
+	return nil
+}
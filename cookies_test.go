@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cookiesTxt builds a cookies.txt export whose YouTube cookies expire at
+// expires
+func cookiesTxt(expires time.Time) string {
+	return fmt.Sprintf(`# Netscape HTTP Cookie File
+# This is a generated file! Do not edit.
+
+.youtube.com	TRUE	/	TRUE	%[1]d	SID	sid-value
+#HttpOnly_.youtube.com	TRUE	/	TRUE	%[1]d	__Secure-3PSID	secure-value
+www.youtube.com	FALSE	/api	FALSE	0	PREF	f6=40000000
+.example.com	TRUE	/	FALSE	%[1]d	tracker	x
+`, expires.Unix())
+}
+
+// newCookieTest points the cache directory and secret key at a temporary
+// directory
+func newCookieTest(t *testing.T) string {
+	dir := t.TempDir()
+	cacheDir = dir
+	t.Setenv("YTSUMMARY_COOKIE_STORE", "")
+	t.Setenv("YTSUMMARY_SECRET_KEY", "")
+	t.Setenv("YTSUMMARY_SECRET_KEY_FILE", filepath.Join(dir, "keys", "secret.key"))
+	return dir
+}
+
+func TestParseCookiesTxt(t *testing.T) {
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	cookies, skipped, err := parseCookiesTxt([]byte(cookiesTxt(expires)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 3 || skipped != 1 {
+		t.Fatalf("got %d cookies, %d skipped; want 3, 1", len(cookies), skipped)
+	}
+	if c := cookies[1]; c.Name != "__Secure-3PSID" || !c.Secure || !c.Expires.Equal(expires) {
+		t.Errorf("HttpOnly cookie = %+v", c)
+	}
+	if !cookies[2].Expires.IsZero() {
+		t.Errorf("session cookie expires %v", cookies[2].Expires)
+	}
+
+	if _, _, err := parseCookiesTxt([]byte("SID=abc; HSID=def")); err == nil {
+		t.Error("Cookie header accepted as cookies.txt")
+	}
+}
+
+func TestCookieJarHeader(t *testing.T) {
+	now := time.Now()
+	cookies, _, _ := parseCookiesTxt([]byte(cookiesTxt(now.Add(time.Hour))))
+	jar := &cookieJar{Cookies: cookies}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.youtube.com/youtubei/v1/player", "SID=sid-value; __Secure-3PSID=secure-value"},
+		{"https://www.youtube.com/api/timedtext?v=x", "SID=sid-value; __Secure-3PSID=secure-value; PREF=f6=40000000"},
+		{"http://www.youtube.com/api/timedtext", "PREF=f6=40000000"},
+		{"https://m.youtube.com/api/timedtext", "SID=sid-value; __Secure-3PSID=secure-value"},
+		{"https://www.example.com/", ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := jar.header(u, now); got != tt.want {
+			t.Errorf("header(%s) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+
+	u, _ := url.Parse("https://www.youtube.com/api/timedtext")
+	if got := jar.header(u, now.Add(2*time.Hour)); got != "PREF=f6=40000000" {
+		t.Errorf("after expiry: header = %q, want only the session cookie", got)
+	}
+}
+
+func TestCookieExpiryWarning(t *testing.T) {
+	now := time.Now()
+	cookies, _, _ := parseCookiesTxt([]byte(cookiesTxt(now.Add(30 * 24 * time.Hour))))
+	jar := &cookieJar{Cookies: cookies}
+	if msg := jar.expiryWarning(now); msg != "" {
+		t.Errorf("fresh cookies: warning %q", msg)
+	}
+	if msg := jar.expiryWarning(now.Add(25 * 24 * time.Hour)); !strings.Contains(msg, "expire on") {
+		t.Errorf("expiring cookies: warning %q", msg)
+	}
+	if msg := jar.expiryWarning(now.Add(31 * 24 * time.Hour)); !strings.HasPrefix(msg, "2 of 3") {
+		t.Errorf("expired cookies: warning %q", msg)
+	}
+}
+
+func TestCookieStore(t *testing.T) {
+	dir := newCookieTest(t)
+	export := filepath.Join(dir, "cookies.txt")
+	os.WriteFile(export, []byte(cookiesTxt(time.Now().Add(30*24*time.Hour))), 0600)
+
+	if err := runAuthImportCookies(nil, []string{export}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "cookies.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sid-value") {
+		t.Error("cookie store holds plaintext cookies")
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "keys", "secret.key")); info == nil || info.Mode().Perm() != 0600 {
+		t.Errorf("secret key file = %v, want mode 0600", info)
+	}
+
+	req, _ := http.NewRequest("GET", "https://www.youtube.com/api/timedtext?v=x", nil)
+	if err := applyCookies(req); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Cookie"); !strings.HasPrefix(got, "SID=sid-value;") {
+		t.Errorf("Cookie = %q", got)
+	}
+
+	// Rotating the key keeps the cookies readable with the new key only
+	oldKey, _ := os.ReadFile(filepath.Join(dir, "keys", "secret.key"))
+	if err := runAuthRotateKey(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	newKey, _ := os.ReadFile(filepath.Join(dir, "keys", "secret.key"))
+	if string(newKey) == string(oldKey) {
+		t.Error("key not rotated")
+	}
+	f, err := readCookieStore(filepath.Join(dir, "cookies.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jar, err := openCookies(f); err != nil || len(jar.Cookies) != 3 {
+		t.Fatalf("after rotation: %v cookies, %v", jar, err)
+	}
+	os.WriteFile(filepath.Join(dir, "keys", "secret.key"), oldKey, 0600)
+	if _, err := openCookies(f); err == nil {
+		t.Error("store opened with the old key")
+	}
+
+	if err := runAuthClear(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if jar, err := storedCookies(); jar != nil || err != nil {
+		t.Errorf("after clear: %v, %v", jar, err)
+	}
+}
+
+func TestCookieStorePassphrase(t *testing.T) {
+	newCookieTest(t)
+	t.Setenv("YTSUMMARY_SECRET_KEY", "correct horse battery staple")
+	cookies, _, _ := parseCookiesTxt([]byte(cookiesTxt(time.Now().Add(time.Hour))))
+
+	f, err := sealCookiesWithConfiguredKey(&cookieJar{Cookies: cookies})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.KeySource != keySourcePassphrase || len(f.Salt) == 0 {
+		t.Fatalf("store = %+v, want a salted passphrase key", f)
+	}
+	if jar, err := openCookies(f); err != nil || len(jar.Cookies) != 3 {
+		t.Errorf("openCookies = %v, %v", jar, err)
+	}
+
+	t.Setenv("YTSUMMARY_SECRET_KEY", "wrong")
+	if _, err := openCookies(f); err == nil {
+		t.Error("store opened with the wrong passphrase")
+	}
+	t.Setenv("YTSUMMARY_SECRET_KEY", "")
+	if _, err := openCookies(f); err == nil || !strings.Contains(err.Error(), "YTSUMMARY_SECRET_KEY") {
+		t.Errorf("without a passphrase: %v", err)
+	}
+}
+
+func TestCookieStoreBindsHeader(t *testing.T) {
+	newCookieTest(t)
+	cookies, _, _ := parseCookiesTxt([]byte(cookiesTxt(time.Now().Add(time.Hour))))
+	f, err := sealCookiesWithConfiguredKey(&cookieJar{Cookies: cookies})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openCookies(f); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key file ignores the salt, so only the additional data catches this
+	tampered := *f
+	tampered.Salt = []byte("salt")
+	if _, err := openCookies(&tampered); err == nil {
+		t.Error("store opened with a changed header")
+	}
+	old := *f
+	old.Version = 1
+	if _, err := openCookies(&old); err == nil || !strings.Contains(err.Error(), "import-cookies") {
+		t.Errorf("version 1 store: %v", err)
+	}
+}
+
+func TestCookieStoreNeedsKeyOutsideCache(t *testing.T) {
+	dir := newCookieTest(t)
+	t.Setenv("YTSUMMARY_SECRET_KEY_FILE", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "")
+	cookies, _, _ := parseCookiesTxt([]byte(cookiesTxt(time.Now().Add(time.Hour))))
+
+	if _, err := sealCookiesWithConfiguredKey(&cookieJar{Cookies: cookies}); err == nil || !strings.Contains(err.Error(), "YTSUMMARY_SECRET_KEY_FILE") {
+		t.Errorf("without a config directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secret.key")); err == nil {
+		t.Error("secret key written to the cache directory")
+	}
+}
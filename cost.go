@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrBudgetExceeded is returned when an LLM call would push spending past --max-cost
+const ErrBudgetExceeded = "budget_exceeded"
+
+var llmMaxCost string
+
+// estimateTokens approximates a token count (1 token ≈ 4 characters)
+func estimateTokens(text string) int {
+	return len(text) / 4
+}
+
+// budgetError is an LLM call refused because it could exceed the spend limit
+type budgetError struct {
+	Model    string
+	Limit    float64
+	Spent    float64
+	Estimate float64
+}
+
+func (e *budgetError) Error() string {
+	return fmt.Sprintf("LLM call to %s would cost up to $%.4f, exceeding the $%.2f budget ($%.4f already spent). Raise --max-cost (YTSUMMARY_MAX_COST), use a cheaper model, or summarize part of the video with --from/--to",
+		e.Model, e.Estimate, e.Limit, e.Spent)
+}
+
+// costBudget tracks estimated spend against a limit. The CLI shares one across
+// the whole invocation; the server makes one per request.
+type costBudget struct {
+	mu    sync.Mutex
+	limit float64
+	spent float64
+}
+
+func newCostBudget(limit float64) *costBudget {
+	return &costBudget{limit: limit}
+}
+
+// reserve checks that a call's worst-case cost fits in what's left of the budget
+func (b *costBudget) reserve(model string, promptTokens, maxCompletionTokens int) error {
+	table, err := loadPricing()
+	if err != nil {
+		return err
+	}
+	price, ok := table.price(model)
+	if !ok {
+		return fmt.Errorf("no pricing known for model %s, so --max-cost can't be enforced (see 'ytsummary models pricing sync')", model)
+	}
+	estimate := price.cost(promptTokens, maxCompletionTokens)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.spent+estimate > b.limit {
+		return &budgetError{Model: model, Limit: b.limit, Spent: b.spent, Estimate: estimate}
+	}
+	return nil
+}
+
+// charge adds a finished call's estimated cost
+func (b *costBudget) charge(model string, promptTokens, completionTokens int) {
+	cost, _ := estimateCost(model, promptTokens, completionTokens)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent += cost
+}
+
+// Spent returns the estimated spend so far
+func (b *costBudget) Spent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
+
+// budgetedClient refuses LLM calls that would exceed the budget
+type budgetedClient struct {
+	LLMClient
+	budget *costBudget
+}
+
+func (c *budgetedClient) Complete(systemPrompt, text string, params GenerationParams) (string, error) {
+	promptTokens := estimateTokens(systemPrompt) + estimateTokens(text)
+	maxCompletion := params.MaxTokens
+	if maxCompletion == 0 {
+		maxCompletion = defaultMaxTokens
+		if isReasoningModel(c.Model()) {
+			maxCompletion = defaultReasoningMaxTokens
+		}
+	}
+	if err := c.budget.reserve(c.Model(), promptTokens, maxCompletion); err != nil {
+		return "", err
+	}
+
+	reply, err := c.LLMClient.Complete(systemPrompt, text, params)
+	if err != nil {
+		return "", err
+	}
+	c.budget.charge(c.Model(), promptTokens, estimateTokens(reply))
+	return reply, nil
+}
+
+// parseMaxCost parses a --max-cost value in USD; "" means no limit
+func parseMaxCost(v string) (float64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	limit, err := strconv.ParseFloat(strings.TrimPrefix(v, "$"), 64)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("invalid max cost %q (use a dollar amount such as 0.50)", v)
+	}
+	return limit, nil
+}
+
+var (
+	invocationBudgetOnce sync.Once
+	invocationBudgetVal  *costBudget
+	invocationBudgetErr  error
+)
+
+// invocationBudget returns the budget from --max-cost (YTSUMMARY_MAX_COST),
+// shared by every LLM call in this run, or nil when there is no limit
+func invocationBudget() (*costBudget, error) {
+	invocationBudgetOnce.Do(func() {
+		limit, err := parseMaxCost(getConfig(llmMaxCost, "YTSUMMARY_MAX_COST"))
+		if err != nil {
+			invocationBudgetErr = err
+		} else if limit > 0 {
+			invocationBudgetVal = newCostBudget(limit)
+		}
+	})
+	return invocationBudgetVal, invocationBudgetErr
+}
+
+// requestBudget returns the budget for one API request: its max_cost, else
+// the server's YTSUMMARY_MAX_COST, or nil when there is no limit
+func requestBudget(maxCost float64) (*costBudget, error) {
+	if maxCost > 0 {
+		return newCostBudget(maxCost), nil
+	}
+	limit, err := parseMaxCost(getConfig(llmMaxCost, "YTSUMMARY_MAX_COST"))
+	if err != nil || limit == 0 {
+		return nil, err
+	}
+	return newCostBudget(limit), nil
+}
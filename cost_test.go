@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseMaxCost(t *testing.T) {
+	if limit, err := parseMaxCost("$0.50"); err != nil || limit != 0.5 {
+		t.Errorf("parseMaxCost($0.50) = %v, %v", limit, err)
+	}
+	if limit, err := parseMaxCost(""); err != nil || limit != 0 {
+		t.Errorf("parseMaxCost(\"\") = %v, %v, want no limit", limit, err)
+	}
+	for _, v := range []string{"cheap", "-1", "0"} {
+		if _, err := parseMaxCost(v); err == nil {
+			t.Errorf("parseMaxCost(%q): expected error", v)
+		}
+	}
+}
+
+// pricedLLMClient is a fake provider billed as model, or gpt-4o if unset
+type pricedLLMClient struct {
+	recordingLLMClient
+	model string
+}
+
+func (c *pricedLLMClient) Model() string {
+	if c.model == "" {
+		return "openai/gpt-4o"
+	}
+	return c.model
+}
+
+func TestBudgetedClient(t *testing.T) {
+	inner := &pricedLLMClient{recordingLLMClient: recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}}}
+	// A 4000-character prompt is ~1000 tokens ($0.0025) plus up to 2000
+	// completion tokens ($0.02)
+	budget := newCostBudget(0.03)
+	client := &budgetedClient{LLMClient: inner, budget: budget}
+	text := strings.Repeat("Word. ", 666)
+
+	if _, err := client.Complete("", text, GenerationParams{}); err != nil {
+		t.Fatalf("first call error = %v, want it within budget", err)
+	}
+	if budget.Spent() <= 0 {
+		t.Error("Spent() = 0, want the call charged")
+	}
+
+	// The worst case of a second call would exceed the limit
+	_, err := client.Complete("", text, GenerationParams{MaxTokens: 4000})
+	var budgetErr *budgetError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("second call error = %v, want a budget error", err)
+	}
+	if len(inner.calls) != 1 {
+		t.Errorf("provider saw %d calls, want the refused call not sent", len(inner.calls))
+	}
+	if llmErrorClass(err) != ErrBudgetExceeded {
+		t.Errorf("llmErrorClass() = %q, want %q", llmErrorClass(err), ErrBudgetExceeded)
+	}
+
+	// Unpriced models can't be budgeted
+	unpriced := &budgetedClient{LLMClient: &pricedLLMClient{model: "local/llama-custom"}, budget: newCostBudget(1)}
+	if _, err := unpriced.Complete("", "text", GenerationParams{}); err == nil {
+		t.Error("expected error for a model without pricing")
+	}
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Google OAuth client and saved login
+	oauthClientID     string
+	oauthClientSecret string
+	oauthTokenFile    string
+
+	creatorOutDir   string
+	creatorLimit    int
+	creatorTemplate string
+	creatorChapters bool
+)
+
+// youtubeDataAPIURL is the YouTube Data API v3; overridden by tests to point
+// at a fake server
+var youtubeDataAPIURL = "https://www.googleapis.com/youtube/v3"
+
+// creatorDescriptionTemplate is the prompt 'creator batch' uses by default
+const creatorDescriptionTemplate = "description"
+
+// trackKindASR marks the Data API's auto-generated caption tracks
+const trackKindASR = "asr"
+
+// youtubeDataClient calls the YouTube Data API as the logged-in creator
+type youtubeDataClient struct {
+	cfg      oauthConfig
+	token    *oauthToken
+	listings *listingCache // nil lists the channel afresh every time
+}
+
+// newCreatorClient returns a client for the creator who ran 'creator login',
+// caching the channel's listings in store
+func newCreatorClient(store ListingStore) (*youtubeDataClient, error) {
+	cfg, err := oauthConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	listings, err := newListingCache(store)
+	if err != nil {
+		return nil, err
+	}
+	token, err := loadOAuthToken(cfg.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+	return &youtubeDataClient{cfg: cfg, token: token, listings: listings}, nil
+}
+
+// dataAPIErrorBody is the Data API's error reply
+type dataAPIErrorBody struct {
+	Error struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Reason string `json:"reason"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// dataAPIError describes a failed Data API response
+func dataAPIError(resp *http.Response) error {
+	var body dataAPIErrorBody
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+	reason := http.StatusText(resp.StatusCode)
+	if len(body.Error.Errors) > 0 {
+		reason = body.Error.Errors[0].Reason
+	}
+	return fmt.Errorf("YouTube Data API error (HTTP %d, %s): %s", resp.StatusCode, reason, redact(body.Error.Message))
+}
+
+// get requests an API resource, returning the response for a 200 OK
+func (c *youtubeDataClient) get(resource string, params url.Values) (*http.Response, error) {
+	resp, err := authorizedRequest(c.cfg, c.token, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, youtubeDataAPIURL+"/"+resource+"?"+params.Encode(), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, dataAPIError(resp)
+	}
+	return resp, nil
+}
+
+// getJSON requests an API resource and decodes it into v
+func (c *youtubeDataClient) getJSON(resource string, params url.Values, v any) error {
+	resp, err := c.get(resource, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", resource, err)
+	}
+	return nil
+}
+
+// creatorChannel is the logged-in creator's channel
+type creatorChannel struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Uploads string `json:"uploads"` // playlist of every upload, including unlisted and private ones
+}
+
+// myChannel returns the channel the creator logged in with. It is cached by
+// token file, which holds one login.
+func (c *youtubeDataClient) myChannel() (*creatorChannel, error) {
+	var cached creatorChannel
+	if found, _ := c.listings.get(listingChannel, c.cfg.TokenFile, &cached); found {
+		return &cached, nil
+	}
+
+	var resp struct {
+		Items []struct {
+			ID      string `json:"id"`
+			Snippet struct {
+				Title string `json:"title"`
+			} `json:"snippet"`
+			ContentDetails struct {
+				RelatedPlaylists struct {
+					Uploads string `json:"uploads"`
+				} `json:"relatedPlaylists"`
+			} `json:"contentDetails"`
+		} `json:"items"`
+	}
+	if err := c.getJSON("channels", url.Values{"part": {"snippet,contentDetails"}, "mine": {"true"}}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Items) == 0 {
+		return nil, fmt.Errorf("the logged-in Google account has no YouTube channel")
+	}
+	item := resp.Items[0]
+	channel := &creatorChannel{ID: item.ID, Title: item.Snippet.Title, Uploads: item.ContentDetails.RelatedPlaylists.Uploads}
+	c.listings.put(listingChannel, c.cfg.TokenFile, channel, true)
+	return channel, nil
+}
+
+// creatorUpload is one of the creator's videos
+type creatorUpload struct {
+	VideoID     string    `json:"video_id"`
+	Title       string    `json:"title"`
+	Privacy     string    `json:"privacy"` // public, unlisted or private
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// myUploads returns up to limit of the creator's uploads, newest first; 0
+// means all of them. A cached listing is used when it has enough uploads;
+// one cut short at an earlier, smaller limit is listed again.
+func (c *youtubeDataClient) myUploads(limit int) ([]creatorUpload, error) {
+	channel, err := c.myChannel()
+	if err != nil {
+		return nil, err
+	}
+
+	var cached []creatorUpload
+	if found, complete := c.listings.get(listingPlaylistItems, channel.Uploads, &cached); found && (complete || limit > 0 && len(cached) >= limit) {
+		if limit > 0 && len(cached) > limit {
+			cached = cached[:limit]
+		}
+		return cached, nil
+	}
+
+	var uploads []creatorUpload
+	params := url.Values{"part": {"snippet,status,contentDetails"}, "playlistId": {channel.Uploads}, "maxResults": {"50"}}
+	for {
+		var page struct {
+			NextPageToken string `json:"nextPageToken"`
+			Items         []struct {
+				Snippet struct {
+					Title string `json:"title"`
+				} `json:"snippet"`
+				Status struct {
+					PrivacyStatus string `json:"privacyStatus"`
+				} `json:"status"`
+				ContentDetails struct {
+					VideoID          string    `json:"videoId"`
+					VideoPublishedAt time.Time `json:"videoPublishedAt"`
+				} `json:"contentDetails"`
+			} `json:"items"`
+		}
+		if err := c.getJSON("playlistItems", params, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			uploads = append(uploads, creatorUpload{
+				VideoID:     item.ContentDetails.VideoID,
+				Title:       item.Snippet.Title,
+				Privacy:     item.Status.PrivacyStatus,
+				PublishedAt: item.ContentDetails.VideoPublishedAt,
+			})
+			if len(uploads) == limit {
+				c.listings.put(listingPlaylistItems, channel.Uploads, uploads, false)
+				return uploads, nil
+			}
+		}
+		if page.NextPageToken == "" {
+			c.listings.put(listingPlaylistItems, channel.Uploads, uploads, true)
+			return uploads, nil
+		}
+		params.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// dataCaptionTrack is a caption track listed by the Data API
+type dataCaptionTrack struct {
+	ID      string `json:"id"`
+	Snippet struct {
+		Language  string `json:"language"`
+		TrackKind string `json:"trackKind"` // standard, asr or forced
+		IsDraft   bool   `json:"isDraft"`
+	} `json:"snippet"`
+}
+
+// pickOfficialTrack chooses the published track for lang, preferring the
+// creator's own captions over speech recognition, or the first published
+// track when none is in lang
+func pickOfficialTrack(tracks []dataCaptionTrack, lang string) *dataCaptionTrack {
+	var best *dataCaptionTrack
+	for i := range tracks {
+		t := &tracks[i]
+		if t.Snippet.IsDraft {
+			continue
+		}
+		switch {
+		case best == nil:
+			best = t
+		case baseLanguage(t.Snippet.Language) == baseLanguage(lang) && baseLanguage(best.Snippet.Language) != baseLanguage(lang):
+			best = t
+		case baseLanguage(t.Snippet.Language) == baseLanguage(best.Snippet.Language) && best.Snippet.TrackKind == trackKindASR && t.Snippet.TrackKind != trackKindASR:
+			best = t
+		}
+	}
+	return best
+}
+
+// officialCaptions downloads a video's captions through the captions API,
+// which works for the creator's unlisted and private videos too
+func (c *youtubeDataClient) officialCaptions(videoID, lang string) (*FetchResult, error) {
+	var list struct {
+		Items []dataCaptionTrack `json:"items"`
+	}
+	if err := c.getJSON("captions", url.Values{"part": {"snippet"}, "videoId": {videoID}}, &list); err != nil {
+		return nil, err
+	}
+	track := pickOfficialTrack(list.Items, lang)
+	if track == nil {
+		return nil, fmt.Errorf("no subtitles available for this video")
+	}
+
+	resp, err := c.get("captions/"+track.ID, url.Values{"tfmt": {"srt"}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download captions: %w", err)
+	}
+	text, _, err := decodeText(body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	segments := parseSRTSegments(text, track.Snippet.TrackKind == trackKindASR)
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no subtitles available for this video")
+	}
+	return &FetchResult{
+		VideoID:       videoID,
+		Transcript:    segmentsText(segments),
+		Segments:      segments,
+		Language:      track.Snippet.Language,
+		AutoGenerated: track.Snippet.TrackKind == trackKindASR,
+	}, nil
+}
+
+// runCreatorLogin signs the creator in and saves the token
+func runCreatorLogin(cmd *cobra.Command, args []string) error {
+	cfg, err := oauthConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	token, err := oauthLogin(cmd.Context(), cfg, func(authURL string) {
+		log("Open this URL in your browser to let ytsummary read your channel:\n\n  %s\n", authURL)
+	})
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	if err := saveOAuthToken(cfg.TokenFile, token); err != nil {
+		return err
+	}
+
+	// The login may be to another account: list its channel afresh
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+	listings, err := newListingCache(cache)
+	if err != nil {
+		return err
+	}
+	listings.refresh = true
+	client := &youtubeDataClient{cfg: cfg, token: token, listings: listings}
+	channel, err := client.myChannel()
+	if err != nil {
+		return err
+	}
+	log("Logged in as %s (%s); token saved to %s", channel.Title, channel.ID, cfg.TokenFile)
+	return nil
+}
+
+// runCreatorUploads lists the creator's uploads
+func runCreatorUploads(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+	client, err := newCreatorClient(cache)
+	if err != nil {
+		return err
+	}
+	uploads, err := client.myUploads(creatorLimit)
+	if err != nil {
+		return err
+	}
+	for _, u := range uploads {
+		fmt.Printf("%s  %-8s  %s  %s\n", u.VideoID, u.Privacy, u.PublishedAt.Format(time.DateOnly), u.Title)
+	}
+	return nil
+}
+
+// runCreatorBatch writes a description (or summary) and optionally chapters
+// for each of the creator's uploads. Videos already written are skipped, so
+// an interrupted run picks up where it stopped.
+func runCreatorBatch(cmd *cobra.Command, args []string) error {
+	count, err := highlightCount(highlightsCount)
+	if err != nil {
+		return err
+	}
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+	client, err := newCreatorClient(cache)
+	if err != nil {
+		return err
+	}
+	llm, err := newLLMClient()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(creatorOutDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	uploads, err := client.myUploads(creatorLimit)
+	if err != nil {
+		return err
+	}
+	log("Writing %d uploads to %s...", len(uploads), creatorOutDir)
+	failed := 0
+	for i, upload := range uploads {
+		path := filepath.Join(creatorOutDir, upload.VideoID+".md")
+		if _, err := os.Stat(path); err == nil {
+			log("[%d/%d] %s already written, skipping", i+1, len(uploads), upload.VideoID)
+			continue
+		}
+		log("[%d/%d] %s (%s)", i+1, len(uploads), upload.Title, upload.Privacy)
+		if err := writeCreatorUpload(client, llm, cache, upload, path, count); err != nil {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s failed: %v\n", i+1, len(uploads), upload.VideoID, err)
+			failed++
+		}
+	}
+	log("Done! %d written, %d failed", len(uploads)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d uploads failed", failed, len(uploads))
+	}
+	return nil
+}
+
+// writeCreatorUpload fetches one upload's official captions, caches them and
+// writes its description, plus chapters with --chapters
+func writeCreatorUpload(client *youtubeDataClient, llm LLMClient, cache Cache, upload creatorUpload, path string, count int) error {
+	result, err := client.officialCaptions(upload.VideoID, language)
+	if err != nil {
+		return err
+	}
+	result.Title = upload.Title
+	if err := cacheFetchResult(cache, upload.VideoID, language, result); err != nil && !errors.Is(err, errCacheReadOnly) {
+		log("warning: failed to cache transcript: %v", err)
+	}
+	entry := result.cacheEntry(upload.VideoID, language)
+	if !keepNonSpeech {
+		stripCaptionArtifacts(entry)
+	}
+
+	budget, err := invocationBudget()
+	if err != nil {
+		return err
+	}
+	opts := SummaryOptions{
+		Template:    creatorTemplate,
+		Vars:        promptVarsFromEntry(entry, summaryLanguage(language)),
+		Checkpoints: cache,
+		Budget:      budget,
+	}
+	summary, err := summarizeWith(llm, entry.Transcript, opts)
+	if err != nil {
+		return fmt.Errorf("failed to summarize: %w", err)
+	}
+	body := formatSummaryMarkdown(entry, summary)
+
+	if creatorChapters {
+		summarizeFn := func(transcript string, opts SummaryOptions) (string, error) {
+			return summarizeWith(llm, transcript, opts)
+		}
+		moments, err := extractHighlights(summarizeFn, entry.Segments, count, opts)
+		if err != nil {
+			return fmt.Errorf("failed to pick chapters: %w", err)
+		}
+		chapters, n := youtubeChapters(moments)
+		if warning := chaptersWarning(n); warning != "" {
+			log("Warning: %s", warning)
+		}
+		body += "\n## Chapters\n\n" + chapters + "\n"
+	}
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// creatorSRT is the official English track of the fake creator's videos
+const creatorSRT = `1
+00:00:00,000 --> 00:00:04,000
+Welcome back to the channel.
+
+2
+00:00:04,000 --> 00:00:09,500
+Today we're baking <i>sourdough</i>.
+
+3
+00:00:12,000 --> 00:00:20,000
+First, feed the starter.
+`
+
+// newFakeGoogle serves Google's token endpoint and the parts of the YouTube
+// Data API creator commands use, for a channel with three uploads over two
+// pages. Only "fresh-token" is accepted as an access token.
+func newFakeGoogle(t *testing.T) (cfg oauthConfig, refreshes *int) {
+	t.Helper()
+	refreshes = new(int)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch {
+		case r.Form.Get("client_secret") != "secret":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client"}`)
+		case r.Form.Get("grant_type") == "refresh_token" && r.Form.Get("refresh_token") == "refresh-token":
+			*refreshes++
+			fmt.Fprint(w, `{"access_token": "fresh-token", "expires_in": 3600}`)
+		case r.Form.Get("grant_type") == "authorization_code" && r.Form.Get("code") == "the-code":
+			// The verifier must hash to the challenge the login URL carried
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if base64.RawURLEncoding.EncodeToString(sum[:]) != loginChallenge {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error": "invalid_grant"}`)
+				return
+			}
+			fmt.Fprint(w, `{"access_token": "fresh-token", "refresh_token": "refresh-token", "expires_in": 3600}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_grant"}`)
+		}
+	})
+	api := func(pattern string, handler http.HandlerFunc) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer fresh-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"error": {"message": "Invalid Credentials", "errors": [{"reason": "authError"}]}}`)
+				return
+			}
+			handler(w, r)
+		})
+	}
+	api("GET /youtube/v3/channels", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"items": [{"id": "UCbaker", "snippet": {"title": "Bakes"}, "contentDetails": {"relatedPlaylists": {"uploads": "UUbaker"}}}]}`)
+	})
+	api("GET /youtube/v3/playlistItems", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"nextPageToken": "p2", "items": [
+				{"snippet": {"title": "Sourdough"}, "status": {"privacyStatus": "unlisted"}, "contentDetails": {"videoId": "bakeVideo01", "videoPublishedAt": "2026-10-01T12:00:00Z"}},
+				{"snippet": {"title": "Rye"}, "status": {"privacyStatus": "public"}, "contentDetails": {"videoId": "bakeVideo02", "videoPublishedAt": "2026-09-01T12:00:00Z"}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"items": [{"snippet": {"title": "Silent"}, "status": {"privacyStatus": "private"}, "contentDetails": {"videoId": "noCaptions1"}}]}`)
+	})
+	api("GET /youtube/v3/captions", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("videoId") == "noCaptions1" {
+			fmt.Fprint(w, `{"items": []}`)
+			return
+		}
+		fmt.Fprint(w, `{"items": [
+			{"id": "asr-en", "snippet": {"language": "en", "trackKind": "asr"}},
+			{"id": "draft-en", "snippet": {"language": "en", "trackKind": "standard", "isDraft": true}},
+			{"id": "std-en", "snippet": {"language": "en", "trackKind": "standard"}},
+			{"id": "std-de", "snippet": {"language": "de", "trackKind": "standard"}}]}`)
+	})
+	api("GET /youtube/v3/captions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "std-en" || r.URL.Query().Get("tfmt") != "srt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, creatorSRT)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	oldToken, oldAuth, oldAPI := googleTokenURL, googleAuthURL, youtubeDataAPIURL
+	googleTokenURL, googleAuthURL, youtubeDataAPIURL = srv.URL+"/token", srv.URL+"/auth", srv.URL+"/youtube/v3"
+	t.Cleanup(func() { googleTokenURL, googleAuthURL, youtubeDataAPIURL = oldToken, oldAuth, oldAPI })
+
+	return oauthConfig{ClientID: "client", ClientSecret: "secret", TokenFile: filepath.Join(t.TempDir(), "youtube-oauth.json")}, refreshes
+}
+
+// loginChallenge is the PKCE challenge of the last login URL the test opened
+var loginChallenge string
+
+func TestOAuthLogin(t *testing.T) {
+	cfg, _ := newFakeGoogle(t)
+
+	// The "browser" consents and follows the redirect back to ytsummary
+	token, err := oauthLogin(context.Background(), cfg, func(authURL string) {
+		u, _ := url.Parse(authURL)
+		q := u.Query()
+		if q.Get("scope") != youtubeOAuthScope || q.Get("access_type") != "offline" || q.Get("code_challenge_method") != "S256" {
+			t.Errorf("auth URL = %s", authURL)
+		}
+		loginChallenge = q.Get("code_challenge")
+		go func() {
+			resp, err := http.Get(q.Get("redirect_uri") + "?state=" + q.Get("state") + "&code=the-code")
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	})
+	if err != nil {
+		t.Fatalf("oauthLogin() error = %v", err)
+	}
+	if token.AccessToken != "fresh-token" || token.RefreshToken != "refresh-token" || token.expired() {
+		t.Errorf("token = %+v", token)
+	}
+
+	_, err = oauthLogin(context.Background(), cfg, func(authURL string) {
+		u, _ := url.Parse(authURL)
+		go http.Get(u.Query().Get("redirect_uri") + "?state=" + u.Query().Get("state") + "&error=access_denied")
+	})
+	if err == nil || !strings.Contains(err.Error(), "access_denied") {
+		t.Errorf("denied login error = %v", err)
+	}
+}
+
+func TestCreatorUploadsAndCaptions(t *testing.T) {
+	cfg, refreshes := newFakeGoogle(t)
+	if _, err := loadOAuthToken(cfg.TokenFile); err != errNotLoggedIn {
+		t.Fatalf("loadOAuthToken() before login = %v, want errNotLoggedIn", err)
+	}
+
+	// An expired token is refreshed, and the refreshed one saved
+	client := &youtubeDataClient{cfg: cfg, token: &oauthToken{AccessToken: "stale", RefreshToken: "refresh-token", Expiry: time.Now().Add(-time.Hour)}}
+	uploads, err := client.myUploads(0)
+	if err != nil {
+		t.Fatalf("myUploads() error = %v", err)
+	}
+	if len(uploads) != 3 || uploads[0].VideoID != "bakeVideo01" || uploads[0].Privacy != "unlisted" || uploads[2].Privacy != "private" {
+		t.Errorf("uploads = %+v", uploads)
+	}
+	saved, err := loadOAuthToken(cfg.TokenFile)
+	if err != nil || saved.AccessToken != "fresh-token" || saved.RefreshToken != "refresh-token" || *refreshes != 1 {
+		t.Errorf("saved token = %+v, %v after %d refreshes", saved, err, *refreshes)
+	}
+	if info, err := os.Stat(cfg.TokenFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("token file mode = %v, %v", info.Mode(), err)
+	}
+	if uploads, _ := client.myUploads(1); len(uploads) != 1 {
+		t.Errorf("myUploads(1) = %+v", uploads)
+	}
+
+	// A token the API rejects is refreshed once and the request retried
+	client.token.AccessToken = "revoked"
+	result, err := client.officialCaptions("bakeVideo01", "en-US")
+	if err != nil {
+		t.Fatalf("officialCaptions() error = %v", err)
+	}
+	if *refreshes != 2 {
+		t.Errorf("refreshes = %d, want 2", *refreshes)
+	}
+	if result.Transcript != "Welcome back to the channel. Today we're baking sourdough. First, feed the starter." || len(result.Segments) != 3 || result.AutoGenerated {
+		t.Errorf("result = %+v", result)
+	}
+	if seg := result.Segments[2]; seg.Start != 12 || seg.Duration != 8 {
+		t.Errorf("segment = %+v", seg)
+	}
+
+	if _, err := client.officialCaptions("noCaptions1", "en"); err == nil || fetchErrorClass(err) != ErrNoCaptions {
+		t.Errorf("no captions error = %v", err)
+	}
+	client.cfg.ClientSecret = "wrong"
+	client.token.AccessToken = "revoked"
+	if _, err := client.myChannel(); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("failed refresh error = %v", err)
+	}
+}
+
+func TestPickOfficialTrack(t *testing.T) {
+	track := func(id, lang, kind string) dataCaptionTrack {
+		var tr dataCaptionTrack
+		tr.ID, tr.Snippet.Language, tr.Snippet.TrackKind = id, lang, kind
+		return tr
+	}
+	tracks := []dataCaptionTrack{track("de", "de", "standard"), track("en-asr", "en", "asr"), track("en", "en-GB", "standard")}
+	if got := pickOfficialTrack(tracks, "en"); got.ID != "en" {
+		t.Errorf("en = %s", got.ID)
+	}
+	if got := pickOfficialTrack(tracks, "fr"); got.ID != "de" {
+		t.Errorf("fr = %s, want the first track", got.ID)
+	}
+	if got := pickOfficialTrack(nil, "en"); got != nil {
+		t.Errorf("no tracks = %+v", got)
+	}
+}
+
+func TestRunCreatorBatch(t *testing.T) {
+	cfg, _ := newFakeGoogle(t)
+	if err := saveOAuthToken(cfg.TokenFile, &oauthToken{AccessToken: "fresh-token", RefreshToken: "refresh-token", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("YTSUMMARY_OAUTH_CLIENT_ID", cfg.ClientID)
+	t.Setenv("YTSUMMARY_OAUTH_CLIENT_SECRET", cfg.ClientSecret)
+	t.Setenv("YTSUMMARY_OAUTH_TOKEN_FILE", cfg.TokenFile)
+	cacheDir = t.TempDir()
+	llmProvider = "fake"
+	creatorOutDir = t.TempDir()
+	creatorLimit, creatorTemplate, creatorChapters, highlightsCount = 2, creatorDescriptionTemplate, true, 3
+	language = defaultLanguage
+	t.Cleanup(func() {
+		llmProvider = ""
+		creatorChapters = false
+	})
+
+	if err := runCreatorBatch(nil, nil); err != nil {
+		t.Fatalf("runCreatorBatch() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(creatorOutDir, "bakeVideo01.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "## Chapters\n\n00:00 Welcome back to the channel\n00:12 First, feed the starter\n"; !strings.HasPrefix(string(data), "# Sourdough\n") || !strings.HasSuffix(string(data), want) {
+		t.Errorf("bakeVideo01.md = %q", data)
+	}
+
+	// The captions were cached for later summarize runs
+	cache, _ := openCache()
+	defer cache.Close()
+	if entry, err := cache.GetTranscript("bakeVideo01", "en"); err != nil || len(entry.Segments) != 3 || entry.Title != "Sourdough" {
+		t.Errorf("cached entry = %+v, %v", entry, err)
+	}
+
+	// Written uploads are skipped; the private one without captions fails
+	os.WriteFile(filepath.Join(creatorOutDir, "bakeVideo01.md"), []byte("edited"), 0644)
+	creatorLimit = 0
+	if err := runCreatorBatch(nil, nil); err == nil || !strings.Contains(err.Error(), "1 of 3 uploads failed") {
+		t.Errorf("second run error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(creatorOutDir, "bakeVideo01.md")); string(data) != "edited" {
+		t.Errorf("written upload was overwritten: %q", data)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How much history the dashboard keeps
+const (
+	dashboardRecentErrors  = 20
+	dashboardLLMLatencies  = 60
+	dashboardThrottleLimit = 1 // clients with fewer tokens than this are throttled
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"ms": func(d time.Duration) string { return fmt.Sprintf("%dms", d.Milliseconds()) },
+}).Parse(dashboardHTML))
+
+// inFlightRequest is a request the server is still handling
+type inFlightRequest struct {
+	Method string
+	Path   string
+	IP     string
+	Start  time.Time
+	ctx    *requestContext // video ID is filled in by the handler
+}
+
+// recentError is a request that ended in a 4xx or 5xx
+type recentError struct {
+	Time    time.Time
+	Method  string
+	Path    string
+	Status  int
+	VideoID string
+}
+
+// llmCall is one summarization, for the latency sparkline
+type llmCall struct {
+	Duration time.Duration
+	Failed   bool
+}
+
+// serverActivity records what the server is doing for /admin/dashboard
+type serverActivity struct {
+	mu       sync.Mutex
+	nextID   uint64
+	inFlight map[uint64]*inFlightRequest
+	errors   []recentError // newest last
+	llmCalls []llmCall     // newest last
+	panics   atomic.Int64  // recovered handler panics
+}
+
+func newServerActivity() *serverActivity {
+	return &serverActivity{inFlight: make(map[uint64]*inFlightRequest)}
+}
+
+// start registers an in-flight request and returns its ID for finish
+func (a *serverActivity) start(req *inFlightRequest) uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nextID++
+	a.inFlight[a.nextID] = req
+	return a.nextID
+}
+
+// finish removes a request, recording it if it failed
+func (a *serverActivity) finish(id uint64, status int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	req := a.inFlight[id]
+	delete(a.inFlight, id)
+	if req == nil || status < 400 {
+		return
+	}
+	a.errors = append(a.errors, recentError{
+		Time: time.Now(), Method: req.Method, Path: req.Path, Status: status, VideoID: req.ctx.video(),
+	})
+	if len(a.errors) > dashboardRecentErrors {
+		a.errors = a.errors[len(a.errors)-dashboardRecentErrors:]
+	}
+}
+
+// recordLLM adds a summarization's latency
+func (a *serverActivity) recordLLM(d time.Duration, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.llmCalls = append(a.llmCalls, llmCall{Duration: d, Failed: err != nil})
+	if len(a.llmCalls) > dashboardLLMLatencies {
+		a.llmCalls = a.llmCalls[len(a.llmCalls)-dashboardLLMLatencies:]
+	}
+}
+
+// middleware tracks requests while they run
+func (a *serverActivity) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := a.start(&inFlightRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			IP:     getClientIP(r),
+			Start:  time.Now(),
+			ctx:    getRequestContext(r),
+		})
+		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() { a.finish(id, wrapped.status) }()
+		next.ServeHTTP(wrapped, r)
+	})
+}
+
+// rateLimiterState summarizes the per-IP limiter
+type rateLimiterState struct {
+	PerMinute int
+	Burst     int
+	Clients   int  // IPs seen recently
+	Throttled int  // IPs out of tokens right now
+	Shared    bool // counted in Redis across servers; Clients and Throttled are this server's fallback
+}
+
+// state returns the limiter's current settings and load
+func (l *ipRateLimiter) state() rateLimiterState {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	st := rateLimiterState{PerMinute: int(float64(l.rate) * 60), Burst: l.burst, Clients: len(l.limiters), Shared: l.shared != nil}
+	for _, entry := range l.limiters {
+		if entry.limiter.Tokens() < dashboardThrottleLimit {
+			st.Throttled++
+		}
+	}
+	return st
+}
+
+// dashboardRequest is an in-flight request as shown on the dashboard
+type dashboardRequest struct {
+	Method  string
+	Path    string
+	IP      string
+	VideoID string
+	Age     time.Duration
+}
+
+// dashboardData is everything the dashboard template renders
+type dashboardData struct {
+	Build        BuildInfo
+	Now          time.Time
+	Uptime       time.Duration
+	InFlight     []dashboardRequest
+	Errors       []recentError // newest first
+	CacheEntries int
+	CacheError   string
+	DeadLetters  int
+	RateLimit    rateLimiterState
+	Panics       int64
+	LLMCalls     int
+	LLMFailures  int
+	LLMAvg       time.Duration
+	LLMMax       time.Duration
+	Sparkline    string // SVG polyline points
+}
+
+// snapshot gathers the dashboard's data
+func (s *Server) dashboardSnapshot() dashboardData {
+	now := time.Now()
+	data := dashboardData{
+		Build:     getBuildInfo(),
+		Now:       now,
+		Uptime:    now.Sub(s.startTime).Round(time.Second),
+		RateLimit: s.limiter.state(),
+		Panics:    s.activity.panics.Load(),
+	}
+
+	a := s.activity
+	a.mu.Lock()
+	for _, req := range a.inFlight {
+		data.InFlight = append(data.InFlight, dashboardRequest{
+			Method: req.Method, Path: req.Path, IP: req.IP, VideoID: req.ctx.video(), Age: now.Sub(req.Start),
+		})
+	}
+	for i := len(a.errors) - 1; i >= 0; i-- {
+		data.Errors = append(data.Errors, a.errors[i])
+	}
+	calls := append([]llmCall(nil), a.llmCalls...)
+	a.mu.Unlock()
+	sort.Slice(data.InFlight, func(i, j int) bool { return data.InFlight[i].Age > data.InFlight[j].Age })
+
+	var total time.Duration
+	for _, c := range calls {
+		total += c.Duration
+		data.LLMMax = max(data.LLMMax, c.Duration)
+		if c.Failed {
+			data.LLMFailures++
+		}
+	}
+	if data.LLMCalls = len(calls); data.LLMCalls > 0 {
+		data.LLMAvg = total / time.Duration(len(calls))
+	}
+	data.Sparkline = sparklinePoints(calls, data.LLMMax)
+
+	if count, err := s.cache.CountTranscripts(); err != nil {
+		data.CacheError = err.Error()
+	} else {
+		data.CacheEntries = count
+	}
+	if s.deadLetters != nil {
+		if letters, err := s.deadLetters.ListDeadLetters(false); err == nil {
+			data.DeadLetters = len(letters)
+		}
+	}
+	return data
+}
+
+// sparklinePoints scales latencies into a 300x40 SVG polyline
+func sparklinePoints(calls []llmCall, longest time.Duration) string {
+	if len(calls) == 0 || longest == 0 {
+		return ""
+	}
+	const width, height = 300.0, 40.0
+	step := width / float64(max(len(calls)-1, 1))
+	points := make([]string, len(calls))
+	for i, c := range calls {
+		y := height - float64(c.Duration)/float64(longest)*height
+		points[i] = fmt.Sprintf("%.1f,%.1f", float64(i)*step, y)
+	}
+	return strings.Join(points, " ")
+}
+
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", dashboardCSP)
+	w.Header().Set("Cache-Control", "no-store")
+	if err := dashboardTemplate.Execute(w, s.dashboardSnapshot()); err != nil {
+		logError("dashboard render failed", slog.String("error", err.Error()))
+	}
+}
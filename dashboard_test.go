@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboard(t *testing.T) {
+	s := newServer(ServerConfig{
+		APIKey: "secret",
+		Cache:  newTestCache(t),
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Injected", Transcript: "One. Two."}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			return "", errors.New("provider unavailable")
+		},
+	})
+	handler := s.Handler()
+
+	req := httptest.NewRequest("POST", "/summarize", bytes.NewBufferString(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`))
+	req.Header.Set("X-API-Key", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Browsers are asked for the key with Basic auth
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/admin/dashboard", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("status = %d, want 401 with a Basic auth challenge", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/admin/dashboard", nil)
+	req.SetBasicAuth("admin", "secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	html := w.Body.String()
+	for _, want := range []string{"POST /summarize", "dQw4w9WgXcQ", "1 failed", "<polyline"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("dashboard missing %q", want)
+		}
+	}
+}
+
+func TestServerActivityKeepsRecentHistory(t *testing.T) {
+	a := newServerActivity()
+	for i := 0; i < dashboardRecentErrors+5; i++ {
+		id := a.start(&inFlightRequest{Path: "/summarize", ctx: &requestContext{}})
+		a.finish(id, http.StatusBadGateway)
+		a.recordLLM(time.Millisecond, nil)
+	}
+	if len(a.inFlight) != 0 {
+		t.Errorf("%d requests still in flight", len(a.inFlight))
+	}
+	if len(a.errors) != dashboardRecentErrors {
+		t.Errorf("kept %d errors, want %d", len(a.errors), dashboardRecentErrors)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// deadLetterAfter is how many runs a video may fail before batch and prefetch
+// stop retrying it
+const deadLetterAfter = 3
+
+// deadLetterClasses are failures that say something about the video itself.
+// Rate limits, scrape breakage and LLM errors are retried indefinitely.
+var deadLetterClasses = []string{ErrNoCaptions, ErrVideoUnavailable, ErrAgeRestricted, ErrGeoRestricted}
+
+var jobsFailedAll bool
+
+// DeadLetter tracks a video's consecutive failures across batch and prefetch runs
+type DeadLetter struct {
+	VideoID       string    `json:"video_id"`
+	ErrorClass    string    `json:"error_class"`
+	Error         string    `json:"error"`
+	Attempts      int       `json:"attempts"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+	Suppressed    bool      `json:"suppressed"` // acknowledged; skipped without reporting a failure
+}
+
+// Dead reports whether the video has failed too often to be retried automatically
+func (d *DeadLetter) Dead() bool {
+	return d.Attempts >= deadLetterAfter
+}
+
+// DeadLetterStore records repeated job failures. SQLiteCache implements it.
+type DeadLetterStore interface {
+	// RecordFailure counts a failed attempt and returns the updated record
+	RecordFailure(videoID, errorClass, message string) (*DeadLetter, error)
+	// ClearFailures forgets a video's failures after it succeeds
+	ClearFailures(videoID string) error
+	// GetFailures returns the failure record, or errCacheMiss if there is none
+	GetFailures(videoID string) (*DeadLetter, error)
+	// ListDeadLetters returns dead-lettered videos, most recent failure first
+	ListDeadLetters(includeSuppressed bool) ([]*DeadLetter, error)
+	// RetryDeadLetter releases a dead-lettered video so the next run tries it again
+	RetryDeadLetter(videoID string) error
+	// SuppressDeadLetter keeps skipping a dead-lettered video without reporting it
+	SuppressDeadLetter(videoID string) error
+}
+
+const deadLetterColumns = "video_id, error_class, error, attempts, first_failed_at, last_failed_at, suppressed"
+
+func scanDeadLetter(row interface{ Scan(...any) error }) (*DeadLetter, error) {
+	var d DeadLetter
+	err := row.Scan(&d.VideoID, &d.ErrorClass, &d.Error, &d.Attempts, &d.FirstFailedAt, &d.LastFailedAt, &d.Suppressed)
+	return &d, err
+}
+
+func (c *SQLiteCache) RecordFailure(videoID, errorClass, message string) (*DeadLetter, error) {
+	if c.cfg.ReadOnly {
+		return nil, errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO job_failures (video_id, error_class, error, attempts)
+		VALUES (?, ?, ?, 1)
+		ON CONFLICT(video_id) DO UPDATE SET
+			error_class = excluded.error_class,
+			error = excluded.error,
+			attempts = attempts + 1,
+			last_failed_at = CURRENT_TIMESTAMP
+	`, videoID, errorClass, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record failure: %w", err)
+	}
+
+	return c.GetFailures(videoID)
+}
+
+func (c *SQLiteCache) ClearFailures(videoID string) error {
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("DELETE FROM job_failures WHERE video_id = ?", videoID); err != nil {
+		return fmt.Errorf("failed to clear failures: %w", err)
+	}
+	return nil
+}
+
+func (c *SQLiteCache) GetFailures(videoID string) (*DeadLetter, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := scanDeadLetter(db.QueryRow("SELECT "+deadLetterColumns+" FROM job_failures WHERE video_id = ?", videoID))
+	if err == sql.ErrNoRows {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failures: %w", err)
+	}
+	return d, nil
+}
+
+func (c *SQLiteCache) ListDeadLetters(includeSuppressed bool) ([]*DeadLetter, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + deadLetterColumns + " FROM job_failures WHERE attempts >= ?"
+	if !includeSuppressed {
+		query += " AND suppressed = 0"
+	}
+	query += " ORDER BY last_failed_at DESC, video_id"
+
+	rows, err := db.Query(query, deadLetterAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := []*DeadLetter{}
+	for rows.Next() {
+		d, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list dead letters: %w", err)
+		}
+		letters = append(letters, d)
+	}
+	return letters, rows.Err()
+}
+
+// updateDeadLetter runs a statement against a dead-lettered video, returning
+// errCacheMiss when the video isn't dead-lettered
+func (c *SQLiteCache) updateDeadLetter(query, videoID string) error {
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	res, err := db.Exec(query, videoID, deadLetterAfter)
+	if err != nil {
+		return fmt.Errorf("failed to update dead letter: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errCacheMiss
+	}
+	return nil
+}
+
+func (c *SQLiteCache) RetryDeadLetter(videoID string) error {
+	return c.updateDeadLetter("DELETE FROM job_failures WHERE video_id = ? AND attempts >= ?", videoID)
+}
+
+func (c *SQLiteCache) SuppressDeadLetter(videoID string) error {
+	return c.updateDeadLetter("UPDATE job_failures SET suppressed = 1 WHERE video_id = ? AND attempts >= ?", videoID)
+}
+
+// deadLettered returns the record of a video that jobs should no longer retry, or nil
+func deadLettered(store DeadLetterStore, videoID string) *DeadLetter {
+	d, err := store.GetFailures(videoID)
+	if err != nil || !d.Dead() {
+		return nil
+	}
+	return d
+}
+
+// recordJobResult counts a video-specific failure towards dead-lettering, or
+// clears the count once the video succeeds. An empty errorClass means success.
+func recordJobResult(store DeadLetterStore, videoID, errorClass, message string) {
+	var err error
+	switch {
+	case errorClass == "":
+		err = store.ClearFailures(videoID)
+	case slices.Contains(deadLetterClasses, errorClass):
+		var d *DeadLetter
+		if d, err = store.RecordFailure(videoID, errorClass, message); err == nil && d.Attempts == deadLetterAfter {
+			fmt.Fprintf(os.Stderr, "%s failed %d times (%s); dead-lettered, see 'ytsummary jobs failed'\n", videoID, d.Attempts, errorClass)
+		}
+	}
+	if err != nil && !errors.Is(err, errCacheReadOnly) {
+		fmt.Fprintf(os.Stderr, "warning: failed to update failure record for %s: %v\n", videoID, err)
+	}
+}
+
+// deadLetterMessage explains why a job skipped a video
+func deadLetterMessage(d *DeadLetter) string {
+	return fmt.Sprintf("dead-lettered after %d failures: %s (retry with 'ytsummary jobs retry %s')", d.Attempts, d.Error, d.VideoID)
+}
+
+// runJobsFailed lists dead-lettered videos
+func runJobsFailed(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	letters, err := cache.ListDeadLetters(jobsFailedAll)
+	if err != nil {
+		return err
+	}
+	if len(letters) == 0 {
+		fmt.Println("No dead-lettered videos")
+		return nil
+	}
+
+	for _, d := range letters {
+		status := ""
+		if d.Suppressed {
+			status = " [suppressed]"
+		}
+		fmt.Printf("%-11s  %-17s  %d attempts  last %s%s\n", d.VideoID, d.ErrorClass, d.Attempts, d.LastFailedAt.Format(time.DateTime), status)
+		fmt.Printf("%-11s  %s\n", "", d.Error)
+	}
+	return nil
+}
+
+// runJobsUpdate applies retry or suppress to each video ID or URL given
+func runJobsUpdate(action func(*SQLiteCache, string) error, done string) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cache, err := openCache()
+		if err != nil {
+			return err
+		}
+		defer cache.Close()
+
+		for _, arg := range args {
+			videoID, err := extractVideoID(arg)
+			if err != nil {
+				return err
+			}
+			if err := action(cache, videoID); errors.Is(err, errCacheMiss) {
+				return fmt.Errorf("%s is not dead-lettered", videoID)
+			} else if err != nil {
+				return err
+			}
+			fmt.Printf("%s %s\n", videoID, done)
+		}
+		return nil
+	}
+}
+
+// handleDeadLetters lists dead-lettered videos (?all=true includes suppressed ones)
+func (s *Server) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := s.deadLetters.ListDeadLetters(r.URL.Query().Get("all") == "true")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Failed to read dead letters")
+		return
+	}
+	writeJSON(w, http.StatusOK, letters)
+}
+
+// handleDeadLetterAction returns a handler applying retry or suppress to the video in the path
+func (s *Server) handleDeadLetterAction(action func(DeadLetterStore, string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		videoID, err := extractVideoID(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid video ID")
+			return
+		}
+
+		switch err := action(s.deadLetters, videoID); {
+		case errors.Is(err, errCacheMiss):
+			writeErrorWithVideo(w, http.StatusNotFound, "not_found", "Video is not dead-lettered", videoID)
+		case errors.Is(err, errCacheReadOnly):
+			writeErrorWithVideo(w, http.StatusConflict, ErrInternal, err.Error(), videoID)
+		case err != nil:
+			writeErrorWithVideo(w, http.StatusInternalServerError, ErrInternal, err.Error(), videoID)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDeadLetterStore(t *testing.T) {
+	cache := newTestCache(t)
+
+	for i := 1; i <= deadLetterAfter; i++ {
+		d, err := cache.RecordFailure("noCaptions1", ErrNoCaptions, "no subtitles available")
+		if err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+		if d.Attempts != i || d.Dead() != (i == deadLetterAfter) {
+			t.Errorf("after %d failures: attempts = %d, dead = %v", i, d.Attempts, d.Dead())
+		}
+		if i < deadLetterAfter {
+			if letters, _ := cache.ListDeadLetters(true); len(letters) != 0 {
+				t.Errorf("listed %d dead letters before the threshold", len(letters))
+			}
+		}
+	}
+
+	letters, err := cache.ListDeadLetters(false)
+	if err != nil {
+		t.Fatalf("ListDeadLetters() error = %v", err)
+	}
+	if len(letters) != 1 || letters[0].VideoID != "noCaptions1" || letters[0].ErrorClass != ErrNoCaptions {
+		t.Fatalf("dead letters = %+v, want noCaptions1", letters)
+	}
+
+	// Suppressed videos are hidden unless asked for
+	if err := cache.SuppressDeadLetter("noCaptions1"); err != nil {
+		t.Fatalf("SuppressDeadLetter() error = %v", err)
+	}
+	if letters, _ := cache.ListDeadLetters(false); len(letters) != 0 {
+		t.Errorf("suppressed video still listed: %+v", letters)
+	}
+	if letters, _ := cache.ListDeadLetters(true); len(letters) != 1 || !letters[0].Suppressed {
+		t.Errorf("dead letters with suppressed = %+v", letters)
+	}
+
+	// Retry forgets the failures
+	if err := cache.RetryDeadLetter("noCaptions1"); err != nil {
+		t.Fatalf("RetryDeadLetter() error = %v", err)
+	}
+	if _, err := cache.GetFailures("noCaptions1"); !errors.Is(err, errCacheMiss) {
+		t.Errorf("GetFailures() after retry error = %v, want %v", err, errCacheMiss)
+	}
+	if err := cache.RetryDeadLetter("noCaptions1"); !errors.Is(err, errCacheMiss) {
+		t.Errorf("RetryDeadLetter() on unknown video error = %v, want %v", err, errCacheMiss)
+	}
+}
+
+func TestRecordJobResult(t *testing.T) {
+	cache := newTestCache(t)
+
+	// Failures that aren't about the video never count
+	for i := 0; i < deadLetterAfter; i++ {
+		recordJobResult(cache, "dQw4w9WgXcQ", ErrRateLimited, "429")
+		recordJobResult(cache, "dQw4w9WgXcQ", ErrLLMError, "timeout")
+	}
+	if _, err := cache.GetFailures("dQw4w9WgXcQ"); !errors.Is(err, errCacheMiss) {
+		t.Errorf("transient failures were recorded: %v", err)
+	}
+
+	// A success resets the count
+	recordJobResult(cache, "dQw4w9WgXcQ", ErrAgeRestricted, "age-restricted")
+	recordJobResult(cache, "dQw4w9WgXcQ", "", "")
+	if _, err := cache.GetFailures("dQw4w9WgXcQ"); !errors.Is(err, errCacheMiss) {
+		t.Errorf("failures not cleared after success: %v", err)
+	}
+}
+
+func TestRunBatchDeadLetter(t *testing.T) {
+	manifestPath, youtube := newBatchTest(t, "https://youtu.be/noCaptions1\n")
+
+	for i := 0; i < deadLetterAfter; i++ {
+		if err := runBatch(nil, nil); err == nil {
+			t.Fatalf("run %d: expected failure", i+1)
+		}
+	}
+	fetches := youtube.playerRequests.Load()
+
+	// The next run skips the video without fetching it
+	err := runBatch(nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "1 of 1 videos failed") {
+		t.Fatalf("runBatch() error = %v, want the dead letter reported", err)
+	}
+	if got := youtube.playerRequests.Load(); got != fetches {
+		t.Errorf("dead-lettered video was fetched again (%d player requests, want %d)", got, fetches)
+	}
+	manifest, err := readManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("readManifest() error = %v", err)
+	}
+	if got := manifest.Videos[0]; got.Status != batchStatusDeadLetter || got.ErrorClass != ErrNoCaptions {
+		t.Errorf("entry = %+v, want dead_letter no_captions", got)
+	}
+
+	// Once suppressed, the run succeeds
+	cache := newSQLiteCache(SQLiteCacheConfig{Dir: cacheDir})
+	defer cache.Close()
+	if err := cache.SuppressDeadLetter("noCaptions1"); err != nil {
+		t.Fatalf("SuppressDeadLetter() error = %v", err)
+	}
+	if err := runBatch(nil, nil); err != nil {
+		t.Errorf("runBatch() with suppressed video error = %v", err)
+	}
+}
+
+func TestE2E_DeadLetter(t *testing.T) {
+	h := newE2EHarness(t)
+	for i := 0; i < deadLetterAfter; i++ {
+		h.cache.RecordFailure("noCaptions1", ErrNoCaptions, "no subtitles available")
+	}
+
+	resp := h.get("/admin/deadletter", "10.0.30.1")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	letters := decodeBody[[]DeadLetter](t, resp)
+	if len(letters) != 1 || letters[0].VideoID != "noCaptions1" || letters[0].Attempts != deadLetterAfter {
+		t.Fatalf("dead letters = %+v", letters)
+	}
+
+	resp = h.post("/admin/deadletter/noCaptions1/suppress", "", "10.0.30.2")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("suppress status = %d, want 204", resp.StatusCode)
+	}
+	if letters := decodeBody[[]DeadLetter](t, h.get("/admin/deadletter", "10.0.30.3")); len(letters) != 0 {
+		t.Errorf("suppressed video listed: %+v", letters)
+	}
+
+	resp = h.post("/admin/deadletter/noCaptions1/retry", "", "10.0.30.4")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("retry status = %d, want 204", resp.StatusCode)
+	}
+	resp = h.post("/admin/deadletter/noCaptions1/retry", "", "10.0.30.5")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second retry status = %d, want 404", resp.StatusCode)
+	}
+}
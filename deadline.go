@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultRequestDeadline leaves time to send a partial result before the
+// server's write timeout cuts the connection
+const defaultRequestDeadline = 100 * time.Second
+
+// Flag for serve --request-deadline
+var requestDeadline string
+
+// Errors from summarizeWithin: the request's deadline passed before the
+// summary was ready, or summarizing panicked (logged and counted already)
+var (
+	errRequestDeadline = errors.New("request deadline exceeded")
+	errSummaryPanicked = errors.New("summarizing panicked")
+)
+
+// requestDeadlineConfig returns --request-deadline
+// (YTSUMMARY_REQUEST_DEADLINE); 0 means requests have no deadline
+func requestDeadlineConfig() (time.Duration, error) {
+	if strings.TrimSpace(getConfig(requestDeadline, "YTSUMMARY_REQUEST_DEADLINE")) == "0" {
+		return 0, nil
+	}
+	return timeoutConfig(requestDeadline, "YTSUMMARY_REQUEST_DEADLINE", defaultRequestDeadline)
+}
+
+// deadlineMiddleware gives each request a context that ends after d, so
+// handlers stop waiting and answer with what they have
+func deadlineMiddleware(d time.Duration, next http.Handler) http.Handler {
+	if d <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// partialSummary collects chunk summaries as they finish, so a request that
+// stops waiting for the rest can still return them
+type partialSummary struct {
+	mu     sync.Mutex
+	chunks []string
+}
+
+// add records a chunk summary; it's safe on a nil partialSummary
+func (p *partialSummary) add(summary string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chunks = append(p.chunks, summary)
+}
+
+// summaries returns the chunk summaries so far, in transcript order
+func (p *partialSummary) summaries() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.chunks...)
+}
+
+// summarizeWithin summarizes unless the request's context ends first, in
+// which case it returns errRequestDeadline and leaves the summary running in
+// the background: its chunk summaries are checkpointed, so a retry resumes
+// from them. release is called once summarizing is over either way.
+func (s *Server) summarizeWithin(r *http.Request, transcript string, opts SummaryOptions, release func()) (string, error) {
+	reqCtx := getRequestContext(r)
+	type result struct {
+		summary string
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer release()
+		// The handler may be gone, so the panic is logged here
+		defer func() {
+			if v := recover(); v != nil {
+				s.logPanic("summarizing panicked", v, slog.String("request_id", reqCtx.ID), slog.String("video_id", reqCtx.video()))
+				done <- result{err: errSummaryPanicked}
+			}
+		}()
+		summary, err := s.summarize(transcript, opts)
+		done <- result{summary, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.summary, res.err
+	case <-r.Context().Done():
+		select {
+		case res := <-done:
+			return res.summary, res.err
+		default:
+			return "", errRequestDeadline
+		}
+	}
+}
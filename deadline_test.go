@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSummarizePastDeadline(t *testing.T) {
+	finish := make(chan struct{})
+	t.Cleanup(func() { close(finish) })
+	handler := newServer(ServerConfig{
+		Cache: newTestCache(t),
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Injected", Transcript: "One. Two. Three."}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			opts.Partial.add("The first part.")
+			<-finish // the rest never arrives in time
+			return "A summary.", nil
+		},
+		RequestDeadline: 100 * time.Millisecond,
+	}).Handler()
+
+	start := time.Now()
+	req := httptest.NewRequest("GET", "/v1/summarize?url=dQw4w9WgXcQ", nil)
+	req.RemoteAddr = "192.0.2.27:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("answered after %s, want soon after the deadline", elapsed)
+	}
+
+	var resp TranscriptResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != 200 || !resp.TimedOut || resp.Summary != "" {
+		t.Fatalf("status %d, timed_out %v, summary %q; want a partial result", w.Code, resp.TimedOut, resp.Summary)
+	}
+	if resp.Transcript != "One. Two. Three." || len(resp.ChunkSummaries) != 1 || resp.ChunkSummaries[0] != "The first part." {
+		t.Errorf("transcript %q, chunk summaries %q", resp.Transcript, resp.ChunkSummaries)
+	}
+}
+
+func TestRequestDeadlineConfig(t *testing.T) {
+	t.Setenv("YTSUMMARY_REQUEST_DEADLINE", "")
+	if d, err := requestDeadlineConfig(); d != defaultRequestDeadline || err != nil {
+		t.Errorf("default = %s, %v", d, err)
+	}
+	t.Setenv("YTSUMMARY_REQUEST_DEADLINE", "0")
+	if d, err := requestDeadlineConfig(); d != 0 || err != nil {
+		t.Errorf("0 = %s, %v; want no deadline", d, err)
+	}
+	t.Setenv("YTSUMMARY_REQUEST_DEADLINE", "45")
+	if d, err := requestDeadlineConfig(); d != 45*time.Second || err != nil {
+		t.Errorf("45 = %s, %v", d, err)
+	}
+	t.Setenv("YTSUMMARY_REQUEST_DEADLINE", "soon")
+	if _, err := requestDeadlineConfig(); err == nil {
+		t.Error("invalid deadline accepted")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxOverlapWords bounds how far back a cue's opening words are matched
+// against what was already said; rolling captions repeat at most a line or two
+const maxOverlapWords = 40
+
+// cueMerger drops the words that auto-generated captions repeat from one cue
+// to the next. YouTube's speech recognition VTT rolls: each cue restates the
+// end of the previous one before adding a word or two, so keeping only exact
+// duplicates out still roughly doubles the transcript.
+type cueMerger struct {
+	// rolling trims words carried over from the cues before; without it only
+	// exact repeats are dropped, since in written captions a cue opening with
+	// the previous one's last words ("thank you" / "Thank you, he replied") is
+	// real speech
+	rolling bool
+
+	prev string   // the previous cue as given
+	tail []uint64 // wordKeys of the words kept, at least the last maxOverlapWords
+
+	// Scratch for the current cue: each word's key and where it ends
+	keys []uint64
+	ends []int
+}
+
+// merge returns the part of cue that doesn't repeat the cues before it, or ""
+// if it says nothing new. Cue must already be cleaned.
+func (m *cueMerger) merge(cue string) string {
+	if cue == m.prev {
+		return ""
+	}
+	m.prev = cue
+	if !m.rolling {
+		return cue
+	}
+
+	m.keys, m.ends = m.keys[:0], m.ends[:0]
+	for i := 0; ; {
+		start, end := nextWord(cue, i)
+		if start == end {
+			break
+		}
+		m.keys = append(m.keys, wordKey(cue[start:end]))
+		m.ends = append(m.ends, end)
+		i = end
+	}
+	n := captionOverlap(m.tail, m.keys)
+
+	m.tail = append(m.tail, m.keys[n:]...)
+	if len(m.tail) > 2*maxOverlapWords {
+		m.tail = append(m.tail[:0], m.tail[len(m.tail)-maxOverlapWords:]...)
+	}
+	switch n {
+	case 0:
+		return cue
+	case len(m.keys):
+		return ""
+	}
+	rest := cue[m.ends[n-1]:]
+	start, _ := nextWord(rest, 0)
+	return rest[start:]
+}
+
+// captionOverlap returns how many leading words of a cue repeat the last
+// words of tail. One word alone isn't enough to call it a repeat, since
+// speech repeats single words often; two or more is.
+func captionOverlap(tail, words []uint64) int {
+	if len(words) < 2 {
+		return 0
+	}
+	for n := min(len(tail), len(words), maxOverlapWords); n >= 2; n-- {
+		same := true
+		for i := 0; i < n && same; i++ {
+			same = tail[len(tail)-n+i] == words[i]
+		}
+		if same {
+			return n
+		}
+	}
+	return 0
+}
+
+// nextWord returns the bounds of the first whitespace-separated word in s at
+// or after from; start == end when there is none
+func nextWord(s string, from int) (start, end int) {
+	start = from
+	for start < len(s) {
+		if c := s[start]; c < utf8.RuneSelf {
+			if !asciiSpace[c] {
+				break
+			}
+			start++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[start:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	end = start
+	for end < len(s) {
+		if c := s[end]; c < utf8.RuneSelf {
+			if asciiSpace[c] {
+				break
+			}
+			end++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[end:])
+		if unicode.IsSpace(r) {
+			break
+		}
+		end += size
+	}
+	return start, end
+}
+
+// asciiSpace and asciiPunct mark the ASCII characters unicode.IsSpace and
+// unicode.IsPunct accept
+var asciiSpace, asciiPunct [utf8.RuneSelf]bool
+
+func init() {
+	for c := range asciiPunct {
+		asciiSpace[c] = unicode.IsSpace(rune(c))
+		asciiPunct[c] = unicode.IsPunct(rune(c))
+	}
+}
+
+// wordKey hashes a word (FNV-1a) ignoring case and the punctuation around
+// it, which speech recognition adds and removes as a line grows
+func wordKey(w string) uint64 {
+	const offset, prime = 14695981039346656037, 1099511628211
+	lo, hi := 0, len(w)
+	for lo < hi && w[lo] < utf8.RuneSelf && asciiPunct[w[lo]] {
+		lo++
+	}
+	for hi > lo && w[hi-1] < utf8.RuneSelf && asciiPunct[w[hi-1]] {
+		hi--
+	}
+	h := uint64(offset)
+	for i := lo; i < hi; i++ {
+		c := w[i]
+		if c >= utf8.RuneSelf {
+			return wordKeyUnicode(w)
+		}
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		h = (h ^ uint64(c)) * prime
+	}
+	return h
+}
+
+// wordKeyUnicode is wordKey for words with non-ASCII characters. ASCII
+// words hash the same either way.
+func wordKeyUnicode(w string) uint64 {
+	const offset, prime = 14695981039346656037, 1099511628211
+	h := uint64(offset)
+	for _, r := range strings.TrimFunc(w, unicode.IsPunct) {
+		for r = unicode.ToLower(r); r > 0; r >>= 8 {
+			h = (h ^ uint64(r&0xFF)) * prime
+		}
+	}
+	return h
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCueMerger(t *testing.T) {
+	tests := []struct {
+		name string
+		cues []string
+		want []string
+	}{
+		{"rolling", []string{"so today we're", "today we're going to", "going to talk"}, []string{"so today we're", "going to", "talk"}},
+		{"case and punctuation", []string{"Hello there, friends", "there friends. How are", "How are you?"}, []string{"Hello there, friends", "How are", "you?"}},
+		{"exact repeat", []string{"no", "no", "yes"}, []string{"no", "", "yes"}},
+		{"one word is not an overlap", []string{"I said no", "no way"}, []string{"I said no", "no way"}},
+		{"repeats an earlier cue's end", []string{"one two", "three four", "two three four five"}, []string{"one two", "three four", "five"}},
+		{"nothing new", []string{"we can go now", "go now"}, []string{"we can go now", ""}},
+		{"non-Latin", []string{"Привет всем друзья", "ВСЕМ ДРУЗЬЯ сегодня"}, []string{"Привет всем друзья", "сегодня"}},
+	}
+	for _, tt := range tests {
+		m := cueMerger{rolling: true}
+		for i, cue := range tt.cues {
+			if got := m.merge(cue); got != tt.want[i] {
+				t.Errorf("%s: merge(%q) = %q, want %q", tt.name, cue, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestManualCaptionsKeepRepeats(t *testing.T) {
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nShe said thank you.\n\n" +
+		"2\n00:00:02,000 --> 00:00:03,000\nThank you, he replied.\n\n" +
+		"3\n00:00:03,000 --> 00:00:04,000\nWe will rock you\n\n" +
+		"4\n00:00:04,000 --> 00:00:05,000\nrock you, we will rock you\n\n" +
+		"5\n00:00:05,000 --> 00:00:06,000\nrock you, we will rock you\n"
+	want := "She said thank you. Thank you, he replied. We will rock you rock you, we will rock you"
+	if got := cleanSRT(srt, false); got != want {
+		t.Errorf("cleanSRT:\n got %q\nwant %q", got, want)
+	}
+	if got := segmentsText(parseSRTSegments(srt, false)); got != want {
+		t.Errorf("parseSRTSegments:\n got %q\nwant %q", got, want)
+	}
+	// Inline word timings mark uploaded VTT as speech recognition captions
+	if hasInlineWordTimings(srt) {
+		t.Error("plain SRT taken for rolling captions")
+	}
+}
+
+func TestRollingCaptionsDedup(t *testing.T) {
+	vtt, err := os.ReadFile("testdata/rolling_asr.vtt")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	want := "so today we're going to talk about rolling captions, which repeat a lot and that doubles everything no no that's fine"
+	if got := cleanSRT(string(vtt), true); got != want {
+		t.Errorf("cleanSRT:\n got %q\nwant %q", got, want)
+	}
+
+	xml := `<transcript>
+<text start="0" dur="2">so today we&#39;re going</text>
+<text start="2" dur="2">we&#39;re going to talk about</text>
+<text start="4" dur="2">to talk about captions</text>
+</transcript>`
+	segments := parseTimedTextSegments(xml, true)
+	if len(segments) != 3 || segments[1].Text != "to talk about" || segments[2].Start != 4 || segments[2].Text != "captions" {
+		t.Errorf("segments = %+v", segments)
+	}
+	if got, want := segmentsText(segments), "so today we're going to talk about captions"; got != want {
+		t.Errorf("segmentsText = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var debugScrapeDir string
+
+// Player response fields dropped from diagnostics: tracking and ad URLs carry
+// per-viewer tokens and say nothing about the caption format
+var diagnosticsDroppedFields = map[string]bool{
+	"playbackTracking": true,
+	"adPlacements":     true,
+	"playerAds":        true,
+	"adSlots":          true,
+}
+
+// Player response fields that identify the client or session
+var diagnosticsSecretFields = map[string]bool{
+	"visitorData":               true,
+	"serializedShareEntity":     true,
+	"botguardData":              true,
+	"playerAttestationRenderer": true,
+	"attestation":               true,
+	"adSignalsInfo":             true,
+}
+
+// Query parameters kept on URLs in diagnostics; signatures, IPs, expiry and
+// session tokens are dropped
+var diagnosticsURLParams = []string{"v", "lang", "kind", "fmt", "tlang", "name", "itag", "mime", "caps"}
+
+// scrapeTrace collects what YouTube returned during one fetch so it can be
+// saved for a bug report if the fetch fails. A nil trace records nothing.
+type scrapeTrace struct {
+	VideoID      string `json:"video_id"`
+	Language     string `json:"language"`
+	PlayerStatus int    `json:"player_status,omitempty"`
+	CaptionURL   string `json:"caption_url,omitempty"`
+	player       []byte
+	captions     string
+}
+
+// newScrapeTrace starts a trace when --debug-scrape (YTSUMMARY_DEBUG_SCRAPE)
+// names a diagnostics directory, and returns nil otherwise
+func newScrapeTrace(videoID, language string) *scrapeTrace {
+	if getConfig(debugScrapeDir, "YTSUMMARY_DEBUG_SCRAPE") == "" {
+		return nil
+	}
+	return &scrapeTrace{VideoID: videoID, Language: language}
+}
+
+func (t *scrapeTrace) recordPlayer(status int, body []byte) {
+	if t != nil {
+		t.PlayerStatus, t.player = status, body
+	}
+}
+
+func (t *scrapeTrace) recordCaptionURL(captionURL string) {
+	if t != nil {
+		t.CaptionURL = scrubURL(captionURL)
+	}
+}
+
+func (t *scrapeTrace) recordCaptions(content string) {
+	if t != nil {
+		t.captions = content
+	}
+}
+
+// save writes the trace and the error it ended with to
+// <dir>/<video>-<time>/: error.json, player.json and captions.xml
+func (t *scrapeTrace) save(fetchErr error) {
+	if t == nil || t.player == nil {
+		return
+	}
+	dir := filepath.Join(getConfig(debugScrapeDir, "YTSUMMARY_DEBUG_SCRAPE"),
+		fmt.Sprintf("%s-%s", t.VideoID, time.Now().UTC().Format("20060102T150405Z")))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to save scrape diagnostics: %v\n", err)
+		return
+	}
+
+	meta := struct {
+		*scrapeTrace
+		Error         string    `json:"error"`
+		ErrorClass    string    `json:"error_class"`
+		ClientProfile string    `json:"client_profile,omitempty"`
+		Build         BuildInfo `json:"build"`
+		Time          time.Time `json:"time"`
+	}{
+		scrapeTrace: t,
+		Error:       fetchErr.Error(),
+		ErrorClass:  fetchErrorClass(fetchErr),
+		Build:       getBuildInfo(),
+		Time:        time.Now().UTC(),
+	}
+	if profile, err := scraperProfile(); err == nil {
+		meta.ClientProfile = profile.ClientName + " " + profile.ClientVersion
+	}
+	files := map[string][]byte{}
+	files["error.json"], _ = json.MarshalIndent(meta, "", "  ")
+	files["player.json"] = scrubPlayerResponse(t.player)
+	if t.captions != "" {
+		files["captions.xml"] = []byte(t.captions)
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to save scrape diagnostics: %v\n", err)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Saved scrape diagnostics to %s (attach it to a bug report)\n", dir)
+}
+
+// scrubPlayerResponse removes session identifiers and URL tokens from a raw
+// player response, keeping its structure. Non-JSON bodies (error pages) are
+// kept as they are.
+func scrubPlayerResponse(body []byte) []byte {
+	var raw any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return body
+	}
+	scrubbed, err := json.MarshalIndent(scrubDiagnostics(raw), "", "  ")
+	if err != nil {
+		return body
+	}
+	return scrubbed
+}
+
+// scrubDiagnostics walks a decoded JSON value, dropping tracking fields,
+// blanking secrets and stripping tokens from URLs
+func scrubDiagnostics(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			switch {
+			case diagnosticsDroppedFields[k]:
+				delete(val, k)
+			case diagnosticsSecretFields[k]:
+				val[k] = "[scrubbed]"
+			case k == "signatureCipher" || k == "cipher":
+				val[k] = "[scrubbed]"
+			default:
+				if s, ok := child.(string); ok && strings.HasSuffix(strings.ToLower(k), "url") {
+					val[k] = scrubURL(s)
+				} else {
+					val[k] = scrubDiagnostics(child)
+				}
+			}
+		}
+	case []any:
+		for i, child := range val {
+			val[i] = scrubDiagnostics(child)
+		}
+	}
+	return v
+}
+
+// scrubURL keeps a URL's host and path but only non-sensitive query parameters
+func scrubURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	q := u.Query()
+	kept := url.Values{}
+	for _, param := range diagnosticsURLParams {
+		if v := q.Get(param); v != "" {
+			kept.Set(param, v)
+		}
+	}
+	u.RawQuery = kept.Encode()
+	return u.String()
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+// Flags for weekly
+var (
+	digestSince    string
+	digestChannels []string
+	digestOutput   string
+	digestSchedule string
+)
+
+const (
+	defaultDigestWindow = 7 * 24 * time.Hour
+
+	// maxDigestVideos and maxDigestSummaryChars keep the digest prompt within
+	// one request: the newest videos are kept, each summary cut to length
+	maxDigestVideos       = 60
+	maxDigestSummaryChars = 1500
+)
+
+// digestPrompt asks for the cross-video digest. Videos are cited by their
+// [n] markers, which the video list under the digest resolves to links.
+const digestPrompt = `You are writing a digest of the YouTube videos below, which were summarized over the last days. Each starts with a [n] marker, its title and channel, followed by its summary.
+
+Write a short digest of what happened across them:
+- Group the videos into 2 to 6 themes. Give each theme a "## " heading and a paragraph saying what the videos on it covered, where they agree and where they differ.
+- Cite videos by their markers, e.g. [3], after the points they make. Every video should be cited at least once.
+- Start with one or two sentences on the period as a whole. Don't list the videos again at the end.
+
+Use Markdown. Don't invent anything the summaries don't say.`
+
+// digestVideo is one video in a digest, with its newest summary
+type digestVideo struct {
+	VideoID string
+	Title   string
+	Channel string
+	Summary string
+}
+
+// parseWindow parses --since: a Go duration ("72h") or a number of days
+// ("7d"). Empty is def.
+func parseWindow(value string, def time.Duration) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return def, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok && isDigits(days) {
+		n, err := strconv.Atoi(days)
+		if err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --since %q (use e.g. 7d or 48h)", value)
+	}
+	return d, nil
+}
+
+// digestChannelFilter returns the watched channels from --channel, or the
+// comma-separated YTSUMMARY_WEEKLY_CHANNELS; none means every channel
+func digestChannelFilter() []string {
+	channels := digestChannels
+	if len(channels) == 0 {
+		if env := os.Getenv("YTSUMMARY_WEEKLY_CHANNELS"); env != "" {
+			channels = strings.Split(env, ",")
+		}
+	}
+	var filter []string
+	for _, c := range channels {
+		if c = strings.TrimSpace(c); c != "" {
+			filter = append(filter, c)
+		}
+	}
+	return filter
+}
+
+// ListSummariesSince returns the summaries created at or after since, newest first
+func (c *SQLiteCache) ListSummariesSince(since time.Time) ([]*StoredSummary, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT "+storedSummaryColumns+" FROM summaries WHERE created_at >= ? ORDER BY created_at DESC, id DESC",
+		since.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list summaries: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []*StoredSummary
+	for rows.Next() {
+		s, err := scanStoredSummary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list summaries: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// collectDigestVideos returns the videos summarized since a time, each with
+// its newest summary, oldest first. With channels, only videos from those
+// channels (by name, case-insensitive) are kept.
+func collectDigestVideos(cache *SQLiteCache, since time.Time, channels []string) ([]digestVideo, error) {
+	summaries, err := cache.ListSummariesSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var videos []digestVideo
+	for _, s := range summaries {
+		if seen[s.VideoID] {
+			continue // an older summary of a video already in the digest
+		}
+		seen[s.VideoID] = true
+
+		v := digestVideo{VideoID: s.VideoID, Title: s.VideoID, Summary: s.Summary}
+		if entry, err := cache.GetTranscript(s.VideoID, s.Language); err == nil {
+			if entry.Title != "" {
+				v.Title = entry.Title
+			}
+			v.Channel = entry.Channel
+		}
+		if len(channels) > 0 && !containsFold(channels, v.Channel) {
+			continue
+		}
+		videos = append(videos, v)
+	}
+
+	if len(videos) > maxDigestVideos {
+		log("Digesting the newest %d of %d videos", maxDigestVideos, len(videos))
+		videos = videos[:maxDigestVideos]
+	}
+	// Summaries come newest first; the digest reads in order
+	for i, j := 0, len(videos)-1; i < j; i, j = i+1, j-1 {
+		videos[i], videos[j] = videos[j], videos[i]
+	}
+	return videos, nil
+}
+
+// containsFold reports whether list holds s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// digestInput renders the videos as the digest prompt's input
+func digestInput(videos []digestVideo) string {
+	var b strings.Builder
+	for i, v := range videos {
+		summary := strings.TrimSpace(v.Summary)
+		if utf8.RuneCountInString(summary) > maxDigestSummaryChars {
+			summary = string([]rune(summary)[:maxDigestSummaryChars]) + "…"
+		}
+		fmt.Fprintf(&b, "[%d] %s", i+1, v.Title)
+		if v.Channel != "" {
+			fmt.Fprintf(&b, " (%s)", v.Channel)
+		}
+		fmt.Fprintf(&b, "\n%s\n\n", summary)
+	}
+	return b.String()
+}
+
+// buildDigest writes the digest of videos summarized between since and
+// until: the LLM's themes, then the videos it cites as links
+func buildDigest(client LLMClient, videos []digestVideo, since, until time.Time) (string, error) {
+	themes, err := client.Complete(digestPrompt, digestInput(videos), GenerationParams{})
+	if err != nil {
+		return "", fmt.Errorf("failed to write digest: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# What happened: %s – %s\n\n", since.Format("Jan 2"), until.Format("Jan 2, 2006"))
+	b.WriteString(strings.TrimSpace(themes))
+	b.WriteString("\n\n## Videos\n\n")
+	for i, v := range videos {
+		fmt.Fprintf(&b, "%d. [%s](%s)", i+1, v.Title, canonicalVideoURL(v.VideoID))
+		if v.Channel != "" {
+			fmt.Fprintf(&b, " — %s", v.Channel)
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// runDigest writes one digest of the window ending now to stdout (or --output)
+// and the notification sinks. With no videos in the window there is nothing
+// to send.
+func runDigest(cache *SQLiteCache, client LLMClient, window time.Duration, notifiers []Notifier) error {
+	until := time.Now()
+	since := until.Add(-window)
+	videos, err := collectDigestVideos(cache, since, digestChannelFilter())
+	if err != nil {
+		return err
+	}
+	if len(videos) == 0 {
+		log("No videos were summarized since %s; no digest to send", since.Format(time.DateTime))
+		return nil
+	}
+
+	log("Writing a digest of %d videos...", len(videos))
+	digest, err := buildDigest(client, videos, since, until)
+	if err != nil {
+		return err
+	}
+
+	if digestOutput != "" {
+		if err := os.WriteFile(digestOutput, []byte(digest), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", digestOutput, err)
+		}
+		log("Wrote %s", digestOutput)
+	} else {
+		fmt.Print(digest)
+	}
+	event := newJobEvent("weekly", len(videos), 0, nil)
+	event.Digest = digest
+	publish(notifiers, event)
+	return nil
+}
+
+// weeklySchedule is a weekly run time: a weekday and a local time of day
+type weeklySchedule struct {
+	Weekday time.Weekday
+	Hour    int
+	Minute  int
+}
+
+// parseWeeklySchedule parses --schedule, e.g. "mon 09:00" or "Friday 17:30"
+func parseWeeklySchedule(value string) (weeklySchedule, error) {
+	invalid := fmt.Errorf("invalid --schedule %q (use a weekday and a time, e.g. \"mon 09:00\")", value)
+	day, clock, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok {
+		return weeklySchedule{}, invalid
+	}
+	var s weeklySchedule
+	found := false
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if day = strings.ToLower(day); day == name || day == name[:3] {
+			s.Weekday, found = d, true
+		}
+	}
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if !found || err != nil {
+		return weeklySchedule{}, invalid
+	}
+	s.Hour, s.Minute = t.Hour(), t.Minute()
+	return s, nil
+}
+
+// next returns the first scheduled time after now, in now's location
+func (s weeklySchedule) next(now time.Time) time.Time {
+	days := (int(s.Weekday) - int(now.Weekday()) + 7) % 7
+	next := time.Date(now.Year(), now.Month(), now.Day()+days, s.Hour, s.Minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// runWeekly writes a digest of the videos summarized recently. With
+// --schedule it keeps running and writes one every week.
+func runWeekly(cmd *cobra.Command, args []string) error {
+	window, err := parseWindow(digestSince, defaultDigestWindow)
+	if err != nil {
+		return err
+	}
+	var schedule weeklySchedule
+	if digestSchedule != "" {
+		if schedule, err = parseWeeklySchedule(digestSchedule); err != nil {
+			return err
+		}
+	}
+	notifiers, err := newNotifiersFromConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newLLMClient()
+	if err != nil {
+		return err
+	}
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	if digestSchedule == "" {
+		return runDigest(cache, client, window, notifiers)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	for {
+		next := schedule.next(time.Now())
+		log("Next digest at %s", next.Format("Mon Jan 2 15:04 MST"))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+		// A failed digest is reported; the next week's still runs
+		if err := runDigest(cache, client, window, notifiers); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: digest failed: %v\n", err)
+		}
+	}
+}
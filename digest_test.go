@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 7 * 24 * time.Hour},
+		{"7d", 7 * 24 * time.Hour},
+		{"1d", 24 * time.Hour},
+		{"48h", 48 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got, err := parseWindow(tt.value, defaultDigestWindow); err != nil || got != tt.want {
+			t.Errorf("parseWindow(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"0d", "-2h", "week", "d"} {
+		if _, err := parseWindow(value, defaultDigestWindow); err == nil {
+			t.Errorf("parseWindow(%q) accepted", value)
+		}
+	}
+}
+
+func TestWeeklySchedule(t *testing.T) {
+	s, err := parseWeeklySchedule("Mon 09:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tz := time.FixedZone("CEST", 2*60*60)
+	tests := []struct {
+		now, want time.Time
+	}{
+		// Friday runs wait for Monday
+		{time.Date(2026, 10, 16, 12, 0, 0, 0, tz), time.Date(2026, 10, 19, 9, 0, 0, 0, tz)},
+		// Monday before the time runs today, at or after it next week
+		{time.Date(2026, 10, 19, 8, 59, 0, 0, tz), time.Date(2026, 10, 19, 9, 0, 0, 0, tz)},
+		{time.Date(2026, 10, 19, 9, 0, 0, 0, tz), time.Date(2026, 10, 26, 9, 0, 0, 0, tz)},
+	}
+	for _, tt := range tests {
+		if got := s.next(tt.now); !got.Equal(tt.want) {
+			t.Errorf("next(%s) = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+
+	if s, err := parseWeeklySchedule("friday 17:30"); err != nil || s.Weekday != time.Friday || s.Hour != 17 || s.Minute != 30 {
+		t.Errorf("friday 17:30 = %+v, %v", s, err)
+	}
+	for _, value := range []string{"mon", "9:00", "funday 09:00", "mon 25:00"} {
+		if _, err := parseWeeklySchedule(value); err == nil {
+			t.Errorf("parseWeeklySchedule(%q) accepted", value)
+		}
+	}
+}
+
+func TestRunDigest(t *testing.T) {
+	cache := newTestCache(t)
+	store := func(videoID, title, channel, summary string, age time.Duration) {
+		cache.StoreTranscript(&CacheEntry{VideoID: videoID, Language: "en", Title: title, Channel: channel, Transcript: "Words."})
+		s := &StoredSummary{VideoID: videoID, Language: "en", Template: "default", Model: "fake", Summary: summary}
+		if err := cache.SaveSummary(s); err != nil {
+			t.Fatal(err)
+		}
+		db, _ := cache.conn()
+		db.Exec("UPDATE summaries SET created_at = ? WHERE id = ?", time.Now().Add(-age).UTC().Format(sqliteTimeFormat), s.ID)
+	}
+	store("bakeVideo01", "Sourdough", "Bakes", "An old take on starters.", 3*24*time.Hour)
+	store("bakeVideo01", "Sourdough", "Bakes", "Starters need daily feeding.", 2*24*time.Hour)
+	store("bakeVideo02", "Rye", "Bakes", "Rye ferments faster.", 24*time.Hour)
+	store("dQw4w9WgXcQ", "Never Gonna Give You Up", "Rick Astley", "A song about commitment.", time.Hour)
+	store("jNQXAC9IVRw", "Me at the zoo", "jawed", "Elephants have long trunks.", 10*24*time.Hour)
+
+	videos, err := collectDigestVideos(cache, time.Now().Add(-defaultDigestWindow), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, v := range videos {
+		ids = append(ids, v.VideoID)
+	}
+	if strings.Join(ids, ",") != "bakeVideo01,bakeVideo02,dQw4w9WgXcQ" || videos[0].Summary != "Starters need daily feeding." {
+		t.Errorf("videos = %+v", videos)
+	}
+
+	// The digest goes to the output file and every sink; only watched channels
+	sink := newNotifySink(t)
+	digestChannels = []string{"bakes"}
+	digestOutput = filepath.Join(t.TempDir(), "digest.md")
+	t.Cleanup(func() { digestChannels, digestOutput = nil, "" })
+	llm := &recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}}
+	if err := runDigest(cache, llm, defaultDigestWindow, []Notifier{&webhookNotifier{url: sink.URL}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(llm.calls) != 1 || llm.prompts[0] != digestPrompt {
+		t.Fatalf("LLM calls = %q", llm.calls)
+	}
+	if want := "[1] Sourdough (Bakes)\nStarters need daily feeding.\n\n[2] Rye (Bakes)\nRye ferments faster."; !strings.HasPrefix(llm.calls[0], want) {
+		t.Errorf("digest input = %q", llm.calls[0])
+	}
+	data, err := os.ReadFile(digestOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := string(data)
+	if !strings.HasPrefix(digest, "# What happened: ") ||
+		!strings.HasSuffix(digest, "## Videos\n\n1. [Sourdough](https://www.youtube.com/watch?v=bakeVideo01) — Bakes\n2. [Rye](https://www.youtube.com/watch?v=bakeVideo02) — Bakes\n") {
+		t.Errorf("digest = %q", digest)
+	}
+
+	if len(sink.bodies) != 1 {
+		t.Fatalf("%d notifications", len(sink.bodies))
+	}
+	var event Event
+	json.Unmarshal([]byte(sink.bodies[0]), &event)
+	if event.Type != "weekly.completed" || event.Succeeded != 2 || event.Digest != digest || event.Title() != "ytsummary weekly digest: 2 videos" {
+		t.Errorf("event = %+v", event)
+	}
+
+	// A quiet week sends nothing
+	digestChannels = []string{"Nobody"}
+	if err := runDigest(cache, llm, defaultDigestWindow, []Notifier{&webhookNotifier{url: sink.URL}}); err != nil || len(sink.bodies) != 1 || len(llm.calls) != 1 {
+		t.Errorf("empty digest: err = %v, %d notifications, %d LLM calls", err, len(sink.bodies), len(llm.calls))
+	}
+}
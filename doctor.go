@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorYouTubeURL is fetched to check outbound access to YouTube
+var doctorYouTubeURL = "https://www.youtube.com/"
+
+// Doctor check outcomes
+const (
+	doctorPass = "PASS"
+	doctorFail = "FAIL"
+	doctorInfo = "INFO"
+)
+
+// doctorCheck is one line of the doctor report
+type doctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+// runDoctorChecks verifies the things a fresh install needs to work
+func runDoctorChecks() []doctorCheck {
+	return []doctorCheck{
+		checkCacheDir(cacheDir),
+		checkYouTube(doctorYouTubeURL),
+		checkLLM(),
+		checkYtDlp(),
+	}
+}
+
+// checkCacheDir verifies the cache directory is writable and the database opens
+func checkCacheDir(dir string) doctorCheck {
+	check := doctorCheck{Name: "cache"}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		check.Status, check.Detail = doctorFail, fmt.Sprintf("can't create %s: %v", dir, err)
+		return check
+	}
+	probe, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		check.Status, check.Detail = doctorFail, fmt.Sprintf("%s is not writable: %v", dir, err)
+		return check
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	cache := newSQLiteCache(SQLiteCacheConfig{Dir: dir})
+	defer cache.Close()
+	count, err := cache.CountTranscripts()
+	if err != nil {
+		check.Status, check.Detail = doctorFail, fmt.Sprintf("can't open %s: %v", filepath.Join(dir, "transcripts.db"), err)
+		return check
+	}
+	check.Status, check.Detail = doctorPass, fmt.Sprintf("%s is writable (%d cached transcripts)", dir, count)
+	return check
+}
+
+// checkYouTube verifies outbound HTTPS access to YouTube
+func checkYouTube(url string) doctorCheck {
+	check := doctorCheck{Name: "youtube"}
+	client := &http.Client{Timeout: 10 * time.Second}
+	start := time.Now()
+	resp, err := client.Get(url)
+	if err != nil {
+		check.Status, check.Detail = doctorFail, fmt.Sprintf("can't reach %s: %v", url, err)
+		return check
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		check.Status, check.Detail = doctorFail, fmt.Sprintf("%s returned %d (this IP may be blocked)", url, resp.StatusCode)
+		return check
+	}
+	check.Status, check.Detail = doctorPass, fmt.Sprintf("%s reachable in %dms", url, time.Since(start).Milliseconds())
+	return check
+}
+
+// checkLLM verifies the LLM endpoint is reachable and accepts the API key with
+// a tiny completion request
+func checkLLM() doctorCheck {
+	check := doctorCheck{Name: "llm"}
+	client, err := newLLMClient()
+	if err != nil {
+		check.Status, check.Detail = doctorFail, err.Error()
+		return check
+	}
+	start := time.Now()
+	if _, err := client.Complete("Reply with the single word OK.", "ping", GenerationParams{}); err != nil {
+		check.Status, check.Detail = doctorFail, err.Error()
+		var llmErr *llmError
+		if !errors.As(err, &llmErr) {
+			apiURL := getConfig(llmBaseURL, "YTSUMMARY_API_URL")
+			if apiURL == "" {
+				apiURL = defaultAPIURL
+			}
+			check.Detail = fmt.Sprintf("can't reach %s: %v", apiURL, err)
+		}
+		return check
+	}
+	check.Status, check.Detail = doctorPass, fmt.Sprintf("%s answered in %dms", client.Model(), time.Since(start).Milliseconds())
+	return check
+}
+
+// checkYtDlp reports yt-dlp's version. ytsummary talks to YouTube directly, so
+// yt-dlp is optional and never fails the report.
+func checkYtDlp() doctorCheck {
+	check := doctorCheck{Name: "yt-dlp", Status: doctorInfo}
+	path, err := exec.LookPath("yt-dlp")
+	if err != nil {
+		check.Detail = "not installed (not required)"
+		return check
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		check.Detail = redact(fmt.Sprintf("%s found but --version failed: %v", path, err))
+		return check
+	}
+	check.Detail = redact(fmt.Sprintf("%s %s (not required)", path, strings.TrimSpace(string(out))))
+	return check
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	failed := 0
+	for _, check := range runDoctorChecks() {
+		fmt.Printf("%-4s  %-8s %s\n", check.Status, check.Name, check.Detail)
+		if check.Status == doctorFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
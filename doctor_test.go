@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckCacheDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	if check := checkCacheDir(dir); check.Status != doctorPass {
+		t.Errorf("checkCacheDir() = %+v, want pass", check)
+	}
+
+	// A file where the directory should be
+	blocked := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(blocked, nil, 0644)
+	if check := checkCacheDir(blocked); check.Status != doctorFail {
+		t.Errorf("checkCacheDir() = %+v, want fail", check)
+	}
+}
+
+func TestCheckYouTube(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	if check := checkYouTube(srv.URL); check.Status != doctorPass {
+		t.Errorf("checkYouTube() = %+v, want pass", check)
+	}
+	status = http.StatusTooManyRequests
+	if check := checkYouTube(srv.URL); check.Status != doctorFail {
+		t.Errorf("checkYouTube() = %+v, want fail when rate limited", check)
+	}
+}
+
+func TestCheckLLM(t *testing.T) {
+	t.Setenv("YTSUMMARY_PROVIDER", "fake")
+	if check := checkLLM(); check.Status != doctorPass {
+		t.Errorf("checkLLM() = %+v, want pass", check)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"message": "No auth credentials found"}}`))
+	}))
+	defer srv.Close()
+	t.Setenv("YTSUMMARY_PROVIDER", "openai")
+	t.Setenv("YTSUMMARY_API_KEY", "bad")
+	t.Setenv("YTSUMMARY_API_URL", srv.URL)
+	if check := checkLLM(); check.Status != doctorFail {
+		t.Errorf("checkLLM() = %+v, want fail for a rejected key", check)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Flag for serve --egress-allow
+var egressAllow string
+
+// youtubeEgressHosts are always allowed under an egress policy: the player,
+// caption and clip pages, and the Data API
+var youtubeEgressHosts = []string{"youtube.com", "*.youtube.com", "www.googleapis.com"}
+
+// errEgressDenied is returned for a request to a host the egress policy
+// doesn't allow
+var errEgressDenied = errors.New("egress denied")
+
+// egressPolicy returns the hosts LLM and YouTube requests may go to, from
+// --egress-allow (YTSUMMARY_EGRESS_ALLOW): comma-separated hostnames, where
+// *.example.com allows example.com's subdomains. YouTube's hosts are added.
+// nil means no policy, so requests may go anywhere. It is read per request,
+// so a reload changes it.
+func egressPolicy() []string {
+	v := getConfig(egressAllow, "YTSUMMARY_EGRESS_ALLOW")
+	if strings.TrimSpace(v) == "" {
+		return nil
+	}
+	hosts := append([]string(nil), youtubeEgressHosts...)
+	for _, h := range strings.Split(v, ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// egressAllowed reports whether the policy allows requests to host
+func egressAllowed(policy []string, host string) bool {
+	if policy == nil {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range policy {
+		if domain, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEgress returns errEgressDenied when the policy doesn't allow rawURL
+func checkEgress(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if !egressAllowed(egressPolicy(), u.Hostname()) {
+		return fmt.Errorf("%w: %s is not in YTSUMMARY_EGRESS_ALLOW", errEgressDenied, u.Hostname())
+	}
+	return nil
+}
+
+// egressTransport refuses requests the egress policy doesn't allow before
+// they leave. Every redirect passes through it too, so an allowed endpoint
+// can't bounce a request to an internal one.
+type egressTransport struct {
+	base http.RoundTripper
+}
+
+func (t egressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !egressAllowed(egressPolicy(), req.URL.Hostname()) {
+		logWarn("egress denied", slog.String("host", req.URL.Hostname()))
+		return nil, fmt.Errorf("%w: %s is not in YTSUMMARY_EGRESS_ALLOW", errEgressDenied, req.URL.Hostname())
+	}
+	base := t.base
+	if base == nil {
+		base = safeTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// egressClient returns a client for LLM and YouTube requests, which follow
+// the egress policy
+func egressClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: egressTransport{}}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEgressAllowed(t *testing.T) {
+	t.Setenv("YTSUMMARY_EGRESS_ALLOW", "openrouter.ai, *.openai.azure.com")
+	policy := egressPolicy()
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"openrouter.ai", true},
+		{"OpenRouter.ai.", true},
+		{"evil-openrouter.ai", false},
+		{"team.openai.azure.com", true},
+		{"openai.azure.com", false},
+		{"www.youtube.com", true},
+		{"youtube.com", true},
+		{"www.googleapis.com", true},
+		{"169.254.169.254", false},
+		{"localhost", false},
+	}
+	for _, tt := range tests {
+		if got := egressAllowed(policy, tt.host); got != tt.want {
+			t.Errorf("egressAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	t.Setenv("YTSUMMARY_EGRESS_ALLOW", "")
+	if policy := egressPolicy(); policy != nil || !egressAllowed(policy, "10.0.0.1") {
+		t.Error("without a policy, every host should be allowed")
+	}
+}
+
+func TestEgressPolicyLLM(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a host outside the policy")
+	}))
+	defer internal.Close()
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("bounce") != "" {
+			// localhost isn't 127.0.0.1 as far as the policy goes
+			http.Redirect(w, r, strings.Replace(internal.URL, "127.0.0.1", "localhost", 1)+"/chat/completions", http.StatusTemporaryRedirect)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"a summary"}}]}`))
+	}))
+	defer llm.Close()
+
+	t.Setenv("YTSUMMARY_EGRESS_ALLOW", "openrouter.ai")
+	client := &openAIClient{apiKey: "k", model: "m", apiURL: llm.URL}
+	if _, err := client.Complete("s", "t", GenerationParams{}); !errors.Is(err, errEgressDenied) {
+		t.Errorf("host outside the policy: err = %v, want errEgressDenied", err)
+	}
+
+	t.Setenv("YTSUMMARY_EGRESS_ALLOW", "openrouter.ai,127.0.0.1")
+	if _, err := client.Complete("s", "t", GenerationParams{}); err != nil {
+		t.Errorf("allowed host: %v", err)
+	}
+	req, _ := http.NewRequest("POST", llm.URL+"/chat/completions?bounce=1", nil)
+	if _, err := egressClient(0).Do(req); !errors.Is(err, errEgressDenied) {
+		t.Errorf("redirect outside the policy: err = %v, want errEgressDenied", err)
+	}
+}
+
+func TestEgressPolicyServeStartup(t *testing.T) {
+	t.Setenv("YTSUMMARY_EGRESS_ALLOW", "openrouter.ai")
+	t.Setenv("YTSUMMARY_API_URL", "http://169.254.169.254/latest")
+	t.Setenv("YTSUMMARY_PROVIDER", "")
+	cacheDir = t.TempDir()
+	if err := runServe(nil, nil); err == nil || !strings.Contains(err.Error(), "LLM API URL isn't allowed") {
+		t.Errorf("runServe = %v, want the API URL refused", err)
+	}
+}
+
+func TestEgressPolicyYouTube(t *testing.T) {
+	t.Setenv("YTSUMMARY_EGRESS_ALLOW", "openrouter.ai")
+	// A caption URL pointing at the cloud metadata service never leaves
+	if _, err := fetchCaptions(youtubeClient(), "http://169.254.169.254/api/timedtext?v=x"); !errors.Is(err, errEgressDenied) {
+		t.Errorf("fetchCaptions = %v, want errEgressDenied", err)
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// embeddingModel is --embedding-model
+var embeddingModel string
+
+const (
+	// defaultEmbeddingModel is OpenRouter's name for it; plain OpenAI calls it
+	// text-embedding-3-small
+	defaultEmbeddingModel = "openai/text-embedding-3-small"
+
+	// embeddingBatchSize is how many texts go in one embeddings request
+	embeddingBatchSize = 64
+
+	// maxEmbeddingChars is how much of a transcript is embedded: the opening
+	// says what a video is about, and fits every embedding model's input
+	maxEmbeddingChars = 6000
+
+	// fakeEmbeddingDims is the size of the fake provider's vectors
+	fakeEmbeddingDims = 64
+)
+
+// Embedder turns texts into vectors whose cosine similarity tracks how
+// related the texts are
+type Embedder interface {
+	Embed(texts []string) ([][]float32, error)
+	Model() string
+}
+
+// newEmbedder builds the embedder for the configured provider. The model is
+// --embedding-model (YTSUMMARY_EMBEDDING_MODEL), served by
+// YTSUMMARY_EMBEDDING_API_URL or else the LLM's API.
+func newEmbedder() (Embedder, error) {
+	switch provider := getConfig(llmProvider, "YTSUMMARY_PROVIDER"); provider {
+	case "", "openai":
+		apiKey, err := llmAPIKeyConfig()
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("no API key provided. Set YTSUMMARY_API_KEY, use --api-key or store one with 'ytsummary auth set-key'")
+		}
+		model := getConfig(embeddingModel, "YTSUMMARY_EMBEDDING_MODEL")
+		if model == "" {
+			model = defaultEmbeddingModel
+		}
+		apiURL := getConfig("", "YTSUMMARY_EMBEDDING_API_URL")
+		if apiURL == "" {
+			apiURL = getConfig(llmBaseURL, "YTSUMMARY_API_URL")
+		}
+		if apiURL == "" {
+			apiURL = defaultAPIURL
+		}
+		timeout, err := llmTimeoutConfig()
+		if err != nil {
+			return nil, err
+		}
+		return &openAIEmbedder{apiKey: apiKey, model: model, apiURL: apiURL, client: egressClient(timeout)}, nil
+	case "fake":
+		return fakeEmbedder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q (use openai or fake)", provider)
+	}
+}
+
+// openAIEmbedder calls an OpenAI-compatible embeddings API
+type openAIEmbedder struct {
+	apiKey string
+	model  string
+	apiURL string
+	client *http.Client
+}
+
+func (e *openAIEmbedder) Model() string {
+	return e.model
+}
+
+func (e *openAIEmbedder) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		batch, err := e.embedBatch(texts[start:min(start+embeddingBatchSize, len(texts))])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *openAIEmbedder) embedBatch(texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", e.apiURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newLLMError(e.model, resp.StatusCode, data)
+	}
+
+	var result struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+			Index     int       `json:"index"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings response: %w", err)
+	}
+	cliMetrics.recordTokens(result.Usage.PromptTokens, 0)
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) || len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("embeddings response has an unexpected item %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("embeddings response is missing item %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// fakeEmbedder hashes words into a small vector, so texts sharing words end
+// up close. Deterministic and offline, for tests and demos (--provider fake).
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Model() string {
+	return "fake"
+}
+
+func (fakeEmbedder) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, fakeEmbeddingDims)
+		for _, word := range topicWords(text) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			v[h.Sum32()%fakeEmbeddingDims]++
+		}
+		vectors[i] = normalizeVector(v)
+	}
+	return vectors, nil
+}
+
+// embeddingText is what gets embedded for a transcript: its title and opening
+func embeddingText(entry *CacheEntry) string {
+	text := []rune(entry.Transcript)
+	if len(text) > maxEmbeddingChars {
+		text = text[:maxEmbeddingChars]
+	}
+	return strings.TrimSpace(entry.Title + "\n" + string(text))
+}
+
+// normalizeVector scales v to unit length, so a dot product is the cosine
+func normalizeVector(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
+
+// dot returns the dot product of two vectors of the same length
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// topicWords returns the lowercase words of text that can carry a topic:
+// four letters or more, and not a common function word
+func topicWords(text string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		w = strings.Trim(w, "'")
+		if len([]rune(w)) >= 4 && !topicStopwords[w] {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// topicStopwords are frequent words that say nothing about a topic
+var topicStopwords = func() map[string]bool {
+	words := map[string]bool{}
+	for _, list := range languageStopwords {
+		for _, w := range list {
+			words[w] = true
+		}
+	}
+	for _, w := range strings.Fields(`
+		about actually after again also always another back basically because been before being
+		come could didn't doesn't don't down each even every first from going gonna good great
+		have here into it's just kind know let's like little look lot made make many maybe mean
+		more most much need never okay only other over people pretty really right same should
+		some something still such sure than that that's their them then there there's these they
+		they're thing things think this those through time today very want we're well were what
+		what's when where which while will with would yeah you're your`) {
+		words[w] = true
+	}
+	return words
+}()
+
+// EmbeddingStore caches transcript embeddings so reports don't embed the
+// same transcript twice. SQLiteCache implements it.
+type EmbeddingStore interface {
+	// GetEmbedding returns a transcript's cached vector for model, or
+	// errCacheMiss when there is none for text with this hash
+	GetEmbedding(videoID, language, model, textHash string) ([]float32, error)
+	StoreEmbedding(videoID, language, model, textHash string, vector []float32) error
+}
+
+func (c *SQLiteCache) GetEmbedding(videoID, language, model, textHash string) ([]float32, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = db.QueryRow("SELECT vector FROM embeddings WHERE video_id = ? AND language = ? AND model = ? AND text_hash = ?",
+		videoID, language, model, textHash).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embedding: %w", err)
+	}
+	vector := make([]float32, len(data)/4)
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, vector); err != nil {
+		return nil, fmt.Errorf("failed to decode cached embedding: %w", err)
+	}
+	return vector, nil
+}
+
+func (c *SQLiteCache) StoreEmbedding(videoID, language, model, textHash string, vector []float32) error {
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, vector)
+	_, err = db.Exec(`
+		INSERT OR REPLACE INTO embeddings (video_id, language, model, text_hash, vector, created_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`, videoID, language, model, textHash, data.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to cache embedding: %w", err)
+	}
+	return nil
+}
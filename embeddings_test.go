@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFakeEmbedder(t *testing.T) {
+	vectors, err := fakeEmbedder{}.Embed([]string{
+		"Sourdough starters need flour and water",
+		"Feeding a sourdough starter with flour",
+		"Elephants at the zoo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 3 || len(vectors[0]) != fakeEmbeddingDims {
+		t.Fatalf("vectors = %d of %d dims", len(vectors), len(vectors[0]))
+	}
+	if same, other := dot(vectors[0], vectors[1]), dot(vectors[0], vectors[2]); same <= other {
+		t.Errorf("related texts are %.2f similar, unrelated %.2f", same, other)
+	}
+}
+
+func TestTopicWords(t *testing.T) {
+	got := topicWords("So, what's REALLY going on with the Sourdough? It's the starter's yeast.")
+	want := []string{"sourdough", "starter's", "yeast"}
+	if len(got) != len(want) {
+		t.Fatalf("topicWords() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("topicWords() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestEmbeddingStore(t *testing.T) {
+	cache := newTestCache(t)
+	if _, err := cache.GetEmbedding("bakeVideo01", "en", "fake", "h1"); !errors.Is(err, errCacheMiss) {
+		t.Fatalf("empty store: err = %v", err)
+	}
+	if err := cache.StoreEmbedding("bakeVideo01", "en", "fake", "h1", []float32{0.6, -0.8}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := cache.GetEmbedding("bakeVideo01", "en", "fake", "h1")
+	if err != nil || len(got) != 2 || got[0] != 0.6 || got[1] != -0.8 {
+		t.Errorf("GetEmbedding() = %v, %v", got, err)
+	}
+
+	// A changed transcript or another model is a miss
+	if _, err := cache.GetEmbedding("bakeVideo01", "en", "fake", "h2"); !errors.Is(err, errCacheMiss) {
+		t.Errorf("changed text: err = %v", err)
+	}
+	if _, err := cache.GetEmbedding("bakeVideo01", "en", "other", "h1"); !errors.Is(err, errCacheMiss) {
+		t.Errorf("other model: err = %v", err)
+	}
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	var inputs [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" || r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("request to %s, auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		inputs = append(inputs, body.Input)
+
+		// Items may come back in any order
+		type item struct {
+			Embedding []float32 `json:"embedding"`
+			Index     int       `json:"index"`
+		}
+		var data []item
+		for i := len(body.Input) - 1; i >= 0; i-- {
+			data = append(data, item{[]float32{float32(len(inputs)), float32(i)}, i})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer srv.Close()
+
+	texts := make([]string, embeddingBatchSize+1)
+	for i := range texts {
+		texts[i] = "text"
+	}
+	e := &openAIEmbedder{apiKey: "k", model: "m", apiURL: srv.URL, client: egressClient(10 * time.Second)}
+	vectors, err := e.Embed(texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 2 || len(inputs[0]) != embeddingBatchSize || len(inputs[1]) != 1 {
+		t.Errorf("batches = %d", len(inputs))
+	}
+	if len(vectors) != len(texts) || vectors[1][1] != 1 || vectors[embeddingBatchSize][0] != 2 {
+		t.Errorf("vectors = %v", vectors)
+	}
+}
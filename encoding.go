@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// errCaptionEncoding marks caption payloads that can't be decoded as text
+var errCaptionEncoding = errors.New("undecodable caption encoding")
+
+// xmlEncodingPattern finds the encoding in an XML declaration
+var xmlEncodingPattern = regexp.MustCompile(`^<\?xml[^>]*\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// decodeText converts a caption or transcript payload to UTF-8. A byte order
+// mark wins, then the charset from contentType or an XML declaration, resolved
+// by its WHATWG label; undeclared text must be UTF-8, BOM-less UTF-16 or
+// Windows-1252. UTF-8 that was mistakenly decoded as Windows-1252 and
+// re-encoded ("cafÃ©") is repaired. It also returns the encoding converted
+// from, "" for UTF-8.
+func decodeText(body []byte, contentType string) (text, encoding string, err error) {
+	switch {
+	case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+		body = body[3:]
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE, 0, 0}), bytes.HasPrefix(body, []byte{0, 0, 0xFE, 0xFF}):
+		return "", "", fmt.Errorf("%w: UTF-32 is not supported", errCaptionEncoding)
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		text, err = decodeUTF16(body[2:], false)
+		return text, "utf-16le", err
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		text, err = decodeUTF16(body[2:], true)
+		return text, "utf-16be", err
+	}
+
+	if charset := declaredCharset(contentType, body); charset != "" {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: unsupported charset %q", errCaptionEncoding, charset)
+		}
+		name, _ := htmlindex.Name(enc)
+		switch name {
+		case "utf-8":
+		case "utf-16le", "utf-16be":
+			// A plain "utf-16" label says nothing of the byte order
+			bigEndian := name == "utf-16be" || charset == "utf-16" && looksUTF16(body) == "utf-16be"
+			text, err = decodeUTF16(body, bigEndian)
+			if bigEndian {
+				return text, "utf-16be", err
+			}
+			return text, "utf-16le", err
+		case "windows-1252":
+			// Servers often label UTF-8 as Latin-1; bytes that are valid
+			// UTF-8 are almost never Latin-1 text
+			if !utf8.Valid(body) {
+				return decodeWindows1252(body), "windows-1252", nil
+			}
+		case "replacement", "x-user-defined":
+			return "", "", fmt.Errorf("%w: unsupported charset %q", errCaptionEncoding, charset)
+		default:
+			decoded, err := enc.NewDecoder().Bytes(body)
+			if err != nil || !looksLikeText(string(decoded)) {
+				return "", "", fmt.Errorf("%w: invalid %s", errCaptionEncoding, name)
+			}
+			return string(decoded), name, nil
+		}
+	}
+
+	if utf8.Valid(body) {
+		text = string(body)
+		if repaired, ok := repairDoubleEncoding(text); ok {
+			text, encoding = repaired, "utf-8 (double-encoded)"
+		}
+		if !looksLikeText(text) {
+			return "", "", fmt.Errorf("%w: payload is not text", errCaptionEncoding)
+		}
+		return text, encoding, nil
+	}
+	if order := looksUTF16(body); order != "" {
+		text, err = decodeUTF16(body, order == "utf-16be")
+		return text, order, err
+	}
+	if hasValidMultibyte(body) {
+		return "", "", fmt.Errorf("%w: mixes UTF-8 with invalid bytes", errCaptionEncoding)
+	}
+	text = decodeWindows1252(body)
+	if !looksLikeText(text) {
+		return "", "", fmt.Errorf("%w: payload is not text", errCaptionEncoding)
+	}
+	return text, "windows-1252", nil
+}
+
+// declaredCharset returns the lowercased charset named by a Content-Type
+// header, else by the body's XML declaration, else ""
+func declaredCharset(contentType string, body []byte) string {
+	if contentType != "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+			return strings.ToLower(params["charset"])
+		}
+	}
+	if m := xmlEncodingPattern.FindSubmatch(body[:min(len(body), 200)]); m != nil {
+		return strings.ToLower(string(m[1]))
+	}
+	return ""
+}
+
+// looksUTF16 guesses the byte order of BOM-less UTF-16 from where the zero
+// high bytes of ASCII characters fall, or returns "" if it isn't UTF-16
+func looksUTF16(body []byte) string {
+	if len(body) < 4 || len(body)%2 != 0 {
+		return ""
+	}
+	sample := body[:min(len(body), 512)]
+	even, odd := 0, 0
+	for i := 0; i+1 < len(sample); i += 2 {
+		if sample[i] == 0 {
+			even++
+		}
+		if sample[i+1] == 0 {
+			odd++
+		}
+	}
+	pairs := len(sample) / 2
+	switch {
+	case odd*2 > pairs && even == 0:
+		return "utf-16le"
+	case even*2 > pairs && odd == 0:
+		return "utf-16be"
+	}
+	return ""
+}
+
+// decodeUTF16 decodes UTF-16 without a BOM
+func decodeUTF16(body []byte, bigEndian bool) (string, error) {
+	if len(body)%2 != 0 {
+		return "", fmt.Errorf("%w: truncated UTF-16", errCaptionEncoding)
+	}
+	order := unicode.LittleEndian
+	if bigEndian {
+		order = unicode.BigEndian
+	}
+	decoded, err := unicode.UTF16(order, unicode.IgnoreBOM).NewDecoder().Bytes(body)
+	if err != nil || !looksLikeText(string(decoded)) {
+		return "", fmt.Errorf("%w: invalid UTF-16", errCaptionEncoding)
+	}
+	return string(decoded), nil
+}
+
+// decodeWindows1252 decodes Windows-1252, a superset of Latin-1's printable
+// characters. Its five undefined bytes map to the C1 controls, as browsers do.
+func decodeWindows1252(body []byte) string {
+	decoded, _ := charmap.Windows1252.NewDecoder().Bytes(body)
+	return string(decoded)
+}
+
+// repairDoubleEncoding undoes UTF-8 that was decoded as Windows-1252 or
+// Latin-1 and encoded again. It only applies when every character maps back
+// to a single byte and those bytes form UTF-8 with multibyte sequences, which
+// real Latin-1 text practically never does.
+func repairDoubleEncoding(s string) (string, bool) {
+	if isASCII(s) {
+		return "", false
+	}
+	raw := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch b, ok := charmap.Windows1252.EncodeRune(r); {
+		case r < 0x100:
+			raw = append(raw, byte(r))
+		case ok:
+			raw = append(raw, b)
+		default:
+			return "", false
+		}
+	}
+	if !utf8.Valid(raw) {
+		return "", false
+	}
+	return string(raw), true
+}
+
+// hasValidMultibyte reports whether body contains at least one well-formed
+// multibyte UTF-8 sequence
+func hasValidMultibyte(body []byte) bool {
+	for len(body) > 0 {
+		r, size := utf8.DecodeRune(body)
+		if r != utf8.RuneError && size > 1 {
+			return true
+		}
+		body = body[size:]
+	}
+	return false
+}
+
+// isASCIIBytes reports whether body is entirely ASCII
+func isASCIIBytes(body []byte) bool {
+	for _, c := range body {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// looksLikeText rejects decoded binary: NULs, or more than one in a hundred
+// characters being control characters or replacement characters
+func looksLikeText(s string) bool {
+	bad, total := 0, 0
+	for _, r := range s {
+		total++
+		switch {
+		case r == 0:
+			return false
+		case r == '\t' || r == '\n' || r == '\r':
+		case r < 0x20 || r >= 0x7F && r < 0xA0 || r == utf8.RuneError:
+			bad++
+		}
+	}
+	return bad*100 <= total
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16Bytes encodes s as UTF-16 in the given byte order, without a BOM
+func utf16Bytes(s string, bigEndian bool) []byte {
+	var out []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return out
+}
+
+func TestDecodeText(t *testing.T) {
+	const caption = `<text start="0" dur="1">Café — “naïve”</text>`
+	tests := []struct {
+		name         string
+		body         []byte
+		contentType  string
+		want         string
+		wantEncoding string
+	}{
+		{"utf-8", []byte(caption), "text/xml; charset=UTF-8", caption, ""},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, caption...), "", caption, ""},
+		{"utf-16le bom", append([]byte{0xFF, 0xFE}, utf16Bytes(caption, false)...), "", caption, "utf-16le"},
+		{"utf-16be bom", append([]byte{0xFE, 0xFF}, utf16Bytes(caption, true)...), "text/xml", caption, "utf-16be"},
+		{"utf-16le without bom", utf16Bytes(caption, false), "", caption, "utf-16le"},
+		{"utf-16 declared", utf16Bytes(caption, true), "text/xml; charset=utf-16", caption, "utf-16be"},
+		{"undeclared windows-1252", []byte("Caf\xe9 \x97 \x93na\xefve\x94"), "", "Café — “naïve”", "windows-1252"},
+		{"declared latin-1", []byte("Caf\xe9"), "text/plain; charset=ISO-8859-1", "Café", "windows-1252"},
+		{"utf-8 mislabelled latin-1", []byte("Café"), "text/plain; charset=ISO-8859-1", "Café", ""},
+		{"xml declaration", []byte(`<?xml version="1.0" encoding="windows-1252"?><text>Caf` + "\xe9</text>"), "", `<?xml version="1.0" encoding="windows-1252"?><text>Café</text>`, "windows-1252"},
+		{"double-encoded", []byte("CafÃ© â€” â€œnaÃ¯veâ€\u009d"), "", "Café — “naïve”", "utf-8 (double-encoded)"},
+		{"declared shift_jis", []byte("\x93\xfa\x96\x7b\x8c\xea"), "text/plain; charset=Shift_JIS", "日本語", "shift_jis"},
+		{"declared euc-kr in xml", []byte(`<?xml version="1.0" encoding="EUC-KR"?>` + "\xc7\xd1\xb1\xb9\xbe\xee"), "", `<?xml version="1.0" encoding="EUC-KR"?>한국어`, "euc-kr"},
+		{"ascii labelled latin-1", []byte("Cafe"), "text/plain; charset=us-ascii", "Cafe", ""},
+		{"latin-1 letters left alone", []byte("Ångström Ã la carte"), "", "Ångström Ã la carte", ""},
+	}
+	for _, tt := range tests {
+		got, encoding, err := decodeText(tt.body, tt.contentType)
+		if err != nil {
+			t.Errorf("%s: error = %v", tt.name, err)
+			continue
+		}
+		if got != tt.want || encoding != tt.wantEncoding {
+			t.Errorf("%s: got %q (%q), want %q (%q)", tt.name, got, encoding, tt.want, tt.wantEncoding)
+		}
+	}
+}
+
+func TestDecodeTextRejectsUndecodable(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+	}{
+		{"unknown charset", []byte("caption"), "text/xml; charset=x-klingon"},
+		{"replacement charset", []byte("caption"), "text/xml; charset=ISO-2022-KR"},
+		{"gzip", []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\x03\xcbH\xcd\xc9\xc9\x07\x00"), ""},
+		{"mixed", []byte("Café and caf\xe9"), ""},
+		{"utf-32", []byte{0xFF, 0xFE, 0, 0, 'a', 0, 0, 0}, ""},
+		{"truncated utf-16", []byte{0xFF, 0xFE, 'a', 0, 'b'}, ""},
+	}
+	for _, tt := range tests {
+		if _, _, err := decodeText(tt.body, tt.contentType); !errors.Is(err, errCaptionEncoding) {
+			t.Errorf("%s: error = %v, want errCaptionEncoding", tt.name, err)
+		}
+	}
+}
+
+func TestFetchCaptionsDecodes(t *testing.T) {
+	body := append([]byte{0xFF, 0xFE}, utf16Bytes(`<transcript><text start="0" dur="1">Caf&#233; ouvert</text></transcript>`, false)...)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("charset") != "" {
+			w.Header().Set("Content-Type", "text/xml; charset="+r.URL.Query().Get("charset"))
+			w.Write([]byte("<transcript></transcript>"))
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	content, err := fetchCaptions(youtubeClient(), srv.URL)
+	if err != nil {
+		t.Fatalf("fetchCaptions() error = %v", err)
+	}
+	if got := parseTimedText(content, false); got != "Café ouvert" {
+		t.Errorf("parseTimedText = %q, want %q", got, "Café ouvert")
+	}
+
+	_, err = fetchCaptions(youtubeClient(), srv.URL+"?charset=x-klingon")
+	if class := fetchErrorClass(err); class != ErrCaptionEncoding {
+		t.Errorf("fetchErrorClass(%v) = %q, want %q", err, class, ErrCaptionEncoding)
+	}
+}
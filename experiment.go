@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Variant names of an A/B experiment
+const (
+	variantA = "a"
+	variantB = "b"
+)
+
+// experimentVariant is one arm of an experiment: a template and, optionally,
+// a model other than the configured one
+type experimentVariant struct {
+	Name     string
+	Template string
+	Model    string
+}
+
+// experiment splits /summarize traffic between two variants
+type experiment struct {
+	Name     string
+	Variants [2]experimentVariant
+	Split    float64 // share of requests served by variant b
+}
+
+// parseExperimentVariant parses TEMPLATE[@MODEL], e.g. key-points@openai/gpt-4o-mini,
+// checking the template is one of templates
+func parseExperimentVariant(name, v string, templates map[string]*PromptTemplate) (experimentVariant, error) {
+	template, model, _ := strings.Cut(strings.TrimSpace(v), "@")
+	if template == "" {
+		template = defaultTemplateName
+	}
+	if _, ok := templates[template]; !ok {
+		return experimentVariant{}, fmt.Errorf("experiment variant %s: unknown template %q (see 'ytsummary templates list')", name, template)
+	}
+	return experimentVariant{Name: name, Template: template, Model: model}, nil
+}
+
+// experimentFromEnv reads the experiment configured with YTSUMMARY_EXPERIMENT
+// (its name), YTSUMMARY_EXPERIMENT_A and _B (TEMPLATE[@MODEL]) and
+// YTSUMMARY_EXPERIMENT_SPLIT (percent of requests for b, default 50). It
+// returns nil when no experiment is running.
+func experimentFromEnv() (*experiment, error) {
+	if strings.TrimSpace(os.Getenv("YTSUMMARY_EXPERIMENT")) == "" {
+		return nil, nil
+	}
+	templates, err := loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	return experimentFrom(os.Getenv, templates)
+}
+
+// experimentFrom reads the experiment from the variables getenv returns,
+// which a reload uses to check .env before applying it
+func experimentFrom(getenv func(string) string, templates map[string]*PromptTemplate) (*experiment, error) {
+	name := strings.TrimSpace(getenv("YTSUMMARY_EXPERIMENT"))
+	if name == "" {
+		return nil, nil
+	}
+	e := &experiment{Name: name, Split: 0.5}
+	for i, v := range []string{variantA, variantB} {
+		variant, err := parseExperimentVariant(v, getenv("YTSUMMARY_EXPERIMENT_"+strings.ToUpper(v)), templates)
+		if err != nil {
+			return nil, err
+		}
+		e.Variants[i] = variant
+	}
+	if v := getenv("YTSUMMARY_EXPERIMENT_SPLIT"); v != "" {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid YTSUMMARY_EXPERIMENT_SPLIT %q (use a percentage of requests for variant b, 0-100)", v)
+		}
+		e.Split = percent / 100
+	}
+	return e, nil
+}
+
+// pick chooses the variant for one request
+func (e *experiment) pick() experimentVariant {
+	if rand.Float64() < e.Split {
+		return e.Variants[1]
+	}
+	return e.Variants[0]
+}
+
+// VariantReport compares how one variant of an experiment did
+type VariantReport struct {
+	Experiment       string
+	Variant          string
+	Models           []string
+	Summaries        int
+	Rated            int
+	AvgRating        float64 // over rated summaries
+	AvgWords         float64
+	AvgPromptTokens  float64
+	AvgOutputTokens  float64
+	AvgCostUSD       float64
+	UnpricedModelUse bool // some summaries used a model without known prices
+}
+
+// experimentReport aggregates the kept summaries of each experiment variant,
+// or of one experiment when name is set
+func experimentReport(summaries []*StoredSummary, name string) []*VariantReport {
+	byVariant := map[[2]string]*VariantReport{}
+	ratings := map[[2]string]int{}
+	for _, s := range summaries {
+		if s.Experiment == "" || name != "" && s.Experiment != name {
+			continue
+		}
+		key := [2]string{s.Experiment, s.Variant}
+		r := byVariant[key]
+		if r == nil {
+			r = &VariantReport{Experiment: s.Experiment, Variant: s.Variant}
+			byVariant[key] = r
+		}
+		r.Summaries++
+		if !slices.Contains(r.Models, s.Model) {
+			r.Models = append(r.Models, s.Model)
+		}
+		if s.Rating > 0 {
+			r.Rated++
+			ratings[key] += s.Rating
+		}
+		r.AvgWords += float64(len(strings.Fields(s.Summary)))
+		r.AvgPromptTokens += float64(s.PromptTokens)
+		r.AvgOutputTokens += float64(s.CompletionTokens)
+		cost, ok := estimateCost(s.Model, s.PromptTokens, s.CompletionTokens)
+		r.AvgCostUSD += cost
+		r.UnpricedModelUse = r.UnpricedModelUse || !ok
+	}
+
+	var reports []*VariantReport
+	for key, r := range byVariant {
+		n := float64(r.Summaries)
+		r.AvgWords /= n
+		r.AvgPromptTokens /= n
+		r.AvgOutputTokens /= n
+		r.AvgCostUSD /= n
+		if r.Rated > 0 {
+			r.AvgRating = float64(ratings[key]) / float64(r.Rated)
+		}
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Experiment != reports[j].Experiment {
+			return reports[i].Experiment < reports[j].Experiment
+		}
+		return reports[i].Variant < reports[j].Variant
+	})
+	return reports
+}
+
+// runExperimentsReport compares the variants of experiments run by the server
+func runExperimentsReport(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	summaries, err := cache.ListExperimentSummaries(name)
+	if err != nil {
+		return err
+	}
+	reports := experimentReport(summaries, name)
+	if len(reports) == 0 {
+		fmt.Println("No experiment summaries kept yet")
+		return nil
+	}
+
+	fmt.Printf("%-20s %-7s %9s %6s %10s %9s %12s %10s  %s\n", "EXPERIMENT", "VARIANT", "SUMMARIES", "RATED", "AVG RATING", "AVG WORDS", "AVG TOKENS", "AVG COST", "MODEL")
+	for _, r := range reports {
+		rating := "-"
+		if r.Rated > 0 {
+			rating = fmt.Sprintf("%.2f", r.AvgRating)
+		}
+		cost := fmt.Sprintf("$%.4f", r.AvgCostUSD)
+		if r.UnpricedModelUse {
+			cost += "*"
+		}
+		tokens := fmt.Sprintf("%.0f+%.0f", r.AvgPromptTokens, r.AvgOutputTokens)
+		fmt.Printf("%-20s %-7s %9d %6d %10s %9.0f %12s %10s  %s\n", r.Experiment, r.Variant, r.Summaries, r.Rated, rating, r.AvgWords, tokens, cost, strings.Join(r.Models, ","))
+	}
+	for _, r := range reports {
+		if r.UnpricedModelUse {
+			fmt.Println("* some models have no known prices and count as free; see 'ytsummary models pricing'")
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExperimentFromEnv(t *testing.T) {
+	if e, err := experimentFromEnv(); err != nil || e != nil {
+		t.Fatalf("experimentFromEnv() without YTSUMMARY_EXPERIMENT = %+v, %v; want nil", e, err)
+	}
+
+	t.Setenv("YTSUMMARY_EXPERIMENT", "prompt-v2")
+	t.Setenv("YTSUMMARY_EXPERIMENT_B", "key-points@openai/gpt-4o-mini")
+	t.Setenv("YTSUMMARY_EXPERIMENT_SPLIT", "20%")
+	e, err := experimentFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [2]experimentVariant{
+		{Name: variantA, Template: defaultTemplateName},
+		{Name: variantB, Template: "key-points", Model: "openai/gpt-4o-mini"},
+	}
+	if e.Name != "prompt-v2" || e.Variants != want || e.Split != 0.2 {
+		t.Errorf("experiment = %+v, want prompt-v2 %+v split 0.2", e, want)
+	}
+
+	for _, tt := range []struct{ name, value string }{
+		{"YTSUMMARY_EXPERIMENT_SPLIT", "150"},
+		{"YTSUMMARY_EXPERIMENT_SPLIT", "half"},
+		{"YTSUMMARY_EXPERIMENT_A", "no-such-template"},
+	} {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			if _, err := experimentFromEnv(); err == nil {
+				t.Errorf("%s=%s accepted", tt.name, tt.value)
+			}
+		})
+	}
+}
+
+func TestExperimentPick(t *testing.T) {
+	e := &experiment{Variants: [2]experimentVariant{{Name: variantA}, {Name: variantB}}}
+	for split, want := range map[float64]string{0: variantA, 1: variantB} {
+		e.Split = split
+		for range 20 {
+			if got := e.pick().Name; got != want {
+				t.Fatalf("split %v picked %s, want %s", split, got, want)
+			}
+		}
+	}
+}
+
+func TestExperimentReport(t *testing.T) {
+	summaries := []*StoredSummary{
+		{Experiment: "x", Variant: variantA, Model: "m", Summary: "one two three four", PromptTokens: 100, CompletionTokens: 10, Rating: 4},
+		{Experiment: "x", Variant: variantA, Model: "m", Summary: "one two", PromptTokens: 200, CompletionTokens: 20, Rating: 2},
+		{Experiment: "x", Variant: variantB, Model: "m2", Summary: "one", PromptTokens: 50, CompletionTokens: 5},
+		{Experiment: "other", Variant: variantA, Model: "m", Summary: "skipped"},
+	}
+	reports := experimentReport(summaries, "x")
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, want 2", len(reports))
+	}
+	a, b := reports[0], reports[1]
+	if a.Variant != variantA || a.Summaries != 2 || a.Rated != 2 || a.AvgRating != 3 || a.AvgWords != 3 || a.AvgPromptTokens != 150 {
+		t.Errorf("variant a = %+v", a)
+	}
+	if b.Variant != variantB || b.Summaries != 1 || b.Rated != 0 || b.Models[0] != "m2" || !b.UnpricedModelUse {
+		t.Errorf("variant b = %+v", b)
+	}
+	if all := experimentReport(summaries, ""); len(all) != 3 {
+		t.Errorf("report of all experiments has %d variants, want 3", len(all))
+	}
+}
+
+func TestExperimentServedAndRated(t *testing.T) {
+	t.Setenv("YTSUMMARY_EXPERIMENT", "prompt-v2")
+	t.Setenv("YTSUMMARY_EXPERIMENT_B", "key-points@cheap-model")
+	t.Setenv("YTSUMMARY_EXPERIMENT_SPLIT", "100")
+	defaults, err := requestDefaultsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newTestCache(t)
+	cacheTranscript(cache, "dQw4w9WgXcQ", "en", "Rated", "One. Two. Three.")
+	var gotModel, gotTemplate string
+	handler := newServer(ServerConfig{
+		Cache:     cache,
+		Summaries: cache,
+		Defaults:  defaults,
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			gotModel, gotTemplate = opts.Model, opts.Template
+			return summarizeWith(&fakeLLMClient{sentences: 1}, transcript, opts)
+		},
+	}).Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/v1/summarize", bytes.NewBufferString(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("summarize status = %d: %s", w.Code, w.Body)
+	}
+	var resp TranscriptResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Experiment != "prompt-v2" || resp.Variant != variantB || resp.SummaryID == 0 {
+		t.Errorf("response experiment = %q/%q summary %d, want prompt-v2/b and a summary ID", resp.Experiment, resp.Variant, resp.SummaryID)
+	}
+	if gotModel != "cheap-model" || gotTemplate != "key-points" {
+		t.Errorf("summarized with %s/%s, want key-points/cheap-model", gotTemplate, gotModel)
+	}
+
+	for _, tt := range []struct {
+		id, body string
+		want     int
+	}{
+		{fmt.Sprint(resp.SummaryID), `{"rating": 9}`, http.StatusBadRequest},
+		{"999", `{"rating": 3}`, http.StatusNotFound},
+		{fmt.Sprint(resp.SummaryID), `{"rating": 5, "feedback": "spot on"}`, http.StatusNoContent},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/summaries/"+tt.id+"/feedback", bytes.NewBufferString(tt.body))
+		req.RemoteAddr = "192.0.2.77:1234"
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("feedback %s on %s: status = %d, want %d: %s", tt.body, tt.id, w.Code, tt.want, w.Body)
+		}
+	}
+
+	kept, err := cache.ListExperimentSummaries("prompt-v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 || kept[0].Variant != variantB || kept[0].Rating != 5 || kept[0].Feedback != "spot on" {
+		t.Errorf("kept experiment summaries = %+v", kept)
+	}
+
+	// Naming a template opts out
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/v1/summarize", bytes.NewBufferString(`{"url": "https://youtu.be/dQw4w9WgXcQ", "template": "detailed"}`)))
+	resp = TranscriptResponse{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Variant != "" || gotModel != "" {
+		t.Errorf("explicit template served variant %q with model %q, want none", resp.Variant, gotModel)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export page sizes
+const (
+	defaultExportLimit = 100
+	maxExportLimit     = 1000
+)
+
+// ExportRecord is one NDJSON line of GET /export
+type ExportRecord struct {
+	VideoID         string    `json:"video_id"`
+	Language        string    `json:"language"`
+	Title           string    `json:"title,omitempty"`
+	Channel         string    `json:"channel,omitempty"`
+	DurationSeconds int       `json:"duration_seconds,omitempty"`
+	Transcript      string    `json:"transcript"`
+	FetchedAt       time.Time `json:"fetched_at"`
+	Notes           string    `json:"notes,omitempty"` // the user's notes on the video
+
+	// Summaries are the video's newest summary of each template in Language
+	Summaries []*StoredSummary `json:"summaries,omitempty"`
+}
+
+// exportCursor is the position of the last exported row, for keyset pagination
+type exportCursor struct {
+	FetchedAt string `json:"f"` // sqliteTimeFormat
+	VideoID   string `json:"v"`
+	Language  string `json:"l"`
+}
+
+func (c exportCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeExportCursor(s string) (*exportCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c exportCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.FetchedAt == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &c, nil
+}
+
+// parseExportSince accepts a date (2024-01-01) or an RFC 3339 timestamp
+func parseExportSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since %q: use YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// handleExport streams cached transcripts as NDJSON, one page at a time.
+// When more rows remain, the X-Next-Cursor header and a Link rel="next" header
+// point at the next page.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	since, err := parseExportSince(q.Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	limit := defaultExportLimit
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxExportLimit {
+			writeError(w, http.StatusBadRequest, ErrInvalidRequest, fmt.Sprintf("limit must be between 1 and %d", maxExportLimit))
+			return
+		}
+	}
+
+	var after *exportCursor
+	if v := q.Get("cursor"); v != "" {
+		if after, err = decodeExportCursor(v); err != nil {
+			writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	entries, err := s.cache.ListTranscripts(since, after, limit+1)
+	if err != nil {
+		logError("export failed", slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, ErrInternal, "Failed to read cache")
+		return
+	}
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		next := exportCursor{
+			FetchedAt: last.FetchedAt.UTC().Format(sqliteTimeFormat),
+			VideoID:   last.VideoID,
+			Language:  last.Language,
+		}.encode()
+
+		nextQuery := url.Values{"cursor": {next}, "limit": {strconv.Itoa(limit)}}
+		if s := q.Get("since"); s != "" {
+			nextQuery.Set("since", s)
+		}
+		w.Header().Set("X-Next-Cursor", next)
+		w.Header().Add("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, nextQuery.Encode()))
+	}
+
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.VideoID
+	}
+
+	notes := map[string]string{}
+	if s.notes != nil {
+		if notes, err = s.notes.ListNotes(ids); err != nil {
+			logError("export failed", slog.String("error", err.Error()))
+			writeError(w, http.StatusInternalServerError, ErrInternal, "Failed to read notes")
+			return
+		}
+	}
+
+	summaries := map[string][]*StoredSummary{}
+	if s.summaries != nil {
+		if summaries, err = s.summaries.ListLatestSummaries(ids); err != nil {
+			logError("export failed", slog.String("error", err.Error()))
+			writeError(w, http.StatusInternalServerError, ErrInternal, "Failed to read summaries")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		err := enc.Encode(ExportRecord{
+			VideoID:         entry.VideoID,
+			Language:        entry.Language,
+			Title:           entry.Title,
+			Channel:         entry.Channel,
+			DurationSeconds: entry.DurationSeconds,
+			Transcript:      entry.Transcript,
+			FetchedAt:       entry.FetchedAt.UTC(),
+			Notes:           notes[entry.VideoID],
+			Summaries:       summariesInLanguage(summaries[entry.VideoID], entry.Language),
+		})
+		if err != nil {
+			// Client went away; nothing more to do
+			logWarn("export write failed", slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	logDebug("export page written", slog.Int("records", len(entries)), slog.Bool("more", w.Header().Get("X-Next-Cursor") != ""))
+}
+
+// summariesInLanguage returns the summaries written in lang
+func summariesInLanguage(summaries []*StoredSummary, lang string) []*StoredSummary {
+	var matched []*StoredSummary
+	for _, summary := range summaries {
+		if strings.EqualFold(summary.Language, lang) {
+			matched = append(matched, summary)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeYouTube serves innertube player responses and timedtext captions from
+// recorded fixtures (see --record-fixtures) so scraper tests never hit YouTube
+type fakeYouTube struct {
+	*httptest.Server
+	fixtureDir string
+
+	// playerStatus forces the player endpoint to fail with this status when non-zero
+	playerStatus int
+
+	playerRequests  atomic.Int32
+	captionRequests atomic.Int32
+
+	// lastPlayer is the most recent player request, for checking client identity
+	lastPlayer atomic.Pointer[playerCall]
+}
+
+// playerCall records what a client sent to the player endpoint
+type playerCall struct {
+	header http.Header
+	client string
+	region string // gl in the client context
+}
+
+// newFakeYouTube starts a fake YouTube backed by testdata/innertube and points
+// the scraper at it for the duration of the test
+func newFakeYouTube(t *testing.T) *fakeYouTube {
+	t.Helper()
+
+	fake := &fakeYouTube{fixtureDir: "testdata/innertube"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /youtubei/v1/player", fake.handlePlayer)
+	mux.HandleFunc("GET /api/timedtext", fake.handleTimedText)
+	mux.HandleFunc("GET /clip/{id}", fake.handleClip)
+	fake.Server = httptest.NewServer(mux)
+
+	oldURL, oldWebURL := innertubeURL, youtubeWebURL
+	innertubeURL = fake.URL + "/youtubei/v1/player"
+	youtubeWebURL = fake.URL
+	t.Cleanup(func() {
+		innertubeURL, youtubeWebURL = oldURL, oldWebURL
+		fake.Close()
+	})
+
+	return fake
+}
+
+func (f *fakeYouTube) handlePlayer(w http.ResponseWriter, r *http.Request) {
+	f.playerRequests.Add(1)
+
+	if f.playerStatus != 0 {
+		w.WriteHeader(f.playerStatus)
+		return
+	}
+
+	var req innertubeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.lastPlayer.Store(&playerCall{header: r.Header.Clone(), client: req.Context.Client.ClientName, region: req.Context.Client.Gl})
+
+	// Responses for a region hint are stored as <id>.<gl>.json
+	body, err := os.ReadFile(filepath.Join(f.fixtureDir, req.VideoID+"."+req.Context.Client.Gl+".json"))
+	if req.Context.Client.Gl == "" || err != nil {
+		body, err = os.ReadFile(filepath.Join(f.fixtureDir, req.VideoID+".json"))
+	}
+	if err != nil {
+		// Unknown videos look like deleted ones
+		w.Write([]byte(`{"playabilityStatus":{"status":"ERROR","reason":"This video is unavailable"}}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(strings.ReplaceAll(string(body), fixtureBaseURL, f.URL)))
+}
+
+func (f *fakeYouTube) handleTimedText(w http.ResponseWriter, r *http.Request) {
+	f.captionRequests.Add(1)
+
+	// Machine translations (tlang) are stored as <id>.<source>.<target>.xml
+	q := r.URL.Query()
+	lang := q.Get("lang")
+	if tlang := q.Get("tlang"); tlang != "" {
+		lang += "." + tlang
+	}
+	body, err := os.ReadFile(filepath.Join(f.fixtureDir, q.Get("v")+"."+lang+".xml"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Write(body)
+}
+
+func (f *fakeYouTube) handleClip(w http.ResponseWriter, r *http.Request) {
+	body, err := os.ReadFile(filepath.Join(f.fixtureDir, "clip_"+r.PathValue("id")+".html"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(body)
+}
+
+func TestFetchTranscriptDirect_FakeYouTube(t *testing.T) {
+	newFakeYouTube(t)
+
+	tests := []struct {
+		name        string
+		url         string
+		lang        string
+		wantText    string
+		wantLang    string
+		wantErrText string
+	}{
+		{"english asr", "https://youtu.be/dQw4w9WgXcQ", "en", "We're no strangers to love", "en", ""},
+		{"spanish track", "https://youtu.be/dQw4w9WgXcQ", "es", "Nunca te voy a abandonar", "es", ""},
+		{"no captions", "https://youtu.be/noCaptions1", "en", "", "", "no subtitles available"},
+		{"private", "https://youtu.be/privateVid1", "en", "", "", "Private video"},
+		{"age restricted", "https://youtu.be/ageRestrict", "en", "", "", "age-restricted"},
+		{"geo restricted", "https://youtu.be/geoBlocked1", "en", "", "", "not available in this region"},
+		{"unknown video", "https://youtu.be/unknownVid1", "en", "", "", "video error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fetchTranscriptDirect(tt.url, tt.lang, false)
+			if tt.wantErrText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrText) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErrText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchTranscriptDirect() error = %v", err)
+			}
+			if !strings.Contains(result.Transcript, tt.wantText) {
+				t.Errorf("Transcript = %q, want containing %q", result.Transcript, tt.wantText)
+			}
+			if result.Language != tt.wantLang {
+				t.Errorf("Language = %q, want %q", result.Language, tt.wantLang)
+			}
+		})
+	}
+}
+
+func TestFetchTranscriptDirect_AutoTranslate(t *testing.T) {
+	newFakeYouTube(t)
+
+	tests := []struct {
+		name           string
+		lang           string
+		allow          bool
+		wantText       string
+		wantLang       string
+		wantTranslated string
+	}{
+		{"translated from uploaded track", "fr", true, "Je ne t'abandonnerai jamais", "fr", "es"},
+		{"translation not allowed", "fr", false, "We're no strangers to love", "en", ""},
+		{"translation not offered", "it", true, "We're no strangers to love", "en", ""},
+		{"native track preferred", "es", true, "Nunca te voy a abandonar", "es", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fetchTranscriptDirect("https://youtu.be/dQw4w9WgXcQ", tt.lang, tt.allow)
+			if err != nil {
+				t.Fatalf("fetchTranscriptDirect() error = %v", err)
+			}
+			if !strings.Contains(result.Transcript, tt.wantText) {
+				t.Errorf("Transcript = %q, want containing %q", result.Transcript, tt.wantText)
+			}
+			if result.Language != tt.wantLang || result.TranslatedFrom != tt.wantTranslated {
+				t.Errorf("Language = %q, TranslatedFrom = %q, want %q from %q", result.Language, result.TranslatedFrom, tt.wantLang, tt.wantTranslated)
+			}
+		})
+	}
+}
+
+func TestFetchTranscriptDirect_RateLimited(t *testing.T) {
+	fake := newFakeYouTube(t)
+	fake.playerStatus = http.StatusTooManyRequests
+
+	_, err := fetchTranscriptDirect("https://youtu.be/dQw4w9WgXcQ", "en", false)
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("error = %v, want rate limit error", err)
+	}
+}
+
+func TestFetchTranscriptDirect_RegionHint(t *testing.T) {
+	fake := newFakeYouTube(t)
+	oldRegion := youtubeRegion
+	t.Cleanup(func() { youtubeRegion = oldRegion })
+
+	// A region the video is blocked in too keeps the geo-restriction error
+	youtubeRegion = "fr"
+	_, err := fetchTranscriptDirect("https://youtu.be/geoBlocked1", "en", false)
+	if !errors.Is(err, errGeoRestricted) {
+		t.Fatalf("error = %v, want errGeoRestricted", err)
+	}
+	if call := fake.lastPlayer.Load(); call.region != "FR" {
+		t.Errorf("retry region = %q, want FR", call.region)
+	}
+
+	youtubeRegion = "GB"
+	fake.playerRequests.Store(0)
+	result, err := fetchTranscriptDirect("https://youtu.be/geoBlocked1", "en", false)
+	if err != nil {
+		t.Fatalf("fetchTranscriptDirect() error = %v", err)
+	}
+	if !strings.Contains(result.Transcript, "We're no strangers to love") {
+		t.Errorf("Transcript = %q", result.Transcript)
+	}
+	if n := fake.playerRequests.Load(); n != 2 {
+		t.Errorf("player requests = %d, want 2", n)
+	}
+
+	// Playable videos never use the hint
+	fake.playerRequests.Store(0)
+	if _, err := fetchTranscriptDirect("https://youtu.be/dQw4w9WgXcQ", "en", false); err != nil {
+		t.Fatal(err)
+	}
+	if call := fake.lastPlayer.Load(); fake.playerRequests.Load() != 1 || call.region != "" {
+		t.Errorf("playable video: %d requests, region %q", fake.playerRequests.Load(), call.region)
+	}
+}
+
+func TestRecordFixtures(t *testing.T) {
+	newFakeYouTube(t)
+
+	recordFixturesDir = t.TempDir()
+	defer func() { recordFixturesDir = "" }()
+
+	if _, err := fetchTranscriptDirect("https://youtu.be/dQw4w9WgXcQ", "en", false); err != nil {
+		t.Fatalf("fetchTranscriptDirect() error = %v", err)
+	}
+
+	player, err := os.ReadFile(filepath.Join(recordFixturesDir, "dQw4w9WgXcQ.json"))
+	if err != nil {
+		t.Fatalf("player fixture not recorded: %v", err)
+	}
+	if !strings.Contains(string(player), fixtureBaseURL+"/api/timedtext") {
+		t.Errorf("recorded caption URL not rewritten to %s:\n%s", fixtureBaseURL, player)
+	}
+
+	if _, err := os.ReadFile(filepath.Join(recordFixturesDir, "dQw4w9WgXcQ.en.xml")); err != nil {
+		t.Errorf("caption fixture not recorded: %v", err)
+	}
+}
+
+func TestSanitizePlayerResponse(t *testing.T) {
+	body := `{
+		"responseContext": {"visitorData": "secret-visitor"},
+		"streamingData": {"formats": [{"url": "https://rr1.googlevideo.com/videoplayback?ip=1.2.3.4"}]},
+		"videoDetails": {"videoId": "dQw4w9WgXcQ", "title": "Test"},
+		"captions": {"playerCaptionsTracklistRenderer": {"captionTracks": [
+			{"baseUrl": "https://www.youtube.com/api/timedtext?v=dQw4w9WgXcQ&ei=abc&caps=asr&opi=1&xoaf=5&hl=en&ip=0.0.0.0&ipbits=0&expire=1700000000&sparams=ip,ipbits,expire&signature=DEADBEEF&key=yt8&kind=asr&lang=en", "languageCode": "en"}
+		]}},
+		"playabilityStatus": {"status": "OK"}
+	}`
+
+	got, err := sanitizePlayerResponse([]byte(body))
+	if err != nil {
+		t.Fatalf("sanitizePlayerResponse() error = %v", err)
+	}
+
+	for _, leaked := range []string{"secret-visitor", "googlevideo", "signature", "DEADBEEF", "0.0.0.0", "expire"} {
+		if strings.Contains(string(got), leaked) {
+			t.Errorf("sanitized fixture still contains %q:\n%s", leaked, got)
+		}
+	}
+
+	var pr YouTubePlayerResponse
+	if err := json.Unmarshal(got, &pr); err != nil {
+		t.Fatalf("sanitized fixture is not a valid player response: %v", err)
+	}
+	tracks := pr.Captions.PlayerCaptionsTracklistRenderer.CaptionTracks
+	if len(tracks) != 1 || tracks[0].BaseURL != fixtureBaseURL+"/api/timedtext?kind=asr&lang=en&v=dQw4w9WgXcQ" {
+		t.Errorf("unexpected caption tracks: %+v", tracks)
+	}
+}
+
+func TestDebugScrapeSavesFailedFetch(t *testing.T) {
+	newFakeYouTube(t)
+	dir := t.TempDir()
+	t.Setenv("YTSUMMARY_DEBUG_SCRAPE", dir)
+
+	// Successful fetches leave nothing behind
+	if _, err := fetchTranscriptDirect("https://youtu.be/dQw4w9WgXcQ", "en", false); err != nil {
+		t.Fatalf("fetchTranscriptDirect() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("saved diagnostics for a successful fetch: %v", entries)
+	}
+
+	if _, err := fetchTranscriptDirect("https://youtu.be/privateVid1", "en", false); err == nil {
+		t.Fatal("expected error for a private video")
+	}
+	saved, _ := filepath.Glob(filepath.Join(dir, "privateVid1-*"))
+	if len(saved) != 1 {
+		t.Fatalf("diagnostics dirs = %v, want one for the failed fetch", saved)
+	}
+	meta, err := os.ReadFile(filepath.Join(saved[0], "error.json"))
+	if err != nil || !strings.Contains(string(meta), `"error_class": "video_unavailable"`) {
+		t.Errorf("error.json = %s (%v), want the error class", meta, err)
+	}
+	if _, err := os.Stat(filepath.Join(saved[0], "player.json")); err != nil {
+		t.Errorf("player.json not saved: %v", err)
+	}
+}
+
+func TestScrubPlayerResponse(t *testing.T) {
+	body := `{
+		"responseContext": {"visitorData": "secret-visitor"},
+		"playbackTracking": {"videostatsPlaybackUrl": {"baseUrl": "https://s.youtube.com/api/stats?cpn=x"}},
+		"streamingData": {"formats": [{"itag": 18, "url": "https://rr1.googlevideo.com/videoplayback?ip=1.2.3.4&itag=18&sig=abc"}]},
+		"captions": {"playerCaptionsTracklistRenderer": {"captionTracks": [
+			{"baseUrl": "https://www.youtube.com/api/timedtext?v=dQw4w9WgXcQ&signature=DEADBEEF&lang=en", "languageCode": "en"}
+		]}}
+	}`
+	got := string(scrubPlayerResponse([]byte(body)))
+	for _, secret := range []string{"secret-visitor", "1.2.3.4", "DEADBEEF", "sig=abc", "playbackTracking"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("scrubbed response still contains %q:\n%s", secret, got)
+		}
+	}
+	for _, kept := range []string{"itag=18", "https://www.youtube.com/api/timedtext?lang=en", `"languageCode": "en"`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("scrubbed response lost %q:\n%s", kept, got)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// fixtureBaseURL is substituted for YouTube's host in recorded caption URLs so
+// the fake YouTube server used by tests can point them back at itself
+const fixtureBaseURL = "{{BASE_URL}}"
+
+// Top-level player response fields the scraper reads; everything else
+// (streaming URLs, tracking, visitor data) is dropped from recorded fixtures
+var fixturePlayerFields = []string{"videoDetails", "captions", "playabilityStatus", "microformat"}
+
+// Caption URL query parameters worth keeping; signatures, IPs and expiry are dropped
+var fixtureCaptionParams = []string{"v", "lang", "kind", "fmt", "tlang", "name"}
+
+// recordPlayerFixture saves a sanitized innertube player response as <videoID>.json
+func recordPlayerFixture(videoID string, body []byte) {
+	sanitized, err := sanitizePlayerResponse(body)
+	if err != nil {
+		logWarn("failed to sanitize fixture", "video_id", videoID, "error", err.Error())
+		return
+	}
+	writeFixture(videoID+".json", sanitized)
+}
+
+// recordCaptionFixture saves a raw caption payload as <videoID>.<lang>.xml
+func recordCaptionFixture(videoID, lang, content string) {
+	writeFixture(fmt.Sprintf("%s.%s.xml", videoID, lang), []byte(content))
+}
+
+func writeFixture(name string, data []byte) {
+	if err := os.MkdirAll(recordFixturesDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to create fixture directory: %v\n", err)
+		return
+	}
+	path := filepath.Join(recordFixturesDir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record fixture: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "recorded fixture %s\n", path)
+}
+
+// sanitizePlayerResponse strips a player response down to the fields the scraper
+// uses and scrubs per-request tokens from caption URLs
+func sanitizePlayerResponse(body []byte) ([]byte, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse player response: %w", err)
+	}
+
+	kept := make(map[string]any)
+	for _, field := range fixturePlayerFields {
+		if v, ok := raw[field]; ok {
+			kept[field] = scrubCaptionURLs(v)
+		}
+	}
+
+	return json.MarshalIndent(kept, "", "  ")
+}
+
+// scrubCaptionURLs walks a decoded JSON value rewriting every baseUrl
+func scrubCaptionURLs(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if s, ok := child.(string); ok && k == "baseUrl" {
+				val[k] = sanitizeCaptionURL(s)
+			} else {
+				val[k] = scrubCaptionURLs(child)
+			}
+		}
+	case []any:
+		for i, child := range val {
+			val[i] = scrubCaptionURLs(child)
+		}
+	}
+	return v
+}
+
+// sanitizeCaptionURL replaces the host with fixtureBaseURL and keeps only
+// non-sensitive query parameters
+func sanitizeCaptionURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fixtureBaseURL + "/api/timedtext"
+	}
+
+	q := u.Query()
+	kept := url.Values{}
+	for _, param := range fixtureCaptionParams {
+		if v := q.Get(param); v != "" {
+			kept.Set(param, v)
+		}
+	}
+
+	return fixtureBaseURL + u.Path + "?" + kept.Encode()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Video formats, classified from a transcript's structure
+const (
+	formatMonologue = "monologue" // one speaker talking to the camera
+	formatInterview = "interview" // two speakers taking turns
+	formatPanel     = "panel"     // three or more speakers
+	formatTutorial  = "tutorial"  // step-by-step instructions
+	formatVlog      = "vlog"      // first-person diary of someone's day
+	formatMusic     = "music"     // songs and lyric captions
+)
+
+// formatTemplates is the prompt template best suited to each format; formats
+// left out summarize with the default template
+var formatTemplates = map[string]string{
+	formatInterview: "conversation",
+	formatPanel:     "conversation",
+	formatTutorial:  "tutorial",
+	formatMusic:     "music",
+}
+
+// Thresholds, tuned on captions of each format. Rates are per 1000 words.
+const (
+	minFormatWords = 40 // shorter transcripts are too little to go on
+
+	minLyricRepetition = 0.3 // share of words in phrases sung three times or more
+	maxWordsPerNote    = 25  // lyric captions mark every line with ♪
+
+	// In YouTube's Music category, which also holds reviews and lessons, a
+	// little repetition or sparse words are enough for a song
+	minCategoryLyricRepetition = 0.15
+	maxSongWordsPerMinute      = 100
+
+	minSpeakerTurns    = 4
+	minSpeakerTurnRate = 2.0
+
+	minTutorialCueRate       = 10.0
+	minTitledTutorialCueRate = 3.0
+
+	minFirstPersonShare = 0.05
+	minVlogCueRate      = 4.0
+)
+
+var (
+	// speakerTurnRe matches a change of speaker: the >> auto-generated
+	// captions insert, or an uppercase speaker label such as "JOHN:"
+	speakerTurnRe = regexp.MustCompile(`>>\s*(?:([A-Z]{2,}(?:[ .'-]+[A-Z]{2,})*):\s)?|\b([A-Z]{2,}(?:[ .'-]+[A-Z]{2,})*):\s`)
+
+	tutorialCueRe = regexp.MustCompile(`(?i)\b(?:step (?:one|two|three|four|five|\d+)|the next step|click|tap on|select|install|download|go ahead and|make sure|you(?:'ll| will) need|let's (?:go|start|add|create|open|move|take)|(?:first|next|now) we(?:'re going to| need to| can)?)\b`)
+	vlogCueRe     = regexp.MustCompile(`(?i)\b(?:vlog|today|this morning|tonight|hey guys|you guys|come with me|day in (?:the|my) life|morning routine)\b`)
+
+	musicTitleRe    = regexp.MustCompile(`(?i)official (?:music )?video|official audio|lyric video|\blyrics\b`)
+	tutorialTitleRe = regexp.MustCompile(`(?i)\b(?:how to|tutorial|guide|walkthrough|step[- ]by[- ]step|for beginners|course|lesson)\b`)
+	vlogTitleRe     = regexp.MustCompile(`(?i)\b(?:vlog|day in (?:the|my) life)\b`)
+	panelTitleRe    = regexp.MustCompile(`(?i)\b(?:panel|roundtable|round table|debate)\b`)
+)
+
+// FormatClassification is what a transcript's structure says about a video
+type FormatClassification struct {
+	Format   string `json:"format"`
+	Speakers int    `json:"speakers"` // estimated; 1 when no speaker changes are marked
+}
+
+// classifyFormat classifies a video from its raw transcript (before caption
+// artifacts are stripped: ♪ and >> are evidence), title and category. It
+// never fails; without enough to go on a video is a monologue.
+func classifyFormat(entry *CacheEntry) FormatClassification {
+	text := entry.Transcript
+	words := wordRe.FindAllString(strings.ToLower(text), -1)
+	turns, speakers := speakerTurns(text)
+	result := FormatClassification{Format: formatMonologue, Speakers: speakers}
+	notes := strings.Count(text, "♪") + strings.Count(text, "♫")
+	music := entry.Category == musicCategory
+	switch {
+	case musicTitleRe.MatchString(entry.Title), notes > 0 && notes*maxWordsPerNote >= len(words):
+		result.Format = formatMusic
+		return result
+	case len(words) < minFormatWords:
+		if music { // next to no words: instrumental
+			result.Format = formatMusic
+		}
+		return result
+	}
+	perThousand := func(n int) float64 { return float64(n) * 1000 / float64(len(words)) }
+	tutorialCues := perThousand(len(tutorialCueRe.FindAllString(text, -1)))
+	repetition := lyricRepetition(words)
+	sparse := entry.DurationSeconds > 0 && float64(len(words))*60/float64(entry.DurationSeconds) < maxSongWordsPerMinute
+
+	switch {
+	case repetition >= minLyricRepetition, music && (repetition >= minCategoryLyricRepetition || sparse):
+		result.Format = formatMusic
+
+	case turns >= minSpeakerTurns && perThousand(turns) >= minSpeakerTurnRate:
+		result.Format = formatInterview
+		if speakers >= 3 || panelTitleRe.MatchString(entry.Title) {
+			result.Format = formatPanel
+		}
+
+	case tutorialCues >= minTutorialCueRate, tutorialTitleRe.MatchString(entry.Title) && tutorialCues >= minTitledTutorialCueRate:
+		result.Format = formatTutorial
+
+	case vlogTitleRe.MatchString(entry.Title),
+		firstPersonShare(words) >= minFirstPersonShare && perThousand(len(vlogCueRe.FindAllString(text, -1))) >= minVlogCueRate:
+		result.Format = formatVlog
+	}
+	return result
+}
+
+// speakerTurns counts the marked changes of speaker in a transcript and
+// estimates how many people speak: the distinct labels, or two when turns are
+// marked without names
+func speakerTurns(text string) (turns, speakers int) {
+	labels := make(map[string]bool)
+	for _, m := range speakerTurnRe.FindAllStringSubmatch(text, -1) {
+		turns++
+		if label := m[1] + m[2]; label != "" {
+			labels[label] = true
+		}
+	}
+	switch {
+	case len(labels) > 0:
+		return turns, max(len(labels), 2)
+	case turns > 0:
+		return turns, 2
+	}
+	return 0, 1
+}
+
+// lyricRepetition returns the share of words that start a four-word phrase
+// repeated three times or more, as choruses are and speech rarely is
+func lyricRepetition(words []string) float64 {
+	if len(words) < 4 {
+		return 0
+	}
+	counts := make(map[string]int)
+	for i := 0; i+4 <= len(words); i++ {
+		counts[strings.Join(words[i:i+4], " ")]++
+	}
+	repeated := 0
+	for i := 0; i+4 <= len(words); i++ {
+		if counts[strings.Join(words[i:i+4], " ")] >= 3 {
+			repeated++
+		}
+	}
+	return float64(repeated) / float64(len(words)-3)
+}
+
+// firstPersonShare returns the share of words that are I, me or my
+func firstPersonShare(words []string) float64 {
+	n := 0
+	for _, w := range words {
+		switch w {
+		case "i", "me", "my", "myself", "mine":
+			n++
+		}
+	}
+	return float64(n) / float64(len(words))
+}
+
+// videoFormat returns an entry's format, classifying entries cached before
+// formats were
+func videoFormat(entry *CacheEntry) string {
+	if entry.Format != "" {
+		return entry.Format
+	}
+	return classifyFormat(entry).Format
+}
+
+// formatTemplate picks the prompt template for a video: the one chosen (by
+// --template, a channel rule or the request), else the one suited to its
+// format. Returns "" for the default template.
+func formatTemplate(chosen string, entry *CacheEntry) string {
+	if chosen != "" {
+		return chosen
+	}
+	return formatTemplates[videoFormat(entry)]
+}
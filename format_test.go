@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyFormat(t *testing.T) {
+	lecture := `The economy grew faster than expected last quarter, driven by exports and a recovery in
+		manufacturing output. Inflation eased as energy prices fell back from their winter peak, while
+		wages kept rising in most sectors. Central banks now face a choice between holding rates and
+		cutting them before unemployment starts to climb again.`
+	tests := []struct {
+		name       string
+		title      string
+		transcript string
+		want       string
+		speakers   int
+	}{
+		{"lecture", "The state of the economy", lecture, formatMonologue, 1},
+		{"too short to tell", "", "Click install. Click next. Click finish.", formatMonologue, 1},
+		{"music video title", "Rick Astley - Never Gonna Give You Up (Official Music Video)", "We're no strangers to love", formatMusic, 1},
+		{"chorus", "", strings.Repeat("never gonna give you up never gonna let you down ", 4) + lecture, formatMusic, 1},
+		{"lyric captions", "", `♪ the night is young ♪ ♪ and so are we ♪ ♪ dancing on the rooftops ♪ ♪ under city lights ♪
+			♪ nobody can stop us now ♪ ♪ hold on to the feeling ♪ ♪ before the morning comes ♪ ♪ and takes it all away ♪`, formatMusic, 1},
+		{"interview", "", `>> What drove the growth last quarter? >> Mostly exports, and factories recovered faster than
+			anyone forecast. >> Will inflation keep easing? >> Energy prices fell back, so probably, unless wages jump.
+			>> And what should central banks do? >> Hold for now, then cut before unemployment climbs.`, formatInterview, 2},
+		{"labelled panel", "", `ANNA: Exports drove the growth, and factories recovered faster than anyone forecast.
+			BEN: I disagree, consumer spending mattered more than trade this time around.
+			CARL: Both matter, but energy prices falling did most of the work on inflation.
+			ANNA: Wages are the risk now, they kept rising in most sectors.`, formatPanel, 3},
+		{"unlabelled panel", "Economists' roundtable", `>> Exports drove the growth, factories recovered quickly.
+			>> Consumer spending mattered more than trade this time. >> Energy prices did most of the work on
+			inflation. >> Wages are the risk now, they kept rising everywhere across most sectors of the economy. >> So do we expect a cut?`, formatPanel, 2},
+		{"tutorial", "", `First we open the settings and select the network tab. Make sure the box at the bottom
+			is ticked, then click save. Now we can download the driver; the next step is to install it and
+			restart. You'll need an administrator account for that part.`, formatTutorial, 1},
+		{"titled tutorial", "How to repot a fern", `Take the fern out of its old pot and loosen the roots gently
+			with your fingers. You'll need fresh compost and a pot one size bigger. Water it well afterwards and
+			keep it out of direct sun for a week or two while it settles in.`, formatTutorial, 1},
+		{"vlog", "", `So today I woke up early because my plan was to finish the garden, but I got distracted by my
+			neighbour and his new dog. Then I went into town, grabbed a coffee, and honestly I spent way too much
+			on plants again. Tonight I'm cooking for friends.`, formatVlog, 1},
+	}
+	for _, tt := range tests {
+		got := classifyFormat(&CacheEntry{Title: tt.title, Transcript: tt.transcript})
+		if got.Format != tt.want || got.Speakers != tt.speakers {
+			t.Errorf("%s: classifyFormat() = %+v, want %s with %d speakers", tt.name, got, tt.want, tt.speakers)
+		}
+	}
+}
+
+func TestFormatTemplate(t *testing.T) {
+	interview := &CacheEntry{Format: formatInterview}
+	if got := formatTemplate("", interview); got != "conversation" {
+		t.Errorf("interview template = %q", got)
+	}
+	if got := formatTemplate("key-points", interview); got != "key-points" {
+		t.Errorf("a chosen template should win, got %q", got)
+	}
+	if got := formatTemplate("", &CacheEntry{Format: formatVlog}); got != "" {
+		t.Errorf("vlog template = %q, want the default", got)
+	}
+	// Entries cached before classification are classified on the fly
+	if got := formatTemplate("", &CacheEntry{Title: "Song (Official Audio)", Transcript: "la la la"}); got != "music" {
+		t.Errorf("unclassified entry template = %q", got)
+	}
+	for format, name := range formatTemplates {
+		if _, err := getTemplate(name); err != nil {
+			t.Errorf("%s template: %v", format, err)
+		}
+	}
+}
+
+func TestCacheKeepsFormat(t *testing.T) {
+	cache := newTestCache(t)
+	result := &FetchResult{Title: "Never Gonna Give You Up (Official Music Video)", Transcript: "We're no strangers to love"}
+	if err := cacheFetchResult(cache, "dQw4w9WgXcQ", "en", result); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := cache.GetTranscript("dQw4w9WgXcQ", "en")
+	if err != nil || entry.Format != formatMusic {
+		t.Errorf("cached format = %q, %v", entry.Format, err)
+	}
+}
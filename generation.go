@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// defaultMaxTokens caps summary length when nothing else is configured
+const defaultMaxTokens = 2000
+
+// maxMaxTokens is the largest max_tokens accepted from flags or requests
+const maxMaxTokens = 100000
+
+var (
+	llmTemperature string
+	llmTopP        string
+	llmMaxTokens   string
+)
+
+// GenerationParams are the sampling settings sent with each chat completion.
+// Nil and zero fields are unset: the provider's default applies to
+// temperature and top_p, and defaultMaxTokens to max_tokens.
+type GenerationParams struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+}
+
+// validate checks the params are within what OpenAI-compatible APIs accept
+func (p GenerationParams) validate() error {
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1")
+	}
+	if p.MaxTokens < 0 || p.MaxTokens > maxMaxTokens {
+		return fmt.Errorf("max_tokens must be between 1 and %d", maxMaxTokens)
+	}
+	return nil
+}
+
+// withDefaults fills unset fields from defaults
+func (p GenerationParams) withDefaults(defaults GenerationParams) GenerationParams {
+	if p.Temperature == nil {
+		p.Temperature = defaults.Temperature
+	}
+	if p.TopP == nil {
+		p.TopP = defaults.TopP
+	}
+	if p.MaxTokens == 0 {
+		p.MaxTokens = defaults.MaxTokens
+	}
+	return p
+}
+
+// generationConfig returns the params from --temperature, --top-p and
+// --max-tokens (YTSUMMARY_TEMPERATURE, YTSUMMARY_TOP_P, YTSUMMARY_MAX_TOKENS)
+func generationConfig() (GenerationParams, error) {
+	var p GenerationParams
+	if v := getConfig(llmTemperature, "YTSUMMARY_TEMPERATURE"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return p, fmt.Errorf("invalid temperature %q", v)
+		}
+		p.Temperature = &t
+	}
+	if v := getConfig(llmTopP, "YTSUMMARY_TOP_P"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return p, fmt.Errorf("invalid top_p %q", v)
+		}
+		p.TopP = &t
+	}
+	if v := getConfig(llmMaxTokens, "YTSUMMARY_MAX_TOKENS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return p, fmt.Errorf("invalid max_tokens %q", v)
+		}
+		p.MaxTokens = n
+	}
+	return p, p.validate()
+}
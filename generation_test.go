@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenerationConfig(t *testing.T) {
+	t.Setenv("YTSUMMARY_TEMPERATURE", "0.2")
+	t.Setenv("YTSUMMARY_TOP_P", "")
+	t.Setenv("YTSUMMARY_MAX_TOKENS", "8000")
+
+	p, err := generationConfig()
+	if err != nil {
+		t.Fatalf("generationConfig() error = %v", err)
+	}
+	if p.Temperature == nil || *p.Temperature != 0.2 {
+		t.Errorf("Temperature = %v, want 0.2", p.Temperature)
+	}
+	if p.TopP != nil {
+		t.Errorf("TopP = %v, want unset", *p.TopP)
+	}
+	if p.MaxTokens != 8000 {
+		t.Errorf("MaxTokens = %d, want 8000", p.MaxTokens)
+	}
+
+	for env, value := range map[string]string{
+		"YTSUMMARY_TEMPERATURE": "3",
+		"YTSUMMARY_TOP_P":       "warm",
+		"YTSUMMARY_MAX_TOKENS":  "-1",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			if _, err := generationConfig(); err == nil {
+				t.Errorf("%s=%s: expected error", env, value)
+			}
+		})
+	}
+}
+
+func TestGenerationParamsWithDefaults(t *testing.T) {
+	low, high := 0.1, 0.9
+	defaults := GenerationParams{Temperature: &low, TopP: &high, MaxTokens: 4000}
+
+	got := GenerationParams{Temperature: &high}.withDefaults(defaults)
+	if *got.Temperature != high || *got.TopP != high || got.MaxTokens != 4000 {
+		t.Errorf("withDefaults() = %+v, want the request's temperature and the default top_p and max_tokens", got)
+	}
+}
+
+func TestOpenAIClientGenerationParams(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Write([]byte(`{"choices":[{"message":{"content":"a summary"}}]}`))
+	}))
+	defer srv.Close()
+
+	temp := 0.3
+	client := &openAIClient{apiKey: "k", model: "m", apiURL: srv.URL, params: GenerationParams{Temperature: &temp}}
+
+	// Unconfigured params are left to the provider, except max_tokens
+	if _, err := (&openAIClient{apiKey: "k", model: "m", apiURL: srv.URL}).Complete("s", "t", GenerationParams{}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if _, ok := bodies[0]["temperature"]; ok {
+		t.Error("temperature should be omitted when unset")
+	}
+	if bodies[0]["max_tokens"] != float64(defaultMaxTokens) {
+		t.Errorf("max_tokens = %v, want %d", bodies[0]["max_tokens"], defaultMaxTokens)
+	}
+
+	// Per-call params override the configured ones
+	if _, err := client.Complete("s", "t", GenerationParams{MaxTokens: 16000}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if bodies[1]["temperature"] != 0.3 || bodies[1]["max_tokens"] != float64(16000) {
+		t.Errorf("request body = %v, want temperature 0.3 and max_tokens 16000", bodies[1])
+	}
+}
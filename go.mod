@@ -6,10 +6,11 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/mattn/go-sqlite3 v1.14.33
 	github.com/spf13/cobra v1.8.0
+	golang.org/x/text v0.30.0
+	golang.org/x/time v0.14.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/time v0.14.0 // indirect
 )
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Summary modes: a written summary, or the video's most important moments
+const (
+	summaryModeSummary    = "summary"
+	summaryModeHighlights = "highlights"
+)
+
+const (
+	defaultHighlights = 5  // moments picked when no count is given
+	maxHighlights     = 50 // most moments one request may ask for
+)
+
+// Highlight is one of a video's most important moments, timed by the
+// captions it spans, for cutting clips
+type Highlight struct {
+	Start       float64 `json:"start"`
+	End         float64 `json:"end"`
+	Description string  `json:"description"`
+}
+
+// errNoHighlights means the LLM's reply named no usable moments
+var errNoHighlights = errors.New("the LLM picked no highlights that match the transcript")
+
+// highlightsPrompt asks for the moments of a numbered transcript as JSON line
+// ranges, which parseHighlights maps back to caption timings. The same prompt
+// combines the candidates picked from each chunk of a long transcript.
+const highlightsPrompt = `You pick the most important moments of a YouTube video for a highlight reel. The input is the video's transcript, one numbered caption sentence per line ("[12] 3:05 text"), or lists of candidate moments already picked from sections of it.
+
+Pick the %d most important moments. A moment is a run of consecutive lines that makes sense as a clip on its own, usually 15 to 90 seconds long, and moments must not overlap. Reply with only a JSON array, most important first:
+[{"from": 12, "to": 15, "score": 9, "description": "One line saying what happens"}]
+"from" and "to" are the numbers of the moment's first and last lines, and "score" rates its importance from 1 to 10.`
+
+// highlightsChunkPrompt picks candidates from one chunk of a long transcript
+const highlightsChunkPrompt = `This is a section of a YouTube video's transcript, one numbered caption sentence per line ("[12] 3:05 text"). Pick up to %d of its most important moments for a highlight reel: runs of consecutive lines that make sense as clips on their own, usually 15 to 90 seconds long. Reply with only a JSON array:
+[{"from": 12, "to": 15, "score": 9, "description": "One line saying what happens"}]
+"from" and "to" are the numbers of the moment's first and last lines, and "score" rates its importance from 1 to 10.`
+
+// highlightsPrompts returns the final and per-chunk prompts picking n moments,
+// described in the given language
+func highlightsPrompts(n int, lang string) (prompt, chunkPrompt string) {
+	prompt, chunkPrompt = fmt.Sprintf(highlightsPrompt, n), fmt.Sprintf(highlightsChunkPrompt, n)
+	if lang != "" {
+		instruction := "\n\nWrite the descriptions in " + languageName(lang) + "."
+		prompt += instruction
+		chunkPrompt += instruction
+	}
+	return prompt, chunkPrompt
+}
+
+// validSummaryMode checks a --mode or "mode" value
+func validSummaryMode(mode string) error {
+	switch mode {
+	case "", summaryModeSummary, summaryModeHighlights:
+		return nil
+	}
+	return fmt.Errorf("invalid mode %q (use summary or highlights)", mode)
+}
+
+// highlightCount returns how many moments to pick for a requested count
+func highlightCount(count int) (int, error) {
+	switch {
+	case count == 0:
+		return defaultHighlights, nil
+	case count < 0 || count > maxHighlights:
+		return 0, fmt.Errorf("count must be between 1 and %d", maxHighlights)
+	}
+	return count, nil
+}
+
+// numberedTranscript renders sentences one per line with their number and
+// start time, so the LLM can name moments by line
+func numberedTranscript(sentences []captionSentence) string {
+	var b strings.Builder
+	for i, s := range sentences {
+		fmt.Fprintf(&b, "[%d] %s %s\n", i, formatTimestamp(s.Start), s.Text)
+	}
+	return b.String()
+}
+
+// highlightReply is one moment in the LLM's reply
+type highlightReply struct {
+	From        int     `json:"from"`
+	To          int     `json:"to"`
+	Score       float64 `json:"score"`
+	Description string  `json:"description"`
+}
+
+// parseHighlights reads the LLM's JSON reply and grounds each moment in the
+// caption timings of the lines it names. Moments naming lines that don't
+// exist are dropped, as are those overlapping a more important one. At most
+// n are kept, in playback order.
+func parseHighlights(reply string, sentences []captionSentence, n int) ([]Highlight, error) {
+	// Models often wrap JSON in a code fence or a sentence of their own
+	start, end := strings.Index(reply, "["), strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, errNoHighlights
+	}
+	var moments []highlightReply
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &moments); err != nil {
+		return nil, fmt.Errorf("failed to parse highlights: %w", err)
+	}
+
+	sort.SliceStable(moments, func(i, j int) bool { return moments[i].Score > moments[j].Score })
+	var picked []highlightReply
+	for _, m := range moments {
+		if m.To < m.From {
+			m.From, m.To = m.To, m.From
+		}
+		if m.From < 0 || m.To >= len(sentences) || len(picked) == n {
+			continue
+		}
+		overlaps := false
+		for _, p := range picked {
+			if m.From <= p.To && p.From <= m.To {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			picked = append(picked, m)
+		}
+	}
+	if len(picked) == 0 {
+		return nil, errNoHighlights
+	}
+
+	sort.Slice(picked, func(i, j int) bool { return picked[i].From < picked[j].From })
+	highlights := make([]Highlight, len(picked))
+	for i, m := range picked {
+		description := strings.TrimSpace(m.Description)
+		if description == "" {
+			description = sentences[m.From].Text
+		}
+		highlights[i] = Highlight{Start: sentences[m.From].Start, End: sentences[m.To].End, Description: description}
+	}
+	// Caption sentences can share a cue; keep each clip before the next
+	for i := 0; i+1 < len(highlights); i++ {
+		if highlights[i].End > highlights[i+1].Start {
+			highlights[i].End = highlights[i+1].Start
+		}
+	}
+	return highlights, nil
+}
+
+// extractHighlights has the LLM pick the n most important moments of the
+// captions, through summarizeFn in highlights mode
+func extractHighlights(summarizeFn func(string, SummaryOptions) (string, error), segments []TranscriptSegment, n int, opts SummaryOptions) ([]Highlight, error) {
+	sentences := transcriptSentences(segments)
+	if len(sentences) == 0 {
+		return nil, errNoHighlights
+	}
+	opts.Highlights = n
+	reply, err := summarizeFn(numberedTranscript(sentences), opts)
+	if err != nil {
+		return nil, err
+	}
+	return parseHighlights(reply, sentences, n)
+}
+
+// formatHighlights renders highlights one per line as "[start-end] description"
+func formatHighlights(highlights []Highlight) string {
+	var b strings.Builder
+	for _, h := range highlights {
+		b.WriteString("[" + formatTimestamp(h.Start) + "-" + formatTimestamp(h.End) + "] " + h.Description + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// numberedLine matches a line of a numbered transcript
+var numberedLine = regexp.MustCompile(`\[(\d+)\] [\d:]+ ([^\[\n]*)`)
+
+// fakeHighlights is the fake provider's reply in highlights mode: the first n
+// lines of the numbered transcript, each its own moment
+func fakeHighlights(text string, n int) string {
+	var moments []highlightReply
+	for _, m := range numberedLine.FindAllStringSubmatch(text, n) {
+		line, _ := strconv.Atoi(m[1])
+		moments = append(moments, highlightReply{From: line, To: line, Score: 5, Description: strings.TrimSpace(m[2])})
+	}
+	reply, _ := json.Marshal(moments)
+	return string(reply)
+}
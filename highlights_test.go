@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNumberedTranscript(t *testing.T) {
+	want := "[0] 0:00 Welcome back to the channel.\n" +
+		"[1] 0:03 Today we're looking at sourdough starters, which need flour, water and patience.\n" +
+		"[2] 0:07 Feed yours daily!\n" +
+		"[3] 0:10 Also, like and subscribe.\n" +
+		"[4] 0:15 Bake the loaf at 250 degrees for forty minutes.\n"
+	if got := numberedTranscript(transcriptSentences(talkSegments)); got != want {
+		t.Errorf("numberedTranscript:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseHighlights(t *testing.T) {
+	sentences := transcriptSentences(talkSegments)
+	reply := "Here are the highlights:\n```json\n[" +
+		`{"from": 4, "to": 4, "score": 9, "description": "Baking the loaf"},` +
+		`{"from": 2, "to": 1, "score": 7, "description": "What a starter needs"},` +
+		`{"from": 2, "to": 3, "score": 6, "description": "Overlaps a better moment"},` +
+		`{"from": 7, "to": 9, "score": 10, "description": "Lines that don't exist"},` +
+		`{"from": 0, "to": 0, "score": 2, "description": ""}` +
+		"]\n```"
+
+	got, err := parseHighlights(reply, sentences, 5)
+	if err != nil {
+		t.Fatalf("parseHighlights() error = %v", err)
+	}
+	want := []Highlight{
+		{0, 3, "Welcome back to the channel."},
+		{3, 10, "What a starter needs"},
+		{15, 19, "Baking the loaf"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("highlight %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// The most important moments are kept, in playback order
+	if got, _ := parseHighlights(reply, sentences, 2); len(got) != 2 || got[0].Description != "What a starter needs" || got[1].Description != "Baking the loaf" {
+		t.Errorf("top 2 = %+v", got)
+	}
+
+	for _, reply := range []string{"I couldn't find any.", `[{"from": 12, "to": 14}]`, "[]"} {
+		if _, err := parseHighlights(reply, sentences, 5); err != errNoHighlights {
+			t.Errorf("parseHighlights(%q) error = %v, want errNoHighlights", reply, err)
+		}
+	}
+	if _, err := parseHighlights(`[{"from": "one"}]`, sentences, 5); err == nil {
+		t.Error("expected an error for malformed JSON")
+	}
+}
+
+func TestExtractHighlightsFake(t *testing.T) {
+	fake := func(transcript string, opts SummaryOptions) (string, error) {
+		return summarizeWith(&fakeLLMClient{sentences: 2}, transcript, opts)
+	}
+	got, err := extractHighlights(fake, talkSegments, 2, SummaryOptions{})
+	if err != nil {
+		t.Fatalf("extractHighlights() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != (Highlight{0, 3, "Welcome back to the channel."}) || got[1].Start != 3 {
+		t.Errorf("highlights = %+v", got)
+	}
+	if got := formatHighlights(got); !strings.HasPrefix(got, "[0:00-0:03] Welcome back to the channel.\n[0:03-0:10] Today") {
+		t.Errorf("formatHighlights = %q", got)
+	}
+}
+
+func TestSummarizeHighlights(t *testing.T) {
+	cache := newTestCache(t)
+	cache.StoreTranscript(&CacheEntry{VideoID: "dQw4w9WgXcQ", Language: "en", Transcript: segmentsText(talkSegments), Segments: talkSegments})
+	var gotOpts SummaryOptions
+	handler := newServer(ServerConfig{
+		Cache: cache,
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			gotOpts = opts
+			return `[{"from": 4, "to": 4, "score": 8, "description": "Baking the loaf"}]`, nil
+		},
+	}).Handler()
+
+	// Each client gets a burst of 5 requests before the rate limit
+	serve := func(req *http.Request, addr string) *httptest.ResponseRecorder {
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(httptest.NewRequest("GET", "/v1/summarize?url=dQw4w9WgXcQ&mode=highlights&count=3", nil), "192.0.2.22:1234")
+	var resp TranscriptResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status = %d, %v", w.Code, err)
+	}
+	if gotOpts.Highlights != 3 {
+		t.Errorf("opts.Highlights = %d, want 3", gotOpts.Highlights)
+	}
+	if len(resp.Highlights) != 1 || resp.Highlights[0] != (Highlight{15, 19, "Baking the loaf"}) || resp.Summary != "" {
+		t.Errorf("response = %+v", resp)
+	}
+
+	req := httptest.NewRequest("POST", "/v1/summarize", strings.NewReader(`{"url": "dQw4w9WgXcQ", "mode": "highlights"}`))
+	req.Header.Set("Accept", "text/plain")
+	if w := serve(req, "192.0.2.22:1234"); w.Body.String() != "[0:15-0:19] Baking the loaf\n" {
+		t.Errorf("plain text = %q", w.Body)
+	}
+	if gotOpts.Highlights != defaultHighlights {
+		t.Errorf("opts.Highlights = %d, want the default %d", gotOpts.Highlights, defaultHighlights)
+	}
+
+	for _, body := range []string{
+		`{"url": "dQw4w9WgXcQ", "mode": "clips"}`,
+		`{"url": "dQw4w9WgXcQ", "mode": "highlights", "count": 51}`,
+		`{"url": "dQw4w9WgXcQ", "count": 3}`,
+		`{"url": "dQw4w9WgXcQ", "mode": "highlights", "subtitles": true}`,
+	} {
+		if w := serve(httptest.NewRequest("POST", "/v1/summarize", strings.NewReader(body)), "192.0.2.23:1234"); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+}
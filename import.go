@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var importOutput string
+
+// Video list formats readVideoList recognizes besides a plain URL list
+const (
+	formatTakeoutHistory = "Google Takeout watch history"
+	formatTakeoutCSV     = "Google Takeout playlist"
+	formatHTML           = "HTML page or bookmarks export"
+)
+
+// takeoutHistoryItem is one entry of Takeout's watch-history.json. Videos
+// that were removed since have no titleUrl.
+type takeoutHistoryItem struct {
+	TitleURL string `json:"titleUrl"`
+}
+
+// htmlHref matches the links of an HTML page or a browser's bookmarks export
+var htmlHref = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']+)["']`)
+
+// readVideoList reads the --file of prefetch and batch ("-" for standard
+// input): one URL or video ID per line, or an export parseVideoExport
+// recognizes
+func readVideoList(path string) ([]string, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open URL list: %w", err)
+	}
+	urls, format, err := parseVideoExport(data)
+	if err != nil {
+		return nil, err
+	}
+	if format != "" {
+		log("Read %d videos from a %s", len(urls), format)
+	}
+	return urls, nil
+}
+
+// parseVideoExport extracts the videos of a Google Takeout watch history
+// (watch-history.json) or playlist (Watch later-videos.csv and other
+// playlist CSVs), or of the YouTube links in an HTML page such as a saved
+// playlist or a browser's bookmarks export. Videos are returned once each, in
+// the export's order. Anything else is read as a plain URL list, and format
+// is "".
+func parseVideoExport(data []byte) (urls []string, format string, err error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Takeout CSVs start with a BOM
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var items []takeoutHistoryItem
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, "", fmt.Errorf("failed to parse watch history JSON: %w", err)
+		}
+		var links []string
+		for _, item := range items {
+			links = append(links, item.TitleURL)
+		}
+		return uniqueVideoURLs(links), formatTakeoutHistory, nil
+
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		var links []string
+		for _, m := range htmlHref.FindAllSubmatch(trimmed, -1) {
+			links = append(links, html.UnescapeString(string(m[1])))
+		}
+		return uniqueVideoURLs(links), formatHTML, nil
+
+	case isTakeoutCSV(trimmed):
+		links, err := takeoutCSVVideoIDs(trimmed)
+		if err != nil {
+			return nil, "", err
+		}
+		return uniqueVideoURLs(links), formatTakeoutCSV, nil
+	}
+
+	urls, err = readURLList(bytes.NewReader(data))
+	return urls, "", err
+}
+
+// isTakeoutCSV reports whether data looks like a Takeout playlist CSV, which
+// has a "Video ID" column, possibly after a few lines about the playlist
+func isTakeoutCSV(data []byte) bool {
+	for i, line := range strings.Split(string(data), "\n") {
+		if i == 10 {
+			break
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "Video ID,") {
+			return true
+		}
+	}
+	return false
+}
+
+// takeoutCSVVideoIDs returns the Video ID column of a Takeout playlist CSV
+func takeoutCSVVideoIDs(data []byte) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1 // the playlist lines before the header are shorter
+	column := -1
+	var ids []string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse playlist CSV: %w", err)
+		}
+		if column < 0 {
+			for i, field := range record {
+				if strings.TrimSpace(field) == "Video ID" {
+					column = i
+				}
+			}
+			continue
+		}
+		if column < len(record) {
+			ids = append(ids, strings.TrimSpace(record[column]))
+		}
+	}
+}
+
+// uniqueVideoURLs returns the canonical URL of each video links point at,
+// once each and in order, skipping links that aren't YouTube videos
+func uniqueVideoURLs(links []string) []string {
+	seen := make(map[string]bool)
+	urls := []string{}
+	for _, link := range links {
+		videoID, err := extractVideoID(link)
+		if err != nil || seen[videoID] {
+			continue
+		}
+		seen[videoID] = true
+		urls = append(urls, canonicalVideoURL(videoID))
+	}
+	return urls
+}
+
+// runImport converts an export into a URL list for prefetch and batch
+func runImport(cmd *cobra.Command, args []string) error {
+	urls, err := readVideoList(args[0])
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		return fmt.Errorf("no YouTube videos found in %s", inputName(args[0]))
+	}
+
+	out := io.Writer(os.Stdout)
+	if importOutput != "" {
+		f, err := os.Create(importOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create URL list: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	for _, u := range urls {
+		fmt.Fprintln(out, u)
+	}
+	if importOutput != "" {
+		log("Wrote %d videos to %s; queue them with 'ytsummary prefetch -f %s' or 'ytsummary batch -f %s'", len(urls), importOutput, importOutput, importOutput)
+	}
+	return nil
+}
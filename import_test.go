@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseVideoExport(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantFormat string
+		want       []string
+	}{
+		{
+			name: "takeout watch history",
+			data: `[
+  {"header": "YouTube", "title": "Watched Never Gonna Give You Up", "titleUrl": "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+  {"header": "YouTube", "title": "Watched a video that has been removed"},
+  {"header": "YouTube", "title": "Watched Me at the zoo", "titleUrl": "https://www.youtube.com/watch?v=jNQXAC9IVRw"},
+  {"header": "YouTube", "title": "Watched Never Gonna Give You Up", "titleUrl": "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}
+]`,
+			wantFormat: formatTakeoutHistory,
+			want:       []string{canonicalVideoURL("dQw4w9WgXcQ"), canonicalVideoURL("jNQXAC9IVRw")},
+		},
+		{
+			name: "takeout playlist csv",
+			data: "\xef\xbb\xbfPlaylist ID,Channel ID,Time Created,Time Updated,Title,Description,Visibility\n" +
+				"WL,UCabc,2024-01-01,2024-02-01,Watch later,,Private\n" +
+				"\n" +
+				"Video ID,Time Added\n" +
+				"kJQP7kiw5Fk,2024-01-02 10:00:00 UTC\n" +
+				"dQw4w9WgXcQ,2024-01-03 10:00:00 UTC\n",
+			wantFormat: formatTakeoutCSV,
+			want:       []string{canonicalVideoURL("kJQP7kiw5Fk"), canonicalVideoURL("dQw4w9WgXcQ")},
+		},
+		{
+			name: "bookmarks html",
+			data: `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+  <DT><A HREF="https://www.youtube.com/watch?v=jNQXAC9IVRw&amp;list=WL&amp;index=2">Me at the zoo</A>
+  <DT><A HREF="https://example.com/">Not a video</A>
+  <DT><A HREF="https://youtu.be/kJQP7kiw5Fk">Despacito</A>
+</DL><p>`,
+			wantFormat: formatHTML,
+			want:       []string{canonicalVideoURL("jNQXAC9IVRw"), canonicalVideoURL("kJQP7kiw5Fk")},
+		},
+		{
+			name:       "plain list",
+			data:       "# to watch\nhttps://youtu.be/dQw4w9WgXcQ\nkJQP7kiw5Fk\n",
+			wantFormat: "",
+			want:       []string{"https://youtu.be/dQw4w9WgXcQ", "kJQP7kiw5Fk"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, format, err := parseVideoExport([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseVideoExport() error = %v", err)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", format, tt.wantFormat)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseVideoExport() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := parseVideoExport([]byte(`[{"titleUrl": `)); err == nil {
+		t.Error("truncated watch history accepted")
+	}
+}
+
+func TestRunImport(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "watch-history.json")
+	os.WriteFile(in, []byte(`[{"titleUrl": "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}]`), 0o644)
+
+	importOutput = filepath.Join(dir, "urls.txt")
+	t.Cleanup(func() { importOutput = "" })
+	if err := runImport(nil, []string{in}); err != nil {
+		t.Fatal(err)
+	}
+	urls, err := readVideoList(importOutput)
+	if err != nil || !reflect.DeepEqual(urls, []string{canonicalVideoURL("dQw4w9WgXcQ")}) {
+		t.Errorf("imported list = %v, %v", urls, err)
+	}
+
+	empty := filepath.Join(dir, "empty.json")
+	os.WriteFile(empty, []byte(`[]`), 0o644)
+	if err := runImport(nil, []string{empty}); err == nil {
+		t.Error("export without videos accepted")
+	}
+}
@@ -50,7 +50,7 @@ var testVideos = map[string]struct {
 }
 
 func TestInnertubePublicVideo(t *testing.T) {
-	result, err := fetchTranscriptDirect("https://www.youtube.com/watch?v=dQw4w9WgXcQ", "en")
+	result, err := fetchTranscriptDirect("https://www.youtube.com/watch?v=dQw4w9WgXcQ", "en", false)
 	if err != nil {
 		t.Fatalf("failed to fetch public video: %v", err)
 	}
@@ -77,7 +77,7 @@ func TestInnertubePublicVideo(t *testing.T) {
 }
 
 func TestInnertubePrivateVideo(t *testing.T) {
-	_, err := fetchTranscriptDirect("https://www.youtube.com/watch?v=private12345", "en")
+	_, err := fetchTranscriptDirect("https://www.youtube.com/watch?v=private12345", "en", false)
 	if err == nil {
 		t.Fatal("expected error for non-existent video")
 	}
@@ -101,7 +101,7 @@ func TestInnertubeRateLimiting(t *testing.T) {
 	t.Logf("Making %d rapid requests to test rate limiting...", numRequests)
 
 	for i := 0; i < numRequests; i++ {
-		_, err := fetchTranscriptDirect("https://www.youtube.com/watch?v=dQw4w9WgXcQ", "en")
+		_, err := fetchTranscriptDirect("https://www.youtube.com/watch?v=dQw4w9WgXcQ", "en", false)
 		if err != nil {
 			errorCount++
 			lastError = err
@@ -133,10 +133,10 @@ func TestInnertubeRateLimiting(t *testing.T) {
 
 func TestInnertubeLanguageSelection(t *testing.T) {
 	// Test Spanish video with Spanish language preference
-	result, err := fetchTranscriptDirect("https://www.youtube.com/watch?v=kJQP7kiw5Fk", "es")
+	result, err := fetchTranscriptDirect("https://www.youtube.com/watch?v=kJQP7kiw5Fk", "es", false)
 	if err != nil {
 		// Might not have Spanish captions, try English
-		result, err = fetchTranscriptDirect("https://www.youtube.com/watch?v=kJQP7kiw5Fk", "en")
+		result, err = fetchTranscriptDirect("https://www.youtube.com/watch?v=kJQP7kiw5Fk", "en", false)
 		if err != nil {
 			t.Skipf("Could not fetch captions: %v", err)
 		}
@@ -167,7 +167,7 @@ func TestInnertubeErrorMessages(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := fetchTranscriptDirect(tt.url, "en")
+			_, err := fetchTranscriptDirect(tt.url, "en", false)
 			if err == nil {
 				t.Log("Unexpectedly succeeded")
 				return
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// keyringService is what ytsummary's secrets are filed under
+	keyringService = "ytsummary"
+	// keyringLLMAccount holds the LLM API key 'auth set-key' stores
+	keyringLLMAccount = "llm-api-key"
+)
+
+var (
+	errKeyringNotFound    = errors.New("not found in the keyring")
+	errKeyringUnavailable = errors.New("no OS keyring available (needs the macOS Keychain or secret-tool for GNOME Keyring/KWallet)")
+)
+
+// keyring is an OS secret store
+type keyring interface {
+	Get(account string) (string, error) // errKeyringNotFound when unset
+	Set(account, secret string) error
+	Delete(account string) error
+}
+
+// openKeyring returns the OS keyring: the macOS Keychain through security(1),
+// or the freedesktop Secret Service (GNOME Keyring, KWallet) through
+// secret-tool. Tests replace it.
+var openKeyring = func() (keyring, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return macKeychain{}, nil
+	case "linux", "freebsd", "openbsd":
+		if _, err := exec.LookPath("secret-tool"); err == nil {
+			return secretService{}, nil
+		}
+	}
+	return nil, errKeyringUnavailable
+}
+
+// macKeychain keeps secrets as generic passwords in the login keychain
+type macKeychain struct{}
+
+func (macKeychain) Get(account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", keyringService, "-a", account, "-w").Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 { // errSecItemNotFound
+		return "", errKeyringNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the keychain: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (macKeychain) Set(account, secret string) error {
+	// Commands on stdin (-i) keep the secret out of the process list
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keyringService, account, shellQuote(secret)))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write the keychain: %v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func (macKeychain) Delete(account string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", keyringService, "-a", account).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+		return errKeyringNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete from the keychain: %w", err)
+	}
+	return nil
+}
+
+// shellQuote single-quotes s for security -i, which splits commands like a
+// shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// secretService keeps secrets in the Secret Service with secret-tool
+type secretService struct{}
+
+func (secretService) Get(account string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", keyringService, "account", account).Output()
+	// lookup fails without a word when there is no such secret
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(out) == 0 && len(bytes.TrimSpace(exitErr.Stderr)) == 0 {
+		return "", errKeyringNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the keyring: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (secretService) Set(account, secret string) error {
+	// store reads the secret from stdin
+	cmd := exec.Command("secret-tool", "store", "--label=ytsummary "+account, "service", keyringService, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write the keyring: %v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func (s secretService) Delete(account string) error {
+	if _, err := s.Get(account); err != nil {
+		return err
+	}
+	if err := exec.Command("secret-tool", "clear", "service", keyringService, "account", account).Run(); err != nil {
+		return fmt.Errorf("failed to delete from the keyring: %w", err)
+	}
+	return nil
+}
+
+// keyringAPIKey is the LLM API key read from the keyring, kept so a server
+// asks the keyring once instead of on every request
+var keyringAPIKey struct {
+	sync.Mutex
+	loaded bool
+	key    string
+}
+
+// llmAPIKeyConfig returns --api-key (YTSUMMARY_API_KEY), else the key
+// 'auth set-key' stored in the OS keyring, else ""
+func llmAPIKeyConfig() (string, error) {
+	if key := getConfig(llmAPIKey, "YTSUMMARY_API_KEY"); key != "" {
+		return key, nil
+	}
+
+	keyringAPIKey.Lock()
+	defer keyringAPIKey.Unlock()
+	if keyringAPIKey.loaded {
+		return keyringAPIKey.key, nil
+	}
+	ring, err := openKeyring()
+	if errors.Is(err, errKeyringUnavailable) {
+		keyringAPIKey.loaded = true
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	key, err := ring.Get(keyringLLMAccount)
+	if err != nil && !errors.Is(err, errKeyringNotFound) {
+		return "", err
+	}
+	keyringAPIKey.loaded, keyringAPIKey.key = true, key
+	return key, nil
+}
+
+// loadedKeyringSecrets returns the secrets read from the keyring so far, for
+// redaction; it never asks the keyring itself
+func loadedKeyringSecrets() []string {
+	keyringAPIKey.Lock()
+	defer keyringAPIKey.Unlock()
+	if keyringAPIKey.key == "" {
+		return nil
+	}
+	return []string{keyringAPIKey.key}
+}
+
+// forgetKeyringAPIKey makes the next llmAPIKeyConfig ask the keyring again
+func forgetKeyringAPIKey() {
+	keyringAPIKey.Lock()
+	keyringAPIKey.loaded, keyringAPIKey.key = false, ""
+	keyringAPIKey.Unlock()
+}
+
+// runAuthSetKey stores the LLM API key read from standard input in the keyring
+func runAuthSetKey(cmd *cobra.Command, args []string) error {
+	ring, err := openKeyring()
+	if err != nil {
+		return err
+	}
+	if f, ok := stdin.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprint(os.Stderr, "Paste the LLM API key and press Enter, then Ctrl-D: ")
+		}
+	}
+	data, err := readInput(stdinPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the API key: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" || strings.ContainsAny(key, " \n\t") {
+		return fmt.Errorf("expected one API key on standard input")
+	}
+	if err := ring.Set(keyringLLMAccount, key); err != nil {
+		return err
+	}
+	forgetKeyringAPIKey()
+	log("LLM API key stored in the OS keyring; remove YTSUMMARY_API_KEY from .env files")
+	return nil
+}
+
+// runAuthDeleteKey removes the LLM API key from the keyring
+func runAuthDeleteKey(cmd *cobra.Command, args []string) error {
+	ring, err := openKeyring()
+	if err != nil {
+		return err
+	}
+	if err := ring.Delete(keyringLLMAccount); errors.Is(err, errKeyringNotFound) {
+		log("No LLM API key in the OS keyring")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	forgetKeyringAPIKey()
+	log("LLM API key removed from the OS keyring")
+	return nil
+}
+
+// apiKeySource describes where the LLM API key comes from, for auth status
+func apiKeySource() string {
+	if getConfig(llmAPIKey, "YTSUMMARY_API_KEY") != "" {
+		return "--api-key / YTSUMMARY_API_KEY"
+	}
+	key, err := llmAPIKeyConfig()
+	switch {
+	case err != nil:
+		return err.Error()
+	case key != "":
+		return "OS keyring"
+	}
+	return "not set"
+}
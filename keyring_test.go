@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// fakeKeyring keeps secrets in memory and counts reads
+type fakeKeyring struct {
+	secrets map[string]string
+	gets    int
+}
+
+func (k *fakeKeyring) Get(account string) (string, error) {
+	k.gets++
+	secret, ok := k.secrets[account]
+	if !ok {
+		return "", errKeyringNotFound
+	}
+	return secret, nil
+}
+
+func (k *fakeKeyring) Set(account, secret string) error {
+	k.secrets[account] = secret
+	return nil
+}
+
+func (k *fakeKeyring) Delete(account string) error {
+	if _, ok := k.secrets[account]; !ok {
+		return errKeyringNotFound
+	}
+	delete(k.secrets, account)
+	return nil
+}
+
+// withFakeKeyring replaces the OS keyring for the test
+func withFakeKeyring(t *testing.T) *fakeKeyring {
+	ring := &fakeKeyring{secrets: map[string]string{}}
+	orig := openKeyring
+	openKeyring = func() (keyring, error) { return ring, nil }
+	forgetKeyringAPIKey()
+	t.Cleanup(func() {
+		openKeyring = orig
+		forgetKeyringAPIKey()
+	})
+	return ring
+}
+
+func TestKeyringAPIKey(t *testing.T) {
+	ring := withFakeKeyring(t)
+	t.Setenv("YTSUMMARY_API_KEY", "")
+	t.Setenv("YTSUMMARY_PROVIDER", "")
+
+	if _, err := newLLMClient(); err == nil || !strings.Contains(err.Error(), "auth set-key") {
+		t.Errorf("without a key: %v", err)
+	}
+
+	withStdin(t, "sk-or-v1-keyring-0123456789abcdef\n")
+	if err := runAuthSetKey(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ring.secrets[keyringLLMAccount] != "sk-or-v1-keyring-0123456789abcdef" {
+		t.Errorf("keyring = %v", ring.secrets)
+	}
+
+	gets := ring.gets
+	for i := 0; i < 3; i++ {
+		if key, err := llmAPIKeyConfig(); key != "sk-or-v1-keyring-0123456789abcdef" || err != nil {
+			t.Fatalf("llmAPIKeyConfig = %q, %v", key, err)
+		}
+	}
+	if ring.gets-gets != 1 {
+		t.Errorf("keyring read %d times, want once", ring.gets-gets)
+	}
+	if _, err := newLLMClient(); err != nil {
+		t.Errorf("with a keyring key: %v", err)
+	}
+	if got := redact("key was sk-or-v1-keyring-0123456789abcdef"); strings.Contains(got, "keyring-0123") {
+		t.Errorf("keyring key not redacted: %q", got)
+	}
+
+	// The environment wins over the keyring
+	t.Setenv("YTSUMMARY_API_KEY", "env-key")
+	if key, _ := llmAPIKeyConfig(); key != "env-key" {
+		t.Errorf("with YTSUMMARY_API_KEY: key = %q", key)
+	}
+	t.Setenv("YTSUMMARY_API_KEY", "")
+
+	if err := runAuthDeleteKey(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := llmAPIKeyConfig(); key != "" {
+		t.Errorf("after delete-key: key = %q", key)
+	}
+}
+
+func TestAuthSetKeyRejectsBadInput(t *testing.T) {
+	withFakeKeyring(t)
+	for _, input := range []string{"", "two keys\n"} {
+		withStdin(t, input)
+		if err := runAuthSetKey(nil, nil); err == nil {
+			t.Errorf("input %q accepted", input)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote(`it's`); got != `'it'"'"'s'` {
+		t.Errorf("shellQuote = %s", got)
+	}
+}
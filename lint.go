@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	lintSummaries bool
+	maxWords      int
+)
+
+// maxWordsTolerance lets a summary run slightly over its word budget before
+// the lint pass asks for a rewrite
+const maxWordsTolerance = 1.1
+
+// minStopwordHits is how many common words a summary needs before its
+// language is judged; shorter or unusual text is given the benefit of the doubt
+const minStopwordHits = 5
+
+// languageStopwords are frequent function words, used to tell Latin-script
+// languages apart
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "this", "are", "on", "as", "be"},
+	"es": {"el", "la", "de", "que", "y", "en", "los", "las", "del", "por", "con", "una", "para", "es", "se"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "une", "du", "que", "pour", "dans", "qui", "sur", "pas"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "ein", "eine", "den", "von", "zu", "sich", "auf", "für"},
+	"it": {"il", "di", "che", "la", "e", "per", "un", "una", "del", "della", "sono", "non", "con", "gli", "le"},
+	"pt": {"o", "a", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "os", "se"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "op", "te", "niet", "met", "voor", "zijn", "die", "ook"},
+}
+
+// languageScripts is the writing system of languages that don't use Latin script
+var languageScripts = map[string]string{
+	"ru": "cyrillic", "uk": "cyrillic", "bg": "cyrillic", "sr": "cyrillic", "be": "cyrillic", "kk": "cyrillic",
+	"el": "greek",
+	"ar": "arabic", "fa": "arabic", "ur": "arabic",
+	"he": "hebrew",
+	"hi": "devanagari", "mr": "devanagari", "ne": "devanagari",
+	"th": "thai",
+	"ko": "hangul",
+	"ja": "japanese",
+	"zh": "han",
+}
+
+// languageNames are used in corrective instructions
+var languageNames = map[string]string{
+	"en": "English", "es": "Spanish", "fr": "French", "de": "German", "it": "Italian",
+	"pt": "Portuguese", "nl": "Dutch", "ru": "Russian", "uk": "Ukrainian", "el": "Greek",
+	"ar": "Arabic", "he": "Hebrew", "hi": "Hindi", "th": "Thai", "ko": "Korean",
+	"ja": "Japanese", "zh": "Chinese",
+}
+
+// languageName returns the English name of a language code, or the code itself
+func languageName(code string) string {
+	if name, ok := languageNames[baseLanguage(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// baseLanguage strips the region from a language code ("pt-BR" → "pt")
+func baseLanguage(code string) string {
+	base, _, _ := strings.Cut(strings.ToLower(code), "-")
+	return base
+}
+
+// lintEnabled reports whether summaries are checked (--lint / YTSUMMARY_LINT)
+func lintEnabled() bool {
+	if lintSummaries {
+		return true
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv("YTSUMMARY_LINT"))
+	return enabled
+}
+
+// dominantScript returns the writing system most letters in text belong to
+func dominantScript(text string) string {
+	counts := map[string]int{}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			counts["japanese"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["hangul"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["greek"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["arabic"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["hebrew"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["devanagari"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["thai"]++
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		}
+	}
+	// Japanese mixes kanji with kana; any real amount of kana makes it Japanese
+	if counts["japanese"] > 0 && counts["japanese"]*5 >= counts["han"] {
+		counts["japanese"] += counts["han"]
+		counts["han"] = 0
+	}
+
+	best := ""
+	for script, n := range counts {
+		if best == "" || n > counts[best] || (n == counts[best] && script < best) {
+			best = script
+		}
+	}
+	return best
+}
+
+// detectLanguage guesses the language of text. It returns a language code for
+// Latin-script languages it knows, a script name ("cyrillic", "han"...) for
+// other writing systems, or "" when it can't tell.
+func detectLanguage(text string) string {
+	script := dominantScript(text)
+	if script != "latin" {
+		return script
+	}
+
+	hits := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		for lang, stopwords := range languageStopwords {
+			for _, sw := range stopwords {
+				if word == sw {
+					hits[lang]++
+					break
+				}
+			}
+		}
+	}
+
+	best, second := "", 0
+	for lang, n := range hits {
+		switch {
+		case best == "" || n > hits[best]:
+			second = hits[best]
+			best = lang
+		case n > second:
+			second = n
+		}
+	}
+	// Too few words, or too close to call between similar languages
+	if best == "" || hits[best] < minStopwordHits || float64(hits[best]) < 1.5*float64(second) {
+		return ""
+	}
+	return best
+}
+
+// lintSummary returns the problems with a summary: written in a language other
+// than expected, or longer than maxWords. Languages the detector doesn't know
+// are not checked.
+func lintSummary(summary, expected string, maxWords int) []string {
+	var problems []string
+
+	if want := baseLanguage(expected); want != "" {
+		detected := detectLanguage(summary)
+		wantScript, nonLatin := languageScripts[want]
+		_, knownLatin := languageStopwords[want]
+		switch {
+		case detected == "":
+		case nonLatin && detected != wantScript && !(want == "ja" && detected == "han"):
+			problems = append(problems, fmt.Sprintf("it is not written in %s", languageName(want)))
+		case knownLatin && detected != want:
+			if _, ok := languageStopwords[detected]; ok {
+				problems = append(problems, fmt.Sprintf("it is written in %s instead of %s", languageName(detected), languageName(want)))
+			} else {
+				problems = append(problems, fmt.Sprintf("it is not written in %s", languageName(want)))
+			}
+		}
+	}
+
+	if maxWords > 0 {
+		if n := len(strings.Fields(summary)); float64(n) > float64(maxWords)*maxWordsTolerance {
+			problems = append(problems, fmt.Sprintf("it is %d words long, over the %d-word limit", n, maxWords))
+		}
+	}
+
+	return problems
+}
+
+// correctivePrompt asks the model to fix the problems lintSummary found
+func correctivePrompt(problems []string, expected string, maxWords int) string {
+	var b strings.Builder
+	b.WriteString("A previous answer to this request was rejected because " + strings.Join(problems, " and ") + ".")
+	if expected != "" {
+		b.WriteString(" Write the summary in " + languageName(expected) + ".")
+	}
+	if maxWords > 0 {
+		fmt.Fprintf(&b, " Keep it under %d words.", maxWords)
+	}
+	return b.String()
+}
+
+// completeWithLint runs the final summary request and, when lint is enabled
+// and the summary has problems, retries once with a corrective instruction
+func completeWithLint(client LLMClient, text, prompt string, opts SummaryOptions) (string, error) {
+	summary, err := summarizeChunk(client, text, prompt, opts.Generation)
+	if err != nil || !opts.Lint {
+		return summary, err
+	}
+
+	problems := lintSummary(summary, opts.Vars.Language, opts.MaxWords)
+	if len(problems) == 0 {
+		return summary, nil
+	}
+	fmt.Fprintf(os.Stderr, "Summary failed lint (%s); retrying once\n", strings.Join(problems, "; "))
+
+	retry, err := summarizeChunk(client, text, prompt+"\n\n"+correctivePrompt(problems, opts.Vars.Language, opts.MaxWords), opts.Generation)
+	if err != nil {
+		return "", err
+	}
+	if problems := lintSummary(retry, opts.Vars.Language, opts.MaxWords); len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: summary still fails lint: %s\n", strings.Join(problems, "; "))
+	}
+	return retry, nil
+}
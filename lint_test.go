@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"The video explains how the engine works and why it is important for the team.":                   "en",
+		"El video explica cómo funciona el motor y por qué es importante para los equipos de la empresa.": "es",
+		"Видео объясняет, как работает двигатель.":                                                        "cyrillic",
+		"このビデオはエンジンの仕組みを説明します。":                                                                           "japanese",
+		"Short text.": "",
+	}
+	for text, want := range tests {
+		if got := detectLanguage(text); got != want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestLintSummary(t *testing.T) {
+	spanish := "El video explica cómo funciona el motor y por qué es importante para los equipos de la empresa."
+	if problems := lintSummary(spanish, "es", 0); len(problems) != 0 {
+		t.Errorf("lintSummary() = %v, want no problems", problems)
+	}
+	problems := lintSummary(spanish, "en-US", 0)
+	if len(problems) != 1 || !strings.Contains(problems[0], "Spanish instead of English") {
+		t.Errorf("lintSummary() = %v, want a language problem", problems)
+	}
+	if problems := lintSummary(spanish, "", 5); len(problems) != 1 || !strings.Contains(problems[0], "5-word limit") {
+		t.Errorf("lintSummary() = %v, want a length problem", problems)
+	}
+	// Languages the detector doesn't know are not checked
+	if problems := lintSummary(spanish, "sw", 0); len(problems) != 0 {
+		t.Errorf("lintSummary() = %v, want no problems for an unknown language", problems)
+	}
+}
+
+// lintLLMClient answers in Spanish until it is told which language to use
+type lintLLMClient struct {
+	fakeLLMClient
+	prompts []string
+}
+
+func (c *lintLLMClient) Complete(systemPrompt, text string, params GenerationParams) (string, error) {
+	c.prompts = append(c.prompts, systemPrompt)
+	if strings.Contains(systemPrompt, "Write the summary in English") {
+		return "The video explains how the engine works and why it is important for the team.", nil
+	}
+	return "El video explica cómo funciona el motor y por qué es importante para los equipos de la empresa.", nil
+}
+
+func TestCompleteWithLintRetries(t *testing.T) {
+	opts := SummaryOptions{Vars: PromptVars{Language: "en"}}
+
+	client := &lintLLMClient{}
+	if _, err := completeWithLint(client, "text", "prompt", opts); err != nil {
+		t.Fatalf("completeWithLint() error = %v", err)
+	}
+	if len(client.prompts) != 1 {
+		t.Errorf("made %d calls with lint disabled, want 1", len(client.prompts))
+	}
+
+	opts.Lint = true
+	client = &lintLLMClient{}
+	got, err := completeWithLint(client, "text", "prompt", opts)
+	if err != nil {
+		t.Fatalf("completeWithLint() error = %v", err)
+	}
+	if len(client.prompts) != 2 {
+		t.Fatalf("made %d calls, want a single retry", len(client.prompts))
+	}
+	if !strings.HasPrefix(got, "The video") {
+		t.Errorf("completeWithLint() = %q, want the corrected English summary", got)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Flags for creator --channel-ttl, --playlist-ttl and --refresh
+var (
+	channelTTL      string
+	playlistTTL     string
+	refreshListings bool
+)
+
+const (
+	defaultChannelTTL  = 24 * time.Hour // a channel's uploads playlist practically never changes
+	defaultPlaylistTTL = time.Hour      // new uploads show up within the hour
+)
+
+// Kinds of cached listings
+const (
+	listingChannel       = "channel"        // a channel and its uploads playlist
+	listingPlaylistItems = "playlist_items" // a playlist's videos, in order
+)
+
+// Listing is a cached channel or playlist enumeration. Data is the listing's
+// JSON; Complete is false when enumeration stopped early, at a --limit.
+type Listing struct {
+	Kind      string
+	Key       string
+	Data      json.RawMessage
+	Complete  bool
+	FetchedAt time.Time
+}
+
+// ListingStore caches channel and playlist listings so repeated runs don't
+// page through them again. SQLiteCache implements it.
+type ListingStore interface {
+	// GetListing returns a cached listing, or errCacheMiss
+	GetListing(kind, key string) (*Listing, error)
+	// StoreListing replaces the cached listing of its kind and key
+	StoreListing(l *Listing) error
+	// DeleteListings drops the cached listings of a kind, or all of them for
+	// "", returning how many there were
+	DeleteListings(kind string) (int64, error)
+}
+
+func (c *SQLiteCache) GetListing(kind, key string) (*Listing, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	l := Listing{Kind: kind, Key: key}
+	var data string
+	err = db.QueryRow("SELECT data, complete, fetched_at FROM listings WHERE kind = ? AND key = ?", kind, key).
+		Scan(&data, &l.Complete, &l.FetchedAt)
+	if err == sql.ErrNoRows {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query listing: %w", err)
+	}
+	l.Data = json.RawMessage(data)
+	return &l, nil
+}
+
+func (c *SQLiteCache) StoreListing(l *Listing) error {
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	fetchedAt := l.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+	_, err = db.Exec(`
+		INSERT OR REPLACE INTO listings (kind, key, data, complete, fetched_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, l.Kind, l.Key, string(l.Data), l.Complete, fetchedAt.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return fmt.Errorf("failed to cache listing: %w", err)
+	}
+	return nil
+}
+
+func (c *SQLiteCache) DeleteListings(kind string) (int64, error) {
+	if c.cfg.ReadOnly {
+		return 0, errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return 0, err
+	}
+
+	query, args := "DELETE FROM listings", []any{}
+	if kind != "" {
+		query, args = query+" WHERE kind = ?", append(args, kind)
+	}
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete listings: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// listingTTLConfig reads a listing TTL from its flag or environment variable:
+// a Go duration ("6h") or plain seconds. 0 turns caching of that listing off.
+func listingTTLConfig(flagVal, envKey string, def time.Duration) (time.Duration, error) {
+	value := strings.TrimSpace(getConfig(flagVal, envKey))
+	if value == "" {
+		return def, nil
+	}
+	if isDigits(value) {
+		value += "s"
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q (use e.g. 30m or 6h, or 0 not to cache)", envKey, getConfig(flagVal, envKey))
+	}
+	return d, nil
+}
+
+// listingCache reads and writes one client's cached listings
+type listingCache struct {
+	store ListingStore // nil caches nothing
+	ttls  map[string]time.Duration
+
+	// refresh skips cached listings; fresh ones are still stored
+	refresh bool
+}
+
+// newListingCache returns the listing cache configured by --channel-ttl,
+// --playlist-ttl and --refresh
+func newListingCache(store ListingStore) (*listingCache, error) {
+	channel, err := listingTTLConfig(channelTTL, "YTSUMMARY_CHANNEL_TTL", defaultChannelTTL)
+	if err != nil {
+		return nil, err
+	}
+	playlist, err := listingTTLConfig(playlistTTL, "YTSUMMARY_PLAYLIST_TTL", defaultPlaylistTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &listingCache{
+		store:   store,
+		ttls:    map[string]time.Duration{listingChannel: channel, listingPlaylistItems: playlist},
+		refresh: refreshListings,
+	}, nil
+}
+
+// get decodes a cached listing younger than its kind's TTL into v, reporting
+// whether there was one and whether it is complete
+func (lc *listingCache) get(kind, key string, v any) (found, complete bool) {
+	if lc == nil || lc.store == nil || lc.refresh || lc.ttls[kind] <= 0 {
+		return false, false
+	}
+	l, err := lc.store.GetListing(kind, key)
+	if err != nil {
+		if !errors.Is(err, errCacheMiss) {
+			log("warning: failed to read cached listing: %v", err)
+		}
+		return false, false
+	}
+	age := time.Since(l.FetchedAt)
+	if age > lc.ttls[kind] {
+		return false, false
+	}
+	if err := json.Unmarshal(l.Data, v); err != nil {
+		return false, false
+	}
+	log("Using the %s listing cached %s ago (--refresh lists it again)", strings.ReplaceAll(kind, "_", " "), age.Round(time.Second))
+	return true, l.Complete
+}
+
+// put caches a listing just fetched. Failing to is only worth a warning.
+func (lc *listingCache) put(kind, key string, v any, complete bool) {
+	if lc == nil || lc.store == nil || lc.ttls[kind] <= 0 {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = lc.store.StoreListing(&Listing{Kind: kind, Key: key, Data: data, Complete: complete})
+	}
+	if err != nil && !errors.Is(err, errCacheReadOnly) {
+		log("warning: failed to cache listing: %v", err)
+	}
+}
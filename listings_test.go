@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestListingStore(t *testing.T) {
+	cache := newTestCache(t)
+	if _, err := cache.GetListing(listingPlaylistItems, "UUbaker"); !errors.Is(err, errCacheMiss) {
+		t.Fatalf("empty cache: err = %v, want errCacheMiss", err)
+	}
+
+	fetchedAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	if err := cache.StoreListing(&Listing{Kind: listingPlaylistItems, Key: "UUbaker", Data: json.RawMessage(`[{"video_id":"bakeVideo01"}]`), FetchedAt: fetchedAt}); err != nil {
+		t.Fatal(err)
+	}
+	cache.StoreListing(&Listing{Kind: listingChannel, Key: "token.json", Data: json.RawMessage(`{}`), Complete: true})
+	l, err := cache.GetListing(listingPlaylistItems, "UUbaker")
+	if err != nil || string(l.Data) != `[{"video_id":"bakeVideo01"}]` || l.Complete || !l.FetchedAt.Equal(fetchedAt) {
+		t.Errorf("listing = %+v, %v", l, err)
+	}
+
+	if n, err := cache.DeleteListings(listingPlaylistItems); err != nil || n != 1 {
+		t.Errorf("DeleteListings(playlist items) = %d, %v", n, err)
+	}
+	if n, err := cache.DeleteListings(""); err != nil || n != 1 {
+		t.Errorf("DeleteListings(all) = %d, %v", n, err)
+	}
+}
+
+func TestCreatorListingCache(t *testing.T) {
+	cfg, _ := newFakeGoogle(t)
+	cache := newTestCache(t)
+	newClient := func() *youtubeDataClient {
+		listings, err := newListingCache(cache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &youtubeDataClient{cfg: cfg, token: &oauthToken{AccessToken: "fresh-token", Expiry: time.Now().Add(time.Hour)}, listings: listings}
+	}
+	apiURL := youtubeDataAPIURL
+	offline := func() { youtubeDataAPIURL = "http://127.0.0.1:1/youtube/v3" }
+	online := func() { youtubeDataAPIURL = apiURL }
+
+	// A listing cut short at --limit serves smaller limits but not larger ones
+	if uploads, err := newClient().myUploads(1); err != nil || len(uploads) != 1 {
+		t.Fatalf("myUploads(1) = %+v, %v", uploads, err)
+	}
+	offline()
+	if uploads, err := newClient().myUploads(1); err != nil || len(uploads) != 1 || uploads[0].VideoID != "bakeVideo01" {
+		t.Errorf("cached myUploads(1) = %+v, %v", uploads, err)
+	}
+	if _, err := newClient().myUploads(0); err == nil {
+		t.Error("all uploads were served from a partial listing")
+	}
+
+	online()
+	if _, err := newClient().myUploads(0); err != nil {
+		t.Fatal(err)
+	}
+	offline()
+	uploads, err := newClient().myUploads(2)
+	if err != nil || len(uploads) != 2 || uploads[1].Title != "Rye" || !uploads[1].PublishedAt.Equal(time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("cached myUploads(2) = %+v, %v", uploads, err)
+	}
+
+	// --refresh lists again; so does a listing older than its TTL
+	refreshListings = true
+	if _, err := newClient().myUploads(0); err == nil {
+		t.Error("--refresh used the cached listing")
+	}
+	refreshListings = false
+	l, _ := cache.GetListing(listingPlaylistItems, "UUbaker")
+	l.FetchedAt = time.Now().Add(-defaultPlaylistTTL - time.Minute)
+	cache.StoreListing(l)
+	if _, err := newClient().myUploads(0); err == nil {
+		t.Error("an expired listing was used")
+	}
+
+	t.Setenv("YTSUMMARY_PLAYLIST_TTL", "-1h")
+	if _, err := newListingCache(cache); err == nil {
+		t.Error("a negative TTL was accepted")
+	}
+}
+
+func TestListingCacheDisabled(t *testing.T) {
+	cfg, _ := newFakeGoogle(t)
+	cache := newTestCache(t)
+	t.Setenv("YTSUMMARY_CHANNEL_TTL", "0")
+	t.Setenv("YTSUMMARY_PLAYLIST_TTL", "0")
+	listings, err := newListingCache(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &youtubeDataClient{cfg: cfg, token: &oauthToken{AccessToken: "fresh-token", Expiry: time.Now().Add(time.Hour)}, listings: listings}
+	if _, err := client.myUploads(0); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := cache.DeleteListings(""); n != 0 {
+		t.Errorf("%d listings cached with TTLs of 0", n)
+	}
+}
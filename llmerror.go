@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// LLM provider error codes, more specific than llm_error
+const (
+	ErrLLMAuth          = "llm_auth_failed"
+	ErrLLMCredits       = "llm_insufficient_credits"
+	ErrLLMRateLimited   = "llm_rate_limited"
+	ErrLLMContextLength = "llm_context_length_exceeded"
+)
+
+// maxLLMErrorMessage caps how much of the provider's message is kept
+const maxLLMErrorMessage = 300
+
+// llmError is a failed chat completion request, classified so callers can
+// report an error code and an actionable hint instead of the raw response
+type llmError struct {
+	Code    string // ErrLLM* code, or ErrLLMError when unclassified
+	Status  int    // provider HTTP status
+	Model   string
+	Message string // the provider's own explanation, if it sent one
+}
+
+func (e *llmError) Error() string {
+	var b strings.Builder
+	switch e.Code {
+	case ErrLLMAuth:
+		b.WriteString("LLM provider rejected the API key")
+	case ErrLLMCredits:
+		b.WriteString("LLM provider account is out of credits")
+	case ErrLLMRateLimited:
+		b.WriteString("LLM provider is rate limiting requests")
+	case ErrLLMContextLength:
+		b.WriteString("transcript is too long for " + e.Model)
+	default:
+		b.WriteString("LLM API error")
+	}
+	fmt.Fprintf(&b, " (%d)", e.Status)
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	if hint := e.hint(); hint != "" {
+		b.WriteString(". " + hint)
+	}
+	return b.String()
+}
+
+// hint tells the user how to fix the error
+func (e *llmError) hint() string {
+	switch e.Code {
+	case ErrLLMAuth:
+		return "Check YTSUMMARY_API_KEY (or --api-key) and that it belongs to the provider at YTSUMMARY_API_URL"
+	case ErrLLMCredits:
+		return "Add credits to the provider account, or pick a free model with YTSUMMARY_MODEL (or --model)"
+	case ErrLLMRateLimited:
+		return "Wait a minute and retry; batch and prefetch runs can slow down with --delay"
+	case ErrLLMContextLength:
+		return "Use a model with a larger context window, or summarize part of the video with --from/--to"
+	}
+	return ""
+}
+
+// httpStatus is the status the server answers with for this error
+func (e *llmError) httpStatus() int {
+	switch e.Code {
+	case ErrLLMRateLimited:
+		return http.StatusServiceUnavailable
+	case ErrLLMContextLength:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// newLLMError classifies a non-200 chat completion response. OpenAI-compatible
+// APIs send {"error": {"message", "code", "type"}}; OpenRouter's code is the
+// HTTP status, OpenAI's a string such as "context_length_exceeded".
+func newLLMError(model string, status int, body []byte) *llmError {
+	var parsed struct {
+		Error struct {
+			Message string          `json:"message"`
+			Code    json.RawMessage `json:"code"`
+			Type    string          `json:"type"`
+		} `json:"error"`
+	}
+	e := &llmError{Code: ErrLLMError, Status: status, Model: model}
+	if err := json.Unmarshal(body, &parsed); err == nil && parsed.Error.Message != "" {
+		e.Message = parsed.Error.Message
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	// Providers echo keys and request headers back in error bodies
+	e.Message = redact(e.Message)
+	if len(e.Message) > maxLLMErrorMessage {
+		e.Message = e.Message[:maxLLMErrorMessage] + "..."
+	}
+
+	detail := strings.ToLower(string(parsed.Error.Code) + " " + parsed.Error.Type + " " + e.Message)
+	switch {
+	case strings.Contains(detail, "context_length_exceeded") || strings.Contains(detail, "context length"):
+		e.Code = ErrLLMContextLength
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		e.Code = ErrLLMAuth
+	case status == http.StatusPaymentRequired:
+		e.Code = ErrLLMCredits
+	case status == http.StatusTooManyRequests:
+		e.Code = ErrLLMRateLimited
+	}
+	return e
+}
+
+// llmErrorClass returns the error code for a summarization failure
+func llmErrorClass(err error) string {
+	var llmErr *llmError
+	var budgetErr *budgetError
+	switch {
+	case errors.As(err, &llmErr):
+		return llmErr.Code
+	case errors.As(err, &budgetErr):
+		return ErrBudgetExceeded
+	}
+	return ErrLLMError
+}
+
+// writeLLMError reports a summarization failure with its specific code
+func writeLLMError(w http.ResponseWriter, err error, videoID, transcript string) {
+	status := http.StatusBadGateway
+	var llmErr *llmError
+	var budgetErr *budgetError
+	switch {
+	case errors.As(err, &llmErr):
+		status = llmErr.httpStatus()
+	case errors.As(err, &budgetErr):
+		status = http.StatusPaymentRequired
+	}
+	writeJSON(w, status, ErrorResponse{
+		Error:      llmErrorClass(err),
+		Message:    redact("Summarization failed: " + err.Error()),
+		VideoID:    videoID,
+		Transcript: transcript,
+	})
+}
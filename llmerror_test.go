@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewLLMError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode string
+		wantMsg  string
+	}{
+		{"invalid key", 401, `{"error":{"message":"No auth credentials found","code":401}}`, ErrLLMAuth, "No auth credentials found"},
+		{"forbidden", 403, `{"error":{"message":"Key disabled"}}`, ErrLLMAuth, "Key disabled"},
+		{"out of credits", 402, `{"error":{"message":"Insufficient credits","code":402}}`, ErrLLMCredits, "Insufficient credits"},
+		{"rate limited", 429, `{"error":{"message":"Rate limit exceeded"}}`, ErrLLMRateLimited, "Rate limit exceeded"},
+		{"openai context length", 400, `{"error":{"message":"This model's maximum context length is 128000 tokens.","type":"invalid_request_error","code":"context_length_exceeded"}}`, ErrLLMContextLength, "This model's maximum context length is 128000 tokens."},
+		{"openrouter context length", 400, `{"error":{"message":"This endpoint's maximum context length is 1048576 tokens","code":400}}`, ErrLLMContextLength, "This endpoint's maximum context length is 1048576 tokens"},
+		{"server error", 500, `upstream timed out`, ErrLLMError, "upstream timed out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newLLMError("test-model", tt.status, []byte(tt.body))
+			if e.Code != tt.wantCode {
+				t.Errorf("Code = %q, want %q", e.Code, tt.wantCode)
+			}
+			if e.Message != tt.wantMsg {
+				t.Errorf("Message = %q, want %q", e.Message, tt.wantMsg)
+			}
+			if strings.Contains(e.Error(), "{") {
+				t.Errorf("Error() = %q, should not include the raw body", e.Error())
+			}
+			if tt.wantCode != ErrLLMError && e.hint() == "" {
+				t.Error("classified errors should have a hint")
+			}
+		})
+	}
+}
+
+func TestLLMErrorLongMessage(t *testing.T) {
+	e := newLLMError("test-model", 500, []byte(strings.Repeat("x", 2*maxLLMErrorMessage)))
+	if len(e.Message) != maxLLMErrorMessage+3 {
+		t.Errorf("message length = %d, want %d", len(e.Message), maxLLMErrorMessage+3)
+	}
+}
+
+func TestOpenAIClientErrorClass(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusPaymentRequired)
+		w.Write([]byte(`{"error":{"message":"Insufficient credits","code":402}}`))
+	}))
+	defer srv.Close()
+
+	client := &openAIClient{apiKey: "test-key", model: "test-model", apiURL: srv.URL}
+	_, err := client.Complete("system", "text", GenerationParams{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	// The class survives wrapping, e.g. by multi-chunk summarization
+	wrapped := fmt.Errorf("failed to summarize chunk 2: %w", err)
+	if got := llmErrorClass(wrapped); got != ErrLLMCredits {
+		t.Errorf("llmErrorClass() = %q, want %q", got, ErrLLMCredits)
+	}
+	if got := llmErrorClass(errors.New("no response from API")); got != ErrLLMError {
+		t.Errorf("llmErrorClass(unclassified) = %q, want %q", got, ErrLLMError)
+	}
+}
+
+func TestWriteLLMError(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeLLMError(w, newLLMError("test-model", 429, []byte(`{"error":{"message":"slow down"}}`)), "dQw4w9WgXcQ", "the transcript")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+	resp := decodeBody[ErrorResponse](t, w.Result())
+	if resp.Error != ErrLLMRateLimited || resp.Transcript != "the transcript" {
+		t.Errorf("response = %+v", resp)
+	}
+}
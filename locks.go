@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// videoLockTTL bounds how long a lock outlives a replica that died holding
+// it; the next waiter takes over once it expires. Live holders renew it every
+// videoLockRenew, however long their fetch or LLM call takes.
+const (
+	videoLockTTL   = 2 * time.Minute
+	videoLockRenew = videoLockTTL / 3
+)
+
+// videoLockPoll is how often a replica waiting on another's lock retries
+const videoLockPoll = 250 * time.Millisecond
+
+// videoLockPrefix namespaces lock keys in Redis
+const videoLockPrefix = "ytsummary:lock:"
+
+// videoLocker gives one caller at a time the right to do expensive work for a
+// key such as a video and language, so the others can wait and reuse the
+// result instead of repeating it
+type videoLocker interface {
+	// lock waits until key is free or ctx is done, returning the function
+	// that frees it
+	lock(ctx context.Context, key string) (func(), error)
+}
+
+// videoLockKey names the lock for fetching a video's transcript in lang
+func videoLockKey(videoID, lang string) string {
+	return "transcript:" + videoID + ":" + lang
+}
+
+// summaryLockKey names the lock for summarizing a video with the request
+// options requestHash identifies (see summaryRequestHash)
+func summaryLockKey(videoID, requestHash string) string {
+	return "summary:" + videoID + ":" + requestHash
+}
+
+// localVideoLocks serializes work within one server
+type localVideoLocks struct {
+	mu   sync.Mutex
+	held map[string]chan struct{} // closed when the holder unlocks
+}
+
+func newLocalVideoLocks() *localVideoLocks {
+	return &localVideoLocks{held: map[string]chan struct{}{}}
+}
+
+func (l *localVideoLocks) lock(ctx context.Context, key string) (func(), error) {
+	for {
+		l.mu.Lock()
+		done, held := l.held[key]
+		if !held {
+			done = make(chan struct{})
+			l.held[key] = done
+			l.mu.Unlock()
+			return func() {
+				l.mu.Lock()
+				delete(l.held, key)
+				l.mu.Unlock()
+				close(done)
+			}, nil
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// redisUnlockScript deletes a lock only if it's still ours, so a holder
+// whose lock expired can't free the next holder's
+const redisUnlockScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+
+// redisRenewScript extends a lock only if it's still ours
+const redisRenewScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+
+// redisVideoLocks shares locks between replicas through Redis. Each replica
+// first takes its local lock, so only one request per replica polls Redis.
+type redisVideoLocks struct {
+	client *redisClient
+	local  *localVideoLocks
+	renew  time.Duration // videoLockRenew; shorter in tests
+
+	// fallbacks counts requests that went ahead without the shared lock
+	// because Redis failed, reported by /health
+	fallbacks atomic.Int64
+}
+
+// newRedisVideoLocks connects to the Redis at rawURL
+func newRedisVideoLocks(rawURL string) (*redisVideoLocks, error) {
+	client, err := newRedisClient(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("lock store: %w", err)
+	}
+	return &redisVideoLocks{client: client, local: newLocalVideoLocks(), renew: videoLockRenew}, nil
+}
+
+func (l *redisVideoLocks) lock(ctx context.Context, key string) (func(), error) {
+	unlockLocal, err := l.local.lock(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	redisKey := videoLockPrefix + key
+	for {
+		_, err := l.client.do("SET", redisKey, token, "NX", "PX", fmt.Sprint(videoLockTTL.Milliseconds()))
+		if err == nil {
+			stop := make(chan struct{})
+			renewed := make(chan struct{})
+			go l.keepAlive(key, redisKey, token, stop, renewed)
+			return func() {
+				close(stop)
+				<-renewed
+				if _, err := l.client.do("EVAL", redisUnlockScript, "1", redisKey, token); err != nil {
+					logWarn("failed to release lock", slog.String("key", key), slog.String("error", err.Error()))
+				}
+				unlockLocal()
+			}, nil
+		}
+		if !errors.Is(err, errRedisNil) {
+			// Better to repeat the work than to fail the request
+			l.fallbacks.Add(1)
+			logError("lock store unavailable, going ahead without the shared lock", slog.String("key", key), slog.String("error", err.Error()))
+			return unlockLocal, nil
+		}
+
+		// Another replica holds it
+		select {
+		case <-time.After(videoLockPoll):
+		case <-ctx.Done():
+			unlockLocal()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// keepAlive renews a held lock until stop is closed, then closes done
+func (l *redisVideoLocks) keepAlive(key, redisKey, token string, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(l.renew)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		reply, err := l.client.do("EVAL", redisRenewScript, "1", redisKey, token, fmt.Sprint(videoLockTTL.Milliseconds()))
+		switch n, _ := reply.(int64); {
+		case err != nil:
+			logWarn("failed to renew lock", slog.String("key", key), slog.String("error", err.Error()))
+		case n == 0:
+			logWarn("lock expired before it was renewed", slog.String("key", key))
+			return
+		}
+	}
+}
+
+// Close closes the connection
+func (l *redisVideoLocks) Close() error {
+	return l.client.Close()
+}
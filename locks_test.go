@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLocalVideoLocks(t *testing.T) {
+	l := newLocalVideoLocks()
+	unlock, err := l.lock(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A different key isn't held up
+	unlockB, err := l.lock(context.Background(), "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.lock(ctx, "a"); err == nil {
+		t.Fatal("lock() on a held key succeeded before it was released")
+	}
+
+	got := make(chan struct{})
+	go func() {
+		unlock, err := l.lock(context.Background(), "a")
+		if err == nil {
+			unlock()
+		}
+		close(got)
+	}()
+	unlock()
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("waiter didn't get the lock after it was released")
+	}
+}
+
+func TestRedisVideoLocks(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	var replicas []*redisVideoLocks
+	for range 2 {
+		l, err := newRedisVideoLocks(srv.url())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		replicas = append(replicas, l)
+	}
+
+	unlock, err := replicas[0].lock(context.Background(), "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*videoLockPoll)
+	defer cancel()
+	if _, err := replicas[1].lock(ctx, "k"); err == nil {
+		t.Fatal("second replica took a lock the first holds")
+	}
+
+	// A holder whose lock expired mustn't free the next holder's
+	srv.mu.Lock()
+	delete(srv.values, videoLockPrefix+"k")
+	srv.mu.Unlock()
+	unlock2, err := replicas[1].lock(context.Background(), "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	srv.mu.Lock()
+	_, held := srv.values[videoLockPrefix+"k"]
+	srv.mu.Unlock()
+	if !held {
+		t.Error("stale unlock released another replica's lock")
+	}
+	unlock2()
+}
+
+func TestRedisVideoLocksRenew(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	l, err := newRedisVideoLocks(srv.url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.renew = 10 * time.Millisecond
+
+	unlock, err := l.lock(context.Background(), "slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Work outlasting the TTL keeps the lock while its holder lives
+	srv.mu.Lock()
+	srv.expires[videoLockPrefix+"slow"] = time.Now().Add(30 * time.Millisecond)
+	srv.mu.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	srv.mu.Lock()
+	srv.expireLocked(videoLockPrefix + "slow")
+	_, held := srv.values[videoLockPrefix+"slow"]
+	srv.mu.Unlock()
+	if !held {
+		t.Error("lock expired while its holder was still working")
+	}
+	unlock()
+
+	// Without Redis, requests go ahead unlocked and are counted
+	srv.failing.Store(true)
+	unlock, err = l.lock(context.Background(), "down")
+	if err != nil {
+		t.Fatalf("lock() with Redis failing = %v, want to go ahead", err)
+	}
+	unlock()
+	if n := l.fallbacks.Load(); n != 1 {
+		t.Errorf("fallbacks = %d, want 1", n)
+	}
+}
+
+func TestSharedLocksFetchOnce(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	cache := newTestCache(t)
+	var fetches atomic.Int32
+	fetch := func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+		fetches.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return &FetchResult{Title: "Locked", Transcript: "Only once."}, nil
+	}
+
+	// Two replicas sharing a cache and a lock store
+	var handlers []http.Handler
+	for range 2 {
+		locks, err := newRedisVideoLocks(srv.url())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer locks.Close()
+		handlers = append(handlers, newServer(ServerConfig{Cache: cache, Fetch: fetch, Locks: locks}).Handler())
+	}
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			handlers[i%2].ServeHTTP(w, httptest.NewRequest("POST", "/transcript", bytes.NewBufferString(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`)))
+			if w.Code != http.StatusOK {
+				t.Errorf("request %d: status = %d: %s", i, w.Code, w.Body)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+}
+
+func TestSharedLocksSummarizeOnce(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	cache := newTestCache(t)
+	if err := cacheTranscript(cache, "dQw4w9WgXcQ", "en", "Locked", "Summarize me once."); err != nil {
+		t.Fatal(err)
+	}
+	var calls atomic.Int32
+	summarize := func(transcript string, opts SummaryOptions) (string, error) {
+		n := calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return fmt.Sprintf("Summary %d.", n), nil
+	}
+
+	// Two replicas sharing a cache, a summary store and a lock store
+	var handlers []http.Handler
+	for range 2 {
+		locks, err := newRedisVideoLocks(srv.url())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer locks.Close()
+		handlers = append(handlers, newServer(ServerConfig{Cache: cache, Summaries: cache, Summarize: summarize, Locks: locks}).Handler())
+	}
+
+	summaries := make([]string, 4)
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/summarize", bytes.NewBufferString(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`))
+			req.RemoteAddr = fmt.Sprintf("198.51.100.%d:1234", 20+i)
+			w := httptest.NewRecorder()
+			handlers[i%2].ServeHTTP(w, req)
+			var resp TranscriptResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if w.Code != http.StatusOK {
+				t.Errorf("request %d: status = %d", i, w.Code)
+			}
+			summaries[i] = resp.Summary
+		}()
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("summarized %d times, want 1", n)
+	}
+	for i, summary := range summaries {
+		if summary != "Summary 1." {
+			t.Errorf("request %d summary = %q, want the shared one", i, summary)
+		}
+	}
+
+	// Different options summarize again
+	req := httptest.NewRequest("POST", "/summarize", bytes.NewBufferString(`{"url": "https://youtu.be/dQw4w9WgXcQ", "max_words": 50}`))
+	w := httptest.NewRecorder()
+	handlers[0].ServeHTTP(w, req)
+	if n := calls.Load(); w.Code != http.StatusOK || n != 2 {
+		t.Errorf("max_words request: status = %d, summarized %d times, want 2", w.Code, n)
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"log/slog"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -17,7 +20,7 @@ func initLogger(level slog.Level) {
 	}
 
 	handler := slog.NewJSONHandler(os.Stdout, opts)
-	logger = slog.New(handler)
+	logger = slog.New(redactingHandler{handler})
 	slog.SetDefault(logger)
 }
 
@@ -60,10 +63,20 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// requestContext holds request-scoped data for logging
+// requestContext holds request-scoped data for logging. The dashboard reads
+// the video ID of requests in flight, so it's behind setVideo and video; the
+// other fields are only used by the request's own goroutine.
 type requestContext struct {
-	VideoID  string
+	mu      sync.Mutex
+	videoID string
+
+	ID       string // random, sent back as X-Request-ID and logged as request_id
 	CacheHit bool
+	Source   string         // transcript source, see transcriptSource
+	Timeline *videoTimeline // stage timings for /admin/audit; nil outside video handlers
+
+	// KeyLanguage is the default language of the API key the request used
+	KeyLanguage string
 }
 
 type ctxKey string
@@ -75,6 +88,27 @@ func setRequestContext(r *http.Request, ctx *requestContext) *http.Request {
 	return r.WithContext(context.WithValue(r.Context(), reqCtxKey, ctx))
 }
 
+// setVideo records the video a request is for
+func (c *requestContext) setVideo(videoID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.videoID = videoID
+}
+
+// video returns the video a request is for, or "" before it's known
+func (c *requestContext) video() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.videoID
+}
+
+// newRequestID returns a random ID to tie a response to its log lines
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 // getRequestContext retrieves request context for logging
 func getRequestContext(r *http.Request) *requestContext {
 	if ctx, ok := r.Context().Value(reqCtxKey).(*requestContext); ok {
@@ -89,7 +123,9 @@ func loggingMiddleware(next http.Handler) http.Handler {
 		start := time.Now()
 
 		// Initialize request context
-		r = setRequestContext(r, &requestContext{})
+		id := newRequestID()
+		w.Header().Set("X-Request-ID", id)
+		r = setRequestContext(r, &requestContext{ID: id})
 
 		// Wrap response writer to capture status
 		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
@@ -107,14 +143,18 @@ func loggingMiddleware(next http.Handler) http.Handler {
 			slog.Int("status", wrapped.status),
 			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
 			slog.String("ip", getClientIP(r)),
+			slog.String("request_id", reqCtx.ID),
 		}
 
-		if reqCtx.VideoID != "" {
-			attrs = append(attrs, slog.String("video_id", reqCtx.VideoID))
+		if videoID := reqCtx.video(); videoID != "" {
+			attrs = append(attrs, slog.String("video_id", videoID))
 		}
 		if r.Method == "POST" {
 			attrs = append(attrs, slog.Bool("cache_hit", reqCtx.CacheHit))
 		}
+		if reqCtx.Source != "" {
+			attrs = append(attrs, slog.String("source", reqCtx.Source))
+		}
 
 		// Log based on status code
 		if wrapped.status >= 500 {
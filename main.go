@@ -1,16 +1,18 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
 )
 
 func init() {
 	// Load .env file if present (silently ignore if missing)
-	godotenv.Load()
+	loadEnvFile()
 }
 
 var (
@@ -19,9 +21,55 @@ var (
 	llmModel     string
 	llmAPIKey    string
 	llmBaseURL   string
+	llmProvider  string
 	language     string
 	serverAddr   string
 	serverAPIKey string
+
+	// Cache sharing flags
+	cacheReadOnly  bool
+	cacheServerURL string
+	blobStoreURL   string
+
+	// Prompt templates
+	summaryTemplate string
+	templatesDir    string
+
+	// Transcript time ranges
+	clipOnly  bool
+	rangeFrom string
+	rangeTo   string
+
+	// Print per-word timings instead of the transcript
+	wordTimings bool
+
+	// Write the key sentences of the transcript to an SRT file
+	subtitlesFile string
+
+	// Pick the most important moments instead of summarizing
+	summaryMode     string
+	highlightsCount int
+	chaptersOutput  bool
+
+	// Summary focus
+	focusLinkedTimestamp bool
+
+	// Use YouTube's machine-translated captions when --lang has no native track
+	allowAutoTranslate bool
+
+	// Send [Music]-style annotations and filler words to the LLM as-is
+	keepNonSpeech bool
+
+	// Flag or mask profanity and add a content note to summaries
+	contentFilter string
+
+	// Client identity presented to YouTube
+	clientProfileName string
+	userAgent         string
+	extraHeaders      []string
+
+	// Developer flags
+	recordFixturesDir string
 )
 
 const defaultLanguage = "en"
@@ -33,6 +81,18 @@ func main() {
 		Long: `A CLI tool that fetches YouTube video transcripts and generates summaries using an LLM.
 
 Supports any OpenAI-compatible API for summarization.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := configureRedaction(); err != nil {
+				return err
+			}
+			if err := resolveSecretRefs(); err != nil {
+				return err
+			}
+			if err := checkSummaryLanguages(); err != nil {
+				return err
+			}
+			return configureTimeouts()
+		},
 	}
 
 	// Summarize command
@@ -42,6 +102,24 @@ Supports any OpenAI-compatible API for summarization.`,
 		Args:  cobra.ExactArgs(1),
 		RunE:  runSummarize,
 	}
+	summarizeCmd.Flags().StringVar(&summaryTemplate, "template", "", "Prompt template to use (see 'ytsummary templates list')")
+	summarizeCmd.Flags().BoolVar(&clipOnly, "clip-only", false, "For youtube.com/clip/ URLs, only use captions within the clipped range")
+	summarizeCmd.Flags().StringVar(&rangeFrom, "from", "", "Only use captions from this timestamp (e.g. 12:30)")
+	summarizeCmd.Flags().StringVar(&rangeTo, "to", "", "Only use captions up to this timestamp (e.g. 25:00)")
+	summarizeCmd.Flags().BoolVar(&focusLinkedTimestamp, "focus-linked-timestamp", false, "When the URL has t=..., emphasize the section it links to")
+	summarizeCmd.Flags().BoolVar(&keepNonSpeech, "keep-non-speech", false, "Don't strip [Music]/[Applause]-style annotations and filler words before summarizing")
+	summarizeCmd.Flags().StringVar(&contentFilter, "content-filter", "", "Flag (or mask) profanity and sensitive topics and add a content note: flag or mask (default: from YTSUMMARY_CONTENT_FILTER env)")
+	summarizeCmd.Flags().StringVar(&musicVideos, "music-videos", "", "What to do with music videos: describe (the song and its themes), skip or summarize (like any video) (default: from YTSUMMARY_MUSIC_VIDEOS env, else describe)")
+	summarizeCmd.Flags().StringVar(&subtitlesFile, "subtitles", "", "Also write the transcript sentences that match the summary, at their original times, to this SRT file")
+	summarizeCmd.Flags().StringVar(&summaryMode, "mode", summaryModeSummary, "What to produce: summary, or highlights for the most important moments with their start and end times as JSON")
+	summarizeCmd.Flags().IntVar(&highlightsCount, "count", defaultHighlights, "Moments to pick with --mode highlights")
+	summarizeCmd.Flags().BoolVar(&chaptersOutput, "chapters", false, "With --mode highlights, print the moments as YouTube description chapters (00:00 Intro) instead of JSON")
+	summarizeCmd.Flags().IntVar(&maxWords, "max-words", 0, "Ask for a summary under this many words")
+	summarizeCmd.Flags().BoolVar(&withNotes, "with-notes", false, "Give the LLM your notes on the video (see 'ytsummary note') as context")
+	summarizeCmd.Flags().StringVar(&maxTranscriptChars, "max-transcript-chars", "", "Summarize only part of longer transcripts, for a cheap summary of a very long video (default: from YTSUMMARY_MAX_TRANSCRIPT_CHARS env, else no limit)")
+	summarizeCmd.Flags().StringVar(&truncateStrategy, "truncate-strategy", "", "Which part --max-transcript-chars keeps: head, head+tail or sampled (default: from YTSUMMARY_TRUNCATE_STRATEGY env, else head+tail)")
+	summarizeCmd.Flags().BoolVar(&lintSummaries, "lint", false, "Check the summary language and length, retrying once if off (default: from YTSUMMARY_LINT env)")
+	summarizeCmd.Flags().StringVar(&compareTo, "compare-to", "", "Show how the new summary differs from a kept one: previous or a summary ID (see 'ytsummary summaries list')")
 
 	// Transcript command (just fetch, no summarize)
 	transcriptCmd := &cobra.Command{
@@ -50,6 +128,463 @@ Supports any OpenAI-compatible API for summarization.`,
 		Args:  cobra.ExactArgs(1),
 		RunE:  runTranscript,
 	}
+	transcriptCmd.Flags().BoolVar(&clipOnly, "clip-only", false, "For youtube.com/clip/ URLs, only use captions within the clipped range")
+	transcriptCmd.Flags().StringVar(&rangeFrom, "from", "", "Only use captions from this timestamp (e.g. 12:30)")
+	transcriptCmd.Flags().StringVar(&rangeTo, "to", "", "Only use captions up to this timestamp (e.g. 25:00)")
+	transcriptCmd.Flags().BoolVar(&wordTimings, "words", false, "Print the per-word timings of auto-generated captions as JSON (word, start, duration)")
+	transcriptCmd.Flags().StringVar(&contentFilter, "content-filter", "", "Flag (or mask) profanity and sensitive topics and add a content note: flag or mask (default: from YTSUMMARY_CONTENT_FILTER env)")
+
+	// Info command (metadata only, no captions)
+	infoCmd := &cobra.Command{
+		Use:   "info <youtube-url>",
+		Short: "Show video metadata and available caption languages",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runInfo,
+	}
+
+	// Languages command (caption tracks, cached per video)
+	languagesCmd := &cobra.Command{
+		Use:   "languages <youtube-url>",
+		Short: "List a video's caption languages, from the cache when it has been seen before",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runLanguages,
+	}
+	languagesCmd.Flags().BoolVar(&languagesRefresh, "refresh", false, "Ask YouTube even if the video's caption tracks are cached")
+
+	// Summarize-text command (bring your own transcript)
+	summarizeTextCmd := &cobra.Command{
+		Use:   "summarize-text [transcript.txt | -]",
+		Short: "Summarize a transcript file without fetching from YouTube",
+		Long: `Summarize a transcript you already have. Accepts plain text, VTT/SRT subtitle
+files, or YouTube timedtext XML; timestamps and markup are stripped first.
+Use - to read standard input, e.g. ytsummary transcript <url> | ytsummary summarize-text -`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runSummarizeText,
+	}
+	summarizeTextCmd.Flags().StringVarP(&textFile, "file", "f", "", "Transcript file to summarize (- for standard input)")
+	summarizeTextCmd.Flags().StringVar(&textTitle, "title", "", "Title to give the LLM as context")
+	summarizeTextCmd.Flags().StringVar(&summaryTemplate, "template", "", "Prompt template to use (see 'ytsummary templates list')")
+	summarizeTextCmd.Flags().BoolVar(&keepNonSpeech, "keep-non-speech", false, "Don't strip [Music]/[Applause]-style annotations and filler words before summarizing")
+	summarizeTextCmd.Flags().StringVar(&contentFilter, "content-filter", "", "Flag (or mask) profanity and sensitive topics and add a content note: flag or mask (default: from YTSUMMARY_CONTENT_FILTER env)")
+	summarizeTextCmd.Flags().IntVar(&maxWords, "max-words", 0, "Ask for a summary under this many words")
+	summarizeTextCmd.Flags().BoolVar(&lintSummaries, "lint", false, "Check the summary language and length, retrying once if off (default: from YTSUMMARY_LINT env)")
+
+	// Prefetch command (warm the cache, no LLM usage)
+	prefetchCmd := &cobra.Command{
+		Use:   "prefetch -f <urls.txt>",
+		Short: "Fetch and cache transcripts for a list of videos without summarizing",
+		Long: `Fetch and cache transcripts for every URL in a file (one per line, # for comments)
+so later summarize runs hit the cache instantly. No LLM calls are made.`,
+		Args: cobra.NoArgs,
+		RunE: runPrefetch,
+	}
+	prefetchCmd.Flags().StringVarP(&prefetchFile, "file", "f", "", "File with one YouTube URL or video ID per line, or a Takeout or browser export (see 'ytsummary import'); - for standard input")
+	prefetchCmd.Flags().DurationVar(&prefetchDelay, "delay", defaultPrefetchDelay, "Delay between YouTube fetches")
+	prefetchCmd.Flags().StringVar(&channelRulesFile, "channel-rules", "", "JSON file of per-channel language/template defaults (default: from YTSUMMARY_CHANNEL_RULES env)")
+	prefetchCmd.Flags().StringArrayVar(&notifyURLs, "notify", nil, "Send the run's outcome to this sink, repeatable: Slack/Discord/generic webhook URL, ntfy://host/topic or mailto:address (default: from YTSUMMARY_NOTIFY env, comma-separated)")
+	prefetchCmd.MarkFlagRequired("file")
+
+	// Batch command (summarize a list of videos to files)
+	batchCmd := &cobra.Command{
+		Use:   "batch (-f <urls.txt> | --resume <manifest.json>) --out-dir <dir>",
+		Short: "Summarize a list of videos, writing one file per video and a manifest",
+		Long: `Summarize every URL in a file (one per line, # for comments), writing
+<out-dir>/<video-id>.md for each and a manifest.json recording per-video status,
+error class, output path, token usage and timings.
+
+--resume <manifest.json> continues an interrupted or partially failed run: videos
+that succeeded are skipped and failures are retried (limit which with --retry-classes).
+
+--channel-rules applies per-channel caption languages and templates; --lang and
+--template given on the command line override them.`,
+		Args: cobra.NoArgs,
+		RunE: runBatch,
+	}
+	batchCmd.Flags().StringVarP(&batchFile, "file", "f", "", "File with one YouTube URL or video ID per line, or a Takeout or browser export (see 'ytsummary import'); - for standard input")
+	batchCmd.Flags().StringVar(&batchOutDir, "out-dir", "summaries", "Directory for summary files")
+	batchCmd.Flags().StringVar(&batchManifest, "manifest", "", "Manifest path (default: <out-dir>/manifest.json)")
+	batchCmd.Flags().DurationVar(&batchDelay, "delay", defaultPrefetchDelay, "Delay between YouTube fetches")
+	batchCmd.Flags().StringVar(&summaryTemplate, "template", "", "Prompt template to use (see 'ytsummary templates list')")
+	batchCmd.Flags().StringVar(&batchResume, "resume", "", "Resume from a previous manifest: skip successful videos and retry failures")
+	batchCmd.Flags().StringSliceVar(&batchRetryClasses, "retry-classes", nil, "With --resume, only retry failures of these error classes (e.g. rate_limited,scrape_failed)")
+	batchCmd.Flags().StringVar(&channelRulesFile, "channel-rules", "", "JSON file of per-channel language/template defaults (default: from YTSUMMARY_CHANNEL_RULES env)")
+	batchCmd.Flags().BoolVar(&keepNonSpeech, "keep-non-speech", false, "Don't strip [Music]/[Applause]-style annotations and filler words before summarizing")
+	batchCmd.Flags().StringVar(&contentFilter, "content-filter", "", "Flag (or mask) profanity and sensitive topics and add a content note: flag or mask (default: from YTSUMMARY_CONTENT_FILTER env)")
+	batchCmd.Flags().StringVar(&musicVideos, "music-videos", "", "What to do with music videos: describe (the song and its themes), skip or summarize (like any video) (default: from YTSUMMARY_MUSIC_VIDEOS env, else describe)")
+	batchCmd.Flags().IntVar(&maxWords, "max-words", 0, "Ask for a summary under this many words")
+	batchCmd.Flags().BoolVar(&lintSummaries, "lint", false, "Check the summary language and length, retrying once if off (default: from YTSUMMARY_LINT env)")
+	batchCmd.Flags().StringArrayVar(&notifyURLs, "notify", nil, "Send the run's outcome to this sink, repeatable: Slack/Discord/generic webhook URL, ntfy://host/topic or mailto:address (default: from YTSUMMARY_NOTIFY env, comma-separated)")
+
+	// Worker command (consume jobs from an external queue)
+	workerCmd := &cobra.Command{
+		Use:   "worker --queue redis://host:6379",
+		Short: "Summarize videos from a shared job queue",
+		Long: `Take jobs from a Redis list and summarize them, publishing each result to
+the list's :results twin. Run as many workers as you like against one queue;
+point them at a shared cache (--cache-dir on shared storage, or a read-only cache
+with --cache-server) so a video is only fetched once.
+
+Jobs are JSON objects: {"id": "...", "url": "...", "language": "es", "template": "...", "max_cost": 0.05}.
+The list defaults to ytsummary:jobs; choose another with ?list= on the queue URL.
+SIGINT or SIGTERM stops the worker after the job in hand.`,
+		Args: cobra.NoArgs,
+		RunE: runWorker,
+	}
+	workerCmd.PersistentFlags().StringVar(&workerQueueURL, "queue", "", "Queue URL, e.g. redis://:password@host:6379/0?list=ytsummary:jobs (default: from YTSUMMARY_QUEUE env)")
+	workerCmd.Flags().IntVar(&workerConcurrency, "concurrency", 1, "Jobs to process at once")
+	workerCmd.PersistentFlags().StringVar(&summaryTemplate, "template", "", "Prompt template for jobs that don't name one")
+	workerCmd.PersistentFlags().StringVar(&musicVideos, "music-videos", "", "What to do with music videos: describe (the song and its themes), skip or summarize (like any video) (default: from YTSUMMARY_MUSIC_VIDEOS env, else describe)")
+	workerCmd.Flags().IntVar(&maxWords, "max-words", 0, "Ask for summaries under this many words")
+	workerCmd.Flags().BoolVar(&keepNonSpeech, "keep-non-speech", false, "Don't strip [Music]/[Applause]-style annotations and filler words before summarizing")
+	workerEnqueueCmd := &cobra.Command{
+		Use:   "enqueue <url>...",
+		Short: "Push videos onto the job queue, printing each job's ID",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runWorkerEnqueue,
+	}
+	workerCmd.AddCommand(workerEnqueueCmd)
+
+	// Templates command
+	templatesCmd := &cobra.Command{
+		Use:   "templates",
+		Short: "Manage prompt templates",
+	}
+	templatesCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List available prompt templates",
+		Args:  cobra.NoArgs,
+		RunE:  runTemplatesList,
+	})
+
+	// Jobs command (dead-lettered batch/prefetch videos)
+	jobsCmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "Inspect videos that batch and prefetch runs keep failing on",
+	}
+	jobsFailedCmd := &cobra.Command{
+		Use:   "failed",
+		Short: fmt.Sprintf("List videos dead-lettered after %d failed runs", deadLetterAfter),
+		Args:  cobra.NoArgs,
+		RunE:  runJobsFailed,
+	}
+	jobsFailedCmd.Flags().BoolVar(&jobsFailedAll, "all", false, "Include suppressed videos")
+	jobsCmd.AddCommand(jobsFailedCmd)
+	jobsCmd.AddCommand(&cobra.Command{
+		Use:   "retry <video>...",
+		Short: "Let the next batch or prefetch run try dead-lettered videos again",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runJobsUpdate((*SQLiteCache).RetryDeadLetter, "will be retried"),
+	})
+	jobsCmd.AddCommand(&cobra.Command{
+		Use:   "suppress <video>...",
+		Short: "Keep skipping dead-lettered videos without reporting them as failures",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runJobsUpdate((*SQLiteCache).SuppressDeadLetter, "suppressed"),
+	})
+
+	// Summaries command (kept summaries of a video)
+	summariesCmd := &cobra.Command{
+		Use:   "summaries",
+		Short: "Browse the summaries kept for each video",
+	}
+	summariesCmd.AddCommand(&cobra.Command{
+		Use:   "list <video>",
+		Short: "List a video's summaries, newest first, with model, template and tokens",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runSummariesList,
+	})
+	summariesCmd.AddCommand(&cobra.Command{
+		Use:   "show <id>",
+		Short: "Print a kept summary",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runSummariesShow,
+	})
+
+	// Weekly digest command
+	weeklyCmd := &cobra.Command{
+		Use:   "weekly",
+		Short: "Write a digest of the videos summarized this week, grouped by theme, and send it to --notify sinks",
+		Long: `Gather the videos summarized in the last week (--since) and have the LLM write one
+digest across them: the themes they covered, citing each video, followed by links to
+them all. It goes to stdout (or --output) and to every --notify sink.
+
+--channel keeps only videos from the channels you watch. With --schedule, weekly keeps
+running and sends a digest every week at that time, e.g. "mon 09:00" local time.`,
+		Args: cobra.NoArgs,
+		RunE: runWeekly,
+	}
+	weeklyCmd.Flags().StringVar(&digestSince, "since", "7d", "How far back to look: days (7d) or a duration (48h)")
+	weeklyCmd.Flags().StringArrayVar(&digestChannels, "channel", nil, "Only videos from this channel, by name, repeatable (default: from YTSUMMARY_WEEKLY_CHANNELS env, comma-separated, else all)")
+	weeklyCmd.Flags().StringVarP(&digestOutput, "output", "o", "", "Write the digest to this file instead of stdout")
+	weeklyCmd.Flags().StringVar(&digestSchedule, "schedule", "", "Keep running and send a digest every week at this weekday and local time, e.g. \"mon 09:00\"")
+	weeklyCmd.Flags().StringArrayVar(&notifyURLs, "notify", nil, "Send the digest to this sink, repeatable: Slack/Discord/generic webhook URL, ntfy://host/topic or mailto:address (default: from YTSUMMARY_NOTIFY env, comma-separated)")
+
+	// Topics command
+	topicsCmd := &cobra.Command{
+		Use:   "topics",
+		Short: "Report the topics that keep coming up across the cached transcripts",
+		Long: `Embed every transcript cached in the window (--since, default 30 days) and cluster
+them by similarity into recurring topics. Each topic is named by its most particular
+keywords and listed with its most representative videos; topics with a single video
+are counted as one-offs.
+
+Embeddings are cached, so later reports only embed new transcripts. The model is
+--embedding-model, served by YTSUMMARY_EMBEDDING_API_URL or else the LLM's API.`,
+		Args: cobra.NoArgs,
+		RunE: runTopics,
+	}
+	topicsCmd.Flags().StringVar(&topicsSince, "since", "30d", "How far back to look: days (30d) or a duration (72h)")
+	topicsCmd.Flags().IntVar(&topicsClusters, "clusters", 0, "Number of clusters to look for (default: picked from the number of videos)")
+	topicsCmd.Flags().IntVar(&topicsPerTopic, "per-topic", defaultPerTopic, "Representative videos listed per topic")
+	topicsCmd.Flags().BoolVar(&topicsJSON, "json", false, "Print the report as JSON")
+	topicsCmd.Flags().StringVar(&embeddingModel, "embedding-model", "", "Embedding model (default: from YTSUMMARY_EMBEDDING_MODEL env, else "+defaultEmbeddingModel+")")
+
+	// Quick command (launcher integrations)
+	quickCmd := &cobra.Command{
+		Use:   "quick <youtube-url>",
+		Short: "Print a short one-paragraph summary for Raycast, Alfred and other launchers",
+		Long: `Print a single short paragraph of plain text and nothing else: no progress output,
+headings or bullets. Uses a fast model (YTSUMMARY_QUICK_MODEL, or ` + defaultQuickModel + `
+on OpenRouter) unless --model is given, and summarizes only part of very long
+transcripts.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runQuick,
+	}
+	quickCmd.Flags().IntVar(&quickMaxWords, "max-words", defaultQuickMaxWords, "Ask for a summary under this many words")
+	quickCmd.Flags().IntVar(&quickMaxTranscriptChars, "max-transcript-chars", defaultQuickMaxTranscriptChars, "Summarize only part of transcripts longer than this (0: from YTSUMMARY_MAX_TRANSCRIPT_CHARS env, else the whole transcript)")
+
+	// Import command (watch later and history exports)
+	importCmd := &cobra.Command{
+		Use:   "import <export-file | ->",
+		Short: "Turn a Google Takeout watch history or playlist, or a browser export, into a URL list",
+		Long: `Read the videos of a Google Takeout export (watch-history.json, or a playlist CSV
+such as "Watch later-videos.csv") or the YouTube links of an HTML page or bookmarks
+export, and print them as a URL list for prefetch and batch. Those commands also
+accept the export directly with --file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runImport,
+	}
+	importCmd.Flags().StringVarP(&importOutput, "output", "o", "", "Write the URL list to this file instead of stdout")
+
+	// Note command (your own notes on videos)
+	noteCmd := &cobra.Command{
+		Use:   "note",
+		Short: "Keep your own notes on videos, for exports and --with-notes",
+	}
+	noteCmd.AddCommand(&cobra.Command{
+		Use:   "add <video> <note>",
+		Short: "Add a note to a video's notes",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runNoteAdd,
+	})
+	noteCmd.AddCommand(&cobra.Command{
+		Use:   "show <video>",
+		Short: "Print a video's notes",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runNoteShow,
+	})
+	noteCmd.AddCommand(&cobra.Command{
+		Use:   "clear <video>",
+		Short: "Delete a video's notes",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runNoteClear,
+	})
+
+	// Experiments command (A/B prompt and model variants)
+	experimentsCmd := &cobra.Command{
+		Use:   "experiments",
+		Short: "Compare the variants of A/B experiments run by the server",
+	}
+	experimentsCmd.AddCommand(&cobra.Command{
+		Use:   "report [experiment]",
+		Short: "Compare ratings, summary length and cost between experiment variants",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runExperimentsReport,
+	})
+
+	// Models command
+	modelsCmd := &cobra.Command{
+		Use:   "models",
+		Short: "Inspect LLM models",
+	}
+	modelsCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the models the configured provider offers, with context window sizes",
+		Args:  cobra.NoArgs,
+		RunE:  runModelsList,
+	})
+	modelsPricingCmd := &cobra.Command{
+		Use:   "pricing",
+		Short: "Show the model prices used for cost estimates and --max-cost",
+		Args:  cobra.NoArgs,
+		RunE:  runModelsPricing,
+	}
+	modelsPricingSyncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Download current model prices into the cache directory",
+		Args:  cobra.NoArgs,
+		RunE:  runModelsPricingSync,
+	}
+	modelsPricingSyncCmd.Flags().StringVar(&pricingURL, "url", "", "Pricing JSON to download, in ytsummary's or OpenRouter's format (default: from YTSUMMARY_PRICING_URL env, else "+defaultPricingURL+")")
+	modelsPricingCmd.AddCommand(modelsPricingSyncCmd)
+	modelsCmd.AddCommand(modelsPricingCmd)
+
+	// Doctor command
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "doctor",
+		Short: "Check the cache, YouTube access and the LLM endpoint work, for debugging installs",
+		Args:  cobra.NoArgs,
+		RunE:  runDoctor,
+	})
+
+	// Service command (run serve under systemd or launchd)
+	serviceCmd := &cobra.Command{
+		Use:   "service",
+		Short: "Install serve mode as a systemd (Linux) or launchd (macOS) service",
+	}
+	serviceInstallCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Write and start a service running 'ytsummary serve', with an environment file and restart policy",
+		Args:  cobra.NoArgs,
+		RunE:  runServiceInstall,
+	}
+	serviceInstallCmd.Flags().StringVar(&serviceAddr, "addr", ":8080", "Address the service listens on")
+	serviceInstallCmd.Flags().BoolVar(&serviceDryRun, "dry-run", false, "Print the unit file instead of installing it")
+	serviceCmd.PersistentFlags().BoolVar(&serviceUser, "user", false, "Use a systemd user unit instead of a system one (no root needed)")
+	serviceCmd.AddCommand(serviceInstallCmd)
+	serviceCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show whether the service is running",
+		Args:  cobra.NoArgs,
+		RunE:  runServiceStatus,
+	})
+	serviceCmd.AddCommand(&cobra.Command{
+		Use:   "uninstall",
+		Short: "Stop the service and remove its unit file, keeping configuration and cache",
+		Args:  cobra.NoArgs,
+		RunE:  runServiceUninstall,
+	})
+
+	// Creator commands (your own uploads via the YouTube Data API)
+	creatorCmd := &cobra.Command{
+		Use:   "creator",
+		Short: "Summarize your own uploads through the YouTube Data API",
+		Long: `Sign in with your Google account to list your channel's uploads, including
+unlisted and private ones, and write descriptions, chapters or summaries from their
+official captions.
+
+Creator commands need a Google OAuth client of type "Desktop app" with the YouTube
+Data API v3 enabled: set YTSUMMARY_OAUTH_CLIENT_ID and YTSUMMARY_OAUTH_CLIENT_SECRET.`,
+	}
+	creatorCmd.PersistentFlags().StringVar(&oauthClientID, "oauth-client-id", "", "Google OAuth client ID (default: from YTSUMMARY_OAUTH_CLIENT_ID env)")
+	creatorCmd.PersistentFlags().StringVar(&oauthClientSecret, "oauth-client-secret", "", "Google OAuth client secret (default: from YTSUMMARY_OAUTH_CLIENT_SECRET env)")
+	creatorCmd.PersistentFlags().StringVar(&oauthTokenFile, "oauth-token-file", "", "Where to keep the login (default: from YTSUMMARY_OAUTH_TOKEN_FILE env, else youtube-oauth.json in --cache-dir)")
+	creatorCmd.PersistentFlags().StringVar(&channelTTL, "channel-ttl", "", "How long to reuse the cached channel lookup, 0 not to cache (default: from YTSUMMARY_CHANNEL_TTL env, else 24h)")
+	creatorCmd.PersistentFlags().StringVar(&playlistTTL, "playlist-ttl", "", "How long to reuse the cached uploads listing, 0 not to cache (default: from YTSUMMARY_PLAYLIST_TTL env, else 1h)")
+	creatorCmd.PersistentFlags().BoolVar(&refreshListings, "refresh", false, "List the channel's uploads again instead of using the cached listing")
+
+	creatorLoginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Sign in with Google in your browser and save the token",
+		Args:  cobra.NoArgs,
+		RunE:  runCreatorLogin,
+	}
+	creatorUploadsCmd := &cobra.Command{
+		Use:   "uploads",
+		Short: "List your uploads, newest first",
+		Args:  cobra.NoArgs,
+		RunE:  runCreatorUploads,
+	}
+	creatorUploadsCmd.Flags().IntVar(&creatorLimit, "limit", 0, "Most uploads to list (default: all)")
+	creatorBatchCmd := &cobra.Command{
+		Use:   "batch --out-dir <dir>",
+		Short: "Write a description (and chapters) for each of your uploads",
+		Long: `Download the official captions of each upload and write <out-dir>/<video-id>.md
+with a description generated from them; --template picks another prompt, such as
+default for a summary. --chapters adds a YouTube chapter block (see summarize --mode
+highlights). Uploads already written are skipped, so rerunning continues an
+interrupted run.
+
+Downloading captions costs 250 units of the Data API's daily quota of 10,000 per video.`,
+		Args: cobra.NoArgs,
+		RunE: runCreatorBatch,
+	}
+	creatorBatchCmd.Flags().StringVar(&creatorOutDir, "out-dir", "descriptions", "Directory for the generated files")
+	creatorBatchCmd.Flags().IntVar(&creatorLimit, "limit", 0, "Only the newest this many uploads (default: all)")
+	creatorBatchCmd.Flags().StringVar(&creatorTemplate, "template", creatorDescriptionTemplate, "Prompt template to use (see 'ytsummary templates list')")
+	creatorBatchCmd.Flags().BoolVar(&creatorChapters, "chapters", false, "Also pick chapters and add them as a YouTube chapter block")
+	creatorBatchCmd.Flags().IntVar(&highlightsCount, "count", defaultHighlights, "Chapters to pick with --chapters, besides the intro")
+	creatorBatchCmd.Flags().BoolVar(&keepNonSpeech, "keep-non-speech", false, "Don't strip [Music]/[Applause]-style annotations and filler words before summarizing")
+	creatorCmd.AddCommand(creatorLoginCmd, creatorUploadsCmd, creatorBatchCmd)
+
+	// Auth commands
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage the LLM API key and the YouTube cookies the scraper signs in with",
+		Long: `Keep the LLM API key in the OS keyring (the macOS Keychain, or GNOME Keyring/KWallet
+through secret-tool) instead of a .env file; --api-key and YTSUMMARY_API_KEY still take
+precedence.
+
+Import a signed-in browser's YouTube cookies for age-restricted and members-only
+videos. They are kept encrypted at rest in --cookie-store and only decrypted in
+memory: with the key in YTSUMMARY_SECRET_KEY_FILE (default: secret.key in your config
+directory, created on first import) or, when set, a key derived from the
+YTSUMMARY_SECRET_KEY passphrase.`,
+	}
+	authImportCookiesCmd := &cobra.Command{
+		Use:   "import-cookies <cookies.txt | ->",
+		Short: "Encrypt a cookies.txt export into the cookie store",
+		Long: `Read a Netscape cookies.txt export (from a browser extension or
+yt-dlp --cookies-from-browser) and store its YouTube and Google cookies encrypted,
+replacing any imported before. Delete the plaintext export afterwards.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runAuthImportCookies,
+	}
+	authStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show when the cookies were imported and when they expire",
+		Args:  cobra.NoArgs,
+		RunE:  runAuthStatus,
+	}
+	authRotateKeyCmd := &cobra.Command{
+		Use:   "rotate-key",
+		Short: "Re-encrypt the cookie store with a new key",
+		Long: `Generate a new key file and re-encrypt the cookie store with it. A store
+encrypted with YTSUMMARY_SECRET_KEY is re-encrypted with the passphrase in
+YTSUMMARY_NEW_SECRET_KEY instead.`,
+		Args: cobra.NoArgs,
+		RunE: runAuthRotateKey,
+	}
+	authClearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Delete the imported cookies",
+		Args:  cobra.NoArgs,
+		RunE:  runAuthClear,
+	}
+	authSetKeyCmd := &cobra.Command{
+		Use:   "set-key",
+		Short: "Store the LLM API key read from standard input in the OS keyring",
+		Example: `  ytsummary auth set-key            # paste the key, then Enter and Ctrl-D
+  pbpaste | ytsummary auth set-key`,
+		Args: cobra.NoArgs,
+		RunE: runAuthSetKey,
+	}
+	authDeleteKeyCmd := &cobra.Command{
+		Use:   "delete-key",
+		Short: "Remove the LLM API key from the OS keyring",
+		Args:  cobra.NoArgs,
+		RunE:  runAuthDeleteKey,
+	}
+	authCmd.AddCommand(authSetKeyCmd, authDeleteKeyCmd, authImportCookiesCmd, authStatusCmd, authRotateKeyCmd, authClearCmd)
+
+	// Version command
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version, commit, platform and build features",
+		Args:  cobra.NoArgs,
+		RunE:  runVersion,
+	}
+	versionCmd.Flags().BoolVar(&versionJSON, "json", false, "Print as JSON")
 
 	// Serve command (HTTP API server)
 	serveCmd := &cobra.Command{
@@ -57,129 +592,536 @@ Supports any OpenAI-compatible API for summarization.`,
 		Short: "Start the HTTP API server",
 		Long: `Start an HTTP server exposing the transcript and summarization API.
 
-Endpoints:
-  GET  /health     - Health check
-  POST /transcript - Fetch transcript only
-  POST /summarize  - Fetch transcript and summarize
+Endpoints (under /v1; the unversioned paths remain as aliases):
+  GET  /health          - Health check
+  POST /transcript      - Fetch transcript only (format=segments or words for timings, offset/limit to page)
+  POST /summarize       - Fetch transcript and summarize
+  GET  /transcript, /summarize?url=... - Same, cacheable, with If-None-Match support
+  POST /summarize/text  - Summarize provided transcript text
+  GET  /video/{id}      - Video metadata and caption languages (no captions fetched)
+  GET  /video/{id}/languages - Caption languages, cached per video (?refresh=true)
+  GET  /cache/{id}      - Whether a transcript is cached (?lang=), 200 or 404, also HEAD
+  POST /cache/status   - Which of up to 100 videos are cached, and since when
+  POST /prefetch       - Fetch and cache a transcript without summarizing ("queue": true to return at once)
+  GET  /export          - Cached transcripts as NDJSON (?since=, ?limit=, ?cursor=)
+  GET  /videos/{id}/summaries - Summaries kept for a video, newest first (.../{summary} for one)
+  POST /summaries/{id}/feedback - Rate a summary 1-5, e.g. to compare experiment variants
+  GET  /videos/{id}/notes - Your notes on a video (PATCH to replace or add to them)
+  POST /admin/reload    - Re-read .env (also on SIGHUP)
+  GET  /admin/dashboard - Live HTML view of requests, errors, cache and LLM latency
+  GET  /admin/audit/{id} - Stage timings of recent requests for a video
+  GET  /admin/queue     - Busy work slots and waiting requests by priority
+  GET  /admin/deadletter - Videos batch/prefetch stopped retrying (POST .../{id}/retry or /suppress)
 
-Set YTSUMMARY_SERVER_API_KEY or use --server-api-key to require authentication.`,
+Set YTSUMMARY_SERVER_API_KEY or use --server-api-key to require authentication.
+YTSUMMARY_SERVER_API_KEYS adds keys with their own default language (KEY=es,...).`,
 		RunE: runServe,
 	}
 	serveCmd.Flags().StringVar(&serverAddr, "addr", ":8080", "Server listen address")
 	serveCmd.Flags().StringVar(&serverAPIKey, "server-api-key", "", "API key for authentication (default: from YTSUMMARY_SERVER_API_KEY env)")
+	serveCmd.Flags().StringVar(&egressAllow, "egress-allow", "", "Comma-separated hosts LLM requests may go to, e.g. openrouter.ai or *.openai.azure.com; YouTube is always allowed (default: from YTSUMMARY_EGRESS_ALLOW env, else any host)")
+	serveCmd.Flags().StringVar(&requestDeadline, "request-deadline", "", "Longest a request may take before /summarize answers with partial results, 0 for none (default: from YTSUMMARY_REQUEST_DEADLINE env, else 100s)")
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "./cache", "Directory for SQLite cache database")
 	rootCmd.PersistentFlags().StringVar(&llmModel, "model", "", "LLM model to use (default: from YTSUMMARY_MODEL env)")
 	rootCmd.PersistentFlags().StringVar(&llmAPIKey, "api-key", "", "LLM API key (default: from YTSUMMARY_API_KEY env)")
 	rootCmd.PersistentFlags().StringVar(&llmBaseURL, "api-url", "", "LLM API base URL (default: from YTSUMMARY_API_URL env)")
+	rootCmd.PersistentFlags().StringVar(&llmTemperature, "temperature", "", "LLM sampling temperature, 0-2 (default: from YTSUMMARY_TEMPERATURE env, else the provider's)")
+	rootCmd.PersistentFlags().StringVar(&llmTopP, "top-p", "", "LLM nucleus sampling top_p, 0-1 (default: from YTSUMMARY_TOP_P env, else the provider's)")
+	rootCmd.PersistentFlags().StringVar(&llmMaxCost, "max-cost", "", "Most this run may spend on LLM calls in USD, e.g. 0.50; calls that could exceed it are refused (default: from YTSUMMARY_MAX_COST env, else no limit)")
+	rootCmd.PersistentFlags().StringVar(&pricingFile, "pricing-file", "", "JSON file of model prices overriding the built-in and synced ones (default: from YTSUMMARY_PRICING_FILE env)")
+	rootCmd.PersistentFlags().StringVar(&llmMaxTokens, "max-tokens", "", fmt.Sprintf("Longest summary the LLM may write, in tokens (default: from YTSUMMARY_MAX_TOKENS env, else %d)", defaultMaxTokens))
+	rootCmd.PersistentFlags().StringVar(&llmReasoning, "reasoning", "", "Treat the model as a reasoning model (max_completion_tokens, no temperature/top_p): auto, on or off (default: from YTSUMMARY_REASONING env, else auto-detect from the model name)")
+	rootCmd.PersistentFlags().StringVar(&llmReasoningEffort, "reasoning-effort", "", "Thinking budget for reasoning models: low, medium or high (default: from YTSUMMARY_REASONING_EFFORT env, else the provider's)")
+	rootCmd.PersistentFlags().StringVar(&openRouterProviders, "openrouter-providers", "", "Comma-separated OpenRouter inference providers to try in order (default: from YTSUMMARY_OPENROUTER_PROVIDERS env)")
+	rootCmd.PersistentFlags().StringVar(&openRouterAllowFallbacks, "openrouter-allow-fallbacks", "", "Let OpenRouter fall back to providers outside --openrouter-providers: true or false (default: from YTSUMMARY_OPENROUTER_ALLOW_FALLBACKS env, else OpenRouter's)")
+	rootCmd.PersistentFlags().StringVar(&llmProvider, "provider", "", "LLM provider: openai (any OpenAI-compatible API) or fake for offline testing (default: from YTSUMMARY_PROVIDER env)")
+	rootCmd.PersistentFlags().StringVar(&templatesDir, "templates-dir", "", "Directory of custom *.tmpl prompt templates (default: from YTSUMMARY_TEMPLATES_DIR env)")
 	rootCmd.PersistentFlags().StringVar(&language, "lang", defaultLanguage, "Preferred transcript language (e.g., en, es, fr)")
+	rootCmd.PersistentFlags().StringVar(&summaryLang, "summary-lang", "", "Language to write summaries in (default: from YTSUMMARY_SUMMARY_LANG env, else the transcript's)")
+	rootCmd.PersistentFlags().StringVar(&promptLang, "prompt-lang", "", "Language of the prompts sent to the LLM, e.g. en to keep English prompts for other summary languages (default: from YTSUMMARY_PROMPT_LANG env, else the summary language where templates are translated)")
+	rootCmd.PersistentFlags().BoolVar(&allowAutoTranslate, "allow-auto-translate", false, "Use YouTube's machine translation when --lang has no native captions (default: from YTSUMMARY_ALLOW_AUTO_TRANSLATE env)")
+	rootCmd.PersistentFlags().StringVar(&blobStoreURL, "blob-store", "", "Store large transcripts in object storage, e.g. s3://bucket/prefix or gs://bucket/prefix (default: from YTSUMMARY_BLOB_STORE env)")
+	rootCmd.PersistentFlags().BoolVar(&cacheReadOnly, "cache-readonly", false, "Open the cache read-only (for sharing a cache owned by a serve instance)")
+	rootCmd.PersistentFlags().StringVar(&cacheServerURL, "cache-server", "", "Serve instance to fetch through on cache miss when read-only (default: from YTSUMMARY_CACHE_SERVER env)")
+
+	rootCmd.PersistentFlags().StringVar(&clientProfileName, "client-profile", "", "YouTube client to present as: "+strings.Join(clientProfileNames(), ", ")+" (default: from YTSUMMARY_CLIENT_PROFILE env, else "+defaultClientProfile+")")
+	rootCmd.PersistentFlags().StringVar(&cookieStorePath, "cookie-store", "", "Encrypted store of the YouTube cookies 'auth import-cookies' imports (default: from YTSUMMARY_COOKIE_STORE env, else cookies.enc in --cache-dir)")
+	rootCmd.PersistentFlags().StringArrayVar(&redactPatterns, "redact-patterns", nil, "Regular expression whose matches are hidden from logs and error messages, on top of the built-in key and cookie patterns; repeatable (default: from YTSUMMARY_REDACT_PATTERNS env, one per line)")
+	rootCmd.PersistentFlags().StringVar(&youtubeTimeout, "youtube-timeout", "", "Timeout for each YouTube request, e.g. 45s (default: from YTSUMMARY_YOUTUBE_TIMEOUT env, else 30s)")
+	rootCmd.PersistentFlags().StringVar(&llmTimeout, "llm-timeout", "", "Timeout for each LLM request, e.g. 2m for slow local models (default: from YTSUMMARY_LLM_TIMEOUT env, else 60s)")
+	rootCmd.PersistentFlags().StringVar(&youtubeRegion, "region", "", "Two-letter country code to ask YouTube for again when a video is geo-restricted, e.g. US (default: from YTSUMMARY_REGION env)")
+	rootCmd.PersistentFlags().StringVar(&userAgent, "user-agent", "", "Override the client profile's User-Agent (default: from YTSUMMARY_USER_AGENT env)")
+	rootCmd.PersistentFlags().StringVar(&youtubeAPIKey, "youtube-api-key", "", "YouTube Data API v3 key for metadata innertube leaves out, and view counts in info (default: from YTSUMMARY_YOUTUBE_API_KEY env)")
+	rootCmd.PersistentFlags().StringArrayVar(&extraHeaders, "header", nil, "Extra \"Name: value\" header for YouTube requests, repeatable (default: from YTSUMMARY_HEADERS env, separated by |)")
+
+	rootCmd.PersistentFlags().StringVar(&statsdAddr, "statsd", "", "Send per-run metrics to this StatsD host:port (default: from YTSUMMARY_STATSD env)")
+	rootCmd.PersistentFlags().StringVar(&pushgatewayURL, "pushgateway", "", "Push per-run metrics to this Prometheus Pushgateway URL (default: from YTSUMMARY_PUSHGATEWAY env)")
+
+	rootCmd.PersistentFlags().StringVar(&debugScrapeDir, "debug-scrape", "", "Save the raw YouTube responses of failed fetches, with secrets scrubbed, to this directory for bug reports (default: from YTSUMMARY_DEBUG_SCRAPE env)")
+	rootCmd.PersistentFlags().StringVar(&recordFixturesDir, "record-fixtures", "", "Developer mode: save sanitized YouTube responses to this directory as test fixtures")
 
 	rootCmd.AddCommand(summarizeCmd)
 	rootCmd.AddCommand(transcriptCmd)
+	rootCmd.AddCommand(infoCmd)
+	rootCmd.AddCommand(languagesCmd)
+	rootCmd.AddCommand(summarizeTextCmd)
+	rootCmd.AddCommand(prefetchCmd)
+	rootCmd.AddCommand(quickCmd)
+	rootCmd.AddCommand(batchCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(workerCmd)
+	rootCmd.AddCommand(templatesCmd)
+	rootCmd.AddCommand(jobsCmd)
+	rootCmd.AddCommand(summariesCmd)
+	rootCmd.AddCommand(weeklyCmd)
+	rootCmd.AddCommand(topicsCmd)
+	rootCmd.AddCommand(noteCmd)
+	rootCmd.AddCommand(experimentsCmd)
+	rootCmd.AddCommand(modelsCmd)
 	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(serviceCmd)
+	rootCmd.AddCommand(creatorCmd)
+	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(versionCmd)
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	cmd, err := rootCmd.ExecuteC()
+	if runMetricsCommands[cmd.Name()] {
+		emitRunMetrics(cmd.Name(), err)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, redact(err.Error()))
 		os.Exit(1)
 	}
 }
 
 func log(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, "→ "+format+"\n", args...)
+	if quiet {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "→ "+redact(fmt.Sprintf(format, args...)))
 }
 
 func runSummarize(cmd *cobra.Command, args []string) error {
 	url := args[0]
-	defer closeCache()
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
 
 	log("Parsing URL...")
-	videoID, err := extractVideoID(url)
+	videoID, clip, err := resolveVideoURL(url)
 	if err != nil {
 		return fmt.Errorf("invalid YouTube URL: %w", err)
 	}
 	log("Video ID: %s", videoID)
 
-	// Check cache first
-	log("Checking cache for language '%s'...", language)
-	var transcript string
-	entry, err := getCachedTranscript(videoID, language)
+	window, err := transcriptWindow(clip)
 	if err != nil {
-		log("Not cached, fetching transcript...")
-		transcript, err = fetchTranscript(url)
-		if err != nil {
-			return fmt.Errorf("failed to fetch transcript: %w", err)
+		return err
+	}
+	filter := contentFilterMode()
+	if err := validContentFilter(filter); err != nil {
+		return err
+	}
+	music := musicVideosMode()
+	if err := validMusicVideos(music); err != nil {
+		return err
+	}
+
+	if err := validSummaryMode(summaryMode); err != nil {
+		return err
+	}
+	highlights := summaryMode == summaryModeHighlights
+	count, err := highlightCount(highlightsCount)
+	if err != nil {
+		return err
+	}
+	if highlights && (subtitlesFile != "" || compareTo != "") {
+		return fmt.Errorf("--subtitles and --compare-to need a written summary, not --mode highlights")
+	}
+	if chaptersOutput && !highlights {
+		return fmt.Errorf("--chapters needs --mode highlights")
+	}
+	limit, strategy, err := transcriptLimit(0, "")
+	if err != nil {
+		return err
+	}
+
+	linkedAt, linked := linkedTimestamp(url)
+	focus := focusLinkedTimestamp && linked
+
+	need := timingNone
+	if focus || subtitlesFile != "" || highlights {
+		need = timingSegments
+	}
+	entry, err := loadVideoTranscript(cache, videoID, window, need)
+	if err != nil {
+		cliMetrics.recordFailure(fetchErrorClass(err))
+		return err
+	}
+	if (subtitlesFile != "" || highlights) && len(entry.Segments) == 0 {
+		return fmt.Errorf("caption timings unavailable for this video")
+	}
+	warnTranscriptQuality(entry)
+	template, err := pickTemplate(summaryTemplate, entry, music)
+	if err != nil {
+		cliMetrics.recordSkipped()
+		return fmt.Errorf("%w (summarize it anyway with --music-videos summarize, or --music-videos describe for the song and its themes)", err)
+	}
+	if template != summaryTemplate {
+		log("Looks like a %s video, using the %s template (pick another with --template)", videoFormat(entry), template)
+	}
+	notes := applyContentFilter(entry, filter)
+	if !keepNonSpeech {
+		stripCaptionArtifacts(entry)
+	}
+
+	budget, err := invocationBudget()
+	if err != nil {
+		return err
+	}
+	lang := summaryLanguage(language)
+	var prev *StoredSummary
+	if compareTo != "" {
+		if prev, err = previousSummary(cache, compareTo, videoID, lang); err != nil {
+			return err
 		}
-		log("Transcript fetched (%d chars)", len(transcript))
-		// Cache it
-		if err := cacheTranscript(videoID, language, "", transcript); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to cache transcript: %v\n", err)
-		} else {
-			log("Cached transcript")
+		if prev == nil {
+			log("No earlier summary of %s to compare with", videoID)
 		}
-	} else {
-		transcript = entry.Transcript
-		log("Found cached transcript (%d chars)", len(transcript))
+	}
+	opts := SummaryOptions{
+		Template:    template,
+		Vars:        promptVarsFromEntry(entry, lang),
+		Checkpoints: cache,
+		MaxWords:    maxWords,
+		Lint:        lintEnabled(),
+		Budget:      budget,
+		Meta:        &SummaryMeta{},
+	}
+	if focus {
+		opts.Focus = focusOnLinkedSection(entry, linkedAt)
+		log("Focusing on the linked section at %s", opts.Focus)
+	}
+	if withNotes {
+		if opts.Notes, err = videoNotesText(cache, videoID); err != nil {
+			return err
+		}
+		if opts.Notes != "" {
+			log("Including your notes on the video")
+		}
+	}
+
+	if highlights {
+		return printHighlights(entry, count, opts, notes)
+	}
+
+	transcript, truncation := truncateTranscript(entry.Transcript, limit, strategy)
+	if truncation != nil {
+		log("Transcript truncated: %s", truncation)
 	}
 
 	// Summarize
 	log("Sending to LLM for summarization...")
-	summary, err := summarize(transcript)
+	summary, err := summarize(transcript, opts)
 	if err != nil {
+		cliMetrics.recordFailure(llmErrorClass(err))
 		return fmt.Errorf("failed to summarize: %w", err)
 	}
+	cliMetrics.recordProcessed()
+	keepSummary(cache, newStoredSummary(videoID, lang, template, maxWords, summary, opts.Meta))
+	if subtitlesFile != "" {
+		if err := writeCondensedSubtitles(subtitlesFile, entry.Segments, summary); err != nil {
+			return err
+		}
+	}
 
 	log("Done!\n")
-	fmt.Println(summary)
+	if prev != nil {
+		printSummaryDiff(prev, summary)
+	}
+	fmt.Println(withTruncationNote(withContentNote(summary, notes), truncation))
 	return nil
 }
 
 func runTranscript(cmd *cobra.Command, args []string) error {
 	url := args[0]
-	defer closeCache()
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
 
 	log("Parsing URL...")
-	videoID, err := extractVideoID(url)
+	videoID, clip, err := resolveVideoURL(url)
 	if err != nil {
 		return fmt.Errorf("invalid YouTube URL: %w", err)
 	}
 	log("Video ID: %s", videoID)
 
-	// Check cache first
-	log("Checking cache for language '%s'...", language)
-	var transcript string
-	entry, err := getCachedTranscript(videoID, language)
+	window, err := transcriptWindow(clip)
 	if err != nil {
-		log("Not cached, fetching transcript...")
-		transcript, err = fetchTranscript(url)
-		if err != nil {
-			return fmt.Errorf("failed to fetch transcript: %w", err)
+		return err
+	}
+	filter := contentFilterMode()
+	if err := validContentFilter(filter); err != nil {
+		return err
+	}
+
+	need := timingNone
+	if wordTimings {
+		need = timingWords
+	}
+	entry, err := loadVideoTranscript(cache, videoID, window, need)
+	if err != nil {
+		cliMetrics.recordFailure(fetchErrorClass(err))
+		return err
+	}
+	cliMetrics.recordProcessed()
+
+	if notes := applyContentFilter(entry, filter); notes != nil && notes.String() != "" {
+		log("Content note: %s", notes)
+	}
+
+	log("Done!\n")
+	if wordTimings {
+		words := transcriptWords(entry.Segments)
+		if len(words) == 0 {
+			return errNoWordTimings
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(words)
+	}
+	fmt.Println(entry.Transcript)
+	return nil
+}
+
+// printHighlights has the LLM pick the entry's most important moments and
+// prints them as JSON, or as chapters with --chapters
+func printHighlights(entry *CacheEntry, count int, opts SummaryOptions, notes *ContentNotes) error {
+	log("Asking the LLM for the %d most important moments...", count)
+	moments, err := extractHighlights(summarize, entry.Segments, count, opts)
+	if err != nil {
+		cliMetrics.recordFailure(llmErrorClass(err))
+		return fmt.Errorf("failed to pick highlights: %w", err)
+	}
+	cliMetrics.recordProcessed()
+	if notes != nil && notes.String() != "" {
+		log("Content note: %s", notes)
+	}
+
+	log("Done!\n")
+	if chaptersOutput {
+		chapters, n := youtubeChapters(moments)
+		if warning := chaptersWarning(n); warning != "" {
+			log("Warning: %s", warning)
 		}
-		log("Transcript fetched (%d chars)", len(transcript))
-		// Cache it
-		if err := cacheTranscript(videoID, language, "", transcript); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to cache transcript: %v\n", err)
-		} else {
-			log("Cached transcript")
+		fmt.Println(chapters)
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(moments)
+}
+
+// transcriptWindow returns the time range to restrict the transcript to:
+// --from/--to when given, otherwise the clip range with --clip-only
+func transcriptWindow(clip *TimeRange) (*TimeRange, error) {
+	window, err := parseTimeRange(rangeFrom, rangeTo)
+	if err != nil {
+		return nil, err
+	}
+	if window == nil && clipOnly {
+		window = clip
+	}
+	return window, nil
+}
+
+// loadVideoTranscript loads the transcript, restricting it to window when set.
+// need makes sure caption timings are loaded even without a window.
+func loadVideoTranscript(cache Cache, videoID string, window *TimeRange, need timingDetail) (*CacheEntry, error) {
+	if window != nil {
+		need = max(need, timingSegments)
+	}
+	entry, err := loadTranscript(cache, videoID, language, need)
+	if err != nil || window == nil {
+		return entry, err
+	}
+
+	if err := restrictToRange(entry, *window); err != nil {
+		return nil, err
+	}
+	log("Restricted to %s (%d chars)", window, len(entry.Transcript))
+	return entry, nil
+}
+
+// loadTranscript returns the transcript from cache, fetching and caching it on a miss.
+// In read-only cache mode, misses are fetched through the cache server if one is
+// configured so that it stays the only writer. Entries cached without the
+// caption timings need asks for are refetched, as are machine-translated entries
+// unless auto-translation is allowed.
+func loadTranscript(cache Cache, videoID, lang string, need timingDetail) (*CacheEntry, error) {
+	allowTranslate := autoTranslateAllowed()
+
+	log("Checking cache for language '%s'...", lang)
+	entry, err := cache.GetTranscript(videoID, lang)
+	switch {
+	case err != nil:
+	case !hasTimings(entry, need):
+		log("Cached transcript has no caption timings, refetching...")
+	case entry.TranslatedFrom != "" && !allowTranslate:
+		log("Cached transcript is machine-translated, refetching...")
+	default:
+		log("Found cached transcript (%d chars)", len(entry.Transcript))
+		return entry, nil
+	}
+
+	if cacheReadOnly {
+		if serverURL := getConfig(cacheServerURL, "YTSUMMARY_CACHE_SERVER"); serverURL != "" {
+			log("Not cached, fetching via cache server %s...", serverURL)
+			resp, err := fetchTranscriptViaServer(serverURL, getConfig(serverAPIKey, "YTSUMMARY_SERVER_API_KEY"), canonicalVideoURL(videoID), lang)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch transcript: %w", err)
+			}
+			log("Transcript fetched (%d chars)", len(resp.Transcript))
+			return &CacheEntry{
+				VideoID:    videoID,
+				Language:   lang,
+				Title:      resp.Title,
+				Transcript: resp.Transcript,
+			}, nil
 		}
+	}
+
+	log("Not cached, fetching transcript...")
+	result, err := fetchTranscript(canonicalVideoURL(videoID), lang, allowTranslate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transcript: %w", err)
+	}
+	log("Transcript fetched (%d chars)", len(result.Transcript))
+	warnLanguageMismatch(lang, result)
+
+	// Cache it
+	if err := cacheFetchResult(cache, videoID, lang, result); errors.Is(err, errCacheReadOnly) {
+		log("Cache is read-only, not caching")
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to cache transcript: %v\n", err)
 	} else {
-		transcript = entry.Transcript
-		log("Found cached transcript (%d chars)", len(transcript))
+		log("Cached transcript")
 	}
 
-	log("Done!\n")
-	fmt.Println(transcript)
+	return result.cacheEntry(videoID, lang), nil
+}
+
+func runTemplatesList(cmd *cobra.Command, args []string) error {
+	templates, err := loadTemplates()
+	if err != nil {
+		return err
+	}
+
+	langs, err := templateLanguages()
+	if err != nil {
+		return err
+	}
+	translated := make(map[string][]string)
+	for _, lang := range langs {
+		translations, err := loadTranslations(lang)
+		if err != nil {
+			return err
+		}
+		for name := range translations {
+			translated[name] = append(translated[name], lang)
+		}
+	}
+
+	for _, tmpl := range sortedTemplates(templates) {
+		fmt.Printf("%-16s %s\n", tmpl.Name, tmpl.Description)
+		if tmpl.Source != "embedded" {
+			fmt.Printf("%-16s (from %s)\n", "", tmpl.Source)
+		}
+		if langs := translated[tmpl.Name]; len(langs) > 0 {
+			fmt.Printf("%-16s (also in %s)\n", "", strings.Join(langs, ", "))
+		}
+	}
 	return nil
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
-	defer closeCache()
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	// Get API key from flag or environment (the environment one can change on reload)
+	apiKey := getConfig(serverAPIKey, "YTSUMMARY_SERVER_API_KEY")
+	apiKeys, err := apiKeysFromEnv()
+	if err != nil {
+		return err
+	}
+	defaults, err := requestDefaultsFromEnv()
+	if err != nil {
+		return err
+	}
+	deadline, err := requestDeadlineConfig()
+	if err != nil {
+		return err
+	}
+	youtube, err := youtubeTimeoutConfig()
+	if err != nil {
+		return err
+	}
+	// Fail now rather than on every summary when the policy leaves out the LLM
+	if getConfig(llmProvider, "YTSUMMARY_PROVIDER") != "fake" {
+		apiURL := getConfig(llmBaseURL, "YTSUMMARY_API_URL")
+		if apiURL == "" {
+			apiURL = defaultAPIURL
+		}
+		if err := checkEgress(apiURL); err != nil {
+			return fmt.Errorf("the LLM API URL isn't allowed: %w", err)
+		}
+	}
 
-	// Get API key from flag or environment
-	apiKey := serverAPIKey
-	if apiKey == "" {
-		apiKey = os.Getenv("YTSUMMARY_SERVER_API_KEY")
+	perMinute, burst := rateLimitConfig()
+
+	// Replicas behind one load balancer share limits through Redis
+	var sharedLimit *redisRateLimiter
+	if url := os.Getenv("YTSUMMARY_RATE_LIMIT_REDIS"); url != "" {
+		if sharedLimit, err = newRedisRateLimiter(url); err != nil {
+			return err
+		}
+		defer sharedLimit.Close()
+	}
+	var locks videoLocker
+	if url := os.Getenv("YTSUMMARY_LOCK_REDIS"); url != "" {
+		redisLocks, err := newRedisVideoLocks(url)
+		if err != nil {
+			return err
+		}
+		defer redisLocks.Close()
+		locks = redisLocks
 	}
 
-	return startServer(serverAddr, apiKey)
+	return startServer(serverAddr, newServer(ServerConfig{
+		APIKey:          apiKey,
+		APIKeys:         apiKeys,
+		PinAPIKey:       serverAPIKey != "",
+		Cache:           cache,
+		DeadLetters:     cache,
+		Summaries:       cache,
+		Notes:           cache,
+		SharedRateLimit: sharedLimit,
+		Locks:           locks,
+		RequestDeadline: deadline,
+
+		RateLimitPerMinute: perMinute,
+		RateLimitBurst:     burst,
+		WorkSlots:          workSlotsConfig(),
+		YouTubeTimeout:     youtube,
+		Defaults:           defaults,
+	}))
 }
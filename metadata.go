@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// youtubeAPIKey is a YouTube Data API v3 key; with one, metadata innertube
+// leaves out is looked up there instead
+var youtubeAPIKey string
+
+// VideoStatistics are a video's public counts, only available from the Data
+// API. Likes and comments are left out when the creator hides them.
+type VideoStatistics struct {
+	Views    int64 `json:"views"`
+	Likes    int64 `json:"likes,omitempty"`
+	Comments int64 `json:"comments,omitempty"`
+}
+
+// dataAPIVideo is the metadata the Data API has for a video
+type dataAPIVideo struct {
+	Title           string
+	Channel         string
+	ChannelID       string
+	DurationSeconds int
+	PublishDate     string
+	Category        string
+	Statistics      *VideoStatistics
+}
+
+// dataAPIVideosResponse is the part of a videos.list reply ytsummary reads
+type dataAPIVideosResponse struct {
+	Items []struct {
+		Snippet struct {
+			Title        string `json:"title"`
+			ChannelID    string `json:"channelId"`
+			ChannelTitle string `json:"channelTitle"`
+			PublishedAt  string `json:"publishedAt"`
+			CategoryID   string `json:"categoryId"`
+		} `json:"snippet"`
+		ContentDetails struct {
+			Duration string `json:"duration"`
+		} `json:"contentDetails"`
+		Statistics struct {
+			ViewCount    string `json:"viewCount"`
+			LikeCount    string `json:"likeCount"`
+			CommentCount string `json:"commentCount"`
+		} `json:"statistics"`
+	} `json:"items"`
+}
+
+// dataAPIKey returns the key from --youtube-api-key or YTSUMMARY_YOUTUBE_API_KEY
+func dataAPIKey() string {
+	return getConfig(youtubeAPIKey, "YTSUMMARY_YOUTUBE_API_KEY")
+}
+
+// fetchDataAPIVideo looks a video up with videos.list, which costs one unit
+// of the key's daily quota
+func fetchDataAPIVideo(client *http.Client, videoID, key string) (*dataAPIVideo, error) {
+	params := url.Values{
+		"part": {"snippet,contentDetails,statistics"},
+		"id":   {videoID},
+		"key":  {key},
+	}
+	resp, err := client.Get(youtubeDataAPIURL + "/videos?" + params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the YouTube Data API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, dataAPIError(resp)
+	}
+
+	var body dataAPIVideosResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse videos response: %w", err)
+	}
+	if len(body.Items) == 0 {
+		return nil, fmt.Errorf("the YouTube Data API has no video %s", videoID)
+	}
+
+	item := body.Items[0]
+	duration, _ := parseISODuration(item.ContentDetails.Duration)
+	publishDate, _, _ := strings.Cut(item.Snippet.PublishedAt, "T")
+	stats := &VideoStatistics{}
+	stats.Views, _ = strconv.ParseInt(item.Statistics.ViewCount, 10, 64)
+	stats.Likes, _ = strconv.ParseInt(item.Statistics.LikeCount, 10, 64)
+	stats.Comments, _ = strconv.ParseInt(item.Statistics.CommentCount, 10, 64)
+	return &dataAPIVideo{
+		Title:           item.Snippet.Title,
+		Channel:         item.Snippet.ChannelTitle,
+		ChannelID:       item.Snippet.ChannelID,
+		DurationSeconds: duration,
+		PublishDate:     publishDate,
+		Category:        youtubeCategories[item.Snippet.CategoryID],
+		Statistics:      stats,
+	}, nil
+}
+
+// youtubeCategories names the Data API's category IDs as the player response does
+var youtubeCategories = map[string]string{
+	"1": "Film & Animation", "2": "Autos & Vehicles", "10": musicCategory, "15": "Pets & Animals",
+	"17": "Sports", "19": "Travel & Events", "20": "Gaming", "22": "People & Blogs", "23": "Comedy",
+	"24": "Entertainment", "25": "News & Politics", "26": "Howto & Style", "27": "Education",
+	"28": "Science & Technology", "29": "Nonprofits & Activism",
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseISODuration parses the Data API's ISO 8601 durations, e.g. PT1H2M3S.
+// Live streams report P0D.
+func parseISODuration(s string) (int, error) {
+	m := isoDuration.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "PT" {
+		return 0, fmt.Errorf("invalid ISO 8601 duration %q", s)
+	}
+	seconds := 0
+	for i, unit := range []int{86400, 3600, 60, 1} {
+		n, _ := strconv.Atoi(m[i+1])
+		seconds += n * unit
+	}
+	return seconds, nil
+}
+
+// lookupDataAPIVideo fetches a video's Data API metadata when a key is
+// configured. Failures are logged and return nil: the fallback never fails
+// a request that innertube answered.
+func lookupDataAPIVideo(client *http.Client, videoID string) *dataAPIVideo {
+	key := dataAPIKey()
+	if key == "" {
+		return nil
+	}
+	video, err := fetchDataAPIVideo(client, videoID, key)
+	if err != nil {
+		logWarn("YouTube Data API lookup failed", slog.String("video_id", videoID), slog.String("error", err.Error()))
+		return nil
+	}
+	return video
+}
+
+// completeFetchResult fills in the title, channel and duration innertube
+// left empty, so they reach the cache and responses
+func completeFetchResult(client *http.Client, videoID string, result *FetchResult) {
+	if result.Title != "" && result.Channel != "" && result.DurationSeconds > 0 {
+		return
+	}
+	video := lookupDataAPIVideo(client, videoID)
+	if video == nil {
+		return
+	}
+	fillString(&result.Title, video.Title)
+	fillString(&result.Channel, video.Channel)
+	fillString(&result.Category, video.Category)
+	if result.DurationSeconds == 0 {
+		result.DurationSeconds = video.DurationSeconds
+	}
+}
+
+// completeVideoInfo fills in the metadata innertube left empty. Live streams
+// have no duration, so that alone doesn't count as missing.
+func completeVideoInfo(client *http.Client, info *VideoInfo) {
+	if info.Title != "" && info.Channel != "" && info.ChannelID != "" && (info.DurationSeconds > 0 || info.IsLive) {
+		return
+	}
+	mergeDataAPIVideo(info, lookupDataAPIVideo(client, info.VideoID))
+}
+
+// addVideoStatistics adds the Data API's view, like and comment counts to
+// info, which innertube doesn't report reliably
+func addVideoStatistics(client *http.Client, info *VideoInfo) {
+	if info.Statistics != nil {
+		return
+	}
+	mergeDataAPIVideo(info, lookupDataAPIVideo(client, info.VideoID))
+}
+
+// mergeDataAPIVideo copies video's fields into info's empty ones
+func mergeDataAPIVideo(info *VideoInfo, video *dataAPIVideo) {
+	if video == nil {
+		return
+	}
+	fillString(&info.Title, video.Title)
+	fillString(&info.Channel, video.Channel)
+	fillString(&info.ChannelID, video.ChannelID)
+	fillString(&info.PublishDate, video.PublishDate)
+	if info.DurationSeconds == 0 {
+		info.DurationSeconds = video.DurationSeconds
+	}
+	info.Statistics = video.Statistics
+}
+
+// fillString sets *dst to value when it's empty
+func fillString(dst *string, value string) {
+	if *dst == "" {
+		*dst = value
+	}
+}
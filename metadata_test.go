@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"PT3M33S", 213, true},
+		{"PT1H2M3S", 3723, true},
+		{"P1DT1S", 86401, true},
+		{"P0D", 0, true},
+		{"PT", 0, false},
+		{"3:33", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseISODuration(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parseISODuration(%q) = %d, %v", tt.in, got, err)
+		}
+	}
+}
+
+// fakeDataAPI serves videos.list for one video, counting the requests
+func fakeDataAPI(t *testing.T, status int) *int {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		q := r.URL.Query()
+		if r.URL.Path != "/videos" || q.Get("key") != "test-key" || q.Get("id") != "dQw4w9WgXcQ" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			fmt.Fprint(w, `{"error": {"message": "quota exceeded", "errors": [{"reason": "quotaExceeded"}]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"items": [{
+			"snippet": {"title": "Never Gonna Give You Up", "channelId": "UCuAXFkgsw1L7xaCfnd5JJOw", "channelTitle": "Rick Astley", "publishedAt": "2009-10-25T06:57:33Z"},
+			"contentDetails": {"duration": "PT3M33S"},
+			"statistics": {"viewCount": "1500000000", "likeCount": "17000000"}
+		}]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	oldURL, oldKey := youtubeDataAPIURL, youtubeAPIKey
+	youtubeDataAPIURL, youtubeAPIKey = srv.URL, "test-key"
+	t.Cleanup(func() { youtubeDataAPIURL, youtubeAPIKey = oldURL, oldKey })
+	return &calls
+}
+
+func TestCompleteVideoInfo(t *testing.T) {
+	calls := fakeDataAPI(t, http.StatusOK)
+
+	// Complete scraped metadata needs no lookup
+	info := &VideoInfo{VideoID: "dQw4w9WgXcQ", Title: "Scraped", Channel: "Scraped", ChannelID: "UC1", DurationSeconds: 10}
+	completeVideoInfo(youtubeClient(), info)
+	if *calls != 0 || info.Statistics != nil {
+		t.Fatalf("complete info looked up: %d calls", *calls)
+	}
+
+	info = &VideoInfo{VideoID: "dQw4w9WgXcQ", Title: "Scraped title"}
+	completeVideoInfo(youtubeClient(), info)
+	if info.Title != "Scraped title" {
+		t.Errorf("Title = %q, scraped value should win", info.Title)
+	}
+	if info.Channel != "Rick Astley" || info.ChannelID != "UCuAXFkgsw1L7xaCfnd5JJOw" || info.DurationSeconds != 213 || info.PublishDate != "2009-10-25" {
+		t.Errorf("info = %+v", info)
+	}
+	if s := info.Statistics; s == nil || s.Views != 1500000000 || s.Likes != 17000000 || s.Comments != 0 {
+		t.Errorf("Statistics = %+v", s)
+	}
+
+	// Statistics already fetched aren't asked for again
+	addVideoStatistics(youtubeClient(), info)
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
+
+func TestCompleteFetchResult(t *testing.T) {
+	fakeDataAPI(t, http.StatusOK)
+	result := &FetchResult{VideoID: "dQw4w9WgXcQ", Channel: "Scraped channel"}
+	completeFetchResult(youtubeClient(), "dQw4w9WgXcQ", result)
+	if result.Title != "Never Gonna Give You Up" || result.Channel != "Scraped channel" || result.DurationSeconds != 213 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestCompleteFetchResultAPIError(t *testing.T) {
+	fakeDataAPI(t, http.StatusForbidden)
+	result := &FetchResult{VideoID: "dQw4w9WgXcQ"}
+	completeFetchResult(youtubeClient(), "dQw4w9WgXcQ", result)
+	if result.Title != "" || result.DurationSeconds != 0 {
+		t.Errorf("failed lookup changed result: %+v", result)
+	}
+
+	// Without a key the API isn't called at all
+	youtubeAPIKey = ""
+	t.Setenv("YTSUMMARY_YOUTUBE_API_KEY", "")
+	if video := lookupDataAPIVideo(youtubeClient(), "dQw4w9WgXcQ"); video != nil {
+		t.Errorf("lookup without a key = %+v", video)
+	}
+}
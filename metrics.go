@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics emission for CLI runs, which are too short-lived to be scraped
+var (
+	statsdAddr     string
+	pushgatewayURL string
+)
+
+// runMetricsCommands are the commands that emit metrics when they finish
+var runMetricsCommands = map[string]bool{"summarize": true, "transcript": true, "summarize-text": true, "prefetch": true, "batch": true}
+
+// runMetrics counts what a single CLI run did
+type runMetrics struct {
+	mu               sync.Mutex
+	start            time.Time
+	processed        int
+	skipped          int
+	failures         map[string]int // by error class
+	promptTokens     int
+	completionTokens int
+	schemaProblems   int // player responses missing critical fields
+}
+
+var cliMetrics = newRunMetrics()
+
+func newRunMetrics() *runMetrics {
+	return &runMetrics{start: time.Now(), failures: make(map[string]int)}
+}
+
+func (m *runMetrics) recordProcessed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.processed++
+}
+
+func (m *runMetrics) recordSkipped() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.skipped++
+}
+
+func (m *runMetrics) recordFailure(class string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[class]++
+}
+
+func (m *runMetrics) recordTokens(prompt, completion int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.promptTokens += prompt
+	m.completionTokens += completion
+}
+
+func (m *runMetrics) recordSchemaProblem() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.schemaProblems++
+}
+
+// tokens returns the running token totals
+func (m *runMetrics) tokens() (prompt, completion int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.promptTokens, m.completionTokens
+}
+
+// sortedFailureClasses returns failure classes in a stable order for output
+func (m *runMetrics) sortedFailureClasses() []string {
+	classes := make([]string, 0, len(m.failures))
+	for class := range m.failures {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// statsdLines renders the run as StatsD counters and timers
+func (m *runMetrics) statsdLines(command string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix := "ytsummary." + command + "."
+	lines := []string{
+		fmt.Sprintf("%svideos_processed:%d|c", prefix, m.processed),
+		fmt.Sprintf("%svideos_skipped:%d|c", prefix, m.skipped),
+		fmt.Sprintf("%stokens.prompt:%d|c", prefix, m.promptTokens),
+		fmt.Sprintf("%stokens.completion:%d|c", prefix, m.completionTokens),
+		fmt.Sprintf("%syoutube_schema_problems:%d|c", prefix, m.schemaProblems),
+		fmt.Sprintf("%srun_duration:%d|ms", prefix, time.Since(m.start).Milliseconds()),
+	}
+	for _, class := range m.sortedFailureClasses() {
+		lines = append(lines, fmt.Sprintf("%sfailures.%s:%d|c", prefix, class, m.failures[class]))
+	}
+	return lines
+}
+
+// prometheusText renders the run in the Prometheus text exposition format for a Pushgateway
+func (m *runMetrics) prometheusText(runErr error) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	success := 1
+	if runErr != nil {
+		success = 0
+	}
+
+	var b strings.Builder
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("ytsummary_videos_processed", "gauge", "Videos processed successfully in the last run.")
+	fmt.Fprintf(&b, "ytsummary_videos_processed %d\n", m.processed)
+	metric("ytsummary_videos_skipped", "gauge", "Videos skipped (already cached) in the last run.")
+	fmt.Fprintf(&b, "ytsummary_videos_skipped %d\n", m.skipped)
+	metric("ytsummary_failures", "gauge", "Failed videos in the last run by error class.")
+	for _, class := range m.sortedFailureClasses() {
+		fmt.Fprintf(&b, "ytsummary_failures{class=%q} %d\n", class, m.failures[class])
+	}
+	metric("ytsummary_llm_tokens", "gauge", "LLM tokens used in the last run.")
+	fmt.Fprintf(&b, "ytsummary_llm_tokens{type=\"prompt\"} %d\n", m.promptTokens)
+	fmt.Fprintf(&b, "ytsummary_llm_tokens{type=\"completion\"} %d\n", m.completionTokens)
+	metric("ytsummary_youtube_schema_problems", "gauge", "YouTube player responses missing critical fields in the last run.")
+	fmt.Fprintf(&b, "ytsummary_youtube_schema_problems %d\n", m.schemaProblems)
+	metric("ytsummary_run_duration_seconds", "gauge", "Duration of the last run.")
+	fmt.Fprintf(&b, "ytsummary_run_duration_seconds %.3f\n", time.Since(m.start).Seconds())
+	metric("ytsummary_last_run_success", "gauge", "Whether the last run succeeded.")
+	fmt.Fprintf(&b, "ytsummary_last_run_success %d\n", success)
+	metric("ytsummary_last_run_timestamp_seconds", "gauge", "When the last run finished.")
+	fmt.Fprintf(&b, "ytsummary_last_run_timestamp_seconds %d\n", time.Now().Unix())
+
+	return b.String()
+}
+
+// emitRunMetrics sends the run's metrics to StatsD and/or a Pushgateway when
+// configured. Failures are reported but never fail the run.
+func emitRunMetrics(command string, runErr error) {
+	if addr := getConfig(statsdAddr, "YTSUMMARY_STATSD"); addr != "" {
+		if err := sendStatsD(addr, cliMetrics.statsdLines(command)); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to send StatsD metrics: %v\n", err)
+		}
+	}
+	if gateway := getConfig(pushgatewayURL, "YTSUMMARY_PUSHGATEWAY"); gateway != "" {
+		if err := pushMetrics(gateway, command, cliMetrics.prometheusText(runErr)); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to push metrics: %v\n", err)
+		}
+	}
+}
+
+// sendStatsD writes the lines to a StatsD server over UDP in a single packet
+func sendStatsD(addr string, lines []string) error {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(strings.Join(lines, "\n")))
+	return err
+}
+
+// pushMetrics replaces this command's group on the Pushgateway
+func pushMetrics(gateway, command, body string) error {
+	endpoint := strings.TrimSuffix(gateway, "/") + "/metrics/job/ytsummary/command/" + url.PathEscape(command)
+
+	req, err := http.NewRequest("PUT", endpoint, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("pushgateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
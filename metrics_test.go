@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRunMetrics() *runMetrics {
+	m := newRunMetrics()
+	m.recordProcessed()
+	m.recordProcessed()
+	m.recordSkipped()
+	m.recordFailure(ErrRateLimited)
+	m.recordFailure(ErrNoCaptions)
+	m.recordFailure(ErrRateLimited)
+	m.recordTokens(1200, 300)
+	return m
+}
+
+func TestRunMetricsStatsDLines(t *testing.T) {
+	lines := testRunMetrics().statsdLines("prefetch")
+
+	for _, want := range []string{
+		"ytsummary.prefetch.videos_processed:2|c",
+		"ytsummary.prefetch.videos_skipped:1|c",
+		"ytsummary.prefetch.tokens.prompt:1200|c",
+		"ytsummary.prefetch.tokens.completion:300|c",
+		"ytsummary.prefetch.failures.no_captions:1|c",
+		"ytsummary.prefetch.failures.rate_limited:2|c",
+	} {
+		found := false
+		for _, line := range lines {
+			if line == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing StatsD line %q in %v", want, lines)
+		}
+	}
+}
+
+func TestRunMetricsPrometheusText(t *testing.T) {
+	text := testRunMetrics().prometheusText(errors.New("2 of 5 videos failed"))
+
+	for _, want := range []string{
+		"ytsummary_videos_processed 2\n",
+		`ytsummary_failures{class="rate_limited"} 2` + "\n",
+		`ytsummary_llm_tokens{type="prompt"} 1200` + "\n",
+		"ytsummary_last_run_success 0\n",
+		"# TYPE ytsummary_videos_processed gauge\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("exposition missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestSendStatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	if err := sendStatsD(conn.LocalAddr().String(), []string{"a:1|c", "b:2|c"}); err != nil {
+		t.Fatalf("sendStatsD() error = %v", err)
+	}
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read packet: %v", err)
+	}
+	if got := string(buf[:n]); got != "a:1|c\nb:2|c" {
+		t.Errorf("packet = %q, want %q", got, "a:1|c\nb:2|c")
+	}
+}
+
+func TestPushMetrics(t *testing.T) {
+	var gotMethod, gotPath, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotBody = r.Method, r.URL.Path, string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	if err := pushMetrics(server.URL+"/", "summarize", "ytsummary_videos_processed 1\n"); err != nil {
+		t.Fatalf("pushMetrics() error = %v", err)
+	}
+	if gotMethod != "PUT" || gotPath != "/metrics/job/ytsummary/command/summarize" {
+		t.Errorf("request = %s %s, want PUT /metrics/job/ytsummary/command/summarize", gotMethod, gotPath)
+	}
+	if gotBody != "ytsummary_videos_processed 1\n" {
+		t.Errorf("body = %q", gotBody)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// modelInfo is one entry from a provider's model list
+type modelInfo struct {
+	ID            string
+	ContextLength int // 0 when the provider doesn't say
+}
+
+// listModels queries an OpenAI-compatible /models endpoint. OpenAI returns
+// only IDs; OpenRouter adds context_length, vLLM max_model_len and some
+// gateways context_window.
+func listModels(apiURL, apiKey string) ([]modelInfo, error) {
+	req, err := http.NewRequest("GET", apiURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	client := egressClient(30 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newLLMError("", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Data []struct {
+			ID            string `json:"id"`
+			ContextLength int    `json:"context_length"`
+			ContextWindow int    `json:"context_window"`
+			MaxModelLen   int    `json:"max_model_len"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("unexpected /models response: %w", err)
+	}
+
+	models := make([]modelInfo, 0, len(result.Data))
+	for _, m := range result.Data {
+		info := modelInfo{ID: m.ID, ContextLength: m.ContextLength}
+		if info.ContextLength == 0 {
+			info.ContextLength = max(m.ContextWindow, m.MaxModelLen)
+		}
+		models = append(models, info)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+func runModelsList(cmd *cobra.Command, args []string) error {
+	if getConfig(llmProvider, "YTSUMMARY_PROVIDER") == "fake" {
+		fmt.Println("fake")
+		return nil
+	}
+
+	apiURL := getConfig(llmBaseURL, "YTSUMMARY_API_URL")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	apiKey, err := llmAPIKeyConfig()
+	if err != nil {
+		return err
+	}
+	models, err := listModels(apiURL, apiKey)
+	if err != nil {
+		return err
+	}
+	if len(models) == 0 {
+		fmt.Printf("%s lists no models\n", apiURL)
+		return nil
+	}
+
+	for _, m := range models {
+		context := "-"
+		if m.ContextLength > 0 {
+			context = fmt.Sprintf("%d", m.ContextLength)
+		}
+		fmt.Printf("%-50s %10s\n", m.ID, context)
+	}
+	return nil
+}
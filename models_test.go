@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			t.Errorf("path = %s, want /models", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": {"message": "Invalid API key"}}`))
+			return
+		}
+		w.Write([]byte(`{"data": [
+			{"id": "openai/gpt-4o", "context_length": 128000},
+			{"id": "local/llama", "max_model_len": 8192},
+			{"id": "gpt-3.5-turbo"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	models, err := listModels(srv.URL, "good")
+	if err != nil {
+		t.Fatalf("listModels() error = %v", err)
+	}
+	want := []modelInfo{{"gpt-3.5-turbo", 0}, {"local/llama", 8192}, {"openai/gpt-4o", 128000}}
+	if len(models) != len(want) {
+		t.Fatalf("listModels() = %+v, want %+v", models, want)
+	}
+	for i := range want {
+		if models[i] != want[i] {
+			t.Errorf("models[%d] = %+v, want %+v", i, models[i], want[i])
+		}
+	}
+
+	_, err = listModels(srv.URL, "bad")
+	var llmErr *llmError
+	if !errors.As(err, &llmErr) || llmErr.Code != ErrLLMAuth {
+		t.Errorf("listModels() error = %v, want %s", err, ErrLLMAuth)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// musicVideos is --music-videos
+var musicVideos string
+
+// What to do with music videos (--music-videos / YTSUMMARY_MUSIC_VIDEOS /
+// "music_videos"): summaries of lyrics are useless
+const (
+	musicVideosDescribe  = "describe"  // use the music template: the song and its themes (default)
+	musicVideosSkip      = "skip"      // don't summarize, answer with a music_video result
+	musicVideosSummarize = "summarize" // summarize like any other video
+)
+
+// ErrMusicVideo is the result for music videos skipped with music_videos=skip
+const ErrMusicVideo = "music_video"
+
+// musicCategory is YouTube's category for music; its Data API ID is 10
+const musicCategory = "Music"
+
+// errMusicVideo is returned instead of summarizing a music video
+var errMusicVideo = errors.New("skipped: this looks like a music video, and lyrics don't make a useful summary")
+
+// validMusicVideos checks a --music-videos / music_videos value
+func validMusicVideos(mode string) error {
+	switch mode {
+	case "", musicVideosDescribe, musicVideosSkip, musicVideosSummarize:
+		return nil
+	}
+	return fmt.Errorf("invalid music videos mode %q (use describe, skip or summarize)", mode)
+}
+
+// musicVideosMode returns the CLI and server default (--music-videos / YTSUMMARY_MUSIC_VIDEOS)
+func musicVideosMode() string {
+	if mode := getConfig(musicVideos, "YTSUMMARY_MUSIC_VIDEOS"); mode != "" {
+		return mode
+	}
+	return musicVideosDescribe
+}
+
+// pickTemplate returns the prompt template for a video: the one chosen (by
+// --template, a channel rule or the request), else the one suited to its
+// format, "" being the default. Music videos follow the music videos mode;
+// with skip, errMusicVideo is returned whatever the template.
+func pickTemplate(chosen string, entry *CacheEntry, music string) (string, error) {
+	if videoFormat(entry) == formatMusic {
+		switch music {
+		case musicVideosSkip:
+			return "", errMusicVideo
+		case musicVideosSummarize:
+			return chosen, nil
+		}
+	}
+	return formatTemplate(chosen, entry), nil
+}
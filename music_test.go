@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPickTemplateMusicVideos(t *testing.T) {
+	song := &CacheEntry{Format: formatMusic}
+	tests := []struct {
+		mode, chosen, want string
+		err                error
+	}{
+		{musicVideosDescribe, "", "music", nil},
+		{musicVideosDescribe, "key-points", "key-points", nil},
+		{musicVideosSummarize, "", "", nil},
+		{musicVideosSkip, "", "", errMusicVideo},
+		{musicVideosSkip, "key-points", "", errMusicVideo},
+	}
+	for _, tt := range tests {
+		got, err := pickTemplate(tt.chosen, song, tt.mode)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("pickTemplate(%q, music, %s) = %q, %v, want %q, %v", tt.chosen, tt.mode, got, err, tt.want, tt.err)
+		}
+	}
+	// Only music videos are skipped
+	if got, err := pickTemplate("", &CacheEntry{Format: formatInterview}, musicVideosSkip); got != "conversation" || err != nil {
+		t.Errorf("interview with skip = %q, %v", got, err)
+	}
+
+	if err := validMusicVideos("ignore"); err == nil {
+		t.Error("validMusicVideos accepted an unknown mode")
+	}
+}
+
+func TestClassifyFormatMusicCategory(t *testing.T) {
+	verse := `Walking down the empty road at night, the city sleeps and so do I, counting every
+		streetlight on the way back home, thinking of the summer when we were young and wild and free,
+		dancing by the river till the morning came to take it all away from you and me.`
+	lesson := `Today we'll learn the G chord. First we place the third finger on the low E string, then
+		make sure the second finger sits on the A string. Now we can strum slowly and check every string
+		rings out. Next we switch to C, so select your metronome speed and click start.`
+	tests := []struct {
+		name  string
+		entry CacheEntry
+		want  string
+	}{
+		{"sung slowly", CacheEntry{Category: musicCategory, Transcript: verse, DurationSeconds: 60}, formatMusic},
+		{"instrumental", CacheEntry{Category: musicCategory, Transcript: "[Music] [Applause]", DurationSeconds: 240}, formatMusic},
+		{"spoken pace outside Music", CacheEntry{Category: "People & Blogs", Transcript: verse, DurationSeconds: 60}, formatMonologue},
+		{"guitar lesson", CacheEntry{Category: musicCategory, Transcript: lesson, DurationSeconds: 20}, formatTutorial},
+	}
+	for _, tt := range tests {
+		if got := classifyFormat(&tt.entry).Format; got != tt.want {
+			t.Errorf("%s: format = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeSkipsMusicVideos(t *testing.T) {
+	cache := newTestCache(t)
+	var templates []string
+	handler := newServer(ServerConfig{
+		Cache: cache,
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Never Gonna Give You Up (Official Music Video)", Category: musicCategory, Transcript: "We're no strangers to love", Language: "en"}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			templates = append(templates, opts.Template)
+			return "A song about commitment.", nil
+		},
+	}).Handler()
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/summarize", strings.NewReader(body))
+		req.RemoteAddr = "198.51.100.220:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"url": "https://youtu.be/dQw4w9WgXcQ", "music_videos": "skip"}`)
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if w.Code != http.StatusUnprocessableEntity || errResp.Error != ErrMusicVideo || len(templates) != 0 {
+		t.Fatalf("skip: status = %d, error = %+v, %d summaries", w.Code, errResp, len(templates))
+	}
+
+	// By default the song is described
+	w = post(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`)
+	var resp TranscriptResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || resp.Format != formatMusic || resp.Template != "music" || templates[0] != "music" {
+		t.Errorf("describe: status = %d, format %q, template %q", w.Code, resp.Format, resp.Template)
+	}
+
+	if w := post(`{"url": "https://youtu.be/dQw4w9WgXcQ", "music_videos": "ignore"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid mode: status = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// prefersPlainText reports whether an Accept header ranks text/plain above
+// JSON. Ties go to JSON, so "*/*" and a missing header keep the default.
+func prefersPlainText(accept string) bool {
+	textQ, jsonQ := 0.0, 0.0
+	textSpecific, jsonSpecific := 0, 0 // how specific the range that set each q was
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		// The most specific range matching a type decides its q
+		set := func(dst *float64, specific *int, level int) {
+			if level > *specific {
+				*dst, *specific = q, level
+			}
+		}
+		switch mediaRange {
+		case "text/plain":
+			set(&textQ, &textSpecific, 3)
+		case "text/*":
+			set(&textQ, &textSpecific, 2)
+		case "application/json":
+			set(&jsonQ, &jsonSpecific, 3)
+		case "application/*":
+			set(&jsonQ, &jsonSpecific, 2)
+		case "*/*":
+			set(&textQ, &textSpecific, 1)
+			set(&jsonQ, &jsonSpecific, 1)
+		}
+	}
+	return textQ > 0 && textQ > jsonQ
+}
+
+// acceptedLanguage returns the language an Accept-Language header ranks
+// highest, or "" if it names none. Regions are dropped ("en-US" → "en") since
+// browsers send one with nearly every header, and each would otherwise be
+// cached separately.
+func acceptedLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.EqualFold(strings.TrimSpace(name), "q") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = v
+		}
+		lang := baseLanguage(strings.TrimSpace(tag))
+		if !validLanguageSubtag(lang) {
+			continue // "*" and malformed tags
+		}
+		// The first of equally ranked languages wins
+		if q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// validLanguageSubtag reports whether s looks like a primary language subtag
+func validLanguageSubtag(s string) bool {
+	if len(s) < 2 || len(s) > 3 {
+		return false
+	}
+	for _, c := range s {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// plainTextBody is the text of a /transcript or /summarize response without
+// the JSON envelope: the summary, else the transcript, else one line per
+// segment or word
+func plainTextBody(resp TranscriptResponse) string {
+	switch {
+	case resp.Summary != "":
+		return resp.Summary
+	case resp.Chapters != "":
+		return resp.Chapters
+	case resp.Transcript != "":
+		return resp.Transcript
+	}
+	var b strings.Builder
+	for _, seg := range resp.Segments {
+		b.WriteString("[" + formatTimestamp(seg.Start) + "] " + seg.Text + "\n")
+	}
+	for _, w := range resp.Words {
+		b.WriteString("[" + formatTimestamp(w.Start) + "] " + w.Word + "\n")
+	}
+	if len(resp.Highlights) > 0 {
+		b.WriteString(formatHighlights(resp.Highlights))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// varyHeaders are the request headers a /transcript or /summarize response
+// can depend on besides its URL and API key
+const varyHeaders = "Accept, Accept-Language"
+
+// writeTranscriptBody writes resp as JSON, or as plain text when the request's
+// Accept header prefers it. Plain text keeps the video, summary ID and any
+// summary error in headers.
+func writeTranscriptBody(w http.ResponseWriter, r *http.Request, resp TranscriptResponse) {
+	w.Header().Add("Vary", varyHeaders)
+	if !prefersPlainText(r.Header.Get("Accept")) {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("X-Video-ID", resp.VideoID)
+	if resp.SummaryID != 0 {
+		h.Set("X-Summary-ID", strconv.FormatInt(resp.SummaryID, 10))
+	}
+	if resp.SummaryError != "" {
+		h.Set("X-Summary-Error", strings.ReplaceAll(resp.SummaryError, "\n", " "))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(plainTextBody(resp) + "\n"))
+}
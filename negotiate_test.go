@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrefersPlainText(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"text/plain", true},
+		{"TEXT/PLAIN; charset=utf-8", true},
+		{"text/*", true},
+		{"text/plain, application/json", false},
+		{"text/plain, application/json;q=0.5", true},
+		{"application/json;q=0.9, text/plain", true},
+		{"text/plain;q=0, */*", false},
+		{"text/*;q=0.1, text/plain;q=0.8, */*;q=0.5", true},
+		{"text/plain;q=0.2, */*;q=0.5", false},
+		{"text/html", false},
+	}
+	for _, tt := range tests {
+		if got := prefersPlainText(tt.accept); got != tt.want {
+			t.Errorf("prefersPlainText(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptedLanguage(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", ""},
+		{"*", ""},
+		{"de", "de"},
+		{"en-US,en;q=0.9", "en"},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", "fr"},
+		{"en;q=0.5, ja;q=0.8", "ja"},
+		{"es, pt", "es"},
+		{"PT-br", "pt"},
+		{"de;q=0, it;q=0.1", "it"},
+		{"zh-Hant-TW;q=0.9, *", "zh"},
+		{"not a language, english, nl;q=bad, sv", "sv"},
+	}
+	for _, tt := range tests {
+		if got := acceptedLanguage(tt.header); got != tt.want {
+			t.Errorf("acceptedLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestPlainTextBody(t *testing.T) {
+	resp := TranscriptResponse{Segments: []TranscriptSegment{{Start: 0, Text: "Hello"}, {Start: 65, Text: "world"}}}
+	if got, want := plainTextBody(resp), "[0:00] Hello\n[1:05] world"; got != want {
+		t.Errorf("segments = %q, want %q", got, want)
+	}
+	resp.Transcript = "Hello world"
+	if got := plainTextBody(resp); got != "Hello world" {
+		t.Errorf("transcript = %q", got)
+	}
+	resp.Summary = "A greeting."
+	if got := plainTextBody(resp); got != "A greeting." {
+		t.Errorf("summary = %q", got)
+	}
+}
+
+func TestPlainTextResponses(t *testing.T) {
+	cache := newTestCache(t)
+	s := newServer(ServerConfig{
+		Cache:     cache,
+		Summaries: cache,
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Injected", Transcript: "One. Two. Three."}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			return "A deterministic summary.", nil
+		},
+	})
+	handler := s.Handler()
+
+	get := func(path, accept, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", accept)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/transcript?url=https://youtu.be/dQw4w9WgXcQ", "text/plain", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("transcript: status = %d: %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := w.Body.String(); got != "One. Two. Three.\n" {
+		t.Errorf("transcript body = %q", got)
+	}
+	if got := w.Header().Get("X-Video-ID"); got != "dQw4w9WgXcQ" {
+		t.Errorf("X-Video-ID = %q", got)
+	}
+	if got := w.Header().Get("Vary"); got != varyHeaders {
+		t.Errorf("Vary = %q, want %q", got, varyHeaders)
+	}
+
+	w = get("/summarize?url=https://youtu.be/dQw4w9WgXcQ", "text/plain", "")
+	if w.Code != http.StatusOK || w.Body.String() != "A deterministic summary.\n" {
+		t.Fatalf("summarize: status = %d, body = %q", w.Code, w.Body)
+	}
+	textETag := w.Header().Get("ETag")
+
+	// The JSON and text forms of one response must not share a tag
+	w = get("/summarize?url=https://youtu.be/dQw4w9WgXcQ", "application/json", "")
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("JSON Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+	if textETag == "" || w.Header().Get("ETag") == textETag {
+		t.Errorf("text ETag %q, JSON ETag %q; want distinct tags", textETag, w.Header().Get("ETag"))
+	}
+	if w := get("/summarize?url=https://youtu.be/dQw4w9WgXcQ", "application/json", textETag); w.Code != http.StatusOK {
+		t.Errorf("JSON with text ETag: status = %d, want 200", w.Code)
+	}
+	if w := get("/summarize?url=https://youtu.be/dQw4w9WgXcQ", "text/plain", textETag); w.Code != http.StatusNotModified {
+		t.Errorf("text with text ETag: status = %d, want 304", w.Code)
+	}
+
+	// Errors stay JSON so scripts can still tell what went wrong
+	req := httptest.NewRequest("GET", "/transcript?url=https://example.com/nope", nil)
+	req.Header.Set("Accept", "text/plain")
+	req.RemoteAddr = "192.0.2.2:1234"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("error: status = %d, Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxNormalizeBodySize fits a long chat export or email thread
+const maxNormalizeBodySize = 1024 * 1024
+
+// youtubeLinkRe finds YouTube links, and Google redirects that may wrap one,
+// in free text. Brackets and quotes end a match so Markdown and HTML links
+// come out clean; trailing punctuation is trimmed afterwards.
+var youtubeLinkRe = regexp.MustCompile(`(?i)\b(?:https?://)?(?:[a-z0-9-]+\.)*(?:youtube(?:-nocookie)?\.com|youtu\.be|google\.[a-z.]+/url\?)[^\s<>"'()\[\]{}]*`)
+
+// linkTrailers are characters a sentence or markup can leave on the end of a link
+const linkTrailers = ".,;:!?*"
+
+// NormalizeRequest is text to find YouTube videos in
+type NormalizeRequest struct {
+	Text string `json:"text"`
+
+	// BareIDs also picks up 11-character video IDs standing on their own.
+	// Off by default: plain words can look like IDs.
+	BareIDs bool `json:"bare_ids,omitempty"`
+}
+
+// NormalizedVideo is one video found in the text
+type NormalizedVideo struct {
+	VideoID     string `json:"video_id"`
+	URL         string `json:"url"`
+	Occurrences int    `json:"occurrences"`
+
+	// StartSeconds is the first t= (or start=) timestamp a link to the video carried
+	StartSeconds float64 `json:"start_seconds,omitempty"`
+}
+
+// NormalizeResponse lists the videos found, once each, in order of first mention
+type NormalizeResponse struct {
+	Count  int               `json:"count"`
+	Videos []NormalizedVideo `json:"videos"`
+
+	// Ignored are YouTube links that aren't to a video, such as channels
+	Ignored []string `json:"ignored,omitempty"`
+}
+
+// normalizeVideoLinks extracts the YouTube videos linked in text
+func normalizeVideoLinks(text string, bareIDs bool) NormalizeResponse {
+	resp := NormalizeResponse{Videos: []NormalizedVideo{}}
+	index := make(map[string]int) // video ID -> position in resp.Videos
+	ignored := make(map[string]bool)
+
+	add := func(videoID, link string) {
+		i, ok := index[videoID]
+		if !ok {
+			i = len(resp.Videos)
+			index[videoID] = i
+			resp.Videos = append(resp.Videos, NormalizedVideo{VideoID: videoID, URL: canonicalVideoURL(videoID)})
+		}
+		v := &resp.Videos[i]
+		v.Occurrences++
+		if v.StartSeconds == 0 && link != "" {
+			if start, ok := linkedTimestamp(link); ok {
+				v.StartSeconds = start
+			}
+		}
+	}
+
+	// Links can't contain whitespace, so each field holds any number of
+	// links or one bare ID
+	for _, field := range strings.Fields(text) {
+		links := youtubeLinkRe.FindAllString(field, -1)
+		for _, link := range links {
+			link = html.UnescapeString(link) // &amp; in email and HTML bodies
+			if videoID, trimmed, ok := linkVideoID(link); ok {
+				add(videoID, trimmed)
+			} else if link = strings.TrimRight(link, linkTrailers); !ignored[link] && youtubeHostLink(link) {
+				ignored[link] = true
+				resp.Ignored = append(resp.Ignored, link)
+			}
+		}
+		if len(links) == 0 && bareIDs {
+			if id := strings.TrimFunc(field, isIDDelimiter); looksLikeVideoID(id) {
+				add(id, "")
+			}
+		}
+	}
+
+	resp.Count = len(resp.Videos)
+	return resp
+}
+
+// linkVideoID extracts the video ID from a link found in text, trimming
+// trailing punctuation while the link still parses to the same video, and
+// returns the link as trimmed. IDs that end in - or _ are kept whole.
+func linkVideoID(link string) (videoID, trimmed string, ok bool) {
+	for link != "" {
+		id, err := extractVideoID(link)
+		if err == nil && videoID != "" && id != videoID {
+			break // trimming changed the video: keep the last good link
+		}
+		if err == nil {
+			videoID, trimmed = id, link
+		} else if videoID != "" {
+			break
+		}
+		if !strings.ContainsRune(linkTrailers, rune(link[len(link)-1])) {
+			break
+		}
+		link = link[:len(link)-1]
+	}
+	return videoID, trimmed, videoID != ""
+}
+
+// youtubeHostLink reports whether link is to a page on a YouTube host, as
+// opposed to a bare mention of youtube.com or a redirect to somewhere else
+func youtubeHostLink(link string) bool {
+	u, err := parseLooseURL(link)
+	return err == nil && youtubeHost(u.Hostname()) != "" && strings.Trim(u.Path, "/") != ""
+}
+
+// isIDDelimiter reports whether r can't be part of a video ID
+func isIDDelimiter(r rune) bool {
+	return !(r == '-' || r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+}
+
+// looksLikeVideoID reports whether a bare word is plausibly a video ID: the
+// right shape, and not an ordinary word such as "programming". IDs nearly
+// always have a digit, - or _, or a capital past the first letter.
+func looksLikeVideoID(s string) bool {
+	if !videoIDRe.MatchString(s) {
+		return false
+	}
+	return strings.ContainsAny(s, "0123456789-_") || strings.IndexFunc(s[1:], unicode.IsUpper) >= 0
+}
+
+// handleNormalize finds the YouTube videos in pasted text, such as a chat
+// log or email thread, and returns each once as a canonical URL ready for
+// /summarize or /prefetch. It never fetches anything.
+func (s *Server) handleNormalize(w http.ResponseWriter, r *http.Request) {
+	var req NormalizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "text is required")
+		return
+	}
+	writeJSON(w, http.StatusOK, normalizeVideoLinks(req.Text, req.BareIDs))
+}
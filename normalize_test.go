@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeVideoLinks(t *testing.T) {
+	text := `[09:14] alice: have you seen https://youtu.be/dQw4w9WgXcQ?t=43?
+[09:15] bob: yes! also (https://www.youtube.com/watch?v=jNQXAC9IVRw).
+[09:15] bob: and [the short](https://youtube.com/shorts/9bZkp7q19f0), plus
+  <a href="https://www.youtube.com/watch?feature=share&amp;v=jNQXAC9IVRw">this</a>
+[09:16] alice: m.youtube.com/watch?v=dQw4w9WgXcQ, and her channel youtube.com/@someone
+[09:16] bob: https://www.google.com/url?q=https%3A%2F%2Fyoutu.be%2FkJQP7kiw5Fk&sa=D
+[09:17] alice: not a video: notyoutube.com/watch?v=aaaaaaaaaaa or youtube.com or dQw4w9WgXc_`
+
+	resp := normalizeVideoLinks(text, false)
+	want := []NormalizedVideo{
+		{VideoID: "dQw4w9WgXcQ", URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Occurrences: 2, StartSeconds: 43},
+		{VideoID: "jNQXAC9IVRw", URL: "https://www.youtube.com/watch?v=jNQXAC9IVRw", Occurrences: 2},
+		{VideoID: "9bZkp7q19f0", URL: "https://www.youtube.com/watch?v=9bZkp7q19f0", Occurrences: 1},
+		{VideoID: "kJQP7kiw5Fk", URL: "https://www.youtube.com/watch?v=kJQP7kiw5Fk", Occurrences: 1},
+	}
+	if resp.Count != len(want) || len(resp.Videos) != len(want) {
+		t.Fatalf("videos = %+v", resp.Videos)
+	}
+	for i, v := range want {
+		if resp.Videos[i] != v {
+			t.Errorf("video %d = %+v, want %+v", i, resp.Videos[i], v)
+		}
+	}
+	if len(resp.Ignored) != 1 || resp.Ignored[0] != "youtube.com/@someone" {
+		t.Errorf("ignored = %q", resp.Ignored)
+	}
+
+	// An ID ending in a trailing punctuation character is kept whole
+	if resp := normalizeVideoLinks("see youtu.be/abcdefghij_.", false); len(resp.Videos) != 1 || resp.Videos[0].VideoID != "abcdefghij_" {
+		t.Errorf("trailing _: %+v", resp.Videos)
+	}
+}
+
+func TestNormalizeBareIDs(t *testing.T) {
+	text := "ids: dQw4w9WgXcQ, (jNQXAC9IVRw) and https://youtu.be/dQw4w9WgXcQ; programming Programming"
+	if resp := normalizeVideoLinks(text, false); resp.Count != 1 {
+		t.Errorf("without bare_ids: %+v", resp.Videos)
+	}
+	resp := normalizeVideoLinks(text, true)
+	if resp.Count != 2 || resp.Videos[0].VideoID != "dQw4w9WgXcQ" || resp.Videos[0].Occurrences != 2 || resp.Videos[1].VideoID != "jNQXAC9IVRw" {
+		t.Errorf("with bare_ids: %+v", resp.Videos)
+	}
+}
+
+func TestNormalizeEndpoint(t *testing.T) {
+	handler := newServer(ServerConfig{Cache: newTestCache(t)}).Handler()
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/normalize", strings.NewReader(body))
+		req.RemoteAddr = "198.51.100.210:1234"
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"text": "watch https://youtu.be/dQw4w9WgXcQ and https://www.youtube.com/watch?v=dQw4w9WgXcQ"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp NormalizeResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != 1 || resp.Videos[0].Occurrences != 2 {
+		t.Errorf("response = %+v", resp)
+	}
+
+	if w := post(`{"text": "no videos here"}`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"videos":[]`) {
+		t.Errorf("no videos: %d %s", w.Code, w.Body)
+	}
+	if w := post(`{"text": "  "}`); w.Code != http.StatusBadRequest {
+		t.Errorf("empty text: status = %d, want 400", w.Code)
+	}
+
+	// A long thread fits the route's body limit
+	thread, _ := json.Marshal(NormalizeRequest{Text: strings.Repeat("lorem ipsum https://youtu.be/dQw4w9WgXcQ\n", 10000)})
+	if w := post(string(thread)); w.Code != http.StatusOK {
+		t.Errorf("%d byte thread: status = %d", len(thread), w.Code)
+	}
+}
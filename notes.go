@@ -0,0 +1,276 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Flag for summarize --with-notes
+var withNotes bool
+
+// notesPrompt gives the LLM a viewer's notes as context for the summary
+const notesPrompt = `The viewer keeps these notes on the video. Use them as context, for example to know what they care about or what they have already corrected, but summarize what the video says and don't present the notes as part of it:
+
+%s`
+
+// maxNotesBodySize caps PATCH /videos/{id}/notes bodies: notes run to
+// paragraphs, well past the default limit
+const maxNotesBodySize = 64 * 1024
+
+// ErrCacheReadOnly answers writes to a cache opened with --cache-readonly
+const ErrCacheReadOnly = "cache_read_only"
+
+// VideoNotes is the freeform text a user keeps on a video
+type VideoNotes struct {
+	VideoID   string    `json:"video_id"`
+	Notes     string    `json:"notes"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotesStore keeps users' notes on videos. SQLiteCache implements it.
+type NotesStore interface {
+	// GetNotes returns a video's notes, or errCacheMiss if it has none
+	GetNotes(videoID string) (*VideoNotes, error)
+	// SetNotes replaces a video's notes; empty notes delete them
+	SetNotes(videoID, notes string) error
+	// ListNotes returns the notes of those of videoIDs that have any
+	ListNotes(videoIDs []string) (map[string]string, error)
+}
+
+func (c *SQLiteCache) GetNotes(videoID string) (*VideoNotes, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	n := VideoNotes{VideoID: videoID}
+	err = db.QueryRow("SELECT notes, updated_at FROM video_notes WHERE video_id = ?", videoID).Scan(&n.Notes, &n.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	return &n, nil
+}
+
+func (c *SQLiteCache) SetNotes(videoID, notes string) error {
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	if notes == "" {
+		_, err = db.Exec("DELETE FROM video_notes WHERE video_id = ?", videoID)
+	} else {
+		_, err = db.Exec(`
+			INSERT INTO video_notes (video_id, notes) VALUES (?, ?)
+			ON CONFLICT(video_id) DO UPDATE SET notes = excluded.notes, updated_at = CURRENT_TIMESTAMP
+		`, videoID, notes)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save notes: %w", err)
+	}
+	return nil
+}
+
+func (c *SQLiteCache) ListNotes(videoIDs []string) (map[string]string, error) {
+	notes := make(map[string]string)
+	if len(videoIDs) == 0 {
+		return notes, nil
+	}
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]any, len(videoIDs))
+	for i, id := range videoIDs {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(videoIDs)), ",")
+	rows, err := db.Query("SELECT video_id, notes FROM video_notes WHERE video_id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, text string
+		if err := rows.Scan(&id, &text); err != nil {
+			return nil, fmt.Errorf("failed to list notes: %w", err)
+		}
+		notes[id] = text
+	}
+	return notes, rows.Err()
+}
+
+// appendNote adds a note to a video's notes as a new paragraph
+func appendNote(notes, note string) string {
+	if notes == "" {
+		return note
+	}
+	return notes + "\n\n" + note
+}
+
+// videoNotesText returns a video's notes, or "" when it has none
+func videoNotesText(store NotesStore, videoID string) (string, error) {
+	n, err := store.GetNotes(videoID)
+	if errors.Is(err, errCacheMiss) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return n.Notes, nil
+}
+
+// VideoNotesRequest is the body of PATCH /videos/{id}/notes: Notes replaces
+// the video's notes ("" deletes them) and Add appends a paragraph to them
+type VideoNotesRequest struct {
+	Notes *string `json:"notes,omitempty"`
+	Add   string  `json:"add,omitempty"`
+}
+
+// handleGetNotes returns a video's notes
+func (s *Server) handleGetNotes(w http.ResponseWriter, r *http.Request) {
+	videoID, err := extractVideoID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid video ID")
+		return
+	}
+
+	notes, err := s.notes.GetNotes(videoID)
+	switch {
+	case errors.Is(err, errCacheMiss):
+		writeErrorWithVideo(w, http.StatusNotFound, "not_found", "No notes on this video", videoID)
+	case err != nil:
+		writeErrorWithVideo(w, http.StatusInternalServerError, ErrInternal, "Failed to read notes", videoID)
+	default:
+		writeJSON(w, http.StatusOK, notes)
+	}
+}
+
+// handlePatchNotes replaces or adds to a video's notes and returns them
+func (s *Server) handlePatchNotes(w http.ResponseWriter, r *http.Request) {
+	videoID, err := extractVideoID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid video ID")
+		return
+	}
+	var req VideoNotesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorWithVideo(w, http.StatusBadRequest, ErrInvalidRequest, "invalid JSON: "+err.Error(), videoID)
+		return
+	}
+	add := strings.TrimSpace(req.Add)
+	if (req.Notes == nil) == (add == "") {
+		writeErrorWithVideo(w, http.StatusBadRequest, ErrInvalidRequest, "set either notes or add", videoID)
+		return
+	}
+
+	var notes string
+	if req.Notes != nil {
+		notes = strings.TrimSpace(*req.Notes)
+	} else {
+		current, err := videoNotesText(s.notes, videoID)
+		if err != nil {
+			writeErrorWithVideo(w, http.StatusInternalServerError, ErrInternal, "Failed to read notes", videoID)
+			return
+		}
+		notes = appendNote(current, add)
+	}
+
+	switch err := s.notes.SetNotes(videoID, notes); {
+	case errors.Is(err, errCacheReadOnly):
+		writeErrorWithVideo(w, http.StatusConflict, ErrCacheReadOnly, err.Error(), videoID)
+		return
+	case err != nil:
+		writeErrorWithVideo(w, http.StatusInternalServerError, ErrInternal, err.Error(), videoID)
+		return
+	}
+	if notes == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.handleGetNotes(w, r)
+}
+
+// runNoteAdd appends a note to a video's notes
+func runNoteAdd(cmd *cobra.Command, args []string) error {
+	videoID, err := extractVideoID(args[0])
+	if err != nil {
+		return err
+	}
+	note := strings.TrimSpace(args[1])
+	if note == "" {
+		return fmt.Errorf("the note is empty")
+	}
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	notes, err := videoNotesText(cache, videoID)
+	if err != nil {
+		return err
+	}
+	if err := cache.SetNotes(videoID, appendNote(notes, note)); err != nil {
+		return err
+	}
+	log("Note added to %s", videoID)
+	return nil
+}
+
+// runNoteShow prints a video's notes
+func runNoteShow(cmd *cobra.Command, args []string) error {
+	videoID, err := extractVideoID(args[0])
+	if err != nil {
+		return err
+	}
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	notes, err := videoNotesText(cache, videoID)
+	if err != nil {
+		return err
+	}
+	if notes == "" {
+		fmt.Printf("No notes on %s\n", videoID)
+		return nil
+	}
+	fmt.Println(notes)
+	return nil
+}
+
+// runNoteClear deletes a video's notes
+func runNoteClear(cmd *cobra.Command, args []string) error {
+	videoID, err := extractVideoID(args[0])
+	if err != nil {
+		return err
+	}
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	if err := cache.SetNotes(videoID, ""); err != nil {
+		return err
+	}
+	log("Notes on %s cleared", videoID)
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteNotes(t *testing.T) {
+	cache := newTestCache(t)
+
+	if _, err := cache.GetNotes("dQw4w9WgXcQ"); !errors.Is(err, errCacheMiss) {
+		t.Fatalf("GetNotes before any = %v, want errCacheMiss", err)
+	}
+	if err := cache.SetNotes("dQw4w9WgXcQ", "Watched for the chorus"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SetNotes("dQw4w9WgXcQ", appendNote("Watched for the chorus", "The dance is at 0:43")); err != nil {
+		t.Fatal(err)
+	}
+	n, err := cache.GetNotes("dQw4w9WgXcQ")
+	if err != nil || n.Notes != "Watched for the chorus\n\nThe dance is at 0:43" || n.UpdatedAt.IsZero() {
+		t.Fatalf("GetNotes = %+v, %v", n, err)
+	}
+
+	all, err := cache.ListNotes([]string{"dQw4w9WgXcQ", "jNQXAC9IVRw"})
+	if err != nil || len(all) != 1 || all["dQw4w9WgXcQ"] != n.Notes {
+		t.Errorf("ListNotes = %v, %v", all, err)
+	}
+
+	if err := cache.SetNotes("dQw4w9WgXcQ", ""); err != nil {
+		t.Fatal(err)
+	}
+	if notes, err := videoNotesText(cache, "dQw4w9WgXcQ"); notes != "" || err != nil {
+		t.Errorf("notes after clearing = %q, %v", notes, err)
+	}
+}
+
+func TestNotesEndpoints(t *testing.T) {
+	cache := newTestCache(t)
+	cacheTranscript(cache, "dQw4w9WgXcQ", "en", "Rick Astley", "never gonna give you up")
+	var prompt string
+	handler := newServer(ServerConfig{
+		APIKey: "secret",
+		Cache:  cache,
+		Notes:  cache,
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			prompt, _, _ = summaryPrompts(opts)
+			return "A summary.", nil
+		},
+	}).Handler()
+
+	calls := 0
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		// A client address per call keeps clear of the rate limit
+		calls++
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:1234", calls)
+		req.Header.Set("X-API-Key", "secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("GET", "/v1/videos/dQw4w9WgXcQ/notes", ""); w.Code != 404 {
+		t.Errorf("GET before notes: status = %d, want 404", w.Code)
+	}
+	do("PATCH", "/v1/videos/dQw4w9WgXcQ/notes", `{"notes": "Shared in the team chat"}`)
+	w := do("PATCH", "/v1/videos/dQw4w9WgXcQ/notes", `{"add": "Check the remaster"}`)
+	var notes VideoNotes
+	json.NewDecoder(w.Body).Decode(&notes)
+	if w.Code != 200 || notes.Notes != "Shared in the team chat\n\nCheck the remaster" {
+		t.Fatalf("PATCH add: status %d, notes %q", w.Code, notes.Notes)
+	}
+	if w := do("PATCH", "/v1/videos/dQw4w9WgXcQ/notes", `{}`); w.Code != 400 {
+		t.Errorf("PATCH without notes or add: status = %d, want 400", w.Code)
+	}
+
+	// Exports carry the notes
+	w = do("GET", "/v1/export", "")
+	var record ExportRecord
+	if err := json.NewDecoder(w.Body).Decode(&record); err != nil || record.Notes != notes.Notes {
+		t.Errorf("export record = %+v, %v", record, err)
+	}
+
+	// with_notes gives them to the LLM
+	do("GET", "/v1/summarize?url=dQw4w9WgXcQ", "")
+	if strings.Contains(prompt, "Check the remaster") {
+		t.Error("notes in the prompt without with_notes")
+	}
+	do("GET", "/v1/summarize?url=dQw4w9WgXcQ&with_notes=true", "")
+	if !strings.Contains(prompt, "The viewer keeps these notes") || !strings.Contains(prompt, "Check the remaster") {
+		t.Errorf("prompt = %q, want the notes", prompt)
+	}
+
+	// Notes run well past the default body limit
+	long := strings.Repeat("A paragraph about the remaster and its mix. ", 120)
+	if w := do("PATCH", "/v1/videos/dQw4w9WgXcQ/notes", `{"notes": "`+long+`"}`); w.Code != 200 {
+		t.Errorf("PATCH %d-character notes: status = %d, %s", len(long), w.Code, w.Body)
+	}
+
+	if w := do("PATCH", "/v1/videos/dQw4w9WgXcQ/notes", `{"notes": ""}`); w.Code != 204 {
+		t.Errorf("PATCH clear: status = %d, want 204", w.Code)
+	}
+}
+
+// readOnlyNotes refuses writes like a --cache-readonly cache
+type readOnlyNotes struct{ NotesStore }
+
+func (readOnlyNotes) SetNotes(videoID, notes string) error { return errCacheReadOnly }
+
+func TestPatchNotesReadOnly(t *testing.T) {
+	cache := newTestCache(t)
+	handler := newServer(ServerConfig{Cache: cache, Notes: readOnlyNotes{cache}}).Handler()
+	req := httptest.NewRequest("PATCH", "/v1/videos/dQw4w9WgXcQ/notes", strings.NewReader(`{"notes": "Shared in the team chat"}`))
+	req.RemoteAddr = "198.51.100.240:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if w.Code != http.StatusConflict || errResp.Error != ErrCacheReadOnly {
+		t.Errorf("status = %d, error = %+v", w.Code, errResp)
+	}
+}
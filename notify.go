@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	neturl "net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// notifyURLs are the sinks given with --notify
+var notifyURLs []string
+
+// maxEventFailures caps how many failed videos a notification lists
+const maxEventFailures = 10
+
+// Event is a job outcome published to notification sinks
+type Event struct {
+	Type      string         `json:"type"` // "<job>.completed" or "<job>.failed"
+	Job       string         `json:"job"`  // "batch", "prefetch" or "weekly"
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+	Failures  []EventFailure `json:"failures,omitempty"` // videos that failed in this run
+	Time      time.Time      `json:"time"`
+
+	// Digest is the Markdown of a weekly digest, whose Succeeded counts its videos
+	Digest string `json:"digest,omitempty"`
+}
+
+// EventFailure is one failed video in an Event
+type EventFailure struct {
+	VideoID    string `json:"video_id,omitempty"`
+	URL        string `json:"url"`
+	ErrorClass string `json:"error_class"`
+	Error      string `json:"error"`
+}
+
+// newJobEvent builds the event for a finished job run
+func newJobEvent(job string, succeeded, failed int, failures []EventFailure) Event {
+	eventType := job + ".completed"
+	if failed > 0 {
+		eventType = job + ".failed"
+	}
+	return Event{
+		Type:      eventType,
+		Job:       job,
+		Succeeded: succeeded,
+		Failed:    failed,
+		Failures:  failures,
+		Time:      time.Now().UTC(),
+	}
+}
+
+// Title is a one-line summary of the event
+func (e Event) Title() string {
+	if e.Digest != "" {
+		return fmt.Sprintf("ytsummary %s digest: %d videos", e.Job, e.Succeeded)
+	}
+	return fmt.Sprintf("ytsummary %s: %d succeeded, %d failed", e.Job, e.Succeeded, e.Failed)
+}
+
+// Text renders the event as plain text for chat and email sinks
+func (e Event) Text() string {
+	var b strings.Builder
+	b.WriteString(e.Title())
+	for i, f := range e.Failures {
+		if i == maxEventFailures {
+			fmt.Fprintf(&b, "\n... and %d more", len(e.Failures)-i)
+			break
+		}
+		id := f.VideoID
+		if id == "" {
+			id = f.URL
+		}
+		fmt.Fprintf(&b, "\n- %s (%s): %s", id, f.ErrorClass, f.Error)
+	}
+	if e.Digest != "" {
+		b.WriteString("\n\n" + e.Digest)
+	}
+	return b.String()
+}
+
+// Notifier delivers job events to an external sink
+type Notifier interface {
+	Notify(event Event) error
+}
+
+// newNotifier picks a sink by URL:
+//
+//	https://hooks.slack.com/...           Slack incoming webhook
+//	https://discord.com/api/webhooks/...  Discord webhook
+//	ntfy://ntfy.sh/topic                  ntfy topic (https)
+//	mailto:ops@example.com                email via YTSUMMARY_SMTP_* settings
+//	any other http(s) URL                 generic webhook receiving the Event as JSON
+func newNotifier(rawURL string) (Notifier, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notify URL %q: %w", rawURL, err)
+	}
+
+	if u.Scheme == "mailto" {
+		return newEmailNotifier(u.Opaque)
+	}
+	ntfy := u.Scheme == "ntfy"
+	if ntfy {
+		u.Scheme = "https"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid notify URL %q: use http(s), ntfy:// or mailto:", rawURL)
+	}
+	// Webhooks are user-provided, so they may not point at metadata services
+	if err := validateOutboundURL(u.String()); err != nil {
+		return nil, fmt.Errorf("invalid notify URL %q: %w", rawURL, err)
+	}
+
+	switch {
+	case ntfy:
+		return &ntfyNotifier{url: u.String()}, nil
+	case u.Host == "hooks.slack.com":
+		return &slackNotifier{url: rawURL}, nil
+	case (u.Host == "discord.com" || u.Host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks/"):
+		return &discordNotifier{url: rawURL}, nil
+	default:
+		return &webhookNotifier{url: rawURL}, nil
+	}
+}
+
+// newNotifiersFromConfig returns the sinks from --notify, or the comma-separated
+// YTSUMMARY_NOTIFY env var
+func newNotifiersFromConfig() ([]Notifier, error) {
+	urls := notifyURLs
+	if len(urls) == 0 {
+		if env := os.Getenv("YTSUMMARY_NOTIFY"); env != "" {
+			urls = strings.Split(env, ",")
+		}
+	}
+
+	var notifiers []Notifier
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u == "" {
+			continue
+		}
+		n, err := newNotifier(u)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+// publish sends an event to every sink. A failing sink is reported but never
+// fails the job.
+func publish(notifiers []Notifier, event Event) {
+	for _, n := range notifiers {
+		if err := n.Notify(event); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: notification failed: %v\n", err)
+		}
+	}
+}
+
+// postNotification sends a payload to a sink and checks the response status
+func postNotification(url, contentType string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
+
+// postJSON marshals a payload and posts it to a sink
+func postJSON(url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	return postNotification(url, "application/json", body, nil)
+}
+
+// webhookNotifier posts the Event as JSON
+type webhookNotifier struct{ url string }
+
+func (n *webhookNotifier) Notify(event Event) error {
+	return postJSON(n.url, event)
+}
+
+// slackNotifier posts to a Slack incoming webhook
+type slackNotifier struct{ url string }
+
+func (n *slackNotifier) Notify(event Event) error {
+	return postJSON(n.url, map[string]string{"text": event.Text()})
+}
+
+// discordMessageLimit is the most characters Discord accepts in a message
+const discordMessageLimit = 2000
+
+// discordNotifier posts to a Discord webhook
+type discordNotifier struct{ url string }
+
+func (n *discordNotifier) Notify(event Event) error {
+	text := event.Text()
+	if len(text) > discordMessageLimit {
+		text = text[:discordMessageLimit-3] + "..."
+	}
+	return postJSON(n.url, map[string]string{"content": text})
+}
+
+// ntfyNotifier publishes to an ntfy topic
+type ntfyNotifier struct{ url string }
+
+func (n *ntfyNotifier) Notify(event Event) error {
+	headers := map[string]string{"Title": event.Title(), "Tags": "white_check_mark"}
+	if event.Failed > 0 {
+		headers["Tags"] = "warning"
+	}
+	return postNotification(n.url, "text/plain", []byte(event.Text()), headers)
+}
+
+// emailNotifier sends events through the SMTP server in YTSUMMARY_SMTP_ADDR
+type emailNotifier struct {
+	addr     string // host:port
+	from     string
+	to       []string
+	username string
+	password string
+}
+
+func newEmailNotifier(recipients string) (*emailNotifier, error) {
+	n := &emailNotifier{
+		addr:     os.Getenv("YTSUMMARY_SMTP_ADDR"),
+		from:     os.Getenv("YTSUMMARY_SMTP_FROM"),
+		username: os.Getenv("YTSUMMARY_SMTP_USERNAME"),
+		password: os.Getenv("YTSUMMARY_SMTP_PASSWORD"),
+	}
+	for _, to := range strings.Split(recipients, ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			n.to = append(n.to, to)
+		}
+	}
+	switch {
+	case len(n.to) == 0:
+		return nil, fmt.Errorf("mailto: notify URL has no recipients")
+	case n.addr == "":
+		return nil, fmt.Errorf("email notifications need YTSUMMARY_SMTP_ADDR (host:port)")
+	case n.from == "":
+		n.from = "ytsummary@localhost"
+	}
+	return n, nil
+}
+
+// message renders the event as an RFC 5322 email
+func (n *emailNotifier) message(event Event) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", event.Title())
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(event.Text(), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+func (n *emailNotifier) Notify(event Event) error {
+	var auth smtp.Auth
+	if n.username != "" {
+		host, _, _ := strings.Cut(n.addr, ":")
+		auth = smtp.PlainAuth("", n.username, n.password, host)
+	}
+	if err := smtp.SendMail(n.addr, auth, n.from, n.to, n.message(event)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
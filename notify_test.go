@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewNotifier(t *testing.T) {
+	t.Setenv("YTSUMMARY_SMTP_ADDR", "smtp.example.com:587")
+
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"https://hooks.slack.com/services/T0/B0/xyz", "*main.slackNotifier", false},
+		{"https://discord.com/api/webhooks/1/abc", "*main.discordNotifier", false},
+		{"ntfy://ntfy.sh/ytsummary", "*main.ntfyNotifier", false},
+		{"mailto:ops@example.com,dev@example.com", "*main.emailNotifier", false},
+		{"https://example.com/hooks/ytsummary", "*main.webhookNotifier", false},
+		{"https://discord.com/channels/1", "*main.webhookNotifier", false},
+		{"ftp://example.com/x", "", true},
+		{"mailto:", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			n, err := newNotifier(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newNotifier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && fmt.Sprintf("%T", n) != tt.want {
+				t.Errorf("newNotifier() = %T, want %s", n, tt.want)
+			}
+		})
+	}
+}
+
+// notifySink records the requests sent to a fake notification endpoint
+type notifySink struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	bodies  []string
+	headers []http.Header
+}
+
+func newNotifySink(t *testing.T) *notifySink {
+	t.Helper()
+	sink := &notifySink{}
+	sink.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		sink.bodies = append(sink.bodies, string(body))
+		sink.headers = append(sink.headers, r.Header.Clone())
+	}))
+	t.Cleanup(sink.Close)
+	return sink
+}
+
+func TestNotifierPayloads(t *testing.T) {
+	event := newJobEvent("batch", 1, 1, []EventFailure{{VideoID: "noCaptions1", ErrorClass: ErrNoCaptions, Error: "no subtitles available"}})
+	sink := newNotifySink(t)
+
+	notifiers := []Notifier{
+		&webhookNotifier{url: sink.URL},
+		&slackNotifier{url: sink.URL},
+		&discordNotifier{url: sink.URL},
+		&ntfyNotifier{url: sink.URL},
+	}
+	for _, n := range notifiers {
+		if err := n.Notify(event); err != nil {
+			t.Fatalf("%T.Notify() error = %v", n, err)
+		}
+	}
+
+	var got Event
+	if err := json.Unmarshal([]byte(sink.bodies[0]), &got); err != nil || got.Type != "batch.failed" || len(got.Failures) != 1 {
+		t.Errorf("webhook body = %s, want the event as JSON", sink.bodies[0])
+	}
+	wantText := "ytsummary batch: 1 succeeded, 1 failed\n- noCaptions1 (no_captions): no subtitles available"
+	for i, key := range []string{"text", "content"} {
+		var payload map[string]string
+		json.Unmarshal([]byte(sink.bodies[i+1]), &payload)
+		if payload[key] != wantText {
+			t.Errorf("%T payload = %s, want %s %q", notifiers[i+1], sink.bodies[i+1], key, wantText)
+		}
+	}
+	if sink.bodies[3] != wantText || sink.headers[3].Get("Title") != event.Title() || sink.headers[3].Get("Tags") != "warning" {
+		t.Errorf("ntfy request = %q %v", sink.bodies[3], sink.headers[3])
+	}
+}
+
+func TestNotifierError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	err := (&webhookNotifier{url: srv.URL}).Notify(newJobEvent("batch", 1, 0, nil))
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Notify() error = %v, want status 403", err)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	n := &emailNotifier{from: "bot@example.com", to: []string{"ops@example.com"}}
+	msg := string(n.message(newJobEvent("prefetch", 3, 0, nil)))
+
+	for _, want := range []string{"From: bot@example.com\r\n", "To: ops@example.com\r\n", "Subject: ytsummary prefetch: 3 succeeded, 0 failed\r\n"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestRunBatchNotifies(t *testing.T) {
+	newBatchTest(t, "https://youtu.be/dQw4w9WgXcQ\nhttps://youtu.be/noCaptions1\n")
+	sink := newNotifySink(t)
+	notifyURLs = []string{sink.URL}
+	t.Cleanup(func() { notifyURLs = nil })
+
+	if err := runBatch(nil, nil); err == nil {
+		t.Fatal("expected the no-captions video to fail")
+	}
+
+	if len(sink.bodies) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(sink.bodies))
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(sink.bodies[0]), &event); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+	if event.Type != "batch.failed" || event.Succeeded != 1 || event.Failed != 1 {
+		t.Errorf("event = %+v, want batch.failed with 1 success and 1 failure", event)
+	}
+	if len(event.Failures) != 1 || event.Failures[0].VideoID != "noCaptions1" || event.Failures[0].ErrorClass != ErrNoCaptions {
+		t.Errorf("failures = %+v", event.Failures)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Google's OAuth endpoints; overridden by tests to point at a fake server
+var (
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+// youtubeOAuthScope lets creator commands list the channel's uploads and
+// download their captions (captions.download needs force-ssl)
+const youtubeOAuthScope = "https://www.googleapis.com/auth/youtube.force-ssl"
+
+// oauthLoginTimeout is how long login waits for the browser to come back
+const oauthLoginTimeout = 5 * time.Minute
+
+// errNotLoggedIn is returned by creator commands before 'creator login'
+var errNotLoggedIn = errors.New("not logged in to YouTube; run 'ytsummary creator login' first")
+
+// oauthConfig is the Google OAuth client creators register for ytsummary
+// (a "Desktop app" client in Google Cloud Console)
+type oauthConfig struct {
+	ClientID     string
+	ClientSecret string
+	TokenFile    string // where the token is saved between runs
+}
+
+// oauthToken is a saved OAuth token. The refresh token outlives the access
+// token, so a login lasts until the creator revokes it.
+type oauthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// expired reports whether the access token has run out, or is about to
+func (t *oauthToken) expired() bool {
+	return time.Now().Add(time.Minute).After(t.Expiry)
+}
+
+// oauthConfigFromEnv returns the OAuth client from --oauth-client-id and
+// --oauth-client-secret (or YTSUMMARY_OAUTH_CLIENT_ID / _SECRET), saving
+// the token in --oauth-token-file (default: youtube-oauth.json in the cache
+// directory)
+func oauthConfigFromEnv() (oauthConfig, error) {
+	cfg := oauthConfig{
+		ClientID:     getConfig(oauthClientID, "YTSUMMARY_OAUTH_CLIENT_ID"),
+		ClientSecret: getConfig(oauthClientSecret, "YTSUMMARY_OAUTH_CLIENT_SECRET"),
+		TokenFile:    getConfig(oauthTokenFile, "YTSUMMARY_OAUTH_TOKEN_FILE"),
+	}
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return cfg, fmt.Errorf("creator commands need a Google OAuth client: set YTSUMMARY_OAUTH_CLIENT_ID and YTSUMMARY_OAUTH_CLIENT_SECRET")
+	}
+	if cfg.TokenFile == "" {
+		cfg.TokenFile = filepath.Join(cacheDir, "youtube-oauth.json")
+	}
+	return cfg, nil
+}
+
+// loadOAuthToken reads the saved token, or returns errNotLoggedIn
+func loadOAuthToken(path string) (*oauthToken, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errNotLoggedIn
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OAuth token: %w", err)
+	}
+	var token oauthToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse OAuth token %s: %w", path, err)
+	}
+	return &token, nil
+}
+
+// saveOAuthToken writes the token readable by the owner alone
+func saveOAuthToken(path string, token *oauthToken) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save OAuth token: %w", err)
+	}
+	return nil
+}
+
+// tokenResponse is the token endpoint's reply
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken posts a grant to the token endpoint
+func requestToken(cfg oauthConfig, form url.Values) (*oauthToken, error) {
+	form.Set("client_id", cfg.ClientID)
+	form.Set("client_secret", cfg.ClientSecret)
+	resp, err := httpClient.PostForm(googleTokenURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Google's token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("failed to parse token response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if tr.Error != "" || tr.AccessToken == "" {
+		return nil, fmt.Errorf("OAuth token request failed (HTTP %d): %s %s", resp.StatusCode, tr.Error, tr.ErrorDescription)
+	}
+	return &oauthToken{
+		AccessToken:  tr.AccessToken,
+		RefreshToken: tr.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// refreshOAuthToken trades the refresh token for a new access token. Google
+// doesn't always return a new refresh token, so the old one is kept.
+func refreshOAuthToken(cfg oauthConfig, token *oauthToken) (*oauthToken, error) {
+	if token.RefreshToken == "" {
+		return nil, errNotLoggedIn
+	}
+	fresh, err := requestToken(cfg, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if fresh.RefreshToken == "" {
+		fresh.RefreshToken = token.RefreshToken
+	}
+	return fresh, nil
+}
+
+// randomURLString returns n random bytes, base64url-encoded
+func randomURLString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// oauthLogin runs Google's loopback flow for installed apps: it listens on
+// a local port, hands the consent URL to show for the creator to open, and
+// exchanges the code the browser brings back (protected with PKCE) for a
+// token
+func oauthLogin(ctx context.Context, cfg oauthConfig, show func(authURL string)) (*oauthToken, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the OAuth redirect: %w", err)
+	}
+	redirectURI := "http://" + listener.Addr().String() + "/callback"
+	state, verifier := randomURLString(16), randomURLString(32)
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authURL := googleAuthURL + "?" + url.Values{
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {redirectURI},
+		"response_type":         {"code"},
+		"scope":                 {youtubeOAuthScope},
+		"access_type":           {"offline"},
+		"prompt":                {"consent"},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}.Encode()
+
+	codes := make(chan string, 1)
+	failures := make(chan error, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case r.URL.Path != "/callback":
+			http.NotFound(w, r)
+			return
+		case q.Get("state") != state:
+			http.Error(w, "Login failed: state mismatch", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			http.Error(w, "Login failed: "+q.Get("error"), http.StatusForbidden)
+			select {
+			case failures <- fmt.Errorf("authorization denied: %s", q.Get("error")):
+			default:
+			}
+			return
+		}
+		fmt.Fprintln(w, "ytsummary is logged in. You can close this tab.")
+		select {
+		case codes <- q.Get("code"):
+		default: // a reload after the first redirect
+		}
+	})}
+	go srv.Serve(listener)
+	defer srv.Close()
+	show(authURL)
+
+	ctx, cancel := context.WithTimeout(ctx, oauthLoginTimeout)
+	defer cancel()
+	select {
+	case code := <-codes:
+		return requestToken(cfg, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {redirectURI},
+			"code_verifier": {verifier},
+		})
+	case err := <-failures:
+		return nil, err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timed out waiting for the browser login")
+	}
+}
+
+// authorizedRequest sends req with the creator's access token, refreshing
+// and saving it when it has expired or the API rejects it
+func authorizedRequest(cfg oauthConfig, token *oauthToken, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if token.expired() || attempt == 1 {
+			fresh, err := refreshOAuthToken(cfg, token)
+			if err != nil {
+				return nil, err
+			}
+			*token = *fresh
+			if err := saveOAuthToken(cfg.TokenFile, token); err != nil {
+				return nil, err
+			}
+		}
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		resp, err := httpClient.Do(req)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || attempt == 1 {
+			return resp, err
+		}
+		resp.Body.Close()
+	}
+}
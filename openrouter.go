@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Attribution sent to OpenRouter unless overridden; it shows the app in
+// OpenRouter's usage rankings
+const (
+	defaultOpenRouterReferer = "https://github.com/alrobwilloliver/ytsummary"
+	defaultOpenRouterTitle   = "ytsummary"
+)
+
+var (
+	openRouterProviders      string
+	openRouterAllowFallbacks string
+)
+
+// openRouterConfig holds the OpenRouter-only request settings
+type openRouterConfig struct {
+	Referer        string   // HTTP-Referer attribution header
+	Title          string   // X-Title attribution header
+	Order          []string // provider.order: inference providers to try, in order
+	AllowFallbacks *bool    // provider.allow_fallbacks; nil leaves OpenRouter's default
+}
+
+// isOpenRouter reports whether the API URL points at OpenRouter
+func isOpenRouter(apiURL string) bool {
+	u, err := neturl.Parse(apiURL)
+	return err == nil && (u.Hostname() == "openrouter.ai" || strings.HasSuffix(u.Hostname(), ".openrouter.ai"))
+}
+
+// openRouterConfigFromEnv reads YTSUMMARY_OPENROUTER_REFERER and
+// YTSUMMARY_OPENROUTER_TITLE, plus --openrouter-providers
+// (YTSUMMARY_OPENROUTER_PROVIDERS, comma-separated) and
+// --openrouter-allow-fallbacks (YTSUMMARY_OPENROUTER_ALLOW_FALLBACKS)
+func openRouterConfigFromEnv() (*openRouterConfig, error) {
+	cfg := &openRouterConfig{
+		Referer: os.Getenv("YTSUMMARY_OPENROUTER_REFERER"),
+		Title:   os.Getenv("YTSUMMARY_OPENROUTER_TITLE"),
+	}
+	if cfg.Referer == "" {
+		cfg.Referer = defaultOpenRouterReferer
+	}
+	if cfg.Title == "" {
+		cfg.Title = defaultOpenRouterTitle
+	}
+
+	for _, p := range strings.Split(getConfig(openRouterProviders, "YTSUMMARY_OPENROUTER_PROVIDERS"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.Order = append(cfg.Order, p)
+		}
+	}
+	if v := getConfig(openRouterAllowFallbacks, "YTSUMMARY_OPENROUTER_ALLOW_FALLBACKS"); v != "" {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow-fallbacks value %q (use true or false)", v)
+		}
+		cfg.AllowFallbacks = &allow
+	}
+	return cfg, nil
+}
+
+// setHeaders adds the attribution headers
+func (c *openRouterConfig) setHeaders(req *http.Request) {
+	req.Header.Set("HTTP-Referer", c.Referer)
+	req.Header.Set("X-Title", c.Title)
+}
+
+// applyTo adds provider routing preferences to a chat completion request
+func (c *openRouterConfig) applyTo(reqBody map[string]any) {
+	provider := map[string]any{}
+	if len(c.Order) > 0 {
+		provider["order"] = c.Order
+	}
+	if c.AllowFallbacks != nil {
+		provider["allow_fallbacks"] = *c.AllowFallbacks
+	}
+	if len(provider) > 0 {
+		reqBody["provider"] = provider
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsOpenRouter(t *testing.T) {
+	tests := map[string]bool{
+		defaultAPIURL:                 true,
+		"https://eu.openrouter.ai/v1": true,
+		"https://api.openai.com/v1":   false,
+		"http://localhost:11434/v1":   false,
+		"https://openrouter.ai.evil/": false,
+	}
+	for url, want := range tests {
+		if got := isOpenRouter(url); got != want {
+			t.Errorf("isOpenRouter(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
+
+func TestOpenRouterConfigFromEnv(t *testing.T) {
+	t.Setenv("YTSUMMARY_OPENROUTER_REFERER", "")
+	t.Setenv("YTSUMMARY_OPENROUTER_TITLE", "my-app")
+	t.Setenv("YTSUMMARY_OPENROUTER_PROVIDERS", "together, fireworks")
+	t.Setenv("YTSUMMARY_OPENROUTER_ALLOW_FALLBACKS", "false")
+
+	cfg, err := openRouterConfigFromEnv()
+	if err != nil {
+		t.Fatalf("openRouterConfigFromEnv() error = %v", err)
+	}
+	if cfg.Referer != defaultOpenRouterReferer || cfg.Title != "my-app" {
+		t.Errorf("attribution = %q, %q", cfg.Referer, cfg.Title)
+	}
+	if len(cfg.Order) != 2 || cfg.Order[0] != "together" || cfg.Order[1] != "fireworks" {
+		t.Errorf("Order = %v, want [together fireworks]", cfg.Order)
+	}
+	if cfg.AllowFallbacks == nil || *cfg.AllowFallbacks {
+		t.Errorf("AllowFallbacks = %v, want false", cfg.AllowFallbacks)
+	}
+
+	t.Setenv("YTSUMMARY_OPENROUTER_ALLOW_FALLBACKS", "maybe")
+	if _, err := openRouterConfigFromEnv(); err == nil {
+		t.Error("expected error for invalid allow-fallbacks value")
+	}
+}
+
+func TestOpenAIClientOpenRouter(t *testing.T) {
+	var header http.Header
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"choices":[{"message":{"content":"a summary"}}]}`))
+	}))
+	defer srv.Close()
+
+	allow := false
+	client := &openAIClient{apiKey: "k", model: "m", apiURL: srv.URL, openRouter: &openRouterConfig{
+		Referer: "https://example.com", Title: "ytsummary", Order: []string{"together"}, AllowFallbacks: &allow,
+	}}
+	if _, err := client.Complete("s", "t", GenerationParams{}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if header.Get("HTTP-Referer") != "https://example.com" || header.Get("X-Title") != "ytsummary" {
+		t.Errorf("attribution headers = %q, %q", header.Get("HTTP-Referer"), header.Get("X-Title"))
+	}
+	provider, _ := body["provider"].(map[string]any)
+	if order, _ := provider["order"].([]any); len(order) != 1 || order[0] != "together" || provider["allow_fallbacks"] != false {
+		t.Errorf("provider = %v, want order [together] without fallbacks", body["provider"])
+	}
+
+	// Other providers get neither
+	client.openRouter = nil
+	if _, err := client.Complete("s", "t", GenerationParams{}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if header.Get("X-Title") != "" || body["provider"] != nil {
+		t.Errorf("non-OpenRouter request has X-Title %q, provider %v", header.Get("X-Title"), body["provider"])
+	}
+}
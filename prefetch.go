@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Prefetch configuration
+var (
+	prefetchFile  string
+	prefetchDelay time.Duration
+)
+
+const defaultPrefetchDelay = 2 * time.Second
+
+// readURLList reads one URL or video ID per line, skipping blank lines and # comments
+func readURLList(r io.Reader) ([]string, error) {
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read URL list: %w", err)
+	}
+	return urls, nil
+}
+
+// runPrefetch fetches and caches transcripts for a list of URLs without summarizing
+func runPrefetch(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	urls, err := readVideoList(prefetchFile)
+	if err != nil {
+		return err
+	}
+
+	defaults, err := newChannelDefaults(cmd)
+	if err != nil {
+		return err
+	}
+	notifiers, err := newNotifiersFromConfig()
+	if err != nil {
+		return err
+	}
+
+	log("Prefetching %d videos (language '%s', %s between fetches)...", len(urls), language, prefetchDelay)
+
+	var fetched, skipped, failed int
+	var failures []EventFailure
+	needsDelay := false
+	for i, url := range urls {
+		videoID, err := extractVideoID(url)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[%d/%d] skipping invalid URL %q: %v\n", i+1, len(urls), url, err)
+			cliMetrics.recordFailure(ErrInvalidRequest)
+			failures = append(failures, EventFailure{URL: url, ErrorClass: ErrInvalidRequest, Error: err.Error()})
+			failed++
+			continue
+		}
+
+		settings, err := defaults.settingsFor(videoID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s failed: %v\n", i+1, len(urls), videoID, err)
+			cliMetrics.recordFailure(fetchErrorClass(err))
+			failures = append(failures, EventFailure{VideoID: videoID, URL: url, ErrorClass: fetchErrorClass(err), Error: err.Error()})
+			failed++
+			continue
+		}
+		lang := settings.Language
+
+		if d := deadLettered(cache, videoID); d != nil {
+			if !d.Suppressed {
+				fmt.Fprintf(os.Stderr, "[%d/%d] %s skipped: %s\n", i+1, len(urls), videoID, deadLetterMessage(d))
+				failures = append(failures, EventFailure{VideoID: videoID, URL: url, ErrorClass: d.ErrorClass, Error: deadLetterMessage(d)})
+				failed++
+			}
+			cliMetrics.recordSkipped()
+			continue
+		}
+
+		if _, err := cache.GetTranscript(videoID, lang); err == nil {
+			log("[%d/%d] %s already cached", i+1, len(urls), videoID)
+			cliMetrics.recordSkipped()
+			skipped++
+			continue
+		}
+
+		// Be polite to YouTube: only pause between actual network fetches
+		if needsDelay {
+			time.Sleep(prefetchDelay)
+		}
+		needsDelay = true
+
+		result, err := fetchTranscript(canonicalVideoURL(videoID), lang, autoTranslateAllowed())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s failed: %v\n", i+1, len(urls), videoID, err)
+			cliMetrics.recordFailure(fetchErrorClass(err))
+			recordJobResult(cache, videoID, fetchErrorClass(err), err.Error())
+			failures = append(failures, EventFailure{VideoID: videoID, URL: url, ErrorClass: fetchErrorClass(err), Error: err.Error()})
+			failed++
+			continue
+		}
+		recordJobResult(cache, videoID, "", "")
+
+		warnLanguageMismatch(lang, result)
+
+		if err := cacheFetchResult(cache, videoID, lang, result); err != nil {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s failed to cache: %v\n", i+1, len(urls), videoID, err)
+			cliMetrics.recordFailure(ErrInternal)
+			failures = append(failures, EventFailure{VideoID: videoID, URL: url, ErrorClass: ErrInternal, Error: err.Error()})
+			failed++
+			continue
+		}
+
+		log("[%d/%d] %s cached (%d chars)", i+1, len(urls), videoID, len(result.Transcript))
+		cliMetrics.recordProcessed()
+		fetched++
+	}
+
+	log("Done! %d fetched, %d already cached, %d failed", fetched, skipped, failed)
+	publish(notifiers, newJobEvent("prefetch", fetched+skipped, failed, failures))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d videos failed to prefetch", failed, len(urls))
+	}
+	return nil
+}
+
+// queuedPrefetchTimeout bounds a queued prefetch, including its wait for a
+// work slot
+const queuedPrefetchTimeout = 2 * time.Minute
+
+// PrefetchRequest asks the server to cache a transcript without summarizing it
+type PrefetchRequest struct {
+	URL                string `json:"url"`
+	Language           string `json:"language,omitempty"`
+	AllowAutoTranslate bool   `json:"allow_auto_translate,omitempty"`
+	Priority           string `json:"priority,omitempty"` // defaults to batch
+
+	// Queue answers 202 straight away and fetches in the background
+	Queue bool `json:"queue,omitempty"`
+}
+
+// PrefetchResponse reports a prefetched, already cached or queued transcript
+type PrefetchResponse struct {
+	VideoID    string     `json:"video_id"`
+	Language   string     `json:"language"`
+	Title      string     `json:"title,omitempty"`
+	Cached     bool       `json:"cached"` // it was cached before this request
+	Queued     bool       `json:"queued,omitempty"`
+	FetchedAt  *time.Time `json:"fetched_at,omitempty"`
+	DurationMS int64      `json:"duration_ms"`
+}
+
+// handlePrefetch warms the cache for a video, so a UI can start the fetch
+// while its user decides whether to ask for a summary. No LLM is called.
+func (s *Server) handlePrefetch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	reqCtx := getRequestContext(r)
+
+	var req PrefetchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "url is required")
+		return
+	}
+	videoID, _, err := resolveVideoURL(req.URL)
+	if errors.Is(err, errClipResolve) {
+		writeParseError(w, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid YouTube URL: "+err.Error())
+		return
+	}
+	if req.Priority == "" {
+		req.Priority = priorityBatch
+	}
+	if err := validPriority(req.Priority); err != nil {
+		writeErrorWithVideo(w, http.StatusBadRequest, ErrInvalidRequest, err.Error(), videoID)
+		return
+	}
+	reqCtx.setVideo(videoID)
+	reqCtx.Timeline.setVideo(videoID)
+	lang := requestLanguage(r, req.Language)
+	allowTranslate := req.AllowAutoTranslate || s.requestDefaults().AllowAutoTranslate
+
+	respond := func(status int, entry *CacheEntry, cached bool) {
+		resp := PrefetchResponse{VideoID: videoID, Language: lang, Cached: cached, Queued: entry == nil}
+		if entry != nil {
+			fetchedAt := entry.FetchedAt.UTC()
+			resp.Title, resp.FetchedAt = entry.Title, &fetchedAt
+		}
+		resp.DurationMS = time.Since(start).Milliseconds()
+		writeJSON(w, status, resp)
+	}
+
+	if req.Queue {
+		if entry, err := s.cache.GetTranscript(videoID, lang); err == nil && (entry.TranslatedFrom == "" || allowTranslate) {
+			reqCtx.CacheHit = true
+			respond(http.StatusOK, entry, true)
+			return
+		}
+		s.prefetches.Add(1)
+		go func() {
+			defer s.prefetches.Done()
+			defer s.recoverPanic("queued prefetch panicked", slog.String("video_id", videoID))
+			s.prefetchInBackground(r, videoID, lang, allowTranslate, req.Priority)
+		}()
+		respond(http.StatusAccepted, nil, false)
+		return
+	}
+
+	entry, cached, err := s.getOrFetchTranscript(r, canonicalVideoURL(videoID), videoID, lang, timingNone, allowTranslate, req.Priority)
+	if err != nil {
+		handleFetchError(w, err, videoID)
+		return
+	}
+	reqCtx.CacheHit = cached
+	if !cached {
+		s.markSuccess()
+	}
+	respond(http.StatusOK, entry, cached)
+}
+
+// prefetchInBackground fetches a queued prefetch once the request that
+// asked for it has been answered
+func (s *Server) prefetchInBackground(r *http.Request, videoID, lang string, allowTranslate bool, priority string) {
+	ctx, cancel := context.WithTimeout(context.Background(), queuedPrefetchTimeout)
+	defer cancel()
+	// A fresh context drops the finished request's cancellation and timeline
+	_, cached, err := s.getOrFetchTranscript(r.WithContext(ctx), canonicalVideoURL(videoID), videoID, lang, timingNone, allowTranslate, priority)
+	if err != nil {
+		logWarn("queued prefetch failed", slog.String("video_id", videoID), slog.String("language", lang), slog.String("error", err.Error()))
+		return
+	}
+	if !cached {
+		s.markSuccess()
+	}
+	logDebug("queued prefetch done", slog.String("video_id", videoID), slog.String("language", lang), slog.Bool("cached", cached))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReadURLList(t *testing.T) {
+	input := `# videos to warm
+https://youtu.be/dQw4w9WgXcQ
+
+  https://www.youtube.com/watch?v=jNQXAC9IVRw  
+# trailing comment
+kJQP7kiw5Fk
+`
+	got, err := readURLList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readURLList() error = %v", err)
+	}
+
+	want := []string{
+		"https://youtu.be/dQw4w9WgXcQ",
+		"https://www.youtube.com/watch?v=jNQXAC9IVRw",
+		"kJQP7kiw5Fk",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readURLList() = %v, want %v", got, want)
+	}
+}
+
+func TestPrefetchEndpoint(t *testing.T) {
+	cache := newTestCache(t)
+	var fetches, summaries atomic.Int32
+	s := newServer(ServerConfig{
+		Cache: cache,
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			fetches.Add(1)
+			return &FetchResult{Title: "Warmed", Transcript: "One. Two. Three."}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			summaries.Add(1)
+			return "unexpected", nil
+		},
+	})
+	handler := s.Handler()
+
+	post := func(body string) (*httptest.ResponseRecorder, PrefetchResponse) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/v1/prefetch", strings.NewReader(body)))
+		var resp PrefetchResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	w, resp := post(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`)
+	if w.Code != http.StatusOK || resp.Cached || resp.Title != "Warmed" || resp.FetchedAt == nil {
+		t.Fatalf("first prefetch: status = %d, response = %+v", w.Code, resp)
+	}
+	if _, err := cache.GetTranscript("dQw4w9WgXcQ", "en"); err != nil {
+		t.Fatalf("transcript not cached: %v", err)
+	}
+	if w, resp := post(`{"url": "dQw4w9WgXcQ", "queue": true}`); w.Code != http.StatusOK || !resp.Cached || resp.Queued {
+		t.Errorf("cached queued prefetch: status = %d, response = %+v", w.Code, resp)
+	}
+
+	w, resp = post(`{"url": "jNQXAC9IVRw", "language": "de", "queue": true}`)
+	if w.Code != http.StatusAccepted || !resp.Queued || resp.Language != "de" {
+		t.Fatalf("queued prefetch: status = %d, response = %+v", w.Code, resp)
+	}
+	s.prefetches.Wait()
+	if _, err := cache.GetTranscript("jNQXAC9IVRw", "de"); err != nil {
+		t.Errorf("queued transcript not cached: %v", err)
+	}
+
+	if w, _ := post(`{"url": "dQw4w9WgXcQ", "priority": "urgent"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("bad priority: status = %d, want 400", w.Code)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetched %d times, want 2", n)
+	}
+	if n := summaries.Load(); n != 0 {
+		t.Errorf("summarized %d times, want never", n)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultPricingURL is OpenRouter's model list, which carries per-token prices
+const defaultPricingURL = "https://openrouter.ai/api/v1/models"
+
+// pricingFileName is where 'models pricing sync' saves the table, in the cache dir
+const pricingFileName = "pricing.json"
+
+//go:embed pricing.json
+var embeddedPricing []byte
+
+var (
+	pricingFile string
+	pricingURL  string
+)
+
+// modelPrice is what a model costs in USD per million tokens
+type modelPrice struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+	Source     string  `json:"-"` // "embedded", or the file the price was loaded from
+}
+
+// cost is the price of a call with the given token counts
+func (p modelPrice) cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Prompt + float64(completionTokens)*p.Completion) / 1e6
+}
+
+// pricingTable maps model IDs to prices
+type pricingTable struct {
+	Source    string                `json:"source,omitempty"`
+	UpdatedAt string                `json:"updated_at,omitempty"`
+	Models    map[string]modelPrice `json:"models"`
+}
+
+// price returns the price of model, if known. Models are looked up with and
+// without their provider prefix, so "gpt-4o" matches "openai/gpt-4o".
+func (t *pricingTable) price(model string) (modelPrice, bool) {
+	if p, ok := t.Models[model]; ok {
+		return p, true
+	}
+	_, name, hasPrefix := strings.Cut(model, "/")
+	for _, known := range t.sortedModels() {
+		_, knownName, _ := strings.Cut(known, "/")
+		if (hasPrefix && name == knownName) || (!hasPrefix && model == knownName) {
+			return t.Models[known], true
+		}
+	}
+	return modelPrice{}, false
+}
+
+// sortedModels returns the model IDs in a stable order
+func (t *pricingTable) sortedModels() []string {
+	models := make([]string, 0, len(t.Models))
+	for model := range t.Models {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// merge adds other's prices, replacing existing ones
+func (t *pricingTable) merge(other *pricingTable, source string) {
+	for model, p := range other.Models {
+		p.Source = source
+		t.Models[model] = p
+	}
+}
+
+// parsePricing reads a pricing table: either ytsummary's own format (USD per
+// million tokens under "models") or an OpenRouter-style model list, whose
+// "data" entries have per-token prices as strings
+func parsePricing(data []byte) (*pricingTable, error) {
+	var parsed struct {
+		pricingTable
+		Data []struct {
+			ID      string `json:"id"`
+			Pricing struct {
+				Prompt     string `json:"prompt"`
+				Completion string `json:"completion"`
+			} `json:"pricing"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid pricing JSON: %w", err)
+	}
+	table := &parsed.pricingTable
+	if table.Models == nil {
+		table.Models = make(map[string]modelPrice)
+	}
+
+	for _, m := range parsed.Data {
+		prompt, err1 := strconv.ParseFloat(m.Pricing.Prompt, 64)
+		completion, err2 := strconv.ParseFloat(m.Pricing.Completion, 64)
+		// Negative prices mark router models whose price depends on the pick
+		if m.ID == "" || err1 != nil || err2 != nil || prompt < 0 || completion < 0 {
+			continue
+		}
+		table.Models[m.ID] = modelPrice{Prompt: prompt * 1e6, Completion: completion * 1e6}
+	}
+
+	if len(table.Models) == 0 {
+		return nil, errors.New("pricing JSON has no model prices")
+	}
+	return table, nil
+}
+
+// readPricingFile parses a pricing table from disk
+func readPricingFile(path string) (*pricingTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	table, err := parsePricing(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return table, nil
+}
+
+var (
+	pricingOnce sync.Once
+	pricingTbl  *pricingTable
+	pricingErr  error
+)
+
+// loadPricing returns the pricing table used for cost estimates: the embedded
+// table, updated by the last 'models pricing sync' and then by --pricing-file
+// (YTSUMMARY_PRICING_FILE). It is read once per process.
+func loadPricing() (*pricingTable, error) {
+	pricingOnce.Do(func() {
+		pricingTbl, pricingErr = readPricing()
+	})
+	return pricingTbl, pricingErr
+}
+
+func readPricing() (*pricingTable, error) {
+	table, err := parsePricing(embeddedPricing)
+	if err != nil {
+		return nil, err
+	}
+	for model, p := range table.Models {
+		p.Source = "embedded"
+		table.Models[model] = p
+	}
+
+	synced := filepath.Join(cacheDir, pricingFileName)
+	if t, err := readPricingFile(synced); err == nil {
+		table.merge(t, synced)
+		table.UpdatedAt = t.UpdatedAt
+	} else if !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "warning: ignoring synced pricing: %v\n", err)
+	}
+
+	if path := getConfig(pricingFile, "YTSUMMARY_PRICING_FILE"); path != "" {
+		t, err := readPricingFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pricing file: %w", err)
+		}
+		table.merge(t, path)
+	}
+	return table, nil
+}
+
+// estimateCost returns the USD cost of a call, if the model's price is known
+func estimateCost(model string, promptTokens, completionTokens int) (float64, bool) {
+	table, err := loadPricing()
+	if err != nil {
+		return 0, false
+	}
+	price, ok := table.price(model)
+	if !ok {
+		return 0, false
+	}
+	return price.cost(promptTokens, completionTokens), true
+}
+
+// syncPricing downloads a pricing table from url and saves it to dest in
+// ytsummary's own format, returning how many models it prices
+func syncPricing(url, dest string) (int, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download pricing: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to download pricing: %s returned %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download pricing: %w", err)
+	}
+
+	table, err := parsePricing(body)
+	if err != nil {
+		return 0, err
+	}
+	table.Source = url
+	table.UpdatedAt = time.Now().UTC().Format(time.DateOnly)
+
+	data, err := json.MarshalIndent(table, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, fmt.Errorf("failed to save pricing: %w", err)
+	}
+	// Write then rename so a running process never reads a partial table
+	tmp := dest + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to save pricing: %w", err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return 0, fmt.Errorf("failed to save pricing: %w", err)
+	}
+	return len(table.Models), nil
+}
+
+func runModelsPricing(cmd *cobra.Command, args []string) error {
+	table, err := loadPricing()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%-40s %10s %10s  %s\n", "MODEL", "PROMPT", "COMPLETION", "SOURCE")
+	for _, model := range table.sortedModels() {
+		p := table.Models[model]
+		fmt.Printf("%-40s %10.4f %10.4f  %s\n", model, p.Prompt, p.Completion, p.Source)
+	}
+	fmt.Println("\nPrices are USD per million tokens.")
+	return nil
+}
+
+func runModelsPricingSync(cmd *cobra.Command, args []string) error {
+	url := getConfig(pricingURL, "YTSUMMARY_PRICING_URL")
+	if url == "" {
+		url = defaultPricingURL
+	}
+	dest := filepath.Join(cacheDir, pricingFileName)
+
+	n, err := syncPricing(url, dest)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved prices for %d models from %s to %s\n", n, url, dest)
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmbeddedPricing(t *testing.T) {
+	table, err := parsePricing(embeddedPricing)
+	if err != nil {
+		t.Fatalf("parsePricing(embedded) error = %v", err)
+	}
+	if _, ok := table.price(defaultModel); !ok {
+		t.Errorf("embedded pricing has no price for the default model %s", defaultModel)
+	}
+	if p, ok := table.price("gpt-4o"); !ok || p.Prompt != 2.50 {
+		t.Errorf("price(gpt-4o) = %+v, %v, want openai/gpt-4o's price", p, ok)
+	}
+	if _, ok := table.price("someone/gpt-4o"); !ok {
+		t.Error("price should match a model under another provider prefix")
+	}
+	if _, ok := table.price("local/llama-custom"); ok {
+		t.Error("price should not know unlisted models")
+	}
+}
+
+func TestParsePricingOpenRouter(t *testing.T) {
+	data := []byte(`{"data": [
+		{"id": "openai/gpt-4o", "pricing": {"prompt": "0.0000025", "completion": "0.00001"}},
+		{"id": "openrouter/auto", "pricing": {"prompt": "-1", "completion": "-1"}},
+		{"id": "broken", "pricing": {"prompt": "", "completion": ""}}
+	]}`)
+	table, err := parsePricing(data)
+	if err != nil {
+		t.Fatalf("parsePricing() error = %v", err)
+	}
+	if len(table.Models) != 1 {
+		t.Errorf("parsed %d models, want only the priced one", len(table.Models))
+	}
+	if p := table.Models["openai/gpt-4o"]; p.Prompt != 2.5 || p.Completion != 10 {
+		t.Errorf("price = %+v, want per-million prices", p)
+	}
+
+	if _, err := parsePricing([]byte(`{"data": []}`)); err == nil {
+		t.Error("expected error for a table with no prices")
+	}
+}
+
+func TestSyncPricing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"id": "acme/cheap-1", "pricing": {"prompt": "0.0000001", "completion": "0.0000002"}}]}`))
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), pricingFileName)
+	n, err := syncPricing(srv.URL, dest)
+	if err != nil || n != 1 {
+		t.Fatalf("syncPricing() = %d, %v", n, err)
+	}
+
+	// The saved file is in ytsummary's format and loads on top of the embedded table
+	cacheDir = filepath.Dir(dest)
+	defer func() { cacheDir = "" }()
+	override := filepath.Join(t.TempDir(), "prices.json")
+	os.WriteFile(override, []byte(`{"models": {"openai/gpt-4o": {"prompt": 1, "completion": 2}}}`), 0644)
+	t.Setenv("YTSUMMARY_PRICING_FILE", override)
+
+	table, err := readPricing()
+	if err != nil {
+		t.Fatalf("readPricing() error = %v", err)
+	}
+	if p, ok := table.price("acme/cheap-1"); !ok || p.Source != dest {
+		t.Errorf("synced price = %+v, %v, want it loaded from %s", p, ok, dest)
+	}
+	if p := table.Models["openai/gpt-4o"]; p.Prompt != 1 || p.Source != override {
+		t.Errorf("overridden price = %+v, want the pricing file's", p)
+	}
+	if p := table.Models[defaultModel]; p.Source != "embedded" {
+		t.Errorf("default model price source = %q, want embedded", p.Source)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Priority classes for work the server does. Waiting interactive requests
+// always get the next free slot, so a playlist backfill sent through the
+// server can't starve someone waiting on a single summary.
+const (
+	priorityInteractive = "interactive" // the default
+	priorityBatch       = "batch"       // batch runs, prefetch and other bulk work
+)
+
+// defaultWorkSlots is how many YouTube fetches and LLM calls the server runs
+// at once unless YTSUMMARY_MAX_CONCURRENT says otherwise
+const defaultWorkSlots = 4
+
+// cacheServerPriority is the priority the CLI asks for when it fetches
+// through a cache server; batch runs lower it
+var cacheServerPriority = priorityInteractive
+
+// validPriority checks a request's priority; "" means interactive
+func validPriority(p string) error {
+	switch p {
+	case "", priorityInteractive, priorityBatch:
+		return nil
+	}
+	return fmt.Errorf("unknown priority %q (use %s or %s)", p, priorityInteractive, priorityBatch)
+}
+
+// workSlotsConfig reads YTSUMMARY_MAX_CONCURRENT
+func workSlotsConfig() int {
+	if n, err := strconv.Atoi(os.Getenv("YTSUMMARY_MAX_CONCURRENT")); err == nil && n > 0 {
+		return n
+	}
+	return defaultWorkSlots
+}
+
+// workQueue limits how much expensive work runs at once, handing free slots
+// to interactive waiters before batch ones. Waiters of the same priority are
+// served in arrival order.
+type workQueue struct {
+	mu      sync.Mutex
+	slots   int
+	busy    int
+	waiting map[string][]chan struct{}
+}
+
+func newWorkQueue(slots int) *workQueue {
+	return &workQueue{slots: slots, waiting: map[string][]chan struct{}{}}
+}
+
+// acquire waits for a slot and returns the function that frees it. It gives
+// up when ctx is done.
+func (q *workQueue) acquire(ctx context.Context, priority string) (func(), error) {
+	if priority == "" {
+		priority = priorityInteractive
+	}
+
+	q.mu.Lock()
+	if q.busy < q.slots && len(q.waiting[priorityInteractive]) == 0 && (priority == priorityInteractive || len(q.waiting[priorityBatch]) == 0) {
+		q.busy++
+		q.mu.Unlock()
+		return q.release, nil
+	}
+	ready := make(chan struct{})
+	q.waiting[priority] = append(q.waiting[priority], ready)
+	q.mu.Unlock()
+
+	select {
+	case <-ready:
+		return q.release, nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for i, c := range q.waiting[priority] {
+			if c == ready {
+				q.waiting[priority] = append(q.waiting[priority][:i], q.waiting[priority][i+1:]...)
+				return nil, ctx.Err()
+			}
+		}
+		// The slot was handed over just as ctx ended; pass it on
+		q.releaseLocked()
+		return nil, ctx.Err()
+	}
+}
+
+func (q *workQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+// releaseLocked hands the slot to the next waiter, if any. Slots over a
+// lowered limit are retired instead.
+func (q *workQueue) releaseLocked() {
+	if q.busy > q.slots {
+		q.busy--
+		return
+	}
+	for _, p := range []string{priorityInteractive, priorityBatch} {
+		if waiters := q.waiting[p]; len(waiters) > 0 {
+			q.waiting[p] = waiters[1:]
+			close(waiters[0])
+			return
+		}
+	}
+	q.busy--
+}
+
+// setSlots changes the number of slots, e.g. on reload. Extra slots go to
+// waiters straight away; when shrinking, busy slots drain as they finish.
+func (q *workQueue) setSlots(slots int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.slots = slots
+	for q.busy < q.slots && len(q.waiting[priorityInteractive])+len(q.waiting[priorityBatch]) > 0 {
+		q.busy++
+		q.releaseLocked()
+	}
+}
+
+// QueueResponse is the state of the server's work queue
+type QueueResponse struct {
+	Slots   int            `json:"slots"`
+	Busy    int            `json:"busy"`
+	Waiting map[string]int `json:"waiting"` // by priority
+}
+
+func (q *workQueue) state() QueueResponse {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueResponse{
+		Slots: q.slots,
+		Busy:  q.busy,
+		Waiting: map[string]int{
+			priorityInteractive: len(q.waiting[priorityInteractive]),
+			priorityBatch:       len(q.waiting[priorityBatch]),
+		},
+	}
+}
+
+// waitForSlot queues for a work slot, timing the wait as a stage of the
+// request. The request's client going away ends the wait.
+func (s *Server) waitForSlot(r *http.Request, priority string) (func(), error) {
+	queued := getRequestContext(r).Timeline.begin(stageQueue)
+	release, err := s.queue.acquire(r.Context(), priority)
+	queued(err)
+	return release, err
+}
+
+// writeQueueError reports a request abandoned while it waited for a slot
+func writeQueueError(w http.ResponseWriter, err error, videoID string) {
+	writeErrorWithVideo(w, http.StatusServiceUnavailable, ErrInternal, "Request cancelled while queued: "+err.Error(), videoID)
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.queue.state())
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// waitForWaiters polls until the queue has n waiters of the priority
+func waitForWaiters(t *testing.T, q *workQueue, priority string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for q.state().Waiting[priority] != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d %s waiters, want %d", q.state().Waiting[priority], priority, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWorkQueueServesInteractiveFirst(t *testing.T) {
+	q := newWorkQueue(1)
+	release, err := q.acquire(context.Background(), priorityBatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan string, 3)
+	waiter := func(priority string) {
+		release, err := q.acquire(context.Background(), priority)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		order <- priority
+		release()
+	}
+	go waiter(priorityBatch)
+	waitForWaiters(t, q, priorityBatch, 1)
+	go waiter(priorityBatch)
+	waitForWaiters(t, q, priorityBatch, 2)
+	go waiter(priorityInteractive)
+	waitForWaiters(t, q, priorityInteractive, 1)
+
+	release()
+	for i, want := range []string{priorityInteractive, priorityBatch, priorityBatch} {
+		if got := <-order; got != want {
+			t.Errorf("slot %d went to %s, want %s", i+1, got, want)
+		}
+	}
+	if st := q.state(); st.Busy != 0 {
+		t.Errorf("busy = %d after every slot was released", st.Busy)
+	}
+}
+
+func TestWorkQueueCancelAndResize(t *testing.T) {
+	q := newWorkQueue(1)
+	if _, err := q.acquire(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.acquire(ctx, priorityInteractive); err == nil {
+		t.Fatal("acquire succeeded with every slot busy")
+	}
+	if st := q.state(); st.Waiting[priorityInteractive] != 0 {
+		t.Errorf("cancelled waiter still queued: %+v", st)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if _, err := q.acquire(context.Background(), priorityBatch); err == nil {
+			close(acquired)
+		}
+	}()
+	waitForWaiters(t, q, priorityBatch, 1)
+	q.setSlots(2)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("waiter not admitted after adding a slot")
+	}
+	if st := q.state(); st.Busy != 2 {
+		t.Errorf("busy = %d, want 2", st.Busy)
+	}
+}
+
+func TestInvalidPriority(t *testing.T) {
+	s := newServer(ServerConfig{Cache: newTestCache(t)})
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest("POST", "/v1/transcript", bytes.NewBufferString(`{"url": "https://youtu.be/dQw4w9WgXcQ", "priority": "urgent"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
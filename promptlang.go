@@ -0,0 +1,95 @@
+package main
+
+import "fmt"
+
+var (
+	summaryLang string // --summary-lang
+	promptLang  string // --prompt-lang
+)
+
+// summaryLanguagePrompts ask for the summary in a language, in the language
+// of each translated prompt; others ask in English
+var summaryLanguagePrompts = map[string]string{
+	"en": "Write the summary in %s.",
+	"es": "Escribe el resumen en %s.",
+	"fr": "Rédige le résumé en %s.",
+	"de": "Schreibe die Zusammenfassung auf %s.",
+	"it": "Scrivi il riassunto in %s.",
+	"pt": "Escreva o resumo em %s.",
+	"ja": "要約は%sで書いてください。",
+	"zh": "请用%s撰写摘要。",
+}
+
+// nativeLanguageNames name languages in translated prompts, where "Escribe el
+// resumen en français" reads better than the English name
+var nativeLanguageNames = map[string]string{
+	"en": "English", "es": "español", "fr": "français", "de": "Deutsch", "it": "italiano",
+	"pt": "português", "nl": "Nederlands", "ru": "русский", "uk": "українська", "el": "ελληνικά",
+	"ar": "العربية", "he": "עברית", "hi": "हिन्दी", "th": "ไทย", "ko": "한국어",
+	"ja": "日本語", "zh": "中文",
+}
+
+// summaryLanguage returns the language summaries are written in: --summary-lang
+// / YTSUMMARY_SUMMARY_LANG, else the transcript's
+func summaryLanguage(transcriptLang string) string {
+	if lang := getConfig(summaryLang, "YTSUMMARY_SUMMARY_LANG"); lang != "" {
+		return lang
+	}
+	return transcriptLang
+}
+
+// requestSummaryLanguage returns the language an API request's summary is
+// written in: the one requested, else the configured one, else the transcript's
+func requestSummaryLanguage(requested, transcriptLang string) string {
+	if requested != "" {
+		return requested
+	}
+	return summaryLanguage(transcriptLang)
+}
+
+// promptLanguage returns the language of the prompts for opts: the one asked
+// for (opts.PromptLanguage, else --prompt-lang / YTSUMMARY_PROMPT_LANG), else
+// the summary's. Templates without a translation into it stay in English.
+func promptLanguage(opts SummaryOptions) string {
+	if opts.PromptLanguage != "" {
+		return opts.PromptLanguage
+	}
+	if lang := getConfig(promptLang, "YTSUMMARY_PROMPT_LANG"); lang != "" {
+		return lang
+	}
+	return opts.Vars.Language
+}
+
+// validLanguageCode checks a --summary-lang / --prompt-lang value, named by
+// what in errors
+func validLanguageCode(what, lang string) error {
+	if lang == "" || validLanguageSubtag(baseLanguage(lang)) {
+		return nil
+	}
+	return fmt.Errorf("invalid %s %q (use a language code such as es or pt-BR)", what, lang)
+}
+
+// checkSummaryLanguages checks the configured summary and prompt languages
+func checkSummaryLanguages() error {
+	if err := validLanguageCode("summary language", getConfig(summaryLang, "YTSUMMARY_SUMMARY_LANG")); err != nil {
+		return err
+	}
+	return validLanguageCode("prompt language", getConfig(promptLang, "YTSUMMARY_PROMPT_LANG"))
+}
+
+// summaryLanguageInstruction asks for the summary in lang, in the language of
+// a template's prompts. English prompts for English summaries need none.
+func summaryLanguageInstruction(tmpl *PromptTemplate, lang string) string {
+	lang = baseLanguage(lang)
+	if lang == "" || (tmpl.Language == "" && lang == "en") {
+		return ""
+	}
+	if format, ok := summaryLanguagePrompts[tmpl.Language]; ok {
+		name, ok := nativeLanguageNames[lang]
+		if !ok {
+			name = languageName(lang)
+		}
+		return fmt.Sprintf(format, name)
+	}
+	return fmt.Sprintf(summaryLanguagePrompts["en"], languageName(lang))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalizedTemplates(t *testing.T) {
+	tests := []struct {
+		name, lang, want string
+	}{
+		{"", "es-MX", "es"},
+		{"key-points", "ja", "ja"},
+		{"", "en", ""},
+		{"", "ko", ""},         // no translation
+		{"tutorial", "fr", ""}, // not translated
+	}
+	for _, tt := range tests {
+		tmpl, err := getLocalizedTemplate(tt.name, tt.lang)
+		if err != nil || tmpl.Language != tt.want {
+			t.Errorf("getLocalizedTemplate(%q, %q) language = %q, %v, want %q", tt.name, tt.lang, tmpl.Language, err, tt.want)
+		}
+	}
+
+	// Every translation renders and translates a built-in template
+	langs, err := templateLanguages()
+	if err != nil || len(langs) == 0 {
+		t.Fatalf("templateLanguages() = %v, %v", langs, err)
+	}
+	originals, _ := loadTemplates()
+	for _, lang := range langs {
+		translations, err := loadTranslations(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, tmpl := range translations {
+			if originals[name] == nil || tmpl.Description == "" {
+				t.Errorf("%s/%s: original %v, description %q", lang, name, originals[name] != nil, tmpl.Description)
+			}
+			prompt, chunkPrompt, err := tmpl.render(PromptVars{Title: "Demo"})
+			if err != nil || prompt == "" || chunkPrompt == partialSummaryPrompt {
+				t.Errorf("%s/%s: render() = %q, %q, %v", lang, name, prompt, chunkPrompt, err)
+			}
+		}
+		if _, ok := summaryLanguagePrompts[lang]; !ok {
+			t.Errorf("no summary language instruction in %s", lang)
+		}
+	}
+}
+
+func TestSummaryPromptsLanguage(t *testing.T) {
+	english, _, _ := summaryPrompts(SummaryOptions{Vars: PromptVars{Language: "en"}})
+	tests := []struct {
+		name       string
+		opts       SummaryOptions
+		prefix     string
+		suffix     string
+		chunkStart string
+	}{
+		{"english", SummaryOptions{Vars: PromptVars{Language: "en"}}, english, english, "Summarize this section"},
+		{"translated", SummaryOptions{Vars: PromptVars{Language: "es"}}, "Resume la transcripción", "Escribe el resumen en español.", "Resume esta sección"},
+		{"untranslated", SummaryOptions{Vars: PromptVars{Language: "ko"}}, english, "Write the summary in Korean.", "Summarize this section"},
+		{"english prompts", SummaryOptions{Vars: PromptVars{Language: "de"}, PromptLanguage: "en"}, english, "Write the summary in German.", "Summarize this section"},
+		{"other prompt language", SummaryOptions{Vars: PromptVars{Language: "fr"}, PromptLanguage: "es"}, "Resume la transcripción", "Escribe el resumen en français.", "Resume esta sección"},
+	}
+	for _, tt := range tests {
+		prompt, chunkPrompt, err := summaryPrompts(tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(prompt, tt.prefix) || !strings.HasSuffix(prompt, tt.suffix) || !strings.HasPrefix(chunkPrompt, tt.chunkStart) {
+			t.Errorf("%s: prompts = %q, %q", tt.name, prompt, chunkPrompt)
+		}
+	}
+
+	// --prompt-lang applies when a request names none
+	promptLang = "en"
+	defer func() { promptLang = "" }()
+	if prompt, _, _ := summaryPrompts(SummaryOptions{Vars: PromptVars{Language: "es"}}); !strings.HasPrefix(prompt, english) {
+		t.Errorf("--prompt-lang en: prompt = %q", prompt)
+	}
+}
+
+func TestCustomTranslations(t *testing.T) {
+	dir := t.TempDir()
+	templatesDir = dir
+	defer func() { templatesDir = "" }()
+
+	write := func(name, body string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("es/default.tmpl", "Resume el vídeo en una frase.")
+	write("ca/default.tmpl", "Resumeix aquest vídeo.")
+	// A custom English template hides the embedded translations of the built-in
+	write("key-points.tmpl", "Custom key points")
+
+	if tmpl, err := getLocalizedTemplate("", "es"); err != nil || tmpl.Source == "embedded" {
+		t.Errorf("custom Spanish default not used: %+v, %v", tmpl, err)
+	}
+	if tmpl, err := getLocalizedTemplate("key-points", "es"); err != nil || tmpl.Language != "" {
+		t.Errorf("embedded translation of a custom template used: %+v, %v", tmpl, err)
+	}
+
+	// Languages without a built-in instruction are asked in English
+	prompt, _, err := summaryPrompts(SummaryOptions{Vars: PromptVars{Language: "ca"}})
+	if err != nil || prompt != "Resumeix aquest vídeo.\n\nWrite the summary in ca." {
+		t.Errorf("Catalan prompt = %q, %v", prompt, err)
+	}
+	if langs, _ := templateLanguages(); !strings.Contains(strings.Join(langs, ","), "ca") {
+		t.Errorf("templateLanguages() = %v", langs)
+	}
+}
+
+func TestSummarizeSummaryLanguage(t *testing.T) {
+	cache := newTestCache(t)
+	var langs []string
+	handler := newServer(ServerConfig{
+		Cache: cache,
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Economics lecture", Transcript: "Inflation eased last quarter.", Language: "en"}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			langs = append(langs, opts.Vars.Language+"/"+opts.PromptLanguage)
+			return "Resumen.", nil
+		},
+	}).Handler()
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/summarize", strings.NewReader(body))
+		req.RemoteAddr = "198.51.100.230:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(`{"url": "https://youtu.be/dQw4w9WgXcQ", "summary_language": "es", "prompt_language": "en"}`); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if w := post(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if len(langs) != 2 || langs[0] != "es/en" || langs[1] != "en/" {
+		t.Errorf("summary/prompt languages = %q", langs)
+	}
+
+	w := post(`{"url": "https://youtu.be/dQw4w9WgXcQ", "summary_language": "spanish!"}`)
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if w.Code != http.StatusBadRequest || !strings.Contains(errResp.Message, "summary_language") {
+		t.Errorf("invalid summary_language: status = %d, %+v", w.Code, errResp)
+	}
+}
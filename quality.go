@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// lowQualityScore is the score below which auto-generated captions are
+// considered too unreliable to summarize without a warning
+const lowQualityScore = 0.5
+
+// nonSpeechRe matches caption annotations such as [Music], [Applause] or [♪♪♪]
+var nonSpeechRe = regexp.MustCompile(`\[[^\]]*\]|\([^)]*(?i:music|applause|laughter)[^)]*\)`)
+
+// TranscriptQuality holds heuristics for how usable auto-generated captions are
+type TranscriptQuality struct {
+	Score            float64 `json:"score"`              // 0 (garbage) to 1 (clean)
+	RepetitionRatio  float64 `json:"repetition_ratio"`   // share of word trigrams seen earlier in the transcript
+	NonSpeechDensity float64 `json:"non_speech_density"` // share of tokens that are [Music]-style annotations
+	AvgSegmentWords  float64 `json:"avg_segment_words,omitempty"`
+	Warning          string  `json:"warning,omitempty"`
+}
+
+// assessTranscriptQuality scores auto-generated captions. Uploaded captions are
+// assumed to be fine and return nil.
+func assessTranscriptQuality(entry *CacheEntry) *TranscriptQuality {
+	if !entry.AutoGenerated {
+		return nil
+	}
+
+	q := &TranscriptQuality{}
+
+	markers := len(nonSpeechRe.FindAllString(entry.Transcript, -1))
+	words := strings.Fields(strings.ToLower(nonSpeechRe.ReplaceAllString(entry.Transcript, " ")))
+	if tokens := markers + len(words); tokens > 0 {
+		q.NonSpeechDensity = float64(markers) / float64(tokens)
+	}
+	q.RepetitionRatio = trigramRepetition(words)
+
+	if len(entry.Segments) > 0 {
+		q.AvgSegmentWords = float64(len(words)) / float64(len(entry.Segments))
+	}
+
+	// Each signal knocks points off a perfect score. Songs and chants repeat
+	// lines legitimately, so some repetition is free.
+	score := 1.0
+	score -= 1.5 * math.Max(0, q.RepetitionRatio-0.2)
+	score -= 2 * q.NonSpeechDensity
+	if q.AvgSegmentWords > 0 && q.AvgSegmentWords < 3 {
+		score -= 0.5 * (3 - q.AvgSegmentWords) / 3
+	}
+	if len(words) == 0 {
+		score = 0
+	}
+	q.Score = math.Round(math.Max(0, score)*100) / 100
+	q.RepetitionRatio = math.Round(q.RepetitionRatio*100) / 100
+	q.NonSpeechDensity = math.Round(q.NonSpeechDensity*100) / 100
+	q.AvgSegmentWords = math.Round(q.AvgSegmentWords*10) / 10
+
+	if q.Score < lowQualityScore {
+		q.Warning = fmt.Sprintf("auto-generated captions look unreliable (quality %.2f); the summary may be inaccurate", q.Score)
+	}
+	return q
+}
+
+// warnTranscriptQuality tells CLI users when a transcript's auto-generated
+// captions look unreliable
+func warnTranscriptQuality(entry *CacheEntry) *TranscriptQuality {
+	q := assessTranscriptQuality(entry)
+	if q != nil && q.Warning != "" {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", q.Warning)
+	}
+	return q
+}
+
+// trigramRepetition returns the share of word trigrams that already appeared
+// earlier, which is high when ASR loops on the same phrase
+func trigramRepetition(words []string) float64 {
+	if len(words) < 3 {
+		return 0
+	}
+	seen := make(map[string]bool)
+	repeats := 0
+	for i := 0; i+3 <= len(words); i++ {
+		key := strings.Join(words[i:i+3], " ")
+		if seen[key] {
+			repeats++
+		}
+		seen[key] = true
+	}
+	return float64(repeats) / float64(len(words)-2)
+}
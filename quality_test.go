@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssessTranscriptQuality(t *testing.T) {
+	clean := "so today we're going to look at how the scheduler decides which task runs next and why that matters for latency"
+	looping := strings.Repeat("thank you for watching ", 20)
+	music := strings.Repeat("[Music] ", 12) + "hello everyone"
+
+	segments := func(n int) []TranscriptSegment { return make([]TranscriptSegment, n) }
+
+	tests := []struct {
+		name     string
+		entry    CacheEntry
+		wantNil  bool
+		minScore float64
+		maxScore float64
+		wantWarn bool
+	}{
+		{"uploaded captions are not scored", CacheEntry{Transcript: looping}, true, 0, 0, false},
+		{"clean speech", CacheEntry{Transcript: clean, AutoGenerated: true, Segments: segments(3)}, false, 0.9, 1, false},
+		{"looping phrase", CacheEntry{Transcript: looping, AutoGenerated: true}, false, 0, 0.3, true},
+		{"mostly music", CacheEntry{Transcript: music, AutoGenerated: true}, false, 0, 0.1, true},
+		{"one word per cue", CacheEntry{Transcript: clean, AutoGenerated: true, Segments: segments(21)}, false, 0.5, 0.7, false},
+		{"empty", CacheEntry{Transcript: "[Music]", AutoGenerated: true}, false, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := assessTranscriptQuality(&tt.entry)
+			if tt.wantNil {
+				if q != nil {
+					t.Errorf("assessTranscriptQuality() = %+v, want nil", q)
+				}
+				return
+			}
+			if q.Score < tt.minScore || q.Score > tt.maxScore {
+				t.Errorf("Score = %v, want between %v and %v (%+v)", q.Score, tt.minScore, tt.maxScore, q)
+			}
+			if (q.Warning != "") != tt.wantWarn {
+				t.Errorf("Warning = %q, want warning: %v", q.Warning, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestTrigramRepetition(t *testing.T) {
+	if got := trigramRepetition(strings.Fields("a b c d e f")); got != 0 {
+		t.Errorf("distinct words: got %v, want 0", got)
+	}
+	if got := trigramRepetition(strings.Fields("a b c a b c")); got != 0.25 {
+		t.Errorf("repeated phrase: got %v, want 0.25", got)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	quickTemplate = "quick"
+	// defaultQuickModel is used on OpenRouter when neither --model nor
+	// YTSUMMARY_QUICK_MODEL names one, since launchers wait on the answer
+	defaultQuickModel = "google/gemini-2.0-flash-lite-001"
+	// Defaults for quick's --max-words and --max-transcript-chars; transcripts
+	// past the limit are cut rather than summarized chunk by chunk
+	defaultQuickMaxWords           = 80
+	defaultQuickMaxTranscriptChars = 60000
+)
+
+// Flags for quick
+var (
+	quickMaxWords           int
+	quickMaxTranscriptChars int
+)
+
+// quiet silences log, for output that a launcher shows in full
+var quiet bool
+
+// quickModel returns the model quick summarizes with: --model, else
+// YTSUMMARY_QUICK_MODEL, else a fast model when the API is OpenRouter. ""
+// keeps the configured model, since other APIs may not know the default.
+func quickModel() string {
+	if llmModel != "" {
+		return llmModel
+	}
+	if model := getConfig("", "YTSUMMARY_QUICK_MODEL"); model != "" {
+		return model
+	}
+	apiURL := getConfig(llmBaseURL, "YTSUMMARY_API_URL")
+	if apiURL == "" || isOpenRouter(apiURL) {
+		return defaultQuickModel
+	}
+	return ""
+}
+
+var (
+	markdownPrefix   = regexp.MustCompile(`^(#{1,6}\s+|[-*+•]\s+|\d+[.)]\s+|>\s*)`)
+	markdownEmphasis = regexp.MustCompile(`\*\*|__|\x60`)
+)
+
+// compactParagraph flattens a summary to one line of plain text: headings,
+// bullets and emphasis are dropped and lines joined, so it fits a launcher's
+// small panel
+func compactParagraph(summary string) string {
+	var parts []string
+	for _, line := range strings.Split(summary, "\n") {
+		line = strings.TrimSpace(markdownPrefix.ReplaceAllString(strings.TrimSpace(line), ""))
+		line = markdownEmphasis.ReplaceAllString(line, "")
+		if line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// runQuick prints a one-paragraph summary of a video and nothing else, for
+// Raycast, Alfred and similar launchers
+func runQuick(cmd *cobra.Command, args []string) error {
+	quiet = true
+	defer func() { quiet = false }()
+
+	if quickMaxWords <= 0 {
+		return fmt.Errorf("--max-words must be positive")
+	}
+	limit, strategy, err := transcriptLimit(quickMaxTranscriptChars, "")
+	if err != nil {
+		return err
+	}
+
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	videoID, clip, err := resolveVideoURL(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid YouTube URL: %w", err)
+	}
+	window, err := transcriptWindow(clip)
+	if err != nil {
+		return err
+	}
+	filter := contentFilterMode()
+	if err := validContentFilter(filter); err != nil {
+		return err
+	}
+	entry, err := loadVideoTranscript(cache, videoID, window, timingNone)
+	if err != nil {
+		return err
+	}
+	notes := applyContentFilter(entry, filter)
+	if !keepNonSpeech {
+		stripCaptionArtifacts(entry)
+	}
+	budget, err := invocationBudget()
+	if err != nil {
+		return err
+	}
+
+	transcript, truncation := truncateTranscript(entry.Transcript, limit, strategy)
+	opts := SummaryOptions{
+		Template: quickTemplate,
+		Model:    quickModel(),
+		Vars:     promptVarsFromEntry(entry, summaryLanguage(language)),
+		MaxWords: quickMaxWords,
+		Budget:   budget,
+		Meta:     &SummaryMeta{},
+	}
+	summary, err := summarize(transcript, opts)
+	if err != nil {
+		return fmt.Errorf("failed to summarize: %w", err)
+	}
+	keepSummary(cache, newStoredSummary(videoID, opts.Vars.Language, quickTemplate, quickMaxWords, summary, opts.Meta))
+
+	fmt.Println(compactParagraph(withTruncationNote(withContentNote(summary, notes), truncation)))
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCompactParagraph(t *testing.T) {
+	summary := `## Overview
+
+Rick Astley **promises** never to give you up.
+
+- Never gonna let you down
+- Never gonna run around
+
+1. Released in 1987`
+	want := "Overview Rick Astley promises never to give you up. Never gonna let you down Never gonna run around Released in 1987"
+	if got := compactParagraph(summary); got != want {
+		t.Errorf("compactParagraph() = %q, want %q", got, want)
+	}
+}
+
+func TestQuickModel(t *testing.T) {
+	t.Setenv("YTSUMMARY_QUICK_MODEL", "")
+	t.Setenv("YTSUMMARY_API_URL", "")
+
+	if got := quickModel(); got != defaultQuickModel {
+		t.Errorf("OpenRouter default = %q, want %q", got, defaultQuickModel)
+	}
+	t.Setenv("YTSUMMARY_API_URL", "http://localhost:11434/v1")
+	if got := quickModel(); got != "" {
+		t.Errorf("other API = %q, want the configured model", got)
+	}
+	t.Setenv("YTSUMMARY_QUICK_MODEL", "openai/gpt-4o-mini")
+	if got := quickModel(); got != "openai/gpt-4o-mini" {
+		t.Errorf("YTSUMMARY_QUICK_MODEL = %q", got)
+	}
+	llmModel = "anthropic/claude-3.5-haiku"
+	t.Cleanup(func() { llmModel = "" })
+	if got := quickModel(); got != llmModel {
+		t.Errorf("--model = %q, want %q", got, llmModel)
+	}
+}
+
+func TestRunQuick(t *testing.T) {
+	newBatchTest(t, "")
+	quickMaxWords = defaultQuickMaxWords
+	quickMaxTranscriptChars = defaultQuickMaxTranscriptChars
+
+	if err := runQuick(nil, []string{"https://youtu.be/dQw4w9WgXcQ"}); err != nil {
+		t.Fatalf("runQuick() error = %v", err)
+	}
+	if quiet {
+		t.Error("logging still silenced after quick")
+	}
+
+	cache, _ := openCache()
+	defer cache.Close()
+	summaries, err := cache.ListSummaries("dQw4w9WgXcQ")
+	if err != nil || len(summaries) != 1 || summaries[0].Template != quickTemplate || summaries[0].MaxWords != defaultQuickMaxWords {
+		t.Errorf("kept summaries = %+v, %v", summaries, err)
+	}
+
+	quickMaxWords = 0
+	if err := runQuick(nil, []string{"https://youtu.be/dQw4w9WgXcQ"}); err == nil {
+		t.Error("--max-words 0 accepted")
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -22,6 +24,7 @@ type ipRateLimiter struct {
 	mu       sync.RWMutex
 	rate     rate.Limit
 	burst    int
+	shared   *redisRateLimiter // when set, limits hold across servers
 }
 
 type rateLimiterEntry struct {
@@ -29,17 +32,42 @@ type rateLimiterEntry struct {
 	lastSeen time.Time
 }
 
-var limiter *ipRateLimiter
-
-func initRateLimiter() {
-	limiter = &ipRateLimiter{
+func newRateLimiter(perMinute, burst int) *ipRateLimiter {
+	limiter := &ipRateLimiter{
 		limiters: make(map[string]*rateLimiterEntry),
-		rate:     rate.Limit(float64(rateLimitPerMinute) / 60.0), // convert to per-second
-		burst:    rateLimitBurst,
+		rate:     rate.Limit(float64(perMinute) / 60.0), // convert to per-second
+		burst:    burst,
 	}
 
 	// Start cleanup goroutine
 	go limiter.cleanup()
+	return limiter
+}
+
+// rateLimitConfig returns the per-IP limits, overridable with
+// YTSUMMARY_RATE_LIMIT (requests per minute) and YTSUMMARY_RATE_BURST
+func rateLimitConfig() (perMinute, burst int) {
+	perMinute, burst = rateLimitPerMinute, rateLimitBurst
+	if v, err := strconv.Atoi(os.Getenv("YTSUMMARY_RATE_LIMIT")); err == nil && v > 0 {
+		perMinute = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("YTSUMMARY_RATE_BURST")); err == nil && v > 0 {
+		burst = v
+	}
+	return perMinute, burst
+}
+
+// setLimits changes the limits for new and existing clients
+func (l *ipRateLimiter) setLimits(perMinute, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rate = rate.Limit(float64(perMinute) / 60.0)
+	l.burst = burst
+	for _, entry := range l.limiters {
+		entry.limiter.SetLimit(l.rate)
+		entry.limiter.SetBurst(l.burst)
+	}
 }
 
 // getLimiter returns the rate limiter for a given IP, creating one if needed
@@ -75,8 +103,18 @@ func (l *ipRateLimiter) cleanup() {
 	}
 }
 
-// allow checks if a request from the given IP is allowed
+// allow checks if a request from the given IP is allowed. With a shared
+// store the count is cluster-wide; if the store is unreachable this server's
+// own limits apply until it's back.
 func (l *ipRateLimiter) allow(ip string) bool {
+	if l.shared != nil {
+		l.mu.RLock()
+		limit, burst := l.rate, l.burst
+		l.mu.RUnlock()
+		if ok, err := l.shared.allow(ip, limit, burst, time.Now()); err == nil {
+			return ok
+		}
+	}
 	return l.getLimiter(ip).Allow()
 }
 
@@ -108,15 +146,11 @@ func getClientIP(r *http.Request) string {
 	return ip
 }
 
-// rateLimitMiddleware wraps a handler with rate limiting
-func rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+// rateLimit wraps a handler with the server's per-IP rate limiting
+func (s *Server) rateLimit(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if limiter == nil {
-			initRateLimiter()
-		}
-
 		ip := getClientIP(r)
-		if !limiter.allow(ip) {
+		if !s.limiter.allow(ip) {
 			w.Header().Set("Retry-After", "60")
 			writeError(w, http.StatusTooManyRequests, "rate_limited", "Too many requests, please try again later")
 			return
@@ -7,9 +7,7 @@ import (
 )
 
 func TestRateLimiter(t *testing.T) {
-	// Reset limiter for clean test
-	limiter = nil
-	initRateLimiter()
+	limiter := newRateLimiter(rateLimitPerMinute, rateLimitBurst)
 
 	ip := "192.168.1.100"
 
@@ -27,9 +25,7 @@ func TestRateLimiter(t *testing.T) {
 }
 
 func TestRateLimiterDifferentIPs(t *testing.T) {
-	// Reset limiter for clean test
-	limiter = nil
-	initRateLimiter()
+	limiter := newRateLimiter(rateLimitPerMinute, rateLimitBurst)
 
 	ip1 := "192.168.1.1"
 	ip2 := "192.168.1.2"
@@ -108,11 +104,10 @@ func TestGetClientIP(t *testing.T) {
 }
 
 func TestRateLimitMiddleware(t *testing.T) {
-	// Reset limiter for clean test
-	limiter = nil
+	s := newServer(ServerConfig{Cache: newTestCache(t)})
 
 	// Create a simple handler that returns 200
-	handler := rateLimitMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	handler := s.rateLimit(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
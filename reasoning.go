@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// defaultReasoningMaxTokens leaves room for a reasoning model's hidden thinking,
+// which counts against the completion limit, on top of the summary itself
+const defaultReasoningMaxTokens = 16000
+
+var (
+	llmReasoning       string
+	llmReasoningEffort string
+)
+
+// reasoningModelPrefixes match models that think before answering. They take
+// max_completion_tokens instead of max_tokens and reject sampling parameters.
+// Provider prefixes such as "openai/" are ignored when matching.
+var reasoningModelPrefixes = []string{"o1", "o3", "o4", "gpt-5", "deepseek-r1", "qwq"}
+
+// reasoningEfforts are the accepted reasoning_effort values
+var reasoningEfforts = []string{"low", "medium", "high"}
+
+// thinkingBlock matches reasoning that some models (DeepSeek R1, QwQ) inline
+// in their reply instead of returning it separately
+var thinkingBlock = regexp.MustCompile(`(?s)<(think|thinking)>.*?</(think|thinking)>`)
+
+// modelCapabilities shapes the chat completion request for a model
+type modelCapabilities struct {
+	Reasoning       bool   // send max_completion_tokens and no temperature/top_p
+	ReasoningEffort string // reasoning_effort to request, "" for the provider default
+}
+
+// isReasoningModel guesses from the name whether a model is a reasoning model
+func isReasoningModel(model string) bool {
+	name := strings.ToLower(model)
+	if _, after, ok := strings.Cut(name, "/"); ok {
+		name = after
+	}
+	for _, prefix := range reasoningModelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// capabilitiesConfig returns the capabilities for model: --reasoning
+// (YTSUMMARY_REASONING) is auto, on or off, and --reasoning-effort
+// (YTSUMMARY_REASONING_EFFORT) sets the thinking budget of reasoning models
+func capabilitiesConfig(model string) (modelCapabilities, error) {
+	var caps modelCapabilities
+	switch mode := strings.ToLower(getConfig(llmReasoning, "YTSUMMARY_REASONING")); mode {
+	case "", "auto":
+		caps.Reasoning = isReasoningModel(model)
+	case "on", "true":
+		caps.Reasoning = true
+	case "off", "false":
+	default:
+		return caps, fmt.Errorf("invalid reasoning mode %q (use auto, on or off)", mode)
+	}
+
+	effort := strings.ToLower(getConfig(llmReasoningEffort, "YTSUMMARY_REASONING_EFFORT"))
+	if effort != "" && !slices.Contains(reasoningEfforts, effort) {
+		return caps, fmt.Errorf("invalid reasoning effort %q (use %s)", effort, strings.Join(reasoningEfforts, ", "))
+	}
+	if caps.Reasoning {
+		caps.ReasoningEffort = effort
+	}
+	return caps, nil
+}
+
+// applyTo sets the token limit and sampling fields of a chat completion request
+func (caps modelCapabilities) applyTo(reqBody map[string]any, params GenerationParams) {
+	if !caps.Reasoning {
+		reqBody["max_tokens"] = params.MaxTokens
+		if params.Temperature != nil {
+			reqBody["temperature"] = *params.Temperature
+		}
+		if params.TopP != nil {
+			reqBody["top_p"] = *params.TopP
+		}
+		return
+	}
+
+	reqBody["max_completion_tokens"] = params.MaxTokens
+	if caps.ReasoningEffort != "" {
+		reqBody["reasoning_effort"] = caps.ReasoningEffort
+	}
+}
+
+// maxTokensDefault is the completion limit when none is configured
+func (caps modelCapabilities) maxTokensDefault() int {
+	if caps.Reasoning {
+		return defaultReasoningMaxTokens
+	}
+	return defaultMaxTokens
+}
+
+// stripReasoning removes inline <think> blocks from a reply
+func stripReasoning(content string) string {
+	if !strings.Contains(content, "</think") {
+		return content
+	}
+	return strings.TrimSpace(thinkingBlock.ReplaceAllString(content, ""))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsReasoningModel(t *testing.T) {
+	tests := map[string]bool{
+		"o1":                          true,
+		"o3-mini":                     true,
+		"openai/o4-mini":              true,
+		"openai/gpt-5":                true,
+		"deepseek/deepseek-r1":        true,
+		"google/gemini-2.0-flash-001": false,
+		"openai/gpt-4o":               false,
+		"anthropic/claude-3.5-sonnet": false,
+	}
+	for model, want := range tests {
+		if got := isReasoningModel(model); got != want {
+			t.Errorf("isReasoningModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestCapabilitiesConfig(t *testing.T) {
+	t.Setenv("YTSUMMARY_REASONING", "")
+	t.Setenv("YTSUMMARY_REASONING_EFFORT", "high")
+
+	caps, err := capabilitiesConfig("openai/o3-mini")
+	if err != nil {
+		t.Fatalf("capabilitiesConfig() error = %v", err)
+	}
+	if !caps.Reasoning || caps.ReasoningEffort != "high" {
+		t.Errorf("caps = %+v, want detected reasoning with high effort", caps)
+	}
+
+	// Effort only applies to reasoning models
+	if caps, _ := capabilitiesConfig("openai/gpt-4o"); caps.Reasoning || caps.ReasoningEffort != "" {
+		t.Errorf("caps = %+v, want no reasoning", caps)
+	}
+
+	t.Setenv("YTSUMMARY_REASONING", "on")
+	if caps, _ := capabilitiesConfig("my-local-thinker"); !caps.Reasoning {
+		t.Error("YTSUMMARY_REASONING=on should force reasoning")
+	}
+	t.Setenv("YTSUMMARY_REASONING", "off")
+	if caps, _ := capabilitiesConfig("o1"); caps.Reasoning {
+		t.Error("YTSUMMARY_REASONING=off should disable detection")
+	}
+
+	t.Setenv("YTSUMMARY_REASONING", "sometimes")
+	if _, err := capabilitiesConfig("o1"); err == nil {
+		t.Error("expected error for invalid reasoning mode")
+	}
+	t.Setenv("YTSUMMARY_REASONING", "")
+	t.Setenv("YTSUMMARY_REASONING_EFFORT", "extreme")
+	if _, err := capabilitiesConfig("o1"); err == nil {
+		t.Error("expected error for invalid reasoning effort")
+	}
+}
+
+func TestStripReasoning(t *testing.T) {
+	tests := map[string]string{
+		"<think>\nThe user wants a summary.\n</think>\n\nThe video covers X.": "The video covers X.",
+		"<thinking>plan</thinking>Summary":                                    "Summary",
+		"No reasoning here, just <b>markup</b>.":                              "No reasoning here, just <b>markup</b>.",
+	}
+	for in, want := range tests {
+		if got := stripReasoning(in); got != want {
+			t.Errorf("stripReasoning(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOpenAIClientReasoningModel(t *testing.T) {
+	var body map[string]any
+	reply := `{"choices":[{"message":{"content":"<think>hmm</think>A summary"},"finish_reason":"stop"}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(reply))
+	}))
+	defer srv.Close()
+
+	temp := 0.2
+	client := &openAIClient{
+		apiKey: "k", model: "openai/o3-mini", apiURL: srv.URL,
+		params: GenerationParams{Temperature: &temp},
+		caps:   modelCapabilities{Reasoning: true, ReasoningEffort: "low"},
+	}
+
+	got, err := client.Complete("s", "t", GenerationParams{})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if got != "A summary" {
+		t.Errorf("Complete() = %q, want reasoning stripped", got)
+	}
+	if _, ok := body["max_tokens"]; ok {
+		t.Error("reasoning models should get max_completion_tokens, not max_tokens")
+	}
+	if body["max_completion_tokens"] != float64(defaultReasoningMaxTokens) {
+		t.Errorf("max_completion_tokens = %v, want %d", body["max_completion_tokens"], defaultReasoningMaxTokens)
+	}
+	if _, ok := body["temperature"]; ok {
+		t.Error("temperature should not be sent to reasoning models")
+	}
+	if body["reasoning_effort"] != "low" {
+		t.Errorf("reasoning_effort = %v, want low", body["reasoning_effort"])
+	}
+
+	// Thinking that exhausts the budget is reported, not returned as an empty summary
+	reply = `{"choices":[{"message":{"content":""},"finish_reason":"length"}]}`
+	if _, err := client.Complete("s", "t", GenerationParams{}); err == nil || !strings.Contains(err.Error(), "max-tokens") {
+		t.Errorf("Complete() error = %v, want a hint to raise --max-tokens", err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// headerTracker notes whether a handler has started its response, after which
+// a panic can no longer be answered with an error
+type headerTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *headerTracker) WriteHeader(code int) {
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *headerTracker) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+// recoveryMiddleware answers a request whose handler panicked with a 500
+// internal_error carrying the request ID, instead of dropping the connection.
+// The panic is logged with its stack and counted for /health and the
+// dashboard.
+func (s *Server) recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracked := &headerTracker{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				// Deliberate aborts keep net/http's handling
+				panic(v)
+			}
+			reqCtx := getRequestContext(r)
+			s.logPanic("handler panicked", v,
+				slog.String("request_id", reqCtx.ID),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("video_id", reqCtx.video()),
+			)
+			if tracked.wroteHeader {
+				return
+			}
+			writeJSON(tracked, http.StatusInternalServerError, ErrorResponse{
+				Error:     ErrInternal,
+				Message:   "Internal server error",
+				VideoID:   reqCtx.video(),
+				RequestID: reqCtx.ID,
+			})
+		}()
+		next.ServeHTTP(tracked, r)
+	})
+}
+
+// recoverPanic is deferred at the top of goroutines the server starts, so a
+// panic in background work is logged and counted rather than crashing it
+func (s *Server) recoverPanic(msg string, attrs ...any) {
+	if v := recover(); v != nil {
+		s.logPanic(msg, v, attrs...)
+	}
+}
+
+// logPanic logs a recovered panic with its stack and counts it
+func (s *Server) logPanic(msg string, v any, attrs ...any) {
+	s.activity.panics.Add(1)
+	attrs = append(attrs, slog.String("panic", fmt.Sprint(v)), slog.String("stack", string(debug.Stack())))
+	logError(msg, attrs...)
+}
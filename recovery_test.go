@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	s := newServer(ServerConfig{
+		Cache: newTestCache(t),
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Injected", Transcript: "One. Two."}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			var m map[string]int
+			m["boom"]++ // nil map write
+			return "", nil
+		},
+	})
+	handler := s.Handler()
+
+	req := httptest.NewRequest("GET", "/v1/summarize?url=dQw4w9WgXcQ", nil)
+	req.RemoteAddr = "192.0.2.26:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("body isn't JSON: %v", err)
+	}
+	if w.Code != http.StatusInternalServerError || resp.Error != ErrInternal || resp.VideoID != "dQw4w9WgXcQ" {
+		t.Errorf("status %d, response %+v; want a 500 internal_error", w.Code, resp)
+	}
+	if id := w.Header().Get("X-Request-ID"); id == "" || resp.RequestID != id {
+		t.Errorf("request_id = %q, X-Request-ID = %q", resp.RequestID, id)
+	}
+
+	// The server keeps serving and counts the panic
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	var health HealthResponse
+	json.NewDecoder(w.Body).Decode(&health)
+	if w.Code != http.StatusOK || health.Panics != 1 {
+		t.Errorf("health: status %d, panics %d; want 200 and 1", w.Code, health.Panics)
+	}
+	if errs := s.dashboardSnapshot().Errors; len(errs) != 1 || errs[0].Status != http.StatusInternalServerError {
+		t.Errorf("dashboard errors = %+v, want the 500", errs)
+	}
+}
+
+func TestRecoverPanicInGoroutine(t *testing.T) {
+	s := newServer(ServerConfig{Cache: newTestCache(t)})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer s.recoverPanic("background work panicked")
+		panic("boom")
+	}()
+	wg.Wait()
+	if got := s.activity.panics.Load(); got != 1 {
+		t.Errorf("panics = %d, want 1", got)
+	}
+}
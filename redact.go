@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// redacted replaces secrets in logged and returned text
+const redacted = "[REDACTED]"
+
+// Flag for --redact-patterns
+var redactPatterns []string
+
+// redaction replaces what re matches with replacement, which may keep
+// parts of the match with ${1}
+type redaction struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// hideAfter redacts what follows the first group of pattern, so
+// "Bearer [REDACTED]" still says what was hidden
+func hideAfter(pattern string) redaction {
+	return redaction{regexp.MustCompile(pattern), "${1}" + redacted}
+}
+
+// hideAll redacts the whole match of pattern
+func hideAll(pattern string) redaction {
+	return redaction{regexp.MustCompile(pattern), redacted}
+}
+
+// builtinRedactions match credentials upstream services echo back in error
+// bodies and tool output
+var builtinRedactions = []redaction{
+	// OpenAI and OpenRouter keys
+	hideAll(`\bsk-[A-Za-z0-9_-]{16,}`),
+	// Google API keys and OAuth access tokens
+	hideAll(`\bAIza[0-9A-Za-z_-]{35}`),
+	hideAll(`\bya29\.[0-9A-Za-z._-]{20,}`),
+	// AWS access key IDs
+	hideAll(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// Authorization, API key and cookie headers
+	hideAfter(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/=-]{8,}`),
+	hideAfter(`(?i)(\bx-api-key:\s*)\S+`),
+	hideAfter(`(?i)(\b(?:set-)?cookie:\s*)[^\r\n]+`),
+	// Credentials in URLs and form bodies: parameters and passwords
+	hideAfter(`(?i)(\b(?:key|api_key|apikey|access_token|token|sig|signature)=)[^&\s"']+`),
+	{regexp.MustCompile(`(://[^/\s:@]+:)[^@\s/]+@`), "${1}" + redacted + "@"},
+}
+
+// customRedactions are the compiled --redact-patterns
+// (YTSUMMARY_REDACT_PATTERNS), which hide their whole match;
+// configureRedaction sets them
+var customRedactions atomic.Pointer[[]redaction]
+
+// configureRedaction compiles --redact-patterns and YTSUMMARY_REDACT_PATTERNS,
+// a regular expression per line, on top of the built-in ones
+func configureRedaction() error {
+	patterns := append([]string(nil), redactPatterns...)
+	for _, line := range strings.Split(os.Getenv("YTSUMMARY_REDACT_PATTERNS"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	compiled := make([]redaction, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, redaction{re, redacted})
+	}
+	customRedactions.Store(&compiled)
+	return nil
+}
+
+// configuredSecrets are the keys ytsummary itself was given; they are hidden
+// wherever they appear, whatever their format
+func configuredSecrets() []string {
+	candidates := []string{
+		getConfig(llmAPIKey, "YTSUMMARY_API_KEY"),
+		getConfig(serverAPIKey, "YTSUMMARY_SERVER_API_KEY"),
+		getConfig(youtubeAPIKey, "YTSUMMARY_YOUTUBE_API_KEY"),
+		getConfig(oauthClientSecret, "YTSUMMARY_OAUTH_CLIENT_SECRET"),
+		os.Getenv("YTSUMMARY_SECRET_KEY"),
+		os.Getenv("YTSUMMARY_NEW_SECRET_KEY"),
+		os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	}
+	candidates = append(candidates, loadedKeyringSecrets()...)
+	if keys, err := apiKeysFromEnv(); err == nil {
+		for key := range keys {
+			candidates = append(candidates, key)
+		}
+	}
+	var secrets []string
+	for _, s := range candidates {
+		// Short values would redact ordinary words
+		if len(s) >= 8 {
+			secrets = append(secrets, s)
+		}
+	}
+	return secrets
+}
+
+// redact hides secrets in text bound for a log or a response: the configured
+// keys, well-known credential formats and any --redact-patterns
+func redact(text string) string {
+	if text == "" {
+		return text
+	}
+	for _, secret := range configuredSecrets() {
+		text = strings.ReplaceAll(text, secret, redacted)
+	}
+	redactions := builtinRedactions
+	if custom := customRedactions.Load(); custom != nil {
+		redactions = append(redactions[:len(redactions):len(redactions)], *custom...)
+	}
+	for _, r := range redactions {
+		text = r.re.ReplaceAllString(text, r.replacement)
+	}
+	return text
+}
+
+// redactingHandler redacts the message and string attributes of every log
+// record before passing it on
+type redactingHandler struct {
+	slog.Handler
+}
+
+func (h redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, out)
+}
+
+func (h redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	for i, a := range attrs {
+		attrs[i] = redactAttr(a)
+	}
+	return redactingHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{h.Handler.WithGroup(name)}
+}
+
+// redactAttr redacts a log attribute's text, inside groups too
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redact(v.String()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		redactedAttrs := make([]any, len(attrs))
+		for i, ga := range attrs {
+			redactedAttrs[i] = redactAttr(ga)
+		}
+		return slog.Group(a.Key, redactedAttrs...)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, redact(err.Error()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisDialTimeout bounds connecting to Redis
+const redisDialTimeout = 10 * time.Second
+
+// redisCommandTimeout bounds each command's round trip, so a Redis that stalls
+// without closing the connection fails commands instead of hanging them and
+// everything waiting behind them
+const redisCommandTimeout = 5 * time.Second
+
+// errRedisNil is a nil reply, e.g. BLMOVE timing out on an empty list
+var errRedisNil = errors.New("redis: nil reply")
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisConn is a minimal Redis client speaking RESP2: enough for list queues
+// and counters without a driver dependency. Commands on one connection run
+// one at a time.
+type redisConn struct {
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// dialRedis connects to redis://[user:password@]host[:port][/db]; rediss://
+// uses TLS
+func dialRedis(rawURL string) (*redisConn, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid Redis URL %q (use redis:// or rediss://)", redactURL(rawURL))
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	var conn net.Conn
+	if u.Scheme == "rediss" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %w", addr, err)
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+
+	if password, ok := u.User.Password(); ok {
+		args := []string{"AUTH", password}
+		if user := u.User.Username(); user != "" {
+			args = []string{"AUTH", user, password}
+		}
+		if _, err := c.do(args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" && db != "0" {
+		if _, err := c.do("SELECT", db); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// redactURL hides the password in a connection URL for messages
+func redactURL(rawURL string) string {
+	if u, err := neturl.Parse(rawURL); err == nil {
+		return u.Redacted()
+	}
+	return rawURL
+}
+
+// do sends a command and returns its reply: a string, int64, []any, or nil
+// for a nil reply (reported as errRedisNil). Error replies become redisError.
+func (c *redisConn) do(args ...string) (any, error) {
+	return c.doWithin(redisCommandTimeout, args...)
+}
+
+// doWithin is do for commands that may take up to timeout, such as a
+// blocking BLMOVE. Past it the command fails and, its reply possibly still to
+// come, the connection can't be used again.
+func (c *redisConn) doWithin(timeout time.Duration, args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	defer c.conn.SetDeadline(time.Time{})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+
+	reply, err := readRESP(c.r)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, errRedisNil
+	}
+	return reply, nil
+}
+
+// Close closes the connection
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
+
+// readRESP reads one reply
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad integer reply %q", line)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readRESP(r); err != nil {
+				var rerr redisError
+				if !errors.As(err, &rerr) {
+					return nil, err
+				}
+				items[i] = rerr
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// redisClient is a redisConn for long-running servers: after a connection
+// error it redials on the next command instead of staying broken
+type redisClient struct {
+	url string
+
+	mu   sync.Mutex
+	conn *redisConn // nil until the next redial
+}
+
+// newRedisClient connects straight away so a bad URL is reported at startup
+func newRedisClient(rawURL string) (*redisClient, error) {
+	conn, err := dialRedis(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &redisClient{url: rawURL, conn: conn}, nil
+}
+
+// do runs a command like redisConn.do, redialing first if needed
+func (c *redisClient) do(args ...string) (any, error) {
+	return c.doWithin(redisCommandTimeout, args...)
+}
+
+// doWithin is do for commands that may take up to timeout, like
+// redisConn.doWithin
+func (c *redisClient) doWithin(timeout time.Duration, args ...string) (any, error) {
+	c.mu.Lock()
+	conn := c.conn
+	if conn == nil {
+		var err error
+		if conn, err = dialRedis(c.url); err != nil {
+			c.mu.Unlock()
+			return nil, err
+		}
+		c.conn = conn
+	}
+	c.mu.Unlock()
+
+	reply, err := conn.doWithin(timeout, args...)
+	var rerr redisError
+	if err != nil && !errors.Is(err, errRedisNil) && !errors.As(err, &rerr) {
+		c.mu.Lock()
+		if c.conn == conn {
+			conn.Close()
+			c.conn = nil
+		}
+		c.mu.Unlock()
+	}
+	return reply, err
+}
+
+// Close closes the connection
+func (c *redisClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-process Redis server supporting the commands ytsummary
+// uses. It speaks just enough RESP for redisConn.
+type fakeRedis struct {
+	addr     string
+	password string
+
+	// failing answers every command with an error, as a Redis still
+	// loading its dataset does
+	failing atomic.Bool
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	lists    map[string][]string
+	counters map[string]int64
+	values   map[string]string
+	expires  map[string]time.Time
+}
+
+// newFakeRedis starts a server; a non-empty password requires AUTH
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeRedis{
+		addr:     ln.Addr().String(),
+		password: password,
+		lists:    map[string][]string{},
+		counters: map[string]int64{},
+		values:   map[string]string{},
+		expires:  map[string]time.Time{},
+	}
+	r.cond = sync.NewCond(&r.mu)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+// url returns the server's redis:// URL
+func (r *fakeRedis) url() string {
+	if r.password != "" {
+		return "redis://:" + r.password + "@" + r.addr
+	}
+	return "redis://" + r.addr
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	authed := r.password == ""
+	for {
+		reply, err := readRESP(br)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		var args []string
+		for _, item := range items {
+			s, _ := item.(string)
+			args = append(args, s)
+		}
+		if len(args) == 0 {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		if !authed && cmd != "AUTH" {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		if r.failing.Load() {
+			fmt.Fprint(conn, "-LOADING Redis is loading the dataset in memory\r\n")
+			continue
+		}
+		switch cmd {
+		case "AUTH":
+			if args[len(args)-1] != r.password {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authed = true
+			fmt.Fprint(conn, "+OK\r\n")
+		case "PING", "SELECT":
+			fmt.Fprint(conn, "+OK\r\n")
+		case "RPUSH":
+			r.mu.Lock()
+			r.lists[args[1]] = append(r.lists[args[1]], args[2:]...)
+			n := len(r.lists[args[1]])
+			r.cond.Broadcast()
+			r.mu.Unlock()
+			fmt.Fprintf(conn, ":%d\r\n", n)
+		case "LPOP":
+			if v, ok := r.pop(args[1], 0); ok {
+				writeBulk(conn, v)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		case "BLPOP":
+			seconds, _ := strconv.Atoi(args[len(args)-1])
+			if v, ok := r.pop(args[1], time.Duration(seconds)*time.Second); ok {
+				fmt.Fprint(conn, "*2\r\n")
+				writeBulk(conn, args[1])
+				writeBulk(conn, v)
+			} else {
+				fmt.Fprint(conn, "*-1\r\n")
+			}
+		case "BLMOVE":
+			// BLMOVE source destination LEFT RIGHT seconds
+			seconds, _ := strconv.Atoi(args[len(args)-1])
+			if v, ok := r.pop(args[1], time.Duration(seconds)*time.Second); ok {
+				r.mu.Lock()
+				r.lists[args[2]] = append(r.lists[args[2]], v)
+				r.mu.Unlock()
+				writeBulk(conn, v)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		case "LREM":
+			// LREM key count value, for a positive count
+			count, _ := strconv.Atoi(args[2])
+			r.mu.Lock()
+			n := r.removeLocked(args[1], args[3], count)
+			r.mu.Unlock()
+			fmt.Fprintf(conn, ":%d\r\n", n)
+		case "LRANGE":
+			// Only LRANGE key 0 -1
+			r.mu.Lock()
+			items := append([]string(nil), r.lists[args[1]]...)
+			r.mu.Unlock()
+			fmt.Fprintf(conn, "*%d\r\n", len(items))
+			for _, v := range items {
+				writeBulk(conn, v)
+			}
+		case "DEL":
+			r.mu.Lock()
+			_, ok := r.values[args[1]]
+			delete(r.values, args[1])
+			delete(r.expires, args[1])
+			r.mu.Unlock()
+			if ok {
+				fmt.Fprint(conn, ":1\r\n")
+			} else {
+				fmt.Fprint(conn, ":0\r\n")
+			}
+		case "SET":
+			// SET key value [NX] [PX ms]
+			r.mu.Lock()
+			r.expireLocked(args[1])
+			_, exists := r.values[args[1]]
+			nx := len(args) > 3 && strings.EqualFold(args[3], "NX")
+			if nx && exists {
+				r.mu.Unlock()
+				fmt.Fprint(conn, "$-1\r\n")
+				continue
+			}
+			r.values[args[1]] = args[2]
+			delete(r.expires, args[1])
+			for i := 3; i+1 < len(args); i++ {
+				if strings.EqualFold(args[i], "PX") {
+					ms, _ := strconv.Atoi(args[i+1])
+					r.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+				}
+			}
+			r.mu.Unlock()
+			fmt.Fprint(conn, "+OK\r\n")
+		case "GET":
+			r.mu.Lock()
+			r.expireLocked(args[1])
+			v, ok := r.values[args[1]]
+			r.mu.Unlock()
+			if ok {
+				writeBulk(conn, v)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		case "EVAL":
+			// Only ytsummary's scripts are supported: EVAL script 1 key arg
+			if args[1] == redisRateLimitScript {
+				ms, _ := strconv.Atoi(args[4])
+				r.mu.Lock()
+				r.expireLocked(args[3])
+				r.counters[args[3]]++
+				n := r.counters[args[3]]
+				if n == 1 {
+					r.expires[args[3]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+				}
+				r.mu.Unlock()
+				fmt.Fprintf(conn, ":%d\r\n", n)
+				continue
+			}
+			if args[1] == redisRequeueScript {
+				// KEYS processing, list, lease; ARGV job
+				r.mu.Lock()
+				r.expireLocked(args[5])
+				n := 0
+				if _, leased := r.values[args[5]]; !leased && r.removeLocked(args[3], args[6], 1) == 1 {
+					r.lists[args[4]] = append([]string{args[6]}, r.lists[args[4]]...)
+					r.cond.Broadcast()
+					n = 1
+				}
+				r.mu.Unlock()
+				fmt.Fprintf(conn, ":%d\r\n", n)
+				continue
+			}
+			if args[1] == redisRenewScript {
+				ms, _ := strconv.Atoi(args[5])
+				r.mu.Lock()
+				r.expireLocked(args[3])
+				n := 0
+				if v, ok := r.values[args[3]]; ok && v == args[4] {
+					r.expires[args[3]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+					n = 1
+				}
+				r.mu.Unlock()
+				fmt.Fprintf(conn, ":%d\r\n", n)
+				continue
+			}
+			// The compare-and-delete unlock script
+			r.mu.Lock()
+			r.expireLocked(args[3])
+			n := 0
+			if v, ok := r.values[args[3]]; ok && v == args[4] {
+				delete(r.values, args[3])
+				n = 1
+			}
+			r.mu.Unlock()
+			fmt.Fprintf(conn, ":%d\r\n", n)
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+	}
+}
+
+func writeBulk(conn net.Conn, s string) {
+	fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(s), s)
+}
+
+// expireLocked drops key if its expiry has passed
+func (r *fakeRedis) expireLocked(key string) {
+	if exp, ok := r.expires[key]; ok && !time.Now().Before(exp) {
+		delete(r.counters, key)
+		delete(r.values, key)
+		delete(r.expires, key)
+	}
+}
+
+// removeLocked removes up to count occurrences of v from a list
+func (r *fakeRedis) removeLocked(list, v string, count int) int {
+	var kept []string
+	n := 0
+	for _, item := range r.lists[list] {
+		if item == v && n < count {
+			n++
+			continue
+		}
+		kept = append(kept, item)
+	}
+	r.lists[list] = kept
+	return n
+}
+
+// pop takes the head of a list, waiting up to wait for one to arrive
+func (r *fakeRedis) pop(list string, wait time.Duration) (string, bool) {
+	deadline := time.Now().Add(wait)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for len(r.lists[list]) == 0 {
+		if !time.Now().Before(deadline) {
+			return "", false
+		}
+		// Wake periodically to check the deadline
+		timer := time.AfterFunc(10*time.Millisecond, r.cond.Broadcast)
+		r.cond.Wait()
+		timer.Stop()
+	}
+	v := r.lists[list][0]
+	r.lists[list] = r.lists[list][1:]
+	return v, true
+}
+
+func TestRedisConn(t *testing.T) {
+	srv := newFakeRedis(t, "hunter2")
+
+	if _, err := dialRedis("redis://:wrong@" + srv.addr); err == nil {
+		t.Error("dialRedis() with a wrong password succeeded")
+	}
+	c, err := dialRedis(srv.url() + "/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if n, err := c.do("RPUSH", "l", "a", "b\r\nc"); err != nil || n != int64(2) {
+		t.Fatalf("RPUSH = %v, %v", n, err)
+	}
+	if v, err := c.do("LPOP", "l"); err != nil || v != "a" {
+		t.Errorf("LPOP = %v, %v", v, err)
+	}
+	if v, err := c.do("BLPOP", "l", "1"); err != nil || fmt.Sprint(v) != "[l b\r\nc]" {
+		t.Errorf("BLPOP = %q, %v", v, err)
+	}
+	if _, err := c.do("LPOP", "l"); err != errRedisNil {
+		t.Errorf("LPOP on an empty list: err = %v, want errRedisNil", err)
+	}
+	if _, err := c.do("NOPE"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("unknown command: err = %v", err)
+	}
+
+	if _, err := dialRedis("http://" + srv.addr); err == nil {
+		t.Error("dialRedis() accepted an http:// URL")
+	}
+}
+
+func TestRedisConnTimeout(t *testing.T) {
+	// A server that accepts commands and never answers, like a stalled Redis
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				bufio.NewReader(conn).WriteTo(io.Discard)
+			}()
+		}
+	}()
+
+	c, err := dialRedis("redis://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	start := time.Now()
+	if _, err := c.doWithin(50*time.Millisecond, "INCR", "k"); err == nil || errors.Is(err, errRedisNil) {
+		t.Errorf("stalled server: err = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("command took %s to time out", elapsed)
+	}
+}
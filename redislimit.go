@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// redisRateLimitPrefix namespaces the limiter's counters
+const redisRateLimitPrefix = "ytsummary:ratelimit:"
+
+// redisRateLimitScript counts a request and, on a window's first, sets the
+// counter's expiry, in one round trip
+const redisRateLimitScript = `local n = redis.call("incr", KEYS[1]) if n == 1 then redis.call("pexpire", KEYS[1], ARGV[1]) end return n`
+
+// redisRateLimiter applies the per-IP limits across every server sharing one
+// Redis, so N replicas still allow each client the configured rate rather
+// than N times it. Each client may make burst requests per window of
+// burst/rate: the same average rate and largest burst as the in-memory
+// limiter, give or take a burst straddling two windows.
+type redisRateLimiter struct {
+	client *redisClient
+
+	mu      sync.Mutex
+	failing bool
+}
+
+// newRedisRateLimiter connects to the Redis at rawURL, failing fast so a
+// misconfigured replica doesn't start with only local limits
+func newRedisRateLimiter(rawURL string) (*redisRateLimiter, error) {
+	client, err := newRedisClient(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit store: %w", err)
+	}
+	return &redisRateLimiter{client: client}, nil
+}
+
+// rateLimitWindow is the window in which a client may make burst requests
+func rateLimitWindow(limit rate.Limit, burst int) time.Duration {
+	return time.Duration(float64(burst) / float64(limit) * float64(time.Second))
+}
+
+// allow counts a request from ip in the current window and reports whether
+// it is within the limit
+func (l *redisRateLimiter) allow(ip string, limit rate.Limit, burst int, now time.Time) (bool, error) {
+	window := rateLimitWindow(limit, burst)
+	key := fmt.Sprintf("%s%s:%d", redisRateLimitPrefix, ip, now.UnixNano()/int64(window))
+
+	// Counters outlive their window a little so clock skew between replicas
+	// can't drop a live one
+	reply, err := l.client.do("EVAL", redisRateLimitScript, "1", key, fmt.Sprint((2 * window).Milliseconds()))
+	l.reportHealth(err)
+	if err != nil {
+		return false, err
+	}
+	n, _ := reply.(int64)
+	return n <= int64(burst), nil
+}
+
+// reportHealth logs when the store becomes unavailable and when it's back,
+// rather than on every request in between
+func (l *redisRateLimiter) reportHealth(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	url := redactURL(l.client.url)
+	switch {
+	case err != nil && !l.failing:
+		l.failing = true
+		logError("rate limit store unavailable, using this server's limits",
+			slog.String("url", url), slog.String("error", err.Error()))
+	case err == nil && l.failing:
+		l.failing = false
+		logInfo("rate limit store reachable again", slog.String("url", url))
+	}
+}
+
+// Close closes the connection
+func (l *redisRateLimiter) Close() error {
+	return l.client.Close()
+}
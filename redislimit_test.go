@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRedisRateLimiterShared(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	// Two replicas sharing one Redis
+	var replicas []*ipRateLimiter
+	for range 2 {
+		shared, err := newRedisRateLimiter(srv.url())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer shared.Close()
+		l := newRateLimiter(rateLimitPerMinute, rateLimitBurst)
+		l.shared = shared
+		replicas = append(replicas, l)
+	}
+
+	ip := "203.0.113.7"
+	for i := 0; i < rateLimitBurst; i++ {
+		if !replicas[i%2].allow(ip) {
+			t.Fatalf("request %d should be allowed (within burst)", i+1)
+		}
+	}
+	for _, l := range replicas {
+		if l.allow(ip) {
+			t.Error("request after the cluster-wide burst should be rate limited")
+		}
+	}
+	if !replicas[0].allow("203.0.113.8") {
+		t.Error("a different IP should be allowed")
+	}
+	if st := replicas[0].state(); !st.Shared {
+		t.Error("state().Shared = false, want true")
+	}
+}
+
+func TestRedisRateLimiterWindow(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	l, err := newRedisRateLimiter(srv.url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	limit := newRateLimiter(60, 2).rate // 2 requests per 2s window
+	if w := rateLimitWindow(limit, 2); w != 2*time.Second {
+		t.Fatalf("rateLimitWindow() = %v, want 2s", w)
+	}
+	start := time.Unix(1_700_000_000, 0)
+	for i, want := range []bool{true, true, false} {
+		if ok, err := l.allow("ip", limit, 2, start); err != nil || ok != want {
+			t.Errorf("request %d: allow() = %v, %v; want %v", i+1, ok, err, want)
+		}
+	}
+	if ok, err := l.allow("ip", limit, 2, start.Add(2*time.Second)); err != nil || !ok {
+		t.Errorf("next window: allow() = %v, %v; want true", ok, err)
+	}
+
+	// Counters expire with their window
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.counters) != 2 || len(srv.expires) != 2 {
+		t.Errorf("counters %v, expiries %v", srv.counters, srv.expires)
+	}
+}
+
+func TestRedisRateLimiterFallback(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	shared, err := newRedisRateLimiter(srv.url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Redis goes away: the next redial fails
+	shared.Close()
+	shared.client.url = "redis://127.0.0.1:1"
+
+	l := newRateLimiter(rateLimitPerMinute, rateLimitBurst)
+	l.shared = shared
+	ip := "203.0.113.9"
+	for i := 0; i < rateLimitBurst; i++ {
+		if !l.allow(ip) {
+			t.Fatalf("request %d should be allowed by the local limiter", i+1)
+		}
+	}
+	if l.allow(ip) {
+		t.Error("local limits should still apply while Redis is down")
+	}
+}
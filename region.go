@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// youtubeRegion is the --region hint: the country YouTube should serve a
+// geo-restricted video for
+var youtubeRegion string
+
+// errGeoRestricted is returned for videos YouTube won't play in the region
+// the request came from
+var errGeoRestricted = errors.New("video is not available in this region")
+
+// playerLanguage is the innertube hl sent with every player request. Keeping
+// it English keeps playability reasons matchable by checkPlayability.
+const playerLanguage = "en"
+
+var regionCode = regexp.MustCompile(`^[A-Za-z]{2}$`)
+
+// regionHint returns --region (YTSUMMARY_REGION) as an upper-case ISO 3166
+// country code, or "" when none is set
+func regionHint() (string, error) {
+	region := strings.TrimSpace(getConfig(youtubeRegion, "YTSUMMARY_REGION"))
+	if region == "" {
+		return "", nil
+	}
+	if !regionCode.MatchString(region) {
+		return "", fmt.Errorf("invalid region %q (use a two-letter country code such as US or GB)", region)
+	}
+	return strings.ToUpper(region), nil
+}
+
+// geoRestrictedReasons are the playability reasons YouTube gives for
+// region-blocked videos
+var geoRestrictedReasons = []string{
+	"not available in your country",
+	"not made this video available in your country",
+	"not available in your location",
+}
+
+// isGeoRestricted reports whether a player response refuses the video
+// because of where the request came from
+func isGeoRestricted(pr *YouTubePlayerResponse) bool {
+	if pr.PlayabilityStatus.Status == "OK" {
+		return false
+	}
+	reason := strings.ToLower(pr.PlayabilityStatus.Reason)
+	for _, r := range geoRestrictedReasons {
+		if strings.Contains(reason, r) {
+			return true
+		}
+	}
+	return false
+}
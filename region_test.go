@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestRegionHint(t *testing.T) {
+	oldRegion := youtubeRegion
+	t.Cleanup(func() { youtubeRegion = oldRegion })
+	t.Setenv("YTSUMMARY_REGION", "")
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"us", "US", false},
+		{" GB ", "GB", false},
+		{"USA", "", true},
+		{"en-GB", "", true},
+	}
+	for _, tt := range tests {
+		youtubeRegion = tt.in
+		got, err := regionHint()
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("regionHint(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestIsGeoRestricted(t *testing.T) {
+	tests := []struct {
+		status, reason string
+		want           bool
+	}{
+		{"UNPLAYABLE", "The uploader has not made this video available in your country", true},
+		{"UNPLAYABLE", "This video is not available in your country", true},
+		{"ERROR", "Video unavailable", false},
+		{"UNPLAYABLE", "Video unavailable", false},
+		{"LOGIN_REQUIRED", "Sign in to confirm your age", false},
+		{"OK", "", false},
+	}
+	for _, tt := range tests {
+		pr := &YouTubePlayerResponse{}
+		pr.PlayabilityStatus.Status = tt.status
+		pr.PlayabilityStatus.Reason = tt.reason
+		if got := isGeoRestricted(pr); got != tt.want {
+			t.Errorf("isGeoRestricted(%s, %q) = %v, want %v", tt.status, tt.reason, got, tt.want)
+		}
+	}
+}
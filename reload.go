@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// envFile is the config file re-read on SIGHUP or POST /admin/reload
+const envFile = ".env"
+
+// processEnv records which variables were set before .env was loaded.
+// Those always win over the file, matching godotenv.Load, so a reload
+// never overrides them.
+var processEnv = func() map[string]bool {
+	keys := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		keys[key] = true
+	}
+	return keys
+}()
+
+// fileEnv is the set of variables most recently applied from envFile
+var (
+	fileEnvMu sync.Mutex
+	fileEnv   = map[string]string{}
+)
+
+// loadEnvFile applies envFile to the environment and returns the names of the
+// variables it changed. Variables removed from the file are unset again.
+func loadEnvFile() ([]string, error) {
+	values, err := readEnvFile()
+	if err != nil {
+		return nil, err
+	}
+	return applyEnvFile(values), nil
+}
+
+// readEnvFile parses envFile, leaving out variables the process environment
+// sets. A missing file reads as empty.
+func readEnvFile() (map[string]string, error) {
+	values, err := godotenv.Read(envFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", envFile, err)
+		}
+		values = map[string]string{}
+	}
+	for key := range values {
+		if processEnv[key] {
+			delete(values, key)
+		}
+	}
+	return values, nil
+}
+
+// applyEnvFile sets the variables in values, unsets those applied from
+// envFile before that values leaves out, and returns the names it changed
+func applyEnvFile(values map[string]string) []string {
+	fileEnvMu.Lock()
+	defer fileEnvMu.Unlock()
+
+	var changed []string
+	for key, value := range values {
+		if old, ok := fileEnv[key]; !ok || old != value {
+			os.Setenv(key, value)
+			changed = append(changed, key)
+		}
+	}
+	for key := range fileEnv {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			changed = append(changed, key)
+		}
+	}
+	fileEnv = values
+
+	sort.Strings(changed)
+	return changed
+}
+
+// envWith returns a getenv that sees the environment as it will be once
+// values are applied
+func envWith(values map[string]string) func(string) string {
+	fileEnvMu.Lock()
+	applied := fileEnv
+	fileEnvMu.Unlock()
+
+	return func(key string) string {
+		if value, ok := values[key]; ok {
+			return value
+		}
+		if _, ok := applied[key]; ok {
+			return ""
+		}
+		return os.Getenv(key)
+	}
+}
+
+// ReloadResponse reports what a config reload applied. Only variable names
+// are returned, never their values.
+type ReloadResponse struct {
+	ReloadedAt         time.Time `json:"reloaded_at"`
+	Changed            []string  `json:"changed"`
+	RateLimitPerMinute int       `json:"rate_limit_per_minute"`
+	RateLimitBurst     int       `json:"rate_limit_burst"`
+	Templates          int       `json:"templates"`
+}
+
+// reloadMu serializes reloads from SIGHUP and the admin endpoint
+var reloadMu sync.Mutex
+
+// reload re-reads .env and applies it to the running server. Secrets, prompt
+// templates, API keys and the experiment are all checked against the new
+// values first; if any check fails, the reload changes nothing. The LLM
+// client and prompt templates are built per request, so they pick up changes
+// on the next one; the YouTube timeout and Redis URLs need a restart.
+func (s *Server) reload() (*ReloadResponse, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	values, err := readEnvFile()
+	if err != nil {
+		return nil, err
+	}
+	getenv := envWith(values)
+
+	// Fetch referenced secrets again, so ones rotated in Vault or SSM apply
+	secrets, err := fetchSecretRefs(getenv)
+	if err != nil {
+		return nil, err
+	}
+	config := func(flagVal, envKey string) string {
+		value := flagVal
+		if value == "" {
+			value = getenv(envKey)
+		}
+		if isSecretRef(value) {
+			return secrets[value]
+		}
+		return value
+	}
+
+	// Catch mistakes now rather than on the next request
+	templates, err := loadTemplateDir(config(templatesDir, "YTSUMMARY_TEMPLATES_DIR"), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load templates: %w", err)
+	}
+	apiKeys, err := parseAPIKeys(config("", "YTSUMMARY_SERVER_API_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	exp, err := experimentFrom(getenv, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := applyEnvFile(values)
+	resolvedSecrets.Store(&secrets)
+
+	perMinute, burst := rateLimitConfig()
+	s.limiter.setLimits(perMinute, burst)
+	s.queue.setSlots(workSlotsConfig())
+
+	// A key given with --server-api-key is fixed for the life of the process
+	if !s.pinAPIKey {
+		s.setAPIKey(getConfig("", "YTSUMMARY_SERVER_API_KEY"))
+	}
+	s.setAPIKeys(apiKeys)
+	s.setRequestDefaults(RequestDefaults{
+		AllowAutoTranslate:   autoTranslateAllowed(),
+		FallbackToTranscript: fallbackToTranscriptDefault(),
+		Experiment:           exp,
+	})
+
+	if changed == nil {
+		changed = []string{}
+	}
+	return &ReloadResponse{
+		ReloadedAt:         time.Now().UTC(),
+		Changed:            changed,
+		RateLimitPerMinute: perMinute,
+		RateLimitBurst:     burst,
+		Templates:          len(templates),
+	}, nil
+}
+
+// logReload reports the outcome of a reload
+func logReload(trigger string, resp *ReloadResponse, err error) {
+	if err != nil {
+		logError("config reload failed", slog.String("trigger", trigger), slog.String("error", err.Error()))
+		return
+	}
+	logInfo("config reloaded",
+		slog.String("trigger", trigger),
+		slog.Any("changed", resp.Changed),
+		slog.Int("rate_limit_per_minute", resp.RateLimitPerMinute),
+		slog.Int("rate_limit_burst", resp.RateLimitBurst),
+		slog.Int("templates", resp.Templates),
+	)
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.reload()
+	logReload("api", resp, err)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// withEnvFile runs the test from a temp dir whose .env has the given contents,
+// restoring the environment applied from the real .env afterwards
+func withEnvFile(t *testing.T, contents string) string {
+	t.Helper()
+
+	fileEnvMu.Lock()
+	saved := fileEnv
+	fileEnvMu.Unlock()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeEnvFile(t, contents)
+
+	t.Cleanup(func() {
+		writeEnvFile(t, "")
+		loadEnvFile()
+		fileEnvMu.Lock()
+		fileEnv = saved
+		fileEnvMu.Unlock()
+		for key, value := range saved {
+			os.Setenv(key, value)
+		}
+	})
+	return dir
+}
+
+func writeEnvFile(t *testing.T, contents string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(".", envFile), []byte(contents), 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", envFile, err)
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	fileEnvMu.Lock()
+	fileEnv = map[string]string{}
+	fileEnvMu.Unlock()
+	withEnvFile(t, "YTSUMMARY_TEST_A=one\nYTSUMMARY_TEST_B=two\n")
+
+	changed, err := loadEnvFile()
+	if err != nil {
+		t.Fatalf("loadEnvFile: %v", err)
+	}
+	if !slices.Equal(changed, []string{"YTSUMMARY_TEST_A", "YTSUMMARY_TEST_B"}) {
+		t.Errorf("changed = %v", changed)
+	}
+
+	// Unchanged values aren't reported; removed ones are unset
+	writeEnvFile(t, "YTSUMMARY_TEST_A=one\n")
+	changed, err = loadEnvFile()
+	if err != nil {
+		t.Fatalf("loadEnvFile: %v", err)
+	}
+	if !slices.Equal(changed, []string{"YTSUMMARY_TEST_B"}) {
+		t.Errorf("changed = %v, want only the removed key", changed)
+	}
+	if got := os.Getenv("YTSUMMARY_TEST_A"); got != "one" {
+		t.Errorf("YTSUMMARY_TEST_A = %q", got)
+	}
+	if _, ok := os.LookupEnv("YTSUMMARY_TEST_B"); ok {
+		t.Error("YTSUMMARY_TEST_B should be unset after removal from .env")
+	}
+}
+
+func TestLoadEnvFileKeepsProcessEnv(t *testing.T) {
+	processEnv["YTSUMMARY_TEST_PINNED"] = true
+	t.Cleanup(func() { delete(processEnv, "YTSUMMARY_TEST_PINNED") })
+	t.Setenv("YTSUMMARY_TEST_PINNED", "from-process")
+	withEnvFile(t, "YTSUMMARY_TEST_PINNED=from-file\n")
+
+	if _, err := loadEnvFile(); err != nil {
+		t.Fatalf("loadEnvFile: %v", err)
+	}
+	if got := os.Getenv("YTSUMMARY_TEST_PINNED"); got != "from-process" {
+		t.Errorf("process environment was overridden by .env: got %q", got)
+	}
+}
+
+func TestRateLimiterSetLimits(t *testing.T) {
+	limiter := newRateLimiter(rateLimitPerMinute, rateLimitBurst)
+
+	ip := "192.168.1.50"
+	for i := 0; i < rateLimitBurst; i++ {
+		limiter.allow(ip)
+	}
+	if limiter.allow(ip) {
+		t.Fatal("burst should be exhausted")
+	}
+
+	// Existing clients get the new limits too
+	limiter.setLimits(120, 10)
+	entry := limiter.getLimiter(ip)
+	if entry.Limit() != 2 || entry.Burst() != 10 {
+		t.Errorf("existing limiter = %v/s burst %d, want 2/s burst 10", entry.Limit(), entry.Burst())
+	}
+	if l := limiter.getLimiter("192.168.1.51"); l.Burst() != 10 {
+		t.Errorf("new limiter burst = %d, want 10", l.Burst())
+	}
+}
+
+func TestE2E_AdminReload(t *testing.T) {
+	h := newE2EHarness(t)
+	withEnvFile(t, "YTSUMMARY_SERVER_API_KEY=rotated-key\nYTSUMMARY_RATE_BURST=7\n")
+
+	resp := h.post("/admin/reload", "", "10.0.20.1")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	body := decodeBody[ReloadResponse](t, resp)
+	if body.RateLimitBurst != 7 || body.RateLimitPerMinute != rateLimitPerMinute {
+		t.Errorf("limits = %d/min burst %d", body.RateLimitPerMinute, body.RateLimitBurst)
+	}
+	if !slices.Contains(body.Changed, "YTSUMMARY_SERVER_API_KEY") {
+		t.Errorf("changed = %v, want the server key listed", body.Changed)
+	}
+	if body.Templates == 0 {
+		t.Error("expected templates to be counted")
+	}
+
+	// The old key stops working, the new one is accepted
+	resp = h.get("/export", "10.0.20.2")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("old key: status = %d, want 401", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest("GET", h.server.URL+"/export", nil)
+	req.Header.Set("Authorization", "Bearer rotated-key")
+	req.Header.Set("X-Forwarded-For", "10.0.20.3")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("rotated key: status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestReloadChecksBeforeApplying(t *testing.T) {
+	withEnvFile(t, "YTSUMMARY_RATE_BURST=7\nYTSUMMARY_FALLBACK_TO_TRANSCRIPT=true\n")
+	s := newServer(ServerConfig{})
+	if _, err := s.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !s.requestDefaults().FallbackToTranscript {
+		t.Error("reload didn't apply YTSUMMARY_FALLBACK_TO_TRANSCRIPT")
+	}
+
+	writeEnvFile(t, "YTSUMMARY_RATE_BURST=9\nYTSUMMARY_EXPERIMENT=prompt-v2\nYTSUMMARY_EXPERIMENT_B=no-such-template\n")
+	if _, err := s.reload(); err == nil || !strings.Contains(err.Error(), "no-such-template") {
+		t.Fatalf("reload = %v, want the unknown template reported", err)
+	}
+	if got := os.Getenv("YTSUMMARY_RATE_BURST"); got != "7" {
+		t.Errorf("YTSUMMARY_RATE_BURST = %q after a failed reload, want 7", got)
+	}
+	if _, ok := os.LookupEnv("YTSUMMARY_EXPERIMENT"); ok {
+		t.Error("YTSUMMARY_EXPERIMENT was applied by a failed reload")
+	}
+}
+
+func TestAdminReloadNeedsAPIKey(t *testing.T) {
+	withEnvFile(t, "YTSUMMARY_RATE_BURST=7\n")
+	srv := httptest.NewServer(newServer(ServerConfig{}).Handler())
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Post(srv.URL+"/v1/admin/reload", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := decodeBody[ErrorResponse](t, resp)
+	if resp.StatusCode != http.StatusForbidden || body.Error != ErrAuthRequired {
+		t.Errorf("status = %d %q, want 403 %s", resp.StatusCode, body.Error, ErrAuthRequired)
+	}
+	if _, ok := os.LookupEnv("YTSUMMARY_RATE_BURST"); ok {
+		t.Error("an unauthenticated request reloaded .env")
+	}
+}
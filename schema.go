@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultSchemaDriftThreshold is how many videos in a row must be missing
+// critical player response fields before a format change is suspected
+const defaultSchemaDriftThreshold = 3
+
+// youtubeSchema watches every parsed player response for format changes
+var youtubeSchema = newSchemaMonitor()
+
+// playerResponseProblems lists the critical fields missing from a player
+// response. Videos that aren't playable only need a status.
+func playerResponseProblems(pr *YouTubePlayerResponse) []string {
+	if pr.PlayabilityStatus.Status == "" {
+		return []string{"playabilityStatus.status"}
+	}
+	if pr.PlayabilityStatus.Status != "OK" {
+		return nil
+	}
+
+	var missing []string
+	if pr.VideoDetails.VideoID == "" {
+		missing = append(missing, "videoDetails.videoId")
+	}
+	if pr.VideoDetails.Title == "" {
+		missing = append(missing, "videoDetails.title")
+	}
+	if pr.VideoDetails.LengthSeconds == "" {
+		missing = append(missing, "videoDetails.lengthSeconds")
+	}
+	for _, track := range pr.Captions.PlayerCaptionsTracklistRenderer.CaptionTracks {
+		if track.BaseURL == "" {
+			missing = append(missing, "captionTracks[].baseUrl")
+			break
+		}
+	}
+	for _, track := range pr.Captions.PlayerCaptionsTracklistRenderer.CaptionTracks {
+		if track.LanguageCode == "" {
+			missing = append(missing, "captionTracks[].languageCode")
+			break
+		}
+	}
+	return missing
+}
+
+// schemaMonitor counts consecutive player responses with missing fields. One
+// odd response is ignored; a run of them means YouTube probably changed format.
+type schemaMonitor struct {
+	mu          sync.Mutex
+	consecutive int
+	drifted     bool
+	missing     []string // fields missing from the latest bad response
+}
+
+func newSchemaMonitor() *schemaMonitor {
+	return &schemaMonitor{}
+}
+
+// schemaDriftThreshold reads YTSUMMARY_SCHEMA_DRIFT_THRESHOLD
+func schemaDriftThreshold() int {
+	if n, err := strconv.Atoi(os.Getenv("YTSUMMARY_SCHEMA_DRIFT_THRESHOLD")); err == nil && n > 0 {
+		return n
+	}
+	return defaultSchemaDriftThreshold
+}
+
+// observe records one parsed response's missing fields
+func (m *schemaMonitor) observe(videoID string, missing []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(missing) == 0 {
+		if m.drifted {
+			logInfo("YouTube player responses look normal again", slog.String("video_id", videoID))
+		}
+		m.consecutive, m.drifted, m.missing = 0, false, nil
+		return
+	}
+
+	m.consecutive++
+	m.missing = missing
+	cliMetrics.recordSchemaProblem()
+	logDebug("player response missing fields", slog.String("video_id", videoID), slog.String("missing", strings.Join(missing, ",")))
+
+	if !m.drifted && m.consecutive >= schemaDriftThreshold() {
+		m.drifted = true
+		msg := fmt.Sprintf("YouTube format may have changed: the last %d player responses were missing %s. Run with --debug-scrape and report it", m.consecutive, strings.Join(missing, ", "))
+		fmt.Fprintln(os.Stderr, "WARNING: "+msg)
+		logError(msg, slog.String("video_id", videoID), slog.Int("consecutive", m.consecutive))
+	}
+}
+
+// driftedFields returns the missing fields while a format change is
+// suspected, and nil otherwise
+func (m *schemaMonitor) driftedFields() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.drifted {
+		return nil
+	}
+	return m.missing
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPlayerResponseProblems(t *testing.T) {
+	for _, name := range []string{"dQw4w9WgXcQ", "noCaptions1", "privateVid1", "ageRestrict"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "innertube", name+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pr YouTubePlayerResponse
+		if err := json.Unmarshal(data, &pr); err != nil {
+			t.Fatal(err)
+		}
+		if got := playerResponseProblems(&pr); len(got) != 0 {
+			t.Errorf("%s: playerResponseProblems() = %v, want none", name, got)
+		}
+	}
+
+	var pr YouTubePlayerResponse
+	if err := json.Unmarshal([]byte(`{"playabilityStatus":{"status":"OK"},"videoDetails":{"videoId":"x"},
+		"captions":{"playerCaptionsTracklistRenderer":{"captionTracks":[{"url":"https://example.com","languageCode":"en"}]}}}`), &pr); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"videoDetails.title", "videoDetails.lengthSeconds", "captionTracks[].baseUrl"}
+	if got := playerResponseProblems(&pr); !reflect.DeepEqual(got, want) {
+		t.Errorf("playerResponseProblems() = %v, want %v", got, want)
+	}
+
+	if got := playerResponseProblems(&YouTubePlayerResponse{}); !reflect.DeepEqual(got, []string{"playabilityStatus.status"}) {
+		t.Errorf("empty response: playerResponseProblems() = %v", got)
+	}
+}
+
+func TestSchemaMonitorDrift(t *testing.T) {
+	t.Setenv("YTSUMMARY_SCHEMA_DRIFT_THRESHOLD", "2")
+	old := youtubeSchema
+	youtubeSchema = newSchemaMonitor()
+	t.Cleanup(func() { youtubeSchema = old })
+
+	missing := []string{"videoDetails.title"}
+	youtubeSchema.observe("a", missing)
+	if got := youtubeSchema.driftedFields(); got != nil {
+		t.Fatalf("drifted after one bad response: %v", got)
+	}
+	youtubeSchema.observe("b", missing)
+	if got := youtubeSchema.driftedFields(); !reflect.DeepEqual(got, missing) {
+		t.Fatalf("driftedFields() = %v, want %v", got, missing)
+	}
+
+	s := newServer(ServerConfig{Cache: newTestCache(t)})
+	rec := httptest.NewRecorder()
+	s.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var health HealthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != "degraded" || !reflect.DeepEqual(health.SchemaDrift, missing) {
+		t.Errorf("health = %q %v, want degraded with schema_drift", health.Status, health.SchemaDrift)
+	}
+
+	youtubeSchema.observe("c", nil)
+	if got := youtubeSchema.driftedFields(); got != nil {
+		t.Errorf("still drifted after a good response: %v", got)
+	}
+}
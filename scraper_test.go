@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -235,7 +236,7 @@ func TestCleanSRT_VTT(t *testing.T) {
 		t.Fatalf("failed to read fixture: %v", err)
 	}
 
-	result := cleanSRT(string(vtt))
+	result := cleanSRT(string(vtt), false)
 
 	// Should contain the lyrics
 	if !strings.Contains(result, "Never gonna give you up") {
@@ -263,7 +264,7 @@ func TestParseTimedText(t *testing.T) {
 		t.Fatalf("failed to read fixture: %v", err)
 	}
 
-	result := parseTimedText(string(xml))
+	result := parseTimedText(string(xml), false)
 
 	// Should contain the lyrics with HTML entities decoded
 	if !strings.Contains(result, "We're no strangers to love") {
@@ -333,3 +334,43 @@ func TestErrorMapping(t *testing.T) {
 		})
 	}
 }
+
+// hourLongTimedText builds a synthetic hour-long ASR transcript in timedtext format
+func hourLongTimedText() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8" ?><timedtext format="3"><body>`)
+	for i := 0; i < 1800; i++ {
+		fmt.Fprintf(&b, "<p t=\"%d\" d=\"2000\">so we&#39;re going to talk about segment %d today</p>\n", i*2000, i)
+	}
+	b.WriteString("</body></timedtext>")
+	return b.String()
+}
+
+// hourLongVTT builds a synthetic hour-long VTT with rolling duplicate lines
+func hourLongVTT() string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\nKind: captions\nLanguage: en\n\n")
+	for i := 0; i < 1800; i++ {
+		fmt.Fprintf(&b, "%02d:%02d:%02d.000 --> %02d:%02d:%02d.000 align:start position:0%%\n", i/1800, (i/30)%60, (i*2)%60, i/1800, (i/30)%60, (i*2+2)%60)
+		fmt.Fprintf(&b, "so we're going to talk<00:00:01.000><c> about</c> segment %d today\n\n", i)
+	}
+	return b.String()
+}
+
+func BenchmarkParseTimedText(b *testing.B) {
+	content := hourLongTimedText()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(content)))
+	for i := 0; i < b.N; i++ {
+		parseTimedText(content, true)
+	}
+}
+
+func BenchmarkCleanSRT(b *testing.B) {
+	content := hourLongVTT()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(content)))
+	for i := 0; i < b.N; i++ {
+		cleanSRT(content, true)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Secret reference schemes: a setting holding vault://... or ssm://... is
+// fetched from that store instead of being used as is
+const (
+	vaultScheme = "vault://"
+	ssmScheme   = "ssm://"
+)
+
+// secretStoreTimeout bounds each request to Vault or SSM
+const secretStoreTimeout = 10 * time.Second
+
+var secretStoreClient = &http.Client{Timeout: secretStoreTimeout}
+
+// secretSettings are the settings that may hold a secret reference. The
+// flag is nil for settings only read from the environment.
+var secretSettings = []struct {
+	flag *string
+	env  string
+}{
+	{&llmAPIKey, "YTSUMMARY_API_KEY"},
+	{&serverAPIKey, "YTSUMMARY_SERVER_API_KEY"},
+	{nil, "YTSUMMARY_SERVER_API_KEYS"},
+}
+
+// resolvedSecrets maps each reference in secretSettings to the value it was
+// last fetched as; resolveSecretRefs replaces it whole
+var resolvedSecrets atomic.Pointer[map[string]string]
+
+// isSecretRef reports whether a setting's value names a secret to fetch
+func isSecretRef(v string) bool {
+	return strings.HasPrefix(v, vaultScheme) || strings.HasPrefix(v, ssmScheme)
+}
+
+// resolveSecretRef returns the fetched value of a reference, "" when it
+// hasn't been fetched, and any other value unchanged
+func resolveSecretRef(v string) string {
+	if !isSecretRef(v) {
+		return v
+	}
+	if m := resolvedSecrets.Load(); m != nil {
+		return (*m)[v]
+	}
+	return ""
+}
+
+// resolveSecretRefs fetches the secrets secretSettings reference, before a
+// command runs and again on each reload so rotated secrets are picked up.
+// On failure the previously fetched values stay in place.
+func resolveSecretRefs() error {
+	values, err := fetchSecretRefs(os.Getenv)
+	if err != nil {
+		return err
+	}
+	resolvedSecrets.Store(&values)
+	return nil
+}
+
+// fetchSecretRefs fetches the secrets secretSettings reference in the
+// variables getenv returns, keyed by reference
+func fetchSecretRefs(getenv func(string) string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, s := range secretSettings {
+		ref := getenv(s.env)
+		if s.flag != nil && *s.flag != "" {
+			ref = *s.flag
+		}
+		if !isSecretRef(ref) {
+			continue
+		}
+		if _, ok := values[ref]; ok {
+			continue
+		}
+		value, err := fetchSecret(ref, getenv)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s from %s: %w", s.env, ref, err)
+		}
+		values[ref] = value
+	}
+	return values, nil
+}
+
+// fetchSecret reads the secret a reference names, taking the store's address
+// and credentials from getenv
+func fetchSecret(ref string, getenv func(string) string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret reference: %w", err)
+	}
+	var value string
+	switch u.Scheme {
+	case "vault":
+		value, err = fetchVaultSecret(u, getenv)
+	case "ssm":
+		value, err = fetchSSMParameter(u, getenv)
+	}
+	if err != nil {
+		return "", err
+	}
+	if value = strings.TrimSpace(value); value == "" {
+		return "", fmt.Errorf("the secret is empty")
+	}
+	return value, nil
+}
+
+// fetchVaultSecret reads one field of a HashiCorp Vault secret, named as
+// vault://<path>#<field>, e.g. vault://secret/data/ytsummary#llm_api_key for
+// a KV v2 engine mounted at secret/. VAULT_ADDR is the server; the token is
+// VAULT_TOKEN, else the one 'vault login' saved in ~/.vault-token.
+func fetchVaultSecret(u *url.URL, getenv func(string) string) (string, error) {
+	addr := getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", fmt.Errorf("set VAULT_ADDR to the Vault server")
+	}
+	path := strings.Trim(u.Host+u.Path, "/")
+	field := u.Fragment
+	if path == "" || field == "" {
+		return "", fmt.Errorf("use vault://<path>#<field>")
+	}
+	token := getenv("VAULT_TOKEN")
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			data, _ := os.ReadFile(filepath.Join(home, ".vault-token"))
+			token = strings.TrimSpace(string(data))
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("set VAULT_TOKEN or run 'vault login'")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := secretStoreClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data   map[string]any `json:"data"`
+		Errors []string       `json:"errors"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Vault returned HTTP %d: %s", resp.StatusCode, strings.Join(body.Errors, "; "))
+	}
+	// KV v2 nests the secret's fields under data.data
+	fields := body.Data
+	if nested, ok := body.Data["data"].(map[string]any); ok {
+		fields = nested
+	}
+	value, ok := fields[field].(string)
+	if !ok {
+		return "", fmt.Errorf("the secret has no string field %q", field)
+	}
+	return value, nil
+}
+
+// fetchSSMParameter reads an AWS SSM Parameter Store parameter, decrypting a
+// SecureString, named as ssm:///<name> (ssm://<name> for names without a
+// leading slash), e.g. ssm:///ytsummary/prod/llm-api-key. ?region=
+// overrides AWS_REGION; credentials are AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN. YTSUMMARY_SSM_ENDPOINT
+// overrides the endpoint, e.g. for LocalStack.
+func fetchSSMParameter(u *url.URL, getenv func(string) string) (string, error) {
+	name := u.Path
+	if u.Host != "" {
+		name = strings.TrimPrefix(u.Host+u.Path, "/")
+	}
+	if name == "" || name == "/" {
+		return "", fmt.Errorf("use ssm:///<parameter-name>")
+	}
+	region := u.Query().Get("region")
+	if region == "" {
+		region = getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	creds := awsCredentials{
+		AccessKey:    getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKey == "" || creds.SecretKey == "" {
+		return "", fmt.Errorf("set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	endpoint := getenv("YTSUMMARY_SSM_ENDPOINT")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://ssm.%s.amazonaws.com", region)
+	}
+
+	body, err := json.Marshal(map[string]any{"Name": name, "WithDecryption": true})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonSSM.GetParameter")
+	signAWSRequest(req, body, creds, region, "ssm", time.Now())
+
+	resp, err := secretStoreClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("SSM request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Parameter struct {
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("SSM returned HTTP %d: %s %s", resp.StatusCode, out.Type, out.Message)
+	}
+	return out.Parameter.Value, nil
+}
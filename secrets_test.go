@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVault serves KV secrets: paths under secret/data/ in the KV v2 shape,
+// others in the KV v1 one
+type fakeVault struct {
+	mu      sync.Mutex
+	secrets map[string]map[string]any
+}
+
+func newFakeVault(t *testing.T) *fakeVault {
+	v := &fakeVault{secrets: map[string]map[string]any{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []string{"permission denied"}})
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		v.mu.Lock()
+		fields, ok := v.secrets[path]
+		v.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []string{}})
+			return
+		}
+		data := map[string]any(fields)
+		if strings.HasPrefix(path, "secret/data/") {
+			data = map[string]any{"data": fields, "metadata": map[string]any{"version": 1}}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "vault-test-token")
+	t.Cleanup(func() { resolvedSecrets.Store(nil) })
+	return v
+}
+
+func (v *fakeVault) set(path, field, value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.secrets[path] == nil {
+		v.secrets[path] = map[string]any{}
+	}
+	v.secrets[path][field] = value
+}
+
+func TestVaultSecretRefs(t *testing.T) {
+	vault := newFakeVault(t)
+	vault.set("secret/data/ytsummary", "llm_api_key", "sk-from-vault-kv2")
+	vault.set("kv/ytsummary", "server_keys", "team-a=es,team-b")
+	t.Setenv("YTSUMMARY_API_KEY", "vault://secret/data/ytsummary#llm_api_key")
+	t.Setenv("YTSUMMARY_SERVER_API_KEYS", "vault://kv/ytsummary#server_keys")
+
+	if got := getConfig("", "YTSUMMARY_API_KEY"); got != "" {
+		t.Errorf("before resolving: %q, want \"\" rather than the reference", got)
+	}
+	if err := resolveSecretRefs(); err != nil {
+		t.Fatal(err)
+	}
+	if got := getConfig("", "YTSUMMARY_API_KEY"); got != "sk-from-vault-kv2" {
+		t.Errorf("KV v2 secret = %q", got)
+	}
+	keys, err := apiKeysFromEnv()
+	if err != nil || len(keys) != 2 || keys["team-a"].Language != "es" {
+		t.Errorf("KV v1 server keys = %v, %v", keys, err)
+	}
+
+	// A failed fetch keeps the values fetched before
+	t.Setenv("YTSUMMARY_API_KEY", "vault://secret/data/ytsummary#missing")
+	if err := resolveSecretRefs(); err == nil || !strings.Contains(err.Error(), `no string field "missing"`) {
+		t.Errorf("missing field: %v", err)
+	}
+	if _, err := apiKeysFromEnv(); err != nil || getConfig("", "YTSUMMARY_SERVER_API_KEYS") != "team-a=es,team-b" {
+		t.Error("a failed fetch dropped the earlier values")
+	}
+
+	t.Setenv("VAULT_TOKEN", "wrong")
+	t.Setenv("YTSUMMARY_API_KEY", "vault://secret/data/ytsummary#llm_api_key")
+	if err := resolveSecretRefs(); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("bad token: %v", err)
+	}
+}
+
+func TestSSMSecretRef(t *testing.T) {
+	var target, auth string
+	var body struct {
+		Name           string
+		WithDecryption bool
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, auth = r.Header.Get("X-Amz-Target"), r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Name != "/ytsummary/prod/llm-api-key" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ParameterNotFound","message":"no such parameter"}`))
+			return
+		}
+		w.Write([]byte(`{"Parameter":{"Name":"/ytsummary/prod/llm-api-key","Type":"SecureString","Value":"sk-from-ssm"}}`))
+	}))
+	defer server.Close()
+	t.Cleanup(func() { resolvedSecrets.Store(nil) })
+	t.Setenv("YTSUMMARY_SSM_ENDPOINT", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+	t.Setenv("YTSUMMARY_API_KEY", "ssm:///ytsummary/prod/llm-api-key?region=eu-west-1")
+
+	if err := resolveSecretRefs(); err != nil {
+		t.Fatal(err)
+	}
+	if got := getConfig("", "YTSUMMARY_API_KEY"); got != "sk-from-ssm" {
+		t.Errorf("SSM parameter = %q", got)
+	}
+	if target != "AmazonSSM.GetParameter" || !body.WithDecryption {
+		t.Errorf("request target %q, decryption %v", target, body.WithDecryption)
+	}
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/ssm/aws4_request") ||
+		!strings.Contains(auth, "x-amz-security-token") || !strings.Contains(auth, "x-amz-target") {
+		t.Errorf("Authorization = %q", auth)
+	}
+
+	t.Setenv("YTSUMMARY_API_KEY", "ssm:///ytsummary/prod/other")
+	if err := resolveSecretRefs(); err == nil || !strings.Contains(err.Error(), "ParameterNotFound") {
+		t.Errorf("missing parameter: %v", err)
+	}
+}
+
+func TestReloadRefetchesSecrets(t *testing.T) {
+	vault := newFakeVault(t)
+	vault.set("secret/data/ytsummary", "server_key", "first-server-key")
+	withEnvFile(t, "YTSUMMARY_SERVER_API_KEY=vault://secret/data/ytsummary#server_key\n")
+	s := newServer(ServerConfig{Cache: newTestCache(t)})
+	handler := s.Handler()
+
+	status := func(key, ip string) int {
+		req := httptest.NewRequest("GET", "/v1/export", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if _, err := s.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if code := status("first-server-key", "198.51.100.200"); code != http.StatusOK {
+		t.Errorf("key from Vault: status = %d, want 200", code)
+	}
+
+	vault.set("secret/data/ytsummary", "server_key", "rotated-server-key")
+	if _, err := s.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if code := status("first-server-key", "198.51.100.201"); code != http.StatusUnauthorized {
+		t.Errorf("old key after rotation: status = %d, want 401", code)
+	}
+	if code := status("rotated-server-key", "198.51.100.202"); code != http.StatusOK {
+		t.Errorf("rotated key: status = %d, want 200", code)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Content-Security-Policy of API responses, which never load anything, and of
+// the dashboard, whose only resources are its inline styles
+const (
+	apiCSP       = "default-src 'none'; frame-ancestors 'none'"
+	dashboardCSP = "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'"
+)
+
+// Error codes for requests the server won't route
+const (
+	ErrMethodNotAllowed     = "method_not_allowed"
+	ErrUnsupportedMediaType = "unsupported_media_type"
+)
+
+// securityHeadersMiddleware sets headers that keep browsers from sniffing
+// JSON as HTML, framing the dashboard or leaking URLs (which may hold video
+// IDs) in Referer headers. Handlers serving HTML replace the CSP.
+func securityHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Content-Security-Policy", apiCSP)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// jsonBodyMiddleware rejects POST, PUT and PATCH bodies declared as anything
+// but JSON with 415, so form posts from other sites can't reach the API. A
+// missing Content-Type is read as JSON.
+func jsonBodyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			if ct := r.Header.Get("Content-Type"); ct != "" && !isJSONMediaType(ct) {
+				w.Header().Set("Accept-Post", "application/json")
+				writeError(w, http.StatusUnsupportedMediaType, ErrUnsupportedMediaType, "request body must be application/json, not "+ct)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isJSONMediaType reports whether a Content-Type is application/json or a
+// +json type such as application/merge-patch+json
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || (strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
+}
+
+// routableMethods are the methods a 405 is registered for on paths that
+// don't serve them; the mux answers any other with its own plain-text 405
+var routableMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// unroutedMethods returns the routable methods a path serving methods
+// doesn't. Patterns per method, rather than one for the bare path, keep
+// clear of conflicts with wildcard routes such as GET /cache/{id}.
+func unroutedMethods(methods []string) []string {
+	var unrouted []string
+	for _, m := range routableMethods {
+		if slices.Contains(methods, m) || (m == http.MethodHead && slices.Contains(methods, http.MethodGet)) {
+			continue
+		}
+		unrouted = append(unrouted, m)
+	}
+	return unrouted
+}
+
+// methodNotAllowed answers requests for a route's path with a method it
+// doesn't serve: 405 with the methods it does in Allow
+func methodNotAllowed(methods []string) http.HandlerFunc {
+	allow := slices.Clone(methods)
+	if slices.Contains(allow, http.MethodGet) {
+		allow = append(allow, http.MethodHead)
+	}
+	slices.Sort(allow)
+	allow = slices.Compact(allow)
+	header := strings.Join(allow, ", ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", header)
+		writeError(w, http.StatusMethodNotAllowed, ErrMethodNotAllowed, r.Method+" is not allowed here (use "+header+")")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	handler := newServer(ServerConfig{APIKey: "secret", Cache: newTestCache(t)}).Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/health", nil))
+	for name, want := range map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Referrer-Policy":         "no-referrer",
+		"Content-Security-Policy": apiCSP,
+	} {
+		if got := w.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/v1/admin/dashboard", nil)
+	req.RemoteAddr = "192.0.2.28:1234"
+	req.Header.Set("X-API-Key", "secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if got := w.Header().Get("Content-Security-Policy"); got != dashboardCSP {
+		t.Errorf("dashboard CSP = %q, want %q", got, dashboardCSP)
+	}
+}
+
+func TestJSONBodyRequired(t *testing.T) {
+	handler := newServer(ServerConfig{
+		Cache: newTestCache(t),
+		Notes: newTestCache(t),
+	}).Handler()
+
+	tests := []struct {
+		method, path, contentType string
+		wantStatus                int
+	}{
+		{"POST", "/v1/transcript", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"POST", "/v1/summarize/text", "text/plain", http.StatusUnsupportedMediaType},
+		{"PATCH", "/v1/videos/dQw4w9WgXcQ/notes", "multipart/form-data; boundary=x", http.StatusUnsupportedMediaType},
+		{"PATCH", "/v1/videos/dQw4w9WgXcQ/notes", "application/merge-patch+json", http.StatusOK},
+		{"PATCH", "/v1/videos/dQw4w9WgXcQ/notes", "application/json; charset=utf-8", http.StatusOK},
+		{"PATCH", "/v1/videos/dQw4w9WgXcQ/notes", "", http.StatusOK},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"add": "A note"}`))
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:1234", 100+i)
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s %s as %q: status %d, want %d", tt.method, tt.path, tt.contentType, w.Code, tt.wantStatus)
+		}
+		if tt.wantStatus == http.StatusUnsupportedMediaType {
+			var resp ErrorResponse
+			if json.NewDecoder(w.Body).Decode(&resp); resp.Error != ErrUnsupportedMediaType {
+				t.Errorf("%s %s: error = %q, want %s", tt.method, tt.path, resp.Error, ErrUnsupportedMediaType)
+			}
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	handler := newServer(ServerConfig{Cache: newTestCache(t)}).Handler()
+
+	tests := []struct {
+		method, path, wantAllow string
+	}{
+		{"DELETE", "/v1/health", "GET, HEAD"},
+		{"PUT", "/v1/summarize", "GET, HEAD, POST"},
+		{"GET", "/v1/cache/status", "POST"},
+		{"POST", "/video/dQw4w9WgXcQ", "GET, HEAD"},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:1234", 150+i)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		var resp ErrorResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusMethodNotAllowed || resp.Error != ErrMethodNotAllowed {
+			t.Errorf("%s %s: status %d, error %q; want a JSON 405", tt.method, tt.path, w.Code, resp.Error)
+		}
+		if got := w.Header().Get("Allow"); got != tt.wantAllow {
+			t.Errorf("%s %s: Allow = %q, want %q", tt.method, tt.path, got, tt.wantAllow)
+		}
+	}
+
+	// Served methods still route
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("HEAD", "/v1/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("HEAD /v1/health: status %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TranscriptSegment is one caption cue with its timing in seconds
+type TranscriptSegment struct {
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+	Text     string  `json:"text"`
+
+	// Words times each word of auto-generated captions; nil for uploaded ones
+	Words []TranscriptWord `json:"words,omitempty"`
+}
+
+// TimeRange restricts a transcript to [Start, End) seconds. End of 0 means until the end.
+type TimeRange struct {
+	Start float64
+	End   float64
+}
+
+// String renders the range as "12:30-25:00", using "end" for an open end
+func (r TimeRange) String() string {
+	end := "end"
+	if r.End > 0 {
+		end = formatTimestamp(r.End)
+	}
+	return formatTimestamp(r.Start) + "-" + end
+}
+
+// parseTimeRange builds a range from --from/--to style timestamps, returning
+// nil when neither is set
+func parseTimeRange(from, to string) (*TimeRange, error) {
+	if from == "" && to == "" {
+		return nil, nil
+	}
+
+	var r TimeRange
+	var err error
+	if from != "" {
+		if r.Start, err = parseTimestamp(from); err != nil {
+			return nil, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if to != "" {
+		if r.End, err = parseTimestamp(to); err != nil {
+			return nil, fmt.Errorf("invalid to: %w", err)
+		}
+		if r.End <= r.Start {
+			return nil, fmt.Errorf("to (%s) must be after from (%s)", to, from)
+		}
+	}
+	return &r, nil
+}
+
+// parseTimestamp parses "H:MM:SS", "MM:SS" or plain seconds ("754", "754.5")
+func parseTimestamp(s string) (float64, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	var total float64
+	for i, part := range parts {
+		last := i == len(parts)-1
+		var v float64
+		var err error
+		if last {
+			v, err = strconv.ParseFloat(part, 64)
+		} else {
+			var n int
+			n, err = strconv.Atoi(part)
+			v = float64(n)
+		}
+		// ParseFloat accepts "NaN" and "Inf", which are no time. Minutes and
+		// seconds after the leading component must be under 60.
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || v < 0 || (i > 0 && v >= 60) {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		total = total*60 + v
+	}
+	return total, nil
+}
+
+// parseTimedTextSegments parses YouTube's XML timedtext format into timed segments.
+// Empty cues and repeats are dropped, and for autoGenerated captions words a
+// cue repeats from the ones before it are trimmed, matching parseTimedText.
+func parseTimedTextSegments(xmlContent string, autoGenerated bool) []TranscriptSegment {
+	// Format: <p t="1360" d="1680">text here</p> (milliseconds)
+	// Or: <text start="1.36" dur="1.68">text here</text> (seconds)
+	// srv3 auto-generated captions time each word: <p t="1360" d="1680"><s>text</s><s t="400"> here</s></p>
+
+	b := newSegmentBuilder(autoGenerated)
+
+	// Try <p> format first (format="3"), then <text>
+	n := scanTimedTextElements(xmlContent, "p", func(attrs, body string) {
+		start := attrMillis(attrs, "t")
+		text, words := body, []TranscriptWord(nil)
+		if strings.IndexByte(body, '<') >= 0 {
+			text, words = parseSrv3Words(body, start)
+		}
+		b.add(start, attrMillis(attrs, "d"), html.UnescapeString(text), words)
+	})
+	if n == 0 {
+		scanTimedTextElements(xmlContent, "text", func(attrs, body string) {
+			b.add(attrFloat(attrs, "start"), attrFloat(attrs, "dur"), html.UnescapeString(body), nil)
+		})
+	}
+
+	return b.finish()
+}
+
+// parseSRTSegments parses SubRip (or WebVTT) captions into timed segments,
+// cleaned like parseTimedTextSegments. Cues with unreadable timings are dropped.
+func parseSRTSegments(content string, autoGenerated bool) []TranscriptSegment {
+	b := newSegmentBuilder(autoGenerated)
+	var start, end float64
+	var text []string
+	timed := false
+	flush := func() {
+		if timed {
+			b.add(start, end-start, strings.Join(text, " "), nil)
+		}
+		timed, text = false, text[:0]
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if from, to, ok := strings.Cut(line, "-->"); ok {
+			flush()
+			var err error
+			if start, err = parseSRTTimestamp(from); err != nil {
+				continue
+			}
+			if end, err = parseSRTTimestamp(to); err != nil {
+				continue
+			}
+			timed = true
+			continue
+		}
+		switch {
+		case line == "":
+			flush()
+		case timed:
+			text = append(text, stripTags(line))
+		}
+	}
+	flush()
+	return b.finish()
+}
+
+// parseSRTTimestamp parses an SRT "00:01:02,500" or VTT "01:02.500" cue
+// time, ignoring any VTT cue settings after it
+func parseSRTTimestamp(s string) (float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("missing cue time")
+	}
+	return parseTimestamp(strings.Replace(fields[0], ",", ".", 1))
+}
+
+// segmentBuilder collects caption cues into segments
+type segmentBuilder struct {
+	segments []TranscriptSegment
+	merger   cueMerger
+}
+
+// newSegmentBuilder returns a builder trimming rolled-over words from
+// autoGenerated captions, and only dropping exact repeats otherwise
+func newSegmentBuilder(autoGenerated bool) *segmentBuilder {
+	return &segmentBuilder{merger: cueMerger{rolling: autoGenerated}}
+}
+
+// add appends a cue unless it is empty or only repeats earlier cues. Words
+// the cue repeats are trimmed from its text and word timings alike.
+func (b *segmentBuilder) add(start, duration float64, text string, words []TranscriptWord) {
+	// Normalize so repeats compare equal
+	text = cleanCaptionText(text)
+	if text == "" {
+		return
+	}
+	merged := b.merger.merge(text)
+	if merged == "" {
+		return
+	}
+	if merged != text && words != nil {
+		repeated := len(strings.Fields(text)) - len(strings.Fields(merged))
+		words = words[min(repeated, len(words)):]
+	}
+	b.segments = append(b.segments, TranscriptSegment{Start: start, Duration: duration, Text: merged, Words: words})
+}
+
+// finish returns the segments with each word lasting until the next begins
+func (b *segmentBuilder) finish() []TranscriptSegment {
+	timeWords(b.segments)
+	return b.segments
+}
+
+// segmentsText joins segment texts into a plain transcript
+func segmentsText(segments []TranscriptSegment) string {
+	var b strings.Builder
+	for _, seg := range segments {
+		appendCue(&b, seg.Text)
+	}
+	return withParagraphDirection(b.String())
+}
+
+// markLinkedSection returns the transcript text with linkedSectionMarker before
+// the first segment still playing at the given time. ok is false when there
+// are no timings or the time is past the last segment.
+func markLinkedSection(segments []TranscriptSegment, at float64) (text string, ok bool) {
+	for i, seg := range segments {
+		if seg.Start+seg.Duration <= at && seg.Start < at {
+			continue
+		}
+		before, after := segmentsText(segments[:i]), segmentsText(segments[i:])
+		if before == "" {
+			return linkedSectionMarker + " " + after, true
+		}
+		return before + " " + linkedSectionMarker + " " + after, true
+	}
+	return "", false
+}
+
+// focusOnLinkedSection marks the linked section in the entry's transcript and
+// returns the focus hint for SummaryOptions. Without timings only the hint is given.
+func focusOnLinkedSection(entry *CacheEntry, at float64) string {
+	if text, ok := markLinkedSection(entry.Segments, at); ok {
+		entry.Transcript = text
+	}
+	return formatTimestamp(at)
+}
+
+// sliceSegments returns the segments overlapping the time range
+func sliceSegments(segments []TranscriptSegment, r TimeRange) []TranscriptSegment {
+	var out []TranscriptSegment
+	for _, seg := range segments {
+		if seg.Start < r.Start && seg.Start+seg.Duration <= r.Start {
+			continue
+		}
+		if r.End > 0 && seg.Start >= r.End {
+			break
+		}
+		out = append(out, seg)
+	}
+	return out
+}
+
+// formatTimestamp renders seconds as M:SS or H:MM:SS
+func formatTimestamp(seconds float64) string {
+	total := int(seconds)
+	h, m, sec := total/3600, (total%3600)/60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+	}
+	return fmt.Sprintf("%d:%02d", m, sec)
+}
+
+// attrValue returns the value of name="value" within a tag's attribute string
+func attrValue(attrs, name string) string {
+	for len(attrs) > 0 {
+		i := strings.Index(attrs, name+"=\"")
+		if i < 0 {
+			return ""
+		}
+		// Make sure we matched a whole attribute name, not a suffix of another
+		if i > 0 && attrs[i-1] != ' ' && attrs[i-1] != '\t' && attrs[i-1] != '\n' {
+			attrs = attrs[i+len(name)+2:]
+			continue
+		}
+		rest := attrs[i+len(name)+2:]
+		if end := strings.IndexByte(rest, '"'); end >= 0 {
+			return rest[:end]
+		}
+		return ""
+	}
+	return ""
+}
+
+func attrFloat(attrs, name string) float64 {
+	v, _ := strconv.ParseFloat(attrValue(attrs, name), 64)
+	return v
+}
+
+func attrMillis(attrs, name string) float64 {
+	v, _ := strconv.ParseInt(attrValue(attrs, name), 10, 64)
+	return float64(v) / 1000
+}
+
+const (
+	defaultPageSegments = 500  // page size when offset is given without limit
+	maxPageSegments     = 5000 // largest page a client may request
+)
+
+// TranscriptPage describes one page of a paginated transcript
+type TranscriptPage struct {
+	Offset        int  `json:"offset"`
+	Limit         int  `json:"limit"`
+	TotalSegments int  `json:"total_segments"`
+	NextOffset    *int `json:"next_offset,omitempty"` // nil on the last page
+}
+
+// paginateSegments restricts the entry to limit segments starting at offset,
+// so long transcripts can be loaded a page at a time
+func paginateSegments(entry *CacheEntry, offset, limit int) (*TranscriptPage, error) {
+	if len(entry.Segments) == 0 {
+		return nil, fmt.Errorf("caption timings unavailable for this video")
+	}
+	if limit == 0 {
+		limit = defaultPageSegments
+	}
+
+	page := &TranscriptPage{Offset: offset, Limit: limit, TotalSegments: len(entry.Segments)}
+	if offset >= len(entry.Segments) {
+		return nil, fmt.Errorf("offset %d is past the last segment (%d segments)", offset, len(entry.Segments))
+	}
+	end := min(offset+limit, len(entry.Segments))
+	if end < len(entry.Segments) {
+		page.NextOffset = &end
+	}
+
+	entry.Segments = entry.Segments[offset:end]
+	entry.Transcript = segmentsText(entry.Segments)
+	return page, nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseTimedTextSegments(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []TranscriptSegment
+	}{
+		{
+			name:  "p format in milliseconds",
+			input: `<timedtext format="3"><body><p t="1360" d="1680">Hello</p><p t="3040" d="2000">world &amp; more</p></body></timedtext>`,
+			want: []TranscriptSegment{
+				{Start: 1.36, Duration: 1.68, Text: "Hello"},
+				{Start: 3.04, Duration: 2, Text: "world & more"},
+			},
+		},
+		{
+			name:  "text format in seconds",
+			input: `<transcript><text start="0.5" dur="1.25">First</text><text start="1.75" dur="2">Second</text></transcript>`,
+			want: []TranscriptSegment{
+				{Start: 0.5, Duration: 1.25, Text: "First"},
+				{Start: 1.75, Duration: 2, Text: "Second"},
+			},
+		},
+		{
+			name:  "drops empty cues and duplicates",
+			input: `<timedtext><body><p t="0" d="1000">Same</p><p t="1000" d="1000">Same</p><p t="2000" d="500">  </p><p t="2500" d="1000">Next</p></body></timedtext>`,
+			want: []TranscriptSegment{
+				{Start: 0, Duration: 1, Text: "Same"},
+				{Start: 2.5, Duration: 1, Text: "Next"},
+			},
+		},
+		{
+			name:  "attribute names are matched whole",
+			input: `<timedtext><body><p wt="99" t="2000" d="1000">Hi</p></body></timedtext>`,
+			want:  []TranscriptSegment{{Start: 2, Duration: 1, Text: "Hi"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTimedTextSegments(tt.input, false)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d segments, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("segment %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseTimedTextSegments_MatchesPlainText(t *testing.T) {
+	content, err := os.ReadFile("testdata/innertube/dQw4w9WgXcQ.en.xml")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	if got, want := segmentsText(parseTimedTextSegments(string(content), false)), parseTimedText(string(content), false); got != want {
+		t.Errorf("segmentsText() = %q, want %q", got, want)
+	}
+}
+
+func TestParseSRTSegments(t *testing.T) {
+	srt := "1\r\n00:00:01,500 --> 00:00:03,000\r\n<i>Hello</i> there\r\nfriends\r\n\r\n" +
+		"2\r\n00:00:03,000 --> bad\r\nDropped\r\n\r\n" +
+		"3\r\n00:01:04,250 --> 00:01:06,000\r\nthere friends, welcome\r\n"
+	want := []TranscriptSegment{
+		{Start: 1.5, Duration: 1.5, Text: "Hello there friends"},
+		{Start: 64.25, Duration: 1.75, Text: "welcome"},
+	}
+	if got := parseSRTSegments(srt, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSRTSegments = %+v, want %+v", got, want)
+	}
+	// Written captions keep words repeated from the cue before
+	want[1].Text = "there friends, welcome"
+	if got := parseSRTSegments(srt, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("manual parseSRTSegments = %+v, want %+v", got, want)
+	}
+
+	vtt := "WEBVTT\n\n00:05.000 --> 00:07.000 align:start\nFrom VTT\n"
+	if got := parseSRTSegments(vtt, false); len(got) != 1 || got[0].Start != 5 || got[0].Duration != 2 || got[0].Text != "From VTT" {
+		t.Errorf("vtt = %+v", got)
+	}
+}
+
+func TestSliceSegments(t *testing.T) {
+	segments := []TranscriptSegment{
+		{Start: 0, Duration: 2, Text: "a"},
+		{Start: 2, Duration: 2, Text: "b"},
+		{Start: 4, Duration: 2, Text: "c"},
+		{Start: 6, Duration: 2, Text: "d"},
+	}
+
+	tests := []struct {
+		name string
+		r    TimeRange
+		want string
+	}{
+		{"whole range", TimeRange{}, "a b c d"},
+		{"aligned range", TimeRange{Start: 2, End: 6}, "b c"},
+		{"overlapping edges", TimeRange{Start: 3, End: 5}, "b c"},
+		{"open end", TimeRange{Start: 5}, "c d"},
+		{"past the end", TimeRange{Start: 10, End: 20}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := segmentsText(sliceSegments(segments, tt.r)); got != tt.want {
+				t.Errorf("sliceSegments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "0:00"},
+		{65.9, "1:05"},
+		{3725, "1:02:05"},
+	}
+
+	for _, tt := range tests {
+		if got := formatTimestamp(tt.seconds); got != tt.want {
+			t.Errorf("formatTimestamp(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{"754", 754, false},
+		{"12.5", 12.5, false},
+		{"12:30", 750, false},
+		{"1:02:05", 3725, false},
+		{"90:00", 5400, false},
+		{"1:60", 0, true},
+		{"1:2:3:4", 0, true},
+		{"abc", 0, true},
+		{"-5", 0, true},
+		{"-0.5", 0, true},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+		{"+Inf", 0, true},
+		{"-Inf", 0, true},
+		{"1:NaN", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimestamp(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseTimestamp(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     *TimeRange
+		wantErr  bool
+	}{
+		{"", "", nil, false},
+		{"12:30", "25:00", &TimeRange{Start: 750, End: 1500}, false},
+		{"12:30", "", &TimeRange{Start: 750}, false},
+		{"", "1:00", &TimeRange{End: 60}, false},
+		{"2:00", "1:00", nil, true},
+		{"x", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTimeRange(tt.from, tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimeRange(%q, %q) error = %v, wantErr %v", tt.from, tt.to, err, tt.wantErr)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("parseTimeRange(%q, %q) = %+v, want %+v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestTimeRangeString(t *testing.T) {
+	if got := (TimeRange{Start: 750, End: 1500}).String(); got != "12:30-25:00" {
+		t.Errorf("String() = %q, want 12:30-25:00", got)
+	}
+	if got := (TimeRange{Start: 750}).String(); got != "12:30-end" {
+		t.Errorf("String() = %q, want 12:30-end", got)
+	}
+}
+
+func TestMarkLinkedSection(t *testing.T) {
+	segments := []TranscriptSegment{
+		{Start: 0, Duration: 2, Text: "a"},
+		{Start: 2, Duration: 2, Text: "b"},
+		{Start: 4, Duration: 2, Text: "c"},
+	}
+
+	tests := []struct {
+		at     float64
+		want   string
+		wantOK bool
+	}{
+		{0, linkedSectionMarker + " a b c", true},
+		{2, "a " + linkedSectionMarker + " b c", true},
+		{3, "a " + linkedSectionMarker + " b c", true},
+		{10, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := markLinkedSection(segments, tt.at)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("markLinkedSection(%v) = %q, %v; want %q, %v", tt.at, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	if _, ok := markLinkedSection(nil, 1); ok {
+		t.Error("markLinkedSection(nil) should report no timings")
+	}
+}
+
+func TestPaginateSegments(t *testing.T) {
+	segments := []TranscriptSegment{
+		{Start: 0, Duration: 2, Text: "a"},
+		{Start: 2, Duration: 2, Text: "b"},
+		{Start: 4, Duration: 2, Text: "c"},
+		{Start: 6, Duration: 2, Text: "d"},
+		{Start: 8, Duration: 2, Text: "e"},
+	}
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		want          string
+		next          int // 0 for the last page
+	}{
+		{"first page", 0, 2, "a b", 2},
+		{"middle page", 2, 2, "c d", 4},
+		{"last page", 4, 2, "e", 0},
+		{"default limit", 1, 0, "b c d e", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &CacheEntry{Segments: segments}
+			page, err := paginateSegments(entry, tt.offset, tt.limit)
+			if err != nil {
+				t.Fatalf("paginateSegments() error = %v", err)
+			}
+			if entry.Transcript != tt.want {
+				t.Errorf("Transcript = %q, want %q", entry.Transcript, tt.want)
+			}
+			if page.TotalSegments != len(segments) {
+				t.Errorf("TotalSegments = %d, want %d", page.TotalSegments, len(segments))
+			}
+			switch {
+			case tt.next == 0 && page.NextOffset != nil:
+				t.Errorf("NextOffset = %d, want nil", *page.NextOffset)
+			case tt.next != 0 && (page.NextOffset == nil || *page.NextOffset != tt.next):
+				t.Errorf("NextOffset = %v, want %d", page.NextOffset, tt.next)
+			}
+		})
+	}
+
+	if _, err := paginateSegments(&CacheEntry{Segments: segments}, 5, 2); err == nil {
+		t.Error("expected error for offset past the last segment")
+	}
+	if _, err := paginateSegments(&CacheEntry{Transcript: "untimed"}, 0, 2); err == nil {
+		t.Error("expected error without caption timings")
+	}
+}
@@ -3,12 +3,17 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -26,23 +31,194 @@ const (
 
 type TranscriptRequest struct {
 	URL      string `json:"url"`
-	Language string `json:"language,omitempty"` // defaults to "en"
+	Language string `json:"language,omitempty"`  // defaults to "en"
+	Template string `json:"template,omitempty"`  // prompt template for /summarize
+	ClipOnly bool   `json:"clip_only,omitempty"` // for clip URLs, only use captions within the clip
+	From     string `json:"from,omitempty"`      // only use captions from this timestamp, e.g. "12:30"
+	To       string `json:"to,omitempty"`        // only use captions up to this timestamp
+
+	// SummaryLanguage is the language /summarize writes in (default: Language),
+	// and PromptLanguage that of the prompts it sends (default: the summary's,
+	// where the template is translated)
+	SummaryLanguage string `json:"summary_language,omitempty"`
+	PromptLanguage  string `json:"prompt_language,omitempty"`
+
+	// FocusLinkedTimestamp biases /summarize toward the section a t= URL links to
+	FocusLinkedTimestamp bool `json:"focus_linked_timestamp,omitempty"`
+
+	// AllowAutoTranslate uses YouTube's machine translation when the video has
+	// no captions in Language
+	AllowAutoTranslate bool `json:"allow_auto_translate,omitempty"`
+
+	// KeepNonSpeech sends [Music]-style annotations and filler words to the
+	// LLM instead of stripping them before /summarize
+	KeepNonSpeech bool `json:"keep_non_speech,omitempty"`
+
+	// ContentFilter is "flag" to report profanity and sensitive topics, or
+	// "mask" to also mask profanity in the transcript and summary
+	ContentFilter string `json:"content_filter,omitempty"`
+
+	// MusicVideos is what /summarize does with music videos: "describe" the
+	// song, "skip" them with a music_video error, or "summarize" them as usual
+	MusicVideos string `json:"music_videos,omitempty"`
+
+	// Offset and Limit page through the transcript's segments on /transcript;
+	// the response then carries the page's segments and text
+	Offset int `json:"offset,omitempty"`
+	Limit  int `json:"limit,omitempty"`
+
+	// Format is "text" (default), "segments" to return /transcript as timed
+	// segments instead of plain text, or "words" for per-word timings
+	Format string `json:"format,omitempty"`
+
+	// FallbackToTranscript makes /summarize answer 200 with just the transcript
+	// when the LLM fails, instead of an llm_error
+	FallbackToTranscript bool `json:"fallback_to_transcript,omitempty"`
+
+	// GenerationParams (temperature, top_p, max_tokens) override the
+	// configured sampling settings for /summarize
+	GenerationParams
+
+	// MaxWords asks /summarize for a summary under this many words
+	MaxWords int `json:"max_words,omitempty"`
+
+	// Lint checks the summary is in the requested language and within
+	// MaxWords, retrying once if not (also enabled by YTSUMMARY_LINT)
+	Lint bool `json:"lint,omitempty"`
+
+	// MaxCost is the most /summarize may spend on LLM calls, in USD;
+	// defaults to the server's YTSUMMARY_MAX_COST
+	MaxCost float64 `json:"max_cost,omitempty"`
+
+	// Priority is "interactive" (default) or "batch"; interactive requests
+	// get the next free work slot first
+	Priority string `json:"priority,omitempty"`
+
+	// Subtitles asks /summarize for the transcript sentences matching the
+	// summary as an SRT track, at their original times
+	Subtitles bool `json:"subtitles,omitempty"`
+
+	// Mode is "summary" (default) or "highlights" for /summarize to return
+	// the Count most important moments with their times instead
+	Mode  string `json:"mode,omitempty"`
+	Count int    `json:"count,omitempty"`
+
+	// MaxTranscriptChars has /summarize summarize only the part of a longer
+	// transcript TruncateStrategy keeps (head, head+tail or sampled);
+	// defaults to the server's YTSUMMARY_MAX_TRANSCRIPT_CHARS
+	MaxTranscriptChars int    `json:"max_transcript_chars,omitempty"`
+	TruncateStrategy   string `json:"truncate_strategy,omitempty"`
+
+	// WithNotes gives /summarize the video's notes (PATCH /videos/{id}/notes)
+	// as context
+	WithNotes bool `json:"with_notes,omitempty"`
+
+	// Set by parseRequest: the time range to restrict the transcript to, and
+	// the linked timestamp to focus on
+	window *TimeRange
+	focus  *float64
 }
 
 type TranscriptResponse struct {
-	VideoID    string `json:"video_id"`
-	Title      string `json:"title,omitempty"`
-	Transcript string `json:"transcript,omitempty"`
-	Summary    string `json:"summary,omitempty"`
-	Language   string `json:"language"`
-	Cached     bool   `json:"cached"`
-	DurationMS int64  `json:"duration_ms"`
+	VideoID      string `json:"video_id"`
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Transcript   string `json:"transcript,omitempty"`
+	Summary      string `json:"summary,omitempty"`
+	Language     string `json:"language"`
+	Cached       bool   `json:"cached"`
+	DurationMS   int64  `json:"duration_ms"`
+
+	// Source says where the transcript came from (see transcriptSource) and
+	// FetchedAt when it was fetched from YouTube
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+
+	// TranslatedFrom is set when the captions are YouTube's machine translation
+	// from this language
+	TranslatedFrom string `json:"translated_from,omitempty"`
+
+	// TranscriptQuality scores auto-generated captions; nil for uploaded ones
+	TranscriptQuality *TranscriptQuality `json:"transcript_quality,omitempty"`
+
+	// ContentNotes is set when a content filter was requested
+	ContentNotes *ContentNotes `json:"content_notes,omitempty"`
+
+	// Format is the video's format classified from its transcript, e.g.
+	// "interview", and Template the prompt template the summary used
+	Format   string `json:"format,omitempty"`
+	Template string `json:"template,omitempty"`
+
+	// SummaryError is set when summarization failed and the request asked
+	// to fall back to the transcript
+	SummaryError string `json:"summary_error,omitempty"`
+
+	// Segments is set for format=segments and when a page of the transcript
+	// was requested; Page describes that page
+	Segments []TranscriptSegment `json:"segments,omitempty"`
+	Page     *TranscriptPage     `json:"page,omitempty"`
+
+	// Words is set for format=words: each word of auto-generated captions
+	// with its timing
+	Words []TranscriptWord `json:"words,omitempty"`
+
+	// Subtitles is the condensed SRT track requested with subtitles
+	Subtitles string `json:"subtitles,omitempty"`
+
+	// Highlights are the moments picked with mode=highlights
+	Highlights []Highlight `json:"highlights,omitempty"`
+
+	// Chapters are the highlights as YouTube description chapter lines,
+	// requested with format=chapters
+	Chapters string `json:"chapters,omitempty"`
+
+	// Truncation is set when only part of a long transcript was summarized
+	Truncation *Truncation `json:"truncation,omitempty"`
+
+	// TimedOut is set when the request deadline passed before the summary
+	// was ready; the response then carries the transcript and the
+	// ChunkSummaries finished so far instead
+	TimedOut       bool     `json:"timed_out,omitempty"`
+	ChunkSummaries []string `json:"chunk_summaries,omitempty"`
+
+	// SummaryID is the kept summary, for /summaries/{id}/feedback; Experiment
+	// and Variant name the A/B experiment arm that wrote it
+	SummaryID  int64  `json:"summary_id,omitempty"`
+	Experiment string `json:"experiment,omitempty"`
+	Variant    string `json:"variant,omitempty"`
+}
+
+// paginated reports whether the request asked for a page of the transcript
+func (r *TranscriptRequest) paginated() bool {
+	return r.Offset != 0 || r.Limit != 0
+}
+
+// fallbackToTranscriptDefault reports whether /summarize falls back to the
+// transcript on LLM failure for every request (YTSUMMARY_FALLBACK_TO_TRANSCRIPT),
+// for clients that predate fallback_to_transcript
+func fallbackToTranscriptDefault() bool {
+	fallback, _ := strconv.ParseBool(os.Getenv("YTSUMMARY_FALLBACK_TO_TRANSCRIPT"))
+	return fallback
 }
 
+// Transcript formats for /transcript
+const (
+	transcriptFormatText     = "text"
+	transcriptFormatSegments = "segments"
+	transcriptFormatWords    = "words"
+)
+
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message"`
 	VideoID string `json:"video_id,omitempty"`
+
+	// Transcript is included with llm_error from /summarize so clients can
+	// still show the captions that were fetched
+	Transcript string `json:"transcript,omitempty"`
+
+	// RequestID is set on internal errors, to find the request in the logs
+	RequestID string `json:"request_id,omitempty"`
 }
 
 type HealthResponse struct {
@@ -51,6 +227,20 @@ type HealthResponse struct {
 	UptimeSeconds        int64  `json:"uptime_seconds"`
 	LastSuccess          string `json:"last_success,omitempty"`
 	LastSuccessAgeSeconds int64  `json:"last_success_age_seconds,omitempty"`
+
+	// SchemaDrift lists player response fields that have been missing from
+	// several videos in a row, a sign YouTube changed its format
+	SchemaDrift []string `json:"schema_drift,omitempty"`
+
+	// Panics counts handler panics recovered since the server started
+	Panics int64 `json:"panics,omitempty"`
+
+	// LockFallbacks counts requests that went ahead without the shared
+	// YTSUMMARY_LOCK_REDIS lock because Redis failed
+	LockFallbacks int64 `json:"lock_fallbacks,omitempty"`
+
+	// Build identifies the binary answering the request
+	Build BuildInfo `json:"build"`
 }
 
 // Error codes (from Gap 1)
@@ -58,58 +248,201 @@ const (
 	ErrNoCaptions       = "no_captions"
 	ErrVideoUnavailable = "video_unavailable"
 	ErrAgeRestricted    = "age_restricted"
+	ErrGeoRestricted    = "geo_restricted"
 	ErrRateLimited      = "rate_limited"
 	ErrScrapeFailed     = "scrape_failed"
+	ErrCaptionEncoding  = "caption_encoding"
 	ErrLLMError         = "llm_error"
 	ErrInvalidRequest   = "invalid_request"
+	ErrInternal         = "internal_error"
 )
 
-var (
-	serverStartTime time.Time
-	lastSuccessTime time.Time
-)
+// ServerConfig holds the dependencies of a Server. Fetch and Summarize
+// default to scraping YouTube and the configured LLM provider.
+type ServerConfig struct {
+	APIKey    string // required in X-API-Key or Authorization; empty disables auth
+	PinAPIKey bool   // keep APIKey on reload instead of re-reading YTSUMMARY_SERVER_API_KEY
+	// APIKeys are further accepted keys with per-key defaults, from
+	// YTSUMMARY_SERVER_API_KEYS (re-read on reload)
+	APIKeys map[string]apiKeySettings
+	Cache   Cache
+	// DeadLetters enables the /admin/deadletter endpoints when set
+	DeadLetters DeadLetterStore
+	// Summaries keeps generated summaries and enables /videos/{id}/summaries when set
+	Summaries SummaryStore
+	// Notes keeps users' notes on videos and enables /videos/{id}/notes when set
+	Notes     NotesStore
+	Fetch     func(url, lang string, allowTranslate bool) (*FetchResult, error)
+	Summarize func(transcript string, opts SummaryOptions) (string, error)
 
-// startServer starts the HTTP server with graceful shutdown
-func startServer(addr string, apiKey string) error {
-	serverStartTime = time.Now()
+	// SharedRateLimit, when set, counts the per-IP limits in Redis so they
+	// hold across replicas
+	SharedRateLimit *redisRateLimiter
+	// Locks keep replicas from fetching the same video at once; when nil,
+	// requests are only serialized within this server
+	Locks videoLocker
+	// RequestDeadline bounds how long a request may take; /summarize answers
+	// with partial results when it passes. 0 means no deadline.
+	RequestDeadline time.Duration
+	// RateLimitPerMinute and RateLimitBurst are the per-IP limits and
+	// WorkSlots how much fetching and summarizing runs at once; 0 uses the
+	// defaults. A reload re-reads them from the environment.
+	RateLimitPerMinute int
+	RateLimitBurst     int
+	WorkSlots          int
+	// YouTubeTimeout bounds each YouTube request made by the default Fetch
+	// and the video endpoints; 0 uses the default
+	YouTubeTimeout time.Duration
+	// Defaults apply to requests that leave the options out; a reload
+	// re-reads them from the environment
+	Defaults RequestDefaults
+}
 
-	// Initialize logger (INFO level for production)
-	initLogger(slog.LevelInfo)
-	logInfo("starting server", slog.String("addr", addr))
+// RequestDefaults are the server-wide defaults for request options
+type RequestDefaults struct {
+	AllowAutoTranslate   bool        // YTSUMMARY_ALLOW_AUTO_TRANSLATE
+	FallbackToTranscript bool        // YTSUMMARY_FALLBACK_TO_TRANSCRIPT
+	Experiment           *experiment // YTSUMMARY_EXPERIMENT; nil runs none
+}
 
-	mux := http.NewServeMux()
+// Server is the HTTP API. Each Server has its own cache, rate limiter and
+// health state, so several can run in one process.
+type Server struct {
+	pinAPIKey   bool
+	apiKey      atomic.Pointer[string] // can change on reload
+	apiKeys     atomic.Pointer[map[string]apiKeySettings]
+	cache       Cache
+	deadLetters DeadLetterStore
+	summaries   SummaryStore
+	notes       NotesStore
+	fetch       func(url, lang string, allowTranslate bool) (*FetchResult, error)
+	summarize   func(transcript string, opts SummaryOptions) (string, error)
+	limiter     *ipRateLimiter
+	activity    *serverActivity
+	audit       *auditLog
+	queue       *workQueue
+	locks       videoLocker
+	deadline    time.Duration
+	youtube     *http.Client                    // for the video endpoints' YouTube requests
+	defaults    atomic.Pointer[RequestDefaults] // can change on reload
+	prefetches  sync.WaitGroup                  // queued prefetches still running
+	startTime   time.Time
 
-	// Wrap handlers with API key auth if configured
-	authMiddleware := func(next http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			if apiKey != "" {
-				providedKey := r.Header.Get("X-API-Key")
-				if providedKey == "" {
-					providedKey = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
-				}
-				if providedKey != apiKey {
-					writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing API key")
-					return
-				}
-			}
-			next(w, r)
+	mu          sync.Mutex
+	lastSuccess time.Time
+}
+
+func newServer(cfg ServerConfig) *Server {
+	if cfg.YouTubeTimeout <= 0 {
+		cfg.YouTubeTimeout = defaultYouTubeTimeout
+	}
+	youtube := egressClient(cfg.YouTubeTimeout)
+	if cfg.Fetch == nil {
+		cfg.Fetch = func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return fetchTranscriptWith(youtube, url, lang, allowTranslate)
 		}
 	}
+	if cfg.Summarize == nil {
+		cfg.Summarize = summarize
+	}
+	if cfg.Locks == nil {
+		cfg.Locks = newLocalVideoLocks()
+	}
+	if cfg.RateLimitPerMinute <= 0 {
+		cfg.RateLimitPerMinute = rateLimitPerMinute
+	}
+	if cfg.RateLimitBurst <= 0 {
+		cfg.RateLimitBurst = rateLimitBurst
+	}
+	if cfg.WorkSlots <= 0 {
+		cfg.WorkSlots = defaultWorkSlots
+	}
+	s := &Server{
+		pinAPIKey:   cfg.PinAPIKey,
+		cache:       cfg.Cache,
+		deadLetters: cfg.DeadLetters,
+		summaries:   cfg.Summaries,
+		notes:       cfg.Notes,
+		fetch:       cfg.Fetch,
+		summarize:   cfg.Summarize,
+		limiter:     newRateLimiter(cfg.RateLimitPerMinute, cfg.RateLimitBurst),
+		activity:    newServerActivity(),
+		audit:       newAuditLog(),
+		queue:       newWorkQueue(cfg.WorkSlots),
+		locks:       cfg.Locks,
+		deadline:    cfg.RequestDeadline,
+		youtube:     youtube,
+		startTime:   time.Now(),
+	}
+	// Summaries are timed for the dashboard's latency sparkline
+	s.summarize = func(transcript string, opts SummaryOptions) (string, error) {
+		start := time.Now()
+		summary, err := cfg.Summarize(transcript, opts)
+		s.activity.recordLLM(time.Since(start), err)
+		return summary, err
+	}
+	s.limiter.shared = cfg.SharedRateLimit
+	s.setAPIKey(cfg.APIKey)
+	s.setAPIKeys(cfg.APIKeys)
+	s.setRequestDefaults(cfg.Defaults)
+	return s
+}
 
-	// Initialize rate limiter
-	initRateLimiter()
+func (s *Server) setRequestDefaults(d RequestDefaults) {
+	s.defaults.Store(&d)
+}
 
-	// Routes (rate limiting applied to all endpoints except health)
-	mux.HandleFunc("GET /health", handleHealth)
-	mux.HandleFunc("POST /transcript", rateLimitMiddleware(authMiddleware(handleTranscript)))
-	mux.HandleFunc("POST /summarize", rateLimitMiddleware(authMiddleware(handleSummarize)))
+func (s *Server) requestDefaults() RequestDefaults {
+	return *s.defaults.Load()
+}
+
+// requestDefaultsFromEnv reads RequestDefaults from their flags and
+// environment variables
+func requestDefaultsFromEnv() (RequestDefaults, error) {
+	exp, err := experimentFromEnv()
+	if err != nil {
+		return RequestDefaults{}, err
+	}
+	return RequestDefaults{
+		AllowAutoTranslate:   autoTranslateAllowed(),
+		FallbackToTranscript: fallbackToTranscriptDefault(),
+		Experiment:           exp,
+	}, nil
+}
+
+func (s *Server) setAPIKey(key string) {
+	s.apiKey.Store(&key)
+}
+
+func (s *Server) getAPIKey() string {
+	return *s.apiKey.Load()
+}
+
+// markSuccess records a successful request for the health check
+func (s *Server) markSuccess() {
+	s.mu.Lock()
+	s.lastSuccess = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *Server) lastSuccessTime() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSuccess
+}
+
+// startServer starts the HTTP server with graceful shutdown
+func startServer(addr string, s *Server) error {
+	// Initialize logger (INFO level for production)
+	initLogger(slog.LevelInfo)
+	logInfo("starting server", slog.String("addr", addr), slog.String("build", getBuildInfo().String()))
 
 	// Create server with timeouts and logging
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      loggingMiddleware(http.MaxBytesHandler(mux, maxRequestBodySize)),
+		Handler:      s.Handler(),
 		ReadTimeout:  serverReadTimeout,
-		WriteTimeout: serverWriteTimeout,
+		WriteTimeout: max(serverWriteTimeout, s.deadline+10*time.Second), // room to send partial results
 		IdleTimeout:  serverIdleTimeout,
 	}
 
@@ -117,7 +450,19 @@ func startServer(addr string, apiKey string) error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	// Reload config on SIGHUP without interrupting the server
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			resp, err := s.reload()
+			logReload("sighup", resp, err)
+		}
+	}()
+
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		<-quit
 		logInfo("shutdown signal received, gracefully stopping server")
 
@@ -127,21 +472,163 @@ func startServer(addr string, apiKey string) error {
 		if err := server.Shutdown(ctx); err != nil {
 			logError("server forced to shutdown", slog.String("error", err.Error()))
 		}
+
+		// Let queued prefetches finish rather than drop fetches already underway
+		s.prefetches.Wait()
 	}()
 
-	logInfo("server started", slog.String("addr", addr), slog.Bool("auth_enabled", apiKey != ""))
+	logInfo("server started", slog.String("addr", addr), slog.Bool("auth_enabled", s.authEnabled()))
 
 	if err := server.ListenAndServe(); err != http.ErrServerClosed {
 		logError("server error", slog.String("error", err.Error()))
 		return fmt.Errorf("server error: %w", err)
 	}
 
+	<-stopped
 	logInfo("server stopped")
 	return nil
 }
 
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	cacheCount, err := getCacheStats()
+// requireAPIKey rejects requests without one of the server's API keys, if
+// any are set, and applies the key's defaults to the request
+func (s *Server) requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.authEnabled() {
+			providedKey := r.Header.Get("X-API-Key")
+			if providedKey == "" {
+				providedKey = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			}
+			// Browsers (for /admin/dashboard) send the key as the Basic auth password
+			if _, password, ok := r.BasicAuth(); ok {
+				providedKey = password
+			}
+			settings, ok := s.lookupAPIKey(providedKey)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Basic realm="ytsummary"`)
+				writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing API key")
+				return
+			}
+			getRequestContext(r).KeyLanguage = settings.Language
+		}
+		next(w, r)
+	}
+}
+
+// Handler builds the full HTTP handler: routes, auth, rate limiting and logging
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// Wraps a handler with rate limiting and API key auth
+	protected := func(h http.HandlerFunc) http.HandlerFunc {
+		return s.rateLimit(s.requireAPIKey(h))
+	}
+	// Like protected, but closed entirely while no API key is configured
+	restricted := func(h http.HandlerFunc) http.HandlerFunc {
+		return protected(s.requireAuthConfigured(h))
+	}
+
+	// Every route is served under /v1 and, for clients written before
+	// versioning, unversioned
+	methods := make(map[string][]string) // by path, for 405 responses
+	var paths []string
+	route := func(pattern string, h http.HandlerFunc) {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.HandleFunc(method+" "+apiVersionPrefix+path, h)
+		mux.HandleFunc(pattern, legacyRoute(h))
+		if methods[path] == nil {
+			paths = append(paths, path)
+		}
+		methods[path] = append(methods[path], method)
+	}
+
+	// Routes (rate limiting applied to all endpoints except health)
+	route("GET /health", s.handleHealth)
+	route("POST /transcript", protected(s.withTimeline(s.handleTranscript)))
+	route("GET /transcript", protected(s.withTimeline(s.handleTranscript)))
+	route("POST /summarize", protected(s.withTimeline(s.handleSummarize)))
+	route("GET /summarize", protected(s.withTimeline(s.handleSummarize)))
+	route("POST /summarize/text", protected(s.handleSummarizeText))
+	route("GET /video/{id}", protected(s.handleVideoInfo))
+	route("GET /video/{id}/languages", protected(s.handleVideoLanguages))
+	route("GET /cache/{id}", protected(s.handleCacheProbe))
+	route("POST /cache/status", protected(s.handleCacheStatus))
+	route("POST /prefetch", protected(s.withTimeline(s.handlePrefetch)))
+	route("POST /normalize", protected(s.handleNormalize))
+	route("GET /export", restricted(s.handleExport))
+	route("POST /admin/reload", restricted(s.handleReload))
+	route("GET /admin/dashboard", restricted(s.handleDashboard))
+	route("GET /admin/audit/{id}", restricted(s.handleAudit))
+	route("GET /admin/queue", restricted(s.handleQueue))
+	if s.deadLetters != nil {
+		route("GET /admin/deadletter", restricted(s.handleDeadLetters))
+		route("POST /admin/deadletter/{id}/retry", restricted(s.handleDeadLetterAction(DeadLetterStore.RetryDeadLetter)))
+		route("POST /admin/deadletter/{id}/suppress", restricted(s.handleDeadLetterAction(DeadLetterStore.SuppressDeadLetter)))
+	}
+	if s.summaries != nil {
+		route("GET /videos/{id}/summaries", protected(s.handleVideoSummaries))
+		route("GET /videos/{id}/summaries/{summary}", protected(s.handleVideoSummary))
+		route("POST /summaries/{summary}/feedback", protected(s.handleSummaryFeedback))
+	}
+	if s.notes != nil {
+		route("GET /videos/{id}/notes", protected(s.handleGetNotes))
+		route("PATCH /videos/{id}/notes", protected(s.handlePatchNotes))
+	}
+
+	// Other methods on those paths get a JSON 405 with an Allow header
+	for _, path := range paths {
+		h := methodNotAllowed(methods[path])
+		for _, method := range unroutedMethods(methods[path]) {
+			mux.Handle(method+" "+apiVersionPrefix+path, h)
+			mux.Handle(method+" "+path, h)
+		}
+	}
+
+	return versionHeaderMiddleware(securityHeadersMiddleware(loggingMiddleware(s.activity.middleware(s.recoveryMiddleware(deadlineMiddleware(s.deadline, jsonBodyMiddleware(bodyLimitMiddleware(mux))))))))
+}
+
+// apiVersionPrefix is the current API version. Responses under it keep their
+// schema; breaking changes will get a new prefix.
+const apiVersionPrefix = "/v1"
+
+// legacyRoute serves an unversioned route, pointing clients at its /v1 twin.
+// It answers exactly as the /v1 route does.
+func legacyRoute(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, apiVersionPrefix, r.URL.Path))
+		h(w, r)
+	}
+}
+
+// Per-route request body limits, keyed by path.Match pattern so routes with
+// path parameters match; everything else gets maxRequestBodySize
+var routeBodyLimits = map[string]int64{
+	"/summarize/text": maxTextRequestBodySize,
+	"/cache/status":   maxCacheStatusBodySize,
+	"/normalize":      maxNormalizeBodySize,
+	"/videos/*/notes": maxNotesBodySize,
+}
+
+// routeBodyLimit returns the request body limit for a path without the
+// version prefix
+func routeBodyLimit(p string) int64 {
+	for pattern, limit := range routeBodyLimits {
+		if ok, _ := path.Match(pattern, p); ok {
+			return limit
+		}
+	}
+	return maxRequestBodySize
+}
+
+// bodyLimitMiddleware caps request body size based on the route
+func bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, routeBodyLimit(strings.TrimPrefix(r.URL.Path, apiVersionPrefix)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	cacheCount, err := s.cache.CountTranscripts()
 	status := "ok"
 	if err != nil {
 		status = "unhealthy"
@@ -151,143 +638,525 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 	resp := HealthResponse{
 		Status:        status,
 		CacheEntries:  cacheCount,
-		UptimeSeconds: int64(time.Since(serverStartTime).Seconds()),
+		UptimeSeconds: int64(time.Since(s.startTime).Seconds()),
+		Panics:        s.activity.panics.Load(),
+		Build:         getBuildInfo(),
+	}
+	if locks, ok := s.locks.(*redisVideoLocks); ok {
+		resp.LockFallbacks = locks.fallbacks.Load()
 	}
 
-	if !lastSuccessTime.IsZero() {
-		resp.LastSuccess = lastSuccessTime.Format(time.RFC3339)
-		resp.LastSuccessAgeSeconds = int64(time.Since(lastSuccessTime).Seconds())
+	if lastSuccess := s.lastSuccessTime(); !lastSuccess.IsZero() {
+		resp.LastSuccess = lastSuccess.Format(time.RFC3339)
+		resp.LastSuccessAgeSeconds = int64(time.Since(lastSuccess).Seconds())
 
 		// Degraded if no success in over an hour
 		if resp.LastSuccessAgeSeconds > 3600 && status == "ok" {
 			resp.Status = "degraded"
 		}
 	}
+	if drift := youtubeSchema.driftedFields(); drift != nil {
+		resp.SchemaDrift = drift
+		if resp.Status == "ok" {
+			resp.Status = "degraded"
+		}
+	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
 
-func handleTranscript(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleTranscript(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
+	reqCtx := getRequestContext(r)
 
+	parsed := reqCtx.Timeline.begin(stageParse)
 	req, videoID, lang, err := parseRequest(r)
+	parsed(err)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		writeParseError(w, err)
 		return
 	}
 
 	// Update request context for logging
-	reqCtx := getRequestContext(r)
-	reqCtx.VideoID = videoID
+	reqCtx.setVideo(videoID)
+	reqCtx.Timeline.setVideo(videoID)
 
-	// Check cache
-	cached := false
-	var transcript, title string
+	if req.Offset < 0 || req.Limit < 0 || req.Limit > maxPageSegments {
+		writeErrorWithVideo(w, http.StatusBadRequest, ErrInvalidRequest, fmt.Sprintf("offset must be >= 0 and limit between 1 and %d", maxPageSegments), videoID)
+		return
+	}
+	asSegments := req.Format == transcriptFormatSegments
+	asWords := req.Format == transcriptFormatWords
+	if req.Format != "" && req.Format != transcriptFormatText && !asSegments && !asWords {
+		writeErrorWithVideo(w, http.StatusBadRequest, ErrInvalidRequest, fmt.Sprintf("unknown format %q (use text, segments or words)", req.Format), videoID)
+		return
+	}
 
-	entry, err := getCachedTranscript(videoID, lang)
-	if err == nil {
-		cached = true
-		transcript = entry.Transcript
-		title = entry.Title
-		logDebug("cache hit", slog.String("video_id", videoID), slog.String("language", lang))
-	} else {
-		logDebug("cache miss, fetching transcript", slog.String("video_id", videoID))
-		// Fetch transcript
-		transcript, err = fetchTranscript(req.URL)
-		if err != nil {
-			logWarn("fetch failed", slog.String("video_id", videoID), slog.String("error", err.Error()))
-			handleFetchError(w, err, videoID)
+	// Check cache, fetching on a miss
+	need := timingNone
+	switch {
+	case asWords:
+		need = timingWords
+	case req.window != nil || req.paginated() || asSegments:
+		need = timingSegments
+	}
+	entry, cached, err := s.getOrFetchTranscript(r, canonicalVideoURL(videoID), videoID, lang, need, req.AllowAutoTranslate || s.requestDefaults().AllowAutoTranslate, req.Priority)
+	if err != nil {
+		handleFetchError(w, err, videoID)
+		return
+	}
+	if req.window != nil {
+		if err := restrictToRange(entry, *req.window); err != nil {
+			writeErrorWithVideo(w, http.StatusNotFound, ErrNoCaptions, err.Error(), videoID)
 			return
 		}
+	}
+	quality := assessTranscriptQuality(entry)
+	notes := applyContentFilter(entry, req.ContentFilter)
 
-		// Cache it
-		_ = cacheTranscript(videoID, lang, "", transcript)
+	// Paginate last so quality and content notes cover the whole transcript
+	var page *TranscriptPage
+	var segments []TranscriptSegment
+	if req.paginated() {
+		if page, err = paginateSegments(entry, req.Offset, req.Limit); err != nil {
+			writeErrorWithVideo(w, http.StatusNotFound, ErrNoCaptions, err.Error(), videoID)
+			return
+		}
+		segments = withoutWords(entry.Segments)
+	}
+	transcript, title := entry.Transcript, entry.Title
+	var words []TranscriptWord
+	switch {
+	case asSegments:
+		if len(entry.Segments) == 0 {
+			writeErrorWithVideo(w, http.StatusNotFound, ErrNoCaptions, "caption timings unavailable for this video", videoID)
+			return
+		}
+		// Segments replace the text rather than repeating it
+		transcript, segments = "", withoutWords(entry.Segments)
+	case asWords:
+		if words = transcriptWords(entry.Segments); len(words) == 0 {
+			writeErrorWithVideo(w, http.StatusNotFound, ErrNoCaptions, errNoWordTimings.Error(), videoID)
+			return
+		}
+		transcript, segments = "", nil
 	}
 
 	reqCtx.CacheHit = cached
-	lastSuccessTime = time.Now()
-
-	writeJSON(w, http.StatusOK, TranscriptResponse{
-		VideoID:    videoID,
-		Title:      title,
-		Transcript: transcript,
-		Language:   lang,
-		Cached:     cached,
-		DurationMS: time.Since(start).Milliseconds(),
+	reqCtx.Source = transcriptSource(entry, cached)
+	s.markSuccess()
+
+	s.writeTranscriptResponse(w, r, TranscriptResponse{
+		VideoID:           videoID,
+		CanonicalURL:      canonicalVideoURL(videoID),
+		Title:             title,
+		Transcript:        transcript,
+		Language:          lang,
+		Cached:            cached,
+		DurationMS:        time.Since(start).Milliseconds(),
+		Source:            transcriptSource(entry, cached),
+		FetchedAt:         entry.FetchedAt.UTC(),
+		TranslatedFrom:    entry.TranslatedFrom,
+		TranscriptQuality: quality,
+		ContentNotes:      notes,
+		Format:            videoFormat(entry),
+		Segments:          segments,
+		Words:             words,
+		Page:              page,
 	})
 }
 
-func handleSummarize(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleSummarize(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
+	reqCtx := getRequestContext(r)
 
+	parsed := reqCtx.Timeline.begin(stageParse)
 	req, videoID, lang, err := parseRequest(r)
+	parsed(err)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		writeParseError(w, err)
 		return
 	}
 
 	// Update request context for logging
-	reqCtx := getRequestContext(r)
-	reqCtx.VideoID = videoID
+	reqCtx.setVideo(videoID)
+	reqCtx.Timeline.setVideo(videoID)
 
-	// Check cache for transcript
-	cached := false
-	var transcript, title string
+	// Requests that don't pick a template take part in the running experiment
+	var variant *experimentVariant
+	exp := s.requestDefaults().Experiment
+	if req.Template == "" && exp != nil {
+		v := exp.pick()
+		variant, req.Template = &v, v.Template
+	}
 
-	entry, err := getCachedTranscript(videoID, lang)
-	if err == nil {
-		cached = true
-		transcript = entry.Transcript
-		title = entry.Title
-		logDebug("cache hit", slog.String("video_id", videoID), slog.String("language", lang))
-	} else {
-		logDebug("cache miss, fetching transcript", slog.String("video_id", videoID))
-		// Fetch transcript
-		transcript, err = fetchTranscript(req.URL)
-		if err != nil {
-			logWarn("fetch failed", slog.String("video_id", videoID), slog.String("error", err.Error()))
-			handleFetchError(w, err, videoID)
+	// Validate the template before doing any expensive work
+	if _, err := getTemplate(req.Template); err != nil {
+		writeErrorWithVideo(w, http.StatusBadRequest, ErrInvalidRequest, err.Error(), videoID)
+		return
+	}
+
+	// Check cache for transcript, fetching on a miss
+	highlights := req.Mode == summaryModeHighlights
+	need := timingNone
+	if req.window != nil || req.focus != nil || req.Subtitles || highlights {
+		need = timingSegments
+	}
+	entry, cached, err := s.getOrFetchTranscript(r, canonicalVideoURL(videoID), videoID, lang, need, req.AllowAutoTranslate || s.requestDefaults().AllowAutoTranslate, req.Priority)
+	if err != nil {
+		handleFetchError(w, err, videoID)
+		return
+	}
+	if req.window != nil {
+		if err := restrictToRange(entry, *req.window); err != nil {
+			writeErrorWithVideo(w, http.StatusNotFound, ErrNoCaptions, err.Error(), videoID)
 			return
 		}
-
-		// Cache it
-		_ = cacheTranscript(videoID, lang, "", transcript)
+	}
+	if (req.Subtitles || highlights) && len(entry.Segments) == 0 {
+		writeErrorWithVideo(w, http.StatusNotFound, ErrNoCaptions, "caption timings unavailable for this video", videoID)
+		return
+	}
+	quality := assessTranscriptQuality(entry)
+	notes := applyContentFilter(entry, req.ContentFilter)
+	transcript, title := entry.Transcript, entry.Title
+	format := videoFormat(entry)
+	template, err := pickTemplate(req.Template, entry, req.MusicVideos)
+	if err != nil {
+		writeErrorWithVideo(w, http.StatusUnprocessableEntity, ErrMusicVideo, err.Error(), videoID)
+		return
+	}
+	// Experiment arms compare fixed templates, so only other requests get
+	// the template suited to the video's format
+	if variant == nil {
+		req.Template = template
+	}
+	// A client revalidating a summary it was given gets 304 Not Modified
+	// without another LLM call
+	if variant == nil && !highlights {
+		if etag, ok := s.revalidateSummary(r, *req, videoID, requestSummaryLanguage(req.SummaryLanguage, lang)); ok {
+			s.setSummaryCacheHeaders(w, etag)
+			w.Header().Add("Vary", varyHeaders)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	if quality != nil && quality.Warning != "" {
+		logWarn("summarizing low-quality captions", slog.String("video_id", videoID), slog.Float64("transcript_quality", quality.Score))
+	}
+	if !req.KeepNonSpeech {
+		stripCaptionArtifacts(entry)
 	}
 
 	reqCtx.CacheHit = cached
+	reqCtx.Source = transcriptSource(entry, cached)
+
+	budget, err := requestBudget(req.MaxCost)
+	if err != nil {
+		writeErrorWithVideo(w, http.StatusInternalServerError, ErrInternal, err.Error(), videoID)
+		return
+	}
+	opts := SummaryOptions{
+		Template:       req.Template,
+		Vars:           promptVarsFromEntry(entry, requestSummaryLanguage(req.SummaryLanguage, lang)),
+		PromptLanguage: req.PromptLanguage,
+		Checkpoints:    s.cache,
+		Generation:     req.GenerationParams,
+		MaxWords:       req.MaxWords,
+		Lint:           req.Lint || lintEnabled(),
+		Budget:         budget,
+		Timeline:       reqCtx.Timeline,
+		Meta:           &SummaryMeta{},
+	}
+	if variant != nil {
+		opts.Model = variant.Model
+	}
+	if req.focus != nil {
+		opts.Focus = focusOnLinkedSection(entry, *req.focus)
+	}
+	if req.WithNotes && s.notes != nil {
+		if opts.Notes, err = videoNotesText(s.notes, videoID); err != nil {
+			writeErrorWithVideo(w, http.StatusInternalServerError, ErrInternal, "Failed to read notes", videoID)
+			return
+		}
+	}
 
 	// Summarize
 	logDebug("starting summarization", slog.String("video_id", videoID), slog.Int("transcript_len", len(transcript)))
-	summary, err := summarize(transcript)
+	// Past the request deadline, answer with the transcript and the chunk
+	// summaries done so far rather than keep the client waiting
+	writeTimedOut := func() {
+		logWarn("request deadline passed before the summary", slog.String("video_id", videoID))
+		writeTranscriptBody(w, r, TranscriptResponse{
+			VideoID:           videoID,
+			CanonicalURL:      canonicalVideoURL(videoID),
+			Title:             title,
+			Transcript:        transcript,
+			Language:          lang,
+			Cached:            cached,
+			DurationMS:        time.Since(start).Milliseconds(),
+			Source:            transcriptSource(entry, cached),
+			FetchedAt:         entry.FetchedAt.UTC(),
+			TranslatedFrom:    entry.TranslatedFrom,
+			TranscriptQuality: quality,
+			ContentNotes:      notes,
+			TimedOut:          true,
+			ChunkSummaries:    opts.Partial.summaries(),
+		})
+	}
+	opts.Partial = &partialSummary{}
+
+	// One request at a time summarizes a video with the same options (across
+	// replicas when the locks are in Redis); the others wait, then reuse its
+	// summary rather than call the LLM again
+	var requestHash string
+	var reused *StoredSummary
+	if s.summaries != nil && !highlights {
+		requestHash = summaryRequestHash(videoID, opts.Vars.Language, configuredModel(opts.Model), *req)
+		var unlock func()
+		unlock, reused, err = s.lockSummary(r, videoID, requestHash)
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeTimedOut()
+			return
+		}
+		if err != nil {
+			writeQueueError(w, err, videoID)
+			return
+		}
+		defer unlock()
+	}
+
+	var summary string
+	var moments []Highlight
+	var truncation *Truncation
+	switch {
+	case reused != nil:
+		logDebug("reusing summary made while waiting for lock", slog.String("video_id", videoID), slog.Int64("summary_id", reused.ID))
+		summary = reused.Summary
+		_, truncation = truncateTranscript(entry.Transcript, req.MaxTranscriptChars, req.TruncateStrategy)
+	default:
+		var release func()
+		release, err = s.waitForSlot(r, req.Priority)
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeTimedOut()
+			return
+		}
+		if err != nil {
+			writeQueueError(w, err, videoID)
+			return
+		}
+		if highlights {
+			moments, err = extractHighlights(s.summarize, entry.Segments, req.Count, opts)
+			release()
+		} else {
+			var summarized string
+			summarized, truncation = truncateTranscript(entry.Transcript, req.MaxTranscriptChars, req.TruncateStrategy)
+			summary, err = s.summarizeWithin(r, summarized, opts, release)
+		}
+	}
+	switch {
+	case errors.Is(err, errRequestDeadline):
+		writeTimedOut()
+		return
+	case errors.Is(err, errSummaryPanicked):
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{
+			Error:     ErrInternal,
+			Message:   "Internal server error",
+			VideoID:   videoID,
+			RequestID: reqCtx.ID,
+		})
+		return
+	}
 	if err != nil {
 		logError("summarization failed", slog.String("video_id", videoID), slog.String("error", err.Error()))
-		// Return transcript even if summarization fails (graceful degradation)
-		writeJSON(w, http.StatusOK, TranscriptResponse{
-			VideoID:    videoID,
-			Title:      title,
-			Transcript: transcript,
-			Language:   lang,
-			Cached:     cached,
-			DurationMS: time.Since(start).Milliseconds(),
+		if !req.FallbackToTranscript && !s.requestDefaults().FallbackToTranscript {
+			writeLLMError(w, err, videoID, transcript)
+			return
+		}
+		// Graceful degradation was requested: return the transcript alone
+		writeTranscriptBody(w, r, TranscriptResponse{
+			VideoID:           videoID,
+			CanonicalURL:      canonicalVideoURL(videoID),
+			Title:             title,
+			Transcript:        transcript,
+			Language:          lang,
+			Cached:            cached,
+			DurationMS:        time.Since(start).Milliseconds(),
+			Source:            transcriptSource(entry, cached),
+			FetchedAt:         entry.FetchedAt.UTC(),
+			TranslatedFrom:    entry.TranslatedFrom,
+			TranscriptQuality: quality,
+			ContentNotes:      notes,
+			SummaryError:      err.Error(),
 		})
 		return
 	}
 
-	lastSuccessTime = time.Now()
+	s.markSuccess()
+	if highlights {
+		var chapters string
+		if req.Format == summaryFormatChapters {
+			chapters, _ = youtubeChapters(moments)
+		}
+		writeTranscriptBody(w, r, TranscriptResponse{
+			VideoID:           videoID,
+			CanonicalURL:      canonicalVideoURL(videoID),
+			Title:             title,
+			Language:          lang,
+			Cached:            cached,
+			DurationMS:        time.Since(start).Milliseconds(),
+			Source:            transcriptSource(entry, cached),
+			FetchedAt:         entry.FetchedAt.UTC(),
+			TranslatedFrom:    entry.TranslatedFrom,
+			TranscriptQuality: quality,
+			ContentNotes:      notes,
+			Highlights:        moments,
+			Chapters:          chapters,
+		})
+		return
+	}
+	kept := reused
+	if kept == nil {
+		kept = newStoredSummary(videoID, opts.Vars.Language, req.Template, req.MaxWords, summary, opts.Meta)
+		if variant != nil {
+			kept.Experiment, kept.Variant = exp.Name, variant.Name
+		}
+		kept.RequestHash = requestHash
+		keepSummary(s.summaries, kept)
+	}
+	var subtitles string
+	if req.Subtitles {
+		subtitles = formatSRT(condensedSubtitles(entry.Segments, summary))
+	}
+
+	s.writeSummaryResponse(w, r, TranscriptResponse{
+		VideoID:           videoID,
+		CanonicalURL:      canonicalVideoURL(videoID),
+		Title:             title,
+		Summary:           withTruncationNote(withContentNote(summary, notes), truncation),
+		Language:          lang,
+		Cached:            cached,
+		DurationMS:        time.Since(start).Milliseconds(),
+		Source:            transcriptSource(entry, cached),
+		FetchedAt:         entry.FetchedAt.UTC(),
+		TranslatedFrom:    entry.TranslatedFrom,
+		TranscriptQuality: quality,
+		ContentNotes:      notes,
+		Format:            format,
+		Template:          kept.Template,
+		SummaryID:         kept.ID,
+		Experiment:        kept.Experiment,
+		Variant:           kept.Variant,
+		Subtitles:         subtitles,
+		Truncation:        truncation,
+	}, *req, kept)
+}
 
-	writeJSON(w, http.StatusOK, TranscriptResponse{
-		VideoID:    videoID,
-		Title:      title,
-		Summary:    summary,
-		Language:   lang,
-		Cached:     cached,
-		DurationMS: time.Since(start).Milliseconds(),
-	})
+// getOrFetchTranscript returns the cached transcript, fetching and caching it
+// on a miss. Entries cached without the caption timings need asks for are refetched;
+// machine-translated entries are refetched unless allowTranslate is set. Fetches
+// wait for a work slot at the given priority.
+func (s *Server) getOrFetchTranscript(r *http.Request, url, videoID, lang string, need timingDetail, allowTranslate bool, priority string) (*CacheEntry, bool, error) {
+	timeline := getRequestContext(r).Timeline
+	lookedUp := timeline.begin(stageCacheLookup)
+	usable := func(entry *CacheEntry) bool {
+		return hasTimings(entry, need) && (entry.TranslatedFrom == "" || allowTranslate)
+	}
+	entry, err := s.cache.GetTranscript(videoID, lang)
+	lookedUp(nil)
+	if err == nil && usable(entry) {
+		logDebug("cache hit", slog.String("video_id", videoID), slog.String("language", lang))
+		return entry, true, nil
+	}
+
+	// One request at a time fetches a video (across replicas when the locks
+	// are in Redis); the others wait and find its result in the cache
+	locked := timeline.begin(stageLock)
+	unlock, err := s.locks.lock(r.Context(), videoLockKey(videoID, lang))
+	locked(err)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+	if entry, err := s.cache.GetTranscript(videoID, lang); err == nil && usable(entry) {
+		logDebug("fetched while waiting for lock", slog.String("video_id", videoID), slog.String("language", lang))
+		return entry, true, nil
+	}
+
+	logDebug("cache miss, fetching transcript", slog.String("video_id", videoID))
+	release, err := s.waitForSlot(r, priority)
+	if err != nil {
+		return nil, false, err
+	}
+	defer release()
+	fetchStart := time.Now()
+	result, err := s.fetch(url, lang, allowTranslate)
+	if err != nil || len(result.Stages) == 0 {
+		timeline.add(stageFetch, fetchStart, time.Since(fetchStart), err)
+	} else {
+		timeline.addAll(result.Stages)
+	}
+	if err != nil {
+		logWarn("fetch failed", slog.String("video_id", videoID), slog.String("error", err.Error()))
+		return nil, false, err
+	}
+
+	// Cache it
+	_ = cacheFetchResult(s.cache, videoID, lang, result)
+
+	entry = result.cacheEntry(videoID, lang)
+	entry.FetchedAt = time.Now()
+	return entry, false, nil
+}
+
+// lockSummary waits for the lock on summarizing videoID with the request
+// options requestHash identifies. A summary another request kept for them
+// while this one waited is returned for reuse.
+func (s *Server) lockSummary(r *http.Request, videoID, requestHash string) (func(), *StoredSummary, error) {
+	var before int64
+	if latest, err := s.summaries.LatestRequestSummary(videoID, requestHash); err == nil {
+		before = latest.ID
+	}
+
+	locked := getRequestContext(r).Timeline.begin(stageLock)
+	unlock, err := s.locks.lock(r.Context(), summaryLockKey(videoID, requestHash))
+	locked(err)
+	if err != nil {
+		return nil, nil, err
+	}
+	if latest, err := s.summaries.LatestRequestSummary(videoID, requestHash); err == nil && latest.ID > before {
+		return unlock, latest, nil
+	}
+	return unlock, nil, nil
+}
+
+// Transcript sources reported in responses
+const (
+	sourceCache     = "cache"     // served from the transcript cache
+	sourceInnertube = "innertube" // uploaded captions fetched just now
+	sourceASR       = "asr"       // YouTube's auto-generated captions fetched just now
+)
+
+// transcriptSource reports where a transcript came from
+func transcriptSource(entry *CacheEntry, cached bool) string {
+	switch {
+	case cached:
+		return sourceCache
+	case entry.AutoGenerated:
+		return sourceASR
+	default:
+		return sourceInnertube
+	}
 }
 
 func parseRequest(r *http.Request) (*TranscriptRequest, string, string, error) {
 	var req TranscriptRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if r.Method == http.MethodGet {
+		var err error
+		if req, err = requestFromQuery(r.URL.Query()); err != nil {
+			return nil, "", "", err
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		return nil, "", "", fmt.Errorf("invalid JSON: %w", err)
 	}
 
@@ -295,34 +1164,130 @@ func parseRequest(r *http.Request) (*TranscriptRequest, string, string, error) {
 		return nil, "", "", fmt.Errorf("url is required")
 	}
 
-	videoID, err := extractVideoID(req.URL)
+	videoID, clip, err := resolveVideoURL(req.URL)
+	if errors.Is(err, errClipResolve) {
+		return nil, "", "", err
+	}
 	if err != nil {
 		return nil, "", "", fmt.Errorf("invalid YouTube URL: %w", err)
 	}
 
-	lang := req.Language
-	if lang == "" {
-		lang = defaultLanguage
+	// Explicit from/to take precedence over a clip's range
+	window, err := parseTimeRange(req.From, req.To)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if window == nil && req.ClipOnly {
+		window = clip
 	}
+	req.window = window
 
-	return &req, videoID, lang, nil
+	if req.ContentFilter == "" {
+		req.ContentFilter = contentFilterMode()
+	}
+	if err := validContentFilter(req.ContentFilter); err != nil {
+		return nil, "", "", err
+	}
+	if req.MusicVideos == "" {
+		req.MusicVideos = musicVideosMode()
+	}
+	if err := validMusicVideos(req.MusicVideos); err != nil {
+		return nil, "", "", err
+	}
+	if err := validLanguageCode("summary_language", req.SummaryLanguage); err != nil {
+		return nil, "", "", err
+	}
+	if err := validLanguageCode("prompt_language", req.PromptLanguage); err != nil {
+		return nil, "", "", err
+	}
+	if err := req.GenerationParams.validate(); err != nil {
+		return nil, "", "", err
+	}
+	if req.MaxWords < 0 {
+		return nil, "", "", fmt.Errorf("max_words must be positive")
+	}
+	if req.MaxCost < 0 {
+		return nil, "", "", fmt.Errorf("max_cost must be positive")
+	}
+	if err := validPriority(req.Priority); err != nil {
+		return nil, "", "", err
+	}
+	if err := validSummaryMode(req.Mode); err != nil {
+		return nil, "", "", err
+	}
+	if req.Mode == summaryModeHighlights {
+		if req.Count, err = highlightCount(req.Count); err != nil {
+			return nil, "", "", err
+		}
+		if req.Subtitles {
+			return nil, "", "", fmt.Errorf("subtitles need a written summary, not mode highlights")
+		}
+	} else if req.Count != 0 || req.Format == summaryFormatChapters {
+		return nil, "", "", fmt.Errorf("count and format chapters are only used with mode highlights")
+	}
+	if req.MaxTranscriptChars < 0 {
+		return nil, "", "", fmt.Errorf("max_transcript_chars must be positive")
+	}
+	if req.MaxTranscriptChars, req.TruncateStrategy, err = transcriptLimit(req.MaxTranscriptChars, req.TruncateStrategy); err != nil {
+		return nil, "", "", err
+	}
+
+	if at, ok := linkedTimestamp(req.URL); ok && req.FocusLinkedTimestamp {
+		req.focus = &at
+	}
+
+	return &req, videoID, requestLanguage(r, req.Language), nil
 }
 
-func handleFetchError(w http.ResponseWriter, err error, videoID string) {
+// writeParseError reports a parseRequest failure. Clip pages that can't be
+// resolved are upstream failures; everything else is a bad request.
+func writeParseError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errClipResolve) {
+		writeError(w, http.StatusBadGateway, ErrScrapeFailed, err.Error())
+		return
+	}
+	writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+}
+
+// fetchErrorClass maps a transcript fetch error to its error code
+func fetchErrorClass(err error) string {
 	errStr := err.Error()
 
-	// Map common errors to error codes
 	switch {
 	case strings.Contains(errStr, "no subtitles available"):
-		writeErrorWithVideo(w, http.StatusNotFound, ErrNoCaptions, "This video has no captions available", videoID)
+		return ErrNoCaptions
 	case strings.Contains(errStr, "Private video"):
-		writeErrorWithVideo(w, http.StatusNotFound, ErrVideoUnavailable, "Video is private or unavailable", videoID)
+		return ErrVideoUnavailable
 	case strings.Contains(errStr, "age-restricted"):
-		writeErrorWithVideo(w, http.StatusForbidden, ErrAgeRestricted, "Video is age-restricted", videoID)
+		return ErrAgeRestricted
+	case errors.Is(err, errGeoRestricted), strings.Contains(errStr, errGeoRestricted.Error()):
+		return ErrGeoRestricted
+	case errors.Is(err, errCaptionEncoding):
+		return ErrCaptionEncoding
 	case strings.Contains(errStr, "429"), strings.Contains(errStr, "rate"):
+		return ErrRateLimited
+	default:
+		return ErrScrapeFailed
+	}
+}
+
+func handleFetchError(w http.ResponseWriter, err error, videoID string) {
+	// Map common errors to error codes
+	switch fetchErrorClass(err) {
+	case ErrNoCaptions:
+		writeErrorWithVideo(w, http.StatusNotFound, ErrNoCaptions, "This video has no captions available", videoID)
+	case ErrVideoUnavailable:
+		writeErrorWithVideo(w, http.StatusNotFound, ErrVideoUnavailable, "Video is private or unavailable", videoID)
+	case ErrAgeRestricted:
+		writeErrorWithVideo(w, http.StatusForbidden, ErrAgeRestricted, "Video is age-restricted", videoID)
+	case ErrGeoRestricted:
+		writeErrorWithVideo(w, http.StatusUnavailableForLegalReasons, ErrGeoRestricted, err.Error(), videoID)
+	case ErrRateLimited:
 		writeErrorWithVideo(w, http.StatusTooManyRequests, ErrRateLimited, "Rate limited by YouTube, try again later", videoID)
+	case ErrCaptionEncoding:
+		writeErrorWithVideo(w, http.StatusBadGateway, ErrCaptionEncoding, err.Error(), videoID)
 	default:
-		writeErrorWithVideo(w, http.StatusBadGateway, ErrScrapeFailed, errStr, videoID)
+		writeErrorWithVideo(w, http.StatusBadGateway, ErrScrapeFailed, err.Error(), videoID)
 	}
 }
 
@@ -335,14 +1300,14 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 func writeError(w http.ResponseWriter, status int, code, message string) {
 	writeJSON(w, status, ErrorResponse{
 		Error:   code,
-		Message: message,
+		Message: redact(message),
 	})
 }
 
 func writeErrorWithVideo(w http.ResponseWriter, status int, code, message, videoID string) {
 	writeJSON(w, status, ErrorResponse{
 		Error:   code,
-		Message: message,
+		Message: redact(message),
 		VideoID: videoID,
 	})
 }
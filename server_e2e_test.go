@@ -0,0 +1,615 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const e2eAPIKey = "e2e-secret"
+
+// e2eHarness runs the full server handler (auth, rate limiting, logging)
+// against a fake YouTube and the fake LLM provider with an isolated cache
+type e2eHarness struct {
+	t       *testing.T
+	server  *httptest.Server
+	youtube *fakeYouTube
+	cache   *SQLiteCache
+}
+
+func newE2EHarness(t *testing.T) *e2eHarness {
+	t.Helper()
+
+	llmProvider = "fake"
+
+	h := &e2eHarness{t: t, youtube: newFakeYouTube(t), cache: newTestCache(t)}
+	h.server = httptest.NewServer(newServer(ServerConfig{APIKey: e2eAPIKey, Cache: h.cache, DeadLetters: h.cache, Summaries: h.cache}).Handler())
+
+	t.Cleanup(func() {
+		h.server.Close()
+		llmProvider = ""
+	})
+
+	return h
+}
+
+// post sends an authenticated JSON request, using a per-call client IP so
+// tests don't trip the rate limiter unless they mean to
+func (h *e2eHarness) post(path, body, clientIP string) *http.Response {
+	h.t.Helper()
+
+	req, err := http.NewRequest("POST", h.server.URL+path, bytes.NewBufferString(body))
+	if err != nil {
+		h.t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", e2eAPIKey)
+	req.Header.Set("X-Forwarded-For", clientIP)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatalf("request failed: %v", err)
+	}
+	return resp
+}
+
+// get sends an authenticated GET request
+func (h *e2eHarness) get(path, clientIP string) *http.Response {
+	h.t.Helper()
+
+	req, err := http.NewRequest("GET", h.server.URL+path, nil)
+	if err != nil {
+		h.t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("X-API-Key", e2eAPIKey)
+	req.Header.Set("X-Forwarded-For", clientIP)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatalf("request failed: %v", err)
+	}
+	return resp
+}
+
+func decodeBody[T any](t *testing.T, resp *http.Response) T {
+	t.Helper()
+	defer resp.Body.Close()
+
+	var v T
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return v
+}
+
+func TestE2E_TranscriptCacheMissThenHit(t *testing.T) {
+	h := newE2EHarness(t)
+	body := `{"url": "https://youtu.be/dQw4w9WgXcQ"}`
+
+	resp := h.post("/transcript", body, "10.0.0.1")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", resp.StatusCode)
+	}
+	first := decodeBody[TranscriptResponse](t, resp)
+	if first.Cached {
+		t.Error("first request should be a cache miss")
+	}
+	if first.Transcript == "" {
+		t.Error("expected transcript in response")
+	}
+
+	resp = h.post("/transcript", body, "10.0.0.1")
+	second := decodeBody[TranscriptResponse](t, resp)
+	if !second.Cached {
+		t.Error("second request should be served from cache")
+	}
+	if second.Transcript != first.Transcript {
+		t.Error("cached transcript differs from fetched transcript")
+	}
+
+	// The fixture's English track is auto-generated
+	if first.Source != sourceASR || second.Source != sourceCache {
+		t.Errorf("Source = %q then %q, want %q then %q", first.Source, second.Source, sourceASR, sourceCache)
+	}
+	if first.FetchedAt.IsZero() || second.FetchedAt.IsZero() {
+		t.Errorf("FetchedAt = %v then %v, want both set", first.FetchedAt, second.FetchedAt)
+	}
+	if second.FetchedAt.After(time.Now()) {
+		t.Errorf("cached FetchedAt %v is in the future", second.FetchedAt)
+	}
+
+	if got := h.youtube.playerRequests.Load(); got != 1 {
+		t.Errorf("YouTube player requests = %d, want 1", got)
+	}
+}
+
+func TestE2E_SummarizeUsesSharedCache(t *testing.T) {
+	h := newE2EHarness(t)
+
+	// Warm via /transcript with a different URL shape for the same video
+	h.post("/transcript", `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}`, "10.0.0.2").Body.Close()
+
+	resp := h.post("/summarize", `{"url": "https://youtu.be/dQw4w9WgXcQ"}`, "10.0.0.2")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	got := decodeBody[TranscriptResponse](t, resp)
+	if !got.Cached {
+		t.Error("summarize should reuse the cached transcript")
+	}
+	if got.Summary == "" {
+		t.Error("expected summary from fake provider")
+	}
+	if got.Transcript != "" {
+		t.Error("summarize response should not include the transcript")
+	}
+}
+
+func TestE2E_ErrorMapping(t *testing.T) {
+	h := newE2EHarness(t)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantCode   string
+	}{
+		{"no captions", "https://youtu.be/noCaptions1", http.StatusNotFound, ErrNoCaptions},
+		{"private", "https://youtu.be/privateVid1", http.StatusNotFound, ErrVideoUnavailable},
+		{"age restricted", "https://youtu.be/ageRestrict", http.StatusForbidden, ErrAgeRestricted},
+		{"geo restricted", "https://youtu.be/geoBlocked1", http.StatusUnavailableForLegalReasons, ErrGeoRestricted},
+		{"invalid url", "https://example.com/video", http.StatusBadRequest, ErrInvalidRequest},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := h.post("/summarize", `{"url": "`+tt.url+`"}`, fmt.Sprintf("10.0.1.%d", i))
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			got := decodeBody[ErrorResponse](t, resp)
+			if got.Error != tt.wantCode {
+				t.Errorf("error = %q, want %q", got.Error, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestE2E_YouTubeRateLimited(t *testing.T) {
+	h := newE2EHarness(t)
+	h.youtube.playerStatus = http.StatusTooManyRequests
+
+	resp := h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ"}`, "10.0.2.1")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", resp.StatusCode)
+	}
+	if got := decodeBody[ErrorResponse](t, resp); got.Error != ErrRateLimited {
+		t.Errorf("error = %q, want %q", got.Error, ErrRateLimited)
+	}
+}
+
+func TestE2E_AuthRequired(t *testing.T) {
+	h := newE2EHarness(t)
+
+	resp, err := http.Post(h.server.URL+"/transcript", "application/json", bytes.NewBufferString(`{"url": "dQw4w9WgXcQ"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	// Health stays open
+	resp, err = http.Get(h.server.URL + "/health")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("health status = %d, want 200", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestE2E_RateLimiting(t *testing.T) {
+	h := newE2EHarness(t)
+
+	var limited bool
+	for i := 0; i < rateLimitBurst+1; i++ {
+		resp := h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ"}`, "10.0.3.1")
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests {
+			limited = true
+			if resp.Header.Get("Retry-After") == "" {
+				t.Error("rate limited response missing Retry-After")
+			}
+		}
+	}
+
+	if !limited {
+		t.Errorf("expected rate limiting after %d requests", rateLimitBurst)
+	}
+}
+
+func TestE2E_SummarizeUnknownTemplate(t *testing.T) {
+	h := newE2EHarness(t)
+
+	resp := h.post("/summarize", `{"url": "https://youtu.be/dQw4w9WgXcQ", "template": "nope"}`, "10.0.4.1")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	if got := h.youtube.playerRequests.Load(); got != 0 {
+		t.Errorf("YouTube contacted %d times for an invalid request", got)
+	}
+}
+
+func TestE2E_ClipURL(t *testing.T) {
+	h := newE2EHarness(t)
+
+	resp := h.post("/transcript", `{"url": "https://www.youtube.com/clip/UgkxTestClip0001", "clip_only": true}`, "10.0.0.30")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	clip := decodeBody[TranscriptResponse](t, resp)
+	if clip.VideoID != "dQw4w9WgXcQ" {
+		t.Errorf("VideoID = %q, want dQw4w9WgXcQ", clip.VideoID)
+	}
+	if !strings.Contains(clip.Transcript, "Gotta make you understand") || strings.Contains(clip.Transcript, "strangers") {
+		t.Errorf("Transcript = %q, want only the clipped range", clip.Transcript)
+	}
+
+	// Without clip_only the whole video is returned, from the same cache entry
+	resp = h.post("/transcript", `{"url": "https://www.youtube.com/clip/UgkxTestClip0001"}`, "10.0.0.30")
+	full := decodeBody[TranscriptResponse](t, resp)
+	if !full.Cached || !strings.Contains(full.Transcript, "strangers") {
+		t.Errorf("full transcript cached=%v, want cached whole-video transcript", full.Cached)
+	}
+
+	resp = h.post("/transcript", `{"url": "https://www.youtube.com/clip/UgkxMissingClip1"}`, "10.0.0.30")
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("unknown clip status = %d, want 502", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestE2E_TimeRange(t *testing.T) {
+	h := newE2EHarness(t)
+
+	resp := h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "from": "0:43", "to": "0:49"}`, "10.0.0.31")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	got := decodeBody[TranscriptResponse](t, resp)
+	if got.Transcript != "♪ Never gonna give you up ♪ ♪ Never gonna let you down ♪" {
+		t.Errorf("Transcript = %q, want only captions between 0:43 and 0:49", got.Transcript)
+	}
+
+	resp = h.post("/summarize", `{"url": "https://youtu.be/dQw4w9WgXcQ", "from": "5:00", "to": "1:00"}`, "10.0.0.31")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("inverted range status = %d, want 400", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "from": "10:00"}`, "10.0.0.31")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("range past the end status = %d, want 404", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestE2E_TranscriptPagination(t *testing.T) {
+	h := newE2EHarness(t)
+
+	resp := h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ"}`, "10.0.0.32")
+	full := decodeBody[TranscriptResponse](t, resp)
+	if full.Page != nil || full.Segments != nil {
+		t.Errorf("unpaginated response has page = %+v, %d segments", full.Page, len(full.Segments))
+	}
+
+	// Walk the pages and check they add up to the full transcript
+	var texts []string
+	offset, pages := 0, 0
+	for {
+		resp = h.post("/transcript", fmt.Sprintf(`{"url": "https://youtu.be/dQw4w9WgXcQ", "offset": %d, "limit": 2}`, offset), fmt.Sprintf("10.0.1.%d", pages))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("page at offset %d status = %d, want 200", offset, resp.StatusCode)
+		}
+		got := decodeBody[TranscriptResponse](t, resp)
+		if got.Page == nil || len(got.Segments) == 0 || len(got.Segments) > 2 {
+			t.Fatalf("page at offset %d = %+v with %d segments", offset, got.Page, len(got.Segments))
+		}
+		if got.Transcript != segmentsText(got.Segments) {
+			t.Errorf("page transcript doesn't match its segments")
+		}
+		texts = append(texts, got.Transcript)
+		pages++
+		if got.Page.NextOffset == nil {
+			break
+		}
+		offset = *got.Page.NextOffset
+	}
+	if pages < 2 {
+		t.Errorf("got %d pages, want several", pages)
+	}
+	if joined := strings.Join(texts, " "); joined != full.Transcript {
+		t.Errorf("pages joined = %q, want full transcript", joined)
+	}
+
+	resp = h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "limit": -1}`, "10.0.0.32")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("negative limit status = %d, want 400", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "offset": 100000}`, "10.0.0.32")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("offset past the end status = %d, want 404", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestE2E_TranscriptSegmentsFormat(t *testing.T) {
+	h := newE2EHarness(t)
+
+	resp := h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ"}`, "10.0.0.33")
+	text := decodeBody[TranscriptResponse](t, resp)
+
+	resp = h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "format": "segments"}`, "10.0.0.33")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	got := decodeBody[TranscriptResponse](t, resp)
+	if got.Transcript != "" {
+		t.Errorf("Transcript = %q, want it omitted for format=segments", got.Transcript)
+	}
+	if len(got.Segments) == 0 || segmentsText(got.Segments) != text.Transcript {
+		t.Errorf("segments text = %q, want %q", segmentsText(got.Segments), text.Transcript)
+	}
+	if got.Page != nil {
+		t.Errorf("Page = %+v, want nil without offset/limit", got.Page)
+	}
+	for i := 1; i < len(got.Segments); i++ {
+		if got.Segments[i].Start < got.Segments[i-1].Start {
+			t.Fatalf("segment %d starts before segment %d", i, i-1)
+		}
+	}
+
+	// Composes with time ranges and pagination
+	resp = h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "format": "segments", "from": "0:43", "limit": 1}`, "10.0.0.33")
+	got = decodeBody[TranscriptResponse](t, resp)
+	if len(got.Segments) != 1 || got.Segments[0].Start < 42 || got.Page == nil || got.Transcript != "" {
+		t.Errorf("windowed page = %+v", got)
+	}
+
+	resp = h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "format": "srt"}`, "10.0.0.33")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown format status = %d, want 400", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestE2E_AutoTranslate(t *testing.T) {
+	h := newE2EHarness(t)
+
+	resp := h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "language": "fr", "allow_auto_translate": true}`, "10.0.0.35")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	got := decodeBody[TranscriptResponse](t, resp)
+	if got.TranslatedFrom != "es" || !strings.Contains(got.Transcript, "Je ne t'abandonnerai jamais") {
+		t.Errorf("response = %+v, want the French translation of the Spanish track", got)
+	}
+
+	// The cached translation isn't served to requests that didn't opt in
+	resp = h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "language": "fr"}`, "10.0.0.35")
+	got = decodeBody[TranscriptResponse](t, resp)
+	if got.TranslatedFrom != "" || got.Cached {
+		t.Errorf("response = %+v, want a fresh untranslated fetch", got)
+	}
+	if n := h.youtube.playerRequests.Load(); n != 2 {
+		t.Errorf("player requests = %d, want 2", n)
+	}
+}
+
+func TestE2E_TranscriptQuality(t *testing.T) {
+	h := newE2EHarness(t)
+
+	// The English fixture is ASR, the Spanish one uploaded
+	resp := h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "language": "en"}`, "10.0.0.36")
+	got := decodeBody[TranscriptResponse](t, resp)
+	if got.TranscriptQuality == nil || got.TranscriptQuality.AvgSegmentWords == 0 {
+		t.Errorf("transcript_quality = %+v, want scored ASR captions", got.TranscriptQuality)
+	}
+
+	resp = h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ", "language": "es"}`, "10.0.0.36")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	got = decodeBody[TranscriptResponse](t, resp)
+	if got.TranscriptQuality != nil {
+		t.Errorf("transcript_quality = %+v, want none for uploaded captions", got.TranscriptQuality)
+	}
+}
+
+func TestE2E_VideoInfo(t *testing.T) {
+	h := newE2EHarness(t)
+
+	resp := h.get("/video/dQw4w9WgXcQ", "10.0.0.40")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	info := decodeBody[VideoInfo](t, resp)
+	if info.Title == "" || info.Channel != "Rick Astley" || info.DurationSeconds != 212 {
+		t.Errorf("info = %+v, want title, channel and duration", info)
+	}
+	if info.PublishDate != "2009-10-24" {
+		t.Errorf("PublishDate = %q, want 2009-10-24", info.PublishDate)
+	}
+	if info.Playability != "OK" {
+		t.Errorf("Playability = %q, want OK", info.Playability)
+	}
+	if len(info.Captions) != 2 || info.Captions[0].Language != "en" || !info.Captions[0].AutoGenerated {
+		t.Errorf("Captions = %+v, want auto-generated en and es", info.Captions)
+	}
+	if got := h.youtube.captionRequests.Load(); got != 0 {
+		t.Errorf("caption requests = %d, want 0", got)
+	}
+
+	// Unplayable videos are reported, not treated as errors
+	resp = h.get("/video/privateVid1", "10.0.0.40")
+	private := decodeBody[VideoInfo](t, resp)
+	if resp.StatusCode != http.StatusOK || private.Playability != "UNPLAYABLE" {
+		t.Errorf("private video status = %d, playability = %q", resp.StatusCode, private.Playability)
+	}
+
+	resp = h.get("/video/not-an-id", "10.0.0.40")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid ID status = %d, want 400", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestE2E_VideoLanguagesCached(t *testing.T) {
+	h := newE2EHarness(t)
+
+	// Fetching a transcript records the video's caption tracks
+	resp := h.post("/transcript", `{"url": "https://youtu.be/dQw4w9WgXcQ"}`, "10.0.0.41")
+	resp.Body.Close()
+
+	resp = h.get("/video/dQw4w9WgXcQ/languages", "10.0.0.41")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	langs := decodeBody[LanguagesResponse](t, resp)
+	if !langs.Cached {
+		t.Error("languages of a fetched video should come from the cache")
+	}
+	if len(langs.Captions) != 2 || langs.Captions[0].Language != "en" || langs.Captions[1].Language != "es" {
+		t.Errorf("Captions = %+v, want en and es", langs.Captions)
+	}
+	if got := h.youtube.playerRequests.Load(); got != 1 {
+		t.Errorf("YouTube player requests = %d, want 1", got)
+	}
+
+	// refresh asks YouTube again
+	resp = h.get("/video/dQw4w9WgXcQ/languages?refresh=true", "10.0.0.41")
+	if langs := decodeBody[LanguagesResponse](t, resp); langs.Cached {
+		t.Error("refresh should not be served from the cache")
+	}
+	if got := h.youtube.playerRequests.Load(); got != 2 {
+		t.Errorf("YouTube player requests = %d, want 2", got)
+	}
+
+	// Unplayable videos aren't cached
+	for range 2 {
+		resp = h.get("/video/privateVid1/languages", "10.0.0.41")
+		if langs := decodeBody[LanguagesResponse](t, resp); langs.Cached || len(langs.Captions) != 0 {
+			t.Errorf("private video = %+v, want uncached with no captions", langs)
+		}
+	}
+	if got := h.youtube.playerRequests.Load(); got != 4 {
+		t.Errorf("YouTube player requests = %d, want 4", got)
+	}
+}
+
+func TestE2E_Export(t *testing.T) {
+	h := newE2EHarness(t)
+
+	for i, fetchedAt := range []string{"2023-12-31 10:00:00", "2024-01-02 10:00:00", "2024-01-02 10:00:00", "2024-02-01 09:30:00"} {
+		videoID := fmt.Sprintf("exportVid%02d", i)
+		if err := cacheTranscript(h.cache, videoID, "en", "Title", "Transcript "+videoID); err != nil {
+			t.Fatalf("cacheTranscript() error = %v", err)
+		}
+		db, err := h.cache.conn()
+		if err != nil {
+			t.Fatalf("failed to open cache: %v", err)
+		}
+		if _, err := db.Exec("UPDATE transcripts SET fetched_at = ? WHERE video_id = ?", fetchedAt, videoID); err != nil {
+			t.Fatalf("failed to set fetched_at: %v", err)
+		}
+	}
+	// Two versions of the default summary, one key-points summary and one in
+	// Spanish; only the newest English one of each template is exported with
+	// the English transcript
+	for _, summary := range []*StoredSummary{
+		{VideoID: "exportVid03", Language: "en", Summary: "Old summary."},
+		{VideoID: "exportVid03", Language: "en", Summary: "New summary."},
+		{VideoID: "exportVid03", Language: "en", Template: "key-points", Summary: "- Point"},
+		{VideoID: "exportVid03", Language: "es", Summary: "Resumen."},
+	} {
+		if err := h.cache.SaveSummary(summary); err != nil {
+			t.Fatalf("SaveSummary() error = %v", err)
+		}
+	}
+
+	readPage := func(path string) ([]ExportRecord, string) {
+		t.Helper()
+		resp := h.get(path, "10.0.0.50")
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want 200", path, resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+		}
+		var records []ExportRecord
+		dec := json.NewDecoder(resp.Body)
+		for dec.More() {
+			var rec ExportRecord
+			if err := dec.Decode(&rec); err != nil {
+				t.Fatalf("failed to decode NDJSON line: %v", err)
+			}
+			records = append(records, rec)
+		}
+		return records, resp.Header.Get("X-Next-Cursor")
+	}
+
+	// Page through everything since 2024-01-01, two at a time
+	var got []string
+	records, cursor := readPage("/export?since=2024-01-01&limit=2")
+	for _, rec := range records {
+		got = append(got, rec.VideoID)
+	}
+	if cursor == "" {
+		t.Fatal("expected a next cursor after the first page")
+	}
+	records, cursor = readPage("/export?since=2024-01-01&limit=2&cursor=" + cursor)
+	for _, rec := range records {
+		got = append(got, rec.VideoID)
+	}
+	if cursor != "" {
+		t.Errorf("unexpected cursor on the last page: %q", cursor)
+	}
+
+	want := []string{"exportVid01", "exportVid02", "exportVid03"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("exported %v, want %v", got, want)
+	}
+	if len(records) == 1 && records[0].Transcript != "Transcript exportVid03" {
+		t.Errorf("Transcript = %q", records[0].Transcript)
+	}
+	if len(records) == 1 {
+		var summaries []string
+		for _, s := range records[0].Summaries {
+			summaries = append(summaries, s.Template+": "+s.Summary)
+		}
+		if want := []string{": New summary.", "key-points: - Point"}; fmt.Sprint(summaries) != fmt.Sprint(want) {
+			t.Errorf("Summaries = %q, want %q", summaries, want)
+		}
+	}
+
+	for _, path := range []string{"/export?since=yesterday", "/export?limit=0", "/export?cursor=bogus"} {
+		resp := h.get(path, "10.0.0.50")
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want 400", path, resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+}
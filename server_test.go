@@ -3,25 +3,24 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
 func TestHealthEndpoint(t *testing.T) {
 	// Setup
-	tmpDir, _ := os.MkdirTemp("", "ytsummary-test-*")
-	defer os.RemoveAll(tmpDir)
-	cacheDir = tmpDir
-	db = nil
-	serverStartTime = time.Now()
+	s := newServer(ServerConfig{Cache: newTestCache(t)})
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
 
-	handleHealth(w, req)
+	s.handleHealth(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("health endpoint returned %d, want %d", w.Code, http.StatusOK)
@@ -39,25 +38,19 @@ func TestHealthEndpoint(t *testing.T) {
 	if resp.UptimeSeconds < 0 {
 		t.Errorf("uptime should be >= 0, got %d", resp.UptimeSeconds)
 	}
-
-	closeCache()
 }
 
 func TestHealthEndpointDegraded(t *testing.T) {
 	// Setup
-	tmpDir, _ := os.MkdirTemp("", "ytsummary-test-*")
-	defer os.RemoveAll(tmpDir)
-	cacheDir = tmpDir
-	db = nil
-	serverStartTime = time.Now()
+	s := newServer(ServerConfig{Cache: newTestCache(t)})
 
 	// Set last success to over an hour ago
-	lastSuccessTime = time.Now().Add(-2 * time.Hour)
+	s.lastSuccess = time.Now().Add(-2 * time.Hour)
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
 
-	handleHealth(w, req)
+	s.handleHealth(w, req)
 
 	var resp HealthResponse
 	json.NewDecoder(w.Body).Decode(&resp)
@@ -65,17 +58,13 @@ func TestHealthEndpointDegraded(t *testing.T) {
 	if resp.Status != "degraded" {
 		t.Errorf("status = %q, want %q (last success > 1 hour ago)", resp.Status, "degraded")
 	}
-
-	// Reset for other tests
-	lastSuccessTime = time.Time{}
-	closeCache()
 }
 
 func TestTranscriptEndpointInvalidJSON(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transcript", bytes.NewBufferString("not json"))
 	w := httptest.NewRecorder()
 
-	handleTranscript(w, req)
+	newServer(ServerConfig{Cache: newTestCache(t)}).handleTranscript(w, req)
 
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("got status %d, want %d", w.Code, http.StatusBadRequest)
@@ -94,7 +83,7 @@ func TestTranscriptEndpointMissingURL(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transcript", bytes.NewBufferString(body))
 	w := httptest.NewRecorder()
 
-	handleTranscript(w, req)
+	newServer(ServerConfig{Cache: newTestCache(t)}).handleTranscript(w, req)
 
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("got status %d, want %d", w.Code, http.StatusBadRequest)
@@ -113,7 +102,7 @@ func TestTranscriptEndpointInvalidURL(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transcript", bytes.NewBufferString(body))
 	w := httptest.NewRecorder()
 
-	handleTranscript(w, req)
+	newServer(ServerConfig{Cache: newTestCache(t)}).handleTranscript(w, req)
 
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("got status %d, want %d", w.Code, http.StatusBadRequest)
@@ -129,23 +118,20 @@ func TestTranscriptEndpointInvalidURL(t *testing.T) {
 
 func TestTranscriptEndpointCacheHit(t *testing.T) {
 	// Setup cache with a transcript
-	tmpDir, _ := os.MkdirTemp("", "ytsummary-test-*")
-	defer os.RemoveAll(tmpDir)
-	cacheDir = tmpDir
-	db = nil
+	cache := newTestCache(t)
 
 	videoID := "dQw4w9WgXcQ"
 	lang := "en"
 	transcript := "Test transcript content"
 
-	cacheTranscript(videoID, lang, "Test Title", transcript)
+	cacheTranscript(cache, videoID, lang, "Test Title", transcript)
 
 	// Make request
 	body := `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "language": "en"}`
 	req := httptest.NewRequest("POST", "/transcript", bytes.NewBufferString(body))
 	w := httptest.NewRecorder()
 
-	handleTranscript(w, req)
+	newServer(ServerConfig{Cache: cache}).handleTranscript(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("got status %d, want %d", w.Code, http.StatusOK)
@@ -166,8 +152,148 @@ func TestTranscriptEndpointCacheHit(t *testing.T) {
 	if resp.Language != lang {
 		t.Errorf("language = %q, want %q", resp.Language, lang)
 	}
+}
+
+func TestServerInjectedDependencies(t *testing.T) {
+	t.Parallel()
+
+	var fetched []string
+	s := newServer(ServerConfig{
+		Cache: newTestCache(t),
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			fetched = append(fetched, url)
+			return &FetchResult{Title: "Injected", Transcript: "One. Two. Three."}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			return "summary of " + transcript, nil
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		body := `{"url": "https://youtu.be/dQw4w9WgXcQ"}`
+		req := httptest.NewRequest("POST", "/summarize", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		s.handleSummarize(w, req)
+
+		var resp TranscriptResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp.Title != "Injected" || resp.Summary != "summary of One. Two. Three." {
+			t.Errorf("request %d: response = %+v, want the injected fetcher and summarizer", i+1, resp)
+		}
+	}
+
+	// The second request is served from the server's own cache
+	if len(fetched) != 1 {
+		t.Errorf("fetched %d times, want 1", len(fetched))
+	}
+	if s.lastSuccessTime().IsZero() {
+		t.Error("last success should be recorded")
+	}
+}
+
+// TestServerConcurrentRequests runs two servers side by side under load; run
+// with -race to check handlers only share state through locked accessors
+func TestServerConcurrentRequests(t *testing.T) {
+	newTestServer := func() http.Handler {
+		return newServer(ServerConfig{
+			APIKey: "secret",
+			Cache:  newTestCache(t),
+			Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+				return &FetchResult{Title: "Injected", Transcript: "One. Two. Three."}, nil
+			},
+			Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+				return "A summary.", nil
+			},
+		}).Handler()
+	}
+	servers := []http.Handler{newTestServer(), newTestServer()}
+
+	paths := []string{"/health", "/v1/summarize?url=dQw4w9WgXcQ", "/v1/transcript?url=jNQXAC9IVRw", "/v1/admin/dashboard", "/v1/admin/queue"}
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", paths[i%len(paths)], nil)
+			req.RemoteAddr = fmt.Sprintf("203.0.113.%d:1234", i)
+			req.Header.Set("X-API-Key", "secret")
+			w := httptest.NewRecorder()
+			servers[i%2].ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Errorf("GET %s: status %d", paths[i%len(paths)], w.Code)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSummarizeBudgetExceeded(t *testing.T) {
+	s := newServer(ServerConfig{
+		Cache: newTestCache(t),
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Injected", Transcript: strings.Repeat("Word. ", 1000)}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			return summarizeWith(&pricedLLMClient{}, transcript, opts)
+		},
+	})
+
+	w := httptest.NewRecorder()
+	body := `{"url": "https://youtu.be/dQw4w9WgXcQ", "max_cost": 0.001}`
+	s.handleSummarize(w, httptest.NewRequest("POST", "/summarize", bytes.NewBufferString(body)))
+	if w.Code != http.StatusPaymentRequired {
+		t.Errorf("status = %d, want 402", w.Code)
+	}
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if errResp.Error != ErrBudgetExceeded || errResp.Transcript == "" {
+		t.Errorf("error response = %+v, want budget_exceeded with the transcript", errResp)
+	}
+}
+
+func TestSummarizeLLMFailure(t *testing.T) {
+	s := newServer(ServerConfig{
+		Cache: newTestCache(t),
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Injected", Transcript: "One. Two. Three."}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			return "", errors.New("provider unavailable")
+		},
+	})
+	summarize := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleSummarize(w, httptest.NewRequest("POST", "/summarize", bytes.NewBufferString(body)))
+		return w
+	}
+
+	// By default the failure is explicit, with the transcript in the error
+	w := summarize(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`)
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", w.Code)
+	}
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if errResp.Error != ErrLLMError || errResp.Transcript != "One. Two. Three." {
+		t.Errorf("error response = %+v, want llm_error with the transcript", errResp)
+	}
+
+	// Opting in degrades to the transcript alone
+	w = summarize(`{"url": "https://youtu.be/dQw4w9WgXcQ", "fallback_to_transcript": true}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("fallback status = %d, want 200", w.Code)
+	}
+	var resp TranscriptResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Transcript != "One. Two. Three." || resp.Summary != "" || resp.SummaryError == "" {
+		t.Errorf("fallback response = %+v, want transcript and summary_error", resp)
+	}
 
-	closeCache()
+	// YTSUMMARY_FALLBACK_TO_TRANSCRIPT makes it the default
+	s.setRequestDefaults(RequestDefaults{FallbackToTranscript: true})
+	if w = summarize(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`); w.Code != http.StatusOK {
+		t.Errorf("status with the fallback default = %d, want 200", w.Code)
+	}
 }
 
 func TestParseRequest(t *testing.T) {
@@ -178,6 +304,17 @@ func TestParseRequest(t *testing.T) {
 		wantLang  string
 		wantError bool
 	}{
+		{
+			name:      "temperature out of range",
+			body:      `{"url": "https://youtu.be/dQw4w9WgXcQ", "temperature": 5}`,
+			wantError: true,
+		},
+		{
+			name:     "generation params",
+			body:     `{"url": "https://youtu.be/dQw4w9WgXcQ", "temperature": 0.2, "top_p": 0.9, "max_tokens": 8000}`,
+			wantID:   "dQw4w9WgXcQ",
+			wantLang: "en",
+		},
 		{
 			name:     "valid request with language",
 			body:     `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "language": "es"}`,
@@ -248,22 +385,7 @@ func TestAPIKeyAuth(t *testing.T) {
 
 	apiKey := "test-secret-key"
 
-	// Create auth middleware manually for testing
-	authHandler := func(w http.ResponseWriter, r *http.Request) {
-		providedKey := r.Header.Get("X-API-Key")
-		if providedKey == "" {
-			// Try Authorization header
-			auth := r.Header.Get("Authorization")
-			if len(auth) > 7 && auth[:7] == "Bearer " {
-				providedKey = auth[7:]
-			}
-		}
-		if providedKey != apiKey {
-			writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing API key")
-			return
-		}
-		handler(w, r)
-	}
+	authHandler := newServer(ServerConfig{APIKey: apiKey, Cache: newTestCache(t)}).requireAPIKey(handler)
 
 	tests := []struct {
 		name       string
@@ -293,3 +415,36 @@ func TestAPIKeyAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestVersionedRoutes(t *testing.T) {
+	s := newServer(ServerConfig{
+		Cache: newTestCache(t),
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			return &FetchResult{Title: "Injected", Transcript: "One. Two."}, nil
+		},
+	})
+	handler := s.Handler()
+
+	for _, path := range []string{"/v1/transcript", "/transcript"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", path, bytes.NewBufferString(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", path, w.Code, w.Body)
+		}
+		var resp TranscriptResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Transcript != "One. Two." {
+			t.Errorf("%s: transcript = %q, %v", path, resp.Transcript, err)
+		}
+
+		link := w.Header().Get("Link")
+		if legacy := !strings.HasPrefix(path, "/v1/"); legacy != (link == `</v1/transcript>; rel="successor-version"`) {
+			t.Errorf("%s: Link = %q", path, link)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("/v1/health status = %d", w.Code)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	serviceName  = "ytsummary"
+	launchdLabel = "com.github.alrobwilloliver.ytsummary"
+)
+
+var (
+	serviceUser   bool
+	serviceAddr   string
+	serviceDryRun bool
+)
+
+// serviceConfig describes the serve-mode service to install
+type serviceConfig struct {
+	Executable string
+	Addr       string
+	ConfigDir  string // holds EnvFile
+	EnvFile    string // configuration, including API keys
+	CacheDir   string
+	User       bool // systemd user unit instead of a system one
+}
+
+// serviceEnvTemplate is written as the service's environment file when none exists
+const serviceEnvTemplate = `# ytsummary serve configuration; restart the service after editing
+YTSUMMARY_API_KEY=
+YTSUMMARY_SERVER_API_KEY=
+# YTSUMMARY_MODEL=google/gemini-2.0-flash-001
+# YTSUMMARY_API_URL=https://openrouter.ai/api/v1
+`
+
+var systemdUnitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description=ytsummary YouTube transcript and summary API
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+ExecStart={{.Executable}} serve --addr {{.Addr}} --cache-dir {{.CacheDir}}
+{{- if .User}}
+# The .env in the working directory is re-read on reload (SIGHUP)
+WorkingDirectory={{.ConfigDir}}
+ExecReload=/bin/kill -HUP $MAINPID
+{{- else}}
+EnvironmentFile={{.EnvFile}}
+WorkingDirectory={{.CacheDir}}
+DynamicUser=yes
+StateDirectory=ytsummary
+{{- end}}
+Restart=on-failure
+RestartSec=5
+
+# Hardening
+NoNewPrivileges=yes
+PrivateTmp=yes
+ProtectSystem=strict
+{{- if .User}}
+ReadWritePaths={{.CacheDir}}
+{{- else}}
+ProtectHome=yes
+{{- end}}
+ProtectKernelTunables=yes
+ProtectKernelModules=yes
+ProtectControlGroups=yes
+RestrictSUIDSGID=yes
+RestrictNamespaces=yes
+LockPersonality=yes
+MemoryDenyWriteExecute=yes
+RestrictAddressFamilies=AF_INET AF_INET6 AF_UNIX
+SystemCallArchitectures=native
+
+[Install]
+WantedBy={{if .User}}default.target{{else}}multi-user.target{{end}}
+`))
+
+var launchdPlistTemplate = template.Must(template.New("plist").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>` + launchdLabel + `</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>{{.Executable}}</string>
+		<string>serve</string>
+		<string>--addr</string>
+		<string>{{.Addr}}</string>
+		<string>--cache-dir</string>
+		<string>{{.CacheDir}}</string>
+	</array>
+	<key>WorkingDirectory</key>
+	<string>{{.ConfigDir}}</string>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ThrottleInterval</key>
+	<integer>5</integer>
+	<key>StandardOutPath</key>
+	<string>{{.ConfigDir}}/ytsummary.log</string>
+	<key>StandardErrorPath</key>
+	<string>{{.ConfigDir}}/ytsummary.log</string>
+</dict>
+</plist>
+`))
+
+// renderService returns the unit file (or plist) for cfg
+func renderService(tmpl *template.Template, cfg serviceConfig) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, cfg); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// serviceManager installs and controls the service on one platform
+type serviceManager struct {
+	cfg       serviceConfig
+	template  *template.Template
+	unitPath  string
+	install   [][]string // commands run after writing the unit
+	status    []string
+	uninstall [][]string // commands run before removing the unit
+}
+
+// newServiceManager picks systemd or launchd for this platform
+func newServiceManager() (*serviceManager, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("can't find the ytsummary binary: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return nil, err
+	}
+	cfg := serviceConfig{Executable: exe, Addr: serviceAddr, User: serviceUser}
+
+	switch runtime.GOOS {
+	case "linux":
+		// System units run as a throwaway user that can't read root's files,
+		// so systemd passes the configuration in instead
+		systemctl := []string{"systemctl"}
+		unitDir := "/etc/systemd/system"
+		cfg.ConfigDir = "/etc/ytsummary"
+		cfg.EnvFile = filepath.Join(cfg.ConfigDir, "ytsummary.env")
+		cfg.CacheDir = "/var/lib/ytsummary"
+		if cfg.User {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			systemctl = append(systemctl, "--user")
+			unitDir = filepath.Join(home, ".config", "systemd", "user")
+			cfg.ConfigDir = filepath.Join(home, ".config", "ytsummary")
+			cfg.EnvFile = filepath.Join(cfg.ConfigDir, envFile)
+			cfg.CacheDir = filepath.Join(home, ".local", "share", "ytsummary")
+		}
+		command := func(args ...string) []string {
+			return append(slices.Clone(systemctl), args...)
+		}
+		return &serviceManager{
+			cfg:       cfg,
+			template:  systemdUnitTemplate,
+			unitPath:  filepath.Join(unitDir, serviceName+".service"),
+			install:   [][]string{command("daemon-reload"), command("enable", "--now", serviceName)},
+			status:    command("status", "--no-pager", serviceName),
+			uninstall: [][]string{command("disable", "--now", serviceName)},
+		}, nil
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		cfg.User = true
+		cfg.ConfigDir = filepath.Join(home, "Library", "Application Support", "ytsummary")
+		cfg.EnvFile = filepath.Join(cfg.ConfigDir, envFile)
+		cfg.CacheDir = filepath.Join(cfg.ConfigDir, "cache")
+		unitPath := filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+		domain := fmt.Sprintf("gui/%d", os.Getuid())
+		return &serviceManager{
+			cfg:       cfg,
+			template:  launchdPlistTemplate,
+			unitPath:  unitPath,
+			install:   [][]string{{"launchctl", "bootstrap", domain, unitPath}},
+			status:    []string{"launchctl", "print", domain + "/" + launchdLabel},
+			uninstall: [][]string{{"launchctl", "bootout", domain + "/" + launchdLabel}},
+		}, nil
+	default:
+		return nil, fmt.Errorf("service install supports Linux (systemd) and macOS (launchd), not %s", runtime.GOOS)
+	}
+}
+
+// runServiceCommand runs a service manager command, passing its output through
+func runServiceCommand(args []string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func runServiceInstall(cmd *cobra.Command, args []string) error {
+	m, err := newServiceManager()
+	if err != nil {
+		return err
+	}
+	unit, err := renderService(m.template, m.cfg)
+	if err != nil {
+		return err
+	}
+	if serviceDryRun {
+		fmt.Printf("# %s\n%s", m.unitPath, unit)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.cfg.ConfigDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", m.cfg.ConfigDir, err)
+	}
+	if _, err := os.Stat(m.cfg.EnvFile); os.IsNotExist(err) {
+		// Holds API keys, so only the owner may read it
+		if err := os.WriteFile(m.cfg.EnvFile, []byte(serviceEnvTemplate), 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", m.cfg.EnvFile, err)
+		}
+		fmt.Printf("Wrote %s; add your API keys there\n", m.cfg.EnvFile)
+	}
+	if m.cfg.User {
+		if err := os.MkdirAll(m.cfg.CacheDir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", m.cfg.CacheDir, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.unitPath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(m.unitPath), err)
+	}
+	if err := os.WriteFile(m.unitPath, []byte(unit), 0644); err != nil {
+		return fmt.Errorf("failed to write %s (system units need root; try --user): %w", m.unitPath, err)
+	}
+	fmt.Printf("Wrote %s\n", m.unitPath)
+
+	for _, c := range m.install {
+		if err := runServiceCommand(c); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("ytsummary is serving on %s\n", m.cfg.Addr)
+	return nil
+}
+
+func runServiceStatus(cmd *cobra.Command, args []string) error {
+	m, err := newServiceManager()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(m.unitPath); os.IsNotExist(err) {
+		return fmt.Errorf("service is not installed (no %s)", m.unitPath)
+	}
+	return runServiceCommand(m.status)
+}
+
+func runServiceUninstall(cmd *cobra.Command, args []string) error {
+	m, err := newServiceManager()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(m.unitPath); os.IsNotExist(err) {
+		return fmt.Errorf("service is not installed (no %s)", m.unitPath)
+	}
+	for _, c := range m.uninstall {
+		if err := runServiceCommand(c); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	if err := os.Remove(m.unitPath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", m.unitPath, err)
+	}
+	fmt.Printf("Removed %s; %s and the cache in %s were kept\n", m.unitPath, m.cfg.ConfigDir, m.cfg.CacheDir)
+	return nil
+}
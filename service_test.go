@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderSystemdUnit(t *testing.T) {
+	cfg := serviceConfig{
+		Executable: "/usr/local/bin/ytsummary",
+		Addr:       ":9000",
+		ConfigDir:  "/etc/ytsummary",
+		EnvFile:    "/etc/ytsummary/ytsummary.env",
+		CacheDir:   "/var/lib/ytsummary",
+	}
+	unit, err := renderService(systemdUnitTemplate, cfg)
+	if err != nil {
+		t.Fatalf("renderService() error = %v", err)
+	}
+	for _, want := range []string{
+		"ExecStart=/usr/local/bin/ytsummary serve --addr :9000 --cache-dir /var/lib/ytsummary",
+		"EnvironmentFile=/etc/ytsummary/ytsummary.env",
+		"Restart=on-failure",
+		"DynamicUser=yes",
+		"ProtectSystem=strict",
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("system unit missing %q:\n%s", want, unit)
+		}
+	}
+
+	// User units read the .env from their working directory and can reload it
+	cfg.User, cfg.ConfigDir, cfg.CacheDir = true, "/home/me/.config/ytsummary", "/home/me/.local/share/ytsummary"
+	unit, _ = renderService(systemdUnitTemplate, cfg)
+	for _, want := range []string{
+		"WorkingDirectory=/home/me/.config/ytsummary",
+		"ExecReload=/bin/kill -HUP $MAINPID",
+		"ReadWritePaths=/home/me/.local/share/ytsummary",
+		"WantedBy=default.target",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("user unit missing %q:\n%s", want, unit)
+		}
+	}
+	if strings.Contains(unit, "DynamicUser") || strings.Contains(unit, "ProtectHome") {
+		t.Errorf("user unit should run as the user with access to home:\n%s", unit)
+	}
+}
+
+func TestRenderLaunchdPlist(t *testing.T) {
+	plist, err := renderService(launchdPlistTemplate, serviceConfig{
+		Executable: "/opt/homebrew/bin/ytsummary",
+		Addr:       ":8080",
+		ConfigDir:  "/Users/me/Library/Application Support/ytsummary",
+		CacheDir:   "/Users/me/Library/Application Support/ytsummary/cache",
+	})
+	if err != nil {
+		t.Fatalf("renderService() error = %v", err)
+	}
+	for _, want := range []string{
+		"<string>" + launchdLabel + "</string>",
+		"<string>/opt/homebrew/bin/ytsummary</string>",
+		"<key>KeepAlive</key>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist missing %q:\n%s", want, plist)
+		}
+	}
+}
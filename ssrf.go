@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// errBlockedAddress is returned for a connection to an address outbound
+// requests may never reach
+var errBlockedAddress = errors.New("blocked address")
+
+// blockedNetworks are never dialed: link-local addresses, where cloud
+// metadata services answer, and addresses that aren't one host
+var blockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("169.254.0.0/16"), // link-local, incl. 169.254.169.254
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("fd00:ec2::254/128"), // AWS metadata over IPv6
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("224.0.0.0/4"), // multicast
+	netip.MustParsePrefix("ff00::/8"),
+	netip.MustParsePrefix("255.255.255.255/32"),
+}
+
+// privateNetworks are loopback and private ranges, refused as well with
+// YTSUMMARY_BLOCK_PRIVATE_URLS. They stay reachable by default for local
+// LLMs such as Ollama and LAN notification sinks.
+var privateNetworks = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("fc00::/7"),      // unique local
+}
+
+// blockPrivateURLs reports whether YTSUMMARY_BLOCK_PRIVATE_URLS is set, for
+// hosted servers where nothing outbound should reach the internal network
+func blockPrivateURLs() bool {
+	block, _ := strconv.ParseBool(os.Getenv("YTSUMMARY_BLOCK_PRIVATE_URLS"))
+	return block
+}
+
+// checkOutboundAddr returns errBlockedAddress for an address outbound
+// requests may not reach
+func checkOutboundAddr(ip netip.Addr) error {
+	ip = ip.Unmap() // ::ffff:169.254.169.254 is 169.254.169.254
+	for _, p := range blockedNetworks {
+		if p.Contains(ip) {
+			return fmt.Errorf("%w %s", errBlockedAddress, ip)
+		}
+	}
+	if blockPrivateURLs() {
+		for _, p := range privateNetworks {
+			if p.Contains(ip) {
+				return fmt.Errorf("%w %s (YTSUMMARY_BLOCK_PRIVATE_URLS)", errBlockedAddress, ip)
+			}
+		}
+	}
+	return nil
+}
+
+// safeDialControl checks the address a connection is about to be made to.
+// It runs after DNS resolution, on the IP actually dialed, so a name that
+// resolves to a safe address when checked and an internal one when used
+// (DNS rebinding) is still refused.
+func safeDialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: unexpected address %q", errBlockedAddress, address)
+	}
+	return checkOutboundAddr(ip)
+}
+
+// safeTransport is http.DefaultTransport dialing through safeDialControl. It
+// carries the shared client, the LLM and YouTube requests and notifications:
+// every outbound request whose address a user or an upstream response can
+// influence.
+var safeTransport = func() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   safeDialControl,
+	}).DialContext
+	return t
+}()
+
+// validateOutboundURL checks a user-provided URL, such as a webhook, before
+// anything is sent to it: http(s), and no address checkOutboundAddr refuses.
+// Names are checked when dialed, against what they resolve to then.
+func validateOutboundURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("use an http(s) URL")
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil {
+		return checkOutboundAddr(ip)
+	}
+	return nil
+}
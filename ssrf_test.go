@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestCheckOutboundAddr(t *testing.T) {
+	t.Setenv("YTSUMMARY_BLOCK_PRIVATE_URLS", "")
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"169.254.169.254", true},
+		{"::ffff:169.254.169.254", true},
+		{"fd00:ec2::254", true},
+		{"fe80::1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.251", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"142.250.72.14", false},
+		{"2607:f8b0:4005:80a::200e", false},
+	}
+	for _, tt := range tests {
+		err := checkOutboundAddr(netip.MustParseAddr(tt.addr))
+		if (err != nil) != tt.blocked {
+			t.Errorf("checkOutboundAddr(%s) = %v, want blocked %v", tt.addr, err, tt.blocked)
+		}
+	}
+
+	t.Setenv("YTSUMMARY_BLOCK_PRIVATE_URLS", "true")
+	for _, addr := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.20.0.5", "192.168.1.1", "100.100.100.200", "fd12::1"} {
+		if err := checkOutboundAddr(netip.MustParseAddr(addr)); !errors.Is(err, errBlockedAddress) {
+			t.Errorf("strict: checkOutboundAddr(%s) = %v, want blocked", addr, err)
+		}
+	}
+	if err := checkOutboundAddr(netip.MustParseAddr("142.250.72.14")); err != nil {
+		t.Errorf("strict: public address blocked: %v", err)
+	}
+}
+
+func TestSafeTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	client := &http.Client{Transport: safeTransport}
+
+	// Refused before the connection is made, so this needs no network
+	if _, err := client.Get("http://169.254.169.254/latest/meta-data/"); !errors.Is(err, errBlockedAddress) {
+		t.Errorf("metadata service: err = %v, want errBlockedAddress", err)
+	}
+
+	t.Setenv("YTSUMMARY_BLOCK_PRIVATE_URLS", "")
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("loopback by default: %v", err)
+	}
+	resp.Body.Close()
+
+	// The name is checked by what it resolves to when dialed, as a rebinding
+	// DNS answer would be
+	t.Setenv("YTSUMMARY_BLOCK_PRIVATE_URLS", "true")
+	if _, err := client.Get(strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)); !errors.Is(err, errBlockedAddress) {
+		t.Errorf("localhost when strict: err = %v, want errBlockedAddress", err)
+	}
+}
+
+func TestNotifierRejectsInternalURLs(t *testing.T) {
+	t.Setenv("YTSUMMARY_BLOCK_PRIVATE_URLS", "")
+	for _, u := range []string{
+		"http://169.254.169.254/latest/meta-data/iam",
+		"http://[::ffff:a9fe:a9fe]/hook",
+		"ntfy://[fe80::1]/alerts",
+	} {
+		if _, err := newNotifier(u); !errors.Is(err, errBlockedAddress) {
+			t.Errorf("newNotifier(%q) = %v, want errBlockedAddress", u, err)
+		}
+	}
+	if _, err := newNotifier("https://hooks.example.com/ytsummary"); err != nil {
+		t.Errorf("public webhook refused: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// stdinPath is the file argument that means standard input, so commands
+// compose with pipes: ytsummary transcript <url> | ytsummary summarize-text -
+const stdinPath = "-"
+
+// stdin is read for a "-" file argument; tests replace it
+var stdin io.Reader = os.Stdin
+
+// readInput reads a file argument, or standard input when path is "-"
+func readInput(path string) ([]byte, error) {
+	if path != stdinPath {
+		return os.ReadFile(path)
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read standard input: %w", err)
+	}
+	return data, nil
+}
+
+// inputName names a file argument in messages
+func inputName(path string) string {
+	if path == stdinPath {
+		return "standard input"
+	}
+	return path
+}
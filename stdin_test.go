@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withStdin makes "-" file arguments read input for the rest of the test
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	stdin = strings.NewReader(input)
+	t.Cleanup(func() { stdin = os.Stdin })
+}
+
+func TestReadInput(t *testing.T) {
+	withStdin(t, "from a pipe")
+	if data, err := readInput("-"); err != nil || string(data) != "from a pipe" {
+		t.Errorf(`readInput("-") = %q, %v`, data, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "transcript.txt")
+	os.WriteFile(path, []byte("from a file"), 0644)
+	if data, err := readInput(path); err != nil || string(data) != "from a file" {
+		t.Errorf("readInput(file) = %q, %v", data, err)
+	}
+}
+
+func TestReadVideoListStdin(t *testing.T) {
+	withStdin(t, "https://youtu.be/dQw4w9WgXcQ\nkJQP7kiw5Fk\n")
+	urls, err := readVideoList("-")
+	if err != nil || !reflect.DeepEqual(urls, []string{"https://youtu.be/dQw4w9WgXcQ", "kJQP7kiw5Fk"}) {
+		t.Errorf(`readVideoList("-") = %v, %v`, urls, err)
+	}
+}
+
+func TestRunSummarizeTextStdin(t *testing.T) {
+	cacheDir = t.TempDir()
+	llmProvider = "fake"
+	t.Cleanup(func() { llmProvider = "" })
+
+	withStdin(t, "WEBVTT\n\n00:00:01.000 --> 00:00:04.000\nNever gonna give you up.\n")
+	if err := runSummarizeText(nil, []string{"-"}); err != nil {
+		t.Errorf("summarize-text - error = %v", err)
+	}
+
+	withStdin(t, "   \n")
+	if err := runSummarizeText(nil, []string{"-"}); err == nil || !strings.Contains(err.Error(), "standard input is empty") {
+		t.Errorf("empty stdin error = %v", err)
+	}
+
+	if err := runSummarizeText(nil, nil); err == nil {
+		t.Error("summarize-text without a file accepted")
+	}
+	textFile = "transcript.txt"
+	t.Cleanup(func() { textFile = "" })
+	if err := runSummarizeText(nil, []string{"-"}); err == nil {
+		t.Error("both --file and an argument accepted")
+	}
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// compareTo is --compare-to: "previous" or a summary ID
+var compareTo string
+
+// StoredSummary is a summary kept after it was generated, with what produced it
+type StoredSummary struct {
+	ID         int64     `json:"id"`
+	VideoID    string    `json:"video_id"`
+	Language   string    `json:"language"`
+	Template   string    `json:"template"`
+	Model      string    `json:"model"`
+	PromptHash string    `json:"prompt_hash"`
+	MaxWords   int       `json:"max_words,omitempty"`
+	Summary    string    `json:"summary"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Estimated tokens spent generating it
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+
+	// Experiment and Variant name the A/B experiment arm that produced it
+	Experiment string `json:"experiment,omitempty"`
+	Variant    string `json:"variant,omitempty"`
+
+	// Rating (1-5) and Feedback are what a client last said about it
+	Rating   int    `json:"rating,omitempty"`
+	Feedback string `json:"feedback,omitempty"`
+
+	// RequestHash identifies the API request options that produced it (see
+	// summaryRequestHash), so concurrent identical requests can share it
+	RequestHash string `json:"-"`
+}
+
+// SummaryStore keeps every generated summary. SQLiteCache implements it.
+type SummaryStore interface {
+	SaveSummary(s *StoredSummary) error
+	// GetSummary returns a summary by ID, or errCacheMiss if there is none
+	GetSummary(id int64) (*StoredSummary, error)
+	// ListSummaries returns a video's summaries, newest first
+	ListSummaries(videoID string) ([]*StoredSummary, error)
+	// ListLatestSummaries returns the newest summary of each template and
+	// language for each of videoIDs, keyed by video ID
+	ListLatestSummaries(videoIDs []string) (map[string][]*StoredSummary, error)
+	// RateSummary records feedback on a summary, or returns errCacheMiss if
+	// there is no such summary
+	RateSummary(id int64, rating int, feedback string) error
+	// LatestRequestSummary returns a video's newest summary made for the
+	// request options requestHash identifies, or errCacheMiss if there is none
+	LatestRequestSummary(videoID, requestHash string) (*StoredSummary, error)
+}
+
+const storedSummaryColumns = "id, video_id, language, template, model, prompt_hash, max_words, summary, created_at, prompt_tokens, completion_tokens, experiment, variant, rating, feedback, request_hash"
+
+func scanStoredSummary(row interface{ Scan(...any) error }) (*StoredSummary, error) {
+	var s StoredSummary
+	err := row.Scan(&s.ID, &s.VideoID, &s.Language, &s.Template, &s.Model, &s.PromptHash, &s.MaxWords, &s.Summary, &s.CreatedAt, &s.PromptTokens, &s.CompletionTokens,
+		&s.Experiment, &s.Variant, &s.Rating, &s.Feedback, &s.RequestHash)
+	return &s, err
+}
+
+func (c *SQLiteCache) SaveSummary(s *StoredSummary) error {
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	res, err := db.Exec(`
+		INSERT INTO summaries (video_id, language, template, model, prompt_hash, max_words, summary, prompt_tokens, completion_tokens, experiment, variant, request_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.VideoID, s.Language, s.Template, s.Model, s.PromptHash, s.MaxWords, s.Summary, s.PromptTokens, s.CompletionTokens, s.Experiment, s.Variant, s.RequestHash)
+	if err != nil {
+		return fmt.Errorf("failed to save summary: %w", err)
+	}
+	s.ID, _ = res.LastInsertId()
+	return nil
+}
+
+func (c *SQLiteCache) GetSummary(id int64) (*StoredSummary, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := scanStoredSummary(db.QueryRow("SELECT "+storedSummaryColumns+" FROM summaries WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query summary: %w", err)
+	}
+	return s, nil
+}
+
+func (c *SQLiteCache) ListSummaries(videoID string) ([]*StoredSummary, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT "+storedSummaryColumns+" FROM summaries WHERE video_id = ? ORDER BY created_at DESC, id DESC", videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []*StoredSummary{}
+	for rows.Next() {
+		s, err := scanStoredSummary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list summaries: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+func (c *SQLiteCache) LatestRequestSummary(videoID, requestHash string) (*StoredSummary, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := scanStoredSummary(db.QueryRow("SELECT "+storedSummaryColumns+" FROM summaries WHERE video_id = ? AND request_hash = ? ORDER BY id DESC LIMIT 1", videoID, requestHash))
+	if err == sql.ErrNoRows {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query summary: %w", err)
+	}
+	return s, nil
+}
+
+func (c *SQLiteCache) ListLatestSummaries(videoIDs []string) (map[string][]*StoredSummary, error) {
+	summaries := make(map[string][]*StoredSummary)
+	if len(videoIDs) == 0 {
+		return summaries, nil
+	}
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]any, len(videoIDs))
+	for i, id := range videoIDs {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(videoIDs)), ",")
+	rows, err := db.Query(`
+		SELECT `+storedSummaryColumns+` FROM summaries WHERE id IN (
+			SELECT MAX(id) FROM summaries WHERE video_id IN (`+placeholders+`)
+			GROUP BY video_id, template, language
+		) ORDER BY video_id, template, language`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list summaries: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		s, err := scanStoredSummary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list summaries: %w", err)
+		}
+		summaries[s.VideoID] = append(summaries[s.VideoID], s)
+	}
+	return summaries, rows.Err()
+}
+
+func (c *SQLiteCache) RateSummary(id int64, rating int, feedback string) error {
+	if c.cfg.ReadOnly {
+		return errCacheReadOnly
+	}
+	db, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	res, err := db.Exec("UPDATE summaries SET rating = ?, feedback = ? WHERE id = ?", rating, feedback, id)
+	if err != nil {
+		return fmt.Errorf("failed to rate summary: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errCacheMiss
+	}
+	return nil
+}
+
+// ListExperimentSummaries returns the summaries experiments produced, or
+// those of one experiment when name is set
+func (c *SQLiteCache) ListExperimentSummaries(name string) ([]*StoredSummary, error) {
+	db, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	query, args := "SELECT "+storedSummaryColumns+" FROM summaries WHERE experiment != ''", []any{}
+	if name != "" {
+		query, args = query+" AND experiment = ?", append(args, name)
+	}
+	rows, err := db.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiment summaries: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []*StoredSummary
+	for rows.Next() {
+		s, err := scanStoredSummary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list experiment summaries: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// newStoredSummary describes a summary just generated with meta
+func newStoredSummary(videoID, lang, template string, maxWords int, summary string, meta *SummaryMeta) *StoredSummary {
+	if template == "" {
+		template = defaultTemplateName
+	}
+	return &StoredSummary{
+		VideoID:          videoID,
+		Language:         lang,
+		Template:         template,
+		Model:            meta.Model,
+		PromptHash:       meta.PromptHash,
+		MaxWords:         maxWords,
+		Summary:          summary,
+		PromptTokens:     meta.PromptTokens,
+		CompletionTokens: meta.CompletionTokens,
+	}
+}
+
+// summaryRequestHash identifies what an API request's summary depends on:
+// the video, the language it's written in, the model and the request's
+// options. How the request named the video, its priority and its spend limit
+// don't change the summary.
+func summaryRequestHash(videoID, lang, model string, req TranscriptRequest) string {
+	req.URL, req.Priority, req.MaxCost = "", "", 0
+	params, _ := json.Marshal(req)
+	return sha256Hex([]byte(strings.Join([]string{videoID, lang, model, string(params)}, "\x00")))[:32]
+}
+
+// meteredClient adds each call's estimated tokens to a SummaryMeta
+type meteredClient struct {
+	LLMClient
+	meta *SummaryMeta
+}
+
+func (c *meteredClient) Complete(systemPrompt, text string, params GenerationParams) (string, error) {
+	reply, err := c.LLMClient.Complete(systemPrompt, text, params)
+	if err != nil {
+		return "", err
+	}
+	c.meta.PromptTokens += estimateTokens(systemPrompt) + estimateTokens(text)
+	c.meta.CompletionTokens += estimateTokens(reply)
+	return reply, nil
+}
+
+// keepSummary stores a summary that was just generated. Failing to keep it
+// doesn't fail the request that produced it.
+func keepSummary(store SummaryStore, s *StoredSummary) {
+	if store == nil {
+		return
+	}
+	if err := store.SaveSummary(s); err != nil && !errors.Is(err, errCacheReadOnly) {
+		fmt.Fprintf(os.Stderr, "warning: failed to keep summary of %s: %v\n", s.VideoID, err)
+	}
+}
+
+// VideoSummariesResponse lists the summaries kept for a video
+type VideoSummariesResponse struct {
+	VideoID   string           `json:"video_id"`
+	Summaries []*StoredSummary `json:"summaries"`
+}
+
+// handleVideoSummaries lists a video's kept summaries, newest first
+func (s *Server) handleVideoSummaries(w http.ResponseWriter, r *http.Request) {
+	videoID, err := extractVideoID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid video ID")
+		return
+	}
+
+	summaries, err := s.summaries.ListSummaries(videoID)
+	if err != nil {
+		writeErrorWithVideo(w, http.StatusInternalServerError, ErrInternal, "Failed to read summaries", videoID)
+		return
+	}
+	writeJSON(w, http.StatusOK, VideoSummariesResponse{VideoID: videoID, Summaries: summaries})
+}
+
+// handleVideoSummary returns one kept summary of a video
+func (s *Server) handleVideoSummary(w http.ResponseWriter, r *http.Request) {
+	videoID, err := extractVideoID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid video ID")
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("summary"), 10, 64)
+	if err != nil {
+		writeErrorWithVideo(w, http.StatusBadRequest, ErrInvalidRequest, "invalid summary ID", videoID)
+		return
+	}
+
+	summary, err := s.summaries.GetSummary(id)
+	if errors.Is(err, errCacheMiss) || err == nil && summary.VideoID != videoID {
+		writeErrorWithVideo(w, http.StatusNotFound, "not_found", "No such summary of this video", videoID)
+		return
+	}
+	if err != nil {
+		writeErrorWithVideo(w, http.StatusInternalServerError, ErrInternal, "Failed to read summary", videoID)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// previousSummary returns the summary --compare-to names: "previous" for the
+// video's latest in lang, or a summary ID. It returns nil if there is none yet.
+func previousSummary(store SummaryStore, compareTo, videoID, lang string) (*StoredSummary, error) {
+	if compareTo != "previous" {
+		id, err := strconv.ParseInt(compareTo, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --compare-to %q (use previous or a summary ID from 'ytsummary summaries list')", compareTo)
+		}
+		s, err := store.GetSummary(id)
+		if errors.Is(err, errCacheMiss) {
+			return nil, fmt.Errorf("no summary %d", id)
+		}
+		return s, err
+	}
+
+	summaries, err := store.ListSummaries(videoID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range summaries {
+		if s.Language == lang {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+// diffLines compares two texts line by line, returning every line of both
+// prefixed with "  " (unchanged), "- " (only in old) or "+ " (only in new)
+func diffLines(old, new string) []string {
+	a, b := strings.Split(old, "\n"), strings.Split(new, "\n")
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	return out
+}
+
+// printSummaryDiff shows how summary differs from prev on stderr, keeping
+// stdout for the summary itself
+func printSummaryDiff(prev *StoredSummary, summary string) {
+	if prev.Summary == summary {
+		log("No changes since summary %d (%s, %s)", prev.ID, prev.Model, prev.CreatedAt.Format(time.DateTime))
+		return
+	}
+	log("Changes since summary %d (%s, %s):", prev.ID, prev.Model, prev.CreatedAt.Format(time.DateTime))
+	for _, line := range diffLines(prev.Summary, summary) {
+		fmt.Fprintln(os.Stderr, line)
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+// runSummariesList lists the summaries kept for a video
+func runSummariesList(cmd *cobra.Command, args []string) error {
+	videoID, err := extractVideoID(args[0])
+	if err != nil {
+		return err
+	}
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	summaries, err := cache.ListSummaries(videoID)
+	if err != nil {
+		return err
+	}
+	if len(summaries) == 0 {
+		fmt.Printf("No summaries of %s kept yet\n", videoID)
+		return nil
+	}
+
+	for _, s := range summaries {
+		fmt.Printf("%-6d  %s  %-5s  %-14s  %s  %d+%d tokens  prompt %s\n", s.ID, s.CreatedAt.Format(time.DateTime), s.Language, s.Template, s.Model, s.PromptTokens, s.CompletionTokens, s.PromptHash)
+	}
+	return nil
+}
+
+// runSummariesShow prints a kept summary
+func runSummariesShow(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid summary ID %q", args[0])
+	}
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	s, err := cache.GetSummary(id)
+	if errors.Is(err, errCacheMiss) {
+		return fmt.Errorf("no summary %d", id)
+	}
+	if err != nil {
+		return err
+	}
+	log("Summary %d of %s (%s, %s, %s)", s.ID, s.VideoID, s.Template, s.Model, s.CreatedAt.Format(time.DateTime))
+	fmt.Println(s.Summary)
+	return nil
+}
+
+// SummaryFeedbackRequest rates a kept summary
+type SummaryFeedbackRequest struct {
+	Rating   int    `json:"rating"` // 1 (poor) to 5 (great)
+	Feedback string `json:"feedback,omitempty"`
+}
+
+// handleSummaryFeedback records a client's rating of a summary it was given
+func (s *Server) handleSummaryFeedback(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("summary"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid summary ID")
+		return
+	}
+	var req SummaryFeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "rating must be 1 to 5")
+		return
+	}
+
+	switch err := s.summaries.RateSummary(id, req.Rating, strings.TrimSpace(req.Feedback)); {
+	case errors.Is(err, errCacheMiss):
+		writeError(w, http.StatusNotFound, "not_found", "No such summary")
+	case errors.Is(err, errCacheReadOnly):
+		writeError(w, http.StatusConflict, ErrInternal, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, ErrInternal, err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSummaryStore(t *testing.T) {
+	cache := newTestCache(t)
+	for _, text := range []string{"First take.", "Second take."} {
+		if err := cache.SaveSummary(&StoredSummary{VideoID: "dQw4w9WgXcQ", Language: "en", Template: "default", Model: "fake", PromptHash: "abc", Summary: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache.SaveSummary(&StoredSummary{VideoID: "other", Language: "en", Template: "default", Model: "fake", Summary: "Elsewhere."})
+
+	got, err := cache.ListSummaries("dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(got))
+	}
+	if got[0].Summary != "Second take." || got[1].Summary != "First take." {
+		t.Errorf("summaries = %q, %q; want newest first", got[0].Summary, got[1].Summary)
+	}
+	if got[0].ID == 0 || got[0].CreatedAt.IsZero() {
+		t.Errorf("stored summary missing id or timestamp: %+v", got[0])
+	}
+
+	if none, err := cache.ListSummaries("unknown"); err != nil || len(none) != 0 {
+		t.Errorf("ListSummaries(unknown) = %v, %v; want empty", none, err)
+	}
+}
+
+func TestVideoSummariesEndpoint(t *testing.T) {
+	cache := newTestCache(t)
+	cacheTranscript(cache, "dQw4w9WgXcQ", "en", "Kept", "One. Two. Three.")
+	s := newServer(ServerConfig{
+		Cache:     cache,
+		Summaries: cache,
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			return summarizeWith(&recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}}, transcript, opts)
+		},
+	})
+	handler := s.Handler()
+
+	for _, body := range []string{`{"url": "https://youtu.be/dQw4w9WgXcQ"}`, `{"url": "https://youtu.be/dQw4w9WgXcQ", "template": "key-points", "max_words": 50}`} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/v1/summarize", bytes.NewBufferString(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("summarize status = %d: %s", w.Code, w.Body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/videos/dQw4w9WgXcQ/summaries", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("summaries status = %d: %s", w.Code, w.Body)
+	}
+	var resp VideoSummariesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Summaries) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(resp.Summaries))
+	}
+	latest, first := resp.Summaries[0], resp.Summaries[1]
+	if latest.Template != "key-points" || latest.MaxWords != 50 || first.Template != "default" {
+		t.Errorf("templates = %s (max %d), %s; want key-points (max 50), default", latest.Template, latest.MaxWords, first.Template)
+	}
+	if latest.Model != "fake" || latest.PromptHash == "" || latest.PromptHash == first.PromptHash {
+		t.Errorf("metadata = %s/%s vs %s; want model fake and distinct prompt hashes", latest.Model, latest.PromptHash, first.PromptHash)
+	}
+	if latest.Summary == "" || latest.Language != "en" {
+		t.Errorf("latest = %+v", latest)
+	}
+	if latest.PromptTokens == 0 || latest.CompletionTokens == 0 {
+		t.Errorf("tokens = %d+%d, want both counted", latest.PromptTokens, latest.CompletionTokens)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/v1/videos/dQw4w9WgXcQ/summaries/%d", first.ID), nil))
+	var one StoredSummary
+	if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&one) != nil || one.Summary != first.Summary {
+		t.Errorf("single summary = %d %+v, want the first summary", w.Code, one)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/v1/videos/jNQXAC9IVRw/summaries/%d", first.ID), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("summary of another video status = %d, want 404", w.Code)
+	}
+
+	// From another client, clear of the rate limit
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/v1/videos/not-a-video!/summaries", nil)
+	req.RemoteAddr = "192.0.2.99:1234"
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID status = %d, want 400", w.Code)
+	}
+}
+
+func TestSummariesMigration(t *testing.T) {
+	// A cache created before token counts were kept
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "transcripts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE summaries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, video_id TEXT NOT NULL, language TEXT NOT NULL,
+		template TEXT NOT NULL, model TEXT NOT NULL, prompt_hash TEXT NOT NULL,
+		max_words INTEGER NOT NULL DEFAULT 0, summary TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		INSERT INTO summaries (video_id, language, template, model, prompt_hash, summary) VALUES ('dQw4w9WgXcQ', 'en', 'default', 'old', 'abc', 'Old.')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	cache := newSQLiteCache(SQLiteCacheConfig{Dir: dir})
+	defer cache.Close()
+	if err := cache.SaveSummary(&StoredSummary{VideoID: "dQw4w9WgXcQ", Language: "en", Template: "default", Model: "new", Summary: "New.", PromptTokens: 10, CompletionTokens: 3}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := cache.ListSummaries("dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].PromptTokens != 10 || got[1].PromptTokens != 0 {
+		t.Errorf("summaries after migration = %+v, %+v", got[0], got[1])
+	}
+}
+
+func TestPreviousSummary(t *testing.T) {
+	cache := newTestCache(t)
+	for _, s := range []*StoredSummary{
+		{VideoID: "dQw4w9WgXcQ", Language: "en", Template: "default", Model: "fake", Summary: "English one."},
+		{VideoID: "dQw4w9WgXcQ", Language: "es", Template: "default", Model: "fake", Summary: "Uno."},
+		{VideoID: "dQw4w9WgXcQ", Language: "en", Template: "default", Model: "fake", Summary: "English two."},
+	} {
+		if err := cache.SaveSummary(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prev, err := previousSummary(cache, "previous", "dQw4w9WgXcQ", "en")
+	if err != nil || prev == nil || prev.Summary != "English two." {
+		t.Errorf("previous = %+v, %v; want the latest English summary", prev, err)
+	}
+	if prev, err := previousSummary(cache, "previous", "jNQXAC9IVRw", "en"); err != nil || prev != nil {
+		t.Errorf("previous of an unsummarized video = %+v, %v; want nil", prev, err)
+	}
+	if prev, err := previousSummary(cache, "1", "dQw4w9WgXcQ", "en"); err != nil || prev.Summary != "English one." {
+		t.Errorf("summary 1 = %+v, %v", prev, err)
+	}
+	for _, bad := range []string{"99", "latest"} {
+		if _, err := previousSummary(cache, bad, "dQw4w9WgXcQ", "en"); err == nil {
+			t.Errorf("previousSummary(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines("Overview\n- point one\n- point two", "Overview\n- point one, revised\n- point two\n- point three")
+	want := []string{"  Overview", "- - point one", "+ - point one, revised", "  - point two", "+ - point three"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffLines() =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -3,77 +3,354 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const defaultModel = "google/gemini-2.0-flash-001"
 const defaultAPIURL = "https://openrouter.ai/api/v1"
-const maxChunkTokens = 100000 // Approximate, will chunk if transcript is very long
+const maxChunkTokens = 100000  // Approximate, will chunk if transcript is very long
+const fakeSummarySentences = 3 // Sentences echoed by the fake provider
 
-// summarize sends the transcript to an LLM and returns a summary
-func summarize(transcript string) (string, error) {
-	apiKey := getConfig(llmAPIKey, "YTSUMMARY_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("no API key provided. Set YTSUMMARY_API_KEY or use --api-key")
+// LLMClient sends a single chat completion request and returns the reply text.
+// Unset params fall back to the client's configured defaults.
+type LLMClient interface {
+	Complete(systemPrompt, text string, params GenerationParams) (string, error)
+	Model() string
+}
+
+// partialSummaryPrompt is used for chunks when a template has no "chunk" block
+const partialSummaryPrompt = `Summarize this section of a YouTube video transcript. Extract the key points and main ideas. Be thorough but concise.`
+
+// SummaryOptions controls how a transcript is summarized
+type SummaryOptions struct {
+	Template string // prompt template name (default: "default")
+	Model    string // overrides the configured model for summarize; "" keeps it
+	Vars     PromptVars
+	Focus    string // timestamp of the section the user linked to, e.g. "12:34"
+
+	// PromptLanguage picks the translation of the template to use; "" follows
+	// --prompt-lang, else Vars.Language, the language summaries are written in
+	PromptLanguage string
+
+	// Generation overrides the configured temperature, top_p and max_tokens
+	Generation GenerationParams
+
+	// MaxWords asks for a summary under this many words; 0 means no limit
+	MaxWords int
+
+	// Lint checks the summary is in Vars.Language and within MaxWords,
+	// retrying once with a corrective instruction if not
+	Lint bool
+
+	// Checkpoints stores chunk summaries so a failed run resumes where it
+	// stopped; nil disables checkpointing
+	Checkpoints Cache
+
+	// Budget refuses LLM calls that would exceed its spend limit; nil means
+	// no limit
+	Budget *costBudget
+
+	// Timeline records how long each LLM call takes; nil disables timing
+	Timeline *videoTimeline
+
+	// Meta, when set, is filled in with how the summary was generated
+	Meta *SummaryMeta
+
+	// Highlights, when set, asks for this many of the transcript's most
+	// important moments as JSON instead of a summary (see extractHighlights).
+	// The template, Focus, MaxWords and Lint don't apply.
+	Highlights int
+
+	// Notes are the user's own notes on the video, given to the LLM as
+	// context for the final summary
+	Notes string
+
+	// Partial, when set, collects chunk summaries as they finish, for a
+	// caller that stops waiting before the final summary
+	Partial *partialSummary
+}
+
+// SummaryMeta describes how a summary was generated
+type SummaryMeta struct {
+	Model      string
+	PromptHash string // of the final-summary prompt, to tell prompt revisions apart
+
+	// Estimated tokens sent and received across all LLM calls; chunks resumed
+	// from checkpoints cost nothing
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// maxWordsPrompt is appended to the final-summary prompt when a word budget is set
+const maxWordsPrompt = "Keep the summary under %d words."
+
+// linkedSectionMarker is inserted into the transcript where the linked section starts
+const linkedSectionMarker = "[LINKED SECTION]"
+
+// Appended to the prompts when a summary should focus on a linked timestamp
+const (
+	linkedFocusPrompt      = "The user linked to the part of the video starting at %s, marked %s in the transcript where timings are available. Cover the whole video, but describe that part in the most detail."
+	linkedFocusChunkPrompt = "If this section contains %s, the user linked to the part that follows it: describe that part in more detail and say it is the linked section."
+)
+
+// newLLMClient builds the client for the configured provider
+func newLLMClient() (LLMClient, error) {
+	return newLLMClientForModel("")
+}
+
+// newLLMClientForModel builds the client for the configured provider with
+// model in place of the configured one, unless it's empty
+func newLLMClientForModel(modelOverride string) (LLMClient, error) {
+	provider := getConfig(llmProvider, "YTSUMMARY_PROVIDER")
+
+	switch provider {
+	case "", "openai":
+		apiKey, err := llmAPIKeyConfig()
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("no API key provided. Set YTSUMMARY_API_KEY, use --api-key or store one with 'ytsummary auth set-key'")
+		}
+
+		model := configuredModel(modelOverride)
+
+		apiURL := getConfig(llmBaseURL, "YTSUMMARY_API_URL")
+		if apiURL == "" {
+			apiURL = defaultAPIURL
+		}
+
+		params, err := generationConfig()
+		if err != nil {
+			return nil, err
+		}
+		caps, err := capabilitiesConfig(model)
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := llmTimeoutConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		client := &openAIClient{apiKey: apiKey, model: model, apiURL: apiURL, params: params, caps: caps, timeout: timeout}
+		if isOpenRouter(apiURL) {
+			if client.openRouter, err = openRouterConfigFromEnv(); err != nil {
+				return nil, err
+			}
+		}
+		return client, nil
+	case "fake":
+		return &fakeLLMClient{sentences: fakeSummarySentences}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q (use openai or fake)", provider)
 	}
+}
 
-	model := getConfig(llmModel, "YTSUMMARY_MODEL")
-	if model == "" {
-		model = defaultModel
+// configuredModel returns the model summaries are asked of: modelOverride,
+// else --model / YTSUMMARY_MODEL, else the default
+func configuredModel(modelOverride string) string {
+	if modelOverride != "" {
+		return modelOverride
 	}
+	if model := getConfig(llmModel, "YTSUMMARY_MODEL"); model != "" {
+		return model
+	}
+	return defaultModel
+}
 
-	apiURL := getConfig(llmBaseURL, "YTSUMMARY_API_URL")
-	if apiURL == "" {
-		apiURL = defaultAPIURL
+// summarize sends the transcript to an LLM and returns a summary
+func summarize(transcript string, opts SummaryOptions) (string, error) {
+	client, err := newLLMClientForModel(opts.Model)
+	if err != nil {
+		return "", err
+	}
+	return summarizeWith(client, transcript, opts)
+}
+
+// summarizeWith summarizes the transcript using the given client
+func summarizeWith(client LLMClient, transcript string, opts SummaryOptions) (string, error) {
+	// Chunk checkpoints are keyed on the params the client will actually send
+	params := opts.Generation
+	if r, ok := client.(paramResolver); ok {
+		params = r.resolveParams(params)
+	}
+	// The fake provider doesn't read prompts, so it's told the mode
+	if fake, ok := client.(*fakeLLMClient); ok && opts.Highlights > 0 {
+		client = &fakeLLMClient{sentences: fake.sentences, highlights: true}
+	}
+	if opts.Budget != nil {
+		client = &budgetedClient{LLMClient: client, budget: opts.Budget}
+	}
+	prompt, chunkPrompt, err := summaryPrompts(opts)
+	if err != nil {
+		return "", err
+	}
+	if opts.Highlights > 0 {
+		opts.Lint = false
+	}
+	if opts.Meta != nil {
+		*opts.Meta = SummaryMeta{Model: client.Model(), PromptHash: sha256Hex([]byte(prompt))[:16]}
+		client = &meteredClient{LLMClient: client, meta: opts.Meta}
 	}
 
 	// For very long transcripts, chunk and summarize each chunk
 	chunks := chunkTranscript(transcript, maxChunkTokens)
 
 	if len(chunks) == 1 {
-		return summarizeChunk(chunks[0], apiKey, model, apiURL, false)
+		done := opts.Timeline.begin(stageSummarize)
+		summary, err := completeWithLint(client, chunks[0], prompt, opts)
+		done(err)
+		if opts.Highlights > 0 {
+			return summary, err
+		}
+		return withParagraphDirection(summary), err
 	}
 
-	// Multi-chunk: summarize each, then combine
+	// Multi-chunk: summarize each, then combine. Each chunk summary is
+	// checkpointed so a retry after a failure resumes instead of starting over.
+	store := opts.Checkpoints
 	var chunkSummaries []string
+	var checkpoints []string
 	for i, chunk := range chunks {
+		key := chunkCheckpointKey(client.Model(), chunkPrompt, params, chunk)
+		checkpoints = append(checkpoints, key)
+
+		if store != nil {
+			if summary, err := store.GetCheckpoint(key); err == nil {
+				fmt.Fprintf(os.Stderr, "Resuming chunk %d/%d from checkpoint\n", i+1, len(chunks))
+				chunkSummaries = append(chunkSummaries, summary)
+				opts.Partial.add(summary)
+				continue
+			}
+		}
+
 		fmt.Fprintf(os.Stderr, "Summarizing chunk %d/%d...\n", i+1, len(chunks))
-		summary, err := summarizeChunk(chunk, apiKey, model, apiURL, true)
+		done := opts.Timeline.begin(stageLLMChunk + strconv.Itoa(i+1))
+		summary, err := summarizeChunk(client, chunk, chunkPrompt, opts.Generation)
+		done(err)
 		if err != nil {
 			return "", fmt.Errorf("failed to summarize chunk %d: %w", i+1, err)
 		}
+		if store != nil {
+			if err := store.SaveCheckpoint(key, client.Model(), summary); err != nil && !errors.Is(err, errCacheReadOnly) {
+				fmt.Fprintf(os.Stderr, "warning: failed to checkpoint chunk %d: %v\n", i+1, err)
+			}
+		}
 		chunkSummaries = append(chunkSummaries, summary)
+		opts.Partial.add(summary)
 	}
 
 	// Combine chunk summaries into final summary
 	combined := strings.Join(chunkSummaries, "\n\n---\n\n")
-	return summarizeChunk(combined, apiKey, model, apiURL, false)
-}
+	done := opts.Timeline.begin(stageCombine)
+	summary, err := completeWithLint(client, combined, prompt, opts)
+	done(err)
+	if err != nil {
+		return "", err
+	}
 
-func summarizeChunk(text, apiKey, model, apiURL string, isPartial bool) (string, error) {
-	prompt := `Summarize this YouTube video transcript. Provide:
-1. A brief overview (2-3 sentences)
-2. Key points (bullet list)
-3. Any notable quotes or moments
+	// Checkpoints are only needed until the whole summary succeeds
+	if store != nil {
+		if err := store.DeleteCheckpoints(checkpoints); err != nil && !errors.Is(err, errCacheReadOnly) {
+			fmt.Fprintf(os.Stderr, "warning: failed to clear chunk checkpoints: %v\n", err)
+		}
+	}
 
-Keep it concise but comprehensive.`
+	if opts.Highlights > 0 {
+		return summary, nil
+	}
+	return withParagraphDirection(summary), nil
+}
 
-	if isPartial {
-		prompt = `Summarize this section of a YouTube video transcript. Extract the key points and main ideas. Be thorough but concise.`
+// summaryPrompts returns the final-summary and per-chunk prompts for opts
+func summaryPrompts(opts SummaryOptions) (prompt, chunkPrompt string, err error) {
+	if opts.Highlights > 0 {
+		prompt, chunkPrompt = highlightsPrompts(opts.Highlights, opts.Vars.Language)
+		return prompt, chunkPrompt, nil
+	}
+	tmpl, err := getLocalizedTemplate(opts.Template, promptLanguage(opts))
+	if err != nil {
+		return "", "", err
+	}
+	if prompt, chunkPrompt, err = tmpl.render(opts.Vars); err != nil {
+		return "", "", err
+	}
+	if instruction := summaryLanguageInstruction(tmpl, opts.Vars.Language); instruction != "" {
+		prompt += "\n\n" + instruction
 	}
+	if opts.Focus != "" {
+		prompt += "\n\n" + fmt.Sprintf(linkedFocusPrompt, opts.Focus, linkedSectionMarker)
+		chunkPrompt += "\n\n" + fmt.Sprintf(linkedFocusChunkPrompt, linkedSectionMarker)
+	}
+	if opts.Notes != "" {
+		prompt += "\n\n" + fmt.Sprintf(notesPrompt, opts.Notes)
+	}
+	if opts.MaxWords > 0 {
+		prompt += "\n\n" + fmt.Sprintf(maxWordsPrompt, opts.MaxWords)
+	}
+	return prompt, chunkPrompt, nil
+}
+
+func summarizeChunk(client LLMClient, text, prompt string, params GenerationParams) (string, error) {
+	return client.Complete(prompt, text, params)
+}
 
+// chunkCheckpointKey identifies a chunk summary by everything that affects its output
+func chunkCheckpointKey(model, prompt string, params GenerationParams, chunk string) string {
+	p, _ := json.Marshal(params)
+	return sha256Hex([]byte(model + "\x00" + prompt + "\x00" + string(p) + "\x00" + chunk))
+}
+
+// paramResolver is an LLMClient that fills unset GenerationParams with
+// defaults of its own
+type paramResolver interface {
+	resolveParams(params GenerationParams) GenerationParams
+}
+
+// openAIClient talks to any OpenAI-compatible chat completions API
+type openAIClient struct {
+	apiKey string
+	model  string
+	apiURL string
+	params GenerationParams // configured defaults
+	caps   modelCapabilities
+
+	timeout time.Duration // for each completion request
+
+	// openRouter adds attribution headers and routing preferences; nil for
+	// other providers
+	openRouter *openRouterConfig
+}
+
+func (c *openAIClient) Model() string {
+	return c.model
+}
+
+// resolveParams fills unset params with the configured defaults, then the model's
+func (c *openAIClient) resolveParams(params GenerationParams) GenerationParams {
+	return params.withDefaults(c.params).withDefaults(GenerationParams{MaxTokens: c.caps.maxTokensDefault()})
+}
+
+func (c *openAIClient) Complete(systemPrompt, text string, params GenerationParams) (string, error) {
+	params = c.resolveParams(params)
 	reqBody := map[string]interface{}{
-		"model": model,
+		"model": c.model,
 		"messages": []map[string]string{
-			{"role": "system", "content": prompt},
+			{"role": "system", "content": systemPrompt},
 			{"role": "user", "content": text},
 		},
-		"max_tokens": 2000,
+	}
+	c.caps.applyTo(reqBody, params)
+	if c.openRouter != nil {
+		c.openRouter.applyTo(reqBody)
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -81,18 +358,18 @@ Keep it concise but comprehensive.`
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", apiURL+"/chat/completions", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequest("POST", c.apiURL+"/chat/completions", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return "", err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{
-		Timeout: 60 * time.Second,
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.openRouter != nil {
+		c.openRouter.setHeaders(req)
 	}
-	resp, err := client.Do(req)
+
+	resp, err := egressClient(c.timeout).Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -104,7 +381,7 @@ Keep it concise but comprehensive.`
 	}
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return "", newLLMError(c.model, resp.StatusCode, body)
 	}
 
 	var result struct {
@@ -112,18 +389,68 @@ Keep it concise but comprehensive.`
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
 		return "", err
 	}
+	cliMetrics.recordTokens(result.Usage.PromptTokens, result.Usage.CompletionTokens)
 
 	if len(result.Choices) == 0 {
 		return "", fmt.Errorf("no response from API")
 	}
 
-	return result.Choices[0].Message.Content, nil
+	// Reasoning returned in separate fields is ignored; inline blocks are stripped
+	choice := result.Choices[0]
+	content := stripReasoning(choice.Message.Content)
+	if content == "" && choice.FinishReason == "length" {
+		return "", fmt.Errorf("%s used all %d completion tokens before answering; raise --max-tokens (YTSUMMARY_MAX_TOKENS)", c.model, params.MaxTokens)
+	}
+	return content, nil
+}
+
+// fakeLLMClient is a deterministic offline provider that echoes the first few
+// sentences of its input. Used for tests, demos and CI (--provider fake).
+type fakeLLMClient struct {
+	sentences  int
+	highlights bool // reply with highlights JSON instead of a summary
+}
+
+func (c *fakeLLMClient) Model() string {
+	return "fake"
+}
+
+func (c *fakeLLMClient) Complete(systemPrompt, text string, params GenerationParams) (string, error) {
+	summary := firstSentences(text, c.sentences)
+	if c.highlights {
+		summary = fakeHighlights(text, c.sentences)
+	}
+	// Report approximate usage (1 token ≈ 4 characters) so metrics work offline
+	cliMetrics.recordTokens((len(systemPrompt)+len(text))/4, len(summary)/4)
+	return summary, nil
+}
+
+// firstSentences returns the first n sentences of text (or all of it if shorter)
+func firstSentences(text string, n int) string {
+	count := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '.', '!', '?':
+			if i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\n' {
+				count++
+				if count == n {
+					return strings.TrimSpace(text[:i+1])
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(text)
 }
 
 // chunkTranscript splits text into chunks that fit within token limits
@@ -160,7 +487,7 @@ func chunkTranscript(text string, maxTokens int) []string {
 // getConfig returns flag value if set, otherwise env var
 func getConfig(flagVal, envKey string) string {
 	if flagVal != "" {
-		return flagVal
+		return resolveSecretRef(flagVal)
 	}
-	return os.Getenv(envKey)
+	return resolveSecretRef(os.Getenv(envKey))
 }
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordingLLMClient wraps the fake provider and records every prompt it sees
+type recordingLLMClient struct {
+	fakeLLMClient
+	calls   []string
+	prompts []string
+
+	// failOn makes the Nth call (1-based) fail when non-zero
+	failOn int
+}
+
+func (c *recordingLLMClient) Complete(systemPrompt, text string, params GenerationParams) (string, error) {
+	c.calls = append(c.calls, text)
+	c.prompts = append(c.prompts, systemPrompt)
+	if len(c.calls) == c.failOn {
+		return "", errors.New("simulated provider failure")
+	}
+	return c.fakeLLMClient.Complete(systemPrompt, text, params)
+}
+
+func TestFirstSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		n    int
+		want string
+	}{
+		{"fewer than n", "One sentence only.", 3, "One sentence only."},
+		{"exactly n", "One. Two! Three?", 3, "One. Two! Three?"},
+		{"more than n", "One. Two. Three. Four.", 2, "One. Two."},
+		{"decimal not a boundary", "Pi is 3.14 roughly. Next.", 1, "Pi is 3.14 roughly."},
+		{"no punctuation", "just some words", 3, "just some words"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstSentences(tt.text, tt.n); got != tt.want {
+				t.Errorf("firstSentences() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeWithFakeProvider(t *testing.T) {
+	llmProvider = "fake"
+	defer func() { llmProvider = "" }()
+
+	summary, err := summarize("First point. Second point. Third point. Fourth point.", SummaryOptions{})
+	if err != nil {
+		t.Fatalf("summarize() error = %v", err)
+	}
+	if summary != "First point. Second point. Third point." {
+		t.Errorf("summary = %q", summary)
+	}
+
+	// Only the mode picks highlights, not what a template's prompt says
+	reply, err := (&fakeLLMClient{sentences: 1}).Complete("Pick moments for a highlight reel.", "[1] 0:00 First point.", GenerationParams{})
+	if err != nil || reply != "[1] 0:00 First point." {
+		t.Errorf("Complete() = %q, %v", reply, err)
+	}
+}
+
+func TestSummarizeWithChunks(t *testing.T) {
+	client := &recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}}
+
+	// Two chunks worth of text
+	transcript := strings.Repeat("word. ", maxChunkTokens*4/6+10)
+
+	partial := &partialSummary{}
+	if _, err := summarizeWith(client, transcript, SummaryOptions{Partial: partial}); err != nil {
+		t.Fatalf("summarizeWith() error = %v", err)
+	}
+
+	// Two chunk summaries plus the combine step
+	if len(client.calls) != 3 {
+		t.Errorf("LLM called %d times, want 3", len(client.calls))
+	}
+	if got := partial.summaries(); len(got) != 2 {
+		t.Errorf("partial has %d chunk summaries, want 2", len(got))
+	}
+}
+
+func TestSummarizeWithFocus(t *testing.T) {
+	client := &recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}}
+
+	if _, err := summarizeWith(client, "Intro. "+linkedSectionMarker+" Details.", SummaryOptions{Focus: "12:34"}); err != nil {
+		t.Fatalf("summarizeWith() error = %v", err)
+	}
+	if len(client.prompts) != 1 || !strings.Contains(client.prompts[0], "starting at 12:34") {
+		t.Errorf("prompt = %q, want focus hint for 12:34", client.prompts)
+	}
+
+	client = &recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}}
+	if _, err := summarizeWith(client, "Intro.", SummaryOptions{}); err != nil {
+		t.Fatalf("summarizeWith() error = %v", err)
+	}
+	if strings.Contains(client.prompts[0], linkedSectionMarker) {
+		t.Errorf("prompt without focus mentions the marker: %q", client.prompts[0])
+	}
+}
+
+func TestNewLLMClientUnknownProvider(t *testing.T) {
+	llmProvider = "bogus"
+	defer func() { llmProvider = "" }()
+
+	if _, err := newLLMClient(); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestOpenAIClientComplete(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("path = %q, want /chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+
+		var body struct {
+			Model    string              `json:"model"`
+			Messages []map[string]string `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "test-model" || len(body.Messages) != 2 {
+			t.Errorf("unexpected request body: %+v", body)
+		}
+
+		w.Write([]byte(`{"choices":[{"message":{"content":"a summary"}}]}`))
+	}))
+	defer srv.Close()
+
+	client := &openAIClient{apiKey: "test-key", model: "test-model", apiURL: srv.URL}
+	got, err := client.Complete("system", "text", GenerationParams{})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if got != "a summary" {
+		t.Errorf("Complete() = %q, want %q", got, "a summary")
+	}
+}
+
+func TestSummarizeResumesFromCheckpoint(t *testing.T) {
+	opts := SummaryOptions{Checkpoints: newTestCache(t)}
+
+	// Three chunks worth of text
+	transcript := strings.Repeat("alpha. ", maxChunkTokens*4/7) + strings.Repeat("beta. ", maxChunkTokens*4/6) + strings.Repeat("gamma. ", maxChunkTokens*4/7)
+	if n := len(chunkTranscript(transcript, maxChunkTokens)); n != 3 {
+		t.Fatalf("test transcript has %d chunks, want 3", n)
+	}
+
+	// First attempt fails on the third chunk
+	first := &recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}, failOn: 3}
+	if _, err := summarizeWith(first, transcript, opts); err == nil {
+		t.Fatal("expected first attempt to fail")
+	}
+
+	// Retry only needs the failed chunk plus the combine step
+	retry := &recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}}
+	if _, err := summarizeWith(retry, transcript, opts); err != nil {
+		t.Fatalf("retry error = %v", err)
+	}
+	if len(retry.calls) != 2 {
+		t.Errorf("retry made %d LLM calls, want 2", len(retry.calls))
+	}
+
+	// Checkpoints are cleared after success, so a fresh run starts over
+	fresh := &recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}}
+	if _, err := summarizeWith(fresh, transcript, opts); err != nil {
+		t.Fatalf("fresh run error = %v", err)
+	}
+	if len(fresh.calls) != 4 {
+		t.Errorf("fresh run made %d LLM calls, want 4", len(fresh.calls))
+	}
+
+	// Chunks summarized with other generation params aren't reused
+	failed := &recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}, failOn: 3}
+	summarizeWith(failed, transcript, opts)
+	temperature := 0.2
+	opts.Generation = GenerationParams{Temperature: &temperature}
+	other := &recordingLLMClient{fakeLLMClient: fakeLLMClient{sentences: 1}}
+	if _, err := summarizeWith(other, transcript, opts); err != nil {
+		t.Fatalf("run with other params error = %v", err)
+	}
+	if len(other.calls) != 4 {
+		t.Errorf("run with other params made %d LLM calls, want 4", len(other.calls))
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl templates/*/*.tmpl
+var embeddedTemplates embed.FS
+
+const defaultTemplateName = "default"
+
+// PromptVars are the variables available to prompt templates
+type PromptVars struct {
+	Title    string
+	Channel  string
+	Duration string // human readable, e.g. "1h02m"
+	Language string
+}
+
+// PromptTemplate is a named prompt. The template body renders the system prompt
+// for the final summary; an optional {{define "chunk"}} block renders the prompt
+// used for each section of a long transcript.
+type PromptTemplate struct {
+	Name        string
+	Description string
+	Source      string // "embedded" or the file path it was loaded from
+	Language    string // of a translation, e.g. "es"; "" for the English originals
+	body        string
+}
+
+// Description comes from a leading {{/* ... */}} comment
+var templateDescriptionRe = regexp.MustCompile(`^\{\{-?\s*/\*\s*(.*?)\s*\*/\s*-?\}\}`)
+
+// loadTemplates returns all templates by name. Files in the templates directory
+// (--templates-dir / YTSUMMARY_TEMPLATES_DIR) override embedded ones of the same name.
+func loadTemplates() (map[string]*PromptTemplate, error) {
+	return loadTranslations("")
+}
+
+// loadTranslations returns the templates translated into lang, which live in a
+// subdirectory named for it ("" for the English originals)
+func loadTranslations(lang string) (map[string]*PromptTemplate, error) {
+	return loadTemplateDir(getConfig(templatesDir, "YTSUMMARY_TEMPLATES_DIR"), lang)
+}
+
+// loadTemplateDir returns the embedded templates translated into lang, with
+// those in dir's subdirectory for it (dir itself for "") on top
+func loadTemplateDir(dir, lang string) (map[string]*PromptTemplate, error) {
+	templates := make(map[string]*PromptTemplate)
+
+	embedded, err := fs.Glob(embeddedTemplates, path.Join("templates", lang, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range embedded {
+		body, err := embeddedTemplates.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		tmpl := newPromptTemplate(path, string(body))
+		tmpl.Source = "embedded"
+		tmpl.Language = lang
+		templates[tmpl.Name] = tmpl
+	}
+
+	if dir == "" {
+		return templates, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, lang, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	for _, path := range paths {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		tmpl := newPromptTemplate(path, string(body))
+		tmpl.Source = path
+		tmpl.Language = lang
+		templates[tmpl.Name] = tmpl
+	}
+
+	return templates, nil
+}
+
+func newPromptTemplate(path, body string) *PromptTemplate {
+	tmpl := &PromptTemplate{
+		Name: strings.TrimSuffix(filepath.Base(path), ".tmpl"),
+		body: body,
+	}
+	if m := templateDescriptionRe.FindStringSubmatch(strings.TrimSpace(body)); m != nil {
+		tmpl.Description = m[1]
+	}
+	return tmpl
+}
+
+// getTemplate looks up a template by name, defaulting to "default"
+func getTemplate(name string) (*PromptTemplate, error) {
+	if name == "" {
+		name = defaultTemplateName
+	}
+
+	templates, err := loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q (see 'ytsummary templates list')", name)
+	}
+	return tmpl, nil
+}
+
+// getLocalizedTemplate looks up a template's translation into lang, else the
+// English template. A custom English template hides the embedded translations
+// of the built-in one it overrides.
+func getLocalizedTemplate(name, lang string) (*PromptTemplate, error) {
+	tmpl, err := getTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	lang = baseLanguage(lang)
+	if lang == "en" || !validLanguageSubtag(lang) {
+		return tmpl, nil
+	}
+
+	translations, err := loadTranslations(lang)
+	if err != nil {
+		return nil, err
+	}
+	translation, ok := translations[tmpl.Name]
+	if !ok || (translation.Source == "embedded" && tmpl.Source != "embedded") {
+		return tmpl, nil
+	}
+	return translation, nil
+}
+
+// templateLanguages returns the languages templates are translated into
+func templateLanguages() ([]string, error) {
+	langs := make(map[string]bool)
+	embedded, err := fs.Glob(embeddedTemplates, "templates/*/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range embedded {
+		langs[path.Base(path.Dir(p))] = true
+	}
+	if dir := getConfig(templatesDir, "YTSUMMARY_TEMPLATES_DIR"); dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*", "*.tmpl"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates: %w", err)
+		}
+		for _, p := range paths {
+			langs[filepath.Base(filepath.Dir(p))] = true
+		}
+	}
+
+	list := make([]string, 0, len(langs))
+	for lang := range langs {
+		if validLanguageSubtag(lang) {
+			list = append(list, lang)
+		}
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// render returns the final-summary and per-chunk prompts for the given variables
+func (p *PromptTemplate) render(vars PromptVars) (prompt, chunkPrompt string, err error) {
+	t, err := template.New(p.Name).Option("missingkey=zero").Parse(p.body)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid template %q: %w", p.Name, err)
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, vars); err != nil {
+		return "", "", fmt.Errorf("failed to render template %q: %w", p.Name, err)
+	}
+	prompt = strings.TrimSpace(b.String())
+
+	chunkPrompt = partialSummaryPrompt
+	if chunk := t.Lookup("chunk"); chunk != nil {
+		b.Reset()
+		if err := chunk.Execute(&b, vars); err != nil {
+			return "", "", fmt.Errorf("failed to render template %q: %w", p.Name, err)
+		}
+		chunkPrompt = strings.TrimSpace(b.String())
+	}
+
+	return prompt, chunkPrompt, nil
+}
+
+// sortedTemplates returns templates ordered by name for listing
+func sortedTemplates(templates map[string]*PromptTemplate) []*PromptTemplate {
+	list := make([]*PromptTemplate, 0, len(templates))
+	for _, tmpl := range templates {
+		list = append(list, tmpl)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// formatDuration renders seconds as a compact human readable duration
+func formatDuration(seconds int) string {
+	if seconds <= 0 {
+		return ""
+	}
+	h, m, s := seconds/3600, (seconds%3600)/60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%dh%02dm", h, m)
+	}
+	if m > 0 {
+		return fmt.Sprintf("%dm%02ds", m, s)
+	}
+	return fmt.Sprintf("%ds", s)
+}
+
+// promptVarsFromEntry builds template variables from cached video metadata
+func promptVarsFromEntry(entry *CacheEntry, lang string) PromptVars {
+	return PromptVars{
+		Title:    entry.Title,
+		Channel:  entry.Channel,
+		Duration: formatDuration(entry.DurationSeconds),
+		Language: lang,
+	}
+}
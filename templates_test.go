@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultTemplateMatchesBuiltinPrompt(t *testing.T) {
+	tmpl, err := getTemplate("")
+	if err != nil {
+		t.Fatalf("getTemplate() error = %v", err)
+	}
+
+	prompt, chunkPrompt, err := tmpl.render(PromptVars{Title: "Ignored"})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	if !strings.HasPrefix(prompt, "Summarize this YouTube video transcript. Provide:") {
+		t.Errorf("unexpected default prompt:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "{{") || strings.Contains(prompt, "Overview, key points") {
+		t.Errorf("template comment or actions leaked into prompt:\n%s", prompt)
+	}
+	if chunkPrompt != partialSummaryPrompt {
+		t.Errorf("chunk prompt = %q, want %q", chunkPrompt, partialSummaryPrompt)
+	}
+}
+
+func TestTemplateVariables(t *testing.T) {
+	tmpl, err := getTemplate("detailed")
+	if err != nil {
+		t.Fatalf("getTemplate() error = %v", err)
+	}
+
+	prompt, _, err := tmpl.render(PromptVars{Title: "Go Generics", Channel: "GopherCon", Duration: "42m10s"})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	for _, want := range []string{`"Go Generics"`, "by GopherCon", "(42m10s long)"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestCustomTemplatesDir(t *testing.T) {
+	dir := t.TempDir()
+	templatesDir = dir
+	defer func() { templatesDir = "" }()
+
+	custom := `{{/* Tweet-length summary */}}Summarize "{{.Title}}" in under 280 characters.`
+	if err := os.WriteFile(filepath.Join(dir, "tweet.tmpl"), []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Overrides the embedded template of the same name
+	if err := os.WriteFile(filepath.Join(dir, "key-points.tmpl"), []byte("Custom key points"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := loadTemplates()
+	if err != nil {
+		t.Fatalf("loadTemplates() error = %v", err)
+	}
+
+	tweet, ok := templates["tweet"]
+	if !ok {
+		t.Fatal("custom template not loaded")
+	}
+	if tweet.Description != "Tweet-length summary" {
+		t.Errorf("Description = %q", tweet.Description)
+	}
+	prompt, _, err := tweet.render(PromptVars{Title: "Demo"})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	if prompt != `Summarize "Demo" in under 280 characters.` {
+		t.Errorf("prompt = %q", prompt)
+	}
+
+	if templates["key-points"].Source == "embedded" {
+		t.Error("custom key-points template should override the embedded one")
+	}
+	if _, ok := templates["meeting-notes"]; !ok {
+		t.Error("embedded templates should still be available")
+	}
+}
+
+func TestUnknownTemplate(t *testing.T) {
+	if _, err := getTemplate("does-not-exist"); err == nil {
+		t.Error("expected error for unknown template")
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, ""},
+		{45, "45s"},
+		{212, "3m32s"},
+		{3725, "1h02m"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.seconds); got != tt.want {
+			t.Errorf("formatDuration(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
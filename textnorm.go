@@ -0,0 +1,180 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Caption text arrives in whatever Unicode form the uploader or YouTube's
+// speech recognition produced: "é" may be one character or "e" plus a
+// combining accent, and Arabic or Hebrew cues may carry directional controls
+// meant for a single line. These helpers put it in NFC and keep each cue's
+// directionality to itself so cues can be compared and joined safely.
+
+// toNFC returns s in Unicode Normalization Form C
+func toNFC(s string) string {
+	return norm.NFC.String(s)
+}
+
+// Directional formatting characters
+const (
+	bidiLRM = '\u200E' // left-to-right mark
+	bidiRLM = '\u200F' // right-to-left mark
+	bidiALM = '\u061C' // Arabic letter mark
+	bidiPDF = '\u202C' // ends an embedding or override
+	bidiPDI = '\u2069' // ends an isolate
+)
+
+// balanceBidiControls closes the embeddings, overrides and isolates a cue
+// opens, and drops closers it never opened, so a cue's direction can't leak
+// into the text joined after it
+func balanceBidiControls(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r >= '\u202A' && r <= '\u202E' || r >= '\u2066' && r <= '\u2069' }) {
+		return s
+	}
+	var b strings.Builder
+	var open []rune // closers owed, innermost last
+	for _, r := range s {
+		switch r {
+		case '\u202A', '\u202B', '\u202D', '\u202E': // LRE, RLE, LRO, RLO
+			open = append(open, bidiPDF)
+		case '\u2066', '\u2067', '\u2068': // LRI, RLI, FSI
+			open = append(open, bidiPDI)
+		case bidiPDF:
+			if len(open) == 0 || open[len(open)-1] != bidiPDF {
+				continue
+			}
+			open = open[:len(open)-1]
+		case bidiPDI:
+			// A PDI also closes embeddings opened inside its isolate
+			i := len(open) - 1
+			for i >= 0 && open[i] != bidiPDI {
+				i--
+			}
+			if i < 0 {
+				continue
+			}
+			for _, closer := range open[i+1:] {
+				b.WriteRune(closer)
+			}
+			open = open[:i]
+		}
+		b.WriteRune(r)
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteRune(open[i])
+	}
+	return b.String()
+}
+
+// isInvisibleFormat reports whether r is whitespace or a format character
+// (directional marks, zero-width spaces and joiners, byte order marks)
+func isInvisibleFormat(r rune) bool {
+	return unicode.IsSpace(r) || unicode.Is(unicode.Cf, r)
+}
+
+// cleanCaptionText normalizes one caption cue: NFC, directional controls
+// balanced, surrounding whitespace trimmed. A cue with nothing visible
+// becomes "".
+func cleanCaptionText(s string) string {
+	if isASCII(s) {
+		return strings.TrimSpace(s)
+	}
+	s = strings.TrimSpace(balanceBidiControls(toNFC(s)))
+	if strings.IndexFunc(s, func(r rune) bool { return !isInvisibleFormat(r) }) < 0 {
+		return ""
+	}
+	return s
+}
+
+// isASCII reports whether s is entirely ASCII, which needs no normalizing
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// startsWithMark reports whether s begins with a combining mark, such as a
+// vowel sign speech recognition split from its consonant across two cues
+func startsWithMark(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.In(r, unicode.Mn, unicode.Mc, unicode.Me)
+}
+
+// appendCue adds a cleaned cue to a transcript being joined, separating it
+// with a space unless it continues the previous cue's last character
+func appendCue(b *strings.Builder, cue string) {
+	if b.Len() > 0 && !startsWithMark(cue) {
+		b.WriteByte(' ')
+	}
+	b.WriteString(cue)
+}
+
+// inRTLBlock reports whether r is in a block of a right-to-left script:
+// Hebrew through Arabic Extended, the Hebrew and Arabic presentation forms,
+// and the historic and Adlam ranges of the supplementary planes
+func inRTLBlock(r rune) bool {
+	return r >= 0x0590 && r <= 0x08FF || r >= 0xFB1D && r <= 0xFDFF || r >= 0xFE70 && r <= 0xFEFF ||
+		r >= 0x10800 && r <= 0x10FFF || r >= 0x1E800 && r <= 0x1EFFF
+}
+
+// strongDirection classifies r as right-to-left (1), left-to-right (-1) or
+// neither (0), e.g. digits and punctuation
+func strongDirection(r rune) int {
+	switch {
+	case r < utf8.RuneSelf:
+		if r|0x20 >= 'a' && r|0x20 <= 'z' {
+			return -1
+		}
+		return 0
+	case r == bidiRLM || r == bidiALM:
+		return 1
+	case r == bidiLRM:
+		return -1
+	case !unicode.IsLetter(r):
+		return 0
+	case inRTLBlock(r):
+		return 1
+	default:
+		return -1
+	}
+}
+
+// withParagraphDirection keeps mostly right-to-left paragraphs displaying as
+// such: renderers take a paragraph's direction from its first letter, so an
+// Arabic or Hebrew caption line or summary bullet opening with a Latin name or
+// acronym would otherwise be laid out left to right. Those get a leading
+// right-to-left mark.
+func withParagraphDirection(text string) string {
+	// Right-to-left letters start at U+0590, whose UTF-8 lead byte is 0xD6,
+	// so most text needn't be decoded
+	i := 0
+	for i < len(text) && text[i] < 0xD6 {
+		i++
+	}
+	if i == len(text) {
+		return text
+	}
+
+	paragraphs := strings.Split(text, "\n")
+	for i, p := range paragraphs {
+		first, balance := 0, 0
+		for _, r := range p {
+			d := strongDirection(r)
+			if first == 0 {
+				first = d
+			}
+			balance += d
+		}
+		if first < 0 && balance > 0 {
+			paragraphs[i] = string(bidiRLM) + p
+		}
+	}
+	return strings.Join(paragraphs, "\n")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestToNFC(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain ASCII", "plain ASCII"},
+		{"e\u0301", "\u00E9"},
+		{"e\u0302\u0323", "\u1EC7"}, // marks out of canonical order
+		{"\u1EC7", "\u1EC7"},
+		{"\u00C5\u0301", "\u01FA"},
+		{"\u212B", "\u00C5"},             // singleton
+		{"\u095D", "\u0922\u093C"},       // excluded from composition
+		{"\u1100\u1161\u11AB", "\uAC04"}, // Hangul jamo
+		{"\uAC00\u11A8", "\uAC01"},       // Hangul syllable plus trailing consonant
+		{"\u0628\u0651\u064E", "\u0628\u064E\u0651"},
+		{"\u05E9\u05B8\u05C1", "\u05E9\u05B8\u05C1"},
+		{"a\u0328\u0301", "\u0105\u0301"},
+		{"A\u030A\u0327", "\u00C5\u0327"},
+		{"\u0915\u093F", "\u0915\u093F"},
+		{"\u0B47\u0B3E", "\u0B4B"}, // two starters
+		{"\u0301e", "\u0301e"},     // nothing for a leading mark to join
+		{"\U0001D15E", "\U0001D157\U0001D165"},
+	}
+	for _, tt := range tests {
+		if got := toNFC(tt.in); got != tt.want {
+			t.Errorf("toNFC(%+q) = %+q, want %+q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBalanceBidiControls(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"\u0645\u0631\u062D\u0628\u0627", "\u0645\u0631\u062D\u0628\u0627"},
+		{"\u202Babc", "\u202Babc\u202C"},
+		{"\u2067abc", "\u2067abc\u2069"},
+		{"abc\u202C", "abc"},
+		{"abc\u2069", "abc"},
+		{"\u2067a\u202Bb\u2069c", "\u2067a\u202Bb\u202C\u2069c"}, // PDI closes the embedding inside it
+		{"\u202B\u2066a\u202C", "\u202B\u2066a\u2069\u202C"},     // PDF can't close past an isolate
+	}
+	for _, tt := range tests {
+		if got := balanceBidiControls(tt.in); got != tt.want {
+			t.Errorf("balanceBidiControls(%+q) = %+q, want %+q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCleanCaptionText(t *testing.T) {
+	for _, invisible := range []string{"  ", "\u200F", "\u200B", " \u200E\u200F ", "\u202B\u202C"} {
+		if got := cleanCaptionText(invisible); got != "" {
+			t.Errorf("cleanCaptionText(%+q) = %+q, want empty", invisible, got)
+		}
+	}
+	if got := cleanCaptionText(" \u200Fe\u0301 "); got != "\u200F\u00E9" {
+		t.Errorf("directional mark was not kept: %+q", got)
+	}
+}
+
+func TestSegmentsTextJoinsSplitClusters(t *testing.T) {
+	// Speech recognition split "किताब" between its consonant and vowel sign
+	segments := []TranscriptSegment{{Text: "\u0906\u091C \u0939\u092E \u0915"}, {Text: "\u093F\u0924\u093E\u092C"}}
+	if got, want := segmentsText(segments), "\u0906\u091C \u0939\u092E \u0915\u093F\u0924\u093E\u092C"; got != want {
+		t.Errorf("segmentsText = %+q, want %+q", got, want)
+	}
+}
+
+func TestWithParagraphDirection(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hello world", "Hello world"},
+		{"\u05E9\u05DC\u05D5\u05DD world", "\u05E9\u05DC\u05D5\u05DD world"},
+		{"Go \u05E9\u05DC\u05D5\u05DD \u05DC\u05DB\u05D5\u05DC\u05DD", "\u200FGo \u05E9\u05DC\u05D5\u05DD \u05DC\u05DB\u05D5\u05DC\u05DD"},
+		{"2024 YouTube \u0645\u0646\u0635\u0629", "2024 YouTube \u0645\u0646\u0635\u0629"}, // mostly Latin
+		{"\u200FGo \u05E9\u05DC\u05D5\u05DD \u05DC\u05DB\u05D5\u05DC\u05DD", "\u200FGo \u05E9\u05DC\u05D5\u05DD \u05DC\u05DB\u05D5\u05DC\u05DD"},
+		// Each line of a summary is its own paragraph
+		{"\u05E9\u05DC\u05D5\u05DD\n- API \u05E9\u05DC \u05D2\u05D5\u05D2\u05DC\n- Go 1.24", "\u05E9\u05DC\u05D5\u05DD\n\u200F- API \u05E9\u05DC \u05D2\u05D5\u05D2\u05DC\n- Go 1.24"},
+	}
+	for _, tt := range tests {
+		if got := withParagraphDirection(tt.in); got != tt.want {
+			t.Errorf("withParagraphDirection(%+q) = %+q, want %+q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNonLatinFixtures(t *testing.T) {
+	read := func(name string) string {
+		data, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatalf("failed to read fixture: %v", err)
+		}
+		return string(data)
+	}
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{
+			// Decomposed and composed repeats collapse; jamo become syllables
+			"vietnamese_timedtext.xml",
+			parseTimedText(read("vietnamese_timedtext.xml"), false),
+			"Xin ch\u00E0o c\u00E1c b\u1EA1n H\u00F4m nay ch\u00FAng ta n\u00F3i v\u1EC1 Vi\u1EC7t Nam \uD55C\uAD6D\uC5B4\uC640 \uD55C\uAE00",
+		},
+		{
+			// Opens with a Latin name but reads right to left; the open
+			// embedding is closed before the next cue
+			"arabic_timedtext.xml",
+			parseTimedText(read("arabic_timedtext.xml"), false),
+			"\u200FYouTube \u0645\u0646\u0635\u0629 \u0641\u064A\u062F\u064A\u0648 \u0627\u0644\u062D\u0628\u064E\u0651 \u202B\u0645\u0631\u062D\u0628\u0627 2024\u202C HTML & CSS",
+		},
+		{
+			"hebrew.vtt",
+			cleanSRT(read("hebrew.vtt"), false),
+			"\u05E9\u05B8\u05C1\u05DC\u05D5\u05B9\u05DD \u05DC\u05DB\u05D5\u05DC\u05DD \u05D4\u05D9\u05D5\u05DD \u05E0\u05D3\u05D1\u05E8 \u05E2\u05DC \u2067Go 1.24\u2069",
+		},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s:\n got %+q\nwant %+q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Timed segments get the same cleanup
+	segments := parseTimedTextSegments(read("arabic_timedtext.xml"), false)
+	if len(segments) != 4 {
+		t.Fatalf("got %d Arabic segments, want 4 (duplicate and mark-only cues dropped): %+v", len(segments), segments)
+	}
+	if !strings.HasSuffix(segments[2].Text, "\u202C") {
+		t.Errorf("embedding left open: %+q", segments[2].Text)
+	}
+}
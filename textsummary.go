@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Text summarization accepts whole transcripts, so it gets a larger body limit
+const maxTextRequestBodySize = 5 * 1024 * 1024 // 5MB
+
+var (
+	textFile  string
+	textTitle string
+)
+
+type TextSummaryRequest struct {
+	Text     string `json:"text"`
+	Title    string `json:"title,omitempty"`
+	Language string `json:"language,omitempty"` // defaults to "en"
+	Template string `json:"template,omitempty"`
+
+	// SummaryLanguage and PromptLanguage work as on /summarize
+	SummaryLanguage string `json:"summary_language,omitempty"`
+	PromptLanguage  string `json:"prompt_language,omitempty"`
+
+	// KeepNonSpeech skips stripping [Music]-style annotations and filler words
+	KeepNonSpeech bool `json:"keep_non_speech,omitempty"`
+
+	// ContentFilter is "flag" or "mask" to add a content note to the summary
+	ContentFilter string `json:"content_filter,omitempty"`
+
+	// GenerationParams override the configured temperature, top_p and max_tokens
+	GenerationParams
+
+	// MaxWords, Lint and MaxCost work as on /summarize
+	MaxWords int     `json:"max_words,omitempty"`
+	Lint     bool    `json:"lint,omitempty"`
+	MaxCost  float64 `json:"max_cost,omitempty"`
+
+	// Priority works as on /summarize
+	Priority string `json:"priority,omitempty"`
+}
+
+type TextSummaryResponse struct {
+	Title      string `json:"title,omitempty"`
+	Summary    string `json:"summary"`
+	Language   string `json:"language"`
+	InputChars int    `json:"input_chars"`
+	DurationMS int64  `json:"duration_ms"`
+
+	// ContentNotes is set when a content filter was requested
+	ContentNotes *ContentNotes `json:"content_notes,omitempty"`
+}
+
+// normalizeTranscriptText accepts plain text, VTT/SRT or YouTube timedtext XML
+// and returns plain transcript text
+func normalizeTranscriptText(content string) string {
+	trimmed := strings.TrimSpace(strings.TrimPrefix(content, "\ufeff"))
+
+	switch {
+	case strings.Contains(trimmed, "<timedtext") || strings.Contains(trimmed, "<transcript"):
+		return parseTimedText(trimmed, false)
+	case strings.HasPrefix(trimmed, "WEBVTT") || strings.Contains(trimmed, " --> "):
+		return cleanSRT(trimmed, hasInlineWordTimings(trimmed))
+	default:
+		return withParagraphDirection(toNFC(strings.Join(strings.Fields(trimmed), " ")))
+	}
+}
+
+// runSummarizeText summarizes a transcript file, or standard input for "-",
+// without fetching from YouTube
+func runSummarizeText(cmd *cobra.Command, args []string) error {
+	path := textFile
+	if len(args) > 0 {
+		if path != "" {
+			return fmt.Errorf("give the transcript file either as an argument or with --file")
+		}
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("no transcript file given (use - to read standard input)")
+	}
+
+	filter := contentFilterMode()
+	if err := validContentFilter(filter); err != nil {
+		return err
+	}
+	budget, err := invocationBudget()
+	if err != nil {
+		return err
+	}
+
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	content, err := readInput(path)
+	if err != nil {
+		return fmt.Errorf("failed to read transcript file: %w", err)
+	}
+
+	decoded, encoding, err := decodeText(content, "")
+	if err != nil {
+		return fmt.Errorf("failed to read transcript file: %w", err)
+	}
+	if encoding != "" {
+		log("Converted transcript file from %s", encoding)
+	}
+	text := normalizeTranscriptText(decoded)
+	if text == "" {
+		return fmt.Errorf("transcript in %s is empty", inputName(path))
+	}
+	log("Read transcript (%d chars)", len(text))
+	text, notes := filterText(text, filter)
+	if !keepNonSpeech {
+		text = removeNonSpeech(text)
+	}
+
+	log("Sending to LLM for summarization...")
+	summary, err := summarize(text, SummaryOptions{
+		Template:    summaryTemplate,
+		Vars:        PromptVars{Title: textTitle, Language: summaryLanguage(language)},
+		Checkpoints: cache,
+		MaxWords:    maxWords,
+		Lint:        lintEnabled(),
+		Budget:      budget,
+	})
+	if err != nil {
+		cliMetrics.recordFailure(llmErrorClass(err))
+		return fmt.Errorf("failed to summarize: %w", err)
+	}
+	cliMetrics.recordProcessed()
+
+	log("Done!\n")
+	fmt.Println(withContentNote(summary, notes))
+	return nil
+}
+
+func (s *Server) handleSummarizeText(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req TextSummaryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+
+	text := normalizeTranscriptText(req.Text)
+	if text == "" {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "text is required")
+		return
+	}
+
+	if _, err := getTemplate(req.Template); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	if req.ContentFilter == "" {
+		req.ContentFilter = contentFilterMode()
+	}
+	if err := validContentFilter(req.ContentFilter); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+	if err := validLanguageCode("summary_language", req.SummaryLanguage); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+	if err := validLanguageCode("prompt_language", req.PromptLanguage); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+	if err := req.GenerationParams.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+	if req.MaxWords < 0 {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "max_words must be positive")
+		return
+	}
+	if req.MaxCost < 0 {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "max_cost must be positive")
+		return
+	}
+	if err := validPriority(req.Priority); err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, err.Error())
+		return
+	}
+	budget, err := requestBudget(req.MaxCost)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, err.Error())
+		return
+	}
+
+	lang := requestLanguage(r, req.Language)
+	text, notes := filterText(text, req.ContentFilter)
+	if !req.KeepNonSpeech {
+		text = removeNonSpeech(text)
+	}
+
+	logDebug("starting text summarization", slog.Int("text_len", len(text)))
+	release, err := s.waitForSlot(r, req.Priority)
+	if err != nil {
+		writeQueueError(w, err, "")
+		return
+	}
+	defer release()
+	summary, err := s.summarize(text, SummaryOptions{
+		Template:       req.Template,
+		Vars:           PromptVars{Title: req.Title, Language: requestSummaryLanguage(req.SummaryLanguage, lang)},
+		PromptLanguage: req.PromptLanguage,
+		Checkpoints:    s.cache,
+		Generation:     req.GenerationParams,
+		MaxWords:       req.MaxWords,
+		Lint:           req.Lint || lintEnabled(),
+		Budget:         budget,
+	})
+	if err != nil {
+		logError("summarization failed", slog.String("error", err.Error()))
+		writeLLMError(w, err, "", "")
+		return
+	}
+
+	s.markSuccess()
+
+	writeJSON(w, http.StatusOK, TextSummaryResponse{
+		Title:        req.Title,
+		Summary:      withContentNote(summary, notes),
+		Language:     lang,
+		InputChars:   len(text),
+		DurationMS:   time.Since(start).Milliseconds(),
+		ContentNotes: notes,
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTranscriptText(t *testing.T) {
+	vtt, _ := os.ReadFile("testdata/sample.vtt")
+	xml, _ := os.ReadFile("testdata/sample_timedtext.xml")
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"plain text", "  hello\n\nworld  ", "hello world"},
+		{"plain text with BOM", "\ufeffhello world", "hello world"},
+		{"vtt", string(vtt), "Never gonna give you up"},
+		{"timedtext", string(xml), "We're no strangers to love"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeTranscriptText(tt.content)
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("normalizeTranscriptText() = %q, want containing %q", got, tt.want)
+			}
+			if strings.Contains(got, "-->") || strings.Contains(got, "<p") {
+				t.Errorf("timing or markup left in output: %q", got)
+			}
+		})
+	}
+}
+
+func TestSummarizeTextEndpoint(t *testing.T) {
+	llmProvider = "fake"
+	defer func() { llmProvider = "" }()
+
+	body := `{"text": "First point. Second point. Third point. Fourth point.", "title": "Notes"}`
+	req := httptest.NewRequest("POST", "/summarize/text", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	newServer(ServerConfig{Cache: newTestCache(t)}).handleSummarizeText(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	var resp TextSummaryResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Summary != "First point. Second point. Third point." {
+		t.Errorf("Summary = %q", resp.Summary)
+	}
+	if resp.Language != "en" || resp.Title != "Notes" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestSummarizeTextEndpointEmpty(t *testing.T) {
+	req := httptest.NewRequest("POST", "/summarize/text", bytes.NewBufferString(`{"text": "   "}`))
+	w := httptest.NewRecorder()
+
+	newServer(ServerConfig{Cache: newTestCache(t)}).handleSummarizeText(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestSummarizeTextBodyLimit(t *testing.T) {
+	handler := newServer(ServerConfig{Cache: newTestCache(t)}).Handler()
+
+	// Larger than the default 1KB limit but well within the text limit
+	text := strings.Repeat("word ", 2000)
+	body, _ := json.Marshal(TextSummaryRequest{Text: text, Template: "nope"})
+
+	req := httptest.NewRequest("POST", "/summarize/text", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	// Rejected for the unknown template, not for the body size
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if !strings.Contains(resp.Message, "unknown template") {
+		t.Errorf("large text body was not accepted: %d %+v", w.Code, resp)
+	}
+}
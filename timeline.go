@@ -0,0 +1,191 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// auditTimelines is how many video timelines the server keeps for /admin/audit
+const auditTimelines = 200
+
+// Stages of processing a video, in the order they usually run. LLM chunks are
+// named stageLLMChunk plus the chunk number, e.g. "llm_chunk_2".
+const (
+	stageParse       = "parse"
+	stageCacheLookup = "cache_lookup"
+	stageLock        = "lock"  // waiting for another request fetching or summarizing the same video, see videoLocker
+	stageQueue       = "queue" // waiting for a work slot, see workQueue
+	stageFetch       = "fetch" // a whole fetch, when the fetcher didn't time its parts
+	stageInnertube   = "innertube"
+	stageCaptions    = "caption_download"
+	stageLLMChunk    = "llm_chunk_"
+	stageSummarize   = "summarize" // the only LLM call for a single-chunk transcript
+	stageCombine     = "combine"   // merging chunk summaries into the final summary
+)
+
+// timelineStage is one timed step of processing a video
+type timelineStage struct {
+	Name       string `json:"name"`
+	StartMS    int64  `json:"start_ms"` // since the request started
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// videoTimeline records the stages of one request for a video. Methods are
+// safe on a nil timeline, so code that runs outside the server needn't check.
+type videoTimeline struct {
+	mu      sync.Mutex
+	videoID string
+	path    string
+	start   time.Time
+	stages  []timelineStage
+}
+
+func newVideoTimeline(path string) *videoTimeline {
+	return &videoTimeline{path: path, start: time.Now()}
+}
+
+// setVideo names the video once the request has been parsed
+func (t *videoTimeline) setVideo(videoID string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.videoID = videoID
+}
+
+// begin starts timing a stage; call the returned function with the stage's
+// error (or nil) when it ends
+func (t *videoTimeline) begin(name string) func(error) {
+	if t == nil {
+		return func(error) {}
+	}
+	start := time.Now()
+	return func(err error) { t.add(name, start, time.Since(start), err) }
+}
+
+// add records a stage that was timed elsewhere
+func (t *videoTimeline) add(name string, start time.Time, d time.Duration, err error) {
+	if t == nil {
+		return
+	}
+	stage := timelineStage{Name: name, StartMS: start.Sub(t.start).Milliseconds(), DurationMS: d.Milliseconds()}
+	if err != nil {
+		stage.Error = err.Error()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stages = append(t.stages, stage)
+}
+
+// addAll records stages timed by the scraper, which measures from its own start
+func (t *videoTimeline) addAll(stages []fetchStage) {
+	for _, s := range stages {
+		t.add(s.Name, s.Start, s.Duration, nil)
+	}
+}
+
+// fetchStage is a stage of a transcript fetch, timed by the scraper
+type fetchStage struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// AuditTimeline is a finished request's timeline as returned by /admin/audit/{id}
+type AuditTimeline struct {
+	Path      string          `json:"path"`
+	StartedAt time.Time       `json:"started_at"`
+	TotalMS   int64           `json:"total_ms"`
+	Status    int             `json:"status"`
+	Stages    []timelineStage `json:"stages"`
+}
+
+// AuditResponse lists a video's recent timelines, newest first
+type AuditResponse struct {
+	VideoID   string          `json:"video_id"`
+	Timelines []AuditTimeline `json:"timelines"`
+}
+
+// auditLog keeps the most recent timelines in memory
+type auditLog struct {
+	mu      sync.Mutex
+	entries []auditEntry // newest last
+}
+
+type auditEntry struct {
+	videoID  string
+	timeline AuditTimeline
+}
+
+func newAuditLog() *auditLog {
+	return &auditLog{}
+}
+
+// record stores a finished timeline. Requests that never got as far as
+// naming a video aren't kept.
+func (a *auditLog) record(t *videoTimeline, status int) {
+	t.mu.Lock()
+	entry := auditEntry{videoID: t.videoID, timeline: AuditTimeline{
+		Path:      t.path,
+		StartedAt: t.start.UTC(),
+		TotalMS:   time.Since(t.start).Milliseconds(),
+		Status:    status,
+		Stages:    append([]timelineStage(nil), t.stages...),
+	}}
+	t.mu.Unlock()
+	if entry.videoID == "" {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, entry)
+	if len(a.entries) > auditTimelines {
+		a.entries = a.entries[len(a.entries)-auditTimelines:]
+	}
+}
+
+// timelines returns a video's timelines, newest first
+func (a *auditLog) timelines(videoID string) []AuditTimeline {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []AuditTimeline
+	for i := len(a.entries) - 1; i >= 0; i-- {
+		if a.entries[i].videoID == videoID {
+			out = append(out, a.entries[i].timeline)
+		}
+	}
+	return out
+}
+
+// withTimeline gives a video handler a timeline in its request context and
+// files it in the audit log when the handler returns
+func (s *Server) withTimeline(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t := newVideoTimeline(r.URL.Path)
+		reqCtx := getRequestContext(r)
+		reqCtx.Timeline = t
+		r = setRequestContext(r, reqCtx)
+
+		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		h(wrapped, r)
+		s.audit.record(t, wrapped.status)
+	}
+}
+
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	videoID, err := extractVideoID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid video ID")
+		return
+	}
+	timelines := s.audit.timelines(videoID)
+	if len(timelines) == 0 {
+		writeErrorWithVideo(w, http.StatusNotFound, "not_found", "No recent requests for this video", videoID)
+		return
+	}
+	writeJSON(w, http.StatusOK, AuditResponse{VideoID: videoID, Timelines: timelines})
+}
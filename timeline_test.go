@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func stageNames(stages []timelineStage) []string {
+	var names []string
+	for _, s := range stages {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestAuditTimeline(t *testing.T) {
+	s := newServer(ServerConfig{
+		APIKey: "secret",
+		Cache:  newTestCache(t),
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			now := time.Now()
+			return &FetchResult{Title: "Injected", Transcript: "One. Two.", Stages: []fetchStage{
+				{Name: stageInnertube, Start: now, Duration: 30 * time.Millisecond},
+				{Name: stageCaptions, Start: now.Add(30 * time.Millisecond), Duration: 10 * time.Millisecond},
+			}}, nil
+		},
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			return summarizeWith(&recordingLLMClient{}, transcript, opts)
+		},
+	})
+	handler := s.Handler()
+
+	for range 2 {
+		req := httptest.NewRequest("POST", "/summarize", bytes.NewBufferString(`{"url": "https://youtu.be/dQw4w9WgXcQ"}`))
+		req.Header.Set("X-API-Key", "secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("summarize status = %d: %s", w.Code, w.Body)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/admin/audit/dQw4w9WgXcQ", nil)
+	req.Header.Set("X-API-Key", "secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("audit status = %d: %s", w.Code, w.Body)
+	}
+	var resp AuditResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Timelines) != 2 {
+		t.Fatalf("got %d timelines, want 2", len(resp.Timelines))
+	}
+
+	// Newest first: the second request was served from the cache
+	cached, fetched := resp.Timelines[0], resp.Timelines[1]
+	if want := []string{stageParse, stageCacheLookup, stageQueue, stageSummarize}; !reflect.DeepEqual(stageNames(cached.Stages), want) {
+		t.Errorf("cached stages = %v, want %v", stageNames(cached.Stages), want)
+	}
+	if want := []string{stageParse, stageCacheLookup, stageLock, stageQueue, stageInnertube, stageCaptions, stageQueue, stageSummarize}; !reflect.DeepEqual(stageNames(fetched.Stages), want) {
+		t.Errorf("fetched stages = %v, want %v", stageNames(fetched.Stages), want)
+	}
+	if fetched.Path != "/summarize" || fetched.Status != http.StatusOK {
+		t.Errorf("timeline = %s %d, want /summarize 200", fetched.Path, fetched.Status)
+	}
+	if d := fetched.Stages[4].DurationMS; d != 30 {
+		t.Errorf("innertube duration = %dms, want 30ms", d)
+	}
+
+	req = httptest.NewRequest("GET", "/admin/audit/jNQXAC9IVRw", nil)
+	req.Header.Set("X-API-Key", "secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown video status = %d, want 404", w.Code)
+	}
+}
+
+func TestTimelineRecordsEachChunk(t *testing.T) {
+	timeline := newVideoTimeline("/summarize")
+	transcript := strings.Repeat("word ", maxChunkTokens*2)
+	if _, err := summarizeWith(&recordingLLMClient{}, transcript, SummaryOptions{Timeline: timeline}); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for i := range chunkTranscript(transcript, maxChunkTokens) {
+		want = append(want, fmt.Sprintf("llm_chunk_%d", i+1))
+	}
+	want = append(want, stageCombine)
+	if got := stageNames(timeline.stages); len(want) < 3 || !reflect.DeepEqual(got, want) {
+		t.Errorf("stages = %v, want %v", got, want)
+	}
+}
+
+func TestAuditLogKeepsRecentTimelines(t *testing.T) {
+	a := newAuditLog()
+	for range auditTimelines + 5 {
+		timeline := newVideoTimeline("/transcript")
+		timeline.setVideo("dQw4w9WgXcQ")
+		a.record(timeline, http.StatusOK)
+	}
+	a.record(newVideoTimeline("/transcript"), http.StatusBadRequest) // never named a video
+
+	if got := len(a.timelines("dQw4w9WgXcQ")); got != auditTimelines {
+		t.Errorf("kept %d timelines, want %d", got, auditTimelines)
+	}
+}
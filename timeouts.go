@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Timeouts given with --youtube-timeout and --llm-timeout
+var (
+	youtubeTimeout string
+	llmTimeout     string
+)
+
+const (
+	defaultYouTubeTimeout = 30 * time.Second // each YouTube request (player, captions, pages)
+	defaultLLMTimeout     = 60 * time.Second // each LLM completion, chunks included
+)
+
+// timeoutConfig reads a timeout from its flag or environment variable. Values
+// are Go durations ("90s", "2m") or plain seconds ("90"); unset means def.
+func timeoutConfig(flagVal, envKey string, def time.Duration) (time.Duration, error) {
+	value := strings.TrimSpace(getConfig(flagVal, envKey))
+	if value == "" {
+		return def, nil
+	}
+	if isDigits(value) {
+		value += "s"
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q (use e.g. 45s or 2m)", envKey, getConfig(flagVal, envKey))
+	}
+	return d, nil
+}
+
+// youtubeTimeoutConfig returns --youtube-timeout (YTSUMMARY_YOUTUBE_TIMEOUT)
+func youtubeTimeoutConfig() (time.Duration, error) {
+	return timeoutConfig(youtubeTimeout, "YTSUMMARY_YOUTUBE_TIMEOUT", defaultYouTubeTimeout)
+}
+
+// llmTimeoutConfig returns --llm-timeout (YTSUMMARY_LLM_TIMEOUT)
+func llmTimeoutConfig() (time.Duration, error) {
+	return timeoutConfig(llmTimeout, "YTSUMMARY_LLM_TIMEOUT", defaultLLMTimeout)
+}
+
+// youtubeClient returns an egress client for one YouTube request, with the
+// --youtube-timeout applied. configureTimeouts has already rejected a bad
+// value, so an error here falls back to the default.
+func youtubeClient() *http.Client {
+	timeout, err := youtubeTimeoutConfig()
+	if err != nil {
+		timeout = defaultYouTubeTimeout
+	}
+	return egressClient(timeout)
+}
+
+// configureTimeouts checks both timeouts before a command runs. serve fixes
+// the YouTube one when it starts; the LLM one is read per request, so a
+// reload picks it up.
+func configureTimeouts() error {
+	if _, err := youtubeTimeoutConfig(); err != nil {
+		return err
+	}
+	_, err := llmTimeoutConfig()
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutConfig(t *testing.T) {
+	tests := []struct {
+		flag, env string
+		want      time.Duration
+		wantErr   bool
+	}{
+		{"", "", defaultLLMTimeout, false},
+		{"90", "", 90 * time.Second, false},
+		{"2m", "", 2 * time.Minute, false},
+		{"", "1m30s", 90 * time.Second, false},
+		{"45s", "2m", 45 * time.Second, false},
+		{"0", "", 0, true},
+		{"soon", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("YTSUMMARY_LLM_TIMEOUT", tt.env)
+		got, err := timeoutConfig(tt.flag, "YTSUMMARY_LLM_TIMEOUT", defaultLLMTimeout)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("timeoutConfig(%q, env %q) = %v, %v", tt.flag, tt.env, got, err)
+		}
+	}
+}
+
+func TestConfigureTimeouts(t *testing.T) {
+	oldYouTube, oldClient := youtubeTimeout, httpClient.Timeout
+	t.Cleanup(func() { youtubeTimeout = oldYouTube })
+
+	youtubeTimeout = "5s"
+	if err := configureTimeouts(); err != nil {
+		t.Fatal(err)
+	}
+	if got := youtubeClient().Timeout; got != 5*time.Second {
+		t.Errorf("youtubeClient().Timeout = %v, want 5s", got)
+	}
+	if httpClient.Timeout != oldClient {
+		t.Errorf("httpClient.Timeout = %v, want it left at %v", httpClient.Timeout, oldClient)
+	}
+
+	t.Setenv("YTSUMMARY_LLM_TIMEOUT", "-1s")
+	if err := configureTimeouts(); err == nil || !strings.Contains(err.Error(), "YTSUMMARY_LLM_TIMEOUT") {
+		t.Errorf("configureTimeouts() = %v, want invalid LLM timeout", err)
+	}
+}
+
+func TestServerYouTubeTimeout(t *testing.T) {
+	a := newServer(ServerConfig{YouTubeTimeout: 5 * time.Second})
+	b := newServer(ServerConfig{})
+	if a.youtube.Timeout != 5*time.Second || b.youtube.Timeout != defaultYouTubeTimeout {
+		t.Errorf("timeouts = %v and %v, want 5s and the default", a.youtube.Timeout, b.youtube.Timeout)
+	}
+}
+
+func TestLLMTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Setenv("YTSUMMARY_PROVIDER", "openai")
+	t.Setenv("YTSUMMARY_API_KEY", "k")
+	t.Setenv("YTSUMMARY_API_URL", srv.URL)
+	t.Setenv("YTSUMMARY_LLM_TIMEOUT", "50ms")
+	oldProvider := llmProvider
+	llmProvider = ""
+	t.Cleanup(func() { llmProvider = oldProvider })
+
+	client, err := newLLMClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := client.Complete("system", "text", GenerationParams{}); err == nil {
+		t.Fatal("Complete() succeeded, want timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Complete() took %v, want the 50ms timeout", elapsed)
+	}
+}
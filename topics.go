@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Flags for topics
+var (
+	topicsSince    string
+	topicsClusters int
+	topicsPerTopic int
+	topicsJSON     bool
+)
+
+const (
+	defaultTopicsWindow = 30 * 24 * time.Hour
+
+	maxTopicClusters  = 12  // most topics --clusters picks by itself
+	maxKMeansRounds   = 50  // k-means converges well before this on real corpora
+	topicKeywords     = 4   // keywords naming each topic
+	topicsListPage    = 500 // transcripts read from the cache at a time
+	defaultPerTopic   = 3
+	minTopicsVideos   = 2
+	topicsRecurringAt = 2 // videos a topic needs to count as recurring
+)
+
+// topicVideo is one transcript in the corpus being clustered
+type topicVideo struct {
+	entry  *CacheEntry
+	vector []float32
+}
+
+// TopicsReport is the clustered archive: recurring topics, largest first
+type TopicsReport struct {
+	Since  time.Time `json:"since"`
+	Videos int       `json:"videos"`
+	Model  string    `json:"model"`
+	Topics []Topic   `json:"topics"`
+
+	// OneOff counts videos whose topic came up only once
+	OneOff int `json:"one_off"`
+}
+
+// Topic is a cluster of related videos
+type Topic struct {
+	Keywords []string `json:"keywords"`
+	Videos   int      `json:"videos"`
+	Channels []string `json:"channels,omitempty"`
+	First    string   `json:"first"` // date of the earliest video, YYYY-MM-DD
+	Last     string   `json:"last"`
+
+	// Representative are the videos closest to the topic's center
+	Representative []TopicVideo `json:"representative"`
+}
+
+// TopicVideo is a representative video of a topic
+type TopicVideo struct {
+	VideoID    string  `json:"video_id"`
+	Title      string  `json:"title"`
+	Channel    string  `json:"channel,omitempty"`
+	URL        string  `json:"url"`
+	Similarity float64 `json:"similarity"`
+}
+
+// loadTopicCorpus returns the transcripts cached since a time, one per video
+func loadTopicCorpus(cache *SQLiteCache, since time.Time) ([]*topicVideo, error) {
+	seen := make(map[string]bool)
+	var videos []*topicVideo
+	var after *exportCursor
+	for {
+		entries, err := cache.ListTranscripts(since, after, topicsListPage)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !seen[entry.VideoID] && strings.TrimSpace(entry.Transcript) != "" {
+				seen[entry.VideoID] = true
+				videos = append(videos, &topicVideo{entry: entry})
+			}
+		}
+		if len(entries) < topicsListPage {
+			return videos, nil
+		}
+		last := entries[len(entries)-1]
+		after = &exportCursor{FetchedAt: last.FetchedAt.UTC().Format(sqliteTimeFormat), VideoID: last.VideoID, Language: last.Language}
+	}
+}
+
+// embedTopicCorpus gives every video its embedding, from the cache where the
+// transcript was embedded before with this model, else from the embedder
+func embedTopicCorpus(embedder Embedder, store EmbeddingStore, videos []*topicVideo) error {
+	var missing []*topicVideo
+	var texts, hashes []string
+	for _, v := range videos {
+		text := embeddingText(v.entry)
+		hash := sha256Hex([]byte(text))[:16]
+		vector, err := store.GetEmbedding(v.entry.VideoID, v.entry.Language, embedder.Model(), hash)
+		if err == nil {
+			v.vector = normalizeVector(vector)
+			continue
+		}
+		if !errors.Is(err, errCacheMiss) {
+			return err
+		}
+		missing = append(missing, v)
+		texts = append(texts, text)
+		hashes = append(hashes, hash)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	log("Embedding %d of %d transcripts with %s...", len(missing), len(videos), embedder.Model())
+	vectors, err := embedder.Embed(texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed transcripts: %w", err)
+	}
+	for i, v := range missing {
+		v.vector = normalizeVector(vectors[i])
+		if err := store.StoreEmbedding(v.entry.VideoID, v.entry.Language, embedder.Model(), hashes[i], vectors[i]); err != nil && !errors.Is(err, errCacheReadOnly) {
+			log("warning: failed to cache embedding: %v", err)
+		}
+	}
+	return nil
+}
+
+// autoClusterCount picks how many topics to look for in n videos
+func autoClusterCount(n int) int {
+	k := int(math.Round(math.Sqrt(float64(n) / 2)))
+	return max(1, min(k, maxTopicClusters, n))
+}
+
+// clusterVectors groups unit vectors into k clusters by cosine similarity
+// (spherical k-means), returning each vector's cluster and the clusters'
+// centers. Seeding is deterministic: the most central vector, then
+// repeatedly the one least like any center so far, so a report doesn't
+// change between runs over the same corpus.
+func clusterVectors(vectors [][]float32, k int) (assign []int, centers [][]float32) {
+	n, dims := len(vectors), len(vectors[0])
+	k = min(k, n)
+
+	mean := make([]float32, dims)
+	for _, v := range vectors {
+		for d := range v {
+			mean[d] += v[d]
+		}
+	}
+	first := 0
+	for i := range vectors {
+		if dot(vectors[i], mean) > dot(vectors[first], mean) {
+			first = i
+		}
+	}
+	centers = [][]float32{slices.Clone(vectors[first])}
+	for len(centers) < k {
+		farthest, farthestSim := -1, math.Inf(1)
+		for i, v := range vectors {
+			best := math.Inf(-1)
+			for _, c := range centers {
+				best = max(best, dot(v, c))
+			}
+			if best < farthestSim {
+				farthest, farthestSim = i, best
+			}
+		}
+		centers = append(centers, slices.Clone(vectors[farthest]))
+	}
+
+	assign = make([]int, n)
+	for round := 0; round < maxKMeansRounds; round++ {
+		changed := round == 0
+		for i, v := range vectors {
+			best := 0
+			for c := range centers {
+				if dot(v, centers[c]) > dot(v, centers[best]) {
+					best = c
+				}
+			}
+			if assign[i] != best {
+				assign[i], changed = best, true
+			}
+		}
+		if !changed {
+			break
+		}
+		for c := range centers {
+			sum := make([]float32, dims)
+			members := 0
+			for i, v := range vectors {
+				if assign[i] == c {
+					members++
+					for d := range v {
+						sum[d] += v[d]
+					}
+				}
+			}
+			if members > 0 { // an empty cluster keeps its old center
+				centers[c] = normalizeVector(sum)
+			}
+		}
+	}
+	return assign, centers
+}
+
+// topicKeywordsFor names a cluster by the words most particular to it: common
+// in its videos, rare in the rest of the corpus
+func topicKeywordsFor(members []*topicVideo, docFreq map[string]int, corpusSize int) []string {
+	inCluster := make(map[string]int)
+	for _, v := range members {
+		seen := make(map[string]bool)
+		for _, w := range topicWords(embeddingText(v.entry)) {
+			if !seen[w] {
+				seen[w] = true
+				inCluster[w]++
+			}
+		}
+	}
+
+	type scored struct {
+		word  string
+		score float64
+	}
+	var words []scored
+	for w, n := range inCluster {
+		if n < 2 && len(members) > 1 {
+			continue // one video's word doesn't name a topic
+		}
+		idf := math.Log(float64(corpusSize+1) / float64(docFreq[w]))
+		words = append(words, scored{w, float64(n) / float64(len(members)) * idf})
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].score != words[j].score {
+			return words[i].score > words[j].score
+		}
+		return words[i].word < words[j].word
+	})
+
+	var keywords []string
+	for _, w := range words[:min(topicKeywords, len(words))] {
+		keywords = append(keywords, w.word)
+	}
+	return keywords
+}
+
+// buildTopicsReport clusters the embedded videos into a report of recurring
+// topics, each with its perTopic most representative videos
+func buildTopicsReport(videos []*topicVideo, k, perTopic int) TopicsReport {
+	vectors := make([][]float32, len(videos))
+	docFreq := make(map[string]int)
+	for i, v := range videos {
+		vectors[i] = v.vector
+		seen := make(map[string]bool)
+		for _, w := range topicWords(embeddingText(v.entry)) {
+			if !seen[w] {
+				seen[w] = true
+				docFreq[w]++
+			}
+		}
+	}
+	if k <= 0 {
+		k = autoClusterCount(len(videos))
+	}
+	assign, centers := clusterVectors(vectors, k)
+
+	report := TopicsReport{Videos: len(videos), Topics: []Topic{}}
+	for c, center := range centers {
+		var members []*topicVideo
+		for i, v := range videos {
+			if assign[i] == c {
+				members = append(members, v)
+			}
+		}
+		if len(members) < topicsRecurringAt {
+			report.OneOff += len(members)
+			continue
+		}
+
+		topic := Topic{Keywords: topicKeywordsFor(members, docFreq, len(videos)), Videos: len(members)}
+		first, last := members[0].entry.FetchedAt, members[0].entry.FetchedAt
+		for _, v := range members {
+			if v.entry.Channel != "" && !slices.Contains(topic.Channels, v.entry.Channel) {
+				topic.Channels = append(topic.Channels, v.entry.Channel)
+			}
+			first, last = minTime(first, v.entry.FetchedAt), maxTime(last, v.entry.FetchedAt)
+		}
+		sort.Strings(topic.Channels)
+		topic.First, topic.Last = first.Format(time.DateOnly), last.Format(time.DateOnly)
+
+		sort.SliceStable(members, func(i, j int) bool {
+			return dot(members[i].vector, center) > dot(members[j].vector, center)
+		})
+		for _, v := range members[:min(perTopic, len(members))] {
+			topic.Representative = append(topic.Representative, TopicVideo{
+				VideoID:    v.entry.VideoID,
+				Title:      v.entry.Title,
+				Channel:    v.entry.Channel,
+				URL:        canonicalVideoURL(v.entry.VideoID),
+				Similarity: math.Round(dot(v.vector, center)*1000) / 1000,
+			})
+		}
+		report.Topics = append(report.Topics, topic)
+	}
+	sort.SliceStable(report.Topics, func(i, j int) bool { return report.Topics[i].Videos > report.Topics[j].Videos })
+	return report
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// formatTopicsReport renders the report as Markdown
+func formatTopicsReport(r TopicsReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Topics since %s\n\n", r.Since.Format("Jan 2, 2006"))
+	fmt.Fprintf(&b, "%d videos, %d recurring topics", r.Videos, len(r.Topics))
+	if r.OneOff > 0 {
+		fmt.Fprintf(&b, ", %d one-off videos", r.OneOff)
+	}
+	b.WriteString("\n")
+	for i, t := range r.Topics {
+		fmt.Fprintf(&b, "\n## %d. %s\n\n", i+1, strings.Join(t.Keywords, ", "))
+		fmt.Fprintf(&b, "%d videos", t.Videos)
+		if len(t.Channels) > 0 {
+			fmt.Fprintf(&b, " from %d channels", len(t.Channels))
+		}
+		fmt.Fprintf(&b, ", %s to %s\n\n", t.First, t.Last)
+		for _, v := range t.Representative {
+			title := v.Title
+			if title == "" {
+				title = v.VideoID
+			}
+			fmt.Fprintf(&b, "- [%s](%s)", title, v.URL)
+			if v.Channel != "" {
+				fmt.Fprintf(&b, " — %s", v.Channel)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// runTopics clusters the cached transcripts of a window into recurring topics
+func runTopics(cmd *cobra.Command, args []string) error {
+	window, err := parseWindow(topicsSince, defaultTopicsWindow)
+	if err != nil {
+		return err
+	}
+	if topicsClusters < 0 || topicsPerTopic < 1 {
+		return fmt.Errorf("--clusters can't be negative and --per-topic must be at least 1")
+	}
+	embedder, err := newEmbedder()
+	if err != nil {
+		return err
+	}
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	since := time.Now().Add(-window)
+	videos, err := loadTopicCorpus(cache, since)
+	if err != nil {
+		return err
+	}
+	if len(videos) < minTopicsVideos {
+		return fmt.Errorf("only %d transcripts were cached since %s; topics need at least %d", len(videos), since.Format(time.DateOnly), minTopicsVideos)
+	}
+	if err := embedTopicCorpus(embedder, cache, videos); err != nil {
+		return err
+	}
+
+	report := buildTopicsReport(videos, topicsClusters, topicsPerTopic)
+	report.Since, report.Model = since.UTC(), embedder.Model()
+	if topicsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	fmt.Print(formatTopicsReport(report))
+	return nil
+}
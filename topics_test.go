@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAutoClusterCount(t *testing.T) {
+	tests := map[int]int{1: 1, 2: 1, 8: 2, 50: 5, 1000: maxTopicClusters}
+	for n, want := range tests {
+		if got := autoClusterCount(n); got != want {
+			t.Errorf("autoClusterCount(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func TestClusterVectors(t *testing.T) {
+	vectors := [][]float32{{1, 0, 0}, {0, 1, 0}, {0.9, 0.1, 0}, {0.1, 0.9, 0.1}, {0.95, 0, 0.05}}
+	for _, v := range vectors {
+		normalizeVector(v)
+	}
+	assign, centers := clusterVectors(vectors, 2)
+	if len(centers) != 2 {
+		t.Fatalf("%d centers", len(centers))
+	}
+	if assign[0] != assign[2] || assign[0] != assign[4] || assign[1] != assign[3] || assign[0] == assign[1] {
+		t.Errorf("assign = %v", assign)
+	}
+
+	// More clusters than vectors is capped
+	if _, centers := clusterVectors(vectors[:2], 5); len(centers) != 2 {
+		t.Errorf("%d centers for 2 vectors", len(centers))
+	}
+}
+
+func TestTopicsReport(t *testing.T) {
+	cache := newTestCache(t)
+	store := func(videoID, title, channel, transcript string, age time.Duration) {
+		if err := cache.StoreTranscript(&CacheEntry{VideoID: videoID, Language: "en", Title: title, Channel: channel, Transcript: transcript}); err != nil {
+			t.Fatal(err)
+		}
+		db, _ := cache.conn()
+		db.Exec("UPDATE transcripts SET fetched_at = ? WHERE video_id = ?", time.Now().Add(-age).UTC().Format(sqliteTimeFormat), videoID)
+	}
+	store("bakeVideo01", "Sourdough starter", "Bakes", "Feed the sourdough starter flour and water daily.", 3*24*time.Hour)
+	store("bakeVideo02", "Rye sourdough", "Bakes", "Rye flour makes the sourdough starter ferment faster.", 2*24*time.Hour)
+	store("bakeVideo03", "Starter troubleshooting", "Crumb", "A sluggish sourdough starter wants warmer water and fresh flour.", 24*time.Hour)
+	store("gpuVideo001", "Benchmarking graphics cards", "Chips", "Graphics cards benchmark frames rendering games.", 5*24*time.Hour)
+	store("gpuVideo002", "Budget graphics cards", "Chips", "Cheap graphics cards still render games at high frames.", 4*24*time.Hour)
+	store("jNQXAC9IVRw", "Me at the zoo", "jawed", "Elephants have really long trunks.", 40*24*time.Hour)
+
+	videos, err := loadTopicCorpus(cache, time.Now().Add(-defaultTopicsWindow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(videos) != 5 {
+		t.Fatalf("%d videos in the window, want 5", len(videos))
+	}
+	if err := embedTopicCorpus(fakeEmbedder{}, cache, videos); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.GetEmbedding("bakeVideo01", "en", "fake", sha256Hex([]byte(embeddingText(videos[2].entry)))[:16]); err != nil {
+		t.Errorf("embedding not cached: %v", err)
+	}
+
+	report := buildTopicsReport(videos, 2, 2)
+	if len(report.Topics) != 2 || report.Videos != 5 || report.OneOff != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	baking, gpus := report.Topics[0], report.Topics[1]
+	if baking.Videos != 3 || strings.Join(baking.Channels, ",") != "Bakes,Crumb" || len(baking.Representative) != 2 {
+		t.Errorf("baking topic = %+v", baking)
+	}
+	if !strings.Contains(strings.Join(baking.Keywords, " "), "sourdough") || !strings.Contains(strings.Join(gpus.Keywords, " "), "graphics") {
+		t.Errorf("keywords = %q and %q", baking.Keywords, gpus.Keywords)
+	}
+	for _, v := range gpus.Representative {
+		if !strings.HasPrefix(v.VideoID, "gpuVideo") || v.URL != canonicalVideoURL(v.VideoID) {
+			t.Errorf("GPU representative = %+v", v)
+		}
+	}
+
+	md := formatTopicsReport(report)
+	if !strings.Contains(md, "5 videos, 2 recurring topics\n") || !strings.Contains(md, "3 videos from 2 channels") {
+		t.Errorf("report = %s", md)
+	}
+}
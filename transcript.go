@@ -2,100 +2,334 @@ package main
 
 import (
 	"fmt"
+	neturl "net/url"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+var videoIDRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{11}$`)
+
+// Path prefixes on youtube.com / youtube-nocookie.com followed by the video ID
+var videoIDPathPrefixes = []string{"/embed/", "/v/", "/e/", "/shorts/", "/live/"}
+
+// Redirect wrappers can nest (e.g. a Google redirect to an attribution link)
+const maxURLUnwrapDepth = 3
+
 // extractVideoID pulls the video ID from various YouTube URL formats
 // Supported formats:
-//   - youtube.com/watch?v=VIDEO_ID
+//   - youtube.com/watch?v=VIDEO_ID (www., m. and music. hosts)
 //   - youtu.be/VIDEO_ID
 //   - youtube.com/embed/VIDEO_ID
+//   - youtube-nocookie.com/embed/VIDEO_ID
 //   - youtube.com/v/VIDEO_ID
 //   - youtube.com/shorts/VIDEO_ID
 //   - youtube.com/live/VIDEO_ID
-//   - m.youtube.com/watch?v=VIDEO_ID
-//   - With extra params: ?v=VIDEO_ID&t=123
+//   - youtube.com/attribution_link?u=/watch%3Fv%3DVIDEO_ID
+//   - google.com/url?q=<any of the above>
+//   - With extra params: ?v=VIDEO_ID&t=123, ?si=SHARE_ID&v=VIDEO_ID
 func extractVideoID(url string) (string, error) {
-	patterns := []string{
-		// Standard watch URL (including mobile)
-		`(?:m\.)?youtube\.com/watch\?v=([a-zA-Z0-9_-]{11})`,
-		// Short URL
-		`youtu\.be/([a-zA-Z0-9_-]{11})`,
-		// Embed and legacy URLs
-		`youtube\.com/(?:embed|v)/([a-zA-Z0-9_-]{11})`,
-		// Shorts
-		`youtube\.com/shorts/([a-zA-Z0-9_-]{11})`,
-		// Live streams
-		`youtube\.com/live/([a-zA-Z0-9_-]{11})`,
+	url = strings.TrimSpace(url)
+
+	// Check if it's already just a video ID
+	if videoIDRe.MatchString(url) {
+		return url, nil
 	}
 
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
-		matches := re.FindStringSubmatch(url)
-		if len(matches) > 1 {
-			return matches[1], nil
-		}
+	u, err := parseUnwrappedURL(url)
+	if err != nil {
+		return "", fmt.Errorf("could not extract video ID from: %s", url)
 	}
 
-	// Check if it's already just a video ID
-	if matched, _ := regexp.MatchString(`^[a-zA-Z0-9_-]{11}$`, url); matched {
-		return url, nil
+	if id := videoIDFromURL(u); id != "" {
+		return id, nil
 	}
 
 	return "", fmt.Errorf("could not extract video ID from: %s", url)
 }
 
-// fetchTranscript fetches transcript using direct HTTP scraping
-func fetchTranscript(url string) (string, error) {
-	result, err := fetchTranscriptDirect(url, "en")
+// parseUnwrappedURL parses a URL, following attribution and redirect wrappers
+func parseUnwrappedURL(raw string) (*neturl.URL, error) {
+	u, err := parseLooseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for depth := 0; depth < maxURLUnwrapDepth; depth++ {
+		target, ok := unwrapRedirect(u)
+		if !ok {
+			break
+		}
+		if u, err = parseLooseURL(target); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// parseLooseURL parses a URL, tolerating a missing scheme ("youtu.be/ID")
+func parseLooseURL(raw string) (*neturl.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := neturl.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+	return u, nil
+}
+
+// youtubeHost normalizes a hostname, returning "" for non-YouTube hosts
+func youtubeHost(host string) string {
+	host = strings.ToLower(host)
+	for _, prefix := range []string{"www.", "m.", "music."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	switch host {
+	case "youtube.com", "youtube-nocookie.com", "youtu.be":
+		return host
+	}
+	return ""
+}
+
+// unwrapRedirect returns the target of attribution links and Google redirect wrappers
+func unwrapRedirect(u *neturl.URL) (string, bool) {
+	host := strings.ToLower(strings.TrimPrefix(u.Hostname(), "www."))
+	q := u.Query()
+
+	switch {
+	case youtubeHost(host) == "youtube.com" && u.Path == "/attribution_link":
+		target := q.Get("u")
+		if strings.HasPrefix(target, "/") {
+			target = "https://www.youtube.com" + target
+		}
+		return target, target != ""
+	case (strings.HasPrefix(host, "google.") || strings.Contains(host, ".google.")) && u.Path == "/url":
+		target := q.Get("q")
+		if target == "" {
+			target = q.Get("url")
+		}
+		return target, target != ""
+	}
+
+	return "", false
+}
+
+// videoIDFromURL extracts the ID from a (non-wrapper) YouTube URL
+func videoIDFromURL(u *neturl.URL) string {
+	var id string
+
+	switch youtubeHost(u.Hostname()) {
+	case "youtu.be":
+		id = strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)[0]
+	case "youtube.com", "youtube-nocookie.com":
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+			break
+		}
+		for _, prefix := range videoIDPathPrefixes {
+			if strings.HasPrefix(u.Path, prefix) {
+				id = strings.SplitN(strings.TrimPrefix(u.Path, prefix), "/", 2)[0]
+				break
+			}
+		}
+	}
+
+	if !videoIDRe.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+// canonicalVideoURL returns the canonical watch URL for a video ID. All URL
+// aliases of a video (youtu.be, shorts, share links...) map to this one form.
+func canonicalVideoURL(videoID string) string {
+	return "https://www.youtube.com/watch?v=" + videoID
+}
+
+// linkedTimestamp returns the start time a URL links to via t= (or start=),
+// e.g. ?t=1234, ?t=1234s, ?t=1h2m3s or #t=20m
+func linkedTimestamp(raw string) (float64, bool) {
+	u, err := parseUnwrappedURL(strings.TrimSpace(raw))
+	if err != nil || youtubeHost(u.Hostname()) == "" {
+		return 0, false
+	}
+
+	q := u.Query()
+	value := q.Get("t")
+	if value == "" {
+		value = q.Get("start")
+	}
+	if value == "" {
+		if fragment, err := neturl.ParseQuery(u.Fragment); err == nil {
+			value = fragment.Get("t")
+		}
+	}
+	if value == "" {
+		return 0, false
+	}
+
+	// Bare numbers are seconds
+	if isDigits(value) {
+		value += "s"
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d.Seconds(), true
+}
+
+// canonicalizeVideoURL returns the video ID and canonical URL for any supported URL form
+func canonicalizeVideoURL(raw string) (videoID, canonical string, err error) {
+	videoID, err = extractVideoID(raw)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	return result.Transcript, nil
+	return videoID, canonicalVideoURL(videoID), nil
 }
 
-// cleanSubtitles removes timestamps and formatting from VTT/SRT content
-func cleanSRT(content string) string {
-	lines := strings.Split(content, "\n")
-	var textLines []string
-	var lastLine string
+// fetchTranscript fetches transcript and video metadata using direct HTTP scraping
+func fetchTranscript(url, lang string, allowTranslate bool) (*FetchResult, error) {
+	return fetchTranscriptDirect(url, lang, allowTranslate)
+}
 
-	// VTT format:
-	// WEBVTT
-	//
-	// 00:00:00.000 --> 00:00:02.000
-	// Text here
-	//
-	// SRT format is similar but with comma instead of dot
+// warnLanguageMismatch tells CLI users when the captions aren't natively in the
+// requested language
+func warnLanguageMismatch(lang string, result *FetchResult) {
+	if result.TranslatedFrom != "" {
+		fmt.Fprintf(os.Stderr, "warning: no '%s' captions; using YouTube's machine translation from '%s'\n", lang, result.TranslatedFrom)
+		return
+	}
+	if result.Language != "" && strings.Split(result.Language, "-")[0] != strings.Split(lang, "-")[0] {
+		fmt.Fprintf(os.Stderr, "warning: no '%s' captions; using '%s' instead (pass --allow-auto-translate to use YouTube's translation)\n", lang, result.Language)
+	}
+}
 
-	timestampRe := regexp.MustCompile(`^\d{2}:\d{2}:\d{2}`)
-	numberRe := regexp.MustCompile(`^\d+$`)
-	tagRe := regexp.MustCompile(`<[^>]+>`)
-	headerRe := regexp.MustCompile(`^(WEBVTT|Kind:|Language:)`)
+// autoTranslateAllowed reports whether machine-translated captions may be used
+// (--allow-auto-translate / YTSUMMARY_ALLOW_AUTO_TRANSLATE)
+func autoTranslateAllowed() bool {
+	if allowAutoTranslate {
+		return true
+	}
+	allowed, _ := strconv.ParseBool(os.Getenv("YTSUMMARY_ALLOW_AUTO_TRANSLATE"))
+	return allowed
+}
+
+// cleanSRT removes timestamps and formatting from VTT/SRT content
+//
+// VTT format:
+// WEBVTT
+//
+// 00:00:00.000 --> 00:00:02.000
+// Text here
+//
+// SRT format is similar but with comma instead of dot. Words rolled over from
+// one cue to the next are only trimmed from autoGenerated captions; otherwise
+// exact repeats alone are dropped.
+func cleanSRT(content string, autoGenerated bool) string {
+	var out strings.Builder
+	out.Grow(len(content) / 2)
+	merger := cueMerger{rolling: autoGenerated}
 
-	for _, line := range lines {
+	for len(content) > 0 {
+		var line string
+		if i := strings.IndexByte(content, '\n'); i >= 0 {
+			line, content = content[:i], content[i+1:]
+		} else {
+			line, content = content, ""
+		}
 		line = strings.TrimSpace(line)
 
 		// Skip empty lines, numbers, timestamps, and VTT headers
-		if line == "" || numberRe.MatchString(line) || timestampRe.MatchString(line) || headerRe.MatchString(line) {
+		if line == "" || isDigits(line) || hasTimestampPrefix(line) || isVTTHeader(line) {
 			continue
 		}
 
 		// Remove HTML-like tags (common in auto-generated subs)
-		line = tagRe.ReplaceAllString(line, "")
-		line = strings.TrimSpace(line)
-
-		if line == "" {
+		if strings.IndexByte(line, '<') >= 0 {
+			line = stripTags(line)
+		}
+		if line = cleanCaptionText(line); line == "" {
 			continue
 		}
 
-		// Avoid duplicates (auto-subs often repeat lines)
-		if line != lastLine {
-			textLines = append(textLines, line)
-			lastLine = line
+		// Auto-subs repeat lines, and roll words over from one cue to the next
+		if line = merger.merge(line); line != "" {
+			appendCue(&out, line)
+		}
+	}
+
+	return withParagraphDirection(out.String())
+}
+
+// hasInlineWordTimings reports whether VTT captions time each word inline
+// (<00:00:01.230><c> word</c>), as YouTube's speech recognition captions do
+func hasInlineWordTimings(content string) bool {
+	return strings.Contains(content, "<c>")
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits (SRT cue numbers)
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTimestampPrefix reports whether s starts with an HH:MM:SS timestamp
+func hasTimestampPrefix(s string) bool {
+	if len(s) < 8 {
+		return false
+	}
+	for i := 0; i < 8; i++ {
+		if i == 2 || i == 5 {
+			if s[i] != ':' {
+				return false
+			}
+		} else if s[i] < '0' || s[i] > '9' {
+			return false
 		}
 	}
+	return true
+}
+
+func isVTTHeader(s string) bool {
+	return strings.HasPrefix(s, "WEBVTT") || strings.HasPrefix(s, "Kind:") || strings.HasPrefix(s, "Language:")
+}
 
-	return strings.Join(textLines, " ")
+// stripTags removes <...> tags such as <c> and inline <00:00:01.000> timings
+func stripTags(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for {
+		open := strings.IndexByte(s, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(s[open+1:], '>')
+		if end < 0 {
+			break
+		}
+		if end == 0 {
+			// "<>" is not a tag
+			b.WriteString(s[:open+2])
+			s = s[open+2:]
+			continue
+		}
+		b.WriteString(s[:open])
+		s = s[open+1+end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
 }
@@ -29,11 +29,23 @@ func TestExtractVideoID(t *testing.T) {
 		// Raw video ID
 		{"raw video id", "dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
 
+		// Privacy-enhanced embeds and wrappers
+		{"nocookie embed", "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ?start=30", "dQw4w9WgXcQ", false},
+		{"music", "https://music.youtube.com/watch?v=dQw4w9WgXcQ&feature=share", "dQw4w9WgXcQ", false},
+		{"no scheme", "youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
+		{"attribution link", "https://www.youtube.com/attribution_link?a=abc&u=%2Fwatch%3Fv%3DdQw4w9WgXcQ%26feature%3Dshare", "dQw4w9WgXcQ", false},
+		{"google redirect", "https://www.google.com/url?sa=t&url=https%3A%2F%2Fwww.youtube.com%2Fwatch%3Fv%3DdQw4w9WgXcQ&usg=xyz", "dQw4w9WgXcQ", false},
+		{"google redirect q param", "https://www.google.co.uk/url?q=https://youtu.be/dQw4w9WgXcQ&sa=D", "dQw4w9WgXcQ", false},
+		{"google redirect to attribution link", "https://www.google.com/url?q=https%3A%2F%2Fwww.youtube.com%2Fattribution_link%3Fu%3D%252Fwatch%253Fv%253DdQw4w9WgXcQ", "dQw4w9WgXcQ", false},
+
 		// Invalid inputs
 		{"empty string", "", "", true},
 		{"random url", "https://example.com/video", "", true},
 		{"too short id", "abc123", "", true},
 		{"too long id", "dQw4w9WgXcQextra", "", true},
+		{"lookalike host", "https://notyoutube.com/watch?v=dQw4w9WgXcQ", "", true},
+		{"google redirect elsewhere", "https://www.google.com/url?q=https://example.com/", "", true},
+		{"watch without id", "https://www.youtube.com/watch?list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", "", true},
 	}
 
 	for _, tt := range tests {
@@ -49,3 +61,48 @@ func TestExtractVideoID(t *testing.T) {
 		})
 	}
 }
+
+func TestStripTags(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain text", "plain text"},
+		{"we're<00:00:01.000><c> going</c>", "we're going"},
+		{"a <> b", "a <> b"},
+		{"unclosed <tag", "unclosed <tag"},
+		{"<i>italic</i> text", "italic text"},
+	}
+
+	for _, tt := range tests {
+		if got := stripTags(tt.in); got != tt.want {
+			t.Errorf("stripTags(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLinkedTimestamp(t *testing.T) {
+	tests := []struct {
+		url    string
+		want   float64
+		wantOK bool
+	}{
+		{"https://youtu.be/dQw4w9WgXcQ?t=1234", 1234, true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=90s", 90, true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=1h2m3s", 3723, true},
+		{"https://www.youtube.com/embed/dQw4w9WgXcQ?start=42", 42, true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ#t=2m", 120, true},
+		{"https://www.google.com/url?q=https%3A%2F%2Fyoutu.be%2FdQw4w9WgXcQ%3Ft%3D30", 30, true},
+		{"https://youtu.be/dQw4w9WgXcQ", 0, false},
+		{"https://youtu.be/dQw4w9WgXcQ?t=0", 0, false},
+		{"https://youtu.be/dQw4w9WgXcQ?t=soon", 0, false},
+		{"dQw4w9WgXcQ", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := linkedTimestamp(tt.url)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("linkedTimestamp(%q) = %v, %v; want %v, %v", tt.url, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
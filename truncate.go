@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Transcript truncation strategies for --truncate-strategy
+const (
+	truncateHead     = "head"      // the start of the video
+	truncateHeadTail = "head+tail" // its start and end, where intros and conclusions are
+	truncateSampled  = "sampled"   // evenly spaced excerpts across the whole video
+)
+
+const (
+	defaultTruncateStrategy = truncateHeadTail
+	minTranscriptChars      = 1000 // less leaves too little to summarize
+	sampleExcerptChars      = 2000 // length of each excerpt the sampled strategy keeps
+	truncationGap           = "\n\n[...]\n\n"
+)
+
+// Flags for --max-transcript-chars and --truncate-strategy
+var (
+	maxTranscriptChars string
+	truncateStrategy   string
+)
+
+// Truncation describes how much of a transcript was summarized when it was
+// longer than the limit
+type Truncation struct {
+	Strategy      string `json:"strategy"`
+	OriginalChars int    `json:"original_chars"`
+	KeptChars     int    `json:"kept_chars"`
+	Excerpts      int    `json:"excerpts"`
+}
+
+// String describes the truncation for a note under the summary
+func (t *Truncation) String() string {
+	switch t.Strategy {
+	case truncateHead:
+		return fmt.Sprintf("only the first %d of %d transcript characters were summarized", t.KeptChars, t.OriginalChars)
+	case truncateHeadTail:
+		return fmt.Sprintf("only the beginning and end of the transcript were summarized (%d of %d characters)", t.KeptChars, t.OriginalChars)
+	default:
+		return fmt.Sprintf("only %d evenly spaced excerpts of the transcript were summarized (%d of %d characters)", t.Excerpts, t.KeptChars, t.OriginalChars)
+	}
+}
+
+// validTruncateStrategy reports whether strategy names a truncation strategy
+func validTruncateStrategy(strategy string) error {
+	switch strategy {
+	case truncateHead, truncateHeadTail, truncateSampled:
+		return nil
+	}
+	return fmt.Errorf("invalid truncate strategy %q (use head, head+tail or sampled)", strategy)
+}
+
+// transcriptLimit checks a transcript length limit and strategy, filling in
+// YTSUMMARY_MAX_TRANSCRIPT_CHARS and YTSUMMARY_TRUNCATE_STRATEGY (or the CLI
+// flags) where they are unset. A limit of 0 means transcripts are never cut.
+func transcriptLimit(maxChars int, strategy string) (int, string, error) {
+	if maxChars == 0 {
+		if v := getConfig(maxTranscriptChars, "YTSUMMARY_MAX_TRANSCRIPT_CHARS"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return 0, "", fmt.Errorf("invalid max transcript chars %q", v)
+			}
+			maxChars = n
+		}
+	}
+	if maxChars != 0 && maxChars < minTranscriptChars {
+		return 0, "", fmt.Errorf("max transcript chars must be at least %d", minTranscriptChars)
+	}
+	if strategy == "" {
+		strategy = getConfig(truncateStrategy, "YTSUMMARY_TRUNCATE_STRATEGY")
+	}
+	if strategy == "" {
+		strategy = defaultTruncateStrategy
+	}
+	return maxChars, strategy, validTruncateStrategy(strategy)
+}
+
+// truncateTranscript cuts a transcript longer than maxChars characters down to
+// the parts strategy keeps, joined with a "[...]" gap marker, for a cheap
+// summary of a very long video instead of summarizing it chunk by chunk. Cuts
+// fall between words. The Truncation is nil when nothing was cut.
+func truncateTranscript(transcript string, maxChars int, strategy string) (string, *Truncation) {
+	text := []rune(transcript)
+	if maxChars <= 0 || len(text) <= maxChars {
+		return transcript, nil
+	}
+
+	var excerpts []string
+	switch strategy {
+	case truncateHead:
+		excerpts = []string{excerpt(text, 0, maxChars)}
+	case truncateHeadTail:
+		half := maxChars / 2
+		excerpts = []string{excerpt(text, 0, half), excerpt(text, len(text)-half, len(text))}
+	default:
+		n := max(maxChars/sampleExcerptChars, 2)
+		size := maxChars / n
+		for i := 0; i < n; i++ {
+			start := i * (len(text) - size) / (n - 1)
+			excerpts = append(excerpts, excerpt(text, start, start+size))
+		}
+	}
+
+	kept := 0
+	for _, e := range excerpts {
+		kept += len([]rune(e))
+	}
+	return strings.Join(excerpts, truncationGap), &Truncation{
+		Strategy:      strategy,
+		OriginalChars: len(text),
+		KeptChars:     kept,
+		Excerpts:      len(excerpts),
+	}
+}
+
+// excerpt returns text[start:end] without the words the range cuts through
+func excerpt(text []rune, start, end int) string {
+	if start > 0 && !unicode.IsSpace(text[start-1]) {
+		for start < end && !unicode.IsSpace(text[start]) {
+			start++
+		}
+	}
+	if end < len(text) && !unicode.IsSpace(text[end]) {
+		for end > start && !unicode.IsSpace(text[end-1]) {
+			end--
+		}
+	}
+	return strings.TrimSpace(string(text[start:end]))
+}
+
+// withTruncationNote adds a note under the summary saying the transcript was
+// cut, so a partial summary isn't mistaken for a full one
+func withTruncationNote(summary string, t *Truncation) string {
+	if t == nil {
+		return summary
+	}
+	return summary + "\n\n**Truncated transcript:** " + t.String() + "."
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// numberedWords returns "w0 w1 w2 ..." with n words
+func numberedWords(n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+	return strings.Join(words, " ")
+}
+
+func TestTruncateTranscript(t *testing.T) {
+	transcript := numberedWords(5000) // 28,889 characters
+
+	if got, tr := truncateTranscript(transcript, 0, truncateHead); got != transcript || tr != nil {
+		t.Errorf("no limit: truncated to %d chars", len(got))
+	}
+	if got, tr := truncateTranscript(transcript, 50000, truncateHead); got != transcript || tr != nil {
+		t.Errorf("short transcript: truncated to %d chars", len(got))
+	}
+
+	got, tr := truncateTranscript(transcript, 1000, truncateHead)
+	if !strings.HasPrefix(got, "w0 w1 ") || strings.Contains(got, "[...]") || len(got) > 1000 {
+		t.Errorf("head = %q", got)
+	}
+	if tr.KeptChars != len(got) || tr.OriginalChars != len(transcript) || tr.Excerpts != 1 {
+		t.Errorf("head truncation = %+v", tr)
+	}
+
+	got, tr = truncateTranscript(transcript, 1000, truncateHeadTail)
+	head, tail, ok := strings.Cut(got, truncationGap)
+	if !ok || !strings.HasPrefix(head, "w0 ") || !strings.HasSuffix(tail, " w4999") || tr.Excerpts != 2 {
+		t.Errorf("head+tail = %q", got)
+	}
+
+	got, tr = truncateTranscript(transcript, 10000, truncateSampled)
+	excerpts := strings.Split(got, truncationGap)
+	if len(excerpts) != 5 || tr.Excerpts != 5 {
+		t.Fatalf("sampled: %d excerpts, want 5", len(excerpts))
+	}
+	if !strings.HasPrefix(excerpts[0], "w0 ") || !strings.HasSuffix(excerpts[4], " w4999") || !strings.Contains(excerpts[2], "w2500") {
+		t.Errorf("sampled excerpts don't span the transcript: %q", got)
+	}
+	if tr.KeptChars > 10000 {
+		t.Errorf("sampled kept %d chars, limit 10000", tr.KeptChars)
+	}
+	for _, e := range excerpts {
+		for _, word := range strings.Fields(e) {
+			if !strings.HasPrefix(word, "w") {
+				t.Fatalf("excerpt cuts through a word: %q", word)
+			}
+		}
+	}
+}
+
+func TestExcerptWordBoundaries(t *testing.T) {
+	text := []rune("héllo wörld and mōre")
+	if got := excerpt(text, 2, 14); got != "wörld" {
+		t.Errorf("excerpt = %q, want %q", got, "wörld")
+	}
+	if got := excerpt(text, 0, len(text)); got != string(text) {
+		t.Errorf("whole excerpt = %q", got)
+	}
+}
+
+func TestTranscriptLimit(t *testing.T) {
+	t.Setenv("YTSUMMARY_MAX_TRANSCRIPT_CHARS", "")
+	t.Setenv("YTSUMMARY_TRUNCATE_STRATEGY", "")
+
+	if n, s, err := transcriptLimit(0, ""); n != 0 || s != truncateHeadTail || err != nil {
+		t.Errorf("defaults = %d, %q, %v", n, s, err)
+	}
+	if _, _, err := transcriptLimit(500, ""); err == nil {
+		t.Error("limit below the minimum accepted")
+	}
+	if _, _, err := transcriptLimit(5000, "middle"); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+
+	t.Setenv("YTSUMMARY_MAX_TRANSCRIPT_CHARS", "40000")
+	t.Setenv("YTSUMMARY_TRUNCATE_STRATEGY", truncateSampled)
+	if n, s, err := transcriptLimit(0, ""); n != 40000 || s != truncateSampled || err != nil {
+		t.Errorf("env = %d, %q, %v", n, s, err)
+	}
+	if n, s, err := transcriptLimit(2000, truncateHead); n != 2000 || s != truncateHead || err != nil {
+		t.Errorf("request = %d, %q, %v", n, s, err)
+	}
+}
+
+func TestSummarizeTruncated(t *testing.T) {
+	cache := newTestCache(t)
+	transcript := numberedWords(5000)
+	cache.StoreTranscript(&CacheEntry{VideoID: "dQw4w9WgXcQ", Language: "en", Transcript: transcript})
+	var sent string
+	handler := newServer(ServerConfig{
+		Cache: cache,
+		Summarize: func(transcript string, opts SummaryOptions) (string, error) {
+			sent = transcript
+			return "A summary.", nil
+		},
+	}).Handler()
+
+	req := httptest.NewRequest("GET", "/v1/summarize?url=dQw4w9WgXcQ&max_transcript_chars=2000&truncate_strategy=head", nil)
+	req.RemoteAddr = "192.0.2.25:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp TranscriptResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(sent) > 2000 || !strings.HasPrefix(sent, "w0 ") {
+		t.Errorf("summarized %d chars starting %q", len(sent), sent[:10])
+	}
+	if resp.Truncation == nil || resp.Truncation.Strategy != truncateHead || resp.Truncation.OriginalChars != len(transcript) {
+		t.Errorf("Truncation = %+v", resp.Truncation)
+	}
+	if !strings.Contains(resp.Summary, "**Truncated transcript:** only the first") {
+		t.Errorf("Summary = %q, want a truncation note", resp.Summary)
+	}
+
+	req = httptest.NewRequest("GET", "/v1/summarize?url=dQw4w9WgXcQ&max_transcript_chars=10", nil)
+	req.RemoteAddr = "192.0.2.25:1234"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != 400 {
+		t.Errorf("tiny limit: status = %d, want 400", w.Code)
+	}
+}
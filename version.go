@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// Set at release build time, e.g.
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=abc1234 -X main.buildDate=2026-10-01T12:00:00Z -X main.buildFeatures=docker"
+//
+// Builds without them fall back to what the Go toolchain records.
+var (
+	version       = ""
+	commit        = ""
+	buildDate     = ""
+	buildFeatures = "" // comma-separated
+)
+
+var versionJSON bool
+
+// BuildInfo identifies the binary
+type BuildInfo struct {
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit,omitempty"`
+	Dirty     bool     `json:"dirty,omitempty"` // built from a tree with uncommitted changes
+	BuildDate string   `json:"build_date,omitempty"`
+	GoVersion string   `json:"go_version"`
+	Platform  string   `json:"platform"` // GOOS/GOARCH
+	Features  []string `json:"features,omitempty"`
+}
+
+var (
+	buildInfoOnce sync.Once
+	buildInfoVal  BuildInfo
+)
+
+// getBuildInfo returns the ldflags values, filled in from the module and VCS
+// information the Go toolchain embeds
+func getBuildInfo() BuildInfo {
+	buildInfoOnce.Do(func() {
+		buildInfoVal = readBuildInfo()
+	})
+	return buildInfoVal
+}
+
+func readBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	features := map[string]bool{}
+	for _, f := range strings.Split(buildFeatures, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			features[f] = true
+		}
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				info.Dirty = s.Value == "true"
+			case "CGO_ENABLED":
+				if s.Value == "1" {
+					features["cgo"] = true
+				}
+			case "-tags":
+				for _, tag := range strings.Split(s.Value, ",") {
+					if tag != "" {
+						features["tag:"+tag] = true
+					}
+				}
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if len(info.Commit) > 12 {
+		info.Commit = info.Commit[:12]
+	}
+
+	for f := range features {
+		info.Features = append(info.Features, f)
+	}
+	sort.Strings(info.Features)
+	return info
+}
+
+// String is the one-line form printed by 'ytsummary version'
+func (b BuildInfo) String() string {
+	s := "ytsummary " + b.Version
+	if b.Commit != "" {
+		s += " (" + b.Commit
+		if b.Dirty {
+			s += "-dirty"
+		}
+		s += ")"
+	}
+	s += " " + b.GoVersion + " " + b.Platform
+	if b.BuildDate != "" {
+		s += " built " + b.BuildDate
+	}
+	if len(b.Features) > 0 {
+		s += " [" + strings.Join(b.Features, ", ") + "]"
+	}
+	return s
+}
+
+// versionHeaderMiddleware names the build on every response
+func versionHeaderMiddleware(next http.Handler) http.Handler {
+	header := getBuildInfo().Version
+	if c := getBuildInfo().Commit; c != "" {
+		header += "+" + c
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ytsummary-Version", header)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	info := getBuildInfo()
+	if !versionJSON {
+		fmt.Println(info)
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(info)
+}
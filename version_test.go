@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestReadBuildInfo(t *testing.T) {
+	version, commit, buildFeatures = "v1.2.0", "0123456789abcdef", "docker, arm"
+	defer func() { version, commit, buildFeatures = "", "", "" }()
+
+	info := readBuildInfo()
+	if info.Version != "v1.2.0" || info.Commit != "0123456789ab" {
+		t.Errorf("info = %+v, want the ldflags version and a short commit", info)
+	}
+	if info.Platform != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("Platform = %q", info.Platform)
+	}
+	if !slices.Contains(info.Features, "docker") || !slices.Contains(info.Features, "arm") {
+		t.Errorf("Features = %v, want the ldflags features", info.Features)
+	}
+}
+
+func TestHealthReportsBuild(t *testing.T) {
+	s := newServer(ServerConfig{Cache: newTestCache(t)})
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+
+	var resp HealthResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Build.Version == "" || resp.Build.GoVersion == "" {
+		t.Errorf("health build = %+v, want version info", resp.Build)
+	}
+	if w.Header().Get("X-Ytsummary-Version") == "" {
+		t.Error("missing X-Ytsummary-Version header")
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var languagesRefresh bool
+
+// VideoInfo is the metadata available from the player response, without captions
+type VideoInfo struct {
+	VideoID           string           `json:"video_id"`
+	CanonicalURL      string           `json:"canonical_url"`
+	Title             string           `json:"title,omitempty"`
+	Channel           string           `json:"channel,omitempty"`
+	ChannelID         string           `json:"channel_id,omitempty"`
+	DurationSeconds   int              `json:"duration_seconds"`
+	PublishDate       string           `json:"publish_date,omitempty"`
+	IsLive            bool             `json:"is_live"`
+	Playability       string           `json:"playability"` // YouTube's status: OK, UNPLAYABLE, LOGIN_REQUIRED, ERROR...
+	PlayabilityReason string           `json:"playability_reason,omitempty"`
+	Captions          []CaptionInfo    `json:"captions"`
+	Statistics        *VideoStatistics `json:"statistics,omitempty"` // from the Data API, with a key
+	DurationMS        int64            `json:"duration_ms,omitempty"`
+}
+
+// CaptionInfo describes one available caption track
+type CaptionInfo struct {
+	Language      string `json:"language"`
+	Name          string `json:"name,omitempty"`
+	AutoGenerated bool   `json:"auto_generated"`
+}
+
+// fetchVideoInfo returns video metadata with a single player request, made
+// with client; captions are not downloaded
+func fetchVideoInfo(client *http.Client, videoID string) (*VideoInfo, error) {
+	pr, err := fetchPlayerResponseTraced(client, videoID, nil)
+	if err != nil {
+		return nil, err
+	}
+	info := videoInfoFromPlayerResponse(videoID, pr)
+	completeVideoInfo(client, info)
+	return info, nil
+}
+
+func videoInfoFromPlayerResponse(videoID string, pr *YouTubePlayerResponse) *VideoInfo {
+	duration, _ := strconv.Atoi(pr.VideoDetails.LengthSeconds)
+
+	info := &VideoInfo{
+		VideoID:           videoID,
+		CanonicalURL:      canonicalVideoURL(videoID),
+		Title:             pr.VideoDetails.Title,
+		Channel:           pr.VideoDetails.Author,
+		ChannelID:         pr.VideoDetails.ChannelID,
+		DurationSeconds:   duration,
+		IsLive:            pr.VideoDetails.IsLiveContent,
+		Playability:       pr.PlayabilityStatus.Status,
+		PlayabilityReason: pr.PlayabilityStatus.Reason,
+		Captions:          []CaptionInfo{},
+	}
+
+	// publishDate is a full timestamp in newer responses; keep just the date
+	if date := pr.Microformat.PlayerMicroformatRenderer.PublishDate; date != "" {
+		info.PublishDate, _, _ = strings.Cut(date, "T")
+	}
+
+	info.Captions = captionInfos(pr)
+
+	return info
+}
+
+// captionInfos lists the caption tracks in a player response
+func captionInfos(pr *YouTubePlayerResponse) []CaptionInfo {
+	captions := []CaptionInfo{}
+	for _, track := range pr.Captions.PlayerCaptionsTracklistRenderer.CaptionTracks {
+		captions = append(captions, CaptionInfo{
+			Language:      track.LanguageCode,
+			Name:          track.Name.SimpleText,
+			AutoGenerated: track.Kind == "asr",
+		})
+	}
+	return captions
+}
+
+// cacheCaptionTracks stores the tracks of a playable video so later language
+// lookups don't need YouTube. Unplayable videos aren't cached since their
+// tracks may appear once they become available.
+func cacheCaptionTracks(cache Cache, info *VideoInfo) error {
+	if info.Playability != "OK" {
+		return nil
+	}
+	return cache.StoreCaptionTracks(info.VideoID, info.Captions)
+}
+
+// videoLanguages returns a video's caption tracks, from the cache when its
+// player response has been fetched before. refresh always asks YouTube.
+func videoLanguages(client *http.Client, cache Cache, videoID string, refresh bool) (captions []CaptionInfo, cached bool, err error) {
+	if !refresh {
+		if captions, err := cache.GetCaptionTracks(videoID); err == nil {
+			return captions, true, nil
+		}
+	}
+
+	info, err := fetchVideoInfo(client, videoID)
+	if err != nil {
+		return nil, false, err
+	}
+	// Caching is best-effort; a read-only cache still answers from YouTube
+	_ = cacheCaptionTracks(cache, info)
+	return info.Captions, false, nil
+}
+
+// runInfo prints video metadata without fetching captions
+func runInfo(cmd *cobra.Command, args []string) error {
+	videoID, _, err := resolveVideoURL(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid YouTube URL: %w", err)
+	}
+
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	client := youtubeClient()
+	info, err := fetchVideoInfo(client, videoID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch video info: %w", err)
+	}
+	_ = cacheCaptionTracks(cache, info)
+	addVideoStatistics(client, info)
+
+	fmt.Printf("Video ID:    %s\n", info.VideoID)
+	fmt.Printf("Title:       %s\n", info.Title)
+	fmt.Printf("Channel:     %s\n", info.Channel)
+	fmt.Printf("Duration:    %s\n", formatDuration(info.DurationSeconds))
+	if info.PublishDate != "" {
+		fmt.Printf("Published:   %s\n", info.PublishDate)
+	}
+	status := info.Playability
+	if info.PlayabilityReason != "" {
+		status += " (" + info.PlayabilityReason + ")"
+	}
+	fmt.Printf("Playability: %s\n", status)
+	if info.Statistics != nil {
+		fmt.Printf("Views:       %d\n", info.Statistics.Views)
+		if info.Statistics.Likes > 0 {
+			fmt.Printf("Likes:       %d\n", info.Statistics.Likes)
+		}
+		if info.Statistics.Comments > 0 {
+			fmt.Printf("Comments:    %d\n", info.Statistics.Comments)
+		}
+	}
+
+	if len(info.Captions) == 0 {
+		fmt.Println("Captions:    none")
+		return nil
+	}
+	fmt.Println("Captions:")
+	printCaptions(info.Captions)
+	return nil
+}
+
+// printCaptions lists caption tracks, one per line
+func printCaptions(captions []CaptionInfo) {
+	for _, c := range captions {
+		kind := ""
+		if c.AutoGenerated {
+			kind = " [auto-generated]"
+		}
+		fmt.Printf("  %-8s %s%s\n", c.Language, c.Name, kind)
+	}
+}
+
+// runLanguages lists a video's caption languages, from the cache when the
+// video has been seen before
+func runLanguages(cmd *cobra.Command, args []string) error {
+	videoID, _, err := resolveVideoURL(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid YouTube URL: %w", err)
+	}
+
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	captions, cached, err := videoLanguages(youtubeClient(), cache, videoID, languagesRefresh)
+	if err != nil {
+		return fmt.Errorf("failed to fetch video info: %w", err)
+	}
+	if cached {
+		log("Using cached caption tracks")
+	}
+
+	if len(captions) == 0 {
+		fmt.Println("No captions")
+		return nil
+	}
+	printCaptions(captions)
+	return nil
+}
+
+func (s *Server) handleVideoInfo(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	videoID, err := extractVideoID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid video ID")
+		return
+	}
+
+	reqCtx := getRequestContext(r)
+	reqCtx.setVideo(videoID)
+
+	info, err := fetchVideoInfo(s.youtube, videoID)
+	if err != nil {
+		logWarn("video info fetch failed", slog.String("video_id", videoID), slog.String("error", err.Error()))
+		handleFetchError(w, err, videoID)
+		return
+	}
+
+	_ = cacheCaptionTracks(s.cache, info)
+	addVideoStatistics(s.youtube, info)
+
+	s.markSuccess()
+	info.DurationMS = time.Since(start).Milliseconds()
+	writeJSON(w, http.StatusOK, info)
+}
+
+// LanguagesResponse lists a video's caption tracks
+type LanguagesResponse struct {
+	VideoID    string        `json:"video_id"`
+	Captions   []CaptionInfo `json:"captions"`
+	Cached     bool          `json:"cached"`
+	DurationMS int64         `json:"duration_ms"`
+}
+
+// handleVideoLanguages answers from cached caption tracks when the video has
+// been seen before (?refresh=true asks YouTube again)
+func (s *Server) handleVideoLanguages(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	videoID, err := extractVideoID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidRequest, "invalid video ID")
+		return
+	}
+
+	reqCtx := getRequestContext(r)
+	reqCtx.setVideo(videoID)
+
+	captions, cached, err := videoLanguages(s.youtube, s.cache, videoID, r.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		logWarn("video info fetch failed", slog.String("video_id", videoID), slog.String("error", err.Error()))
+		handleFetchError(w, err, videoID)
+		return
+	}
+
+	if !cached {
+		s.markSuccess()
+	}
+	writeJSON(w, http.StatusOK, LanguagesResponse{
+		VideoID:    videoID,
+		Captions:   captions,
+		Cached:     cached,
+		DurationMS: time.Since(start).Milliseconds(),
+	})
+}
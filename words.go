@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// TranscriptWord is one word of auto-generated captions with its timing in
+// seconds, for cutting clips or regenerating subtitles to the word
+type TranscriptWord struct {
+	Word     string  `json:"word"`
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+}
+
+// errNoWordTimings explains an empty format=words or --words result
+var errNoWordTimings = errors.New("word timings unavailable for this video (only YouTube's auto-generated captions have them)")
+
+// timingDetail is how much caption timing a caller needs from a transcript
+type timingDetail int
+
+const (
+	timingNone     timingDetail = iota
+	timingSegments              // per-cue timings
+	timingWords                 // per-word timings, which only auto-generated captions have
+)
+
+// hasTimings reports whether entry has the timings need asks for, or
+// whether refetching it couldn't get them: uploaded and machine-translated
+// captions carry no word timings
+func hasTimings(entry *CacheEntry, need timingDetail) bool {
+	switch need {
+	case timingSegments:
+		return len(entry.Segments) > 0
+	case timingWords:
+		return len(entry.Segments) > 0 && (hasWordTimings(entry.Segments) || !entry.AutoGenerated || entry.TranslatedFrom != "")
+	}
+	return true
+}
+
+// hasWordTimings reports whether any segment times its words
+func hasWordTimings(segments []TranscriptSegment) bool {
+	for _, seg := range segments {
+		if len(seg.Words) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// transcriptWords flattens the segments' word timings into one list
+func transcriptWords(segments []TranscriptSegment) []TranscriptWord {
+	var words []TranscriptWord
+	for _, seg := range segments {
+		words = append(words, seg.Words...)
+	}
+	return words
+}
+
+// withoutWords returns the segments without word timings, for responses that
+// asked for segments only
+func withoutWords(segments []TranscriptSegment) []TranscriptSegment {
+	if !hasWordTimings(segments) {
+		return segments
+	}
+	out := make([]TranscriptSegment, len(segments))
+	for i, seg := range segments {
+		seg.Words = nil
+		out[i] = seg
+	}
+	return out
+}
+
+// parseSrv3Words reads the <s> word elements of an srv3 <p> cue starting at
+// start seconds. Each word's t attribute is its offset from the cue in
+// milliseconds. It returns the cue's text, still HTML-escaped, and the words.
+func parseSrv3Words(body string, start float64) (string, []TranscriptWord) {
+	var text strings.Builder
+	var words []TranscriptWord
+	scanTimedTextElements(body, "s", func(attrs, word string) {
+		text.WriteString(word)
+		if w := cleanCaptionText(html.UnescapeString(word)); w != "" {
+			words = append(words, TranscriptWord{Word: w, Start: start + attrMillis(attrs, "t")})
+		}
+	})
+	if words == nil {
+		return stripTags(body), nil
+	}
+	return text.String(), words
+}
+
+// json3Captions is YouTube's fmt=json3 caption format. Auto-generated tracks
+// split each event into timed word segments.
+type json3Captions struct {
+	Events []struct {
+		StartMs    int64 `json:"tStartMs"`
+		DurationMs int64 `json:"dDurationMs"`
+		Segs       []struct {
+			Text     string `json:"utf8"`
+			OffsetMs int64  `json:"tOffsetMs"`
+		} `json:"segs"`
+	} `json:"events"`
+}
+
+// parseJSON3Segments parses fmt=json3 captions into timed segments, with
+// word timings for auto-generated tracks, cleaned like parseTimedTextSegments
+func parseJSON3Segments(content string, autoGenerated bool) ([]TranscriptSegment, error) {
+	var captions json3Captions
+	if err := json.Unmarshal([]byte(content), &captions); err != nil {
+		return nil, fmt.Errorf("failed to parse json3 captions: %w", err)
+	}
+
+	b := newSegmentBuilder(autoGenerated)
+	for _, event := range captions.Events {
+		start := float64(event.StartMs) / 1000
+		var text strings.Builder
+		var words []TranscriptWord
+		for _, seg := range event.Segs {
+			text.WriteString(strings.ReplaceAll(seg.Text, "\n", " "))
+			if w := cleanCaptionText(seg.Text); w != "" {
+				words = append(words, TranscriptWord{Word: w, Start: start + float64(seg.OffsetMs)/1000})
+			}
+		}
+		// Uploaded tracks give each cue as one segment of several words
+		if len(words) == 1 && strings.ContainsFunc(words[0].Word, unicode.IsSpace) {
+			words = nil
+		}
+		b.add(start, float64(event.DurationMs)/1000, text.String(), words)
+	}
+	return b.finish(), nil
+}
+
+// timeWords sets each word's duration: until the next word starts, but not
+// past the end of its cue
+func timeWords(segments []TranscriptSegment) {
+	for i := range segments {
+		seg := &segments[i]
+		end := seg.Start + seg.Duration
+		for j := range seg.Words {
+			next := end
+			switch {
+			case j+1 < len(seg.Words):
+				next = seg.Words[j+1].Start
+			case i+1 < len(segments):
+				next = min(end, segments[i+1].Start)
+			}
+			if next <= seg.Words[j].Start {
+				next = max(end, seg.Words[j].Start)
+			}
+			seg.Words[j].Duration = next - seg.Words[j].Start
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// srv3Captions is the shape of fmt=srv3 auto-generated captions: timed <s>
+// words within each <p>, with empty "append" paragraphs between lines
+const srv3Captions = `<?xml version="1.0" encoding="utf-8" ?><timedtext format="3">
+<head>
+<ws id="0"/>
+<wp id="0"/>
+<pen id="1" fc="#E5E5E5"/>
+</head>
+<body>
+<w t="0" id="1" wp="0" ws="0"/>
+<p t="1200" d="3000" w="1"><s ac="0">so</s><s t="240" ac="0"> today</s><s t="600" ac="0"> we&#39;re</s></p>
+<p t="2900" d="1300" w="1" a="1">
+</p>
+<p t="2910" d="2000" w="1"><s ac="0">talking</s><s t="500" ac="0"> about</s><s t="900" ac="0"> words</s></p>
+</body>
+</timedtext>`
+
+// approx reports whether two timings are equal to the millisecond
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 0.001
+}
+
+func TestParseSrv3Words(t *testing.T) {
+	segments := parseTimedTextSegments(srv3Captions, true)
+	if len(segments) != 2 {
+		t.Fatalf("got %d segments, want 2: %+v", len(segments), segments)
+	}
+	if got := segmentsText(segments); got != "so today we're talking about words" {
+		t.Errorf("text = %q", got)
+	}
+
+	want := []TranscriptWord{
+		{"so", 1.2, 0.24},
+		{"today", 1.44, 0.36},
+		{"we're", 1.8, 1.11}, // until the next line starts
+		{"talking", 2.91, 0.5},
+		{"about", 3.41, 0.4},
+		{"words", 3.81, 1.1}, // until its cue ends
+	}
+	got := transcriptWords(segments)
+	if len(got) != len(want) {
+		t.Fatalf("got %d words, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Word != want[i].Word || !approx(got[i].Start, want[i].Start) || !approx(got[i].Duration, want[i].Duration) {
+			t.Errorf("word %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseJSON3Segments(t *testing.T) {
+	asr := `{"events": [
+		{"tStartMs": 0, "dDurationMs": 5000, "id": 1, "wpWinPosId": 1},
+		{"tStartMs": 1000, "dDurationMs": 2000, "segs": [{"utf8": "hello"}, {"utf8": " there", "tOffsetMs": 400}]},
+		{"tStartMs": 2900, "dDurationMs": 100, "aAppend": 1, "segs": [{"utf8": "\n"}]},
+		{"tStartMs": 3000, "dDurationMs": 1000, "segs": [{"utf8": "friends"}]}
+	]}`
+	segments, err := parseJSON3Segments(asr, true)
+	if err != nil {
+		t.Fatalf("parseJSON3Segments() error = %v", err)
+	}
+	if got := segmentsText(segments); got != "hello there friends" {
+		t.Errorf("text = %q", got)
+	}
+	words := transcriptWords(segments)
+	if len(words) != 3 || words[1].Word != "there" || !approx(words[1].Start, 1.4) || !approx(words[1].Duration, 1.6) || words[2].Word != "friends" {
+		t.Errorf("words = %+v", words)
+	}
+
+	// Uploaded tracks carry whole cues, not words
+	uploaded := `{"events": [{"tStartMs": 0, "dDurationMs": 2000, "segs": [{"utf8": "Hello there\nfriends"}]}]}`
+	if segments, err = parseJSON3Segments(uploaded, false); err != nil || len(segments) != 1 || segments[0].Words != nil || segments[0].Text != "Hello there friends" {
+		t.Errorf("uploaded = %+v, %v", segments, err)
+	}
+
+	if _, err := parseJSON3Segments("{not json", false); err == nil {
+		t.Error("expected an error for invalid json3")
+	}
+}
+
+func TestTranscriptWordsFormat(t *testing.T) {
+	cache := newTestCache(t)
+	// Cached before word timings were kept, so format=words refetches it
+	cache.StoreTranscript(&CacheEntry{
+		VideoID: "dQw4w9WgXcQ", Language: "en", Transcript: "so today", AutoGenerated: true,
+		Segments: []TranscriptSegment{{Start: 1.2, Duration: 3, Text: "so today"}},
+	})
+	cache.StoreTranscript(&CacheEntry{
+		VideoID: "uploaded001", Language: "en", Transcript: "so today",
+		Segments: []TranscriptSegment{{Start: 1.2, Duration: 3, Text: "so today"}},
+	})
+	fetches := 0
+	handler := newServer(ServerConfig{
+		Cache: cache,
+		Fetch: func(url, lang string, allowTranslate bool) (*FetchResult, error) {
+			fetches++
+			segments := parseTimedTextSegments(srv3Captions, true)
+			return &FetchResult{Transcript: segmentsText(segments), Segments: segments, Language: "en", AutoGenerated: true}, nil
+		},
+	}).Handler()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/transcript", strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.20:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"url": "dQw4w9WgXcQ", "format": "words"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp TranscriptResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Words) != 6 || resp.Words[0].Word != "so" || resp.Transcript != "" || resp.Segments != nil {
+		t.Errorf("response = %+v", resp)
+	}
+	if fetches != 1 {
+		t.Errorf("fetches = %d, want a refetch for the missing word timings", fetches)
+	}
+
+	// Segments leave the words out; the refetched entry is reused
+	w = post(`{"url": "dQw4w9WgXcQ", "format": "segments"}`)
+	if strings.Contains(w.Body.String(), `"words"`) {
+		t.Errorf("format=segments included words: %s", w.Body)
+	}
+	if post(`{"url": "dQw4w9WgXcQ", "format": "words", "from": "0:03"}`).Code != http.StatusOK || fetches != 1 {
+		t.Errorf("fetches = %d after cached requests, want 1", fetches)
+	}
+
+	// Uploaded captions have no word timings, and refetching wouldn't help
+	w = post(`{"url": "https://youtu.be/uploaded001", "format": "words"}`)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "auto-generated") {
+		t.Errorf("uploaded: status = %d: %s", w.Code, w.Body)
+	}
+	if fetches != 1 {
+		t.Errorf("fetches = %d, want no refetch for uploaded captions", fetches)
+	}
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	neturl "net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultQueueList is the Redis list jobs are pushed to; results go to the
+// same name with queueResultsSuffix. Jobs being worked on wait in the list
+// with queueProcessingSuffix, each under a lease named with queueLeaseSuffix.
+const (
+	defaultQueueList      = "ytsummary:jobs"
+	queueResultsSuffix    = ":results"
+	queueProcessingSuffix = ":processing"
+	queueLeaseSuffix      = ":lease:"
+)
+
+// workerPollInterval is how long a worker blocks waiting for a job before
+// checking whether it should stop
+const workerPollInterval = 5 * time.Second
+
+// A worker holds a lease on the job in hand, renewing it every
+// queueLeaseRenew; jobs whose lease lapsed (their worker died) are put back
+// on the queue when a worker starts and every queueRequeueInterval after
+const (
+	queueLeaseTTL        = time.Minute
+	queueLeaseRenew      = queueLeaseTTL / 3
+	queueRequeueInterval = time.Minute
+)
+
+// A worker that can't reach the queue retries after a backoff growing from
+// workerMinBackoff to workerMaxBackoff
+const (
+	workerMinBackoff = time.Second
+	workerMaxBackoff = 30 * time.Second
+)
+
+var (
+	workerQueueURL    string
+	workerConcurrency int
+)
+
+// QueueJob is a video to summarize, as pushed onto the queue
+type QueueJob struct {
+	ID       string  `json:"id"`
+	URL      string  `json:"url"`
+	Language string  `json:"language,omitempty"` // defaults to the worker's --lang
+	Template string  `json:"template,omitempty"` // defaults to the worker's --template
+	MaxCost  float64 `json:"max_cost,omitempty"` // defaults to YTSUMMARY_MAX_COST
+
+	payload   string        // as taken from the queue, to acknowledge it by
+	stopLease chan struct{} // closed to stop renewing the lease
+	leaseDone chan struct{} // closed once renewal has stopped
+	stopOnce  sync.Once
+}
+
+// stopRenewing stops renewing the job's lease, which then lapses unless the
+// job is acknowledged first
+func (j *QueueJob) stopRenewing() {
+	if j.stopLease == nil {
+		return
+	}
+	j.stopOnce.Do(func() {
+		close(j.stopLease)
+		<-j.leaseDone
+	})
+}
+
+// QueueResult is a finished job, published to the results list
+type QueueResult struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	VideoID    string    `json:"video_id,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	Language   string    `json:"language,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"`
+	Worker     string    `json:"worker"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// jobQueue is the external queue workers consume. A job taken with next
+// stays on the queue, leased to this worker, until done acknowledges it.
+type jobQueue interface {
+	// next waits up to timeout for a job, returning nil if none arrived
+	next(timeout time.Duration) (*QueueJob, error)
+	// done removes a job taken with next from the queue
+	done(job *QueueJob) error
+	// requeue puts back jobs whose worker died before finishing them
+	requeue() (int, error)
+	enqueue(job *QueueJob) error
+	publish(result *QueueResult) error
+	Close() error
+}
+
+// newJobQueue connects to the queue at rawURL. Only Redis lists are
+// supported: redis://host:6379/0?list=ytsummary:jobs
+func newJobQueue(rawURL string) (jobQueue, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid queue URL: %w", err)
+	}
+	switch u.Scheme {
+	case "redis", "rediss":
+		list := u.Query().Get("list")
+		if list == "" {
+			list = defaultQueueList
+		}
+		u.RawQuery = ""
+		client, err := newRedisClient(u.String())
+		if err != nil {
+			return nil, err
+		}
+		return &redisJobQueue{client: client, list: list, leaseRenew: queueLeaseRenew}, nil
+	case "":
+		return nil, fmt.Errorf("no queue configured (use --queue or YTSUMMARY_QUEUE, e.g. redis://localhost:6379)")
+	default:
+		return nil, fmt.Errorf("unsupported queue %s:// (only redis:// and rediss:// are supported)", u.Scheme)
+	}
+}
+
+// redisRequeueScript moves a job from the processing list back to the front
+// of the queue, unless a worker still holds its lease
+const redisRequeueScript = `if redis.call("exists", KEYS[3]) == 0 and redis.call("lrem", KEYS[1], 1, ARGV[1]) == 1 then redis.call("lpush", KEYS[2], ARGV[1]) return 1 end return 0`
+
+// redisJobQueue keeps jobs in a Redis list: producers RPUSH, workers BLMOVE
+// them to a processing list and remove them from it once the result is
+// published. Delivery is at least once: a job whose worker died is run again.
+type redisJobQueue struct {
+	client     *redisClient
+	list       string
+	leaseRenew time.Duration // queueLeaseRenew; shorter in tests
+}
+
+func (q *redisJobQueue) processingList() string {
+	return q.list + queueProcessingSuffix
+}
+
+// leaseKey names the lease on a job, by its payload since producers may
+// leave out or reuse IDs
+func (q *redisJobQueue) leaseKey(payload string) string {
+	return q.list + queueLeaseSuffix + sha256Hex([]byte(payload))[:32]
+}
+
+func (q *redisJobQueue) next(timeout time.Duration) (*QueueJob, error) {
+	// Redis answers a BLMOVE by the end of its timeout
+	reply, err := q.client.doWithin(timeout+redisCommandTimeout, "BLMOVE", q.list, q.processingList(), "LEFT", "RIGHT", fmt.Sprint(int(timeout.Seconds())))
+	if errors.Is(err, errRedisNil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	payload, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected BLMOVE reply %v", reply)
+	}
+
+	job := &QueueJob{payload: payload}
+	if err := json.Unmarshal([]byte(payload), job); err != nil {
+		// Running it again wouldn't help
+		q.client.do("LREM", q.processingList(), "1", payload)
+		return nil, fmt.Errorf("invalid job %q: %w", payload, err)
+	}
+
+	token := newJobID()
+	lease := q.leaseKey(payload)
+	if _, err := q.client.do("SET", lease, token, "PX", fmt.Sprint(queueLeaseTTL.Milliseconds())); err != nil {
+		// Without a lease the job is requeued for another worker
+		return nil, fmt.Errorf("failed to lease job: %w", err)
+	}
+	job.stopLease, job.leaseDone = make(chan struct{}), make(chan struct{})
+	go q.renewLease(lease, token, job.stopLease, job.leaseDone)
+	return job, nil
+}
+
+// renewLease keeps a job leased until stop is closed, then closes done
+func (q *redisJobQueue) renewLease(lease, token string, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(q.leaseRenew)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if _, err := q.client.do("EVAL", redisRenewScript, "1", lease, token, fmt.Sprint(queueLeaseTTL.Milliseconds())); err != nil {
+			logWarn("failed to renew job lease", slog.String("lease", lease), slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (q *redisJobQueue) done(job *QueueJob) error {
+	job.stopRenewing()
+	if _, err := q.client.do("LREM", q.processingList(), "1", job.payload); err != nil {
+		return err
+	}
+	_, err := q.client.do("DEL", q.leaseKey(job.payload))
+	return err
+}
+
+func (q *redisJobQueue) requeue() (int, error) {
+	reply, err := q.client.do("LRANGE", q.processingList(), "0", "-1")
+	if err != nil && !errors.Is(err, errRedisNil) {
+		return 0, err
+	}
+	items, _ := reply.([]any)
+	requeued := 0
+	for _, item := range items {
+		payload, _ := item.(string)
+		n, err := q.client.do("EVAL", redisRequeueScript, "3", q.processingList(), q.list, q.leaseKey(payload), payload)
+		if err != nil {
+			return requeued, err
+		}
+		if n == int64(1) {
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+func (q *redisJobQueue) enqueue(job *QueueJob) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = q.client.do("RPUSH", q.list, string(payload))
+	return err
+}
+
+func (q *redisJobQueue) publish(result *QueueResult) error {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = q.client.do("RPUSH", q.list+queueResultsSuffix, string(payload))
+	return err
+}
+
+func (q *redisJobQueue) Close() error {
+	return q.client.Close()
+}
+
+// newJobID returns a random job ID
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// workerName identifies this process in results
+func workerName() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s/%d", host, os.Getpid())
+}
+
+// processQueueJob fetches and summarizes one job's video
+func processQueueJob(client LLMClient, cache *SQLiteCache, job *QueueJob) *QueueResult {
+	start := time.Now()
+	result := &QueueResult{ID: job.ID, URL: job.URL, Language: job.Language, Worker: workerName()}
+	finish := func(class string, err error) *QueueResult {
+		if err != nil {
+			result.ErrorClass, result.Error = class, err.Error()
+			cliMetrics.recordFailure(class)
+		} else {
+			cliMetrics.recordProcessed()
+		}
+		if result.VideoID != "" {
+			recordJobResult(cache, result.VideoID, result.ErrorClass, result.Error)
+		}
+		result.FinishedAt = time.Now().UTC()
+		result.DurationMS = time.Since(start).Milliseconds()
+		return result
+	}
+
+	videoID, err := extractVideoID(job.URL)
+	if err != nil {
+		return finish(ErrInvalidRequest, err)
+	}
+	// Checked before VideoID is set so the skip doesn't count as another failure
+	if d := deadLettered(cache, videoID); d != nil {
+		return finish(d.ErrorClass, errors.New(deadLetterMessage(d)))
+	}
+	result.VideoID = videoID
+	if result.Language == "" {
+		result.Language = language
+	}
+	template := job.Template
+	if template == "" {
+		template = summaryTemplate
+	}
+
+	entry, err := loadTranscript(cache, videoID, result.Language, timingNone)
+	if err != nil {
+		return finish(fetchErrorClass(err), err)
+	}
+	result.Title = entry.Title
+	if template, err = pickTemplate(template, entry, musicVideosMode()); err != nil {
+		return finish(ErrMusicVideo, err)
+	}
+	notes := applyContentFilter(entry, contentFilterMode())
+	if !keepNonSpeech {
+		stripCaptionArtifacts(entry)
+	}
+
+	budget, err := requestBudget(job.MaxCost)
+	if err != nil {
+		return finish(ErrInvalidRequest, err)
+	}
+	meta := &SummaryMeta{}
+	summary, err := summarizeWith(client, entry.Transcript, SummaryOptions{
+		Template:    template,
+		Vars:        promptVarsFromEntry(entry, summaryLanguage(result.Language)),
+		Checkpoints: cache,
+		MaxWords:    maxWords,
+		Lint:        lintEnabled(),
+		Budget:      budget,
+		Meta:        meta,
+	})
+	if err != nil {
+		return finish(llmErrorClass(err), err)
+	}
+	keepSummary(cache, newStoredSummary(videoID, summaryLanguage(result.Language), template, maxWords, summary, meta))
+	result.Summary = withContentNote(summary, notes)
+	return finish("", nil)
+}
+
+// runWorkerLoop takes jobs from q until ctx is cancelled, finishing the job
+// in hand. Queue errors are logged and retried with a growing backoff, so a
+// Redis restart only pauses the worker.
+func runWorkerLoop(ctx context.Context, q jobQueue, client LLMClient, cache *SQLiteCache) {
+	backoff := workerMinBackoff
+	// retry logs err and waits out the backoff, reporting whether to go on
+	retry := func(what string, err error) bool {
+		logError("queue unavailable, retrying", slog.String("operation", what), slog.String("error", err.Error()), slog.Duration("backoff", backoff))
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, workerMaxBackoff)
+		return true
+	}
+
+	var lastRequeue time.Time
+	for ctx.Err() == nil {
+		if time.Since(lastRequeue) >= queueRequeueInterval {
+			n, err := q.requeue()
+			if err != nil {
+				retry("requeue", err)
+				continue
+			}
+			if n > 0 {
+				fmt.Fprintf(os.Stderr, "Requeued %d job(s) left unfinished by stopped workers\n", n)
+			}
+			lastRequeue = time.Now()
+		}
+
+		job, err := q.next(workerPollInterval)
+		if err != nil {
+			retry("next", err)
+			continue
+		}
+		backoff = workerMinBackoff
+		if job == nil {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Job %s: %s\n", job.ID, job.URL)
+		result := processQueueJob(client, cache, job)
+		if result.Error != "" {
+			fmt.Fprintf(os.Stderr, "Job %s failed (%s): %s\n", job.ID, result.ErrorClass, result.Error)
+		}
+
+		// The job stays leased until its result is out; if this worker
+		// stops first, the lease lapses and another worker runs it again
+		err = q.publish(result)
+		for err != nil && retry("publish", err) {
+			err = q.publish(result)
+		}
+		if err != nil {
+			job.stopRenewing()
+			continue
+		}
+		err = q.done(job)
+		for err != nil && retry("acknowledge", err) {
+			err = q.done(job)
+		}
+		backoff = workerMinBackoff
+	}
+}
+
+func runWorker(cmd *cobra.Command, args []string) error {
+	queueURL := getConfig(workerQueueURL, "YTSUMMARY_QUEUE")
+	if workerConcurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if _, err := getTemplate(summaryTemplate); err != nil {
+		return err
+	}
+	if err := validMusicVideos(musicVideosMode()); err != nil {
+		return err
+	}
+	client, err := newLLMClient()
+	if err != nil {
+		return err
+	}
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	// Each worker blocks on its own connection
+	var queues []jobQueue
+	for range workerConcurrency {
+		q, err := newJobQueue(queueURL)
+		if err != nil {
+			for _, q := range queues {
+				q.Close()
+			}
+			return err
+		}
+		queues = append(queues, q)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	log("Worker %s waiting for jobs on %s (%d at a time)...", workerName(), redactURL(queueURL), workerConcurrency)
+
+	var wg sync.WaitGroup
+	for _, q := range queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer q.Close()
+			runWorkerLoop(ctx, q, client, cache)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func runWorkerEnqueue(cmd *cobra.Command, args []string) error {
+	q, err := newJobQueue(getConfig(workerQueueURL, "YTSUMMARY_QUEUE"))
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	for _, url := range args {
+		url = strings.TrimSpace(url)
+		if _, err := extractVideoID(url); err != nil {
+			return fmt.Errorf("invalid YouTube URL %q: %w", url, err)
+		}
+		job := &QueueJob{ID: newJobID(), URL: url, Language: language, Template: summaryTemplate}
+		if err := q.enqueue(job); err != nil {
+			return err
+		}
+		fmt.Printf("%s\t%s\n", job.ID, url)
+	}
+	return nil
+}
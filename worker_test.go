@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkerProcessesQueuedJobs(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	cache := newTestCache(t)
+	if err := cacheTranscript(cache, "dQw4w9WgXcQ", "en", "Never Gonna", "First point. Second point. Third point. Fourth point."); err != nil {
+		t.Fatal(err)
+	}
+
+	producer, err := newJobQueue(srv.url() + "?list=test:jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	for _, job := range []*QueueJob{
+		{ID: "ok", URL: "https://youtu.be/dQw4w9WgXcQ", Language: "en"},
+		{ID: "bad", URL: "not a video"},
+	} {
+		if err := producer.enqueue(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	worker, err := newJobQueue(srv.url() + "?list=test:jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer worker.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runWorkerLoop(ctx, worker, &fakeLLMClient{sentences: 2}, cache)
+		close(done)
+	}()
+
+	results := map[string]*QueueResult{}
+	for range 2 {
+		var r QueueResult
+		v, ok := srv.pop("test:jobs"+queueResultsSuffix, 5*time.Second)
+		if !ok {
+			t.Fatal("no result published")
+		}
+		if err := json.Unmarshal([]byte(v), &r); err != nil {
+			t.Fatal(err)
+		}
+		results[r.ID] = &r
+	}
+	cancel()
+	<-done
+	srv.mu.Lock()
+	processing := srv.lists["test:jobs"+queueProcessingSuffix]
+	srv.mu.Unlock()
+	if len(processing) != 0 {
+		t.Errorf("jobs left in the processing list: %q", processing)
+	}
+
+	ok := results["ok"]
+	if ok == nil || ok.Error != "" || ok.VideoID != "dQw4w9WgXcQ" || ok.Title != "Never Gonna" || !strings.Contains(ok.Summary, "First point.") {
+		t.Errorf("ok result = %+v", ok)
+	}
+	if bad := results["bad"]; bad == nil || bad.ErrorClass != ErrInvalidRequest {
+		t.Errorf("bad result = %+v", bad)
+	}
+}
+
+func TestRedisJobQueueRequeue(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	q, err := newJobQueue(srv.url() + "?list=test:jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	for _, id := range []string{"crashed", "running"} {
+		if err := q.enqueue(&QueueJob{ID: id, URL: "https://youtu.be/dQw4w9WgXcQ"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	crashed, err := q.next(time.Second)
+	if err != nil || crashed == nil {
+		t.Fatalf("next() = %v, %v", crashed, err)
+	}
+	running, err := q.next(time.Second)
+	if err != nil || running == nil {
+		t.Fatalf("next() = %v, %v", running, err)
+	}
+	defer running.stopRenewing()
+
+	// The first worker died: its lease lapses
+	crashed.stopRenewing()
+	srv.mu.Lock()
+	delete(srv.values, q.(*redisJobQueue).leaseKey(crashed.payload))
+	srv.mu.Unlock()
+
+	if n, err := q.requeue(); err != nil || n != 1 {
+		t.Fatalf("requeue() = %d, %v; want 1", n, err)
+	}
+	again, err := q.next(time.Second)
+	if err != nil || again == nil || again.ID != "crashed" {
+		t.Fatalf("next() after requeue = %+v, %v; want the crashed job", again, err)
+	}
+	if err := q.done(again); err != nil {
+		t.Fatal(err)
+	}
+	srv.mu.Lock()
+	processing := srv.lists["test:jobs"+queueProcessingSuffix]
+	srv.mu.Unlock()
+	if len(processing) != 1 || !strings.Contains(processing[0], `"running"`) {
+		t.Errorf("processing list = %q, want only the running job", processing)
+	}
+}
+
+func TestWorkerRetriesWhenRedisFails(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	cache := newTestCache(t)
+	if err := cacheTranscript(cache, "dQw4w9WgXcQ", "en", "Never Gonna", "First point. Second point."); err != nil {
+		t.Fatal(err)
+	}
+	worker, err := newJobQueue(srv.url() + "?list=test:jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer worker.Close()
+
+	srv.failing.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runWorkerLoop(ctx, worker, &fakeLLMClient{sentences: 2}, cache)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// Redis comes back: the worker carries on with the next job
+	srv.failing.Store(false)
+	srv.mu.Lock()
+	srv.lists["test:jobs"] = append(srv.lists["test:jobs"], `{"id": "after", "url": "https://youtu.be/dQw4w9WgXcQ"}`)
+	srv.mu.Unlock()
+	v, ok := srv.pop("test:jobs"+queueResultsSuffix, 5*time.Second)
+	if !ok || !strings.Contains(v, `"id":"after"`) {
+		t.Errorf("result after the outage = %q, %v", v, ok)
+	}
+	cancel()
+	<-done
+}
+
+func TestNewJobQueueSchemes(t *testing.T) {
+	for _, url := range []string{"", "nats://localhost:4222", "sqs://queue"} {
+		if _, err := newJobQueue(url); err == nil {
+			t.Errorf("newJobQueue(%q): expected an error", url)
+		}
+	}
+}